@@ -0,0 +1,196 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/trust"
+)
+
+// craftJWT signs an arbitrary header and payload with the identity's key.
+func craftJWT(t testing.TB, signer *authtest.Identity, header map[string]any, payload any) string {
+	t.Helper()
+
+	fullHeader := map[string]any{"alg": "ES256K", "typ": "JWT", "kid": signer.DID + "#key-1"}
+	for name, value := range header {
+		fullHeader[name] = value
+	}
+
+	headerJSON, _ := json.Marshal(fullHeader)
+	payloadJSON, _ := json.Marshal(payload)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payloadJSON)
+	return signJWT(t, signingInput, signer.Key)
+}
+
+// adversarialTokens returns well-signed and unsigned tokens with hostile structure.
+func adversarialTokens(t testing.TB, issuer, holder *authtest.Identity) map[string]string {
+	t.Helper()
+
+	vp := func(credentials any) map[string]any {
+		return map[string]any{"iss": holder.DID, "vp": map[string]any{"holder": holder.DID, "verifiableCredential": credentials}}
+	}
+	credential := func(vc any) string {
+		return craftJWT(t, issuer, nil, map[string]any{"iss": issuer.DID, "sub": holder.DID, "vc": vc})
+	}
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	return map[string]string{
+		"empty":                 "",
+		"dots":                  "..",
+		"five dots":             "....",
+		"null header":           encode("null") + "." + encode("{}") + ".",
+		"array payload":         encode(`{"alg":"ES256K"}`) + "." + encode("[]") + ".",
+		"vp string":             craftJWT(t, holder, nil, map[string]any{"vp": "nope"}),
+		"vp null":               craftJWT(t, holder, nil, map[string]any{"vp": nil}),
+		"payload array":         craftJWT(t, holder, nil, []any{1, 2}),
+		"credentials number":    craftJWT(t, holder, nil, vp(42)),
+		"credential number":     craftJWT(t, holder, nil, vp([]any{42})),
+		"credential object":     craftJWT(t, holder, nil, vp([]any{map[string]any{}})),
+		"credential garbage":    craftJWT(t, holder, nil, vp([]any{"not.a.jwt"})),
+		"vc string":             craftJWT(t, holder, nil, vp([]any{credential("nope")})),
+		"vc subject number":     craftJWT(t, holder, nil, vp([]any{credential(map[string]any{"type": "VerifiableCredential", "credentialSubject": 5})})),
+		"vc type number":        craftJWT(t, holder, nil, vp([]any{credential(map[string]any{"type": 7, "credentialSubject": map[string]any{}})})),
+		"vc subject list mixed": craftJWT(t, holder, nil, vp([]any{credential(map[string]any{"type": []any{"VerifiableCredential", nil}, "credentialSubject": []any{"x", 1, nil}})})),
+		"vc dates wrong type":   craftJWT(t, holder, nil, vp([]any{credential(map[string]any{"type": "VerifiableCredential", "validFrom": 1, "validUntil": []any{}, "credentialSubject": map[string]any{}})})),
+		"x5c empty":             craftJWT(t, holder, map[string]any{"x5c": []any{}}, vp([]any{})),
+		"x5c number":            craftJWT(t, holder, map[string]any{"x5c": []any{1}}, vp([]any{})),
+		"x5c garbage":           craftJWT(t, holder, map[string]any{"x5c": []any{"!!"}}, vp([]any{})),
+		"claims wrong type":     craftJWT(t, holder, nil, map[string]any{"iss": 1, "exp": "tomorrow", "aud": 5, "nonce": map[string]any{}, "jti": []any{}, "vp": map[string]any{}}),
+		"kid number":            craftJWT(t, holder, map[string]any{"kid": 7}, vp([]any{})),
+		"kid without fragment":  craftJWT(t, holder, map[string]any{"kid": holder.DID}, vp([]any{})),
+		"alg number":            craftJWT(t, holder, map[string]any{"alg": 1}, vp([]any{})),
+	}
+}
+
+// TestVerifyTokenAdversarialInput ensures hostile presentations fail with ordinary errors rather than panics.
+func TestVerifyTokenAdversarialInput(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	opts := []auth.VerifyOpt{
+		auth.WithX509Roots(x509.NewCertPool()),
+		auth.WithExpectedNonce("n"),
+		auth.WithExpectedAudience("aud"),
+	}
+
+	for name, token := range adversarialTokens(t, issuer, holder) {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.VerifyToken(context.Background(), token, opts...)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			var panicErr *auth.PanicError
+			if errors.As(err, &panicErr) {
+				t.Fatalf("verification panicked: %v\n%s", panicErr.Value, panicErr.Stack)
+			}
+
+			if _, err := a.VerifyCredential(context.Background(), token); errors.As(err, &panicErr) {
+				t.Fatalf("credential verification panicked: %v\n%s", panicErr.Value, panicErr.Stack)
+			}
+		})
+	}
+}
+
+// TestVerifyTokenStrictParsing ensures structurally ambiguous tokens are rejected while decoding.
+func TestVerifyTokenStrictParsing(t *testing.T) {
+	registry := newTestRegistry(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	header := `{"alg":"ES256K","typ":"JWT","kid":"` + holder.DID + `#key-1"}`
+	payload := `{"iss":"` + holder.DID + `","vp":{"holder":"` + holder.DID + `","verifiableCredential":[]}}`
+	signed := func(header, payload string) string {
+		return signJWT(t, encode(header)+"."+encode(payload), holder.Key)
+	}
+
+	tests := map[string]struct {
+		token string
+		want  string
+	}{
+		"alg none":              {encode(`{"alg":"none"}`) + "." + encode(payload) + ".", "alg none"},
+		"alg NONE":              {encode(`{"alg":"NONE"}`) + "." + encode(payload) + ".", "alg none"},
+		"alg missing":           {signed(`{"typ":"JWT"}`, payload), "alg is missing"},
+		"duplicate header key":  {signed(`{"alg":"ES256K","kid":"x","kid":"`+holder.DID+`#key-1"}`, payload), `duplicate key "kid"`},
+		"duplicate payload key": {signed(header, `{"iss":"a","iss":"`+holder.DID+`"}`), `duplicate key "iss"`},
+		"duplicate nested key":  {signed(header, `{"vp":{"holder":"a","holder":"b"}}`), `duplicate key "holder"`},
+		"unknown crit":          {signed(`{"alg":"ES256K","crit":["b64"],"b64":false}`, payload), "unsupported critical header"},
+		"empty crit":            {signed(`{"alg":"ES256K","crit":[]}`, payload), "crit must be a non-empty array"},
+		"trailing data":         {signed(header+`{}`, payload), "unexpected data"},
+		"oversize header":       {encode(`{"alg":"ES256K","x":"`+strings.Repeat("a", 8<<10)+`"}`) + "." + encode(payload) + ".", "larger than"},
+		"oversize payload":      {encode(header) + "." + strings.Repeat("A", 1<<20+1) + ".", "larger than"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.VerifyToken(context.Background(), test.token)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("error = %v, want %q", err, test.want)
+			}
+		})
+	}
+
+	if _, err := a.VerifyToken(context.Background(), signed(header, payload)); err != nil && strings.Contains(err.Error(), "invalid header") {
+		t.Errorf("well-formed token rejected while parsing: %v", err)
+	}
+}
+
+// panickingRegistry is an IssuerRegistry with a bug.
+type panickingRegistry struct{}
+
+func (panickingRegistry) IsTrusted(ctx context.Context, issuer trust.Issuer) (bool, error) {
+	var trusted map[string]bool
+	trusted[issuer.DID] = true
+	return true, nil
+}
+
+// TestVerifyTokenRecoversPanic ensures a panic during verification is returned as a PanicError.
+func TestVerifyTokenRecoversPanic(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	claims, err := a.VerifyToken(context.Background(), token, auth.WithIssuerRegistry(panickingRegistry{}))
+	var panicErr *auth.PanicError
+	if !errors.As(err, &panicErr) || claims != nil {
+		t.Fatalf("expected PanicError and no claims, got %v, %v", claims, err)
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Fatal("PanicError has no stack")
+	}
+}
+
+// FuzzVerifyToken checks that no input makes VerifyToken panic.
+func FuzzVerifyToken(f *testing.F) {
+	registry := newTestRegistry(f)
+	issuer := registry.newIdentity(f)
+	holder := registry.newIdentity(f)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	for _, token := range adversarialTokens(f, issuer, holder) {
+		f.Add(token)
+	}
+
+	f.Fuzz(func(t *testing.T, token string) {
+		var panicErr *auth.PanicError
+		if _, err := a.VerifyToken(context.Background(), token); errors.As(err, &panicErr) {
+			t.Fatalf("verification panicked: %v\n%s", panicErr.Value, panicErr.Stack)
+		}
+	})
+}
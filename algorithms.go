@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// defaultAlgorithms are accepted when no WithAllowedAlgorithms option is given.
+var defaultAlgorithms = []string{did.AlgES256K}
+
+// WithAllowedAlgorithms sets the JWS algorithms accepted for presentations and credentials
+// (default: ES256K only). Supported values are did.AlgES256K, did.AlgES256, did.AlgES384 and did.AlgEdDSA.
+// Whatever is allowed, a token's "alg" must also be the algorithm of the verification method it names.
+func WithAllowedAlgorithms(algs ...string) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.algorithms = algs
+	}
+}
+
+// verifySignature checks a JWS signature made with alg by key over signingInput.
+// ECDSA signatures use the fixed-size r||s encoding of RFC 7518.
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	if alg == did.AlgEdDSA {
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(edKey, []byte(signingInput), signature) {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	}
+
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("algorithm %s needs an ECDSA key", alg)
+	}
+
+	digest, err := signingPayload(alg, signingInput)
+	if err != nil {
+		return err
+	}
+
+	size := (ecKey.Curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		return errors.New("invalid signature length")
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(ecKey, digest, r, s) {
+		return errors.New("signature verification failed")
+	}
+
+	return nil
+}
+
+// signingPayload returns the bytes signed with alg for signingInput: its SHA-256 digest for ES256K and ES256,
+// its SHA-384 digest for ES384 and the signing input itself for EdDSA.
+func signingPayload(alg, signingInput string) ([]byte, error) {
+	switch alg {
+	case did.AlgES256K, did.AlgES256:
+		sum := sha256.Sum256([]byte(signingInput))
+		return sum[:], nil
+	case did.AlgES384:
+		sum := sha512.Sum384([]byte(signingInput))
+		return sum[:], nil
+	case did.AlgEdDSA:
+		return []byte(signingInput), nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", alg)
+	}
+}
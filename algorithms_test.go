@@ -0,0 +1,219 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+// publishMethod adds a verification method with a JWK to the document of id and returns its kid.
+func (r *testRegistry) publishMethod(t testing.TB, id, fragment, methodType string, key any) string {
+	t.Helper()
+
+	jwk, _ := did.JWKFromKey(key)
+	kid := id + "#" + fragment
+	r.update(t, id, func(doc *did.Document) {
+		doc.VerificationMethod = append(doc.VerificationMethod, did.VerificationMethod{
+			ID: kid, Type: methodType, Controller: id, PublicKeyJwk: &jwk,
+		})
+		doc.AssertionMethod = append(doc.AssertionMethod, kid)
+	})
+	return kid
+}
+
+// resign replaces the alg and kid of a JWT and signs it again with sign.
+func resign(t *testing.T, token, alg, kid string, sign func(signingInput []byte) []byte) string {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	var header map[string]any
+	headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		t.Fatalf("invalid header: %v", err)
+	}
+	header["alg"], header["kid"] = alg, kid
+	headerJSON, _ = json.Marshal(header)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + parts[1]
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signingInput)))
+}
+
+func TestVerifyCredentialAlgorithms(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p256Kid := registry.publishMethod(t, issuer.DID, "p256", "JsonWebKey2020", &p256Key.PublicKey)
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edKid := registry.publishMethod(t, issuer.DID, "ed25519", "Ed25519VerificationKey2020", edPublic)
+
+	signP256 := func(signingInput []byte) []byte {
+		hash := sha256.Sum256(signingInput)
+		r, s, _ := ecdsa.Sign(rand.Reader, p256Key, hash[:])
+		return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	signEd25519 := func(signingInput []byte) []byte {
+		return ed25519.Sign(edPrivate, signingInput)
+	}
+
+	base := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	es256 := resign(t, base, did.AlgES256, p256Kid, signP256)
+	eddsa := resign(t, base, did.AlgEdDSA, edKid, signEd25519)
+	confused := resign(t, base, did.AlgES256K, p256Kid, signP256)
+	mislabeled := resign(t, base, did.AlgES256, edKid, signEd25519)
+
+	all := auth.WithAllowedAlgorithms(did.AlgES256K, did.AlgES256, did.AlgEdDSA)
+
+	tests := []struct {
+		name    string
+		vcJwt   string
+		opts    []auth.VerifyOpt
+		wantErr bool
+	}{
+		{"ES256K by default", base, nil, false},
+		{"ES256 not allowed by default", es256, nil, true},
+		{"ES256 allowed", es256, []auth.VerifyOpt{all}, false},
+		{"EdDSA allowed", eddsa, []auth.VerifyOpt{all}, false},
+		{"ES256K header against a P-256 key", confused, []auth.VerifyOpt{all}, true},
+		{"ES256 header against an Ed25519 key", mislabeled, []auth.VerifyOpt{all}, true},
+		{"ES256K not allowed", base, []auth.VerifyOpt{auth.WithAllowedAlgorithms(did.AlgES256)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.VerifyCredential(context.Background(), tt.vcJwt, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyCredential error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// es256Signer is a provider declaring ES256, like the Vault provider on the transit backend.
+type es256Signer struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s es256Signer) Algorithm() string {
+	return did.AlgES256
+}
+
+func (s es256Signer) Sign(ctx context.Context, payload []byte, options *provider.ProviderOption) ([]byte, error) {
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, payload)
+	if err != nil {
+		return nil, err
+	}
+	return append(r.FillBytes(make([]byte, 32)), sig.FillBytes(make([]byte, 32))...), nil
+}
+
+func TestCreateTokenProviderAlgorithm(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	registry.publishMethod(t, holder.DID, "p256", "JsonWebKey2020", &p256Key.PublicKey)
+	registry.update(t, holder.DID, func(doc *did.Document) {
+		doc.Authentication = append(doc.Authentication, holder.DID+"#p256")
+	})
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	a := registry.newAuth(es256Signer{p256Key})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, auth.WithKeyID("p256"))
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	headerJSON, _ := base64.RawURLEncoding.DecodeString(strings.Split(strings.Trim(token, `"`), ".")[0])
+	if !strings.Contains(string(headerJSON), `"alg":"ES256"`) {
+		t.Errorf("unexpected header %s", headerJSON)
+	}
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithAllowedAlgorithms(did.AlgES256K, did.AlgES256)); err != nil {
+		t.Errorf("VerifyToken failed: %v", err)
+	}
+
+	// Credentials are ES256K: an ES256 provider cannot issue them.
+	results, err := a.IssueCredentials(context.Background(), []auth.CredentialDocument{{
+		Issuer:  holder.DID,
+		Subject: map[string]any{"id": issuer.DID},
+	}})
+	if err != nil || results[0].Err == nil {
+		t.Errorf("expected issuance with an ES256 provider to fail, got %v", err)
+	}
+}
+
+// declaredSigner is a provider declaring alg and signing the payload it is given with sign.
+type declaredSigner struct {
+	alg  string
+	sign func(payload []byte) []byte
+}
+
+func (s declaredSigner) Algorithm() string {
+	return s.alg
+}
+
+func (s declaredSigner) Sign(ctx context.Context, payload []byte, options *provider.ProviderOption) ([]byte, error) {
+	return s.sign(payload), nil
+}
+
+func TestCreateTokenProviderDigest(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	registry.publishMethod(t, holder.DID, "p384", "JsonWebKey2020", &p384Key.PublicKey)
+	registry.publishMethod(t, holder.DID, "ed25519", "Ed25519VerificationKey2020", edPublic)
+	registry.update(t, holder.DID, func(doc *did.Document) {
+		doc.Authentication = append(doc.Authentication, holder.DID+"#p384", holder.DID+"#ed25519")
+	})
+
+	signers := map[string]declaredSigner{
+		"p384": {alg: did.AlgES384, sign: func(payload []byte) []byte {
+			if len(payload) != sha512.Size384 {
+				t.Errorf("ES384 provider given %d bytes, want a SHA-384 digest", len(payload))
+			}
+			r, s, _ := ecdsa.Sign(rand.Reader, p384Key, payload)
+			return append(r.FillBytes(make([]byte, 48)), s.FillBytes(make([]byte, 48))...)
+		}},
+		"ed25519": {alg: did.AlgEdDSA, sign: func(payload []byte) []byte {
+			return ed25519.Sign(edPrivate, payload)
+		}},
+	}
+	for fragment, signer := range signers {
+		t.Run(signer.alg, func(t *testing.T) {
+			a := registry.newAuth(signer)
+			token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, auth.WithKeyID(fragment))
+			if err != nil {
+				t.Fatalf("CreateToken failed: %v", err)
+			}
+			if _, err := a.VerifyToken(context.Background(), token, auth.WithAllowedAlgorithms(did.AlgES256K, signer.alg)); err != nil {
+				t.Errorf("VerifyToken failed: %v", err)
+			}
+		})
+	}
+
+	// A declared algorithm the service cannot compute the signing payload of is refused before signing.
+	unsupported := declaredSigner{alg: "RS256", sign: func(payload []byte) []byte {
+		t.Error("an RS256 provider should not be asked to sign")
+		return nil
+	}}
+	if _, err := registry.newAuth(unsupported).CreateToken(context.Background(), []string{vcJwt}, holder.DID); err == nil {
+		t.Error("expected an RS256 provider to be refused")
+	}
+}
@@ -0,0 +1,115 @@
+// Package anchor writes hashes of issued credentials and status list credentials to a ledger, such as an
+// Ethereum-compatible chain, so a verifier can later prove a credential existed in that exact form when it
+// was anchored. The ledger is reached through a Submitter on the issuer side and a Finder on the verifier
+// side; Ethereum implements both over JSON-RPC.
+package anchor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// ErrNotFound is returned by a Finder when no anchor holds the hash.
+var ErrNotFound = errors.New("hash is not anchored")
+
+// Hash is the SHA-256 digest of an anchored credential.
+type Hash [32]byte
+
+// HashCredential returns the hash anchored for a credential: the SHA-256 of its serialization, e.g. the
+// compact JWT, exactly as issued.
+func HashCredential(credential string) Hash {
+	return sha256.Sum256([]byte(credential))
+}
+
+// String returns the hash as 0x-prefixed hex.
+func (h Hash) String() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+// MarshalText encodes the hash as 0x-prefixed hex.
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText decodes 0x-prefixed or bare hex.
+func (h *Hash) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(strings.TrimPrefix(string(text), "0x"))
+	if err != nil || len(b) != len(h) {
+		return fmt.Errorf("invalid anchor hash %q", text)
+	}
+	copy(h[:], b)
+	return nil
+}
+
+// Anchor records where and when a hash was written.
+type Anchor struct {
+	Hash    Hash      `json:"hash"`
+	Network string    `json:"network"`          // CAIP-2 id of the ledger, e.g. "eip155:1"
+	TxHash  string    `json:"txHash,omitempty"` // Transaction that wrote the hash
+	Block   uint64    `json:"block"`            // Block the transaction was included in
+	Time    time.Time `json:"time"`             // Time of the block
+}
+
+// Submitter writes hashes to a ledger. Submit returns once the hash is included in a block.
+type Submitter interface {
+	Submit(ctx context.Context, hash Hash) (Anchor, error)
+}
+
+// Finder looks up the anchor of a hash, returning ErrNotFound when there is none. When a hash was anchored
+// more than once, the earliest anchor is returned.
+type Finder interface {
+	Find(ctx context.Context, hash Hash) (Anchor, error)
+}
+
+// Memory is an in-memory ledger implementing Submitter and Finder, for tests and development.
+type Memory struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	anchors map[Hash]Anchor
+	blocks  uint64
+}
+
+// NewMemory creates an empty Memory ledger on the network "memory". A nil clock uses the system clock.
+func NewMemory(c clock.Clock) *Memory {
+	return &Memory{clock: clock.OrSystem(c), anchors: make(map[Hash]Anchor)}
+}
+
+// Submit records hash in a new block, keeping its first anchor when it is submitted again.
+func (m *Memory) Submit(ctx context.Context, hash Hash) (Anchor, error) {
+	if err := ctx.Err(); err != nil {
+		return Anchor{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if anchor, ok := m.anchors[hash]; ok {
+		return anchor, nil
+	}
+	m.blocks++
+	anchor := Anchor{Hash: hash, Network: "memory", Block: m.blocks, Time: m.clock.Now().UTC()}
+	m.anchors[hash] = anchor
+	return anchor, nil
+}
+
+// Find returns the anchor of hash, or ErrNotFound.
+func (m *Memory) Find(ctx context.Context, hash Hash) (Anchor, error) {
+	if err := ctx.Err(); err != nil {
+		return Anchor{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	anchor, ok := m.anchors[hash]
+	if !ok {
+		return Anchor{}, ErrNotFound
+	}
+	return anchor, nil
+}
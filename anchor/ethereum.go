@@ -0,0 +1,193 @@
+package anchor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+const (
+	// defaultPollInterval is how often Submit checks whether its transaction was included.
+	defaultPollInterval = 2 * time.Second
+	// maxRPCResponseSize bounds the JSON-RPC responses Ethereum reads.
+	maxRPCResponseSize = 10 << 20
+)
+
+var (
+	// anchorSelector is the selector of the contract function anchor(bytes32).
+	anchorSelector = crypto.Keccak256([]byte("anchor(bytes32)"))[:4]
+	// anchoredTopic is the topic of the contract event Anchored(bytes32 indexed hash).
+	anchoredTopic = hexutil.Encode(crypto.Keccak256([]byte("Anchored(bytes32)")))
+)
+
+// Ethereum anchors hashes through an anchor contract on an Ethereum-compatible chain, reached over
+// JSON-RPC. The contract needs a function anchor(bytes32 hash) emitting the event
+// Anchored(bytes32 indexed hash), e.g.
+//
+//	contract Anchors {
+//	    event Anchored(bytes32 indexed hash);
+//	    function anchor(bytes32 hash) external { emit Anchored(hash); }
+//	}
+//
+// Transactions are sent with eth_sendTransaction from From, so the node, or a signer in front of it such
+// as web3signer, holds that account's key. Verifiers only read logs and need no account.
+type Ethereum struct {
+	URL          string        // JSON-RPC endpoint of the node
+	Contract     string        // Address of the anchor contract
+	From         string        // Account sending the anchor transactions; not needed to Find
+	FromBlock    uint64        // Block the contract was deployed in, where Find starts searching
+	PollInterval time.Duration // How often Submit polls for the transaction receipt (default: 2s)
+	Clock        clock.Clock   // Time source for polling; the system clock when nil
+	HTTPClient   *http.Client  // Client for JSON-RPC calls; http.DefaultClient when nil
+}
+
+// NewEthereum creates an Ethereum anchor for the contract at contract, reached through the node at url
+// and sending transactions from from.
+func NewEthereum(url, contract, from string) *Ethereum {
+	return &Ethereum{URL: url, Contract: contract, From: from}
+}
+
+// Submit sends an anchor transaction for hash and waits until it is included in a block.
+func (e *Ethereum) Submit(ctx context.Context, hash Hash) (Anchor, error) {
+	tx := map[string]string{
+		"from": e.From,
+		"to":   e.Contract,
+		"data": hexutil.Encode(append(append([]byte{}, anchorSelector...), hash[:]...)),
+	}
+	var txHash string
+	if err := e.call(ctx, "eth_sendTransaction", []any{tx}, &txHash); err != nil {
+		return Anchor{}, fmt.Errorf("failed to send anchor transaction: %w", err)
+	}
+
+	interval := e.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	for {
+		var receipt *struct {
+			Status      hexutil.Uint64 `json:"status"`
+			BlockNumber hexutil.Uint64 `json:"blockNumber"`
+		}
+		if err := e.call(ctx, "eth_getTransactionReceipt", []any{txHash}, &receipt); err != nil {
+			return Anchor{}, fmt.Errorf("failed to get receipt of %s: %w", txHash, err)
+		}
+		if receipt != nil {
+			if receipt.Status != 1 {
+				return Anchor{}, fmt.Errorf("anchor transaction %s reverted", txHash)
+			}
+			return e.anchor(ctx, hash, txHash, uint64(receipt.BlockNumber))
+		}
+
+		select {
+		case <-ctx.Done():
+			return Anchor{}, fmt.Errorf("anchor transaction %s not included: %w", txHash, ctx.Err())
+		case <-clock.OrSystem(e.Clock).After(interval):
+		}
+	}
+}
+
+// Find returns the earliest Anchored event of the contract for hash, or ErrNotFound.
+func (e *Ethereum) Find(ctx context.Context, hash Hash) (Anchor, error) {
+	filter := map[string]any{
+		"address":   e.Contract,
+		"topics":    []string{anchoredTopic, hash.String()},
+		"fromBlock": hexutil.EncodeUint64(e.FromBlock),
+		"toBlock":   "latest",
+	}
+	var logs []struct {
+		BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+		TransactionHash string         `json:"transactionHash"`
+		Removed         bool           `json:"removed"`
+	}
+	if err := e.call(ctx, "eth_getLogs", []any{filter}, &logs); err != nil {
+		return Anchor{}, fmt.Errorf("failed to get anchor logs: %w", err)
+	}
+
+	for _, log := range logs {
+		if !log.Removed {
+			return e.anchor(ctx, hash, log.TransactionHash, uint64(log.BlockNumber))
+		}
+	}
+	return Anchor{}, ErrNotFound
+}
+
+// anchor completes the anchor of hash in block with the chain id and the block time.
+func (e *Ethereum) anchor(ctx context.Context, hash Hash, txHash string, block uint64) (Anchor, error) {
+	var chainID hexutil.Big
+	if err := e.call(ctx, "eth_chainId", nil, &chainID); err != nil {
+		return Anchor{}, fmt.Errorf("failed to get chain id: %w", err)
+	}
+	var header *struct {
+		Timestamp hexutil.Uint64 `json:"timestamp"`
+	}
+	if err := e.call(ctx, "eth_getBlockByNumber", []any{hexutil.EncodeUint64(block), false}, &header); err != nil {
+		return Anchor{}, fmt.Errorf("failed to get block %d: %w", block, err)
+	}
+	if header == nil {
+		return Anchor{}, fmt.Errorf("block %d not found", block)
+	}
+
+	return Anchor{
+		Hash:    hash,
+		Network: "eip155:" + chainID.ToInt().String(),
+		TxHash:  txHash,
+		Block:   block,
+		Time:    time.Unix(int64(header.Timestamp), 0).UTC(),
+	}, nil
+}
+
+// call invokes a JSON-RPC method and decodes its result into result.
+func (e *Ethereum) call(ctx context.Context, method string, params []any, result any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := e.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRPCResponseSize)).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s: %s (code %d)", method, rpcResp.Error.Message, rpcResp.Error.Code)
+	}
+	if len(rpcResp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
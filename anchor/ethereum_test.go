@@ -0,0 +1,106 @@
+package anchor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+const testContract = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+
+// newTestNode fakes a node with the anchor contract deployed: transactions are mined on the second receipt poll.
+func newTestNode(t *testing.T) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	anchored := map[string]string{} // hash -> tx hash
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+
+		var result any
+		switch req.Method {
+		case "eth_sendTransaction":
+			var tx struct{ From, To, Data string }
+			_ = json.Unmarshal(req.Params[0], &tx)
+			if tx.To != testContract || !strings.HasPrefix(tx.Data, "0xeecdf927") {
+				t.Errorf("unexpected transaction %+v", tx)
+			}
+			anchored["0x"+tx.Data[10:]] = "0xtx1"
+			result = "0xtx1"
+		case "eth_getTransactionReceipt":
+			if polls++; polls > 1 {
+				result = map[string]string{"status": "0x1", "blockNumber": "0x10"}
+			}
+		case "eth_getLogs":
+			var filter struct {
+				Address string   `json:"address"`
+				Topics  []string `json:"topics"`
+			}
+			_ = json.Unmarshal(req.Params[0], &filter)
+			logs := []map[string]any{}
+			if txHash, ok := anchored[filter.Topics[1]]; ok && filter.Topics[0] == anchoredTopic {
+				logs = append(logs, map[string]any{"blockNumber": "0x10", "transactionHash": txHash})
+			}
+			result = logs
+		case "eth_chainId":
+			result = "0xaa36a7"
+		case "eth_getBlockByNumber":
+			result = map[string]string{"timestamp": "0x6774ab80"}
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": -32601, "message": "method not found"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEthereum(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestNode(t)
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	eth := NewEthereum(srv.URL, testContract, "0x0000000000000000000000000000000000000001")
+	eth.Clock = fake
+
+	hash := HashCredential("eyJhbGciOiJFUzI1NksifQ.e30.sig")
+	if _, err := eth.Find(ctx, hash); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Find before Submit: %v, want ErrNotFound", err)
+	}
+
+	go func() {
+		for fake.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		fake.Advance(defaultPollInterval)
+	}()
+	submitted, err := eth.Submit(ctx, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Anchor{Hash: hash, Network: "eip155:11155111", TxHash: "0xtx1", Block: 16, Time: time.Date(2025, 1, 1, 2, 42, 8, 0, time.UTC)}
+	if submitted != want {
+		t.Errorf("Submit = %+v, want %+v", submitted, want)
+	}
+
+	found, err := eth.Find(ctx, hash)
+	if err != nil || found != want {
+		t.Errorf("Find = %+v, %v; want %+v", found, err, want)
+	}
+}
@@ -0,0 +1,115 @@
+package anchor
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Domain separation of Merkle tree hashes, as in RFC 6962, so a leaf cannot pass for an inner node.
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// LeafHash returns the Merkle leaf hash of data.
+func LeafHash(data []byte) Hash {
+	return sha256.Sum256(append([]byte{leafPrefix}, data...))
+}
+
+// nodeHash returns the hash of the inner node with the given children.
+func nodeHash(left, right Hash) Hash {
+	buf := make([]byte, 0, 1+2*len(left))
+	buf = append(append(append(buf, nodePrefix), left[:]...), right[:]...)
+	return sha256.Sum256(buf)
+}
+
+// ProofStep is a sibling on the path from a leaf to the root.
+type ProofStep struct {
+	Hash Hash `json:"hash"`
+	Left bool `json:"left,omitempty"` // The sibling is the left child
+}
+
+// Proof shows that a leaf is included in the Merkle tree with root Root: hashing the leaf with each
+// sibling of Path in turn yields Root. Only Root needs to be anchored.
+type Proof struct {
+	Root Hash        `json:"root"`
+	Path []ProofStep `json:"path"`
+}
+
+// Verify reports whether p proves leaf is included under p.Root.
+func (p Proof) Verify(leaf Hash) bool {
+	h := leaf
+	for _, step := range p.Path {
+		if step.Left {
+			h = nodeHash(step.Hash, h)
+		} else {
+			h = nodeHash(h, step.Hash)
+		}
+	}
+	return h == p.Root
+}
+
+// Tree is a Merkle tree over leaf hashes. A node without a sibling is promoted to the next level unchanged.
+type Tree struct {
+	levels [][]Hash // levels[0] holds the leaves, the last level the root
+}
+
+// NewTree builds the tree over leaves, which must not be empty.
+func NewTree(leaves []Hash) (*Tree, error) {
+	if len(leaves) == 0 {
+		return nil, errors.New("merkle tree needs at least one leaf")
+	}
+	levels := [][]Hash{append([]Hash(nil), leaves...)}
+	for level := levels[0]; len(level) > 1; {
+		next := make([]Hash, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, nodeHash(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return &Tree{levels: levels}, nil
+}
+
+// Root returns the root hash of the tree.
+func (t *Tree) Root() Hash {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// Proof returns the inclusion proof of leaf i.
+func (t *Tree) Proof(i int) Proof {
+	proof := Proof{Root: t.Root()}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		sibling := i ^ 1
+		if sibling < len(level) {
+			proof.Path = append(proof.Path, ProofStep{Hash: level[sibling], Left: sibling < i})
+		}
+		i /= 2
+	}
+	return proof
+}
+
+// SubmitBatch builds the Merkle tree over leaves and anchors its root with s, returning the root's anchor
+// and the inclusion proof of every leaf, in order. One transaction covers the whole batch.
+func SubmitBatch(ctx context.Context, s Submitter, leaves []Hash) (Anchor, []Proof, error) {
+	tree, err := NewTree(leaves)
+	if err != nil {
+		return Anchor{}, nil, err
+	}
+	anchor, err := s.Submit(ctx, tree.Root())
+	if err != nil {
+		return Anchor{}, nil, fmt.Errorf("failed to anchor merkle root: %w", err)
+	}
+
+	proofs := make([]Proof, len(leaves))
+	for i := range leaves {
+		proofs[i] = tree.Proof(i)
+	}
+	return anchor, proofs, nil
+}
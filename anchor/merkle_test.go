@@ -0,0 +1,37 @@
+package anchor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestMerkleTree(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 5, 8, 13} {
+		leaves := make([]Hash, n)
+		for i := range leaves {
+			leaves[i] = LeafHash(fmt.Appendf(nil, "credential %d", i))
+		}
+
+		ledger := NewMemory(nil)
+		anchored, proofs, err := SubmitBatch(context.Background(), ledger, leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, proof := range proofs {
+			if proof.Root != anchored.Hash || !proof.Verify(leaves[i]) {
+				t.Errorf("%d leaves: proof of leaf %d does not verify", n, i)
+			}
+			if n > 1 && proof.Verify(leaves[(i+1)%n]) {
+				t.Errorf("%d leaves: proof of leaf %d verifies another leaf", n, i)
+			}
+		}
+		if _, err := ledger.Find(context.Background(), anchored.Hash); err != nil {
+			t.Errorf("%d leaves: root not anchored: %v", n, err)
+		}
+	}
+
+	if _, err := NewTree(nil); err == nil {
+		t.Error("expected an error for an empty tree")
+	}
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github/hovanhoa/go-vc-auth/anchor"
+)
+
+// anchorProofClaim is the JWT claim holding the Merkle inclusion proof of a credential issued with
+// WithAnchorBatch.
+const anchorProofClaim = "anchorProof"
+
+// ErrNotAnchored is returned when a credential checked by WithAnchorCheck has no anchor, or its inclusion
+// proof does not lead from the credential to the anchored root.
+var ErrNotAnchored = errors.New("credential is not anchored")
+
+// WithAnchor anchors every issued credential through submitter, e.g. an anchor.Ethereum, and reports the
+// anchor in its result. A credential whose anchor cannot be written is not returned: its result holds
+// the error instead, so no unanchored credential is handed out.
+func WithAnchor(submitter anchor.Submitter) IssueOpt {
+	return func(o *issueOptions) {
+		o.anchor = submitter
+	}
+}
+
+// WithAnchorBatch anchors the credentials of an IssueCredentials call in one transaction, for high-volume
+// issuers: the credentials are hashed into a Merkle tree, its root is anchored through submitter, and each
+// credential is signed with its inclusion proof in the "anchorProof" claim. Every result reports the root's
+// anchor. When the root cannot be anchored, no credential is issued. It replaces WithAnchor.
+func WithAnchorBatch(submitter anchor.Submitter) IssueOpt {
+	return func(o *issueOptions) {
+		o.anchorBatch = submitter
+	}
+}
+
+// WithAnchorCheck fails verification with ErrNotAnchored unless every JWT credential, exactly as
+// presented, was anchored in finder; for a credential issued with WithAnchorBatch, its inclusion proof
+// must match the credential and lead to an anchored root. The anchors are returned in VcClaims.Anchor
+// and each lookup is traced as StepAnchor. Credentials handled by a CredentialParser are left to the parser.
+func WithAnchorCheck(finder anchor.Finder) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.anchors = finder
+	}
+}
+
+// AnchorCredential anchors a credential that was not issued through IssueCredentials, e.g. a new version
+// of a status list credential, so verifiers can tell the published list is the one the issuer signed.
+func AnchorCredential(ctx context.Context, submitter anchor.Submitter, credential string) (anchor.Anchor, error) {
+	a, err := submitter.Submit(ctx, anchor.HashCredential(credential))
+	if err != nil {
+		return anchor.Anchor{}, fmt.Errorf("failed to anchor credential: %w", err)
+	}
+	return a, nil
+}
+
+// issueAnchoredBatch issues documents for WithAnchorBatch: every credential is prepared, the Merkle root over
+// them anchored, and each credential signed with its inclusion proof.
+func (a *Service) issueAnchoredBatch(ctx context.Context, documents []CredentialDocument, limiter *rateLimiter, options *issueOptions, providerOpts []any) []IssuanceResult {
+	results := make([]IssuanceResult, len(documents))
+	signingInputs := make([]string, len(documents))
+	forEachConcurrently(len(documents), options.concurrency, func(index int) {
+		results[index], signingInputs[index] = a.prepareDocument(ctx, index, documents[index], options)
+	})
+
+	var prepared []int
+	var leaves []anchor.Hash
+	for index := range results {
+		if results[index].Err != nil {
+			continue
+		}
+		leaf, err := credentialLeaf(signingInputs[index])
+		if err != nil {
+			results[index].Err, results[index].Status = err, nil
+			continue
+		}
+		prepared = append(prepared, index)
+		leaves = append(leaves, leaf)
+	}
+	if len(prepared) == 0 {
+		return results
+	}
+
+	anchored, proofs, err := anchor.SubmitBatch(ctx, options.anchorBatch, leaves)
+	if err != nil {
+		for _, index := range prepared {
+			results[index].Err, results[index].Status = err, nil
+		}
+		return results
+	}
+
+	forEachConcurrently(len(prepared), options.concurrency, func(i int) {
+		result := &results[prepared[i]]
+		signingInput, err := withAnchorProof(signingInputs[prepared[i]], proofs[i])
+		if err != nil {
+			result.Err, result.Status = err, nil
+			return
+		}
+		a.finishDocument(ctx, result, signingInput, limiter, options, providerOpts)
+		if result.Err == nil {
+			result.Anchor = &anchored
+		}
+	})
+	return results
+}
+
+// credentialLeaf returns the Merkle leaf of a credential: the hash of its JWT payload without the
+// anchorProof claim, encoded as JSON with sorted keys.
+func credentialLeaf(signingInput string) (anchor.Hash, error) {
+	token, err := parseJWT(signingInput + ".")
+	if err != nil {
+		return anchor.Hash{}, err
+	}
+	return payloadLeaf(token.payload)
+}
+
+// payloadLeaf returns the Merkle leaf of a decoded credential payload, see credentialLeaf.
+func payloadLeaf(payload map[string]any) (anchor.Hash, error) {
+	unproven := make(map[string]any, len(payload))
+	for name, value := range payload {
+		if name != anchorProofClaim {
+			unproven[name] = value
+		}
+	}
+	data, err := json.Marshal(unproven)
+	if err != nil {
+		return anchor.Hash{}, err
+	}
+	return anchor.LeafHash(data), nil
+}
+
+// withAnchorProof returns signingInput with proof added to its payload.
+func withAnchorProof(signingInput string, proof anchor.Proof) (string, error) {
+	token, err := parseJWT(signingInput + ".")
+	if err != nil {
+		return "", err
+	}
+	token.payload[anchorProofClaim] = proof
+	return encodeSigningInput(token.header, token.payload)
+}
+
+// checkAnchor looks up the anchor of the credential vcToken, or of the Merkle root its inclusion proof
+// leads to.
+func checkAnchor(ctx context.Context, finder anchor.Finder, vcToken *jwtToken) (*anchor.Anchor, error) {
+	hash := anchor.HashCredential(vcToken.raw)
+	if claim, ok := vcToken.payload[anchorProofClaim]; ok {
+		root, err := verifyAnchorProof(vcToken.payload, claim)
+		if err != nil {
+			return nil, err
+		}
+		hash = root
+	}
+
+	a, err := finder.Find(ctx, hash)
+	if errors.Is(err, anchor.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %v", ErrNotAnchored, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up credential anchor: %w", err)
+	}
+	a.Time = a.Time.UTC()
+	return &a, nil
+}
+
+// verifyAnchorProof checks the inclusion proof claim of a credential payload and returns its root.
+func verifyAnchorProof(payload map[string]any, claim any) (anchor.Hash, error) {
+	var proof anchor.Proof
+	data, err := json.Marshal(claim)
+	if err == nil {
+		err = json.Unmarshal(data, &proof)
+	}
+	if err != nil {
+		return anchor.Hash{}, fmt.Errorf("%w: invalid %s claim: %v", ErrNotAnchored, anchorProofClaim, err)
+	}
+
+	leaf, err := payloadLeaf(payload)
+	if err != nil {
+		return anchor.Hash{}, err
+	}
+	if !proof.Verify(leaf) {
+		return anchor.Hash{}, fmt.Errorf("%w: inclusion proof does not match the credential", ErrNotAnchored)
+	}
+	return proof.Root, nil
+}
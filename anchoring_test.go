@@ -0,0 +1,122 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/anchor"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+// failingSubmitter is a ledger that cannot be written to.
+type failingSubmitter struct{}
+
+func (failingSubmitter) Submit(context.Context, anchor.Hash) (anchor.Anchor, error) {
+	return anchor.Anchor{}, errors.New("node unreachable")
+}
+
+func TestAnchoring(t *testing.T) {
+	ctx := context.Background()
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(issuer))
+	ledger := anchor.NewMemory(nil)
+
+	documents := []auth.CredentialDocument{{
+		Issuer:  issuer.DID,
+		Schemas: []auth.CredentialSchema{{ID: authtest.SchemaURL, Type: "JsonSchema"}},
+		Subject: map[string]any{"id": holder.DID},
+	}}
+	results, err := a.IssueCredentials(ctx, documents, issuer.Address, auth.WithAnchor(ledger))
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("IssueCredentials: %v, %v", err, results[0].Err)
+	}
+	issued := results[0]
+	if issued.Anchor == nil || issued.Anchor.Hash != anchor.HashCredential(issued.Credential) {
+		t.Fatalf("unexpected anchor %+v", issued.Anchor)
+	}
+
+	claims, err := a.VerifyCredential(ctx, issued.Credential, auth.WithAnchorCheck(ledger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Anchor == nil || *claims.Anchor != *issued.Anchor {
+		t.Errorf("claims anchor = %+v, want %+v", claims.Anchor, issued.Anchor)
+	}
+
+	// A credential signed by the issuer but never anchored fails the check.
+	unanchored, err := a.IssueCredentials(ctx, documents, issuer.Address)
+	if err != nil || unanchored[0].Err != nil {
+		t.Fatal(err, unanchored[0].Err)
+	}
+	_, err = a.VerifyCredential(ctx, unanchored[0].Credential, auth.WithAnchorCheck(ledger))
+	if !errors.Is(err, auth.ErrNotAnchored) {
+		t.Errorf("VerifyCredential = %v, want ErrNotAnchored", err)
+	}
+	if code := auth.NewVerificationResult(nil, err).Error.Code; code != auth.ResultCodeNotAnchored {
+		t.Errorf("result code = %q", code)
+	}
+
+	// Without an anchor, the credential is not handed out.
+	failed, err := a.IssueCredentials(ctx, documents, issuer.Address, auth.WithAnchor(failingSubmitter{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed[0].Err == nil || failed[0].Credential != "" || failed[0].Anchor != nil {
+		t.Errorf("expected the issuance to fail: %+v", failed[0])
+	}
+}
+
+func TestAnchorBatch(t *testing.T) {
+	ctx := context.Background()
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(issuer))
+	ledger := anchor.NewMemory(nil)
+
+	documents := make([]auth.CredentialDocument, 5)
+	for i := range documents {
+		documents[i] = auth.CredentialDocument{
+			Issuer:  issuer.DID,
+			Schemas: []auth.CredentialSchema{{ID: authtest.SchemaURL, Type: "JsonSchema"}},
+			Subject: map[string]any{"id": registry.newIdentity(t).DID, "seat": i},
+		}
+	}
+	documents[3].Issuer = "not-a-did"
+	results, err := a.IssueCredentials(ctx, documents, issuer.Address, auth.WithAnchorBatch(ledger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, result := range results {
+		if i == 3 {
+			if result.Err == nil || result.Anchor != nil {
+				t.Errorf("document 3 should fail: %+v", result)
+			}
+			continue
+		}
+		if result.Err != nil {
+			t.Fatalf("document %d: %v", i, result.Err)
+		}
+		// One transaction anchors the whole batch.
+		if result.Anchor == nil || *result.Anchor != *results[0].Anchor || result.Anchor.Block != 1 {
+			t.Errorf("document %d: anchor %+v, want the batch root %+v", i, result.Anchor, results[0].Anchor)
+		}
+		claims, err := a.VerifyCredential(ctx, result.Credential, auth.WithAnchorCheck(ledger))
+		if err != nil {
+			t.Fatalf("document %d: %v", i, err)
+		}
+		if claims.Anchor == nil || claims.Anchor.Hash != result.Anchor.Hash {
+			t.Errorf("document %d: claims anchor %+v", i, claims.Anchor)
+		}
+	}
+
+	// The proof leads to a root the verifier's ledger never saw.
+	_, err = a.VerifyCredential(ctx, results[0].Credential, auth.WithAnchorCheck(anchor.NewMemory(nil)))
+	if !errors.Is(err, auth.ErrNotAnchored) {
+		t.Errorf("VerifyCredential = %v, want ErrNotAnchored", err)
+	}
+}
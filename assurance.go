@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInsufficientAssurance is returned, along with ErrPolicyViolation, when a credential does not reach
+// the assurance level a policy requires, so callers can trigger step-up authentication.
+var ErrInsufficientAssurance = errors.New("insufficient assurance level")
+
+// AssuranceLevel is the level of assurance of a credential, on the eIDAS scale. The zero value means
+// the credential states no level.
+type AssuranceLevel int
+
+// Assurance levels, from the eIDAS regulation (EU 910/2014, article 8).
+const (
+	AssuranceUnspecified AssuranceLevel = iota
+	AssuranceLow
+	AssuranceSubstantial
+	AssuranceHigh
+)
+
+// assuranceNames are the names of the levels, as accepted by ParseAssuranceLevel.
+var assuranceNames = map[AssuranceLevel]string{
+	AssuranceLow:         "low",
+	AssuranceSubstantial: "substantial",
+	AssuranceHigh:        "high",
+}
+
+// eidasLoAPrefix starts the eIDAS level of assurance URIs, e.g. "http://eidas.europa.eu/LoA/high".
+const eidasLoAPrefix = "http://eidas.europa.eu/LoA/"
+
+// ParseAssuranceLevel parses a level name ("low", "substantial", "high", in any case) or an eIDAS
+// level of assurance URI.
+func ParseAssuranceLevel(s string) (AssuranceLevel, error) {
+	name := strings.ToLower(strings.TrimPrefix(s, eidasLoAPrefix))
+	for level, levelName := range assuranceNames {
+		if name == levelName {
+			return level, nil
+		}
+	}
+	return AssuranceUnspecified, fmt.Errorf("unknown assurance level %q", s)
+}
+
+// String returns the name of the level, or "unspecified".
+func (l AssuranceLevel) String() string {
+	if name, ok := assuranceNames[l]; ok {
+		return name
+	}
+	return "unspecified"
+}
+
+// MarshalText encodes the level as its name.
+func (l AssuranceLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes a level name or eIDAS URI.
+func (l *AssuranceLevel) UnmarshalText(text []byte) error {
+	if string(text) == "unspecified" {
+		*l = AssuranceUnspecified
+		return nil
+	}
+	level, err := ParseAssuranceLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// ConfidenceMethod represents a confidenceMethod entry of a Verifiable Credential: how a verifier can
+// gain confidence that the presenter is the subject, e.g. by matching a biometric template.
+type ConfidenceMethod struct {
+	ID             string         `json:"id,omitempty"`
+	Type           string         `json:"type"`
+	AssuranceLevel AssuranceLevel `json:"assuranceLevel,omitzero"` // From its assuranceLevel or levelOfAssurance property
+}
+
+// MinAssurance returns the lowest assurance level of the credentials, e.g. of a verified presentation,
+// to decide whether step-up authentication is needed. It is AssuranceUnspecified when any credential
+// states no level, or when there are none.
+func MinAssurance(credentials []VcClaims) AssuranceLevel {
+	if len(credentials) == 0 {
+		return AssuranceUnspecified
+	}
+	lowest := AssuranceHigh
+	for _, c := range credentials {
+		lowest = min(lowest, c.AssuranceLevel)
+	}
+	return lowest
+}
+
+// assuranceField parses the assurance level stated by an object under assuranceLevel or
+// levelOfAssurance; unknown values count as unspecified.
+func assuranceField(raw map[string]any) AssuranceLevel {
+	for _, key := range []string{"assuranceLevel", "levelOfAssurance"} {
+		if level, err := ParseAssuranceLevel(stringField(raw, key)); err == nil {
+			return level
+		}
+	}
+	return AssuranceUnspecified
+}
+
+// credentialAssurance returns the assurance level a credential states at its top level or, failing
+// that, the lowest level stated by its confidence methods.
+func credentialAssurance(credContents map[string]any, methods []ConfidenceMethod) AssuranceLevel {
+	if level := assuranceField(credContents); level != AssuranceUnspecified {
+		return level
+	}
+
+	var lowest AssuranceLevel
+	for _, method := range methods {
+		if method.AssuranceLevel != AssuranceUnspecified && (lowest == AssuranceUnspecified || method.AssuranceLevel < lowest) {
+			lowest = method.AssuranceLevel
+		}
+	}
+	return lowest
+}
@@ -0,0 +1,63 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestAssuranceLevels(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder),
+		auth.WithNamedPolicy("payments", auth.Policy{MinAssurance: auth.AssuranceSubstantial}),
+		auth.WithNamedPolicy("transfers", auth.Policy{MinAssurance: auth.AssuranceHigh}))
+
+	credential := func(vc map[string]any) string {
+		vc["type"] = "VerifiableCredential"
+		vc["issuer"] = issuer.DID
+		vc["credentialSchema"] = map[string]any{"id": authtest.SchemaURL, "type": "JsonSchema"}
+		vc["credentialSubject"] = map[string]any{"id": holder.DID}
+		return craftJWT(t, issuer, nil, map[string]any{"iss": issuer.DID, "sub": holder.DID, "vc": vc})
+	}
+	substantial := credential(map[string]any{"confidenceMethod": []any{
+		map[string]any{"type": "BiometricTemplate", "levelOfAssurance": "http://eidas.europa.eu/LoA/high"},
+		map[string]any{"type": "Pin", "assuranceLevel": "Substantial"},
+	}})
+
+	claims, err := a.VerifyCredential(context.Background(), substantial, auth.WithPolicy("payments"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.AssuranceLevel != auth.AssuranceSubstantial || len(claims.ConfidenceMethods) != 2 || claims.ConfidenceMethods[0].AssuranceLevel != auth.AssuranceHigh {
+		t.Errorf("assurance = %s, methods %+v", claims.AssuranceLevel, claims.ConfidenceMethods)
+	}
+	if data, _ := json.Marshal(claims); !strings.Contains(string(data), `"assuranceLevel":"substantial"`) {
+		t.Errorf("claims JSON %s does not expose the level", data)
+	}
+
+	_, err = a.VerifyCredential(context.Background(), substantial, auth.WithPolicy("transfers"))
+	if !errors.Is(err, auth.ErrInsufficientAssurance) || !errors.Is(err, auth.ErrPolicyViolation) {
+		t.Errorf("transfers policy error = %v, want ErrInsufficientAssurance", err)
+	}
+
+	unstated := credential(map[string]any{})
+	if _, err := a.VerifyCredential(context.Background(), unstated, auth.WithPolicy("payments")); !errors.Is(err, auth.ErrInsufficientAssurance) {
+		t.Errorf("credential without a level: error = %v, want ErrInsufficientAssurance", err)
+	}
+
+	high := credential(map[string]any{"levelOfAssurance": "high"})
+	highClaims, err := a.VerifyCredential(context.Background(), high)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level := auth.MinAssurance([]auth.VcClaims{claims, highClaims}); level != auth.AssuranceSubstantial {
+		t.Errorf("MinAssurance = %s, want substantial", level)
+	}
+}
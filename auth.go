@@ -2,116 +2,411 @@ package auth
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/did"
 	"github/hovanhoa/go-vc-auth/provider"
+	"github/hovanhoa/go-vc-auth/schema"
 
-	vcdto "github.com/pilacorp/go-credential-sdk/credential/common/dto"
 	"github.com/pilacorp/go-credential-sdk/credential/vc"
-	"github.com/pilacorp/go-credential-sdk/credential/vp"
 )
 
+// defaultTimeout bounds outgoing HTTP requests such as schema downloads.
+const defaultTimeout = 10 * time.Second
+
+// Auth creates and verifies VP tokens.
+// Implementations returned by this package are safe for concurrent use by multiple goroutines.
+// Issuance, credential verification, request handling and the other capabilities are methods of
+// *Service, which NewAuth returns.
 type Auth interface {
 	// CreateToken creates a new VP token with a list of VCs.
 	CreateToken(ctx context.Context, vcsJwt []string, holderDid string, opts ...any) (string, error)
 
 	// VerifyToken verifies a VP token with a list of VCs.
-	VerifyToken(ctx context.Context, token string) ([]VcClaims, error)
+	VerifyToken(ctx context.Context, token string, opts ...VerifyOpt) ([]VcClaims, error)
 }
 
-type auth struct {
-	provider provider.Provider
+var _ Auth = (*Service)(nil)
+
+// Service is the Auth implementation returned by NewAuth.
+// It holds only configuration that is immutable after NewAuth returns.
+// The exceptions are signMu, which serializes signing for providers declaring provider.Serial,
+// and lifecycle, which tracks in-flight signs for Close.
+type Service struct {
+	provider   provider.Provider
+	resolver   did.Resolver
+	httpClient *http.Client
+	signMu     *sync.Mutex
+	tokenStore TokenStore
+	clock      clock.Clock
+	lifecycle  *lifecycle
+	schemas    schema.Source
+	resources  ResourceFetcher
+	policies   map[string]Policy
+
+	degradedMode *degradedMode
+	pinnedKeys   map[string]map[string]bool
+	normalize    SignatureNormalizer
+	strictLowS   bool
+	profile      *Profile
 }
 
 // NewAuth creates a new Auth instance.
-// It initializes the VC and VP SDKs with the provided DID URL.
-func NewAuth(p provider.Provider, didUrl string) Auth {
-	vc.Init(didUrl)
-	vp.Init(didUrl)
-	return &auth{
-		provider: p,
+// The DID URL is scoped to the returned instance and is used to resolve issuer and holder keys,
+// so several Auths configured with different DID registries can coexist in one process. It may be
+// empty when WithProfile provides the registry.
+func NewAuth(p provider.Provider, didUrl string, opts ...Option) *Service {
+	a := &Service{
+		provider:   p,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		clock:      clock.System(),
+		lifecycle:  &lifecycle{},
+		normalize:  provider.NormalizeLowS,
 	}
-}
 
-// CreateToken creates a new VP token with a list of VCs.
-func (a *auth) CreateToken(ctx context.Context, vcsJwt []string, holderDid string, opts ...any) (string, error) {
-	vcs := make([]vc.Credential, len(vcsJwt))
-	for i, vcJwt := range vcsJwt {
-		vc, err := vc.ParseCredential([]byte(vcJwt))
-		if err != nil {
-			return "", err
-		}
+	if p != nil && provider.ConcurrencyOf(p) == provider.Serial {
+		a.signMu = &sync.Mutex{}
+	}
 
-		vcs[i] = vc
+	for _, opt := range opts {
+		opt(a)
 	}
 
-	vpContents := vp.PresentationContents{
-		Context: []any{
-			"https://www.w3.org/ns/credentials/v2",
-			"https://www.w3.org/ns/credentials/examples/v2",
-		},
-		Holder:                holderDid,
-		Types:                 []string{"VerifiablePresentation"},
-		VerifiableCredentials: vcs,
+	// Defaults are created once every option is applied, so they share the configured HTTP client.
+	if didUrl == "" && a.profile != nil {
+		didUrl = a.profile.RegistryURL
+	}
+	if a.resolver == nil {
+		a.resolver = did.NewResolver(didUrl, did.WithHTTPClient(a.httpClient))
+	}
+	if a.schemas == nil {
+		a.schemas = schema.NewRegistry(schema.WithHTTPClient(a.httpClient), schema.WithTTL(0))
 	}
 
-	vpPresentation, err := vp.NewJWTPresentation(vpContents)
-	if err != nil {
-		return "", err
+	// Wrapped once every option is applied, so the cache uses the configured clock.
+	if a.degradedMode != nil {
+		a.resolver = a.degradedMode.resolver(a.resolver, a)
 	}
 
-	signData, err := vpPresentation.GetSigningInput()
-	if err != nil {
-		return "", err
+	return a
+}
+
+// WithResolver resolves DIDs with resolver instead of the HTTP registry at the DID URL given to
+// NewAuth, e.g. a did.CachingResolver or an in-memory registry in tests.
+func WithResolver(resolver did.Resolver) Option {
+	return func(a *Service) {
+		a.resolver = resolver
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for DID resolution, schema and resource downloads and domain
+// linkage checks (default: a client with a 10s timeout). Its Transport can serve responses from memory
+// in tests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(a *Service) {
+		a.httpClient = client
 	}
+}
+
+// ErrNetworkDisabled is returned for every HTTP request of an Auth configured with WithoutNetwork.
+var ErrNetworkDisabled = errors.New("network access is disabled")
+
+// WithoutNetwork makes every HTTP request of the Auth fail with ErrNetworkDisabled, for air-gapped
+// deployments, demos and tests that serve DIDs and schemas from memory and must never leave the process.
+func WithoutNetwork() Option {
+	return WithHTTPClient(&http.Client{Transport: noNetwork{}})
+}
+
+// noNetwork is an http.RoundTripper failing every request with ErrNetworkDisabled.
+type noNetwork struct{}
+
+func (noNetwork) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("%w: %s %s", ErrNetworkDisabled, req.Method, req.URL)
+}
 
-	hash := sha256.Sum256(signData)
-	signature, err := a.provider.Sign(hash[:], opts...)
+// CreateToken creates a new VP token with a list of VCs.
+// opts may mix CreateOpt values, which control the token itself, with provider options.
+// Inputs are validated up front and every VC is verified before it is embedded;
+// ctx bounds DID resolution and signing.
+func (a *Service) CreateToken(ctx context.Context, vcsJwt []string, holderDid string, opts ...any) (string, error) {
+	options, providerOpts, err := splitCreateOpts(opts)
 	if err != nil {
 		return "", err
 	}
 
-	err = vpPresentation.AddCustomProof(&vcdto.Proof{
-		Signature: signature,
-	})
+	vcTokens, err := a.validateCreateInput(vcsJwt, holderDid)
 	if err != nil {
 		return "", err
 	}
 
-	document, err := vpPresentation.Serialize()
+	if options.disclosureDefinition != nil {
+		if err := checkCreateDisclosure(vcsJwt, options); err != nil {
+			return "", err
+		}
+	}
+
+	for i, vcToken := range vcTokens {
+		if err := a.verifyJWT(ctx, vcToken); err != nil {
+			return "", newCredentialError(i, vcsJwt[i], fmt.Errorf("failed to verify credential: %w", err))
+		}
+	}
+
+	if options.singleUse {
+		if a.tokenStore == nil {
+			return "", fmt.Errorf("%w: single-use presentations are redeemed against it", ErrNoTokenRegistry)
+		}
+		if options.lifetime <= 0 {
+			return "", errors.New("single-use presentations require WithExpiry")
+		}
+	}
+
+	if a.tokenStore != nil || options.lifetime > 0 {
+		options.issuedAt = a.clock.Now().UTC().Truncate(time.Second)
+		if options.lifetime > 0 {
+			options.expiresAt = options.issuedAt.Add(options.lifetime)
+		}
+	}
+	if a.tokenStore != nil {
+		if options.tokenID, err = newTokenID(); err != nil {
+			return "", err
+		}
+	}
+
+	if a.profile != nil {
+		options.contexts = a.profile.Contexts
+	}
+	options.algorithm = provider.AlgorithmOf(a.provider)
+	signingInput, err := buildPresentationSigningInput(holderDid, vcsJwt, options)
 	if err != nil {
 		return "", err
 	}
 
-	documentBytes, err := json.Marshal(document)
+	document, err := a.signJWT(ctx, signingInput, providerOpts...)
 	if err != nil {
 		return "", err
 	}
 
-	return string(documentBytes), nil
+	if a.tokenStore != nil {
+		if err := a.recordPresentation(ctx, holderDid, vcTokens, options); err != nil {
+			return "", err
+		}
+	}
+
+	if options.consentSink != nil {
+		if err := a.issueConsentReceipt(ctx, holderDid, vcTokens, document, options, providerOpts); err != nil {
+			return "", err
+		}
+	}
+
+	if options.encryptionKey != nil {
+		if document, err = encryptJWE([]byte(document), options.encryptionKey, options.encryptionKeyID); err != nil {
+			return "", fmt.Errorf("failed to encrypt presentation: %w", err)
+		}
+	}
+
+	if len(options.additionalProofs) > 0 {
+		return signAdditionalProofs(ctx, document, options.additionalProofs)
+	}
+
+	return serializeToken(document, options.outputFormat)
+}
+
+// validateCreateInput checks the CreateToken arguments without any network or signing work
+// and returns the decoded credentials.
+func (a *Service) validateCreateInput(vcsJwt []string, holderDid string) ([]*jwtToken, error) {
+	if a.provider == nil {
+		return nil, ErrNilProvider
+	}
+
+	if len(vcsJwt) == 0 {
+		return nil, ErrEmptyCredentialList
+	}
+
+	if _, err := did.Parse(holderDid); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHolderDID, err)
+	}
+	if a.profile != nil && !a.profile.Owns(holderDid) {
+		return nil, fmt.Errorf("%w: %s is not a %s DID", ErrInvalidHolderDID, holderDid, a.profile.Name)
+	}
+
+	vcTokens := make([]*jwtToken, len(vcsJwt))
+	for i, vcJwt := range vcsJwt {
+		vcToken, err := parseJWT(vcJwt)
+		if err != nil {
+			return nil, newCredentialError(i, vcJwt, fmt.Errorf("%w: %v", ErrMalformedCredential, err))
+		}
+
+		if _, ok := vcToken.payload["vc"].(map[string]any); !ok {
+			return nil, newCredentialError(i, vcJwt, fmt.Errorf("%w: vc claim not found in JWT payload", ErrMalformedCredential))
+		}
+
+		vcTokens[i] = vcToken
+	}
+
+	return vcTokens, nil
+}
+
+// sign signs the payload with the provider, passing ctx along to the signing backend.
+func (a *Service) sign(ctx context.Context, payload []byte, options *provider.ProviderOption) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := a.lifecycle.begin(); err != nil {
+		return nil, err
+	}
+	defer a.lifecycle.end()
+
+	if a.signMu != nil {
+		a.signMu.Lock()
+		defer a.signMu.Unlock()
+	}
+
+	return a.provider.Sign(ctx, payload, options)
 }
 
 // VerifyToken verifies a VP token with a list of VCs.
-func (a *auth) VerifyToken(ctx context.Context, token string) ([]VcClaims, error) {
-	vpPresentation, err := vp.ParseJWTPresentation(token, vp.WithVerifyProof(), vp.WithVCValidation())
+// ctx bounds every DID resolution and schema download performed during verification.
+// A panic while processing the untrusted token is returned as a *PanicError.
+func (a *Service) VerifyToken(ctx context.Context, token string, opts ...VerifyOpt) (_ []VcClaims, err error) {
+	defer recoverPanic(&err)
+
+	options := getVerifyOptions(opts...)
+	ctx, cancel := withTimeouts(ctx, options.timeouts)
+	defer cancel()
+	ctx = withTraceTarget(ctx, options.trace, "presentation")
+	ctx = withDegradation(ctx, options.degradation)
+
+	compacts, err := normalizeToken(token)
 	if err != nil {
+		traceStep(ctx, StepDecode, err)
 		return nil, err
 	}
 
-	// Get VP contents
-	vpContentsBytes, err := vpPresentation.GetContents()
+	if len(compacts) == 1 && isJWE(compacts[0]) {
+		if len(options.decryptionKeys) == 0 {
+			err := fmt.Errorf("%w: no decryption key configured", ErrEncryptedToken)
+			traceStep(ctx, StepDecrypt, err)
+			return nil, err
+		}
+
+		plaintext, err := decryptJWE(compacts[0], options.decryptionKeys)
+		traceStep(ctx, StepDecrypt, err)
+		if err != nil {
+			return nil, err
+		}
+		compacts[0] = string(plaintext)
+	}
+
+	vpToken, holderKey, err := a.verifyPresentationProofs(ctx, compacts, options)
 	if err != nil {
 		return nil, err
 	}
 
-	// Parse VP contents as JSON
-	var vpData map[string]any
-	if err := json.Unmarshal(vpContentsBytes, &vpData); err != nil {
+	if options.keyAttestation != nil {
+		err := checkKeyAttestation(ctx, vpToken, holderKey, options.keyAttestation, a.clock.Now())
+		traceStep(ctx, StepKeyAttestation, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	holderDid, _ := did.SplitDIDURL(stringField(vpToken.header, "kid"))
+	if _, hasChain := vpToken.header["x5c"]; hasChain && options.x509Roots != nil {
+		err := verifyCertificateBinding(vpToken, holderDid, options.x509Roots, a.clock.Now())
+		traceStep(ctx, StepCertificate, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify presentation certificate: %w", err)
+		}
+	}
+
+	err = checkHolderBinding(vpToken, holderDid)
+	if err == nil {
+		err = checkPresentationBinding(vpToken, options, a.clock.Now())
+	}
+	traceStep(ctx, StepBinding, err)
+	if err != nil {
 		return nil, err
 	}
 
+	var singleUse bool
+	if a.tokenStore != nil {
+		singleUse, err = a.checkRevocation(ctx, vpToken)
+		traceStep(ctx, StepRevocation, err, "jti", stringField(vpToken.payload, "jti"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	vcsArray, err := presentedCredentials(vpToken)
+	traceStep(ctx, StepClaims, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse each VC and extract CredentialContents
+	var vcClaimsList []VcClaims
+	for i, vcItem := range vcsArray {
+		claims, err := a.verifyCredential(withTraceTarget(ctx, options.trace, fmt.Sprintf("credential[%d]", i)), vcItem, options)
+		if err != nil {
+			return nil, newCredentialError(i, vcItem, err)
+		}
+
+		claims.raw = rawCredential(vcItem)
+		vcClaimsList = append(vcClaimsList, claims)
+	}
+
+	if options.policy != "" {
+		err := a.applyPolicy(vcClaimsList, options)
+		traceStep(ctx, StepPolicy, err, "policy", options.policy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.linkedDomain != "" {
+		dids := []string{holderDid}
+		for _, claims := range vcClaimsList {
+			dids = append(dids, claims.Issuer)
+		}
+
+		err := a.verifyDomainLinkage(ctx, options.linkedDomain, dids...)
+		traceStep(ctx, StepDomainLinkage, err, "domain", options.linkedDomain)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A single-use presentation is redeemed last, so one failing a later check is not spent.
+	if singleUse {
+		jti := stringField(vpToken.payload, "jti")
+		err := a.redeemPresentation(ctx, jti)
+		traceStep(ctx, StepRedemption, err, "jti", jti)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.rawPresentation != nil {
+		*options.rawPresentation = newRawPresentation(token, vpToken)
+	}
+
+	return vcClaimsList, nil
+}
+
+// presentedCredentials returns the verifiableCredential array of a VP.
+func presentedCredentials(vpToken *jwtToken) ([]any, error) {
+	vpData, ok := vpToken.payload["vp"].(map[string]any)
+	if !ok {
+		return nil, errors.New("vp claim not found in JWT payload")
+	}
+
 	// Extract verifiableCredential array
 	vcsRaw, ok := vpData["verifiableCredential"]
 	if !ok {
@@ -123,34 +418,178 @@ func (a *auth) VerifyToken(ctx context.Context, token string) ([]VcClaims, error
 		return nil, errors.New("verifiableCredential is not an array")
 	}
 
-	// Parse each VC and extract CredentialContents
-	var vcClaimsList []VcClaims
-	for _, vcItem := range vcsArray {
-		var credential vc.Credential
-		var err error
+	return vcsArray, nil
+}
 
-		credential, err = vc.ParseCredential([]byte(vcItem.(string)))
+// VerifyCredential verifies a single JWT VC, given as a compact JWS or an EnvelopedVerifiableCredential,
+// or a credential in a format handled by a registered CredentialParser, with the same checks applied to
+// credentials inside a presentation.
+func (a *Service) VerifyCredential(ctx context.Context, vcJwt string, opts ...VerifyOpt) (_ VcClaims, err error) {
+	defer recoverPanic(&err)
 
+	options := getVerifyOptions(opts...)
+	ctx, cancel := withTimeouts(ctx, options.timeouts)
+	defer cancel()
+	ctx = withTraceTarget(ctx, options.trace, "credential[0]")
+	ctx = withDegradation(ctx, options.degradation)
+
+	credential := vcJwt
+	if _, parser := detectCredentialParser(vcJwt); parser == nil {
+		if credential, err = normalizeCredential(vcJwt); err != nil {
+			traceStep(ctx, StepDecode, err)
+			return VcClaims{}, err
+		}
+	}
+
+	claims, err := a.verifyCredential(ctx, credential, options)
+	if err != nil {
+		return VcClaims{}, newCredentialError(0, credential, err)
+	}
+	claims.raw = vcJwt
+
+	if options.policy != "" {
+		err := a.applyPolicy([]VcClaims{claims}, options)
+		traceStep(ctx, StepPolicy, err, "policy", options.policy)
 		if err != nil {
-			return nil, err
+			return VcClaims{}, err
+		}
+	}
+
+	return claims, nil
+}
+
+// verifyCredential validates and verifies one credential embedded in a presentation.
+// Credentials detected by a registered CredentialParser are handed to verifyParsedCredential.
+func (a *Service) verifyCredential(ctx context.Context, vcItem any, options *verifyOptions) (VcClaims, error) {
+	if format, parser := detectCredentialParser(vcItem); parser != nil {
+		return a.verifyParsedCredential(ctx, format, parser, vcItem, options)
+	}
+
+	credContents, vcToken, err := decodeCredential(vcItem)
+	traceStep(ctx, StepDecode, err)
+	if err != nil {
+		return VcClaims{}, err
+	}
+
+	issuerKey, err := a.verifyJWTKey(ctx, vcToken, options.algorithms, options.purpose(ProofPurposeAssertionMethod))
+	if err != nil {
+		return VcClaims{}, fmt.Errorf("failed to verify credential: %w", err)
+	}
+
+	// Schemas are only fetched for signed credentials, so forged ones cannot make the verifier
+	// download arbitrary URLs.
+	err = a.validateSchema(ctx, credContents)
+	traceStep(ctx, StepSchema, err)
+	if err != nil {
+		return VcClaims{}, fmt.Errorf("failed to validate credential: %w", err)
+	}
+
+	issuerDid, _ := did.SplitDIDURL(stringField(vcToken.header, "kid"))
+	if options.x509Roots != nil {
+		err := verifyCertificateBinding(vcToken, issuerDid, options.x509Roots, a.clock.Now())
+		traceStep(ctx, StepCertificate, err)
+		if err != nil {
+			return VcClaims{}, fmt.Errorf("failed to verify issuer certificate: %w", err)
 		}
+	}
 
-		// Get credential contents
-		credContentsBytes, err := credential.GetContents()
+	if options.issuerRegistry != nil {
+		err := checkIssuerTrust(ctx, options.issuerRegistry, vcToken, issuerDid, issuerKey)
+		traceStep(ctx, StepIssuerTrust, err, "issuer", issuerDid)
 		if err != nil {
-			return nil, err
+			return VcClaims{}, err
 		}
+	}
 
-		var credContents map[string]any
-		if err := json.Unmarshal(credContentsBytes, &credContents); err != nil {
-			return nil, err
+	claims, err := newVcClaims(credContents, vcToken)
+	if err == nil {
+		err = checkIssuerBinding(claims, stringField(vcToken.payload, "iss"), issuerDid)
+	}
+	traceStep(ctx, StepClaims, err)
+	if err != nil {
+		return VcClaims{}, err
+	}
+	claims.Proof.Purpose = options.purpose(ProofPurposeAssertionMethod)
+
+	// Like schemas, resources are only fetched once the credential is known to be signed.
+	if options.relatedResources {
+		if err := a.checkRelatedResources(ctx, &claims); err != nil {
+			return VcClaims{}, err
 		}
+	}
 
-		vcClaimsList = append(vcClaimsList, VcClaims{
-			Issuer:            credContents["issuer"].(string),
-			CredentialSubject: credContents["credentialSubject"].(map[string]any),
-		})
+	if options.anchors != nil {
+		claims.Anchor, err = checkAnchor(ctx, options.anchors, vcToken)
+		traceStep(ctx, StepAnchor, err)
+		if err != nil {
+			return VcClaims{}, err
+		}
 	}
 
-	return vcClaimsList, nil
+	notBefore, notAfter := jwtValidity(vcToken)
+	err = checkValidity(claims, notBefore, notAfter, a.clock.Now())
+	traceStep(ctx, StepValidity, err)
+	if err != nil {
+		return VcClaims{}, err
+	}
+
+	claims.Display = lookupDisplay(options.displaySources, claims)
+
+	return claims, nil
+}
+
+// checkHolderBinding ensures the presentation's "iss" claim and holder, when present, name holderDid,
+// the DID whose key signed the presentation.
+func checkHolderBinding(vpToken *jwtToken, holderDid string) error {
+	if iss := stringField(vpToken.payload, "iss"); iss != "" && iss != holderDid {
+		return fmt.Errorf("%w: presentation iss %q, signed by %q", ErrIssuerMismatch, iss, holderDid)
+	}
+	vp, _ := vpToken.payload["vp"].(map[string]any)
+	if holder := stringField(vp, "holder"); holder != "" && holder != holderDid {
+		return fmt.Errorf("%w: presentation holder %q, signed by %q", ErrIssuerMismatch, holder, holderDid)
+	}
+	return nil
+}
+
+// checkIssuerBinding ensures the credential's issuer and "iss" claim, when present, name signerDid,
+// the DID whose key verified the credential; otherwise anyone could sign a credential in another issuer's name.
+func checkIssuerBinding(claims VcClaims, iss, signerDid string) error {
+	if claims.Issuer != signerDid {
+		return fmt.Errorf("%w: issuer %q, signed by %q", ErrIssuerMismatch, claims.Issuer, signerDid)
+	}
+	if iss != "" && iss != signerDid {
+		return fmt.Errorf("%w: iss %q, signed by %q", ErrIssuerMismatch, iss, signerDid)
+	}
+	return nil
+}
+
+// decodeCredential parses a credential of a presentation into its contents and JWT envelope.
+func decodeCredential(vcItem any) (map[string]any, *jwtToken, error) {
+	vcJwt, ok := vcItem.(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %T is not a JWT string", ErrUnsupportedFormat, vcItem)
+	}
+
+	credential, err := vc.ParseCredential([]byte(vcJwt))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get credential contents
+	credContentsBytes, err := credential.GetContents()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var credContents map[string]any
+	if err := json.Unmarshal(credContentsBytes, &credContents); err != nil {
+		return nil, nil, err
+	}
+
+	vcToken, err := parseJWT(vcJwt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return credContents, vcToken, nil
 }
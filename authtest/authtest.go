@@ -0,0 +1,162 @@
+// Package authtest provides in-memory fakes of the external dependencies of an Auth: a DID registry,
+// a signing provider and a schema source, wired with an HTTP client that refuses to reach the network, so
+// tests of code built on this module run without Vault, a DID registry or schema servers.
+package authtest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/ethaddr"
+	"github/hovanhoa/go-vc-auth/provider"
+	"github/hovanhoa/go-vc-auth/schema"
+)
+
+// SchemaURL is a credentialSchema id served by Schemas, accepting any credential.
+const SchemaURL = "https://schemas.authtest.invalid/any"
+
+// ErrNetwork is returned for every HTTP request of an Auth wired with Registry.Options.
+var ErrNetwork = auth.ErrNetworkDisabled
+
+// Identity is a secp256k1 key pair published in a Registry.
+type Identity struct {
+	DID     string
+	Address string
+	Key     *ecdsa.PrivateKey
+}
+
+// Registry is an in-memory DID registry. It implements did.Resolver and is safe for concurrent use.
+type Registry struct {
+	*did.MemoryRegistry
+}
+
+var _ did.Resolver = (*Registry)(nil)
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{MemoryRegistry: did.NewMemoryRegistry()}
+}
+
+// NewIdentity generates a key pair and publishes a did:nda:testnet document for it, with its key
+// listed for authentication and assertions.
+func (r *Registry) NewIdentity() (*Identity, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	address := strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	id, err := auth.ProfileTestnet.DID(address)
+	if err != nil {
+		return nil, err
+	}
+	r.Publish(did.NewSecp256k1Document(id, &key.PublicKey))
+
+	return &Identity{DID: id, Address: address, Key: key}, nil
+}
+
+// Options returns the options wiring an Auth to the registry and the other fakes: DIDs resolve
+// against r, schemas come from Schemas, and any other HTTP request fails with ErrNetwork.
+func (r *Registry) Options() []auth.Option {
+	return []auth.Option{
+		auth.WithResolver(r),
+		auth.WithSchemaSource(Schemas()),
+		auth.WithoutNetwork(),
+	}
+}
+
+// Signer is a provider signing with in-memory keys, selected by the signer address of the provider
+// options like the Vault provider. It is safe for concurrent use.
+type Signer struct {
+	mu   sync.RWMutex
+	keys map[string]*ecdsa.PrivateKey
+}
+
+var (
+	_ provider.Provider          = (*Signer)(nil)
+	_ provider.PublicKeyExporter = (*Signer)(nil)
+)
+
+// NewSigner creates a Signer holding the keys of identities.
+func NewSigner(identities ...*Identity) *Signer {
+	s := &Signer{keys: map[string]*ecdsa.PrivateKey{}}
+	for _, identity := range identities {
+		s.Add(identity)
+	}
+	return s
+}
+
+// Add adds the key of identity.
+func (s *Signer) Add(identity *Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	address, _ := ethaddr.Normalize(identity.Address)
+	s.keys[address] = identity.Key
+}
+
+// Sign signs the digest payload with the key of the address in options, returning r || s.
+func (s *Signer) Sign(ctx context.Context, payload []byte, options *provider.ProviderOption) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key, err := s.key(options)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := crypto.Sign(payload, key)
+	if err != nil {
+		return nil, err
+	}
+	return signature[:64], nil
+}
+
+// PublicKey returns the public key of the address in options.
+func (s *Signer) PublicKey(ctx context.Context, options *provider.ProviderOption) (*ecdsa.PublicKey, error) {
+	key, err := s.key(options)
+	if err != nil {
+		return nil, err
+	}
+	return &key.PublicKey, nil
+}
+
+// key returns the key of the signer address in options.
+func (s *Signer) key(options *provider.ProviderOption) (*ecdsa.PrivateKey, error) {
+	var address string
+	if options != nil {
+		address = options.Signer
+	}
+	normalized, err := ethaddr.Normalize(address)
+	if err != nil {
+		return nil, fmt.Errorf("authtest: invalid signer address: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[normalized]
+	if !ok {
+		return nil, fmt.Errorf("authtest: unknown signer %q", address)
+	}
+	return key, nil
+}
+
+// schemaSource serves a permissive schema for every id.
+type schemaSource struct{}
+
+func (schemaSource) Schema(ctx context.Context, id string) ([]byte, error) {
+	return []byte(`{"type":"object"}`), nil
+}
+
+// Schemas returns a schema.Source accepting every credential under any schema id, e.g. SchemaURL.
+func Schemas() schema.Source {
+	return schemaSource{}
+}
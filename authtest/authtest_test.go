@@ -0,0 +1,52 @@
+package authtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestOfflineFlow(t *testing.T) {
+	ctx := context.Background()
+	registry := authtest.NewRegistry()
+	issuer, err := registry.NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder, err := registry.NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The DID URL is unreachable: everything must go through the fakes.
+	a := auth.NewAuth(authtest.NewSigner(issuer, holder), "https://registry.authtest.invalid", registry.Options()...)
+
+	results, err := a.IssueCredentials(ctx, []auth.CredentialDocument{{
+		Issuer:  issuer.DID,
+		Schemas: []auth.CredentialSchema{{ID: authtest.SchemaURL, Type: "JsonSchema"}},
+		Subject: map[string]any{"id": holder.DID, "role": "viewer"},
+	}}, issuer.Address)
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("IssueCredentials = %+v, %v", results, err)
+	}
+
+	token, err := a.CreateToken(ctx, []string{results[0].Credential}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := a.VerifyToken(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role := claims[0].Subject().Claims["role"]; role != "viewer" {
+		t.Errorf("role = %v, want viewer", role)
+	}
+
+	_, err = a.VerifyToken(ctx, token, auth.WithRequireLinkedDomain("issuer.example"))
+	if !errors.Is(err, authtest.ErrNetwork) {
+		t.Errorf("linked domain check error = %v, want ErrNetwork", err)
+	}
+}
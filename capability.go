@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+const (
+	// capabilityTokenType is the "typ" header of capability JWTs.
+	capabilityTokenType = "zcap+jwt"
+	// maxCapabilityDepth bounds the links of a delegation chain, the root included.
+	maxCapabilityDepth = 8
+)
+
+// ErrInvalidCapability is returned when a capability token is malformed, its delegation chain is broken
+// or widens the authority it was given, or it does not grant the requested action on the target.
+var ErrInvalidCapability = errors.New("invalid capability")
+
+// CapabilityGrant is the authority a capability token grants.
+type CapabilityGrant struct {
+	Target   string        // Resource the capability applies to, e.g. "https://api.example.com/doors/12"
+	Actions  []string      // Actions allowed on the target, e.g. "open"
+	Lifetime time.Duration // How long the capability is valid; required
+}
+
+// Capability is a verified capability token.
+type Capability struct {
+	ID         string    // jti of the last link
+	Target     string    // Resource the capability applies to
+	Actions    []string  // Actions allowed on Target
+	Controller string    // DID allowed to invoke the capability
+	Delegators []string  // DIDs that signed the links, from the credential holder to the last delegator
+	ExpiresAt  time.Time // Expiry of the last link, never later than its parents'
+	Credential VcClaims  // Credential the chain is rooted in
+}
+
+// CreateCapability mints a capability rooted in the credential vcJwt: the holder, the credential's
+// subject, grants controller the authority of grant. The token embeds the credential and is signed
+// with the holder key through the provider; opts are handled as in CreateToken.
+func (a *Service) CreateCapability(ctx context.Context, vcJwt, holderDid, controller string, grant CapabilityGrant, opts ...any) (string, error) {
+	vcTokens, err := a.validateCreateInput([]string{vcJwt}, holderDid)
+	if err != nil {
+		return "", err
+	}
+	if subject := credentialSubjectID(vcTokens[0]); subject != holderDid {
+		return "", fmt.Errorf("%w: %s is not the subject of the credential", ErrInvalidHolderDID, holderDid)
+	}
+	if err := a.verifyJWT(ctx, vcTokens[0]); err != nil {
+		return "", newCredentialError(0, vcJwt, fmt.Errorf("failed to verify credential: %w", err))
+	}
+
+	return a.signCapability(ctx, holderDid, controller, grant, map[string]any{"vc": vcJwt}, opts)
+}
+
+// DelegateCapability delegates the capability parent, held by delegatorDid, to controller. grant may
+// only attenuate the parent: its actions must be a subset of the parent's, its target the parent's or
+// a path below it, and it cannot outlive the parent.
+func (a *Service) DelegateCapability(ctx context.Context, parent, delegatorDid, controller string, grant CapabilityGrant, opts ...any) (string, error) {
+	chain, err := parseCapabilityChain(parent)
+	if err != nil {
+		return "", err
+	}
+	last := chain[len(chain)-1]
+	if len(chain) >= maxCapabilityDepth {
+		return "", fmt.Errorf("%w: delegation chain exceeds %d links", ErrInvalidCapability, maxCapabilityDepth)
+	}
+	if stringField(last.payload, "sub") != delegatorDid {
+		return "", fmt.Errorf("%w: capability is not held by %s", ErrInvalidCapability, delegatorDid)
+	}
+	if err := attenuates(last.payload, grant, a.clock.Now()); err != nil {
+		return "", err
+	}
+
+	return a.signCapability(ctx, delegatorDid, controller, grant, map[string]any{"prf": parent}, opts)
+}
+
+// signCapability signs a capability link from delegator to controller, adding extra to its claims.
+func (a *Service) signCapability(ctx context.Context, delegator, controller string, grant CapabilityGrant, extra map[string]any, opts []any) (string, error) {
+	options, providerOpts, err := splitCreateOpts(opts)
+	if err != nil {
+		return "", err
+	}
+	if a.provider == nil {
+		return "", ErrNilProvider
+	}
+	if _, err := did.Parse(controller); err != nil {
+		return "", fmt.Errorf("%w: invalid controller: %v", ErrInvalidCapability, err)
+	}
+	if grant.Target == "" || len(grant.Actions) == 0 || grant.Lifetime <= 0 {
+		return "", fmt.Errorf("%w: a grant needs a target, actions and a lifetime", ErrInvalidCapability)
+	}
+	if _, err := parseCapabilityTarget(grant.Target); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCapability, err)
+	}
+
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	now := a.clock.Now().UTC().Truncate(time.Second)
+
+	header := map[string]any{
+		"typ": capabilityTokenType,
+		"alg": provider.AlgorithmOf(a.provider),
+		"kid": fmt.Sprintf("%s#%s", delegator, options.verificationMethodKey),
+	}
+	payload := map[string]any{
+		"jti": jti,
+		"iss": delegator,
+		"sub": controller,
+		"iat": now.Unix(),
+		"exp": now.Add(grant.Lifetime).Unix(),
+		"cap": map[string]any{"target": grant.Target, "actions": grant.Actions},
+	}
+	for name, value := range extra {
+		payload[name] = value
+	}
+
+	signingInput, err := encodeSigningInput(header, payload)
+	if err != nil {
+		return "", err
+	}
+	return a.signJWT(ctx, signingInput, providerOpts...)
+}
+
+// VerifyCapability verifies a capability token for invoking action on target: every link must be signed
+// by the controller of its parent, attenuate it and be unexpired, and the root credential must pass the
+// checks of VerifyToken under opts. The caller still authenticates the invoker as the returned
+// Controller, e.g. with VerifyProof over a proof bound to the capability token.
+func (a *Service) VerifyCapability(ctx context.Context, token, action, target string, opts ...VerifyOpt) (_ *Capability, err error) {
+	defer recoverPanic(&err)
+
+	options := getVerifyOptions(opts...)
+	ctx, cancel := withTimeouts(ctx, options.timeouts)
+	defer cancel()
+	ctx = withTraceTarget(ctx, options.trace, "capability")
+
+	chain, err := parseCapabilityChain(token)
+	if err != nil {
+		traceStep(ctx, StepDecode, err)
+		return nil, err
+	}
+
+	now := a.clock.Now()
+	capability := &Capability{}
+	for i, link := range chain {
+		if _, err := a.verifyJWTKey(ctx, link, options.algorithms, options.purpose(ProofPurposeCapabilityDelegation)); err != nil {
+			return nil, fmt.Errorf("%w: link %d: %v", ErrInvalidCapability, i, err)
+		}
+		if kidDid, _ := did.SplitDIDURL(stringField(link.header, "kid")); kidDid != stringField(link.payload, "iss") {
+			return nil, fmt.Errorf("%w: link %d is not signed by its issuer", ErrInvalidCapability, i)
+		}
+
+		grant, expiresAt, err := capabilityGrant(link.payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: link %d: %v", ErrInvalidCapability, i, err)
+		}
+		if now.After(expiresAt) {
+			return nil, fmt.Errorf("%w: link %d has expired", ErrInvalidCapability, i)
+		}
+		if i > 0 {
+			if stringField(link.payload, "iss") != capability.Controller {
+				return nil, fmt.Errorf("%w: link %d is not signed by the controller of its parent", ErrInvalidCapability, i)
+			}
+			if err := attenuates(chain[i-1].payload, grant, expiresAt); err != nil {
+				return nil, fmt.Errorf("link %d: %w", i, err)
+			}
+		}
+
+		capability.ID, capability.Target, capability.Actions = stringField(link.payload, "jti"), grant.Target, grant.Actions
+		capability.Controller, capability.ExpiresAt = stringField(link.payload, "sub"), expiresAt
+		capability.Delegators = append(capability.Delegators, stringField(link.payload, "iss"))
+	}
+
+	// The root credential is verified last, once the chain is known to be intact.
+	root := chain[0]
+	vcJwt, _ := root.payload["vc"].(string)
+	claims, err := a.verifyCredential(withTraceTarget(ctx, options.trace, "credential[0]"), vcJwt, options)
+	if err != nil {
+		return nil, newCredentialError(0, vcJwt, err)
+	}
+	if claims.Subject().ID != capability.Delegators[0] {
+		return nil, fmt.Errorf("%w: the root is not signed by the credential subject", ErrInvalidCapability)
+	}
+	claims.raw = vcJwt
+	capability.Credential = claims
+
+	if !slices.Contains(capability.Actions, action) {
+		return nil, fmt.Errorf("%w: action %q is not granted", ErrInvalidCapability, action)
+	}
+	if !withinTarget(capability.Target, target) {
+		return nil, fmt.Errorf("%w: target %q is outside the capability", ErrInvalidCapability, target)
+	}
+	return capability, nil
+}
+
+// parseCapabilityChain decodes a capability token and its parents, root first.
+func parseCapabilityChain(token string) ([]*jwtToken, error) {
+	var chain []*jwtToken
+	for token != "" {
+		if len(chain) == maxCapabilityDepth {
+			return nil, fmt.Errorf("%w: delegation chain exceeds %d links", ErrInvalidCapability, maxCapabilityDepth)
+		}
+		link, err := parseJWT(token)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCapability, err)
+		}
+		if typ := stringField(link.header, "typ"); typ != capabilityTokenType {
+			return nil, fmt.Errorf("%w: unexpected typ %q", ErrInvalidCapability, typ)
+		}
+		chain = append(chain, link)
+		token, _ = link.payload["prf"].(string)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%w: empty token", ErrInvalidCapability)
+	}
+	slices.Reverse(chain)
+
+	if _, ok := chain[0].payload["vc"].(string); !ok {
+		return nil, fmt.Errorf("%w: the root does not embed a credential", ErrInvalidCapability)
+	}
+	return chain, nil
+}
+
+// capabilityGrant reads the grant and expiry of a capability link.
+func capabilityGrant(payload map[string]any) (CapabilityGrant, time.Time, error) {
+	capClaim, _ := payload["cap"].(map[string]any)
+	grant := CapabilityGrant{Target: stringField(capClaim, "target"), Actions: stringList(capClaim["actions"])}
+	exp, ok := payload["exp"].(float64)
+	if grant.Target == "" || len(grant.Actions) == 0 || !ok {
+		return CapabilityGrant{}, time.Time{}, errors.New("cap, exp or target claim is missing")
+	}
+	return grant, time.Unix(int64(exp), 0), nil
+}
+
+// attenuates checks that grant, expiring at expiresAt or after its lifetime from now, only narrows the
+// capability link with the given payload.
+func attenuates(parent map[string]any, grant CapabilityGrant, expiresAt time.Time) error {
+	parentGrant, parentExpiry, err := capabilityGrant(parent)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCapability, err)
+	}
+	if grant.Lifetime > 0 {
+		expiresAt = expiresAt.Add(grant.Lifetime)
+	}
+
+	for _, action := range grant.Actions {
+		if !slices.Contains(parentGrant.Actions, action) {
+			return fmt.Errorf("%w: action %q is not granted by the parent", ErrInvalidCapability, action)
+		}
+	}
+	if !withinTarget(parentGrant.Target, grant.Target) {
+		return fmt.Errorf("%w: target %q is outside the parent's", ErrInvalidCapability, grant.Target)
+	}
+	if expiresAt.After(parentExpiry) {
+		return fmt.Errorf("%w: the delegation outlives its parent", ErrInvalidCapability)
+	}
+	return nil
+}
+
+// withinTarget reports whether target is the capability target or a path below it: both must be
+// absolute URLs without dot-segments on the same scheme and host, and the cleaned path of target must
+// equal or extend the capability's.
+func withinTarget(capabilityTarget, target string) bool {
+	parent, err := parseCapabilityTarget(capabilityTarget)
+	if err != nil {
+		return false
+	}
+	child, err := parseCapabilityTarget(target)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(parent.Scheme, child.Scheme) || !strings.EqualFold(parent.Host, child.Host) ||
+		parent.Opaque != child.Opaque || parent.RawQuery != child.RawQuery || parent.Fragment != child.Fragment {
+		return false
+	}
+
+	parentPath, childPath := path.Clean("/"+parent.Path), path.Clean("/"+child.Path)
+	return childPath == parentPath || strings.HasPrefix(childPath, strings.TrimSuffix(parentPath, "/")+"/")
+}
+
+// parseCapabilityTarget parses a capability target, which must be an absolute URL whose path has no
+// "." or ".." segments, escaped or not.
+func parseCapabilityTarget(target string) (*url.URL, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAbs() {
+		return nil, fmt.Errorf("target %q is not an absolute URL", target)
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == "." || segment == ".." {
+			return nil, fmt.Errorf("target %q has dot-segments", target)
+		}
+	}
+	return u, nil
+}
+
+// credentialSubjectID returns the credentialSubject id of a JWT VC.
+func credentialSubjectID(vcToken *jwtToken) string {
+	vcData, _ := vcToken.payload["vc"].(map[string]any)
+	if subject, ok := vcData["credentialSubject"].(map[string]any); ok {
+		return stringField(subject, "id")
+	}
+	return stringField(vcToken.payload, "sub")
+}
@@ -0,0 +1,120 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+func TestCapabilityDelegation(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	delegate := registry.newIdentity(t)
+	guest := registry.newIdentity(t)
+	fake := clock.NewFake(time.Now())
+	a := registry.newAuth(authtest.NewSigner(holder, delegate, guest), auth.WithClock(fake))
+	ctx := context.Background()
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "tenant"})
+	root, err := a.CreateCapability(ctx, vcJwt, holder.DID, delegate.DID, auth.CapabilityGrant{
+		Target:   "https://building.example/doors",
+		Actions:  []string{"open", "lock"},
+		Lifetime: time.Hour,
+	}, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateCapability failed: %v", err)
+	}
+
+	capability, err := a.VerifyCapability(ctx, root, "open", "https://building.example/doors/12")
+	if err != nil {
+		t.Fatalf("VerifyCapability failed: %v", err)
+	}
+	if capability.Controller != delegate.DID || capability.Credential.Subject().Claims["role"] != "tenant" {
+		t.Errorf("unexpected capability: %+v", capability)
+	}
+
+	// The delegate passes a narrower capability on to a guest.
+	narrow := auth.CapabilityGrant{Target: "https://building.example/doors/12", Actions: []string{"open"}, Lifetime: 10 * time.Minute}
+	delegated, err := a.DelegateCapability(ctx, root, delegate.DID, guest.DID, narrow, delegate.Address)
+	if err != nil {
+		t.Fatalf("DelegateCapability failed: %v", err)
+	}
+	capability, err = a.VerifyCapability(ctx, delegated, "open", "https://building.example/doors/12")
+	if err != nil {
+		t.Fatalf("VerifyCapability failed: %v", err)
+	}
+	if capability.Controller != guest.DID || !slices.Equal(capability.Delegators, []string{holder.DID, delegate.DID}) {
+		t.Errorf("unexpected capability: %+v", capability)
+	}
+
+	for name, check := range map[string]func() error{
+		"action not granted": func() error {
+			_, err := a.VerifyCapability(ctx, delegated, "lock", "https://building.example/doors/12")
+			return err
+		},
+		"target outside": func() error {
+			_, err := a.VerifyCapability(ctx, delegated, "open", "https://building.example/doors/13")
+			return err
+		},
+		"dot-segment target": func() error {
+			_, err := a.VerifyCapability(ctx, delegated, "open", "https://building.example/doors/12/../13")
+			return err
+		},
+		"target on another host": func() error {
+			_, err := a.VerifyCapability(ctx, root, "open", "https://building.example.attacker/doors/12")
+			return err
+		},
+		"widened target": func() error {
+			grant := narrow
+			grant.Target = "https://building.example/doors/%2e%2e/vault"
+			_, err := a.DelegateCapability(ctx, root, delegate.DID, guest.DID, grant, delegate.Address)
+			return err
+		},
+		"widened actions": func() error {
+			grant := narrow
+			grant.Actions = []string{"open", "demolish"}
+			_, err := a.DelegateCapability(ctx, root, delegate.DID, guest.DID, grant, delegate.Address)
+			return err
+		},
+		"outlives parent": func() error {
+			grant := narrow
+			grant.Lifetime = 2 * time.Hour
+			_, err := a.DelegateCapability(ctx, root, delegate.DID, guest.DID, grant, delegate.Address)
+			return err
+		},
+		"not the controller": func() error {
+			_, err := a.DelegateCapability(ctx, root, guest.DID, guest.DID, narrow, guest.Address)
+			return err
+		},
+		"forged link": func() error {
+			// The guest signs a link claiming to be the delegate.
+			forged, err := a.DelegateCapability(ctx, root, delegate.DID, guest.DID, narrow, guest.Address)
+			if err != nil {
+				return err
+			}
+			_, err = a.VerifyCapability(ctx, forged, "open", "https://building.example/doors/12")
+			return err
+		},
+		"expired": func() error {
+			fake.Advance(30 * time.Minute)
+			defer fake.Advance(-30 * time.Minute)
+			_, err := a.VerifyCapability(ctx, delegated, "open", "https://building.example/doors/12")
+			return err
+		},
+	} {
+		if err := check(); !errors.Is(err, auth.ErrInvalidCapability) {
+			t.Errorf("%s: expected ErrInvalidCapability, got %v", name, err)
+		}
+	}
+
+	if _, err := a.CreateCapability(ctx, vcJwt, delegate.DID, guest.DID, narrow, delegate.Address); !errors.Is(err, auth.ErrInvalidHolderDID) {
+		t.Errorf("expected ErrInvalidHolderDID for a capability minted by a non-subject, got %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+// Package cas caches fetched artifacts, such as credential schemas and related resources, on disk by
+// the SHA-256 digest of their content, so restarts do not download them again and every entry is
+// checked against its digest when it is loaded.
+package cas
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/schema"
+)
+
+const (
+	defaultHTTPTimeout = 10 * time.Second
+	maxArtifactSize    = 10 << 20
+
+	indexFile = "index.json"
+)
+
+var (
+	// ErrNotFound is returned by Load for a digest that is not cached.
+	ErrNotFound = errors.New("artifact not cached")
+	// ErrIntegrity is returned when a fetched artifact does not match the hashlink of its id.
+	ErrIntegrity = errors.New("artifact integrity check failed")
+)
+
+// FetchFunc downloads the artifact identified by id, usually a URL.
+type FetchFunc func(ctx context.Context, id string) ([]byte, error)
+
+// Opt configures a Cache.
+type Opt func(*Cache)
+
+// WithFetch sets how artifacts missing from the cache are downloaded (default: HTTP GET, up to 10 MiB).
+func WithFetch(fetch FetchFunc) Opt {
+	return func(c *Cache) {
+		c.fetch = fetch
+	}
+}
+
+// WithMaxAge bounds how long an artifact cached under an id without a hashlink is served before it
+// is downloaded again (default: forever). Artifacts pinned by a hashlink never go stale.
+func WithMaxAge(maxAge time.Duration) Opt {
+	return func(c *Cache) {
+		c.maxAge = maxAge
+	}
+}
+
+// WithClock sets the time source used for WithMaxAge.
+func WithClock(c clock.Clock) Opt {
+	return func(cache *Cache) {
+		cache.clock = clock.OrSystem(c)
+	}
+}
+
+// indexEntry records which content an id was last fetched as.
+type indexEntry struct {
+	Digest    string    `json:"digest"` // Hex SHA-256 of the content
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// Cache is a content-addressed artifact cache in a directory: each artifact is a file named by the
+// hex SHA-256 digest of its content, and an index maps ids to digests. Ids carrying a hashlink are
+// looked up by that digest directly, so the same content is shared across URLs. Cache implements
+// schema.Source and auth.ResourceFetcher. It is safe for concurrent use, but not by several processes
+// sharing a directory.
+type Cache struct {
+	dir    string
+	fetch  FetchFunc
+	maxAge time.Duration
+	clock  clock.Clock
+
+	mu    sync.Mutex
+	index map[string]indexEntry
+}
+
+var _ schema.Source = (*Cache)(nil)
+
+// Open opens or creates the cache in dir and loads its index.
+func Open(dir string, opts ...Opt) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c := &Cache{dir: dir, clock: clock.System(), index: map[string]indexEntry{}}
+	client := &http.Client{Timeout: defaultHTTPTimeout}
+	c.fetch = func(ctx context.Context, id string) ([]byte, error) { return httpFetch(ctx, client, id) }
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read cache index: %w", err)
+	default:
+		// A corrupt index only costs downloads: the artifacts are checked on load either way.
+		_ = json.Unmarshal(data, &c.index)
+	}
+	return c, nil
+}
+
+// Get returns the artifact identified by id from the cache, or downloads and caches it. When id
+// carries a hashlink ("hl" query parameter), the artifact is looked up by, and must match, that digest.
+func (c *Cache) Get(ctx context.Context, id string) ([]byte, error) {
+	linked, err := schema.HashlinkDigest(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIntegrity, err)
+	}
+
+	if linked != nil {
+		if data, err := c.Load(linked); err == nil {
+			return data, nil
+		}
+	} else if data, ok := c.lookup(id); ok {
+		return data, nil
+	}
+
+	data, err := c.fetch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(data)
+	if linked != nil && !bytes.Equal(linked, digest[:]) {
+		return nil, fmt.Errorf("%w: %s does not match its hashlink", ErrIntegrity, id)
+	}
+
+	if err := c.store(id, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Schema returns the schema identified by id, see Get.
+func (c *Cache) Schema(ctx context.Context, id string) ([]byte, error) {
+	return c.Get(ctx, id)
+}
+
+// Fetch returns the resource identified by id, see Get.
+func (c *Cache) Fetch(ctx context.Context, id string) ([]byte, error) {
+	return c.Get(ctx, id)
+}
+
+// Load returns the cached artifact with the given SHA-256 digest. An artifact whose content no longer
+// matches its digest is removed and reported as ErrNotFound.
+func (c *Cache) Load(digest []byte) ([]byte, error) {
+	name := hex.EncodeToString(digest)
+	path := filepath.Join(c.dir, name)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if sum := sha256.Sum256(data); !bytes.Equal(sum[:], digest) {
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("%w: %s was corrupted", ErrNotFound, name)
+	}
+	return data, nil
+}
+
+// lookup returns the fresh artifact last fetched for id, if any.
+func (c *Cache) lookup(id string) ([]byte, bool) {
+	c.mu.Lock()
+	entry, ok := c.index[id]
+	c.mu.Unlock()
+	if !ok || (c.maxAge > 0 && c.clock.Now().Sub(entry.FetchedAt) >= c.maxAge) {
+		return nil, false
+	}
+
+	digest, err := hex.DecodeString(entry.Digest)
+	if err != nil {
+		return nil, false
+	}
+	data, err := c.Load(digest)
+	return data, err == nil
+}
+
+// store writes data under its digest and records it in the index for id.
+func (c *Cache) store(id string, data []byte) error {
+	digest := sha256.Sum256(data)
+	name := hex.EncodeToString(digest[:])
+	if err := writeFile(filepath.Join(c.dir, name), data); err != nil {
+		return fmt.Errorf("failed to cache %s: %w", id, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.index[id] = indexEntry{Digest: name, FetchedAt: c.clock.Now().UTC()}
+	index, err := json.Marshal(c.index)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(c.dir, indexFile), index); err != nil {
+		return fmt.Errorf("failed to write cache index: %w", err)
+	}
+	return nil
+}
+
+// writeFile replaces path with data atomically, so a crash never leaves a partial file behind.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// httpFetch downloads id with client.
+func httpFetch(ctx context.Context, client *http.Client, id string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxArtifactSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(body) > maxArtifactSize {
+		return nil, fmt.Errorf("artifact %s exceeds %d bytes", id, maxArtifactSize)
+	}
+	return body, nil
+}
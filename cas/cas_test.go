@@ -0,0 +1,103 @@
+package cas_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/cas"
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/schema"
+)
+
+// countingFetch serves content for every id and counts the downloads.
+func countingFetch(content string, hits *int) cas.FetchFunc {
+	return func(ctx context.Context, id string) ([]byte, error) {
+		*hits++
+		return []byte(content), nil
+	}
+}
+
+func TestCachePersists(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	var hits int
+
+	c, err := cas.Open(dir, cas.WithFetch(countingFetch(`{"type":"object"}`, &hits)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "https://schemas.example/employee"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := cas.Open(dir, cas.WithFetch(countingFetch("changed", &hits)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := reopened.Schema(ctx, "https://schemas.example/employee"); err != nil || string(data) != `{"type":"object"}` || hits != 1 {
+		t.Fatalf("after restart Schema = %q, %v with %d downloads, want the cached schema", data, err, hits)
+	}
+
+	// A corrupted artifact is detected on load and downloaded again.
+	digest := sha256.Sum256([]byte(`{"type":"object"}`))
+	if err := os.WriteFile(filepath.Join(dir, hex.EncodeToString(digest[:])), []byte("tampered"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := reopened.Get(ctx, "https://schemas.example/employee"); err != nil || string(data) != "changed" || hits != 2 {
+		t.Errorf("after corruption Get = %q, %v with %d downloads, want a fresh download", data, err, hits)
+	}
+}
+
+func TestCacheHashlinks(t *testing.T) {
+	ctx := context.Background()
+	content := []byte(`{"type":"object"}`)
+	linked, err := schema.Hashlink("https://a.example/schema", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror, _ := schema.Hashlink("https://mirror.example/schema", content)
+
+	var hits int
+	c, err := cas.Open(t.TempDir(), cas.WithFetch(countingFetch(string(content), &hits)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, linked); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, mirror); err != nil || hits != 1 {
+		t.Errorf("Get(mirror) = %v with %d downloads, want the content shared by digest", err, hits)
+	}
+
+	wrong, err := cas.Open(t.TempDir(), cas.WithFetch(countingFetch("tampered", &hits)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrong.Get(ctx, linked); !errors.Is(err, cas.ErrIntegrity) {
+		t.Errorf("Get(mismatched hashlink) error = %v, want ErrIntegrity", err)
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	var hits int
+	c, err := cas.Open(t.TempDir(), cas.WithFetch(countingFetch("logo", &hits)), cas.WithMaxAge(time.Hour), cas.WithClock(fake))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = c.Fetch(ctx, "https://example.com/logo.png")
+	_, _ = c.Fetch(ctx, "https://example.com/logo.png")
+	fake.Advance(time.Hour)
+	_, _ = c.Fetch(ctx, "https://example.com/logo.png")
+	if hits != 2 {
+		t.Errorf("downloads = %d, want 2", hits)
+	}
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// certificateThumbprintClaim is the confirmation method binding a token to a TLS client certificate
+// (RFC 8705 section 3.1).
+const certificateThumbprintClaim = "x5t#S256"
+
+// ErrChannelBinding is returned when a presentation is not bound to the TLS client certificate of the
+// connection it arrived on.
+var ErrChannelBinding = errors.New("presentation is not bound to this TLS channel")
+
+// CertificateThumbprint returns the base64url SHA-256 thumbprint of a certificate, as carried in the
+// "cnf" claim of a bound presentation.
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// WithBoundCertificate binds the VP to the holder's TLS client certificate through a
+// {"cnf": {"x5t#S256": thumbprint}} claim, so a verifier can reject it on any other mTLS connection.
+func WithBoundCertificate(cert *x509.Certificate) CreateOpt {
+	return func(o *createOptions) {
+		o.certificateThumbprint = CertificateThumbprint(cert)
+	}
+}
+
+// WithExpectedCertificate fails verification with ErrChannelBinding unless the VP is bound to cert, the
+// client certificate of the connection the VP arrived on. Behind a TLS-terminating proxy, pass the
+// certificate the proxy forwards.
+func WithExpectedCertificate(cert *x509.Certificate) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.certificateThumbprint = CertificateThumbprint(cert)
+	}
+}
+
+// checkChannelBinding compares the certificate thumbprint of the VP's "cnf" claim with the expected one.
+func checkChannelBinding(vpToken *jwtToken, thumbprint string) error {
+	cnf, _ := vpToken.payload["cnf"].(map[string]any)
+	bound := stringField(cnf, certificateThumbprintClaim)
+	if bound == "" {
+		return fmt.Errorf("%w: presentation has no certificate binding", ErrChannelBinding)
+	}
+	if bound != thumbprint {
+		return fmt.Errorf("%w: presentation is bound to certificate %s", ErrChannelBinding, bound)
+	}
+	return nil
+}
+
+// TokenFunc returns the VP token carried by a request, e.g. from its Authorization header.
+type TokenFunc func(r *http.Request) (string, error)
+
+// verifiedClaimsKey is the context key of the claims verified by ChannelBindingMiddleware.
+type verifiedClaimsKey struct{}
+
+// VerifiedClaims returns the claims of the presentation verified by ChannelBindingMiddleware for the
+// request whose context is ctx.
+func VerifiedClaims(ctx context.Context) ([]VcClaims, bool) {
+	claims, ok := ctx.Value(verifiedClaimsKey{}).([]VcClaims)
+	return claims, ok
+}
+
+// ChannelBindingMiddleware verifies the VP token returned by tokenOf on every request, with opts, and
+// requires it to be bound to the client certificate of the request's mTLS connection, so a token
+// replayed over another connection is rejected with 401 Unauthorized. The verified claims are available
+// to next through VerifiedClaims. Requests without a client certificate are rejected.
+func ChannelBindingMiddleware(a *Service, tokenOf TokenFunc, opts ...VerifyOpt) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+				http.Error(w, "client certificate required", http.StatusUnauthorized)
+				return
+			}
+
+			token, err := tokenOf(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			verifyOpts := append(opts[:len(opts):len(opts)], WithExpectedCertificate(r.TLS.PeerCertificates[0]))
+			claims, err := a.VerifyToken(r.Context(), token, verifyOpts...)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), verifiedClaimsKey{}, claims)))
+		})
+	}
+}
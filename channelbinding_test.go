@@ -0,0 +1,74 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestChannelBinding(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	clientCert, _ := newTestCertificate(t, holder.DID, nil, nil)
+	otherCert, _ := newTestCertificate(t, "did:example:attacker", nil, nil)
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address, auth.WithBoundCertificate(clientCert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unbound, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithExpectedCertificate(clientCert)); err != nil {
+		t.Errorf("bound token on its channel: %v", err)
+	}
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithExpectedCertificate(otherCert)); !errors.Is(err, auth.ErrChannelBinding) {
+		t.Errorf("bound token on another channel: error = %v, want ErrChannelBinding", err)
+	}
+	if _, err := a.VerifyToken(context.Background(), unbound, auth.WithExpectedCertificate(clientCert)); !errors.Is(err, auth.ErrChannelBinding) {
+		t.Errorf("unbound token: error = %v, want ErrChannelBinding", err)
+	}
+
+	handler := auth.ChannelBindingMiddleware(a, func(r *http.Request) (string, error) {
+		return r.Header.Get("X-VP-Token"), nil
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := auth.VerifiedClaims(r.Context()); !ok || len(claims) != 1 {
+			t.Errorf("VerifiedClaims = %v, %v", claims, ok)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	send := func(cert *x509.Certificate) int {
+		r := httptest.NewRequest(http.MethodGet, "https://api.example.com/me", nil)
+		r.Header.Set("X-VP-Token", token)
+		r.TLS = &tls.ConnectionState{}
+		if cert != nil {
+			r.TLS.PeerCertificates = []*x509.Certificate{cert}
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	if code := send(clientCert); code != http.StatusNoContent {
+		t.Errorf("request on the bound channel: status %d", code)
+	}
+	if code := send(otherCert); code != http.StatusUnauthorized {
+		t.Errorf("replay over another channel: status %d, want 401", code)
+	}
+	if code := send(nil); code != http.StatusUnauthorized {
+		t.Errorf("request without client certificate: status %d, want 401", code)
+	}
+}
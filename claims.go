@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// newVcClaims builds the typed claims of a credential from its JSON contents and JWT envelope.
+func newVcClaims(credContents map[string]any, vcToken *jwtToken) (VcClaims, error) {
+	claims := VcClaims{
+		ID:     stringField(credContents, "id"),
+		Types:  stringList(credContents["type"]),
+		Issuer: issuerID(credContents["issuer"]),
+	}
+
+	var err error
+	if claims.ValidFrom, err = timeField(credContents, "validFrom"); err != nil {
+		return VcClaims{}, err
+	}
+	if claims.ValidUntil, err = timeField(credContents, "validUntil"); err != nil {
+		return VcClaims{}, err
+	}
+
+	for _, raw := range objectList(credContents["credentialStatus"]) {
+		claims.Status = append(claims.Status, CredentialStatus{
+			ID:                   stringField(raw, "id"),
+			Type:                 stringField(raw, "type"),
+			StatusPurpose:        stringField(raw, "statusPurpose"),
+			StatusListIndex:      stringOrNumberField(raw, "statusListIndex"),
+			StatusListCredential: stringField(raw, "statusListCredential"),
+		})
+	}
+
+	for _, raw := range objectList(credContents["credentialSchema"]) {
+		claims.Schemas = append(claims.Schemas, CredentialSchema{
+			ID:   stringField(raw, "id"),
+			Type: stringField(raw, "type"),
+		})
+	}
+
+	for _, raw := range objectList(credContents["refreshService"]) {
+		claims.RefreshServices = append(claims.RefreshServices, RefreshService{
+			ID:   stringField(raw, "id"),
+			Type: stringField(raw, "type"),
+		})
+	}
+
+	for _, raw := range objectList(credContents["relatedResource"]) {
+		claims.RelatedResources = append(claims.RelatedResources, RelatedResource{
+			ID:              stringField(raw, "id"),
+			MediaType:       stringField(raw, "mediaType"),
+			DigestSRI:       stringField(raw, "digestSRI"),
+			DigestMultibase: stringField(raw, "digestMultibase"),
+		})
+	}
+
+	for _, raw := range objectList(credContents["confidenceMethod"]) {
+		claims.ConfidenceMethods = append(claims.ConfidenceMethods, ConfidenceMethod{
+			ID:             stringField(raw, "id"),
+			Type:           stringField(raw, "type"),
+			AssuranceLevel: assuranceField(raw),
+		})
+	}
+	claims.AssuranceLevel = credentialAssurance(credContents, claims.ConfidenceMethods)
+
+	if vcToken != nil {
+		claims.Proof = &ProofMetadata{
+			Format:             "JWT",
+			Algorithm:          stringField(vcToken.header, "alg"),
+			VerificationMethod: stringField(vcToken.header, "kid"),
+		}
+	}
+
+	if claims.CredentialSubject, err = parseCredentialSubjects(credContents["credentialSubject"]); err != nil {
+		return VcClaims{}, err
+	}
+
+	if claims.Typed, err = decodeTypedSubject(claims); err != nil {
+		return VcClaims{}, err
+	}
+
+	return claims, nil
+}
+
+// issuerID returns the issuer identifier, which may be a string or an object with an id.
+func issuerID(issuer any) string {
+	switch v := issuer.(type) {
+	case string:
+		return v
+	case map[string]any:
+		return stringField(v, "id")
+	}
+	return ""
+}
+
+// stringField returns obj[key] when it is a string.
+func stringField(obj map[string]any, key string) string {
+	s, _ := obj[key].(string)
+	return s
+}
+
+// stringOrNumberField returns obj[key] as a string when it is a string or a JSON number.
+func stringOrNumberField(obj map[string]any, key string) string {
+	switch v := obj[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// timeField parses obj[key] as an RFC 3339 timestamp; a missing field yields the zero time.
+func timeField(obj map[string]any, key string) (time.Time, error) {
+	s, ok := obj[key].(string)
+	if !ok || s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return t, nil
+}
+
+// subjectClaimNames returns the sorted, distinct claim names of the credentialSubject of a "vc"
+// claim, across all subjects and excluding "id".
+func subjectClaimNames(vcData map[string]any) []string {
+	subjects := []any{vcData["credentialSubject"]}
+	if list, ok := vcData["credentialSubject"].([]any); ok {
+		subjects = list
+	}
+
+	seen := map[string]bool{}
+	names := []string{}
+	for _, subject := range subjects {
+		fields, _ := subject.(map[string]any)
+		for name := range fields {
+			if name != "id" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// stringList normalizes a JSON string or array of strings into a slice.
+func stringList(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// objectList normalizes a JSON object or array of objects into a slice.
+func objectList(value any) []map[string]any {
+	switch v := value.(type) {
+	case map[string]any:
+		return []map[string]any{v}
+	case []any:
+		list := make([]map[string]any, 0, len(v))
+		for _, item := range v {
+			if obj, ok := item.(map[string]any); ok {
+				list = append(list, obj)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// ParseCredential decodes the claims of a JWT VC without verifying it, e.g. to index credentials
+// held in a wallet. Use VerifyCredential before trusting the result.
+func ParseCredential(vcJwt string) (VcClaims, error) {
+	compact, err := normalizeCredential(vcJwt)
+	if err != nil {
+		return VcClaims{}, err
+	}
+
+	vcToken, err := parseJWT(compact)
+	if err != nil {
+		return VcClaims{}, fmt.Errorf("%w: %v", ErrMalformedCredential, err)
+	}
+
+	credContents, ok := vcToken.payload["vc"].(map[string]any)
+	if !ok {
+		return VcClaims{}, fmt.Errorf("%w: vc claim not found in JWT payload", ErrMalformedCredential)
+	}
+
+	return newVcClaims(credContents, vcToken)
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdh"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// ClientInfo describes a verifier to the wallets it requests credentials from.
+type ClientInfo struct {
+	Name        string   // Name wallets display, "client_name"
+	LogoURI     string   // Logo wallets display, "logo_uri"
+	SigningKeys []KeyRef // Provider keys signing request objects, published in "jwks"
+}
+
+// VPFormat lists the algorithms a verifier accepts for one credential or presentation format.
+type VPFormat struct {
+	Alg []string `json:"alg"`
+}
+
+// ClientMetadata is the OpenID4VP client metadata of a verifier, served from its client_metadata_uri
+// or embedded in presentation requests as "client_metadata".
+type ClientMetadata struct {
+	ClientName string              `json:"client_name,omitempty"`
+	LogoURI    string              `json:"logo_uri,omitempty"`
+	VPFormats  map[string]VPFormat `json:"vp_formats"`
+	JWKS       *JWKS               `json:"jwks,omitempty"`
+}
+
+// ClientMetadata builds the client metadata of a verifier calling VerifyToken with opts, so what wallets
+// are told matches what is accepted: "vp_formats" lists the algorithms allowed by opts (see
+// WithAllowedAlgorithms) and "jwks" holds the signing keys of info, exported as by ExportJWKS, and the
+// public keys of WithDecryptionKeys for encrypting presentations to the verifier.
+func (a *Service) ClientMetadata(ctx context.Context, info ClientInfo, opts ...VerifyOpt) (*ClientMetadata, error) {
+	options := getVerifyOptions(opts...)
+
+	algorithms := append([]string(nil), options.algorithms...)
+	metadata := &ClientMetadata{
+		ClientName: info.Name,
+		LogoURI:    info.LogoURI,
+		VPFormats: map[string]VPFormat{
+			formatJWTVP: {Alg: algorithms},
+			formatJWTVC: {Alg: algorithms},
+		},
+	}
+
+	var keys []did.JWK
+	if len(info.SigningKeys) > 0 {
+		jwks, err := a.ExportJWKS(ctx, info.SigningKeys...)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwks.Keys...)
+	}
+	for _, key := range options.decryptionKeys {
+		jwk, err := encryptionJWK(key.PublicKey())
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwk)
+	}
+	if len(keys) > 0 {
+		metadata.JWKS = &JWKS{Keys: keys}
+	}
+
+	return metadata, nil
+}
+
+// encryptionJWK encodes a WithDecryptionKeys public key as a JWK for WithEncryption, identified by its
+// RFC 7638 thumbprint.
+func encryptionJWK(key *ecdh.PublicKey) (did.JWK, error) {
+	fields, err := publicKeyToJWK(key)
+	if err != nil {
+		return did.JWK{}, fmt.Errorf("failed to export decryption key: %w", err)
+	}
+
+	jwk := did.JWK{
+		Kty: stringField(fields, "kty"),
+		Crv: stringField(fields, "crv"),
+		X:   stringField(fields, "x"),
+		Y:   stringField(fields, "y"),
+		Alg: jweAlgorithm,
+		Use: "enc",
+	}
+	if jwk.Kid, err = jwk.Thumbprint(); err != nil {
+		return did.JWK{}, err
+	}
+	return jwk, nil
+}
+
+// ClientMetadataHandler serves metadata as application/json, e.g. mounted at the client_metadata_uri.
+func ClientMetadataHandler(metadata *ClientMetadata) http.Handler {
+	body, err := json.Marshal(metadata)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		_, _ = w.Write(body)
+	})
+}
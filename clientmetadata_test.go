@@ -0,0 +1,75 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+func TestClientMetadata(t *testing.T) {
+	registry := newTestRegistry(t)
+	verifier := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(verifier))
+	ctx := context.Background()
+
+	decryptionKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := a.ClientMetadata(ctx, auth.ClientInfo{
+		Name:        "Example Verifier",
+		LogoURI:     "https://verifier.example/logo.png",
+		SigningKeys: []auth.KeyRef{{KeyID: verifier.DID + "#key-1", Address: verifier.Address}},
+	}, auth.WithAllowedAlgorithms(did.AlgES256K, did.AlgEdDSA), auth.WithDecryptionKeys(decryptionKey))
+	if err != nil {
+		t.Fatalf("ClientMetadata: %v", err)
+	}
+
+	want := []string{did.AlgES256K, did.AlgEdDSA}
+	if got := metadata.VPFormats["jwt_vp_json"].Alg; !reflect.DeepEqual(got, want) {
+		t.Errorf("jwt_vp_json algorithms = %v, want %v", got, want)
+	}
+	if metadata.JWKS == nil || len(metadata.JWKS.Keys) != 2 {
+		t.Fatalf("jwks = %+v, want the signing and decryption keys", metadata.JWKS)
+	}
+	if signing := metadata.JWKS.Keys[0]; signing.Kid != verifier.DID+"#key-1" || signing.Use != "sig" {
+		t.Errorf("unexpected signing key %+v", signing)
+	}
+	if encryption := metadata.JWKS.Keys[1]; encryption.Use != "enc" || encryption.Crv != "P-256" || encryption.Kid == "" {
+		t.Errorf("unexpected encryption key %+v", encryption)
+	}
+
+	server := httptest.NewServer(auth.ClientMetadataHandler(metadata))
+	t.Cleanup(server.Close)
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var served map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&served); err != nil {
+		t.Fatal(err)
+	}
+	if served["client_name"] != "Example Verifier" || served["vp_formats"] == nil || served["jwks"] == nil {
+		t.Errorf("unexpected served metadata %v", served)
+	}
+
+	defaults, err := a.ClientMetadata(ctx, auth.ClientInfo{Name: "Example Verifier"})
+	if err != nil {
+		t.Fatalf("ClientMetadata: %v", err)
+	}
+	if got := defaults.VPFormats["jwt_vc_json"].Alg; !reflect.DeepEqual(got, []string{did.AlgES256K}) || defaults.JWKS != nil {
+		t.Errorf("default metadata = %+v, want ES256K only and no jwks", defaults)
+	}
+}
@@ -0,0 +1,114 @@
+// Package clock abstracts the time source used for token timestamps, expiry checks and retry
+// backoff, so tests can run deterministically and hosts with skewed clocks can compensate.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for durations to elapse.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the real wall clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// System returns the real wall clock. It is the default everywhere a Clock can be configured.
+func System() Clock {
+	return systemClock{}
+}
+
+// OrSystem returns c, or the system clock when c is nil.
+func OrSystem(c Clock) Clock {
+	if c == nil {
+		return System()
+	}
+	return c
+}
+
+// offsetClock shifts the time reported by another clock.
+type offsetClock struct {
+	Clock
+	offset time.Duration
+}
+
+func (c offsetClock) Now() time.Time { return c.Clock.Now().Add(c.offset) }
+
+// Offset returns a clock reporting c's time shifted by offset, e.g. to correct a host clock known
+// to run behind a trusted time source. Waiting is unaffected.
+func Offset(c Clock, offset time.Duration) Clock {
+	return offsetClock{Clock: c, offset: offset}
+}
+
+// Fake is a manually advanced Clock for tests. The zero value is not usable; use NewFake.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFake returns a Fake clock stopped at now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// After returns a channel that receives the fake time once the clock has been advanced by d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+
+	f.waiters = append(f.waiters, fakeWaiter{deadline: f.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d, firing every After whose duration has elapsed.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.deadline.After(f.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- f.now
+	}
+	f.waiters = pending
+}
+
+// Waiters returns the number of After calls still waiting, so tests can synchronize with code
+// that is about to block on the clock.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.waiters)
+}
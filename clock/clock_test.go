@@ -0,0 +1,42 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+
+	short, long := fake.After(time.Second), fake.After(time.Minute)
+	if fake.Waiters() != 2 {
+		t.Fatalf("expected 2 waiters, got %d", fake.Waiters())
+	}
+
+	fake.Advance(30 * time.Second)
+	select {
+	case now := <-short:
+		if !now.Equal(start.Add(30 * time.Second)) {
+			t.Errorf("unexpected fire time %v", now)
+		}
+	default:
+		t.Error("expected the one-second timer to fire")
+	}
+	select {
+	case <-long:
+		t.Error("the one-minute timer fired early")
+	default:
+	}
+
+	fake.Advance(30 * time.Second)
+	select {
+	case <-long:
+	default:
+		t.Error("expected the one-minute timer to fire")
+	}
+
+	if got := Offset(fake, time.Hour).Now(); !got.Equal(start.Add(time.Minute + time.Hour)) {
+		t.Errorf("unexpected offset time %v", got)
+	}
+}
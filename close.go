@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClosed is returned by signing operations started after Close.
+var ErrClosed = errors.New("auth is closed")
+
+// lifecycle tracks in-flight signing operations so Close can drain them.
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+	done     chan struct{}
+	err      error
+}
+
+// begin registers an in-flight operation, failing with ErrClosed once Close has been called.
+func (l *lifecycle) begin() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	l.inflight.Add(1)
+	return nil
+}
+
+// end marks an operation registered with begin as finished.
+func (l *lifecycle) end() {
+	l.inflight.Done()
+}
+
+// Close stops accepting new signing work, waits for in-flight signs to finish and then releases
+// the provider and idle HTTP connections. If ctx is done before the drain completes, Close returns
+// ctx.Err() and releases nothing; it may be called again to keep waiting.
+// Verification is stateless and keeps working after Close.
+func (a *Service) Close(ctx context.Context) error {
+	l := a.lifecycle
+
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		l.done = make(chan struct{})
+		go func() {
+			l.inflight.Wait()
+			l.err = a.release()
+			close(l.done)
+		}()
+	}
+	done := l.done
+	l.mu.Unlock()
+
+	select {
+	case <-done:
+		return l.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release closes the provider when it is an io.Closer and drops idle HTTP connections.
+func (a *Service) release() error {
+	a.httpClient.CloseIdleConnections()
+	if r, ok := a.resolver.(interface{ CloseIdleConnections() }); ok {
+		r.CloseIdleConnections()
+	}
+
+	if c, ok := a.provider.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+// blockingSigner holds every Sign call until release is closed and records Close.
+type blockingSigner struct {
+	*authtest.Signer
+	started chan struct{}
+	release chan struct{}
+	closed  bool
+}
+
+func (s *blockingSigner) Sign(ctx context.Context, payload []byte, options *provider.ProviderOption) ([]byte, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.Signer.Sign(ctx, payload, options)
+}
+
+func (s *blockingSigner) Close() error {
+	s.closed = true
+	return nil
+}
+
+// TestCloseDrainsInFlightSigns ensures Close waits for running signs, then closes the provider and rejects new work.
+func TestCloseDrainsInFlightSigns(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	signer := &blockingSigner{Signer: authtest.NewSigner(holder), started: make(chan struct{}), release: make(chan struct{})}
+	a := registry.newAuth(signer)
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+
+	created := make(chan error, 1)
+	go func() {
+		_, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+		created <- err
+	}()
+	<-signer.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := a.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Close to time out while a sign is in flight, got %v", err)
+	}
+	if signer.closed {
+		t.Fatal("provider closed before in-flight sign finished")
+	}
+
+	close(signer.release)
+	if err := a.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := <-created; err != nil {
+		t.Fatalf("in-flight CreateToken failed: %v", err)
+	}
+	if !signer.closed {
+		t.Fatal("provider was not closed")
+	}
+
+	if _, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address); !errors.Is(err, auth.ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
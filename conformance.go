@@ -0,0 +1,56 @@
+package auth
+
+// Conformance levels reported by ConformanceLevel.
+const (
+	ConformanceFull    = "full"    // Every required feature is supported
+	ConformancePartial = "partial" // Some required features are missing; see ConformanceReport.Features
+)
+
+// ConformanceFeature is one specification feature and whether this package supports it.
+type ConformanceFeature struct {
+	Spec      string `json:"spec"`           // Specification the feature comes from
+	Name      string `json:"name"`           // Feature name
+	Required  bool   `json:"required"`       // Whether the specification mandates it for conforming verifiers
+	Supported bool   `json:"supported"`      // Whether this package implements it
+	Note      string `json:"note,omitempty"` // Limitations or the alternative this package offers
+}
+
+// ConformanceReport describes how far this package conforms to the W3C VC specifications.
+type ConformanceReport struct {
+	Level    string               `json:"level"`
+	Features []ConformanceFeature `json:"features"`
+}
+
+// conformanceFeatures is kept in sync with the checks VerifyToken and VerifyCredential perform,
+// and with the results of the W3C test suites run through the conformance adapter.
+var conformanceFeatures = []ConformanceFeature{
+	{Spec: "vc-data-model-2.0", Name: "JSON data model (@context, type, issuer, credentialSubject)", Required: true, Supported: true},
+	{Spec: "vc-data-model-2.0", Name: "credentialSchema (JsonSchema) validation", Required: false, Supported: true, Note: "credentialSchema is mandatory for credentials verified by this package"},
+	{Spec: "vc-data-model-2.0", Name: "validFrom/validUntil enforcement", Required: true, Supported: true, Note: "JWT nbf/exp are enforced as well"},
+	{Spec: "vc-data-model-2.0", Name: "credentialStatus checking", Required: false, Supported: false, Note: "status entries are exposed in VcClaims.Status"},
+	{Spec: "vc-data-model-2.0", Name: "Data Integrity proofs", Required: false, Supported: false},
+	{Spec: "vc-jose-cose", Name: "JWT secured credentials (vc+jwt)", Required: true, Supported: true},
+	{Spec: "vc-jose-cose", Name: "JWT secured presentations (vp+jwt)", Required: true, Supported: true},
+	{Spec: "vc-jose-cose", Name: "EnvelopedVerifiableCredential / EnvelopedVerifiablePresentation", Required: true, Supported: true},
+	{Spec: "vc-jose-cose", Name: "ES256K", Required: false, Supported: true},
+	{Spec: "vc-jose-cose", Name: "ES256 / ES384 / EdDSA", Required: false, Supported: true, Note: "enabled with WithAllowedAlgorithms"},
+	{Spec: "vc-jose-cose", Name: "COSE secured credentials", Required: false, Supported: false},
+	{Spec: "vc-api", Name: "credential issuance", Required: false, Supported: true, Note: "JWT credentials, issued with IssueCredentials"},
+}
+
+// ConformanceLevel reports which W3C VC Data Model and VC-JOSE-COSE features this package supports.
+func ConformanceLevel() ConformanceReport {
+	report := ConformanceReport{
+		Level:    ConformanceFull,
+		Features: make([]ConformanceFeature, len(conformanceFeatures)),
+	}
+	copy(report.Features, conformanceFeatures)
+
+	for _, feature := range report.Features {
+		if feature.Required && !feature.Supported {
+			report.Level = ConformancePartial
+		}
+	}
+
+	return report
+}
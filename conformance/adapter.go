@@ -0,0 +1,153 @@
+package conformance
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	auth "github/hovanhoa/go-vc-auth"
+)
+
+// verifyRequest is the body of the VC-API verify endpoints.
+type verifyRequest struct {
+	VerifiableCredential   json.RawMessage `json:"verifiableCredential"`
+	VerifiablePresentation json.RawMessage `json:"verifiablePresentation"`
+	Options                map[string]any  `json:"options"`
+}
+
+// verifyResponse is the VC-API verification result.
+type verifyResponse struct {
+	Checks   []string `json:"checks"`
+	Warnings []string `json:"warnings"`
+	Errors   []string `json:"errors"`
+}
+
+// NewAdapter returns an http.Handler exposing a, through the subset of the W3C VC-API used by the
+// W3C VC Data Model 2.0 and VC-JOSE-COSE test suites:
+//
+//	POST /credentials/verify
+//	POST /presentations/verify
+//	POST /credentials/issue
+//	GET  /conformance       (the auth.ConformanceLevel report)
+//
+// Credentials and presentations are accepted as compact JWTs or as enveloped objects. Issued
+// credentials are signed through a.IssueCredentials with issueOpts, e.g. the issuer's signer address,
+// and returned as EnvelopedVerifiableCredential objects.
+// Point the test suites' implementation manifest at these endpoints to run them.
+func NewAdapter(a *auth.Service, issueOpts ...any) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /credentials/verify", func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeVerifyRequest(w, r)
+		if !ok {
+			return
+		}
+
+		token, err := rawToken(req.VerifiableCredential)
+		if err == nil {
+			_, err = a.VerifyCredential(r.Context(), token)
+		}
+		writeVerifyResult(w, err)
+	})
+
+	mux.HandleFunc("POST /presentations/verify", func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeVerifyRequest(w, r)
+		if !ok {
+			return
+		}
+
+		token, err := rawToken(req.VerifiablePresentation)
+		if err == nil {
+			_, err = a.VerifyToken(r.Context(), token)
+		}
+		writeVerifyResult(w, err)
+	})
+
+	mux.HandleFunc("POST /credentials/issue", func(w http.ResponseWriter, r *http.Request) {
+		var req issueRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Credential) == 0 {
+			http.Error(w, "invalid request body: a credential is required", http.StatusBadRequest)
+			return
+		}
+
+		document, err := auth.ParseCredentialDocument(req.Credential)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		results, err := a.IssueCredentials(r.Context(), []auth.CredentialDocument{document}, issueOpts...)
+		if err == nil {
+			err = results[0].Err
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, issueResponse{VerifiableCredential: envelopedCredential{
+			Context: []string{"https://www.w3.org/ns/credentials/v2"},
+			ID:      "data:application/vc+jwt," + results[0].Credential,
+			Type:    "EnvelopedVerifiableCredential",
+		}})
+	})
+
+	mux.HandleFunc("GET /conformance", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, auth.ConformanceLevel())
+	})
+
+	return mux
+}
+
+// issueRequest is the VC-API body of POST /credentials/issue.
+type issueRequest struct {
+	Credential json.RawMessage `json:"credential"`
+}
+
+// issueResponse is the VC-API response to POST /credentials/issue.
+type issueResponse struct {
+	VerifiableCredential envelopedCredential `json:"verifiableCredential"`
+}
+
+// envelopedCredential is a VC-JOSE-COSE EnvelopedVerifiableCredential.
+type envelopedCredential struct {
+	Context []string `json:"@context"`
+	ID      string   `json:"id"`
+	Type    string   `json:"type"`
+}
+
+func decodeVerifyRequest(w http.ResponseWriter, r *http.Request) (*verifyRequest, bool) {
+	var req verifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// rawToken returns a JSON string as the token itself and any JSON object as its raw text.
+func rawToken(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", errors.New("no credential or presentation in request")
+	}
+
+	var token string
+	if err := json.Unmarshal(raw, &token); err == nil {
+		return token, nil
+	}
+	return string(raw), nil
+}
+
+func writeVerifyResult(w http.ResponseWriter, err error) {
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, verifyResponse{Checks: []string{}, Warnings: []string{}, Errors: []string{err.Error()}})
+		return
+	}
+	writeJSON(w, http.StatusOK, verifyResponse{Checks: []string{"proof"}, Warnings: []string{}, Errors: []string{}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,104 @@
+package conformance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+)
+
+func TestAdapter(t *testing.T) {
+	f := newFixture(t)
+	credential := f.issueCredential(t)
+	adapter := NewAdapter(f.auth)
+
+	post := func(path string, body any) (int, verifyResponse) {
+		data, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		adapter.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(data))))
+
+		var resp verifyResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	enveloped := map[string]any{
+		"@context": []string{"https://www.w3.org/ns/credentials/v2"},
+		"id":       "data:application/vc+jwt," + credential,
+		"type":     "EnvelopedVerifiableCredential",
+	}
+	for name, vc := range map[string]any{"compact": credential, "enveloped": enveloped} {
+		if code, resp := post("/credentials/verify", map[string]any{"verifiableCredential": vc}); code != http.StatusOK {
+			t.Errorf("%s credential: expected 200, got %d %v", name, code, resp.Errors)
+		}
+	}
+
+	tampered := credential[:len(credential)-4] + "AAAA"
+	if code, resp := post("/credentials/verify", map[string]any{"verifiableCredential": tampered}); code != http.StatusBadRequest || len(resp.Errors) == 0 {
+		t.Errorf("tampered credential: expected 400 with errors, got %d", code)
+	}
+
+	token, err := f.auth.CreateToken(t.Context(), []string{credential}, f.holder.DID, f.holder.Address, auth.WithOutputFormat(auth.OutputEnvelopedVP))
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	if code, resp := post("/presentations/verify", map[string]any{"verifiablePresentation": json.RawMessage(token)}); code != http.StatusOK {
+		t.Errorf("presentation: expected 200, got %d %v", code, resp.Errors)
+	}
+
+	rec := httptest.NewRecorder()
+	adapter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/conformance", nil))
+	var report auth.ConformanceReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || report.Level != auth.ConformanceLevel().Level || len(report.Features) == 0 {
+		t.Errorf("unexpected conformance report: %s", rec.Body.String())
+	}
+}
+
+func TestAdapterIssue(t *testing.T) {
+	f := newFixture(t)
+	issuer := auth.NewAuth(keySigner{f.issuer.Address: f.issuer.Key}, registryURL)
+	adapter := NewAdapter(issuer, f.issuer.Address)
+
+	issue := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		adapter.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/credentials/issue", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := issue(`{"credential": {
+		"@context": ["https://www.w3.org/ns/credentials/v2"],
+		"type": ["VerifiableCredential", "EmployeeCredential"],
+		"issuer": "` + f.issuer.DID + `",
+		"credentialSchema": {"id": "` + schemaURL + `", "type": "JsonSchema"},
+		"credentialSubject": {"id": "` + f.holder.DID + `", "role": "engineer", "department": "identity"}
+	}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		VerifiableCredential json.RawMessage `json:"verifiableCredential"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	claims, err := issuer.VerifyCredential(t.Context(), string(resp.VerifiableCredential))
+	if err != nil {
+		t.Fatalf("issued credential does not verify: %v", err)
+	}
+	if claims.Issuer != f.issuer.DID || !claims.HasType("EmployeeCredential") {
+		t.Errorf("unexpected issued credential: %+v", claims)
+	}
+
+	for name, body := range map[string]string{
+		"no credential":  `{}`,
+		"invalid issuer": `{"credential": {"issuer": "not-a-did", "credentialSubject": {"id": "` + f.holder.DID + `"}}}`,
+	} {
+		if rec := issue(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/provider"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pilacorp/go-credential-sdk/credential/vc"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// Fixed test vector inputs. Nothing in the generated tokens depends on the wall clock or randomness.
+const (
+	issuerKeyHex = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	holderKeyHex = "8da4ef21b864d2cc526dbdb2a120bd2874c36c9d0a1fb7f8c63d7f7a8b41de8f"
+
+	registryURL = "https://registry.conformance.test/did"
+	schemaURL   = "https://registry.conformance.test/schemas/employee"
+	schemaJSON  = `{"type":"object","required":["credentialSubject"]}`
+)
+
+var validFrom = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// identity is a fixed key pair and its DID.
+type identity struct {
+	DID     string
+	Address string
+	Key     *ecdsa.PrivateKey
+}
+
+func newIdentity(t *testing.T, keyHex string) identity {
+	t.Helper()
+
+	key, err := crypto.HexToECDSA(keyHex)
+	if err != nil {
+		t.Fatalf("invalid test key: %v", err)
+	}
+
+	address := strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	return identity{DID: "did:nda:testnet:" + address, Address: address, Key: key}
+}
+
+func (id identity) document() *did.Document {
+	return &did.Document{
+		ID: id.DID,
+		VerificationMethod: []did.VerificationMethod{{
+			ID:           id.DID + "#key-1",
+			Type:         "EcdsaSecp256k1VerificationKey2019",
+			Controller:   id.DID,
+			PublicKeyHex: hex.EncodeToString(crypto.FromECDSAPub(&id.Key.PublicKey)),
+		}},
+		Authentication:  []string{id.DID + "#key-1"},
+		AssertionMethod: []string{id.DID + "#key-1"},
+	}
+}
+
+// fixtureTransport serves DID documents and the schema from memory, so every URL embedded in the
+// vectors is stable.
+type fixtureTransport map[string][]byte
+
+func (f fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := f[req.URL.String()]
+	status := http.StatusOK
+	if !ok {
+		status, body = http.StatusNotFound, []byte("not found")
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// keySigner signs with fixed keys; go-ethereum signatures are deterministic (RFC 6979).
+type keySigner map[string]*ecdsa.PrivateKey
+
+func (s keySigner) Sign(ctx context.Context, payload []byte, options *provider.ProviderOption) ([]byte, error) {
+	key, ok := s[options.Signer]
+	if !ok {
+		return nil, fmt.Errorf("unknown signer %v", options.Signer)
+	}
+
+	signature, err := crypto.Sign(payload, key)
+	if err != nil {
+		return nil, err
+	}
+	return signature[:64], nil
+}
+
+// vectors is the machine-readable description of the test inputs, written to testdata/vectors.json.
+type vectors struct {
+	Registry   string                    `json:"registry"`
+	Schema     map[string]any            `json:"schema"`
+	Identities map[string]vectorIdentity `json:"identities"`
+	ValidFrom  time.Time                 `json:"validFrom"`
+}
+
+type vectorIdentity struct {
+	PrivateKeyHex string        `json:"privateKeyHex"`
+	Address       string        `json:"address"`
+	Document      *did.Document `json:"document"`
+}
+
+type fixture struct {
+	issuer, holder identity
+	auth           *auth.Service
+	vectors        vectors
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	issuer := newIdentity(t, issuerKeyHex)
+	holder := newIdentity(t, holderKeyHex)
+
+	transport := fixtureTransport{schemaURL: []byte(schemaJSON)}
+	for _, id := range []identity{issuer, holder} {
+		doc, _ := json.Marshal(id.document())
+		transport[registryURL+"/"+url.PathEscape(id.DID)] = doc
+	}
+
+	previous := http.DefaultTransport
+	http.DefaultTransport = transport
+	t.Cleanup(func() { http.DefaultTransport = previous })
+
+	var schema map[string]any
+	_ = json.Unmarshal([]byte(schemaJSON), &schema)
+
+	return &fixture{
+		issuer: issuer,
+		holder: holder,
+		auth:   auth.NewAuth(keySigner{holder.Address: holder.Key}, registryURL),
+		vectors: vectors{
+			Registry: registryURL,
+			Schema:   map[string]any{"id": schemaURL, "document": schema},
+			Identities: map[string]vectorIdentity{
+				"issuer": {PrivateKeyHex: issuerKeyHex, Address: issuer.Address, Document: issuer.document()},
+				"holder": {PrivateKeyHex: holderKeyHex, Address: holder.Address, Document: holder.document()},
+			},
+			ValidFrom: validFrom,
+		},
+	}
+}
+
+// issueCredential issues the fixed employee credential through the credential SDK.
+func (f *fixture) issueCredential(t *testing.T) string {
+	t.Helper()
+
+	credential, err := vc.NewJWTCredential(vc.CredentialContents{
+		Context:   []any{"https://www.w3.org/ns/credentials/v2"},
+		ID:        "urn:uuid:6f1c2a4e-8d1b-4a53-9c1f-2b8e0e6d7a10",
+		Types:     []string{"VerifiableCredential", "EmployeeCredential"},
+		Issuer:    f.issuer.DID,
+		ValidFrom: validFrom,
+		Subject:   []vc.Subject{{ID: f.holder.DID, CustomFields: map[string]any{"role": "engineer", "department": "identity"}}},
+		Schemas:   []vc.Schema{{ID: schemaURL, Type: "JsonSchema"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create credential: %v", err)
+	}
+
+	signingInput, err := credential.GetSigningInput()
+	if err != nil {
+		t.Fatalf("failed to get signing input: %v", err)
+	}
+
+	return signJWT(t, string(signingInput), f.issuer.Key)
+}
+
+func signJWT(t *testing.T, signingInput string, key *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature[:64])
+}
+
+// golden compares got with testdata/name, rewriting the file when -update is set.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to update %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s (run with -update to create it): %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match the golden file\ngot:  %s\nwant: %s", name, got, want)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	f := newFixture(t)
+
+	data, err := json.MarshalIndent(f.vectors, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal vectors: %v", err)
+	}
+	golden(t, "vectors.json", append(data, '\n'))
+}
+
+func TestCredentialGolden(t *testing.T) {
+	f := newFixture(t)
+
+	credential := f.issueCredential(t)
+	golden(t, "credential_es256k.jwt", []byte(credential+"\n"))
+}
+
+func TestPresentationGolden(t *testing.T) {
+	f := newFixture(t)
+	credential := f.issueCredential(t)
+
+	formats := []struct {
+		name   string
+		format auth.OutputFormat
+	}{
+		{"quoted", auth.OutputQuoted},
+		{"compact", auth.OutputCompact},
+		{"jws_json", auth.OutputJWSJSON},
+		{"enveloped", auth.OutputEnvelopedVP},
+	}
+
+	for _, tt := range formats {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := f.auth.CreateToken(context.Background(), []string{credential}, f.holder.DID, f.holder.Address,
+				auth.WithOutputFormat(tt.format))
+			if err != nil {
+				t.Fatalf("CreateToken failed: %v", err)
+			}
+
+			again, err := f.auth.CreateToken(context.Background(), []string{credential}, f.holder.DID, f.holder.Address,
+				auth.WithOutputFormat(tt.format))
+			if err != nil || again != token {
+				t.Fatalf("CreateToken is not deterministic: %v", err)
+			}
+
+			golden(t, "presentation_es256k_"+tt.name+".golden", []byte(token+"\n"))
+		})
+	}
+}
+
+// TestVerifyGolden verifies every golden presentation, so tokens produced by other implementations
+// from vectors.json can be dropped into testdata and checked the same way.
+func TestVerifyGolden(t *testing.T) {
+	f := newFixture(t)
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "presentation_*.golden"))
+	if err != nil {
+		t.Fatalf("failed to list golden files: %v", err)
+	}
+	if len(paths) == 0 {
+		t.Skip("no golden presentations; run with -update first")
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			token, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", path, err)
+			}
+
+			claims, err := f.auth.VerifyToken(context.Background(), strings.TrimSpace(string(token)))
+			if err != nil {
+				t.Fatalf("VerifyToken failed: %v", err)
+			}
+
+			if len(claims) != 1 || claims[0].Issuer != f.issuer.DID || !claims[0].ValidFrom.Equal(validFrom) {
+				t.Fatalf("unexpected claims: %+v", claims)
+			}
+			if role, _ := claims[0].Subject().Get("role"); role != "engineer" {
+				t.Fatalf("unexpected role claim: %v", role)
+			}
+		})
+	}
+}
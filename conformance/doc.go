@@ -0,0 +1,14 @@
+// Package conformance holds deterministic test vectors for the tokens produced by this module.
+//
+// The tests use fixed secp256k1 keys, fixed timestamps and an in-memory DID registry, and compare
+// every credential and presentation serialization against the golden files in testdata. Any change
+// to serialization or proof construction shows up as a golden diff; run
+//
+//	go test ./conformance -update
+//
+// to accept an intended change. External implementations can cross-check against the same files:
+// testdata/vectors.json lists the keys, DID documents and schema used to produce them.
+//
+// NewAdapter exposes an Auth over the VC-API endpoints driven by the W3C VC Data Model 2.0 and
+// VC-JOSE-COSE test suites, so the official suites can be run against this module.
+package conformance
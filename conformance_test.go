@@ -0,0 +1,20 @@
+package auth_test
+
+import (
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+)
+
+func TestConformanceLevelReportsIssuance(t *testing.T) {
+	report := auth.ConformanceLevel()
+	for _, feature := range report.Features {
+		if feature.Spec == "vc-api" && feature.Name == "credential issuance" {
+			if !feature.Supported {
+				t.Errorf("expected credential issuance to be reported as supported: %+v", feature)
+			}
+			return
+		}
+	}
+	t.Error("conformance report has no credential issuance feature")
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// consentReceiptType is the "typ" header of consent receipt JWTs.
+const consentReceiptType = "consent-receipt+jwt"
+
+// ConsentReceipt records that a holder disclosed credentials to a recipient: who consented, what
+// was shared, with whom, when and why.
+type ConsentReceipt struct {
+	ID               string                // Unique receipt identifier, the "jti" claim
+	Holder           string                // DID of the consenting holder
+	Recipient        string                // Intended verifier, taken from WithAudience; may be empty
+	Purpose          string                // Purpose of the disclosure as given to WithConsentReceipt
+	IssuedAt         time.Time             // Time the presentation was created
+	Credentials      []ConsentedCredential // Credentials included in the presentation
+	PresentationHash string                // Base64url SHA-256 of the compact VP JWS, before encryption
+	JWT              string                // Receipt signed by the holder key as a compact JWS
+}
+
+// ConsentedCredential describes one credential disclosed in a presentation.
+type ConsentedCredential struct {
+	ID     string   `json:"id,omitempty"`
+	Issuer string   `json:"issuer"`
+	Types  []string `json:"type"`
+	Claims []string `json:"claims"` // Names of the disclosed credentialSubject claims
+}
+
+// ConsentSink stores consent receipts, e.g. in an audit log or database.
+// CreateToken fails when the receipt cannot be stored, so no presentation leaves without one.
+type ConsentSink interface {
+	StoreReceipt(ctx context.Context, receipt ConsentReceipt) error
+}
+
+// ConsentSinkFunc adapts a function to a ConsentSink.
+type ConsentSinkFunc func(ctx context.Context, receipt ConsentReceipt) error
+
+// StoreReceipt calls f.
+func (f ConsentSinkFunc) StoreReceipt(ctx context.Context, receipt ConsentReceipt) error {
+	return f(ctx, receipt)
+}
+
+// WithConsentReceipt makes CreateToken generate a consent receipt for the presentation, signed with
+// the holder key, and hand it to sink. purpose states why the holder shares the credentials.
+func WithConsentReceipt(sink ConsentSink, purpose string) CreateOpt {
+	return func(o *createOptions) {
+		o.consentSink = sink
+		o.consentPurpose = purpose
+	}
+}
+
+// issueConsentReceipt signs a receipt for the presentation vpJws and stores it in the configured sink.
+func (a *Service) issueConsentReceipt(ctx context.Context, holderDid string, vcTokens []*jwtToken, vpJws string, options *createOptions, providerOpts []any) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("failed to generate receipt id: %w", err)
+	}
+
+	presentationHash := sha256.Sum256([]byte(vpJws))
+	receipt := ConsentReceipt{
+		ID:               hex.EncodeToString(id),
+		Holder:           holderDid,
+		Recipient:        options.audience,
+		Purpose:          options.consentPurpose,
+		IssuedAt:         a.clock.Now().UTC().Truncate(time.Second),
+		PresentationHash: base64.RawURLEncoding.EncodeToString(presentationHash[:]),
+	}
+	for _, vcToken := range vcTokens {
+		receipt.Credentials = append(receipt.Credentials, consentedCredential(vcToken))
+	}
+
+	header := map[string]any{
+		"typ": consentReceiptType,
+		"alg": "ES256K",
+		"kid": fmt.Sprintf("%s#%s", holderDid, options.verificationMethodKey),
+	}
+	payload := map[string]any{
+		"jti":          receipt.ID,
+		"iss":          holderDid,
+		"sub":          holderDid,
+		"iat":          receipt.IssuedAt.Unix(),
+		"credentials":  receipt.Credentials,
+		"presentation": receipt.PresentationHash,
+	}
+	if receipt.Recipient != "" {
+		payload["aud"] = receipt.Recipient
+	}
+	if receipt.Purpose != "" {
+		payload["purpose"] = receipt.Purpose
+	}
+
+	signingInput, err := encodeSigningInput(header, payload)
+	if err != nil {
+		return err
+	}
+
+	if receipt.JWT, err = a.signJWT(ctx, signingInput, providerOpts...); err != nil {
+		return fmt.Errorf("failed to sign consent receipt: %w", err)
+	}
+
+	if err := options.consentSink.StoreReceipt(ctx, receipt); err != nil {
+		return fmt.Errorf("failed to store consent receipt: %w", err)
+	}
+
+	return nil
+}
+
+// consentedCredential summarizes a credential for a consent receipt without copying claim values.
+func consentedCredential(vcToken *jwtToken) ConsentedCredential {
+	vcData, _ := vcToken.payload["vc"].(map[string]any)
+
+	credential := ConsentedCredential{
+		ID:     stringField(vcData, "id"),
+		Issuer: stringField(vcToken.payload, "iss"),
+		Types:  stringList(vcData["type"]),
+		Claims: subjectClaimNames(vcData),
+	}
+	if credential.Issuer == "" {
+		credential.Issuer = issuerID(vcData["issuer"])
+	}
+
+	return credential
+}
@@ -0,0 +1,114 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestCreateTokenConsentReceipt(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer", "email": "holder@example.com"})
+
+	var receipts []auth.ConsentReceipt
+	sink := auth.ConsentSinkFunc(func(ctx context.Context, receipt auth.ConsentReceipt) error {
+		receipts = append(receipts, receipt)
+		return nil
+	})
+
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address,
+		auth.WithOutputFormat(auth.OutputCompact), auth.WithAudience("https://verifier.example"),
+		auth.WithConsentReceipt(sink, "account opening"))
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	if len(receipts) != 1 {
+		t.Fatalf("expected one receipt, got %d", len(receipts))
+	}
+	receipt := receipts[0]
+
+	if receipt.Holder != holder.DID || receipt.Recipient != "https://verifier.example" || receipt.Purpose != "account opening" {
+		t.Errorf("unexpected receipt parties: %+v", receipt)
+	}
+	if len(receipt.Credentials) != 1 || receipt.Credentials[0].Issuer != issuer.DID ||
+		!reflect.DeepEqual(receipt.Credentials[0].Claims, []string{"email", "role"}) {
+		t.Errorf("unexpected consented credentials: %+v", receipt.Credentials)
+	}
+
+	hash := sha256.Sum256([]byte(token))
+	if receipt.PresentationHash != base64.RawURLEncoding.EncodeToString(hash[:]) {
+		t.Error("receipt is not bound to the presentation")
+	}
+
+	parts := strings.Split(receipt.JWT, ".")
+	if len(parts) != 3 {
+		t.Fatalf("receipt is not a compact JWS: %s", receipt.JWT)
+	}
+	var payload map[string]any
+	payloadJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(payloadJSON, &payload); err != nil || payload["jti"] != receipt.ID || payload["purpose"] != "account opening" {
+		t.Errorf("unexpected receipt payload: %s", payloadJSON)
+	}
+	if strings.Contains(string(payloadJSON), "holder@example.com") {
+		t.Error("receipt must not copy claim values")
+	}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	signed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:64])
+	if !ecdsa.Verify(&holder.Key.PublicKey, signed[:], r, s) {
+		t.Error("receipt is not signed by the holder key")
+	}
+
+	failing := auth.ConsentSinkFunc(func(ctx context.Context, receipt auth.ConsentReceipt) error {
+		return errors.New("audit log unavailable")
+	})
+	if _, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address, auth.WithConsentReceipt(failing, "")); err == nil {
+		t.Error("expected CreateToken to fail when the receipt cannot be stored")
+	}
+}
+
+func TestConsentReceiptObjectIssuer(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+	// A credential without "iss" whose issuer is an object with an id.
+	vcJwt := craftJWT(t, issuer, nil, map[string]any{
+		"vc": map[string]any{
+			"@context":          []string{"https://www.w3.org/ns/credentials/v2"},
+			"type":              []string{"VerifiableCredential"},
+			"issuer":            map[string]any{"id": issuer.DID, "name": "Example Issuer"},
+			"credentialSubject": map[string]any{"id": holder.DID, "role": "viewer"},
+		},
+	})
+
+	var receipt auth.ConsentReceipt
+	sink := auth.ConsentSinkFunc(func(ctx context.Context, r auth.ConsentReceipt) error {
+		receipt = r
+		return nil
+	})
+	if _, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address, auth.WithConsentReceipt(sink, "")); err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	if len(receipt.Credentials) != 1 || receipt.Credentials[0].Issuer != issuer.DID {
+		t.Errorf("expected the issuer id in the receipt, got %+v", receipt.Credentials)
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// subjectDecoder decodes the JSON of a credential subject into a registered Go type.
+type subjectDecoder func(subject []byte) (any, error)
+
+var (
+	credentialTypesMu sync.RWMutex
+	credentialTypes   = map[string]subjectDecoder{}
+)
+
+// RegisterCredentialType makes claims extraction decode the credentialSubject of credentials of
+// credentialType into T, which is then available as VcClaims.Typed and through TypedClaims.
+// The subject, including its "id", is decoded with encoding/json, so T uses ordinary json tags.
+// Registering a type again replaces the previous Go type. Register types once at startup.
+func RegisterCredentialType[T any](credentialType string) {
+	credentialTypesMu.Lock()
+	defer credentialTypesMu.Unlock()
+
+	credentialTypes[credentialType] = func(subject []byte) (any, error) {
+		var typed T
+		if err := json.Unmarshal(subject, &typed); err != nil {
+			return nil, err
+		}
+		return typed, nil
+	}
+}
+
+// TypedClaims returns the decoded credentialSubject of c as T. ok is false when the credential type
+// is not registered or was registered with a different Go type.
+func TypedClaims[T any](c VcClaims) (typed T, ok bool) {
+	typed, ok = c.Typed.(T)
+	return typed, ok
+}
+
+// decodeTypedSubject decodes the first credential subject with the decoder of the first registered
+// type the credential declares. It returns nil for credentials of unregistered types.
+func decodeTypedSubject(claims VcClaims) (any, error) {
+	credentialTypesMu.RLock()
+	var (
+		decoder        subjectDecoder
+		credentialType string
+	)
+	for _, t := range claims.Types {
+		if decoder = credentialTypes[t]; decoder != nil {
+			credentialType = t
+			break
+		}
+	}
+	credentialTypesMu.RUnlock()
+
+	if decoder == nil {
+		return nil, nil
+	}
+
+	subject, err := json.Marshal(claims.Subject())
+	if err != nil {
+		return nil, err
+	}
+
+	typed, err := decoder(subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s claims: %w", credentialType, err)
+	}
+	return typed, nil
+}
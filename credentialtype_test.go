@@ -0,0 +1,78 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+type kycClaims struct {
+	ID          string `json:"id"`
+	Level       int    `json:"level"`
+	Nationality string `json:"nationality"`
+}
+
+// withTypes re-issues a credential declaring the given types.
+func withTypes(t *testing.T, vcJwt string, issuer *authtest.Identity, types ...string) string {
+	t.Helper()
+
+	parts := strings.Split(vcJwt, ".")
+	var payload map[string]any
+	payloadJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	payload["vc"].(map[string]any)["type"] = types
+	payloadJSON, _ = json.Marshal(payload)
+
+	return signJWT(t, parts[0]+"."+base64.RawURLEncoding.EncodeToString(payloadJSON), issuer.Key)
+}
+
+func TestRegisterCredentialType(t *testing.T) {
+	auth.RegisterCredentialType[kycClaims]("TestKYCCredential")
+
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	kyc := withTypes(t, registry.issueCredential(t, issuer, holder, map[string]any{"level": 2, "nationality": "VN"}),
+		issuer, "VerifiableCredential", "TestKYCCredential")
+	other := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+
+	token, err := a.CreateToken(context.Background(), []string{kyc, other}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	claims, err := a.VerifyToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("VerifyToken failed: %v", err)
+	}
+
+	typed, ok := auth.TypedClaims[kycClaims](claims[0])
+	if !ok {
+		t.Fatalf("expected typed claims, got %#v", claims[0].Typed)
+	}
+	if typed != (kycClaims{ID: holder.DID, Level: 2, Nationality: "VN"}) {
+		t.Errorf("unexpected typed claims: %+v", typed)
+	}
+
+	if claims[1].Typed != nil {
+		t.Errorf("expected no typed claims for an unregistered type, got %#v", claims[1].Typed)
+	}
+	if role, _ := claims[1].Subject().Get("role"); role != "viewer" {
+		t.Errorf("expected raw claims for an unregistered type, got %v", claims[1].CredentialSubject)
+	}
+
+	mismatched := withTypes(t, registry.issueCredential(t, issuer, holder, map[string]any{"level": "high"}),
+		issuer, "VerifiableCredential", "TestKYCCredential")
+	if _, err := a.VerifyCredential(context.Background(), mismatched); err == nil {
+		t.Error("expected claims that do not fit the registered type to be rejected")
+	}
+}
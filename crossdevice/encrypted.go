@@ -0,0 +1,107 @@
+package crossdevice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/pii"
+)
+
+// sealedFields are the personal data of a Session sealed by NewEncryptedStore.
+type sealedFields struct {
+	Claims []auth.VcClaims `json:"claims,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// encryptedStore seals the claims and error of sessions before they reach the underlying store.
+type encryptedStore struct {
+	store     Store
+	encryptor pii.Encryptor
+}
+
+// NewEncryptedStore wraps store so the verified claims and the verification error of every session,
+// which may quote them, are encrypted with encryptor, bound to the session id. The underlying store
+// holds them in Session.Sealed; the other fields stay in clear for polling and expiry.
+func NewEncryptedStore(store Store, encryptor pii.Encryptor) Store {
+	return &encryptedStore{store: store, encryptor: encryptor}
+}
+
+func (s *encryptedStore) Create(ctx context.Context, session Session) error {
+	if err := s.seal(ctx, &session); err != nil {
+		return err
+	}
+	return s.store.Create(ctx, session)
+}
+
+func (s *encryptedStore) Get(ctx context.Context, id string) (Session, error) {
+	session, err := s.store.Get(ctx, id)
+	if err != nil {
+		return Session{}, err
+	}
+	if err := s.open(ctx, &session); err != nil {
+		return Session{}, err
+	}
+	return session, nil
+}
+
+func (s *encryptedStore) Update(ctx context.Context, id string, fn func(*Session) error) (Session, error) {
+	updated, err := s.store.Update(ctx, id, func(session *Session) error {
+		if err := s.open(ctx, session); err != nil {
+			return err
+		}
+		if err := fn(session); err != nil {
+			return err
+		}
+		return s.seal(ctx, session)
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	if err := s.open(ctx, &updated); err != nil {
+		return Session{}, err
+	}
+	return updated, nil
+}
+
+func (s *encryptedStore) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	return s.store.DeleteExpired(ctx, before)
+}
+
+// seal moves the claims and error of session into Sealed.
+func (s *encryptedStore) seal(ctx context.Context, session *Session) error {
+	session.Sealed = nil
+	if len(session.Claims) == 0 && session.Error == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(sealedFields{Claims: session.Claims, Error: session.Error})
+	if err != nil {
+		return err
+	}
+	if session.Sealed, err = s.encryptor.Encrypt(ctx, data, []byte(session.ID)); err != nil {
+		return fmt.Errorf("failed to encrypt session: %w", err)
+	}
+	session.Claims, session.Error = nil, ""
+	return nil
+}
+
+// open restores the claims and error of session from Sealed.
+func (s *encryptedStore) open(ctx context.Context, session *Session) error {
+	if len(session.Sealed) == 0 {
+		return nil
+	}
+
+	data, err := s.encryptor.Decrypt(ctx, session.Sealed, []byte(session.ID))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt session: %w", err)
+	}
+	var fields sealedFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("invalid sealed session: %w", err)
+	}
+	session.Claims, session.Error, session.Sealed = fields.Claims, fields.Error, nil
+	return nil
+}
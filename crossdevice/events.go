@@ -0,0 +1,178 @@
+package crossdevice
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// websocketGUID is appended to the client key to compute Sec-WebSocket-Accept (RFC 6455 section 4.2.2).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Updates returns a channel receiving session id each time its status changes, starting with its
+// current status. The channel is closed once the session is verified, failed or expired, when ctx is
+// done, or when the session cannot be read.
+func (f *Flow) Updates(ctx context.Context, id string) <-chan Session {
+	updates := make(chan Session)
+	go func() {
+		defer close(updates)
+
+		var known Status
+		for {
+			session, err := f.Wait(ctx, id, known)
+			if err != nil {
+				return
+			}
+			select {
+			case updates <- session:
+			case <-ctx.Done():
+				return
+			}
+			if session.Status.Done() {
+				return
+			}
+			known = session.Status
+		}
+	}()
+	return updates
+}
+
+// EventsHandler streams the status of the session named by the "id" query parameter to the browser
+// as server-sent events: one "status" event, with the same JSON as StatusHandler, per change, until
+// the session is closed.
+func (f *Flow) EventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		if !f.exists(w, r, id) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		controller := http.NewResponseController(w)
+
+		for session := range f.Updates(r.Context(), id) {
+			data, err := json.Marshal(newStatusResponse(session))
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+// WebSocketHandler streams the status of the session named by the "id" query parameter to the browser
+// over a WebSocket: one text message, with the same JSON as StatusHandler, per change, then a normal
+// closure once the session is closed. Messages from the browser are ignored.
+func (f *Flow) WebSocketHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if r.Method != http.MethodGet || !headerContains(r.Header, "Connection", "upgrade") ||
+			!headerContains(r.Header, "Upgrade", "websocket") || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+			http.Error(w, "WebSocket upgrade required", http.StatusUpgradeRequired)
+			return
+		}
+
+		id := r.URL.Query().Get("id")
+		if !f.exists(w, r, id) {
+			return
+		}
+
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			http.Error(w, "WebSocket not supported", http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		accept := sha1.Sum([]byte(key + websocketGUID))
+		_, _ = fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			base64.StdEncoding.EncodeToString(accept[:]))
+		if err := rw.Flush(); err != nil {
+			return
+		}
+
+		// The browser closing the connection ends the stream.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			_, _ = io.Copy(io.Discard, rw)
+			cancel()
+		}()
+
+		for session := range f.Updates(ctx, id) {
+			data, err := json.Marshal(newStatusResponse(session))
+			if err != nil {
+				return
+			}
+			if err := writeFrame(rw.Writer, 0x1, data); err != nil {
+				return
+			}
+		}
+		_ = writeFrame(rw.Writer, 0x8, []byte{0x03, 0xe8}) // close, 1000 normal closure
+	})
+}
+
+// exists writes 404 and reports false when session id does not exist.
+func (f *Flow) exists(w http.ResponseWriter, r *http.Request, id string) bool {
+	_, err := f.Result(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// writeFrame writes an unmasked, unfragmented WebSocket frame, as servers send them.
+func writeFrame(w *bufio.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// headerContains reports whether the comma-separated header name contains token, ignoring case.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
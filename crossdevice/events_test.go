@@ -0,0 +1,222 @@
+package crossdevice_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/crossdevice"
+	"github/hovanhoa/go-vc-auth/pe"
+)
+
+// newEventsFlow returns a Flow with its event handlers served by a test server.
+func newEventsFlow(t *testing.T) (*crossdevice.Flow, *httptest.Server) {
+	t.Helper()
+
+	n := newNetwork(t)
+	verifierDid, verifierAddress := n.newDID(t)
+	flow, err := crossdevice.New(auth.NewAuth(n, n.server.URL+"/did"), crossdevice.Config{
+		ClientID:    verifierDid,
+		RequestURI:  "https://verifier.example/request",
+		ResponseURI: "https://verifier.example/response",
+		Definition:  &pe.PresentationDefinition{ID: "employment", InputDescriptors: []pe.InputDescriptor{{ID: "employee"}}},
+		SignOpts:    []any{verifierAddress},
+	}, crossdevice.WithNotifier(crossdevice.NewChannelNotifier()))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/events", flow.EventsHandler())
+	mux.Handle("/ws", flow.WebSocketHandler())
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return flow, server
+}
+
+// advance moves a session through retrieved to failed, the way a wallet answering it badly would.
+func advance(t *testing.T, flow *crossdevice.Flow, id string) {
+	t.Helper()
+
+	ctx := context.Background()
+	if _, err := flow.Retrieve(ctx, id); err != nil {
+		t.Errorf("Retrieve: %v", err)
+	}
+	if _, err := flow.Complete(ctx, id, "not-a-token"); err == nil {
+		t.Error("Complete accepted an invalid token")
+	}
+}
+
+func TestUpdates(t *testing.T) {
+	flow, _ := newEventsFlow(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session, _, err := flow.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updates := flow.Updates(ctx, session.ID)
+	if first := <-updates; first.Status != crossdevice.StatusPending {
+		t.Fatalf("first update = %q, want pending", first.Status)
+	}
+	go advance(t, flow, session.ID)
+
+	var last crossdevice.Session
+	for update := range updates {
+		last = update
+	}
+	if last.Status != crossdevice.StatusFailed {
+		t.Errorf("last update = %q, want failed", last.Status)
+	}
+}
+
+func TestEventsHandler(t *testing.T) {
+	flow, server := newEventsFlow(t)
+	session, _, err := flow.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(server.URL + "/events?id=" + session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readStatus := func() crossdevice.Status {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading events: %v", err)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var status struct{ Status crossdevice.Status }
+				if err := json.Unmarshal([]byte(data), &status); err != nil {
+					t.Fatal(err)
+				}
+				return status.Status
+			}
+		}
+	}
+
+	if got := readStatus(); got != crossdevice.StatusPending {
+		t.Fatalf("first event = %q, want pending", got)
+	}
+	advance(t, flow, session.ID)
+	// Changes in quick succession may be coalesced, so the retrieved event can be skipped.
+	got := readStatus()
+	if got == crossdevice.StatusRetrieved {
+		got = readStatus()
+	}
+	if got != crossdevice.StatusFailed {
+		t.Fatalf("last event = %q, want failed", got)
+	}
+	if rest, _ := io.ReadAll(reader); strings.Contains(string(rest), "data:") {
+		t.Errorf("events after the session closed: %q", rest)
+	}
+
+	resp, err = http.Get(server.URL + "/events?id=unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown session status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	flow, server := newEventsFlow(t)
+	session, _, err := flow.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	// Handshake example of RFC 6455 section 1.3.
+	_, _ = io.WriteString(conn, "GET /ws?id="+session.ID+" HTTP/1.1\r\nHost: verifier.example\r\nUpgrade: websocket\r\n"+
+		"Connection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+
+	readFrame := func() (byte, []byte) {
+		t.Helper()
+		var header [2]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			t.Fatalf("reading frame: %v", err)
+		}
+		length := uint64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			var ext [2]byte
+			_, _ = io.ReadFull(reader, ext[:])
+			length = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			_, _ = io.ReadFull(reader, ext[:])
+			length = binary.BigEndian.Uint64(ext[:])
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			t.Fatalf("reading frame: %v", err)
+		}
+		return header[0] & 0x0f, payload
+	}
+
+	var statuses []crossdevice.Status
+	for i := 0; ; i++ {
+		opcode, payload := readFrame()
+		if opcode == 0x8 {
+			break
+		}
+		var status struct{ Status crossdevice.Status }
+		if err := json.Unmarshal(payload, &status); err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, status.Status)
+		if i == 0 {
+			advance(t, flow, session.ID)
+		}
+	}
+
+	if len(statuses) < 2 || statuses[0] != crossdevice.StatusPending || statuses[len(statuses)-1] != crossdevice.StatusFailed {
+		t.Errorf("statuses = %v, want pending first and failed last", statuses)
+	}
+
+	plain, err := http.Get(server.URL + "/ws?id=" + session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain.Body.Close()
+	if plain.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("plain GET status = %d, want 426", plain.StatusCode)
+	}
+}
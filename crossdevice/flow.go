@@ -0,0 +1,282 @@
+package crossdevice
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/pe"
+)
+
+// ErrSessionClosed is returned when a wallet answers a session that is already verified, failed or expired.
+var ErrSessionClosed = errors.New("session is closed")
+
+// defaultLifetime is how long a wallet has to answer when Config.Lifetime is zero.
+const defaultLifetime = 5 * time.Minute
+
+// pollInterval is how often Wait re-reads the store, so it also sees updates made by other instances.
+const pollInterval = time.Second
+
+// Config describes the presentation requests of a Flow.
+type Config struct {
+	ClientID    string                     // Verifier DID; request objects are signed with its key
+	RequestURI  string                     // URL of RequestHandler, from which wallets fetch request objects
+	ResponseURI string                     // URL of ResponseHandler, to which wallets post presentations
+	Definition  *pe.PresentationDefinition // Credentials to request
+	Lifetime    time.Duration              // Time the wallet has to answer (default: 5 minutes)
+	Retention   time.Duration              // Time sessions are kept after expiring, to read results (default: Lifetime)
+	SignOpts    []any                      // Options for Service.SignPresentationRequest, e.g. the signer address
+	VerifyOpts  []auth.VerifyOpt           // Options for Service.VerifyToken, on top of the nonce and audience
+}
+
+// Flow runs cross-device presentation requests. Start creates a session and the URL to show as a QR
+// code; the wallet fetches the request from RequestHandler and posts its presentation to
+// ResponseHandler; the browser polls StatusHandler, and the backend reads the verified claims with
+// Result. A Flow is safe for concurrent use.
+type Flow struct {
+	verifier *auth.Service
+	config   Config
+	store    Store
+	notifier Notifier
+	clock    clock.Clock
+}
+
+// Option configures a Flow.
+type Option func(*Flow)
+
+// WithStore sets the session store (default: in memory).
+func WithStore(store Store) Option {
+	return func(f *Flow) {
+		f.store = store
+	}
+}
+
+// WithNotifier sets how session changes reach waiting requests (default: within the process).
+func WithNotifier(notifier Notifier) Option {
+	return func(f *Flow) {
+		f.notifier = notifier
+	}
+}
+
+// WithClock sets the time source of session expiry (default: the system clock).
+func WithClock(c clock.Clock) Option {
+	return func(f *Flow) {
+		f.clock = clock.OrSystem(c)
+	}
+}
+
+// New creates a Flow signing requests and verifying presentations with verifier.
+func New(verifier *auth.Service, config Config, opts ...Option) (*Flow, error) {
+	if config.ClientID == "" || config.RequestURI == "" || config.ResponseURI == "" {
+		return nil, errors.New("client id, request URI and response URI are required")
+	}
+	if config.Definition == nil || len(config.Definition.InputDescriptors) == 0 {
+		return nil, errors.New("presentation definition is required")
+	}
+	if config.Lifetime <= 0 {
+		config.Lifetime = defaultLifetime
+	}
+	if config.Retention <= 0 {
+		config.Retention = config.Lifetime
+	}
+
+	f := &Flow{
+		verifier: verifier,
+		config:   config,
+		clock:    clock.System(),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.store == nil {
+		f.store = NewMemoryStore()
+	}
+	if f.notifier == nil {
+		f.notifier = NewChannelNotifier()
+	}
+	return f, nil
+}
+
+// Start creates a session and returns it with the authorization request URL to encode in the QR
+// code, e.g. "openid4vp://?client_id=...&request_uri=...".
+func (f *Flow) Start(ctx context.Context) (Session, string, error) {
+	id, err := randomHex()
+	if err != nil {
+		return Session{}, "", err
+	}
+	nonce, err := randomHex()
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	requestJWT, err := f.verifier.SignPresentationRequest(ctx, auth.PresentationRequest{
+		ClientID:               f.config.ClientID,
+		ResponseMode:           "direct_post",
+		ResponseURI:            f.config.ResponseURI,
+		Nonce:                  nonce,
+		State:                  id,
+		PresentationDefinition: f.config.Definition,
+	}, append([]any{auth.WithExpiry(f.config.Lifetime)}, f.config.SignOpts...)...)
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	now := f.clock.Now().UTC()
+	session := Session{
+		ID:         id,
+		Status:     StatusPending,
+		Nonce:      nonce,
+		RequestJWT: requestJWT,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(f.config.Lifetime),
+	}
+	if err := f.store.Create(ctx, session); err != nil {
+		return Session{}, "", fmt.Errorf("failed to store session: %w", err)
+	}
+
+	query := url.Values{
+		"client_id":   {f.config.ClientID},
+		"request_uri": {f.requestURI(id)},
+	}
+	return session, "openid4vp://?" + query.Encode(), nil
+}
+
+// Result returns the session with the given id, reporting pending sessions past their lifetime as expired.
+func (f *Flow) Result(ctx context.Context, id string) (Session, error) {
+	session, err := f.store.Get(ctx, id)
+	if err != nil {
+		return Session{}, err
+	}
+	return f.expire(session), nil
+}
+
+// Retrieve returns the request object of a session for the wallet and marks the session retrieved.
+func (f *Flow) Retrieve(ctx context.Context, id string) (string, error) {
+	session, err := f.store.Update(ctx, id, func(s *Session) error {
+		if f.expire(*s).Status.Done() {
+			return ErrSessionClosed
+		}
+		s.Status = StatusRetrieved
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	_ = f.notifier.Notify(ctx, id)
+	return session.RequestJWT, nil
+}
+
+// Complete verifies the presentation a wallet posted for a session and records the outcome. It fails
+// with ErrSessionClosed, leaving the session unchanged, when the session is no longer awaiting one.
+// A presentation that does not verify closes the session as failed and its error is returned.
+func (f *Flow) Complete(ctx context.Context, id, vpToken string) (Session, error) {
+	session, err := f.Result(ctx, id)
+	if err != nil {
+		return Session{}, err
+	}
+	if session.Status.Done() {
+		return Session{}, ErrSessionClosed
+	}
+
+	verifyOpts := append([]auth.VerifyOpt{
+		auth.WithExpectedNonce(session.Nonce),
+		auth.WithExpectedAudience(f.config.ClientID),
+	}, f.config.VerifyOpts...)
+	claims, verifyErr := f.verifier.VerifyToken(ctx, vpToken, verifyOpts...)
+	if errors.Is(verifyErr, context.Canceled) || errors.Is(verifyErr, context.DeadlineExceeded) {
+		// The verifier gave up, e.g. on a registry timeout; the wallet may try again.
+		return Session{}, verifyErr
+	}
+
+	// A concurrent answer may have closed the session while this one was being verified.
+	session, err = f.store.Update(ctx, id, func(s *Session) error {
+		if f.expire(*s).Status.Done() {
+			return ErrSessionClosed
+		}
+		if verifyErr != nil {
+			s.Status, s.Error = StatusFailed, verifyErr.Error()
+			return nil
+		}
+		s.Status, s.Claims = StatusVerified, claims
+		return nil
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	_ = f.notifier.Notify(ctx, id)
+	return session, verifyErr
+}
+
+// Wait blocks until the status of a session differs from known, or the session expires, and returns it.
+// It returns the session unchanged when ctx is done first, together with the context error.
+func (f *Flow) Wait(ctx context.Context, id string, known Status) (Session, error) {
+	// Subscribing before reading the session ensures a change in between is not missed.
+	changed, unsubscribe := f.notifier.Subscribe(ctx, id)
+	defer unsubscribe()
+
+	for {
+		session, err := f.Result(ctx, id)
+		if err != nil || session.Status != known || session.Status.Done() {
+			return session, err
+		}
+
+		wait := min(pollInterval, session.ExpiresAt.Sub(f.clock.Now()))
+		select {
+		case <-ctx.Done():
+			return session, ctx.Err()
+		case <-changed:
+		case <-f.clock.After(wait):
+		}
+	}
+}
+
+// Sweep removes the sessions that expired more than Config.Retention ago and returns how many it removed.
+func (f *Flow) Sweep(ctx context.Context) (int, error) {
+	return f.store.DeleteExpired(ctx, f.clock.Now().Add(-f.config.Retention))
+}
+
+// RunSweeper calls Sweep every interval until ctx is done.
+func (f *Flow) RunSweeper(ctx context.Context, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.clock.After(interval):
+			_, _ = f.Sweep(ctx)
+		}
+	}
+}
+
+// expire reports a session awaiting the wallet past its lifetime as expired.
+func (f *Flow) expire(session Session) Session {
+	if !session.Status.Done() && !f.clock.Now().Before(session.ExpiresAt) {
+		session.Status = StatusExpired
+	}
+	return session
+}
+
+// requestURI returns the URL the wallet fetches the request object of session id from.
+func (f *Flow) requestURI(id string) string {
+	u, err := url.Parse(f.config.RequestURI)
+	if err != nil {
+		return f.config.RequestURI
+	}
+	query := u.Query()
+	query.Set("id", id)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func randomHex() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
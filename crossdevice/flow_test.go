@@ -0,0 +1,237 @@
+package crossdevice_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/crossdevice"
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/pe"
+	"github/hovanhoa/go-vc-auth/provider"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// network is a DID registry and a signer holding the keys of the DIDs it publishes.
+type network struct {
+	server *httptest.Server
+	mu     sync.Mutex
+	docs   map[string]*did.Document
+	keys   map[string]*ecdsa.PrivateKey // by address
+}
+
+func newNetwork(t *testing.T) *network {
+	n := &network{docs: map[string]*did.Document{}, keys: map[string]*ecdsa.PrivateKey{}}
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/schemas/test" {
+			_, _ = w.Write([]byte(`{"type":"object"}`))
+			return
+		}
+		id, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/did/"))
+		n.mu.Lock()
+		doc, ok := n.docs[id]
+		n.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+// newDID publishes a new DID and returns it with the signer address of its key.
+func (n *network) newDID(t *testing.T) (string, string) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	id := "did:nda:testnet:" + address
+
+	n.mu.Lock()
+	n.keys[address] = key
+	n.docs[id] = &did.Document{
+		ID: id,
+		VerificationMethod: []did.VerificationMethod{{
+			ID:           id + "#key-1",
+			Type:         "EcdsaSecp256k1VerificationKey2019",
+			Controller:   id,
+			PublicKeyHex: hex.EncodeToString(crypto.FromECDSAPub(&key.PublicKey)),
+		}},
+		Authentication:  []string{id + "#key-1"},
+		AssertionMethod: []string{id + "#key-1"},
+	}
+	n.mu.Unlock()
+	return id, address
+}
+
+func (n *network) Sign(ctx context.Context, payload []byte, options *provider.ProviderOption) ([]byte, error) {
+	n.mu.Lock()
+	key, ok := n.keys[options.Signer]
+	n.mu.Unlock()
+	if !ok {
+		return nil, errors.New("unknown signer")
+	}
+	signature, err := crypto.Sign(payload, key)
+	if err != nil {
+		return nil, err
+	}
+	return signature[:64], nil
+}
+
+// credentials is a CredentialSource offering the same credentials for every descriptor.
+type credentials []string
+
+func (c credentials) Select(ctx context.Context, descriptor pe.InputDescriptor) ([]string, error) {
+	return c, nil
+}
+
+func TestFlow(t *testing.T) {
+	n := newNetwork(t)
+	issuerDid, issuerAddress := n.newDID(t)
+	holderDid, holderAddress := n.newDID(t)
+	verifierDid, verifierAddress := n.newDID(t)
+	ctx := context.Background()
+
+	a := auth.NewAuth(n, n.server.URL+"/did")
+	issued, err := a.IssueCredentials(ctx, []auth.CredentialDocument{{
+		Issuer:  issuerDid,
+		Subject: map[string]any{"id": holderDid, "role": "employee"},
+		Schemas: []auth.CredentialSchema{{ID: n.server.URL + "/schemas/test", Type: "JsonSchema"}},
+	}}, issuerAddress)
+	if err != nil || issued[0].Err != nil {
+		t.Fatalf("IssueCredentials: %v %v", err, issued[0].Err)
+	}
+
+	fake := clock.NewFake(time.Now())
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	flow, err := crossdevice.New(a, crossdevice.Config{
+		ClientID:    verifierDid,
+		RequestURI:  server.URL + "/request",
+		ResponseURI: server.URL + "/response",
+		Definition:  &pe.PresentationDefinition{ID: "employment", InputDescriptors: []pe.InputDescriptor{{ID: "employee"}}},
+		Lifetime:    time.Minute,
+		SignOpts:    []any{verifierAddress},
+	}, crossdevice.WithClock(fake))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	mux.Handle("/request", flow.RequestHandler())
+	mux.Handle("/response", flow.ResponseHandler())
+	mux.Handle("/status", flow.StatusHandler())
+
+	session, qr, err := flow.Start(ctx)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// The browser long-polls while the wallet scans the QR code.
+	statuses := make(chan crossdevice.Status, 2)
+	go func() {
+		known := crossdevice.StatusPending
+		for range 2 {
+			resp, err := http.Get(server.URL + "/status?id=" + session.ID + "&status=" + string(known) + "&wait=10s")
+			if err != nil {
+				close(statuses)
+				return
+			}
+			var status struct{ Status crossdevice.Status }
+			_ = json.NewDecoder(resp.Body).Decode(&status)
+			resp.Body.Close()
+			statuses <- status.Status
+			known = status.Status
+		}
+	}()
+
+	// The wallet follows the QR code to the request object and answers it.
+	qrURL, err := url.Parse(qr)
+	if err != nil || qrURL.Query().Get("client_id") != verifierDid {
+		t.Fatalf("unexpected QR URL %q", qr)
+	}
+	resp, err := http.Get(qrURL.Query().Get("request_uri"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestJWT, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got := <-statuses; got != crossdevice.StatusRetrieved {
+		t.Fatalf("status after the request was fetched = %q, want retrieved", got)
+	}
+
+	response, err := a.RespondToRequest(ctx, string(requestJWT), credentials{issued[0].Credential}, holderDid, holderAddress)
+	if err != nil {
+		t.Fatalf("RespondToRequest: %v", err)
+	}
+	resp, err = http.PostForm(response.ResponseURI, url.Values{"vp_token": {response.VPToken}, "state": {response.State}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("response status = %d", resp.StatusCode)
+	}
+	if got := <-statuses; got != crossdevice.StatusVerified {
+		t.Fatalf("status after the wallet answered = %q, want verified", got)
+	}
+
+	result, err := flow.Result(ctx, session.ID)
+	if err != nil || result.Status != crossdevice.StatusVerified || len(result.Claims) != 1 || result.Claims[0].Issuer != issuerDid {
+		t.Fatalf("Result = %+v, %v", result, err)
+	}
+
+	// A replayed response cannot reopen the session.
+	if _, err := flow.Complete(ctx, session.ID, response.VPToken); !errors.Is(err, crossdevice.ErrSessionClosed) {
+		t.Errorf("replayed Complete = %v, want ErrSessionClosed", err)
+	}
+
+	// A presentation bound to another session fails this one.
+	other, _, err := flow.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := flow.Complete(ctx, other.ID, response.VPToken); err == nil {
+		t.Error("presentation for another session was accepted")
+	}
+	if result, _ := flow.Result(ctx, other.ID); result.Status != crossdevice.StatusFailed || result.Error == "" {
+		t.Errorf("session answered with a foreign presentation = %+v, want failed", result)
+	}
+
+	// Unanswered sessions expire, then are swept after the retention period.
+	pending, _, err := flow.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(time.Minute)
+	if result, _ := flow.Result(ctx, pending.ID); result.Status != crossdevice.StatusExpired {
+		t.Errorf("status after the lifetime = %q, want expired", result.Status)
+	}
+	if _, err := flow.Retrieve(ctx, pending.ID); !errors.Is(err, crossdevice.ErrSessionClosed) {
+		t.Errorf("Retrieve of an expired session = %v, want ErrSessionClosed", err)
+	}
+
+	fake.Advance(time.Minute)
+	if removed, err := flow.Sweep(ctx); err != nil || removed != 3 {
+		t.Errorf("Sweep = %d, %v, want 3 sessions removed", removed, err)
+	}
+	if _, err := flow.Result(ctx, session.ID); !errors.Is(err, crossdevice.ErrNotFound) {
+		t.Errorf("Result after sweeping = %v, want ErrNotFound", err)
+	}
+}
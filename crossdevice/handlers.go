@@ -0,0 +1,134 @@
+package crossdevice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// maxLongPoll bounds how long StatusHandler holds a request open.
+const maxLongPoll = 30 * time.Second
+
+// RequestHandler serves the request object of the session named by the "id" query parameter to the
+// wallet, as application/oauth-authz-req+jwt. Mount it at Config.RequestURI.
+func (f *Flow) RequestHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		requestJWT, err := f.Retrieve(r.Context(), r.URL.Query().Get("id"))
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrSessionClosed) {
+			http.Error(w, "unknown or closed session", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/oauth-authz-req+jwt")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write([]byte(requestJWT))
+	})
+}
+
+// ResponseHandler receives the wallet's direct_post response, with the vp_token and state form fields,
+// and verifies it. Mount it at Config.ResponseURI.
+func (f *Flow) ResponseHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, vpToken := r.PostFormValue("state"), r.PostFormValue("vp_token")
+		if id == "" || vpToken == "" {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+
+		_, err := f.Complete(r.Context(), id, vpToken)
+		switch {
+		case err == nil:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte("{}"))
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrSessionClosed):
+			writeJSONError(w, http.StatusBadRequest, "invalid_request")
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			writeJSONError(w, http.StatusServiceUnavailable, "temporarily_unavailable")
+		default:
+			// The session is closed as failed; the wallet only learns that the presentation was refused.
+			writeJSONError(w, http.StatusBadRequest, "invalid_presentation")
+		}
+	})
+}
+
+// statusResponse is the body of StatusHandler and of the messages of EventsHandler and WebSocketHandler.
+// Claims are left to the backend, which reads them with Result.
+type statusResponse struct {
+	ID        string    `json:"id"`
+	Status    Status    `json:"status"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func newStatusResponse(session Session) statusResponse {
+	return statusResponse{ID: session.ID, Status: session.Status, ExpiresAt: session.ExpiresAt}
+}
+
+// StatusHandler reports the status of the session named by the "id" query parameter to the browser.
+// With a "status" parameter, the request is held open until the status differs from it, for up to the
+// "wait" parameter (a Go duration, at most 30s), so the browser can long-poll instead of polling.
+func (f *Flow) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		id := query.Get("id")
+
+		var session Session
+		var err error
+		if known := Status(query.Get("status")); known != "" {
+			wait := maxLongPoll
+			if d, parseErr := time.ParseDuration(query.Get("wait")); parseErr == nil && d >= 0 && d < wait {
+				wait = d
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), wait)
+			session, err = f.Wait(ctx, id, known)
+			cancel()
+			if errors.Is(err, context.DeadlineExceeded) && r.Context().Err() == nil {
+				err = nil
+			}
+		} else {
+			session, err = f.Result(r.Context(), id)
+		}
+
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(newStatusResponse(session))
+	})
+}
+
+func writeJSONError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
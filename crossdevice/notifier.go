@@ -0,0 +1,64 @@
+package crossdevice
+
+import (
+	"context"
+	"sync"
+)
+
+// Notifier signals session changes from the instance that made them to the instances waiting on them,
+// so a browser long-polling or streaming from one instance learns at once that the wallet answered
+// another. Implement it over e.g. Redis pub/sub for several instances; the store is also polled every
+// second, so a Notifier only affects latency. Implementations must be safe for concurrent use.
+type Notifier interface {
+	// Subscribe returns a channel receiving a value whenever session id changes, and a function
+	// ending the subscription. Notifications may be coalesced but never block the notifier.
+	Subscribe(ctx context.Context, id string) (<-chan struct{}, func())
+	// Notify signals that session id changed.
+	Notify(ctx context.Context, id string) error
+}
+
+// channelNotifier delivers notifications within the process over channels.
+type channelNotifier struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+}
+
+// NewChannelNotifier returns a Notifier delivering notifications within the process, suitable for a single instance.
+func NewChannelNotifier() Notifier {
+	return &channelNotifier{subscribers: map[string]map[chan struct{}]struct{}{}}
+}
+
+func (n *channelNotifier) Subscribe(ctx context.Context, id string) (<-chan struct{}, func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// One buffered slot is enough: a pending notification already tells the subscriber to re-read the session.
+	changed := make(chan struct{}, 1)
+	if n.subscribers[id] == nil {
+		n.subscribers[id] = map[chan struct{}]struct{}{}
+	}
+	n.subscribers[id][changed] = struct{}{}
+
+	return changed, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		delete(n.subscribers[id], changed)
+		if len(n.subscribers[id]) == 0 {
+			delete(n.subscribers, id)
+		}
+	}
+}
+
+func (n *channelNotifier) Notify(ctx context.Context, id string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for changed := range n.subscribers[id] {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
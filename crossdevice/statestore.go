@@ -0,0 +1,88 @@
+package crossdevice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/state"
+)
+
+// stateStore keeps sessions in a state.Store as JSON, leaving their removal to the backend's expiry.
+type stateStore struct {
+	store     state.Store
+	retention time.Duration
+}
+
+// NewStateStore creates a Store over a state.Store, so sessions share the backend of the other
+// stores. Sessions are dropped by the backend once retention has passed after they expire, which
+// should match Config.Retention; DeleteExpired is then a no-op.
+func NewStateStore(store state.Store, retention time.Duration) Store {
+	return &stateStore{store: store, retention: retention}
+}
+
+func (s *stateStore) Create(ctx context.Context, session Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	err = s.store.Create(ctx, "session:"+session.ID, data, s.ttl(session))
+	if errors.Is(err, state.ErrExists) {
+		return errors.New("session already exists")
+	}
+	return err
+}
+
+func (s *stateStore) Get(ctx context.Context, id string) (Session, error) {
+	data, err := s.store.Get(ctx, "session:"+id)
+	if errors.Is(err, state.ErrNotFound) {
+		return Session{}, ErrNotFound
+	}
+	if err != nil {
+		return Session{}, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return Session{}, err
+	}
+	return session, nil
+}
+
+func (s *stateStore) Update(ctx context.Context, id string, fn func(*Session) error) (Session, error) {
+	// Updates do not move ExpiresAt, so the current session gives the expiry to keep.
+	current, err := s.Get(ctx, id)
+	if err != nil {
+		return Session{}, err
+	}
+
+	var updated Session
+	_, err = state.Update(ctx, s.store, "session:"+id, s.ttl(current), func(data []byte) ([]byte, error) {
+		var session Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			return nil, err
+		}
+		if err := fn(&session); err != nil {
+			return nil, err
+		}
+		updated = session
+		return json.Marshal(session)
+	})
+	if errors.Is(err, state.ErrNotFound) {
+		return Session{}, ErrNotFound
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	return updated, nil
+}
+
+func (s *stateStore) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	return 0, nil
+}
+
+// ttl keeps session until retention has passed after it expires.
+func (s *stateStore) ttl(session Session) time.Duration {
+	return max(time.Until(session.ExpiresAt)+s.retention, time.Millisecond)
+}
@@ -0,0 +1,78 @@
+package crossdevice_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/crossdevice"
+	"github/hovanhoa/go-vc-auth/pii"
+	"github/hovanhoa/go-vc-auth/secret"
+	"github/hovanhoa/go-vc-auth/state"
+)
+
+func TestStateStore(t *testing.T) {
+	ctx := context.Background()
+	store := crossdevice.NewStateStore(state.NewMemoryStore(), time.Minute)
+
+	session := crossdevice.Session{ID: "s1", Status: crossdevice.StatusPending, Nonce: "n", CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Minute)}
+	if err := store.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Create(ctx, session); err == nil {
+		t.Error("Create accepted a duplicate session")
+	}
+
+	updated, err := store.Update(ctx, "s1", func(s *crossdevice.Session) error {
+		s.Status = crossdevice.StatusRetrieved
+		return nil
+	})
+	if err != nil || updated.Status != crossdevice.StatusRetrieved {
+		t.Fatalf("Update = %+v, %v", updated, err)
+	}
+	if got, err := store.Get(ctx, "s1"); err != nil || got.Status != crossdevice.StatusRetrieved || got.Nonce != "n" {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+
+	abort := errors.New("abort")
+	if _, err := store.Update(ctx, "s1", func(*crossdevice.Session) error { return abort }); !errors.Is(err, abort) {
+		t.Errorf("aborted Update error = %v", err)
+	}
+	if _, err := store.Get(ctx, "unknown"); !errors.Is(err, crossdevice.ErrNotFound) {
+		t.Errorf("Get(unknown) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	ctx := context.Background()
+	inner := crossdevice.NewMemoryStore()
+	enc, err := pii.NewAESGCM(secret.FromBytes(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := crossdevice.NewEncryptedStore(inner, enc)
+
+	session := crossdevice.Session{ID: "s1", Status: crossdevice.StatusPending, ExpiresAt: time.Now().Add(time.Minute)}
+	if err := store.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := store.Update(ctx, "s1", func(s *crossdevice.Session) error {
+		s.Status = crossdevice.StatusVerified
+		s.Claims = []auth.VcClaims{{Issuer: "did:nda:testnet:0x1", CredentialSubject: []auth.CredentialSubject{{Claims: map[string]any{"name": "Nguyen Van A"}}}}}
+		return nil
+	})
+	if err != nil || len(updated.Claims) != 1 {
+		t.Fatalf("Update = %+v, %v", updated, err)
+	}
+
+	stored, _ := inner.Get(ctx, "s1")
+	if len(stored.Claims) != 0 || len(stored.Sealed) == 0 || stored.Status != crossdevice.StatusVerified {
+		t.Errorf("stored session is not sealed: %+v", stored)
+	}
+	got, err := store.Get(ctx, "s1")
+	if err != nil || len(got.Claims) != 1 || got.Claims[0].Subject().Claims["name"] != "Nguyen Van A" || got.Sealed != nil {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package crossdevice runs the cross-device OpenID4VP flow: a browser shows a QR code, the holder's
+// wallet on another device answers the request, and the browser learns the outcome by polling.
+package crossdevice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+)
+
+// ErrNotFound is returned for sessions that do not exist or were swept after expiring.
+var ErrNotFound = errors.New("session not found")
+
+// Status is the stage of a Session.
+type Status string
+
+// Session statuses. A session moves from pending to retrieved when the wallet fetches the request
+// object, then to verified or failed when the wallet posts its presentation. Pending and retrieved
+// sessions become expired once their lifetime has passed.
+const (
+	StatusPending   Status = "pending"
+	StatusRetrieved Status = "retrieved"
+	StatusVerified  Status = "verified"
+	StatusFailed    Status = "failed"
+	StatusExpired   Status = "expired"
+)
+
+// Done reports whether s is final.
+func (s Status) Done() bool {
+	return s == StatusVerified || s == StatusFailed || s == StatusExpired
+}
+
+// Session is one presentation request awaiting a wallet.
+type Session struct {
+	ID         string          `json:"id"`               // Session id, sent to the wallet as the request "state"
+	Status     Status          `json:"status"`           // Current stage
+	Nonce      string          `json:"nonce"`            // Nonce the presentation must be bound to
+	RequestJWT string          `json:"requestJwt"`       // Signed request object served to the wallet
+	Claims     []auth.VcClaims `json:"claims,omitempty"` // Verified credentials, once verified
+	Error      string          `json:"error,omitempty"`  // Verification error, once failed
+	Sealed     []byte          `json:"sealed,omitempty"` // Claims and Error as encrypted by NewEncryptedStore, in the store only
+	CreatedAt  time.Time       `json:"createdAt"`
+	ExpiresAt  time.Time       `json:"expiresAt"`
+}
+
+// Store persists sessions, e.g. in Redis so that any instance behind a load balancer can serve
+// the wallet and the browser. Implementations must be safe for concurrent use.
+type Store interface {
+	// Create stores a new session.
+	Create(ctx context.Context, session Session) error
+	// Get returns the session with the given id, or ErrNotFound.
+	Get(ctx context.Context, id string) (Session, error)
+	// Update applies fn to the session with the given id and stores the result, atomically with
+	// respect to other updates of the session. An error from fn aborts the update and is returned.
+	Update(ctx context.Context, id string, fn func(*Session) error) (Session, error)
+	// DeleteExpired removes the sessions whose ExpiresAt is not after before and returns how many it removed.
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
+
+// memoryStore keeps sessions in memory.
+type memoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewMemoryStore creates an in-memory Store, suitable for a single instance.
+func NewMemoryStore() Store {
+	return &memoryStore{sessions: map[string]Session{}}
+}
+
+func (s *memoryStore) Create(ctx context.Context, session Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[session.ID]; ok {
+		return errors.New("session already exists")
+	}
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, id string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	return session, nil
+}
+
+func (s *memoryStore) Update(ctx context.Context, id string, fn func(*Session) error) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+	if err := fn(&session); err != nil {
+		return Session{}, err
+	}
+	s.sessions[id] = session
+	return session, nil
+}
+
+func (s *memoryStore) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int
+	for id, session := range s.sessions {
+		if !session.ExpiresAt.After(before) {
+			delete(s.sessions, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// StaleResolution is a DID document a verification used from the cache because the registry was unavailable.
+type StaleResolution struct {
+	DID   string        // DID whose cached document was used
+	Age   time.Duration // Time since the document was fetched from the registry
+	Cause string        // Resolution error the cached document stood in for
+}
+
+// Degradation reports the stale data a verification relied on. It is safe for concurrent use.
+// See WithDegradedMode and WithDegradationReport.
+type Degradation struct {
+	mu    sync.Mutex
+	stale []StaleResolution
+}
+
+// Degraded reports whether the verification used any stale data.
+func (d *Degradation) Degraded() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.stale) > 0
+}
+
+// Stale returns a copy of the stale resolutions in the order they happened.
+func (d *Degradation) Stale() []StaleResolution {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]StaleResolution(nil), d.stale...)
+}
+
+// WithDegradedMode keeps resolved DID documents for cacheTTL and, when the DID registry is unavailable,
+// falls back to cached documents up to maxStale past their TTL instead of failing verification.
+// Verifications relying on stale documents are reported through WithDegradationReport and traced as
+// StepDegraded. Only registry outages are masked: a DID the registry reports as unknown still fails.
+// A zero cacheTTL resolves every DID against the registry and only uses the cache as a fallback.
+func WithDegradedMode(cacheTTL, maxStale time.Duration) Option {
+	return func(a *Service) {
+		a.degradedMode = &degradedMode{ttl: cacheTTL, maxStale: maxStale}
+	}
+}
+
+// WithDegradationReport makes VerifyToken and VerifyCredential record in report the stale data they
+// accepted in degraded mode, so callers can e.g. shorten the session or flag the login for review.
+func WithDegradationReport(report *Degradation) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.degradation = report
+	}
+}
+
+// degradedMode is the configuration given to WithDegradedMode.
+type degradedMode struct {
+	ttl      time.Duration
+	maxStale time.Duration
+}
+
+// resolver wraps next with the stale-cache fallback.
+func (m *degradedMode) resolver(next did.Resolver, a *Service) did.Resolver {
+	return &degradingResolver{cache: did.NewCachingResolver(next, m.ttl, m.maxStale, did.WithCacheClock(a.clock))}
+}
+
+// degradingResolver resolves through a CachingResolver and reports stale documents to the context.
+type degradingResolver struct {
+	cache *did.CachingResolver
+}
+
+func (r *degradingResolver) Resolve(ctx context.Context, id string) (*did.Document, error) {
+	doc, metadata, err := r.cache.ResolveWithMetadata(ctx, id)
+	if err != nil || !metadata.Stale {
+		return doc, err
+	}
+
+	traceStep(ctx, StepDegraded, nil, "did", id, "age", metadata.Age.String(), "cause", metadata.Cause.Error())
+	if report, ok := ctx.Value(degradationKey{}).(*Degradation); ok {
+		report.mu.Lock()
+		report.stale = append(report.stale, StaleResolution{DID: id, Age: metadata.Age, Cause: metadata.Cause.Error()})
+		report.mu.Unlock()
+	}
+	return doc, nil
+}
+
+func (r *degradingResolver) CloseIdleConnections() {
+	r.cache.CloseIdleConnections()
+}
+
+// degradationKey is the context key of the Degradation of the running verification.
+type degradationKey struct{}
+
+// withDegradation returns ctx reporting stale data to report; it returns ctx unchanged when report is nil.
+func withDegradation(ctx context.Context, report *Degradation) context.Context {
+	if report == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, degradationKey{}, report)
+}
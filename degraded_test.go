@@ -0,0 +1,103 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+func TestDegradedMode(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	// The verifier reaches the registry through a proxy that can simulate an outage.
+	var down, forgotten atomic.Bool
+	target, _ := url.Parse(registry.serve(t))
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	outage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "registry down", http.StatusServiceUnavailable)
+			return
+		}
+		if forgotten.Load() && strings.HasSuffix(r.URL.Path, holder.DID) {
+			http.NotFound(w, r)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(outage.Close)
+
+	fake := clock.NewFake(time.Now())
+	a := auth.NewAuth(authtest.NewSigner(holder), outage.URL, auth.WithSchemaSource(authtest.Schemas()), auth.WithClock(fake), auth.WithDegradedMode(0, time.Hour))
+	strict := auth.NewAuth(nil, outage.URL, auth.WithSchemaSource(authtest.Schemas()), auth.WithClock(fake))
+	ctx := context.Background()
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(ctx, []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+
+	var report auth.Degradation
+	if _, err := a.VerifyToken(ctx, token, auth.WithDegradationReport(&report)); err != nil {
+		t.Fatalf("VerifyToken with the registry up: %v", err)
+	}
+	if report.Degraded() {
+		t.Errorf("fresh verification reported as degraded: %+v", report.Stale())
+	}
+
+	down.Store(true)
+	fake.Advance(30 * time.Minute)
+
+	if _, err := strict.VerifyToken(ctx, token); err == nil {
+		t.Error("verification without degraded mode succeeded during the outage")
+	}
+
+	var trace auth.VerificationTrace
+	report = auth.Degradation{}
+	if _, err := a.VerifyToken(ctx, token, auth.WithDegradationReport(&report), auth.WithTrace(&trace)); err != nil {
+		t.Fatalf("VerifyToken during the outage: %v", err)
+	}
+	stale := report.Stale()
+	if len(stale) != 2 || stale[0].DID != holder.DID || stale[1].DID != issuer.DID {
+		t.Fatalf("stale resolutions = %+v, want the holder then the issuer", stale)
+	}
+	if stale[0].Age != 30*time.Minute || stale[0].Cause == "" {
+		t.Errorf("stale resolution = %+v, want an age of 30m and a cause", stale[0])
+	}
+	var traced int
+	for _, step := range trace.Steps() {
+		if step.Step == auth.StepDegraded {
+			traced++
+		}
+	}
+	if traced != 2 {
+		t.Errorf("trace has %d degraded steps, want 2", traced)
+	}
+
+	fake.Advance(time.Hour)
+	if _, err := a.VerifyToken(ctx, token); err == nil {
+		t.Error("verification succeeded with documents older than the staleness window")
+	}
+
+	// A registry answering that the DID is unknown is not an outage.
+	down.Store(false)
+	if _, err := a.VerifyToken(ctx, token); err != nil {
+		t.Fatalf("VerifyToken after the outage: %v", err)
+	}
+	forgotten.Store(true)
+	if _, err := a.VerifyToken(ctx, token); err == nil {
+		t.Error("cached document used for a DID the registry no longer knows")
+	}
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/provider"
+	"github/hovanhoa/go-vc-auth/schema"
+)
+
+// DemoSchemaURL is the credentialSchema id of the credentials issued by NewDemoEnvironment. Its schema is
+// bundled: it requires a credential subject with a "role".
+const DemoSchemaURL = "https://schemas.demo.invalid/role"
+
+// demoSchema is the schema served under DemoSchemaURL.
+const demoSchema = `{"type":"object","required":["credentialSubject"],"properties":{"credentialSubject":{"type":"object","required":["role"]}}}`
+
+// DemoIdentity is an ephemeral did:nda:testnet identity of a DemoEnvironment.
+type DemoIdentity struct {
+	DID      string
+	Address  string
+	Provider *provider.LocalProvider // Signs with the identity's in-memory key
+}
+
+// DemoEnvironment wires issuer, holder and verifier services to ephemeral identities, an in-memory DID
+// registry and a bundled schema, so examples and integration tests run the whole issuance and
+// presentation flow without Vault, a DID registry or any other network service.
+type DemoEnvironment struct {
+	Issuer DemoIdentity
+	Holder DemoIdentity
+
+	IssuerAuth   *Service // Signs credentials with the issuer key
+	HolderAuth   *Service // Signs presentations with the holder key
+	VerifierAuth *Service // Verifies presentations; it has no provider
+
+	Credential string              // A JWT VC from Issuer to Holder with the claim role "member"
+	Registry   *did.MemoryRegistry // Resolves the DIDs of the environment
+}
+
+// NewIdentity generates a key pair and publishes a did:nda:testnet document for it in the environment's
+// registry, with its key listed for authentication and assertions, e.g. to add a second holder.
+func (env *DemoEnvironment) NewIdentity() (DemoIdentity, error) {
+	return newDemoIdentity(env.Registry)
+}
+
+// newDemoIdentity generates a key pair and publishes its did:nda:testnet document in registry.
+func newDemoIdentity(registry *did.MemoryRegistry) (DemoIdentity, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return DemoIdentity{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+	signer, err := provider.NewLocalProvider(key)
+	if err != nil {
+		return DemoIdentity{}, err
+	}
+	id, err := ProfileTestnet.DID(signer.Address())
+	if err != nil {
+		return DemoIdentity{}, err
+	}
+
+	registry.Publish(did.NewSecp256k1Document(id, &key.PublicKey))
+	return DemoIdentity{DID: id, Address: signer.Address(), Provider: signer}, nil
+}
+
+// NewDemoEnvironment creates an issuer and a holder identity, has the issuer issue one credential to the
+// holder and returns the services of the three roles. opts are applied to every service after the demo
+// wiring, so they can replace it. The services refuse any HTTP request: everything is served from memory.
+func NewDemoEnvironment(ctx context.Context, opts ...Option) (*DemoEnvironment, error) {
+	registry := did.NewMemoryRegistry()
+	issuer, err := newDemoIdentity(registry)
+	if err != nil {
+		return nil, err
+	}
+	holder, err := newDemoIdentity(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := schema.NewRegistry(schema.WithOffline())
+	if err := schemas.Add(DemoSchemaURL, []byte(demoSchema)); err != nil {
+		return nil, err
+	}
+	demoOpts := append([]Option{
+		WithResolver(registry),
+		WithSchemaSource(schemas),
+		WithoutNetwork(),
+	}, opts...)
+
+	env := &DemoEnvironment{
+		Issuer:       issuer,
+		Holder:       holder,
+		IssuerAuth:   NewAuth(issuer.Provider, "", demoOpts...),
+		HolderAuth:   NewAuth(holder.Provider, "", demoOpts...),
+		VerifierAuth: NewAuth(nil, "", demoOpts...),
+		Registry:     registry,
+	}
+
+	results, err := env.IssuerAuth.IssueCredentials(ctx, []CredentialDocument{{
+		Issuer:  issuer.DID,
+		Schemas: []CredentialSchema{{ID: DemoSchemaURL, Type: "JsonSchema"}},
+		Subject: map[string]any{"id": holder.DID, "role": "member"},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if results[0].Err != nil {
+		return nil, fmt.Errorf("failed to issue the demo credential: %w", results[0].Err)
+	}
+	env.Credential = results[0].Credential
+	return env, nil
+}
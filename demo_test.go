@@ -0,0 +1,62 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+)
+
+// ExampleNewDemoEnvironment runs issuance, presentation and verification entirely in memory.
+func ExampleNewDemoEnvironment() {
+	ctx := context.Background()
+	env, err := auth.NewDemoEnvironment(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token, err := env.HolderAuth.CreateToken(ctx, []string{env.Credential}, env.Holder.DID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	claims, err := env.VerifierAuth.VerifyToken(ctx, token)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(claims[0].Issuer == env.Issuer.DID, claims[0].Subject().Claims["role"])
+	// Output: true member
+}
+
+func TestDemoEnvironment(t *testing.T) {
+	ctx := context.Background()
+	env, err := auth.NewDemoEnvironment(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The holder key cannot present under another identity of the registry.
+	other, err := env.NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := env.HolderAuth.CreateToken(ctx, []string{env.Credential}, other.DID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.VerifierAuth.VerifyToken(ctx, forged); err == nil {
+		t.Error("expected a presentation signed with another identity's key to be rejected")
+	}
+
+	token, err := env.HolderAuth.CreateToken(ctx, []string{env.Credential}, env.Holder.DID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.VerifierAuth.VerifyToken(ctx, token, auth.WithRequireLinkedDomain("issuer.example"))
+	if !errors.Is(err, auth.ErrNetworkDisabled) {
+		t.Errorf("linked domain check error = %v, want the network to be refused", err)
+	}
+}
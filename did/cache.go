@@ -0,0 +1,112 @@
+package did
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// ResolutionMetadata describes how a document returned by a CachingResolver was obtained.
+type ResolutionMetadata struct {
+	Stale bool          // The registry was unavailable and a cached document past its TTL was returned
+	Age   time.Duration // Time since the document was fetched from the registry
+	Cause error         // The resolution error masked by the stale document, when Stale
+}
+
+// CachingResolver caches the documents resolved by another Resolver. Fresh documents are served from
+// the cache for the TTL. Past it, documents are resolved again. When that fails with
+// ErrRegistryUnavailable, the cached document is returned as stale for up to MaxStale after its TTL.
+// Other errors, e.g. an unknown DID, are returned as is.
+// A CachingResolver is safe for concurrent use.
+type CachingResolver struct {
+	next     Resolver
+	ttl      time.Duration
+	maxStale time.Duration
+	clock    clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	doc     *Document
+	fetched time.Time
+}
+
+// CacheOpt configures a CachingResolver.
+type CacheOpt func(*CachingResolver)
+
+// WithCacheClock sets the time source of the TTL and staleness window (default: the system clock).
+func WithCacheClock(c clock.Clock) CacheOpt {
+	return func(r *CachingResolver) {
+		r.clock = clock.OrSystem(c)
+	}
+}
+
+// NewCachingResolver wraps next with a cache keeping documents fresh for ttl and usable as stale for
+// maxStale more during registry outages. A zero ttl resolves every DID against next and only uses the
+// cache as a fallback.
+func NewCachingResolver(next Resolver, ttl, maxStale time.Duration, opts ...CacheOpt) *CachingResolver {
+	r := &CachingResolver{
+		next:     next,
+		ttl:      ttl,
+		maxStale: maxStale,
+		clock:    clock.System(),
+		entries:  make(map[string]cacheEntry),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Resolve returns the document of did, from the cache or from the wrapped resolver.
+func (r *CachingResolver) Resolve(ctx context.Context, did string) (*Document, error) {
+	doc, _, err := r.ResolveWithMetadata(ctx, did)
+	return doc, err
+}
+
+// ResolveWithMetadata is Resolve also reporting whether the document is stale.
+func (r *CachingResolver) ResolveWithMetadata(ctx context.Context, did string) (*Document, ResolutionMetadata, error) {
+	now := r.clock.Now()
+
+	r.mu.Lock()
+	entry, cached := r.entries[did]
+	r.mu.Unlock()
+
+	if cached && now.Sub(entry.fetched) < r.ttl {
+		return entry.doc, ResolutionMetadata{Age: now.Sub(entry.fetched)}, nil
+	}
+
+	doc, err := r.next.Resolve(ctx, did)
+	if err == nil {
+		r.mu.Lock()
+		r.entries[did] = cacheEntry{doc: doc, fetched: now}
+		r.mu.Unlock()
+		return doc, ResolutionMetadata{}, nil
+	}
+
+	// Only an outage is masked, and only when the caller is still waiting for an answer.
+	age := now.Sub(entry.fetched)
+	if cached && errors.Is(err, ErrRegistryUnavailable) && ctx.Err() == nil && age <= r.ttl+r.maxStale {
+		return entry.doc, ResolutionMetadata{Stale: true, Age: age, Cause: err}, nil
+	}
+	return nil, ResolutionMetadata{}, err
+}
+
+// Forget drops the cached document of did, e.g. after learning that it was updated or deactivated.
+func (r *CachingResolver) Forget(did string) {
+	r.mu.Lock()
+	delete(r.entries, did)
+	r.mu.Unlock()
+}
+
+// CloseIdleConnections closes idle connections of the wrapped resolver, when it has any.
+func (r *CachingResolver) CloseIdleConnections() {
+	if c, ok := r.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,49 @@
+package did
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github/hovanhoa/go-vc-auth/ethaddr"
+)
+
+// didPattern follows the DID syntax of DID Core: did:<method-name>:<method-specific-id>.
+var didPattern = regexp.MustCompile(`^did:([a-z0-9]+):((?:[a-zA-Z0-9._-]|%[0-9a-fA-F]{2}|:)*(?:[a-zA-Z0-9._-]|%[0-9a-fA-F]{2}))$`)
+
+// DID is a parsed decentralized identifier.
+type DID struct {
+	Method string // Method name, e.g. "nda"
+	ID     string // Method-specific identifier, e.g. "testnet:0x2af7..."
+}
+
+// Parse parses s as a DID. DID URLs (with path, query or fragment) are rejected.
+func Parse(s string) (DID, error) {
+	if s == "" {
+		return DID{}, errors.New("DID is empty")
+	}
+
+	matches := didPattern.FindStringSubmatch(s)
+	if matches == nil {
+		return DID{}, fmt.Errorf("invalid DID syntax: %q", s)
+	}
+
+	return DID{Method: matches[1], ID: matches[2]}, nil
+}
+
+// String returns the DID in its canonical "did:method:id" form.
+func (d DID) String() string {
+	return "did:" + d.Method + ":" + d.ID
+}
+
+// Address returns the last colon-separated segment of the method-specific identifier,
+// which for did:nda is the Ethereum address of the DID controller. A valid address is returned
+// in its lowercase canonical form, see ethaddr.Normalize.
+func (d DID) Address() string {
+	segment := d.ID[strings.LastIndex(d.ID, ":")+1:]
+	if address, err := ethaddr.Normalize(segment); err == nil {
+		return address
+	}
+	return segment
+}
@@ -0,0 +1,162 @@
+package did
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// JWK represents a JSON Web Key as published in a verification method.
+type JWK struct {
+	Kty string `json:"kty"`           // Key type
+	Crv string `json:"crv"`           // Curve
+	X   string `json:"x"`             // X coordinate
+	Y   string `json:"y"`             // Y coordinate
+	Kid string `json:"kid,omitempty"` // Key ID, set when the key is published in a JWKS
+	Alg string `json:"alg,omitempty"` // Intended algorithm, e.g. "ES256K"
+	Use string `json:"use,omitempty"` // Intended use, e.g. "sig"
+}
+
+// NewJWK encodes a secp256k1 public key as a JWK.
+func NewJWK(publicKey *ecdsa.PublicKey) JWK {
+	return JWK{
+		Kty: "EC",
+		Crv: "secp256k1",
+		X:   base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+// VerificationMethod represents a single verification method in a DID document.
+type VerificationMethod struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	Controller   string `json:"controller"`
+	PublicKeyHex string `json:"publicKeyHex,omitempty"`
+	PublicKeyJwk *JWK   `json:"publicKeyJwk,omitempty"`
+}
+
+// Document represents a resolved DID document.
+type Document struct {
+	Context              []string             `json:"@context"`
+	ID                   string               `json:"id"`
+	VerificationMethod   []VerificationMethod `json:"verificationMethod"`
+	Authentication       []string             `json:"authentication"`
+	AssertionMethod      []string             `json:"assertionMethod"`
+	CapabilityDelegation []string             `json:"capabilityDelegation,omitempty"`
+	Controller           any                  `json:"controller"` // Can be string or []string
+	DIDDocumentMetadata  map[string]any       `json:"didDocumentMetadata"`
+}
+
+// FindVerificationMethod returns the verification method with the given ID.
+func (d *Document) FindVerificationMethod(id string) (*VerificationMethod, error) {
+	for i := range d.VerificationMethod {
+		if d.VerificationMethod[i].ID == id {
+			return &d.VerificationMethod[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("verification method '%s' not found in DID document", id)
+}
+
+// Verification relationships of a DID document, naming the purposes its keys may be used for.
+const (
+	Authentication       = "authentication"
+	AssertionMethod      = "assertionMethod"
+	CapabilityDelegation = "capabilityDelegation"
+)
+
+// HasRelationship reports whether the verification method id is listed under relationship
+// (Authentication, AssertionMethod or CapabilityDelegation). Relative references such as "#key-1" are resolved
+// against the document ID.
+func (d *Document) HasRelationship(relationship, id string) bool {
+	var refs []string
+	switch relationship {
+	case Authentication:
+		refs = d.Authentication
+	case AssertionMethod:
+		refs = d.AssertionMethod
+	case CapabilityDelegation:
+		refs = d.CapabilityDelegation
+	}
+
+	for _, ref := range refs {
+		if strings.HasPrefix(ref, "#") {
+			ref = d.ID + ref
+		}
+		if ref == id {
+			return true
+		}
+	}
+	return false
+}
+
+// PublicKey returns the secp256k1 public key of the verification method.
+// Both publicKeyHex (compressed or uncompressed) and publicKeyJwk encodings are supported.
+func (vm *VerificationMethod) PublicKey() (*ecdsa.PublicKey, error) {
+	if vm.PublicKeyHex != "" {
+		return parsePublicKeyHex(vm.PublicKeyHex)
+	}
+
+	if vm.PublicKeyJwk != nil {
+		return parsePublicKeyJWK(vm.PublicKeyJwk)
+	}
+
+	return nil, fmt.Errorf("no public key found in verification method '%s'", vm.ID)
+}
+
+func parsePublicKeyHex(publicKeyHex string) (*ecdsa.PublicKey, error) {
+	publicKeyBytes, err := hex.DecodeString(strings.TrimPrefix(publicKeyHex, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode hex: %w", err)
+	}
+
+	// Compressed public keys (33 bytes)
+	if len(publicKeyBytes) == 33 && (publicKeyBytes[0] == 0x02 || publicKeyBytes[0] == 0x03) {
+		return crypto.DecompressPubkey(publicKeyBytes)
+	}
+
+	// Uncompressed public keys (65 bytes)
+	if len(publicKeyBytes) == 65 && publicKeyBytes[0] == 0x04 {
+		return crypto.UnmarshalPubkey(publicKeyBytes)
+	}
+
+	return nil, fmt.Errorf("unsupported public key format")
+}
+
+func parsePublicKeyJWK(jwk *JWK) (*ecdsa.PublicKey, error) {
+	if jwk.Kty != "EC" {
+		return nil, fmt.Errorf("unsupported key type: %s", jwk.Kty)
+	}
+
+	if jwk.Crv != "secp256k1" {
+		return nil, fmt.Errorf("unsupported curve: %s", jwk.Crv)
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode X coordinate: %w", err)
+	}
+
+	yBytes, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Y coordinate: %w", err)
+	}
+
+	publicKey := &ecdsa.PublicKey{
+		Curve: crypto.S256(),
+		X:     new(big.Int).SetBytes(xBytes),
+		Y:     new(big.Int).SetBytes(yBytes),
+	}
+
+	if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return nil, fmt.Errorf("public key is not on the secp256k1 curve")
+	}
+
+	return publicKey, nil
+}
@@ -0,0 +1,169 @@
+package did
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// JWS algorithms a verification method may be used with.
+const (
+	AlgES256K = "ES256K" // ECDSA over secp256k1 with SHA-256
+	AlgES256  = "ES256"  // ECDSA over P-256 with SHA-256
+	AlgES384  = "ES384"  // ECDSA over P-384 with SHA-384
+	AlgEdDSA  = "EdDSA"  // Ed25519
+)
+
+// methodTypeAlgorithms maps verification method types bound to a single algorithm.
+// Generic types such as JsonWebKey2020 take the algorithm from the key curve instead.
+var methodTypeAlgorithms = map[string]string{
+	"EcdsaSecp256k1VerificationKey2019": AlgES256K,
+	"EcdsaSecp256k1RecoveryMethod2020":  AlgES256K,
+	"EcdsaSecp256r1VerificationKey2019": AlgES256,
+	"Ed25519VerificationKey2018":        AlgEdDSA,
+	"Ed25519VerificationKey2020":        AlgEdDSA,
+}
+
+// curveAlgorithms maps JWK curves to the algorithm used with them.
+var curveAlgorithms = map[string]string{
+	"secp256k1": AlgES256K,
+	"P-256":     AlgES256,
+	"P-384":     AlgES384,
+	"Ed25519":   AlgEdDSA,
+}
+
+// Key returns the public key of the verification method: an *ecdsa.PublicKey for secp256k1, P-256
+// and P-384 keys, or an ed25519.PublicKey. publicKeyHex keys are secp256k1.
+func (vm *VerificationMethod) Key() (crypto.PublicKey, error) {
+	if vm.PublicKeyHex != "" {
+		return parsePublicKeyHex(vm.PublicKeyHex)
+	}
+
+	if vm.PublicKeyJwk == nil {
+		return nil, fmt.Errorf("no public key found in verification method '%s'", vm.ID)
+	}
+
+	jwk := vm.PublicKeyJwk
+	switch {
+	case jwk.Kty == "EC" && jwk.Crv == "secp256k1":
+		return parsePublicKeyJWK(jwk)
+	case jwk.Kty == "EC" && (jwk.Crv == "P-256" || jwk.Crv == "P-384"):
+		curve := elliptic.P256()
+		if jwk.Crv == "P-384" {
+			curve = elliptic.P384()
+		}
+		return parseECJWK(jwk, curve)
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %s/%s", jwk.Kty, jwk.Crv)
+}
+
+// Algorithm returns the only JWS algorithm the verification method may be used with, derived from
+// its type or, for generic JWK types, its key curve. A JWK "alg" member must agree with it.
+func (vm *VerificationMethod) Algorithm() (string, error) {
+	alg, typed := methodTypeAlgorithms[vm.Type]
+
+	switch {
+	case vm.PublicKeyJwk != nil:
+		curveAlg, ok := curveAlgorithms[vm.PublicKeyJwk.Crv]
+		if !ok {
+			return "", fmt.Errorf("unsupported curve: %s", vm.PublicKeyJwk.Crv)
+		}
+		if typed && curveAlg != alg {
+			return "", fmt.Errorf("verification method type %s does not match curve %s", vm.Type, vm.PublicKeyJwk.Crv)
+		}
+		if vm.PublicKeyJwk.Alg != "" && vm.PublicKeyJwk.Alg != curveAlg {
+			return "", fmt.Errorf("key algorithm %s does not match curve %s", vm.PublicKeyJwk.Alg, vm.PublicKeyJwk.Crv)
+		}
+		return curveAlg, nil
+
+	case vm.PublicKeyHex != "":
+		if typed && alg != AlgES256K {
+			return "", fmt.Errorf("verification method type %s does not match a secp256k1 key", vm.Type)
+		}
+		return AlgES256K, nil
+	}
+
+	return "", fmt.Errorf("no public key found in verification method '%s'", vm.ID)
+}
+
+// JWKFromKey encodes an *ecdsa.PublicKey (secp256k1, P-256, P-384) or ed25519.PublicKey as a JWK.
+func JWKFromKey(key crypto.PublicKey) (JWK, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve == ethcrypto.S256() {
+			return NewJWK(k), nil
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(k)}, nil
+	}
+
+	return JWK{}, fmt.Errorf("unsupported public key type %T", key)
+}
+
+// Thumbprint returns the RFC 7638 JWK thumbprint of key: the base64url SHA-256 digest of its
+// required JWK members. It identifies a key independently of how a DID document encodes it.
+func Thumbprint(key crypto.PublicKey) (string, error) {
+	jwk, err := JWKFromKey(key)
+	if err != nil {
+		return "", err
+	}
+	return jwk.Thumbprint()
+}
+
+// Thumbprint returns the RFC 7638 thumbprint of an EC or OKP JWK.
+func (j JWK) Thumbprint() (string, error) {
+	// Members in lexicographic order, as RFC 7638 requires; encoding/json sorts map keys.
+	members := map[string]string{"crv": j.Crv, "kty": j.Kty, "x": j.X}
+	if j.Kty == "EC" {
+		members["y"] = j.Y
+	}
+	encoded, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(digest[:]), nil
+}
+
+// parseECJWK decodes an EC JWK on one of the standard library curves.
+func parseECJWK(jwk *JWK, curve elliptic.Curve) (*ecdsa.PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode X coordinate: %w", err)
+	}
+
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Y coordinate: %w", err)
+	}
+
+	publicKey := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return nil, fmt.Errorf("public key is not on the %s curve", jwk.Crv)
+	}
+
+	return publicKey, nil
+}
@@ -0,0 +1,65 @@
+package did
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// MemoryRegistry is an in-memory DID registry for tests, demos and air-gapped deployments. It is a
+// Resolver and is safe for concurrent use.
+type MemoryRegistry struct {
+	mu   sync.RWMutex
+	docs map[string]*Document
+}
+
+var _ Resolver = (*MemoryRegistry)(nil)
+
+// NewMemoryRegistry creates an empty MemoryRegistry.
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{docs: map[string]*Document{}}
+}
+
+// Publish adds or replaces a DID document.
+func (r *MemoryRegistry) Publish(doc *Document) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.docs[doc.ID] = doc
+}
+
+// Resolve returns the published document of did.
+func (r *MemoryRegistry) Resolve(ctx context.Context, did string) (*Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	doc, ok := r.docs[did]
+	if !ok {
+		return nil, fmt.Errorf("DID %s is not registered", did)
+	}
+	return doc, nil
+}
+
+// NewSecp256k1Document returns the DID document of id with key as its key-1 verification method, listed
+// for authentication and assertions, like the documents of the did:nda registry.
+func NewSecp256k1Document(id string, key *ecdsa.PublicKey) *Document {
+	return &Document{
+		ID: id,
+		VerificationMethod: []VerificationMethod{{
+			ID:           id + "#key-1",
+			Type:         "EcdsaSecp256k1VerificationKey2019",
+			Controller:   id,
+			PublicKeyHex: hex.EncodeToString(ethcrypto.FromECDSAPub(key)),
+		}},
+		Authentication:  []string{id + "#key-1"},
+		AssertionMethod: []string{id + "#key-1"},
+	}
+}
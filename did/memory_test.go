@@ -0,0 +1,31 @@
+package did_test
+
+import (
+	"context"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+func TestMemoryRegistry(t *testing.T) {
+	key, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const id = "did:nda:testnet:0x01"
+	registry := did.NewMemoryRegistry()
+	registry.Publish(did.NewSecp256k1Document(id, &key.PublicKey))
+
+	doc, err := registry.Resolve(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.HasRelationship(did.AssertionMethod, id+"#key-1") {
+		t.Error("key-1 should be an assertion method")
+	}
+	if _, err := registry.Resolve(context.Background(), "did:nda:testnet:0x02"); err == nil {
+		t.Error("an unpublished DID should not resolve")
+	}
+}
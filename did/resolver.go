@@ -0,0 +1,115 @@
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultTimeout is the timeout of the HTTP client used by the registry resolver.
+	defaultTimeout = 10 * time.Second
+	// maxDocumentSize bounds the DID documents read from the registry.
+	maxDocumentSize = 1 << 20
+)
+
+// ErrRegistryUnavailable marks resolution errors caused by the registry being unreachable or failing,
+// as opposed to the registry answering that the DID is unknown. Resolvers wrap it so callers such as
+// CachingResolver can tell an outage from a negative answer.
+var ErrRegistryUnavailable = errors.New("DID registry unavailable")
+
+// Resolver resolves a DID into its DID document.
+type Resolver interface {
+	Resolve(ctx context.Context, did string) (*Document, error)
+}
+
+// registryResolver resolves DIDs against an HTTP DID registry.
+type registryResolver struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// ResolverOpt configures the Resolver created by NewResolver.
+type ResolverOpt func(*registryResolver)
+
+// WithHTTPClient sets the HTTP client used to fetch DID documents, e.g. one whose Transport serves
+// documents from memory in tests.
+func WithHTTPClient(client *http.Client) ResolverOpt {
+	return func(r *registryResolver) {
+		r.httpClient = client
+	}
+}
+
+// NewResolver creates a Resolver backed by the DID registry at baseURL.
+// Documents are fetched from {baseURL}/{did}.
+func NewResolver(baseURL string, opts ...ResolverOpt) Resolver {
+	r := &registryResolver{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: defaultTimeout,
+		},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Resolve fetches and parses the DID document of did. Documents larger than 1 MiB, or whose id is not
+// did, are rejected.
+func (r *registryResolver) Resolve(ctx context.Context, did string) (*Document, error) {
+	endpoint := r.baseURL + "/" + url.PathEscape(did)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to make HTTP request to DID resolver: %w", ErrRegistryUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("%w: DID resolver API returned status %s", ErrRegistryUnavailable, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DID resolver API returned non-200 status: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read response body from DID resolver: %w", ErrRegistryUnavailable, err)
+	}
+	if len(body) > maxDocumentSize {
+		return nil, fmt.Errorf("DID document of %s exceeds %d bytes", did, maxDocumentSize)
+	}
+
+	var doc Document
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal DID document JSON: %w", err)
+	}
+	if doc.ID != did {
+		return nil, fmt.Errorf("DID resolver returned the document of %q for %s", doc.ID, did)
+	}
+
+	return &doc, nil
+}
+
+// CloseIdleConnections closes idle connections to the DID registry.
+func (r *registryResolver) CloseIdleConnections() {
+	r.httpClient.CloseIdleConnections()
+}
+
+// SplitDIDURL splits a DID URL such as "did:nda:testnet:0xabc#key-1" into the DID and its fragment.
+func SplitDIDURL(didURL string) (string, string) {
+	did, fragment, _ := strings.Cut(didURL, "#")
+	return did, fragment
+}
@@ -0,0 +1,42 @@
+package did_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+func TestRegistryResolver(t *testing.T) {
+	const id = "did:nda:testnet:0x01"
+	bodies := map[string]string{
+		"/" + id:                   `{"id": "` + id + `"}`,
+		"/did:nda:testnet:0x02":    `{"id": "` + id + `"}`,
+		"/did:nda:testnet:0xlarge": `{"id": "did:nda:testnet:0xlarge", "controller": "` + strings.Repeat("x", 2<<20) + `"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	resolver := did.NewResolver(server.URL)
+	doc, err := resolver.Resolve(context.Background(), id)
+	if err != nil || doc.ID != id {
+		t.Fatalf("Resolve = %+v, %v", doc, err)
+	}
+
+	// A registry answering with another DID's document, or an oversized one, is not trusted.
+	for _, other := range []string{"did:nda:testnet:0x02", "did:nda:testnet:0xlarge"} {
+		if _, err := resolver.Resolve(context.Background(), other); err == nil {
+			t.Errorf("Resolve(%s) should fail", other)
+		}
+	}
+}
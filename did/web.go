@@ -0,0 +1,66 @@
+package did
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// WebDocumentURL returns the HTTPS URL the document of a did:web DID is served at, as the did:web method
+// specification maps it: did:web:example.com is served at https://example.com/.well-known/did.json and
+// did:web:example.com:users:alice at https://example.com/users/alice/did.json. A port is encoded as %3A
+// in the DID.
+func WebDocumentURL(id string) (string, error) {
+	parsed, err := Parse(id)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Method != "web" {
+		return "", fmt.Errorf("%s is not a did:web DID", id)
+	}
+
+	segments := strings.Split(parsed.ID, ":")
+	host, err := url.PathUnescape(segments[0])
+	if err != nil || host == "" || strings.ContainsAny(host, "/?#@") {
+		return "", fmt.Errorf("invalid did:web domain %q", segments[0])
+	}
+	for i, segment := range segments[1:] {
+		if segments[i+1], err = url.PathUnescape(segment); err != nil || !validWebSegment(segments[i+1]) {
+			return "", fmt.Errorf("invalid did:web path segment %q", segment)
+		}
+	}
+
+	path := "/.well-known"
+	if len(segments) > 1 {
+		path = "/" + strings.Join(segments[1:], "/")
+	}
+	return (&url.URL{Scheme: "https", Host: host, Path: path + "/did.json"}).String(), nil
+}
+
+// validWebSegment reports whether an unescaped did:web path segment names exactly one path element: it
+// must not be empty, a dot-segment, or contain a separator that would let it escape its parent.
+func validWebSegment(segment string) bool {
+	return segment != "" && segment != "." && segment != ".." && !strings.ContainsAny(segment, "/\\")
+}
+
+// NewWebDID returns the did:web DID whose document is served from location, e.g.
+// "https://example.com/users/alice" gives did:web:example.com:users:alice and "https://example.com"
+// gives did:web:example.com.
+func NewWebDID(location string) (string, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid did:web location %q: an https URL without query is required", location)
+	}
+
+	segments := []string{strings.ReplaceAll(u.Host, ":", "%3A")}
+	for _, segment := range strings.Split(strings.Trim(u.Path, "/"), "/") {
+		if segment != "" {
+			segments = append(segments, url.PathEscape(segment))
+		}
+	}
+	id := "did:web:" + strings.Join(segments, ":")
+	if _, err := WebDocumentURL(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github/hovanhoa/go-vc-auth/pe"
+)
+
+// ErrExcessDisclosure is returned by CreateToken when a presentation would disclose claims the
+// verifier did not ask for and the disclosure check blocks it.
+var ErrExcessDisclosure = errors.New("presentation discloses unrequested claims")
+
+// ExcessDisclosure lists the claims of one credential that no matching input descriptor requested.
+type ExcessDisclosure struct {
+	Index  int      // Position of the credential in the presentation
+	Claims []string // Unrequested credentialSubject claim names; every claim if no descriptor matched
+}
+
+// DisclosureHandler decides what happens when a presentation over-discloses. Returning nil lets
+// CreateToken continue, e.g. after logging a warning; returning an error aborts it.
+type DisclosureHandler func(excess []ExcessDisclosure) error
+
+// BlockExcessDisclosure is a DisclosureHandler that rejects any over-disclosure with ErrExcessDisclosure.
+func BlockExcessDisclosure(excess []ExcessDisclosure) error {
+	parts := make([]string, len(excess))
+	for i, e := range excess {
+		parts[i] = fmt.Sprintf("credential %d: %s", e.Index, strings.Join(e.Claims, ", "))
+	}
+	return fmt.Errorf("%w: %s", ErrExcessDisclosure, strings.Join(parts, "; "))
+}
+
+// WithDisclosureCheck compares the claims in the presentation against the verifier's presentation
+// definition before signing and passes any excess to handler. A nil handler blocks, as BlockExcessDisclosure.
+func WithDisclosureCheck(definition *pe.PresentationDefinition, handler DisclosureHandler) CreateOpt {
+	return func(o *createOptions) {
+		o.disclosureDefinition = definition
+		o.disclosureHandler = handler
+	}
+}
+
+// CheckDisclosure reports, per credential, the credentialSubject claims that would be disclosed
+// beyond what definition requests. A credential's allowance is the union of the claims requested
+// by every input descriptor it satisfies. JWT VCs cannot be partially disclosed, so the holder's
+// remedy is to present a narrower credential or none.
+func CheckDisclosure(vcsJwt []string, definition *pe.PresentationDefinition) ([]ExcessDisclosure, error) {
+	var excess []ExcessDisclosure
+	for i, vcJwt := range vcsJwt {
+		token, err := parseJWT(vcJwt)
+		if err != nil {
+			return nil, newCredentialError(i, vcJwt, fmt.Errorf("%w: %v", ErrMalformedCredential, err))
+		}
+
+		claims, err := excessClaims(token.payload, definition)
+		if err != nil {
+			return nil, err
+		}
+		if len(claims) > 0 {
+			excess = append(excess, ExcessDisclosure{Index: i, Claims: claims})
+		}
+	}
+
+	return excess, nil
+}
+
+// excessClaims returns the sorted claims of the credential payload not requested by definition.
+func excessClaims(payload map[string]any, definition *pe.PresentationDefinition) ([]string, error) {
+	requested := map[string]bool{}
+	for _, descriptor := range definition.InputDescriptors {
+		ok, err := descriptor.Match(payload)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		claims, all, err := descriptor.RequestedClaims()
+		if err != nil {
+			return nil, err
+		}
+		if all {
+			return nil, nil
+		}
+		for _, claim := range claims {
+			requested[claim] = true
+		}
+	}
+
+	vcData, _ := payload["vc"].(map[string]any)
+
+	var excess []string
+	for _, name := range subjectClaimNames(vcData) {
+		if !requested[name] {
+			excess = append(excess, name)
+		}
+	}
+
+	return excess, nil
+}
+
+// checkCreateDisclosure applies the disclosure check configured by WithDisclosureCheck.
+func checkCreateDisclosure(vcsJwt []string, options *createOptions) error {
+	excess, err := CheckDisclosure(vcsJwt, options.disclosureDefinition)
+	if err != nil || len(excess) == 0 {
+		return err
+	}
+
+	handler := options.disclosureHandler
+	if handler == nil {
+		handler = BlockExcessDisclosure
+	}
+	return handler(excess)
+}
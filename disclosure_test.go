@@ -0,0 +1,70 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/pe"
+)
+
+func TestCheckDisclosure(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer", "email": "holder@example.com", "birthDate": "1990-01-01"})
+
+	definition := func(paths ...string) *pe.PresentationDefinition {
+		return &pe.PresentationDefinition{ID: "request", InputDescriptors: []pe.InputDescriptor{{
+			ID:          "viewer",
+			Constraints: pe.Constraints{Fields: []pe.Field{{Path: paths}}},
+		}}}
+	}
+
+	tests := []struct {
+		name       string
+		definition *pe.PresentationDefinition
+		want       []auth.ExcessDisclosure
+	}{
+		{"one claim requested", definition("$.vc.credentialSubject.role"), []auth.ExcessDisclosure{{Index: 0, Claims: []string{"birthDate", "email"}}}},
+		{"alternative paths", definition("$.credentialSubject.role", "$.credentialSubject['email']"), []auth.ExcessDisclosure{{Index: 0, Claims: []string{"birthDate"}}}},
+		{"whole subject requested", definition("$.credentialSubject"), nil},
+		{"no descriptor matches", definition("$.vc.credentialSubject.employer"), []auth.ExcessDisclosure{{Index: 0, Claims: []string{"birthDate", "email", "role"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			excess, err := auth.CheckDisclosure([]string{vcJwt}, tt.definition)
+			if err != nil {
+				t.Fatalf("CheckDisclosure failed: %v", err)
+			}
+			if !reflect.DeepEqual(excess, tt.want) {
+				t.Errorf("got %+v, want %+v", excess, tt.want)
+			}
+		})
+	}
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+	request := definition("$.vc.credentialSubject.role")
+
+	_, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address, auth.WithDisclosureCheck(request, nil))
+	if !errors.Is(err, auth.ErrExcessDisclosure) {
+		t.Errorf("expected ErrExcessDisclosure, got %v", err)
+	}
+
+	var warned []auth.ExcessDisclosure
+	warn := func(excess []auth.ExcessDisclosure) error {
+		warned = excess
+		return nil
+	}
+	if _, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address, auth.WithDisclosureCheck(request, warn)); err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	if len(warned) != 1 {
+		t.Errorf("expected the handler to be warned about one credential, got %+v", warned)
+	}
+}
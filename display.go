@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CredentialDisplay holds how a credential should be rendered to a user, as published by its issuer
+// in OpenID4VCI credential issuer metadata or a DIF credential manifest.
+type CredentialDisplay struct {
+	Name            string                  `json:"name,omitempty"`
+	Description     string                  `json:"description,omitempty"`
+	Locale          string                  `json:"locale,omitempty"`
+	Logo            *DisplayImage           `json:"logo,omitempty"`
+	BackgroundImage *DisplayImage           `json:"backgroundImage,omitempty"`
+	BackgroundColor string                  `json:"backgroundColor,omitempty"`
+	TextColor       string                  `json:"textColor,omitempty"`
+	Claims          map[string]ClaimDisplay `json:"claims,omitempty"` // Keyed by credentialSubject claim name
+}
+
+// DisplayImage references an image used when rendering a credential.
+type DisplayImage struct {
+	URI     string `json:"uri"`
+	AltText string `json:"altText,omitempty"`
+}
+
+// ClaimDisplay holds the human readable label of a credential claim.
+type ClaimDisplay struct {
+	Name   string `json:"name"`
+	Locale string `json:"locale,omitempty"`
+}
+
+// DisplaySource looks up display metadata for a verified credential.
+// Display returns nil when the source has no metadata for the credential.
+type DisplaySource interface {
+	Display(claims VcClaims) *CredentialDisplay
+}
+
+// WithDisplay attaches display metadata from the first matching source to each VcClaims.
+func WithDisplay(sources ...DisplaySource) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.displaySources = append(o.displaySources, sources...)
+	}
+}
+
+// lookupDisplay returns the display metadata of the first source that knows the credential.
+func lookupDisplay(sources []DisplaySource, claims VcClaims) *CredentialDisplay {
+	for _, source := range sources {
+		if display := source.Display(claims); display != nil {
+			return display
+		}
+	}
+	return nil
+}
+
+// issuerMetadataDisplay matches credentials against the configurations of OpenID4VCI issuer metadata.
+type issuerMetadataDisplay struct {
+	configurations []issuerCredentialConfiguration
+	locale         string
+}
+
+type issuerCredentialConfiguration struct {
+	types   []string
+	display []issuerDisplay
+	claims  map[string][]issuerDisplay
+}
+
+// issuerDisplay is a display entry of OpenID4VCI credential issuer metadata.
+type issuerDisplay struct {
+	Name            string `json:"name"`
+	Locale          string `json:"locale"`
+	Description     string `json:"description"`
+	BackgroundColor string `json:"background_color"`
+	TextColor       string `json:"text_color"`
+	Logo            *struct {
+		URI     string `json:"uri"`
+		AltText string `json:"alt_text"`
+	} `json:"logo"`
+	BackgroundImage *struct {
+		URI string `json:"uri"`
+	} `json:"background_image"`
+}
+
+// NewIssuerMetadataDisplay creates a DisplaySource from OpenID4VCI credential issuer metadata
+// (the document served at /.well-known/openid-credential-issuer).
+// Credentials are matched on their types against credential_definition.type of each
+// credential configuration; locale selects the preferred display entry, falling back to the first.
+func NewIssuerMetadataDisplay(metadata []byte, locale string) (DisplaySource, error) {
+	var doc struct {
+		Configurations map[string]struct {
+			CredentialDefinition struct {
+				Type              []string `json:"type"`
+				CredentialSubject map[string]struct {
+					Display []issuerDisplay `json:"display"`
+				} `json:"credentialSubject"`
+			} `json:"credential_definition"`
+			Display []issuerDisplay `json:"display"`
+		} `json:"credential_configurations_supported"`
+	}
+	if err := json.Unmarshal(metadata, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse issuer metadata: %w", err)
+	}
+
+	source := &issuerMetadataDisplay{locale: locale}
+	for _, config := range doc.Configurations {
+		if len(config.CredentialDefinition.Type) == 0 {
+			continue
+		}
+
+		entry := issuerCredentialConfiguration{
+			types:   config.CredentialDefinition.Type,
+			display: config.Display,
+			claims:  map[string][]issuerDisplay{},
+		}
+		for name, claim := range config.CredentialDefinition.CredentialSubject {
+			entry.claims[name] = claim.Display
+		}
+		source.configurations = append(source.configurations, entry)
+	}
+
+	return source, nil
+}
+
+// Display returns the display of the most specific configuration whose types the credential carries.
+func (s *issuerMetadataDisplay) Display(claims VcClaims) *CredentialDisplay {
+	var match *issuerCredentialConfiguration
+	for i, config := range s.configurations {
+		if !hasAllTypes(claims, config.types) {
+			continue
+		}
+		if match == nil || len(config.types) > len(match.types) {
+			match = &s.configurations[i]
+		}
+	}
+	if match == nil {
+		return nil
+	}
+
+	display := &CredentialDisplay{}
+	if entry := selectLocale(match.display, s.locale); entry != nil {
+		display.Name = entry.Name
+		display.Description = entry.Description
+		display.Locale = entry.Locale
+		display.BackgroundColor = entry.BackgroundColor
+		display.TextColor = entry.TextColor
+		if entry.Logo != nil {
+			display.Logo = &DisplayImage{URI: entry.Logo.URI, AltText: entry.Logo.AltText}
+		}
+		if entry.BackgroundImage != nil {
+			display.BackgroundImage = &DisplayImage{URI: entry.BackgroundImage.URI}
+		}
+	}
+
+	for name, entries := range match.claims {
+		if entry := selectLocale(entries, s.locale); entry != nil {
+			if display.Claims == nil {
+				display.Claims = map[string]ClaimDisplay{}
+			}
+			display.Claims[name] = ClaimDisplay{Name: entry.Name, Locale: entry.Locale}
+		}
+	}
+
+	return display
+}
+
+// selectLocale returns the entry for locale, matching on the language when there is no exact match,
+// or the first entry.
+func selectLocale(entries []issuerDisplay, locale string) *issuerDisplay {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	locales := make([]string, len(entries))
+	for i, entry := range entries {
+		locales[i] = entry.Locale
+	}
+	if i := matchLocale(locales, locale); i >= 0 {
+		return &entries[i]
+	}
+
+	return &entries[0]
+}
+
+func hasAllTypes(claims VcClaims, types []string) bool {
+	for _, t := range types {
+		if !claims.HasType(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// manifestDisplay matches credentials against the output descriptors of a DIF credential manifest.
+type manifestDisplay struct {
+	descriptors []manifestOutputDescriptor
+}
+
+type manifestOutputDescriptor struct {
+	Schema  string `json:"schema"`
+	Display struct {
+		Title       manifestDisplayMapping   `json:"title"`
+		Description manifestDisplayMapping   `json:"description"`
+		Properties  []manifestDisplayMapping `json:"properties"`
+	} `json:"display"`
+	Styles struct {
+		Thumbnail  *DisplayImage `json:"thumbnail"`
+		Hero       *DisplayImage `json:"hero"`
+		Background struct {
+			Color string `json:"color"`
+		} `json:"background"`
+		Text struct {
+			Color string `json:"color"`
+		} `json:"text"`
+	} `json:"styles"`
+}
+
+// manifestDisplayMapping is a DIF display mapping object: either constant text or a claim path.
+type manifestDisplayMapping struct {
+	Text     string   `json:"text"`
+	Path     []string `json:"path"`
+	Label    string   `json:"label"`
+	Fallback string   `json:"fallback"`
+}
+
+// NewCredentialManifestDisplay creates a DisplaySource from a DIF credential manifest.
+// Credentials are matched on their credentialSchema ids against output_descriptors[].schema.
+func NewCredentialManifestDisplay(manifest []byte) (DisplaySource, error) {
+	var doc struct {
+		OutputDescriptors []manifestOutputDescriptor `json:"output_descriptors"`
+	}
+	if err := json.Unmarshal(manifest, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse credential manifest: %w", err)
+	}
+
+	return &manifestDisplay{descriptors: doc.OutputDescriptors}, nil
+}
+
+// Display returns the display of the output descriptor matching one of the credential schemas.
+func (s *manifestDisplay) Display(claims VcClaims) *CredentialDisplay {
+	for _, descriptor := range s.descriptors {
+		for _, schema := range claims.Schemas {
+			if schema.ID != descriptor.Schema {
+				continue
+			}
+
+			display := &CredentialDisplay{
+				Name:            descriptor.Display.Title.resolve(claims),
+				Description:     descriptor.Display.Description.resolve(claims),
+				Logo:            descriptor.Styles.Thumbnail,
+				BackgroundImage: descriptor.Styles.Hero,
+				BackgroundColor: descriptor.Styles.Background.Color,
+				TextColor:       descriptor.Styles.Text.Color,
+			}
+
+			for _, property := range descriptor.Display.Properties {
+				name := claimNameFromPath(property.Path)
+				if name == "" || property.Label == "" {
+					continue
+				}
+				if display.Claims == nil {
+					display.Claims = map[string]ClaimDisplay{}
+				}
+				display.Claims[name] = ClaimDisplay{Name: property.Label}
+			}
+
+			return display
+		}
+	}
+	return nil
+}
+
+// resolve returns the constant text of the mapping, or the value of the first claim path that
+// resolves to a string or language-tagged string in the subject, or the fallback.
+func (m manifestDisplayMapping) resolve(claims VcClaims) string {
+	if m.Text != "" {
+		return m.Text
+	}
+
+	for _, path := range m.Path {
+		if name := claimNameFromPath([]string{path}); name != "" {
+			if value, ok := claims.Subject().Localized(name, ""); ok {
+				return value
+			}
+		}
+	}
+
+	return m.Fallback
+}
+
+// claimNameFromPath extracts the claim name from a JSONPath such as "$.credentialSubject.name".
+func claimNameFromPath(paths []string) string {
+	for _, path := range paths {
+		if name, ok := strings.CutPrefix(path, "$.credentialSubject."); ok && !strings.ContainsAny(name, ".[") {
+			return name
+		}
+	}
+	return ""
+}
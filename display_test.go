@@ -0,0 +1,107 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+const testIssuerMetadata = `{
+  "credential_issuer": "https://issuer.example.com",
+  "credential_configurations_supported": {
+    "EmployeeCredential": {
+      "format": "jwt_vc_json",
+      "credential_definition": {
+        "type": ["VerifiableCredential", "EmployeeCredential"],
+        "credentialSubject": {
+          "role": {"display": [{"name": "Role", "locale": "en-US"}, {"name": "Vai trò", "locale": "vi-VN"}]}
+        }
+      },
+      "display": [
+        {"name": "Employee Badge", "locale": "en-US", "logo": {"uri": "https://issuer.example.com/logo.png", "alt_text": "logo"}, "background_color": "#12107c", "text_color": "#FFFFFF"},
+        {"name": "Thẻ nhân viên", "locale": "vi-VN"}
+      ]
+    },
+    "Generic": {
+      "format": "jwt_vc_json",
+      "credential_definition": {"type": ["VerifiableCredential"]},
+      "display": [{"name": "Credential"}]
+    }
+  }
+}`
+
+func TestIssuerMetadataDisplay(t *testing.T) {
+	source, err := auth.NewIssuerMetadataDisplay([]byte(testIssuerMetadata), "vi")
+	if err != nil {
+		t.Fatalf("NewIssuerMetadataDisplay failed: %v", err)
+	}
+
+	display := source.Display(auth.VcClaims{Types: []string{"VerifiableCredential", "EmployeeCredential"}})
+	if display == nil {
+		t.Fatal("expected display for EmployeeCredential")
+	}
+	if display.Name != "Thẻ nhân viên" || display.Locale != "vi-VN" {
+		t.Errorf("unexpected localized name: %+v", display)
+	}
+	if display.Claims["role"].Name != "Vai trò" {
+		t.Errorf("unexpected claim display: %+v", display.Claims)
+	}
+
+	if display := source.Display(auth.VcClaims{Types: []string{"VerifiableCredential"}}); display == nil || display.Name != "Credential" {
+		t.Errorf("expected generic display, got %+v", display)
+	}
+	if display := source.Display(auth.VcClaims{Types: []string{"Other"}}); display != nil {
+		t.Errorf("expected no display, got %+v", display)
+	}
+}
+
+func TestVerifyTokenWithDisplay(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	manifest := fmt.Sprintf(`{
+  "id": "employee",
+  "output_descriptors": [{
+    "id": "employee",
+    "schema": %q,
+    "display": {
+      "title": {"text": "Employee Badge"},
+      "description": {"path": ["$.credentialSubject.role"], "fallback": "Employee"},
+      "properties": [{"path": ["$.credentialSubject.role"], "label": "Role"}]
+    },
+    "styles": {"thumbnail": {"uri": "https://issuer.example.com/logo.png"}, "background": {"color": "#12107c"}}
+  }]
+}`, authtest.SchemaURL)
+
+	source, err := auth.NewCredentialManifestDisplay([]byte(manifest))
+	if err != nil {
+		t.Fatalf("NewCredentialManifestDisplay failed: %v", err)
+	}
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	claims, err := a.VerifyToken(context.Background(), token, auth.WithDisplay(source))
+	if err != nil {
+		t.Fatalf("VerifyToken failed: %v", err)
+	}
+
+	display := claims[0].Display
+	if display == nil {
+		t.Fatal("expected display metadata on claims")
+	}
+	if display.Name != "Employee Badge" || display.Description != "viewer" || display.BackgroundColor != "#12107c" {
+		t.Errorf("unexpected display: %+v", display)
+	}
+	if display.Logo == nil || display.Claims["role"].Name != "Role" {
+		t.Errorf("unexpected display images or claims: %+v", display)
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// Validation errors returned by CreateToken before any verification or signing work is done.
+var (
+	// ErrNilProvider is returned when the Auth has no signing provider.
+	ErrNilProvider = errors.New("provider is nil")
+	// ErrEmptyCredentialList is returned when no credential is given.
+	ErrEmptyCredentialList = errors.New("credential list is empty")
+	// ErrInvalidHolderDID is returned when the holder DID is empty or not a valid DID.
+	ErrInvalidHolderDID = errors.New("invalid holder DID")
+	// ErrMalformedCredential is returned, wrapped in a CredentialError, for credentials that are not well-formed JWTs.
+	ErrMalformedCredential = errors.New("malformed credential")
+)
+
+// ErrIssuerMismatch is returned for credentials whose issuer, or presentations whose holder, is not
+// the DID whose key signed them.
+var ErrIssuerMismatch = errors.New("credential issuer does not match signing key")
+
+// ErrProofPurpose is returned when WithProofPurposes is given and a proof's key is not listed under the
+// verification relationship of its purpose in the signer's DID document.
+var ErrProofPurpose = errors.New("verification method is not authorized for the proof purpose")
+
+// CredentialError reports a failure to parse or verify one credential of a presentation.
+// ID and Issuer are filled in on a best-effort basis, even when the credential itself is invalid.
+type CredentialError struct {
+	Index  int    // Position of the credential in the presentation or input list
+	ID     string // Credential id (or jti) when it could be read
+	Issuer string // Credential issuer (or iss) when it could be read
+	Err    error  // Underlying error
+}
+
+func (e *CredentialError) Error() string {
+	var details []string
+	if e.ID != "" {
+		details = append(details, "id="+e.ID)
+	}
+	if e.Issuer != "" {
+		details = append(details, "issuer="+e.Issuer)
+	}
+
+	if len(details) == 0 {
+		return fmt.Sprintf("credential %d: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("credential %d (%s): %v", e.Index, strings.Join(details, ", "), e.Err)
+}
+
+func (e *CredentialError) Unwrap() error {
+	return e.Err
+}
+
+// newCredentialError wraps err with the index of the credential and whatever identifying
+// information can be decoded from the raw credential.
+func newCredentialError(index int, rawCredential any, err error) error {
+	credErr := &CredentialError{Index: index, Err: err}
+
+	raw, ok := rawCredential.(string)
+	if !ok {
+		return credErr
+	}
+
+	token, parseErr := parseJWT(raw)
+	if parseErr != nil {
+		return credErr
+	}
+
+	credErr.ID = stringField(token.payload, "jti")
+	credErr.Issuer = stringField(token.payload, "iss")
+	if vcData, ok := token.payload["vc"].(map[string]any); ok {
+		if id := stringField(vcData, "id"); id != "" {
+			credErr.ID = id
+		}
+		if issuer := issuerID(vcData["issuer"]); issuer != "" {
+			credErr.Issuer = issuer
+		}
+	}
+
+	return credErr
+}
+
+// PanicError is returned by VerifyToken, VerifyCredential and VerifyProof when verification panicked.
+// The panic is recovered so that a malicious token cannot crash the verifier; it always indicates a bug,
+// either in this package, a dependency or a user-supplied callback such as an IssuerRegistry.
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack trace of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("verification panicked: %v", e.Value)
+}
+
+// recoverPanic converts a panic in the calling function into a *PanicError stored in err.
+// It must be deferred directly.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
@@ -0,0 +1,60 @@
+// Package ethaddr normalizes Ethereum addresses, so the addresses of DIDs, provider options and Vault
+// accounts compare equal whatever their case, and a mistyped EIP-55 checksummed address is caught
+// instead of naming another account.
+package ethaddr
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrInvalid is returned for a string that is not 40 hex digits, with or without the "0x" prefix.
+	ErrInvalid = errors.New("invalid Ethereum address")
+	// ErrChecksum is returned for a mixed-case address whose EIP-55 checksum does not match.
+	ErrChecksum = errors.New("Ethereum address has an invalid EIP-55 checksum")
+)
+
+// Normalize returns the canonical form of an address: "0x" followed by 40 lowercase hex digits. The
+// "0x" prefix is optional. A mixed-case address must carry a valid EIP-55 checksum; all-lowercase and
+// all-uppercase addresses carry none and are accepted as is.
+func Normalize(s string) (string, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(digits) != 2*common.AddressLength {
+		return "", fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	if _, err := hex.DecodeString(digits); err != nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+
+	lower := strings.ToLower(digits)
+	if digits != lower && digits != strings.ToUpper(digits) {
+		if "0x"+digits != common.HexToAddress(lower).Hex() {
+			return "", fmt.Errorf("%w: %q", ErrChecksum, s)
+		}
+	}
+	return "0x" + lower, nil
+}
+
+// Checksum returns the EIP-55 checksummed form of an address accepted by Normalize.
+func Checksum(s string) (string, error) {
+	address, err := Normalize(s)
+	if err != nil {
+		return "", err
+	}
+	return common.HexToAddress(address).Hex(), nil
+}
+
+// Equal reports whether a and b are valid addresses of the same account.
+func Equal(a, b string) bool {
+	normalizedA, err := Normalize(a)
+	if err != nil {
+		return false
+	}
+	normalizedB, err := Normalize(b)
+	return err == nil && normalizedA == normalizedB
+}
@@ -0,0 +1,48 @@
+package ethaddr_test
+
+import (
+	"errors"
+	"testing"
+
+	"github/hovanhoa/go-vc-auth/ethaddr"
+)
+
+// Checksummed test vector from EIP-55.
+const (
+	checksummed = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	lower       = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr error
+	}{
+		{checksummed, nil},
+		{lower, nil},
+		{lower[2:], nil},
+		{"0X5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", nil},
+		{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", ethaddr.ErrChecksum},
+		{lower[:41], ethaddr.ErrInvalid},
+		{lower + "00", ethaddr.ErrInvalid},
+		{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaeg", ethaddr.ErrInvalid},
+		{"", ethaddr.ErrInvalid},
+	}
+	for _, tt := range tests {
+		got, err := ethaddr.Normalize(tt.in)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Normalize(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != lower {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, lower)
+		}
+	}
+
+	if got, err := ethaddr.Checksum(lower[2:]); err != nil || got != checksummed {
+		t.Errorf("Checksum = %q, %v; want %q", got, err, checksummed)
+	}
+	if !ethaddr.Equal(checksummed, lower[2:]) || ethaddr.Equal(lower, "0x"+lower[3:]+"0") {
+		t.Error("Equal compared addresses wrongly")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
 	"github/hovanhoa/go-vc-auth/provider"
 )
 
@@ -20,40 +21,64 @@ func ExampleNewAuth() {
 	// Output: Auth instance created: true
 }
 
-// ExampleAuth_CreateToken demonstrates how to create a VP token from Verifiable Credentials.
-func ExampleAuth_CreateToken() {
-	// Initialize Auth with provider
-	provider := provider.NewVaultProvider("https://vault-dev.pila.vn", "", 3)
-	authInstance := auth.NewAuth(provider, "https://auth-dev.pila.vn/api/v1/did")
+// exampleSetup publishes an issuer and a holder in an in-memory DID registry and issues the holder a
+// credential, returning an Auth wired to the registry that signs with both keys.
+func exampleSetup() (authInstance *auth.Service, vcJwt string, holder *authtest.Identity, err error) {
+	registry := authtest.NewRegistry()
+	issuer, err := registry.NewIdentity()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if holder, err = registry.NewIdentity(); err != nil {
+		return nil, "", nil, err
+	}
 
-	// Prepare VC JWT tokens (these are example tokens - replace with real ones)
-	vcJwts := []string{
-		"eyJhbGciOiJFUzI1NksiLCJraWQiOiJkaWQ6bmRhOnRlc3RuZXQ6MHgxNmM1MTMwZGVmNjQ5NmY1ZGU5M2Y5MDc2YTVjZWIwNWNlNTllNGIwI2tleS0xIiwidHlwIjoiSldUIn0...",
-		// Add more VC JWT tokens as needed
+	authInstance = auth.NewAuth(authtest.NewSigner(issuer, holder), "", registry.Options()...)
+	results, err := authInstance.IssueCredentials(context.Background(), []auth.CredentialDocument{{
+		Issuer:  issuer.DID,
+		Schemas: []auth.CredentialSchema{{ID: authtest.SchemaURL, Type: "JsonSchema"}},
+		Subject: map[string]any{"id": holder.DID, "role": "viewer"},
+	}}, issuer.Address)
+	if err != nil {
+		return nil, "", nil, err
 	}
+	return authInstance, results[0].Credential, holder, results[0].Err
+}
 
-	// Holder DID - the entity that owns and presents these credentials
-	holderDid := "did:nda:testnet:0x2af7e8ebfec14f5e39469d2ce8442a5eef9f3fa4"
+// ExampleAuth_CreateToken demonstrates how to create a VP token from Verifiable Credentials.
+func ExampleAuth_CreateToken() {
+	// Initialize Auth; in production, pass a Vault provider and the DID registry URL
+	authInstance, vcJwt, holder, err := exampleSetup()
+	if err != nil {
+		fmt.Printf("Setup failed: %v\n", err)
+		return
+	}
 
-	// Create a VP token containing the VCs
-	token, err := authInstance.CreateToken(context.Background(), vcJwts, holderDid)
+	// Create a VP token containing the VCs, signed with the holder key
+	token, err := authInstance.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
 	if err != nil {
 		fmt.Printf("Error creating token: %v\n", err)
 		return
 	}
 
-	fmt.Printf("Token created successfully (length: %d)\n", len(token))
-	// Output: Token created successfully (length: <some number>)
+	fmt.Printf("Token created successfully: %v\n", token != "")
+	// Output: Token created successfully: true
 }
 
 // ExampleAuth_VerifyToken demonstrates how to verify a VP token and extract VC claims.
 func ExampleAuth_VerifyToken() {
-	// Initialize Auth with provider
-	provider := provider.NewVaultProvider("https://vault-dev.pila.vn", "", 3)
-	authInstance := auth.NewAuth(provider, "https://auth-dev.pila.vn/api/v1/did")
+	authInstance, vcJwt, holder, err := exampleSetup()
+	if err != nil {
+		fmt.Printf("Setup failed: %v\n", err)
+		return
+	}
 
 	// VP token to verify (this would typically come from a client request)
-	token := "eyJhbGciOiJFUzI1NksiLCJraWQiOiJkaWQ6bmRhOnRlc3RuZXQ6MHgyYWY3ZThlYmZlYzE0ZjVlMzk0NjlkMmNlODQ0MmE1ZWVmOWYzZmE0I2tleS0xIiwidHlwIjoiSldUIn0..."
+	token, err := authInstance.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		fmt.Printf("Error creating token: %v\n", err)
+		return
+	}
 
 	// Verify the token and extract VC claims
 	claims, err := authInstance.VerifyToken(context.Background(), token)
@@ -64,27 +89,27 @@ func ExampleAuth_VerifyToken() {
 
 	// Process the claims
 	for i, claim := range claims {
-		claimJSON, _ := json.MarshalIndent(claim, "", "  ")
-		fmt.Printf("VC Claim %d:\n%s\n", i+1, string(claimJSON))
+		claimJSON, _ := json.Marshal(claim.Subject().Claims)
+		fmt.Printf("VC Claim %d: %s\n", i+1, claimJSON)
 	}
 
 	fmt.Printf("Token verified successfully, found %d credential(s)\n", len(claims))
-	// Output: Token verified successfully, found <number> credential(s)
+	// Output:
+	// VC Claim 1: {"role":"viewer"}
+	// Token verified successfully, found 1 credential(s)
 }
 
 // ExampleAuth_workflow demonstrates a complete workflow: creating and verifying a token.
 func ExampleAuth_workflow() {
-	// Step 1: Initialize Auth
-	provider := provider.NewVaultProvider("https://vault-dev.pila.vn", "", 3)
-	authInstance := auth.NewAuth(provider, "https://auth-dev.pila.vn/api/v1/did")
-
-	// Step 2: Create a token from VCs
-	vcJwts := []string{
-		"eyJhbGciOiJFUzI1NksiLCJraWQiOiJkaWQ6bmRhOnRlc3RuZXQ6MHgxNmM1MTMwZGVmNjQ5NmY1ZGU5M2Y5MDc2YTVjZWIwNWNlNTllNGIwI2tleS0xIiwidHlwIjoiSldUIn0...",
+	// Step 1: Initialize Auth and obtain a credential
+	authInstance, vcJwt, holder, err := exampleSetup()
+	if err != nil {
+		fmt.Printf("Setup failed: %v\n", err)
+		return
 	}
-	holderDid := "did:nda:testnet:0x2af7e8ebfec14f5e39469d2ce8442a5eef9f3fa4"
 
-	token, err := authInstance.CreateToken(context.Background(), vcJwts, holderDid)
+	// Step 2: Create a token from VCs
+	token, err := authInstance.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
 	if err != nil {
 		fmt.Printf("Failed to create token: %v\n", err)
 		return
@@ -98,5 +123,5 @@ func ExampleAuth_workflow() {
 	}
 
 	fmt.Printf("Workflow completed: created and verified token with %d credential(s)\n", len(claims))
-	// Output: Workflow completed: created and verified token with <number> credential(s)
+	// Output: Workflow completed: created and verified token with 1 credential(s)
 }
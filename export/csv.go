@@ -0,0 +1,38 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// csvWriter writes records as RFC 4180 CSV.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// NewCSVWriter returns a Writer producing CSV on w. Values starting with =, +, - or @ are prefixed
+// with a single quote so spreadsheet applications do not evaluate them as formulas.
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteHeader(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) WriteRecord(values []string) error {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+			value = "'" + value
+		}
+		escaped[i] = value
+	}
+	return c.w.Write(escaped)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,261 @@
+// Package export flattens verified credentials into tabular records for reporting pipelines,
+// masking personal data on the way out.
+package export
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+)
+
+// Fixed columns of every record, followed by one "subject.<claim>" column per subject claim.
+// Nested claims are flattened with dots, e.g. "subject.address.city".
+const (
+	ColumnID         = "id"
+	ColumnTypes      = "type"
+	ColumnIssuer     = "issuer"
+	ColumnValidFrom  = "validFrom"
+	ColumnValidUntil = "validUntil"
+	ColumnSubjectID  = "subject.id"
+)
+
+var fixedColumns = []string{ColumnID, ColumnTypes, ColumnIssuer, ColumnValidFrom, ColumnValidUntil, ColumnSubjectID}
+
+// Writer receives the flattened records, e.g. a CSV writer or a Parquet writer. WriteHeader is
+// called once per Export, before the records.
+type Writer interface {
+	WriteHeader(columns []string) error
+	WriteRecord(values []string) error
+	Flush() error
+}
+
+// Mask rewrites the value of a column before it is written. Empty values are never masked.
+type Mask func(value string) string
+
+// Redact replaces the value entirely.
+func Redact() Mask {
+	return func(string) string { return "***" }
+}
+
+// Pseudonymize replaces the value with its HMAC-SHA256 under key, so records can still be joined
+// on the column without revealing it.
+func Pseudonymize(key []byte) Mask {
+	return func(value string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+}
+
+// KeepLast keeps the last n characters of the value and masks the rest, e.g. "*****1234".
+func KeepLast(n int) Mask {
+	return func(value string) string {
+		runes := []rune(value)
+		if len(runes) <= n {
+			return strings.Repeat("*", len(runes))
+		}
+		return strings.Repeat("*", len(runes)-n) + string(runes[len(runes)-n:])
+	}
+}
+
+// maskRule applies a Mask to the columns matching a path.Match pattern.
+type maskRule struct {
+	pattern string
+	mask    Mask
+}
+
+// Exporter flattens batches of VcClaims into records. An Exporter is safe for concurrent use.
+type Exporter struct {
+	columns []string
+	masks   []maskRule
+	drop    []string
+}
+
+// Option configures an Exporter.
+type Option func(*Exporter)
+
+// WithColumns fixes the columns and their order. Without it each Export writes the fixed columns
+// followed by the sorted subject claims found in the batch, so streaming pipelines should fix them.
+func WithColumns(columns ...string) Option {
+	return func(e *Exporter) {
+		e.columns = columns
+	}
+}
+
+// WithMask masks the columns matching pattern (path.Match syntax, e.g. "subject.email" or
+// "subject.address.*"). The first matching rule applies.
+func WithMask(pattern string, mask Mask) Option {
+	return func(e *Exporter) {
+		e.masks = append(e.masks, maskRule{pattern: pattern, mask: mask})
+	}
+}
+
+// WithDrop leaves the columns matching pattern out of the export altogether.
+func WithDrop(pattern string) Option {
+	return func(e *Exporter) {
+		e.drop = append(e.drop, pattern)
+	}
+}
+
+// NewExporter creates an Exporter.
+func NewExporter(opts ...Option) (*Exporter, error) {
+	e := &Exporter{}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	for _, rule := range e.masks {
+		if _, err := path.Match(rule.pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid mask pattern %q: %w", rule.pattern, err)
+		}
+	}
+	for _, pattern := range e.drop {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid drop pattern %q: %w", pattern, err)
+		}
+	}
+	return e, nil
+}
+
+// Export writes the header and one record per credential subject of batch to w, then flushes it.
+func (e *Exporter) Export(w Writer, batch []auth.VcClaims) error {
+	var rows []map[string]string
+	for _, claims := range batch {
+		rows = append(rows, flatten(claims)...)
+	}
+
+	columns := e.columns
+	if columns == nil {
+		columns = discoverColumns(rows)
+	}
+	columns = e.keep(columns)
+
+	if err := w.WriteHeader(columns); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	for _, row := range rows {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = e.mask(column, row[column])
+		}
+		if err := w.WriteRecord(values); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+	return w.Flush()
+}
+
+// keep removes dropped columns.
+func (e *Exporter) keep(columns []string) []string {
+	kept := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if !matchAny(e.drop, column) {
+			kept = append(kept, column)
+		}
+	}
+	return kept
+}
+
+// mask applies the first mask rule matching column to value.
+func (e *Exporter) mask(column, value string) string {
+	if value == "" {
+		return value
+	}
+	for _, rule := range e.masks {
+		if ok, _ := path.Match(rule.pattern, column); ok {
+			return rule.mask(value)
+		}
+	}
+	return value
+}
+
+func matchAny(patterns []string, column string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, column); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// flatten returns one row per credential subject of claims.
+func flatten(claims auth.VcClaims) []map[string]string {
+	base := map[string]string{
+		ColumnID:     claims.ID,
+		ColumnTypes:  strings.Join(claims.Types, ";"),
+		ColumnIssuer: claims.Issuer,
+	}
+	if !claims.ValidFrom.IsZero() {
+		base[ColumnValidFrom] = claims.ValidFrom.UTC().Format(time.RFC3339)
+	}
+	if !claims.ValidUntil.IsZero() {
+		base[ColumnValidUntil] = claims.ValidUntil.UTC().Format(time.RFC3339)
+	}
+
+	subjects := claims.CredentialSubject
+	if len(subjects) == 0 {
+		subjects = []auth.CredentialSubject{{}}
+	}
+
+	rows := make([]map[string]string, len(subjects))
+	for i, subject := range subjects {
+		row := make(map[string]string, len(base)+len(subject.Claims)+1)
+		for column, value := range base {
+			row[column] = value
+		}
+		row[ColumnSubjectID] = subject.ID
+		for name, value := range subject.Claims {
+			flattenValue(row, "subject."+name, value)
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+// flattenValue stores value under column, descending into objects; arrays are kept as JSON.
+func flattenValue(row map[string]string, column string, value any) {
+	switch v := value.(type) {
+	case nil:
+	case string:
+		row[column] = v
+	case map[string]any:
+		for name, nested := range v {
+			flattenValue(row, column+"."+name, nested)
+		}
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			row[column] = fmt.Sprint(v)
+			return
+		}
+		row[column] = string(encoded)
+	}
+}
+
+// discoverColumns returns the fixed columns followed by the sorted other columns of rows.
+func discoverColumns(rows []map[string]string) []string {
+	seen := make(map[string]bool)
+	for _, column := range fixedColumns {
+		seen[column] = true
+	}
+
+	var extra []string
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				extra = append(extra, column)
+			}
+		}
+	}
+	sort.Strings(extra)
+	return append(append([]string(nil), fixedColumns...), extra...)
+}
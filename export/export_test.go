@@ -0,0 +1,114 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+)
+
+// recordingWriter keeps what it is given, as a Parquet writer adapter would receive it.
+type recordingWriter struct {
+	header  []string
+	records [][]string
+	flushed bool
+}
+
+func (r *recordingWriter) WriteHeader(columns []string) error { r.header = columns; return nil }
+func (r *recordingWriter) WriteRecord(values []string) error {
+	r.records = append(r.records, values)
+	return nil
+}
+func (r *recordingWriter) Flush() error { r.flushed = true; return nil }
+
+func testBatch() []auth.VcClaims {
+	return []auth.VcClaims{{
+		ID:        "urn:uuid:1",
+		Types:     []string{"VerifiableCredential", "EmployeeCredential"},
+		Issuer:    "did:example:issuer",
+		ValidFrom: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		CredentialSubject: []auth.CredentialSubject{{
+			ID: "did:example:alice",
+			Claims: map[string]any{
+				"email":   "alice@example.com",
+				"phone":   "+15551234567",
+				"age":     float64(42),
+				"address": map[string]any{"city": "Hanoi", "street": "1 Main St"},
+				"roles":   []any{"admin", "viewer"},
+			},
+		}},
+	}}
+}
+
+func TestExport(t *testing.T) {
+	exporter, err := NewExporter(
+		WithMask("subject.email", Pseudonymize([]byte("k"))),
+		WithMask("subject.phone", KeepLast(4)),
+		WithMask("subject.address.*", Redact()),
+		WithDrop("validUntil"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var w recordingWriter
+	if err := exporter.Export(&w, testBatch()); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	wantHeader := "id,type,issuer,validFrom,subject.id,subject.address.city,subject.address.street,subject.age,subject.email,subject.phone,subject.roles"
+	if got := strings.Join(w.header, ","); got != wantHeader {
+		t.Errorf("header = %s\nwant     %s", got, wantHeader)
+	}
+	if len(w.records) != 1 || !w.flushed {
+		t.Fatalf("got %d records (flushed %v), want 1 flushed", len(w.records), w.flushed)
+	}
+
+	record := map[string]string{}
+	for i, column := range w.header {
+		record[column] = w.records[0][i]
+	}
+	want := map[string]string{
+		"type":                 "VerifiableCredential;EmployeeCredential",
+		"validFrom":            "2025-01-02T03:04:05Z",
+		"subject.id":           "did:example:alice",
+		"subject.address.city": "***",
+		"subject.age":          "42",
+		"subject.phone":        "********4567",
+		"subject.roles":        `["admin","viewer"]`,
+	}
+	for column, value := range want {
+		if record[column] != value {
+			t.Errorf("%s = %q, want %q", column, record[column], value)
+		}
+	}
+	if email := record["subject.email"]; email == "alice@example.com" || len(email) != 64 {
+		t.Errorf("email not pseudonymized: %q", email)
+	}
+}
+
+func TestExportFixedColumnsCSV(t *testing.T) {
+	exporter, err := NewExporter(WithColumns("id", "subject.id", "subject.note"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := testBatch()
+	batch[0].CredentialSubject[0].Claims["note"] = "=HYPERLINK(\"http://evil\")"
+
+	var out bytes.Buffer
+	if err := exporter.Export(NewCSVWriter(&out), batch); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	want := "id,subject.id,subject.note\nurn:uuid:1,did:example:alice,\"'=HYPERLINK(\"\"http://evil\"\")\"\n"
+	if out.String() != want {
+		t.Errorf("CSV = %q\nwant  %q", out.String(), want)
+	}
+}
+
+func TestNewExporterRejectsBadPattern(t *testing.T) {
+	if _, err := NewExporter(WithMask("subject.[", Redact())); err == nil {
+		t.Error("expected an error")
+	}
+}
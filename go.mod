@@ -2,20 +2,25 @@ module github/hovanhoa/go-vc-auth
 
 go 1.24.4
 
-require github.com/pilacorp/go-credential-sdk v1.3.0
+require (
+	github.com/beevik/etree v1.7.0
+	github.com/ethereum/go-ethereum v1.16.7
+	github.com/pilacorp/go-credential-sdk v1.3.0
+	github.com/russellhaering/goxmldsig v1.6.1
+	github.com/xeipuuv/gojsonschema v1.2.0
+	golang.org/x/crypto v0.36.0
+)
 
 require (
 	github.com/ProjectZKM/Ziren/crates/go-runtime/zkvm_runtime v0.0.0-20251001021608-1fe7b43fc4d6 // indirect
 	github.com/btcsuite/btcd/btcec/v2 v2.3.5 // indirect
 	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
-	github.com/ethereum/go-ethereum v1.16.7 // indirect
 	github.com/holiman/uint256 v1.3.2 // indirect
+	github.com/jonboulle/clockwork v0.5.0 // indirect
 	github.com/piprate/json-gold v0.7.0 // indirect
 	github.com/pquerna/cachecontrol v0.0.0-20180517163645-1555304b9b35 // indirect
 	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
 	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
-	github.com/xeipuuv/gojsonschema v1.2.0 // indirect
-	golang.org/x/crypto v0.36.0 // indirect
 	golang.org/x/exp v0.0.0-20250718183923-645b1fa84792 // indirect
 	golang.org/x/sys v0.36.0 // indirect
 )
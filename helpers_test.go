@@ -0,0 +1,139 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/provider"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pilacorp/go-credential-sdk/credential/vc"
+)
+
+// testRegistry is the authtest DID registry of a test, with helpers issuing credentials directly.
+type testRegistry struct {
+	*authtest.Registry
+}
+
+func newTestRegistry(t testing.TB) *testRegistry {
+	t.Helper()
+	return &testRegistry{Registry: authtest.NewRegistry()}
+}
+
+// newAuth creates an Auth wired to the registry and the other authtest fakes; opts are applied after them.
+func (r *testRegistry) newAuth(p provider.Provider, opts ...auth.Option) *auth.Service {
+	return auth.NewAuth(p, "", append(r.Options(), opts...)...)
+}
+
+// serve serves the registry over HTTP and returns its base URL, for tests of the HTTP resolver.
+func (r *testRegistry) serve(t testing.TB) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id, err := url.PathUnescape(strings.TrimPrefix(req.URL.EscapedPath(), "/did/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		doc, err := r.Resolve(req.Context(), id)
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/did"
+}
+
+// newIdentity generates a key pair and publishes its DID document.
+func (r *testRegistry) newIdentity(t testing.TB) *authtest.Identity {
+	t.Helper()
+
+	identity, err := r.NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity
+}
+
+// update republishes the document of id after applying change to it.
+func (r *testRegistry) update(t testing.TB, id string, change func(doc *did.Document)) {
+	t.Helper()
+
+	doc, err := r.Resolve(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := *doc
+	change(&updated)
+	r.Publish(&updated)
+}
+
+// issueCredential issues a JWT VC from issuer to subject.
+func (r *testRegistry) issueCredential(t *testing.T, issuer, subject *authtest.Identity, claims map[string]any) string {
+	t.Helper()
+	return r.issueCredentialWithHeader(t, issuer, subject, claims, nil)
+}
+
+// issueCredentialWithHeader issues a JWT VC whose JOSE header is extended with extraHeader.
+func (r *testRegistry) issueCredentialWithHeader(t *testing.T, issuer, subject *authtest.Identity, claims, extraHeader map[string]any) string {
+	t.Helper()
+
+	credential, err := vc.NewJWTCredential(vc.CredentialContents{
+		Context:   []any{"https://www.w3.org/ns/credentials/v2"},
+		ID:        "urn:uuid:" + subject.Address,
+		Types:     []string{"VerifiableCredential"},
+		Issuer:    issuer.DID,
+		ValidFrom: time.Now().Add(-time.Minute).UTC().Truncate(time.Second),
+		Subject:   []vc.Subject{{ID: subject.DID, CustomFields: claims}},
+		Schemas:   []vc.Schema{{ID: authtest.SchemaURL, Type: "JsonSchema"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create credential: %v", err)
+	}
+
+	signingInput, err := credential.GetSigningInput()
+	if err != nil {
+		t.Fatalf("failed to get signing input: %v", err)
+	}
+
+	parts := strings.Split(string(signingInput), ".")
+	if len(extraHeader) > 0 {
+		var header map[string]any
+		headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+		_ = json.Unmarshal(headerJSON, &header)
+		for k, v := range extraHeader {
+			header[k] = v
+		}
+		headerJSON, _ = json.Marshal(header)
+		parts[0] = base64.RawURLEncoding.EncodeToString(headerJSON)
+	}
+
+	return signJWT(t, parts[0]+"."+parts[1], issuer.Key)
+}
+
+// signJWT appends an ES256K signature by key to a JWT signing input.
+func signJWT(t testing.TB, signingInput string, key *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature[:64])
+}
@@ -0,0 +1,365 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/anchor"
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/did"
+
+	"github.com/pilacorp/go-credential-sdk/credential/vc"
+)
+
+// defaultIssueConcurrency is the number of credentials IssueCredentials signs at once by default.
+const defaultIssueConcurrency = 4
+
+// ErrStatusListFull is returned by a StatusAllocator that has no index left.
+var ErrStatusListFull = errors.New("status list is full")
+
+// CredentialDocument is the unsigned content of a credential to issue.
+type CredentialDocument struct {
+	Context    []any              // JSON-LD contexts; the VC 2.0 context when empty
+	ID         string             // Credential id; a urn:uuid is generated when empty
+	Types      []string           // Credential types; VerifiableCredential is added when missing
+	Issuer     string             // Issuer DID; the credential is signed with its key-1 verification method
+	ValidFrom  time.Time          // Defaults to the issuance time
+	ValidUntil time.Time          // Zero for no expiry
+	Schemas    []CredentialSchema // credentialSchema entries
+	Status     []CredentialStatus // credentialStatus entries; WithStatusList appends one per credential
+	Subject    map[string]any     // credentialSubject claims; "id" is the subject DID
+}
+
+// IssuanceResult reports the outcome of issuing one credential.
+type IssuanceResult struct {
+	Index      int               // Index of the document in the documents given to IssueCredentials
+	Credential string            // Signed JWT VC; empty when Err is set
+	Status     *CredentialStatus // Status list entry allocated to the credential, if any
+	Anchor     *anchor.Anchor    // Anchor of the credential, or of the Merkle root with WithAnchorBatch
+	Err        error             // Why the row could not be issued
+}
+
+// StatusAllocator hands out status list entries for newly issued credentials.
+// It is called concurrently. Entries allocated to credentials that then fail to sign are not reused.
+type StatusAllocator interface {
+	Allocate(ctx context.Context) (CredentialStatus, error)
+}
+
+// IssueOpt configures an IssueCredentials call.
+// IssueOpt values can be mixed with provider options in IssueCredentials' opts; every argument that is
+// not an IssueOpt is forwarded to the provider unchanged.
+type IssueOpt func(*issueOptions)
+
+// issueOptions holds configuration for bulk issuance.
+type issueOptions struct {
+	concurrency int
+	interval    time.Duration
+	statusList  StatusAllocator
+	anchor      anchor.Submitter
+	anchorBatch anchor.Submitter
+}
+
+// WithIssueConcurrency sets how many credentials are signed at once (default: 4).
+func WithIssueConcurrency(n int) IssueOpt {
+	return func(o *issueOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithIssueRate limits signing to n credentials per period, e.g. to stay within a KMS quota.
+func WithIssueRate(n int, per time.Duration) IssueOpt {
+	return func(o *issueOptions) {
+		if n > 0 {
+			o.interval = per / time.Duration(n)
+		}
+	}
+}
+
+// WithStatusList appends a status entry allocated from allocator to every credential.
+func WithStatusList(allocator StatusAllocator) IssueOpt {
+	return func(o *issueOptions) {
+		o.statusList = allocator
+	}
+}
+
+// IssueCredentials issues one credential per document. Documents are signed concurrently through the
+// provider, subject to WithIssueRate. A document that fails does not stop the others; its error is
+// reported in its result. The returned error is set only when nothing could be issued, e.g. there is no
+// provider, or ctx was cancelled.
+func (a *Service) IssueCredentials(ctx context.Context, documents []CredentialDocument, opts ...any) ([]IssuanceResult, error) {
+	options := &issueOptions{concurrency: defaultIssueConcurrency}
+	var providerOpts []any
+	for _, opt := range opts {
+		if issueOpt, ok := opt.(IssueOpt); ok {
+			issueOpt(options)
+			continue
+		}
+		providerOpts = append(providerOpts, opt)
+	}
+
+	if a.provider == nil {
+		return nil, ErrNilProvider
+	}
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+
+	limiter := &rateLimiter{clock: a.clock, interval: options.interval}
+	if options.anchorBatch != nil {
+		options.anchor = nil
+		return a.issueAnchoredBatch(ctx, documents, limiter, options, providerOpts), ctx.Err()
+	}
+
+	results := make([]IssuanceResult, len(documents))
+	forEachConcurrently(len(documents), options.concurrency, func(index int) {
+		results[index] = a.issueDocument(ctx, index, documents[index], limiter, options, providerOpts)
+	})
+	return results, ctx.Err()
+}
+
+// IssueRows issues one credential per subject row from template, e.g. rows read from a CSV export:
+// each row's claims are merged over the template's Subject, and the result for rows[i] has Index i.
+// Rows are issued as by IssueCredentials, with the same opts. The returned error is also set when the
+// template's issuer DID is invalid, as no row could be issued.
+func (a *Service) IssueRows(ctx context.Context, template CredentialDocument, rows []map[string]any, opts ...any) ([]IssuanceResult, error) {
+	if _, err := did.Parse(template.Issuer); err != nil {
+		return nil, fmt.Errorf("invalid issuer DID: %w", err)
+	}
+
+	documents := make([]CredentialDocument, len(rows))
+	for i, claims := range rows {
+		documents[i] = template
+		documents[i].Subject = make(map[string]any, len(template.Subject)+len(claims))
+		maps.Copy(documents[i].Subject, template.Subject)
+		maps.Copy(documents[i].Subject, claims)
+	}
+	return a.IssueCredentials(ctx, documents, opts...)
+}
+
+// forEachConcurrently calls fn for 0..n-1 from at most concurrency goroutines and waits for all calls.
+func forEachConcurrently(n, concurrency int, fn func(index int)) {
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(concurrency, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				fn(index)
+			}
+		}()
+	}
+
+	for index := range n {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// issueDocument builds, rate limits and signs one credential.
+func (a *Service) issueDocument(ctx context.Context, index int, document CredentialDocument, limiter *rateLimiter, options *issueOptions, providerOpts []any) IssuanceResult {
+	result, signingInput := a.prepareDocument(ctx, index, document, options)
+	if result.Err == nil {
+		a.finishDocument(ctx, &result, signingInput, limiter, options, providerOpts)
+	}
+	return result
+}
+
+// prepareDocument builds the JWT signing input of one credential, allocating its status entry.
+func (a *Service) prepareDocument(ctx context.Context, index int, document CredentialDocument, options *issueOptions) (IssuanceResult, string) {
+	result := IssuanceResult{Index: index}
+
+	if _, err := did.Parse(document.Issuer); err != nil {
+		result.Err = fmt.Errorf("invalid issuer DID: %w", err)
+		return result, ""
+	}
+
+	contents, err := a.credentialContents(document)
+	if err != nil {
+		result.Err = err
+		return result, ""
+	}
+
+	if options.statusList != nil {
+		status, err := options.statusList.Allocate(ctx)
+		if err != nil {
+			result.Err = fmt.Errorf("failed to allocate status: %w", err)
+			return result, ""
+		}
+		result.Status = &status
+		contents.CredentialStatus = append(contents.CredentialStatus, vc.Status(status))
+	}
+
+	credential, err := vc.NewJWTCredential(contents)
+	if err != nil {
+		result.Err, result.Status = err, nil
+		return result, ""
+	}
+	signingInput, err := credential.GetSigningInput()
+	if err != nil {
+		result.Err, result.Status = err, nil
+		return result, ""
+	}
+	return result, string(signingInput)
+}
+
+// finishDocument rate limits and signs a prepared credential with the issuer's key-1 verification method,
+// then anchors it with WithAnchor. A credential failing any step is dropped with its status entry.
+func (a *Service) finishDocument(ctx context.Context, result *IssuanceResult, signingInput string, limiter *rateLimiter, options *issueOptions, providerOpts []any) {
+	if result.Err = limiter.wait(ctx); result.Err == nil {
+		result.Credential, result.Err = a.signJWT(ctx, signingInput, providerOpts...)
+	}
+	if result.Err == nil && options.anchor != nil {
+		var anchored anchor.Anchor
+		anchored, result.Err = AnchorCredential(ctx, options.anchor, result.Credential)
+		result.Anchor = &anchored
+	}
+	if result.Err != nil {
+		result.Credential, result.Status, result.Anchor = "", nil, nil
+	}
+}
+
+// credentialContents converts document into the SDK credential model, filling in defaults.
+func (a *Service) credentialContents(document CredentialDocument) (vc.CredentialContents, error) {
+	contents := vc.CredentialContents{
+		Context:    document.Context,
+		ID:         document.ID,
+		Types:      document.Types,
+		Issuer:     document.Issuer,
+		ValidFrom:  document.ValidFrom,
+		ValidUntil: document.ValidUntil,
+	}
+
+	if len(contents.Context) == 0 {
+		contents.Context = []any{"https://www.w3.org/ns/credentials/v2"}
+	}
+	if contents.ID == "" {
+		id, err := newCredentialID()
+		if err != nil {
+			return vc.CredentialContents{}, err
+		}
+		contents.ID = id
+	}
+	if !containsString(contents.Types, "VerifiableCredential") {
+		contents.Types = append([]string{"VerifiableCredential"}, contents.Types...)
+	}
+	if contents.ValidFrom.IsZero() {
+		contents.ValidFrom = a.clock.Now().UTC().Truncate(time.Second)
+	}
+
+	subject := vc.Subject{CustomFields: make(map[string]any, len(document.Subject))}
+	for name, value := range document.Subject {
+		if name != "id" {
+			subject.CustomFields[name] = value
+			continue
+		}
+
+		id, ok := value.(string)
+		if !ok {
+			return vc.CredentialContents{}, fmt.Errorf("credentialSubject.id must be a string, got %T", value)
+		}
+		subject.ID = id
+	}
+	contents.Subject = []vc.Subject{subject}
+
+	for _, schema := range document.Schemas {
+		contents.Schemas = append(contents.Schemas, vc.Schema{ID: schema.ID, Type: schema.Type})
+	}
+	for _, status := range document.Status {
+		contents.CredentialStatus = append(contents.CredentialStatus, vc.Status(status))
+	}
+
+	return contents, nil
+}
+
+// newCredentialID returns a random urn:uuid (version 4) credential id.
+func newCredentialID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate credential id: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// rateLimiter spaces operations at least interval apart; a zero interval does not limit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	clock    clock.Clock
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the caller's slot, or until ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil || l.interval <= 0 {
+		return err
+	}
+
+	l.mu.Lock()
+	now := l.clock.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-l.clock.After(delay):
+		return nil
+	}
+}
+
+// statusListAllocator allocates consecutive indices of one status list.
+type statusListAllocator struct {
+	mu      sync.Mutex
+	listURL string
+	purpose string
+	next    int
+	size    int
+}
+
+// NewStatusListAllocator creates a StatusAllocator handing out the indices next, next+1, ... of the
+// BitstringStatusList credential at listURL until size entries are used, then ErrStatusListFull.
+// purpose is the statusPurpose of every entry, e.g. "revocation".
+func NewStatusListAllocator(listURL, purpose string, next, size int) StatusAllocator {
+	return &statusListAllocator{listURL: listURL, purpose: purpose, next: next, size: size}
+}
+
+// Allocate returns the entry for the next free index.
+func (s *statusListAllocator) Allocate(ctx context.Context) (CredentialStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next >= s.size {
+		return CredentialStatus{}, ErrStatusListFull
+	}
+
+	index := strconv.Itoa(s.next)
+	s.next++
+
+	return CredentialStatus{
+		ID:                   s.listURL + "#" + index,
+		Type:                 "BitstringStatusListEntry",
+		StatusPurpose:        s.purpose,
+		StatusListIndex:      index,
+		StatusListCredential: s.listURL,
+	}, nil
+}
@@ -0,0 +1,181 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+func TestIssueCredentials(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holders := []*authtest.Identity{registry.newIdentity(t), registry.newIdentity(t), registry.newIdentity(t)}
+	a := registry.newAuth(authtest.NewSigner(issuer))
+
+	subjects := []map[string]any{
+		{"id": holders[0].DID, "name": "Alice"},
+		{"id": 42, "name": "Broken"},
+		{"id": holders[1].DID, "name": "Bob"},
+		{"id": holders[2].DID, "name": "Carol"},
+		{"id": holders[0].DID, "name": "Mallory"},
+	}
+	documents := make([]auth.CredentialDocument, len(subjects))
+	for i, subject := range subjects {
+		subject["employer"] = "Example Corp"
+		documents[i] = auth.CredentialDocument{
+			Types:   []string{"EmployeeCredential"},
+			Issuer:  issuer.DID,
+			Schemas: []auth.CredentialSchema{{ID: authtest.SchemaURL, Type: "JsonSchema"}},
+			Subject: subject,
+		}
+	}
+	documents[4].Issuer = "not-a-did"
+	statusList := auth.NewStatusListAllocator("https://issuer.example/status/1", "revocation", 7, 9)
+
+	results, err := a.IssueCredentials(context.Background(), documents, issuer.Address,
+		auth.WithIssueConcurrency(2), auth.WithStatusList(statusList))
+	if err != nil {
+		t.Fatalf("IssueCredentials failed: %v", err)
+	}
+	if len(results) != len(documents) {
+		t.Fatalf("expected %d results, got %d", len(documents), len(results))
+	}
+
+	for _, i := range []int{1, 4} {
+		if results[i].Err == nil || results[i].Credential != "" || results[i].Status != nil {
+			t.Fatalf("expected document %d to fail: %+v", i, results[i])
+		}
+	}
+
+	indices := map[string]bool{}
+	issued := 0
+	for i, result := range results {
+		if result.Index != i {
+			t.Fatalf("result %d reports index %d", i, result.Index)
+		}
+		if result.Err != nil {
+			continue
+		}
+		issued++
+
+		claims, err := a.VerifyCredential(context.Background(), result.Credential)
+		if err != nil {
+			t.Fatalf("document %d: issued credential does not verify: %v", i, err)
+		}
+		if claims.Subject().ID != subjects[i]["id"] || !claims.HasType("EmployeeCredential") || claims.ID == "" {
+			t.Fatalf("document %d: unexpected claims %+v", i, claims)
+		}
+		if employer, _ := claims.Subject().Get("employer"); employer != "Example Corp" {
+			t.Fatalf("document %d: employer claim missing: %+v", i, claims.Subject())
+		}
+		if name, _ := claims.Subject().Get("name"); name != subjects[i]["name"] {
+			t.Fatalf("document %d: name claim missing: %+v", i, claims.Subject())
+		}
+		if len(claims.Status) != 1 || claims.Status[0] != *result.Status {
+			t.Fatalf("document %d: status %+v does not match allocation %+v", i, claims.Status, result.Status)
+		}
+		indices[result.Status.StatusListIndex] = true
+	}
+
+	// Only indices 7 and 8 fit in the list, so one well-formed document must report a full list.
+	if issued != 2 || len(indices) != 2 || !indices["7"] || !indices["8"] {
+		t.Fatalf("unexpected allocation: issued %d, indices %v", issued, indices)
+	}
+	full := 0
+	for _, result := range results {
+		if errors.Is(result.Err, auth.ErrStatusListFull) {
+			full++
+		}
+	}
+	if full != 1 {
+		t.Fatalf("expected one ErrStatusListFull, got %d", full)
+	}
+}
+
+func TestIssueCredentialsRateLimit(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	fake := clock.NewFake(time.Now())
+	a := registry.newAuth(authtest.NewSigner(issuer), auth.WithClock(fake))
+
+	document := auth.CredentialDocument{Issuer: issuer.DID, Subject: map[string]any{"id": holder.DID}}
+	documents := []auth.CredentialDocument{document, document, document}
+
+	done := make(chan []auth.IssuanceResult)
+	go func() {
+		results, _ := a.IssueCredentials(context.Background(), documents, issuer.Address,
+			auth.WithIssueConcurrency(3), auth.WithIssueRate(1, time.Second))
+		done <- results
+	}()
+
+	// The first credential is signed at once; the other two wait for their slots.
+	for fake.Waiters() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatal("issuance finished without waiting for the rate limit")
+	default:
+	}
+
+	fake.Advance(2 * time.Second)
+	for _, result := range <-done {
+		if result.Err != nil {
+			t.Fatalf("document %d failed: %v", result.Index, result.Err)
+		}
+	}
+}
+
+func TestIssueRows(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holders := []*authtest.Identity{registry.newIdentity(t), registry.newIdentity(t)}
+	a := registry.newAuth(authtest.NewSigner(issuer))
+
+	template := auth.CredentialDocument{
+		Types:   []string{"EmployeeCredential"},
+		Issuer:  issuer.DID,
+		Schemas: []auth.CredentialSchema{{ID: authtest.SchemaURL, Type: "JsonSchema"}},
+		Subject: map[string]any{"employer": "Example Corp", "name": "unset"},
+	}
+	rows := []map[string]any{
+		{"id": holders[0].DID, "name": "Alice"},
+		{"id": 42},
+		{"id": holders[1].DID, "name": "Bob"},
+	}
+	results, err := a.IssueRows(context.Background(), template, rows, issuer.Address, auth.WithIssueConcurrency(2))
+	if err != nil {
+		t.Fatalf("IssueRows failed: %v", err)
+	}
+	if len(results) != len(rows) || results[1].Err == nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	for _, i := range []int{0, 2} {
+		if results[i].Index != i || results[i].Err != nil {
+			t.Fatalf("row %d: %+v", i, results[i])
+		}
+		claims, err := a.VerifyCredential(context.Background(), results[i].Credential)
+		if err != nil {
+			t.Fatalf("row %d: issued credential does not verify: %v", i, err)
+		}
+		employer, _ := claims.Subject().Get("employer")
+		name, _ := claims.Subject().Get("name")
+		if claims.Subject().ID != rows[i]["id"] || employer != "Example Corp" || name != rows[i]["name"] {
+			t.Errorf("row %d: unexpected subject %+v", i, claims.Subject())
+		}
+	}
+	if len(template.Subject) != 2 || template.Subject["name"] != "unset" {
+		t.Errorf("the template was modified: %+v", template.Subject)
+	}
+
+	template.Issuer = "not-a-did"
+	if _, err := a.IssueRows(context.Background(), template, rows, issuer.Address); err == nil {
+		t.Error("expected an error for an invalid template issuer")
+	}
+}
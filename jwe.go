@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// JWE parameters used for encrypted presentations (RFC 7516, RFC 7518 section 4.6).
+const (
+	jweAlgorithm  = "ECDH-ES"
+	jweEncryption = "A256GCM"
+	jweKeyBits    = 256
+)
+
+// ErrEncryptedToken is returned when VerifyToken receives an encrypted presentation but no
+// decryption key matches it.
+var ErrEncryptedToken = errors.New("cannot decrypt presentation")
+
+// WithEncryption encrypts the VP token to the verifier's public key as a compact JWE
+// (ECDH-ES key agreement, A256GCM content encryption). P-256, P-384, P-521 and X25519 keys are
+// supported. keyID, when not empty, is sent as the JWE "kid" so the verifier can pick its key.
+func WithEncryption(recipient *ecdh.PublicKey, keyID string) CreateOpt {
+	return func(o *createOptions) {
+		o.encryptionKey = recipient
+		o.encryptionKeyID = keyID
+	}
+}
+
+// WithDecryptionKeys lets VerifyToken accept presentations encrypted with WithEncryption to one of keys.
+func WithDecryptionKeys(keys ...*ecdh.PrivateKey) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.decryptionKeys = append(o.decryptionKeys, keys...)
+	}
+}
+
+// isJWE reports whether token is a compact JWE rather than a JWS.
+func isJWE(token string) bool {
+	return strings.Count(strings.Trim(token, "\""), ".") == 4
+}
+
+// encryptJWE encrypts plaintext to recipient as a compact JWE carrying a nested JWT.
+func encryptJWE(plaintext []byte, recipient *ecdh.PublicKey, keyID string) (string, error) {
+	ephemeral, err := recipient.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+
+	epk, err := publicKeyToJWK(ephemeral.PublicKey())
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]any{
+		"alg": jweAlgorithm,
+		"enc": jweEncryption,
+		"cty": "JWT",
+		"epk": epk,
+	}
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal header: %w", err)
+	}
+	protected := base64.RawURLEncoding.EncodeToString(headerJSON)
+
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return "", fmt.Errorf("failed to agree key: %w", err)
+	}
+
+	gcm, err := newContentCipher(concatKDF(shared, jweEncryption, nil, nil, jweKeyBits))
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("failed to generate iv: %w", err)
+	}
+
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{
+		protected,
+		"", // ECDH-ES uses the agreed key directly, so there is no encrypted key
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// decryptJWE decrypts a compact JWE produced by encryptJWE with the first key on the sender's curve
+// that opens it.
+func decryptJWE(token string, keys []*ecdh.PrivateKey) ([]byte, error) {
+	parts := strings.Split(strings.Trim(token, "\""), ".")
+	if len(parts) != 5 {
+		return nil, errors.New("invalid JWE format")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWE header: %w", err)
+	}
+
+	var header struct {
+		Alg string         `json:"alg"`
+		Enc string         `json:"enc"`
+		Epk map[string]any `json:"epk"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("invalid JWE header: %w", err)
+	}
+
+	if header.Alg != jweAlgorithm || header.Enc != jweEncryption {
+		return nil, fmt.Errorf("unsupported JWE algorithm %s/%s", header.Alg, header.Enc)
+	}
+
+	if parts[1] != "" {
+		return nil, errors.New("unexpected encrypted key for ECDH-ES")
+	}
+
+	epk, err := jwkToPublicKey(header.Epk)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+
+	var decoded [3][]byte
+	for i, part := range parts[2:] {
+		if decoded[i], err = base64.RawURLEncoding.DecodeString(part); err != nil {
+			return nil, fmt.Errorf("invalid JWE encoding: %w", err)
+		}
+	}
+	iv, ciphertext, tag := decoded[0], decoded[1], decoded[2]
+
+	for _, key := range keys {
+		if key.Curve() != epk.Curve() {
+			continue
+		}
+
+		shared, err := key.ECDH(epk)
+		if err != nil {
+			continue
+		}
+
+		gcm, err := newContentCipher(concatKDF(shared, jweEncryption, nil, nil, jweKeyBits))
+		if err != nil {
+			return nil, err
+		}
+		if len(iv) != gcm.NonceSize() {
+			return nil, errors.New("invalid JWE iv length")
+		}
+
+		plaintext, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+		if err == nil {
+			return plaintext, nil
+		}
+	}
+
+	return nil, ErrEncryptedToken
+}
+
+func newContentCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// concatKDF derives the content encryption key from the shared secret as defined for ECDH-ES in
+// RFC 7518 section 4.6.2.
+func concatKDF(shared []byte, algorithmID string, partyUInfo, partyVInfo []byte, keyBits int) []byte {
+	var otherInfo []byte
+	for _, field := range [][]byte{[]byte(algorithmID), partyUInfo, partyVInfo} {
+		otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(len(field)))
+		otherInfo = append(otherInfo, field...)
+	}
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(keyBits))
+
+	var key []byte
+	for counter := uint32(1); len(key) < keyBits/8; counter++ {
+		h := sha256.New()
+		_ = binary.Write(h, binary.BigEndian, counter)
+		h.Write(shared)
+		h.Write(otherInfo)
+		key = h.Sum(key)
+	}
+	return key[:keyBits/8]
+}
+
+// curveNames maps JWK curve names to crypto/ecdh curves.
+var curveNames = map[string]ecdh.Curve{
+	"P-256":  ecdh.P256(),
+	"P-384":  ecdh.P384(),
+	"P-521":  ecdh.P521(),
+	"X25519": ecdh.X25519(),
+}
+
+// publicKeyToJWK encodes an ECDH public key as a JWK map.
+func publicKeyToJWK(key *ecdh.PublicKey) (map[string]any, error) {
+	raw := key.Bytes()
+	for name, curve := range curveNames {
+		if curve != key.Curve() {
+			continue
+		}
+
+		if curve == ecdh.X25519() {
+			return map[string]any{"kty": "OKP", "crv": name, "x": base64.RawURLEncoding.EncodeToString(raw)}, nil
+		}
+
+		// Uncompressed point: 0x04 || X || Y
+		size := (len(raw) - 1) / 2
+		return map[string]any{
+			"kty": "EC",
+			"crv": name,
+			"x":   base64.RawURLEncoding.EncodeToString(raw[1 : 1+size]),
+			"y":   base64.RawURLEncoding.EncodeToString(raw[1+size:]),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported curve %v", key.Curve())
+}
+
+// jwkToPublicKey decodes a JWK map produced by publicKeyToJWK.
+func jwkToPublicKey(jwk map[string]any) (*ecdh.PublicKey, error) {
+	curve, ok := curveNames[stringField(jwk, "crv")]
+	if !ok {
+		return nil, fmt.Errorf("unsupported curve %q", stringField(jwk, "crv"))
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(stringField(jwk, "x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid x coordinate: %w", err)
+	}
+
+	if curve == ecdh.X25519() {
+		return curve.NewPublicKey(x)
+	}
+
+	y, err := base64.RawURLEncoding.DecodeString(stringField(jwk, "y"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid y coordinate: %w", err)
+	}
+
+	// Coordinates are left-padded to the field size, which the uncompressed encoding requires.
+	size := len(x)
+	if len(y) > size {
+		size = len(y)
+	}
+	point := append([]byte{0x04}, new(big.Int).SetBytes(x).FillBytes(make([]byte, size))...)
+	point = append(point, new(big.Int).SetBytes(y).FillBytes(make([]byte, size))...)
+
+	return curve.NewPublicKey(point)
+}
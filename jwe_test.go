@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+// TestConcatKDF checks the key derivation against RFC 7518 Appendix C.
+func TestConcatKDF(t *testing.T) {
+	b64 := base64.RawURLEncoding.DecodeString
+
+	d, _ := b64("VEmDZpDXXK8p8N0Cndsxs924q6nS1RXFASRl6BfUqdw")
+	bob, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		t.Fatalf("invalid private key: %v", err)
+	}
+
+	alice, err := jwkToPublicKey(map[string]any{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   "gI0GAILBdu7T53akrFmMyGcsF3n5dO7MmwNBHKW5SV0",
+		"y":   "SLW_xSffzlPWrHEVI30DHM_4egVwt3NQqeUD7nMFpps",
+	})
+	if err != nil {
+		t.Fatalf("invalid public key: %v", err)
+	}
+
+	shared, err := bob.ECDH(alice)
+	if err != nil {
+		t.Fatalf("ECDH failed: %v", err)
+	}
+
+	key := concatKDF(shared, "A128GCM", []byte("Alice"), []byte("Bob"), 128)
+	if got := base64.RawURLEncoding.EncodeToString(key); got != "VqqN6vgjbSBcIijNcacQGg" {
+		t.Fatalf("concatKDF = %s, want VqqN6vgjbSBcIijNcacQGg", got)
+	}
+}
+
+func TestEncryptDecryptJWE(t *testing.T) {
+	for _, curve := range []ecdh.Curve{ecdh.P256(), ecdh.P384(), ecdh.X25519()} {
+		key, err := curve.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		other, _ := curve.GenerateKey(rand.Reader)
+
+		token, err := encryptJWE([]byte("header.payload.signature"), key.PublicKey(), "verifier-key")
+		if err != nil {
+			t.Fatalf("%v: encryptJWE failed: %v", curve, err)
+		}
+		if !isJWE(token) {
+			t.Fatalf("%v: token is not a compact JWE: %s", curve, token)
+		}
+
+		plaintext, err := decryptJWE(token, []*ecdh.PrivateKey{other, key})
+		if err != nil || string(plaintext) != "header.payload.signature" {
+			t.Fatalf("%v: decryptJWE = %q, %v", curve, plaintext, err)
+		}
+
+		if _, err := decryptJWE(token, []*ecdh.PrivateKey{other}); !errors.Is(err, ErrEncryptedToken) {
+			t.Fatalf("%v: expected ErrEncryptedToken with the wrong key, got %v", curve, err)
+		}
+	}
+}
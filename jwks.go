@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/ethaddr"
+	"github/hovanhoa/go-vc-auth/provider"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// publicKeyProbe is signed to recover a signer's public key from providers that cannot export it.
+var publicKeyProbe = sha256.Sum256([]byte("go-vc-auth public key export"))
+
+// KeyRef identifies a provider-managed key to publish in a JWKS.
+type KeyRef struct {
+	KeyID   string // Published "kid", usually the DID URL of the verification method, e.g. "did:nda:testnet:0x...#key-1"
+	Address string // Signer address passed to the provider
+}
+
+// JWKS is a JSON Web Key Set as served from a jwks_uri.
+type JWKS struct {
+	Keys []did.JWK `json:"keys"`
+}
+
+// ExportJWKS returns the public keys of the given signers as a JWKS, so systems that do not
+// resolve DIDs can still validate signatures made through the provider.
+// Keys are read from providers implementing provider.PublicKeyExporter, and otherwise recovered
+// from a signature over a fixed probe and checked against the signer address.
+func (a *Service) ExportJWKS(ctx context.Context, keys ...KeyRef) (*JWKS, error) {
+	if a.provider == nil {
+		return nil, ErrNilProvider
+	}
+
+	jwks := &JWKS{Keys: make([]did.JWK, 0, len(keys))}
+	for _, key := range keys {
+		publicKey, err := a.publicKey(ctx, key.Address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export key %s: %w", key.KeyID, err)
+		}
+
+		jwk := did.NewJWK(publicKey)
+		jwk.Kid = key.KeyID
+		jwk.Alg = "ES256K"
+		jwk.Use = "sig"
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks, nil
+}
+
+// publicKey returns the public key of the signer with the given address.
+func (a *Service) publicKey(ctx context.Context, address string) (*ecdsa.PublicKey, error) {
+	return providerPublicKey(ctx, a.provider, a.sign, address)
+}
+
+// providerPublicKey returns the public key p holds for the signer address, read from providers
+// implementing provider.PublicKeyExporter and otherwise recovered from a signature made with sign.
+func providerPublicKey(ctx context.Context, p provider.Provider, sign provider.SignFunc, address string) (*ecdsa.PublicKey, error) {
+	options := &provider.ProviderOption{Signer: address}
+	if exporter, ok := p.(provider.PublicKeyExporter); ok {
+		return exporter.PublicKey(ctx, options)
+	}
+
+	signature, err := sign(ctx, publicKeyProbe[:], options)
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) < 64 {
+		return nil, errors.New("invalid signature length")
+	}
+
+	// The provider returns r||s only, so try both recovery ids and keep the key matching the address.
+	for v := byte(0); v < 2; v++ {
+		publicKey, err := crypto.SigToPub(publicKeyProbe[:], append(signature[:64:64], v))
+		if err != nil {
+			continue
+		}
+		if ethaddr.Equal(crypto.PubkeyToAddress(*publicKey).Hex(), address) {
+			return publicKey, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not recover public key for signer %s", address)
+}
+
+// JWKSHandler serves jwks as application/json, e.g. mounted at /.well-known/jwks.json.
+func JWKSHandler(jwks *JWKS) http.Handler {
+	body, err := json.Marshal(jwks)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		_, _ = w.Write(body)
+	})
+}
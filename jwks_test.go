@@ -0,0 +1,52 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+func TestExportJWKS(t *testing.T) {
+	registry := newTestRegistry(t)
+	holder := registry.newIdentity(t)
+	issuer := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(holder, issuer))
+
+	jwks, err := a.ExportJWKS(context.Background(),
+		auth.KeyRef{KeyID: holder.DID + "#key-1", Address: holder.Address},
+		auth.KeyRef{KeyID: issuer.DID + "#key-1", Address: issuer.Address},
+	)
+	if err != nil {
+		t.Fatalf("ExportJWKS failed: %v", err)
+	}
+
+	for i, id := range []*authtest.Identity{holder, issuer} {
+		want := did.NewJWK(&id.Key.PublicKey)
+		got := jwks.Keys[i]
+		if got.Kid != id.DID+"#key-1" || got.Alg != "ES256K" || got.X != want.X || got.Y != want.Y {
+			t.Errorf("unexpected JWK %d: %+v", i, got)
+		}
+	}
+
+	if _, err := a.ExportJWKS(context.Background(), auth.KeyRef{KeyID: "unknown", Address: "0x0000000000000000000000000000000000000000"}); err == nil {
+		t.Error("expected export of unknown signer to fail")
+	}
+
+	rec := httptest.NewRecorder()
+	auth.JWKSHandler(jwks).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	var served auth.JWKS
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil || len(served.Keys) != 2 {
+		t.Fatalf("unexpected served JWKS: %s", rec.Body.String())
+	}
+}
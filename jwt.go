@@ -0,0 +1,332 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+// jwtToken is a decoded compact JWS.
+type jwtToken struct {
+	raw          string
+	signingInput string
+	header       map[string]any
+	payload      map[string]any
+	signature    []byte
+}
+
+// Limits on the encoded segments of a JWT, so oversize input is rejected before it is decoded.
+const (
+	maxJWTHeaderSize  = 8 << 10
+	maxJWTPayloadSize = 1 << 20
+)
+
+// understoodCriticalHeaders lists the "crit" header parameters this package processes. None are,
+// so any critical extension makes a token invalid (RFC 7515 section 4.1.11).
+var understoodCriticalHeaders = map[string]bool{}
+
+// parseJWT decodes a compact JWS without verifying its signature.
+// Surrounding JSON quotes, as produced by CreateToken, are tolerated.
+// Decoding is strict: oversize segments, duplicate JSON keys, "alg": "none" and critical header
+// parameters are rejected.
+func parseJWT(token string) (*jwtToken, error) {
+	return parseJWS(token, false)
+}
+
+// parseJWS is parseJWT, also accepting unsecured ("alg": "none") tokens when allowNone is set.
+func parseJWS(token string, allowNone bool) (*jwtToken, error) {
+	token = strings.Trim(token, "\"")
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid JWT format")
+	}
+	if len(parts[0]) > maxJWTHeaderSize {
+		return nil, fmt.Errorf("invalid header: larger than %d bytes", maxJWTHeaderSize)
+	}
+	if len(parts[1]) > maxJWTPayloadSize {
+		return nil, fmt.Errorf("invalid payload: larger than %d bytes", maxJWTPayloadSize)
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	var header map[string]any
+	if err := decodeStrictJSON(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	if err := checkHeader(header, allowNone); err != nil {
+		return nil, err
+	}
+
+	var payload map[string]any
+	if err := decodeStrictJSON(payloadBytes, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	return &jwtToken{
+		raw:          token,
+		signingInput: parts[0] + "." + parts[1],
+		header:       header,
+		payload:      payload,
+		signature:    signature,
+	}, nil
+}
+
+// checkHeader rejects unsecured tokens unless allowNone is set, and critical extensions.
+func checkHeader(header map[string]any, allowNone bool) error {
+	alg, ok := header["alg"].(string)
+	switch {
+	case !ok || alg == "":
+		return errors.New("invalid header: alg is missing")
+	case strings.EqualFold(alg, "none") && (!allowNone || alg != "none"):
+		return errors.New("unsecured JWT (alg none) is not accepted")
+	}
+
+	crit, ok := header["crit"]
+	if !ok {
+		return nil
+	}
+	names, ok := crit.([]any)
+	if !ok || len(names) == 0 {
+		return errors.New("invalid header: crit must be a non-empty array")
+	}
+	for _, name := range names {
+		name, ok := name.(string)
+		if !ok || !understoodCriticalHeaders[name] {
+			return fmt.Errorf("unsupported critical header parameter %v", name)
+		}
+		if _, present := header[name]; !present {
+			return fmt.Errorf("critical header parameter %s is missing", name)
+		}
+	}
+	return nil
+}
+
+// decodeStrictJSON unmarshals data into v, rejecting objects with duplicate keys at any depth, which
+// parsers disagree on and which could hide a claim from one of them.
+func decodeStrictJSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := checkDuplicateKeys(decoder); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+	return json.Unmarshal(data, v)
+}
+
+// checkDuplicateKeys reads one JSON value from decoder and fails on an object with a repeated key.
+func checkDuplicateKeys(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		keys := map[string]bool{}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key := keyToken.(string)
+			if keys[key] {
+				return fmt.Errorf("duplicate key %q", key)
+			}
+			keys[key] = true
+			if err := checkDuplicateKeys(decoder); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token() // closing brace
+		return err
+	case json.Delim('['):
+		for decoder.More() {
+			if err := checkDuplicateKeys(decoder); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token() // closing bracket
+		return err
+	}
+	return nil
+}
+
+// encodeSigningInput builds the unsigned "header.payload" part of a compact JWS.
+func encodeSigningInput(header, payload map[string]any) (string, error) {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal header: %w", err)
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payloadJSON), nil
+}
+
+// signJWT signs the signing input with the provider and returns the compact JWS. The provider is given
+// the digest its declared algorithm signs, or the signing input itself for EdDSA.
+func (a *Service) signJWT(ctx context.Context, signingInput string, providerOpts ...any) (string, error) {
+	alg := provider.AlgorithmOf(a.provider)
+	if headerAlg := signingInputAlgorithm(signingInput); headerAlg != alg {
+		return "", fmt.Errorf("provider signs %s, the token needs %s", alg, headerAlg)
+	}
+
+	options, err := provider.NewProviderOption(providerOpts...)
+	if err != nil {
+		return "", err
+	}
+	payload, err := signingPayload(alg, signingInput)
+	if err != nil {
+		return "", err
+	}
+	signature, err := a.sign(ctx, payload, options)
+	if err != nil {
+		return "", err
+	}
+
+	if len(signature) == 0 {
+		return "", errors.New("proof signature cannot be empty")
+	}
+	if alg == did.AlgES256K {
+		if signature, err = a.normalizeSignature(signature); err != nil {
+			return "", err
+		}
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// signingInputAlgorithm returns the "alg" header of a JWS signing input.
+func signingInputAlgorithm(signingInput string) string {
+	encodedHeader, _, _ := strings.Cut(signingInput, ".")
+	headerJSON, err := base64.RawURLEncoding.DecodeString(encodedHeader)
+	if err != nil {
+		return ""
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	_ = json.Unmarshal(headerJSON, &header)
+	return header.Alg
+}
+
+// verifyJWT checks the ES256K signature of the token against the key referenced by its kid header.
+// The key is resolved through the instance DID resolver using ctx.
+func (a *Service) verifyJWT(ctx context.Context, token *jwtToken) error {
+	_, err := a.verifyJWTKey(ctx, token, defaultAlgorithms, "")
+	return err
+}
+
+// verifyJWTKey checks the signature of the token against the key referenced by its kid header and
+// returns that key. The "alg" header must be in allowed and must be the algorithm of the resolved
+// verification method, so a token cannot pick a different algorithm than its key is meant for.
+// A non-empty purpose also requires the key to be listed under that verification relationship.
+func (a *Service) verifyJWTKey(ctx context.Context, token *jwtToken, allowed []string, purpose string) (crypto.PublicKey, error) {
+	alg, ok := token.header["alg"].(string)
+	if !ok || !containsString(allowed, alg) {
+		err := fmt.Errorf("unsupported algorithm: %v", token.header["alg"])
+		traceStep(ctx, StepKeySelection, err)
+		return nil, err
+	}
+
+	kid, ok := token.header["kid"].(string)
+	if !ok {
+		err := errors.New("kid not found in header")
+		traceStep(ctx, StepKeySelection, err)
+		return nil, err
+	}
+
+	didPart, _ := did.SplitDIDURL(kid)
+	if didPart == "" {
+		err := fmt.Errorf("invalid verification method URL, could not extract DID: %s", kid)
+		traceStep(ctx, StepKeySelection, err, "kid", kid)
+		return nil, err
+	}
+
+	var doc *did.Document
+	err := runStep(ctx, "DID resolution", resolveBudget, func(ctx context.Context) (err error) {
+		doc, err = a.resolver.Resolve(ctx, didPart)
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to resolve DID '%s': %w", didPart, err)
+		traceStep(ctx, StepResolve, err, "did", didPart)
+		return nil, err
+	}
+	traceStep(ctx, StepResolve, nil, "did", didPart)
+
+	publicKey, err := selectVerificationKey(doc, kid, alg)
+	if err == nil && purpose != "" && !doc.HasRelationship(purpose, kid) {
+		err = fmt.Errorf("%w: %s is not listed under %s", ErrProofPurpose, kid, purpose)
+	}
+	if err == nil {
+		err = a.checkPinnedKey(didPart, publicKey)
+	}
+	traceStep(ctx, StepKeySelection, err, "kid", kid, "alg", alg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.checkLowS(alg, token.signature)
+	if err == nil {
+		err = verifySignature(alg, publicKey, token.signingInput, token.signature)
+	}
+	traceStep(ctx, StepSignature, err, "alg", alg)
+	if err != nil {
+		return nil, err
+	}
+
+	return publicKey, nil
+}
+
+// selectVerificationKey finds the verification method kid in doc and returns its key, provided alg
+// is the algorithm the method is meant for.
+func selectVerificationKey(doc *did.Document, kid, alg string) (crypto.PublicKey, error) {
+	vm, err := doc.FindVerificationMethod(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	methodAlg, err := vm.Algorithm()
+	if err != nil {
+		return nil, fmt.Errorf("invalid verification method: %w", err)
+	}
+	if methodAlg != alg {
+		return nil, fmt.Errorf("algorithm %s does not match verification method %s (%s)", alg, kid, methodAlg)
+	}
+
+	publicKey, err := vm.Key()
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	return publicKey, nil
+}
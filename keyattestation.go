@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// keyAttestationHeader is the VP JWT header carrying the holder key attestation,
+// as in the OpenID4VCI key attestation format.
+const keyAttestationHeader = "key_attestation"
+
+// keyAttestationType is the "typ" header of key attestation JWTs.
+const keyAttestationType = "key-attestation+jwt"
+
+// ErrKeyAttestation is returned when a presentation lacks a required key attestation or the
+// attestation does not vouch for the holder key.
+var ErrKeyAttestation = errors.New("key attestation rejected")
+
+// KeyAttestation is a verified statement by a wallet provider or platform that keys are protected
+// by a secure element, TPM or similar hardware.
+type KeyAttestation struct {
+	AttestedKeys       []did.JWK           // Keys the statement vouches for
+	KeyStorage         []string            // Key storage attack potential resistance, e.g. "iso_18045_high"
+	UserAuthentication []string            // How the user authenticates to use the keys
+	IssuedAt           time.Time           // "iat" of the statement
+	ExpiresAt          time.Time           // "exp" of the statement; zero when it does not expire
+	Certificates       []*x509.Certificate // Attester certificate chain, leaf first
+}
+
+// KeyAttestationVerifier verifies an attestation statement and returns what it attests.
+// Implement it to accept other statement formats, e.g. raw TPM2 quotes or Apple App Attest objects.
+type KeyAttestationVerifier interface {
+	VerifyKeyAttestation(ctx context.Context, attestation string) (*KeyAttestation, error)
+}
+
+// WithKeyAttestation embeds an attestation statement for the holder key in the VP "key_attestation" header.
+func WithKeyAttestation(attestation string) CreateOpt {
+	return func(o *createOptions) {
+		WithHeader(keyAttestationHeader, attestation)(o)
+	}
+}
+
+// WithRequiredKeyAttestation fails verification unless the VP carries a key attestation accepted by
+// verifier that vouches for the key the VP was signed with. Use it for high-assurance flows that
+// require hardware-backed holder keys.
+func WithRequiredKeyAttestation(verifier KeyAttestationVerifier) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.keyAttestation = verifier
+	}
+}
+
+// checkKeyAttestation verifies the attestation carried by the VP at time now and checks it covers holderKey.
+func checkKeyAttestation(ctx context.Context, vpToken *jwtToken, holderKey crypto.PublicKey, verifier KeyAttestationVerifier, now time.Time) error {
+	statement := stringField(vpToken.header, keyAttestationHeader)
+	if statement == "" {
+		return fmt.Errorf("%w: presentation has no key attestation", ErrKeyAttestation)
+	}
+
+	var (
+		attestation *KeyAttestation
+		err         error
+	)
+	if builtin, ok := verifier.(*jwtKeyAttestationVerifier); ok {
+		attestation, err = builtin.verifyAt(ctx, statement, now)
+	} else {
+		attestation, err = verifier.VerifyKeyAttestation(ctx, statement)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrKeyAttestation, err)
+	}
+
+	holderJWK, err := did.JWKFromKey(holderKey)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrKeyAttestation, err)
+	}
+
+	for _, key := range attestation.AttestedKeys {
+		if key.Kty == holderJWK.Kty && key.Crv == holderJWK.Crv && key.X == holderJWK.X && key.Y == holderJWK.Y {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: holder key is not attested", ErrKeyAttestation)
+}
+
+// jwtKeyAttestationVerifier verifies key attestation JWTs signed by an attester certificate.
+type jwtKeyAttestationVerifier struct {
+	roots      *x509.CertPool
+	keyStorage []string
+}
+
+// NewKeyAttestationVerifier creates a KeyAttestationVerifier for "key-attestation+jwt" statements,
+// signed with ES256 or ES384 by the leaf of an "x5c" chain that must validate to roots.
+// When keyStorage is given, the statement must claim at least one of those storage levels.
+func NewKeyAttestationVerifier(roots *x509.CertPool, keyStorage ...string) KeyAttestationVerifier {
+	return &jwtKeyAttestationVerifier{roots: roots, keyStorage: keyStorage}
+}
+
+// VerifyKeyAttestation checks the signature, chain, validity and storage level of the statement
+// against the system clock. During VerifyToken the Auth's clock is used instead.
+func (v *jwtKeyAttestationVerifier) VerifyKeyAttestation(ctx context.Context, attestation string) (*KeyAttestation, error) {
+	return v.verifyAt(ctx, attestation, clock.System().Now())
+}
+
+// verifyAt checks the statement as VerifyKeyAttestation does, at time now.
+func (v *jwtKeyAttestationVerifier) verifyAt(ctx context.Context, attestation string, now time.Time) (*KeyAttestation, error) {
+	token, err := parseJWT(attestation)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ := stringField(token.header, "typ"); typ != keyAttestationType {
+		return nil, fmt.Errorf("unexpected typ %q", typ)
+	}
+
+	chain, err := parseCertificateChain(token)
+	if err != nil {
+		return nil, err
+	}
+	if chain == nil {
+		return nil, errors.New("x5c header is required")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, fmt.Errorf("certificate chain verification failed: %w", err)
+	}
+
+	if err := verifyAttesterSignature(token, chain[0]); err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		AttestedKeys       []did.JWK `json:"attested_keys"`
+		KeyStorage         []string  `json:"key_storage"`
+		UserAuthentication []string  `json:"user_authentication"`
+		IssuedAt           int64     `json:"iat"`
+		ExpiresAt          int64     `json:"exp"`
+	}
+	payload, err := json.Marshal(token.payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("invalid key attestation claims: %w", err)
+	}
+
+	result := &KeyAttestation{
+		AttestedKeys:       claims.AttestedKeys,
+		KeyStorage:         claims.KeyStorage,
+		UserAuthentication: claims.UserAuthentication,
+		IssuedAt:           timeOrZero(claims.IssuedAt),
+		ExpiresAt:          timeOrZero(claims.ExpiresAt),
+		Certificates:       chain,
+	}
+
+	if !result.ExpiresAt.IsZero() && now.After(result.ExpiresAt) {
+		return nil, errors.New("key attestation has expired")
+	}
+
+	if len(v.keyStorage) > 0 && !anyString(result.KeyStorage, v.keyStorage) {
+		return nil, fmt.Errorf("key storage %v does not meet %v", result.KeyStorage, v.keyStorage)
+	}
+
+	return result, nil
+}
+
+// verifyAttesterSignature checks an ES256 or ES384 JWS signature against the attester certificate key.
+func verifyAttesterSignature(token *jwtToken, cert *x509.Certificate) error {
+	publicKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("attester certificate does not hold an ECDSA key")
+	}
+
+	alg := stringField(token.header, "alg")
+	if !(alg == did.AlgES256 && publicKey.Curve == elliptic.P256()) && !(alg == did.AlgES384 && publicKey.Curve == elliptic.P384()) {
+		return fmt.Errorf("algorithm %q does not match the attester key", alg)
+	}
+
+	return verifySignature(alg, publicKey, token.signingInput, token.signature)
+}
+
+// anyString reports whether list and candidates share at least one value.
+func anyString(list, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsString(list, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// newKeyAttestation issues an ES256 key attestation JWT for keys, signed by the attester.
+func newKeyAttestation(t *testing.T, attester *x509.Certificate, attesterKey *ecdsa.PrivateKey, storage string, keys ...*ecdsa.PublicKey) string {
+	t.Helper()
+
+	attested := make([]did.JWK, len(keys))
+	for i, key := range keys {
+		attested[i] = did.NewJWK(key)
+	}
+
+	header, _ := json.Marshal(map[string]any{
+		"alg": "ES256",
+		"typ": "key-attestation+jwt",
+		"x5c": []string{base64.StdEncoding.EncodeToString(attester.Raw)},
+	})
+	payload, _ := json.Marshal(map[string]any{
+		"iat":           time.Now().Unix(),
+		"exp":           time.Now().Add(time.Hour).Unix(),
+		"attested_keys": attested,
+		"key_storage":   []string{storage},
+	})
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hash := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, attesterKey, hash[:])
+	if err != nil {
+		t.Fatalf("failed to sign attestation: %v", err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifyTokenKeyAttestation(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	other := registry.newIdentity(t)
+
+	root, rootKey := newTestCertificate(t, "Wallet Provider Root", nil, nil)
+	attester, attesterKey := newTestCertificate(t, "did:web:wallet.example", root, rootKey)
+	rogue, rogueKey := newTestCertificate(t, "Rogue Root", nil, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	verifier := auth.WithRequiredKeyAttestation(auth.NewKeyAttestationVerifier(roots, "iso_18045_high"))
+
+	tests := []struct {
+		name        string
+		attestation string
+		wantErr     bool
+	}{
+		{"attested hardware key", newKeyAttestation(t, attester, attesterKey, "iso_18045_high", &holder.Key.PublicKey), false},
+		{"no attestation", "", true},
+		{"other key attested", newKeyAttestation(t, attester, attesterKey, "iso_18045_high", &other.Key.PublicKey), true},
+		{"insufficient storage", newKeyAttestation(t, attester, attesterKey, "iso_18045_basic", &holder.Key.PublicKey), true},
+		{"untrusted attester", newKeyAttestation(t, rogue, rogueKey, "iso_18045_high", &holder.Key.PublicKey), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []any{holder.Address}
+			if tt.attestation != "" {
+				opts = append(opts, auth.WithKeyAttestation(tt.attestation))
+			}
+
+			token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, opts...)
+			if err != nil {
+				t.Fatalf("CreateToken failed: %v", err)
+			}
+
+			_, err = a.VerifyToken(context.Background(), token, verifier)
+			if tt.wantErr && !errors.Is(err, auth.ErrKeyAttestation) {
+				t.Errorf("expected ErrKeyAttestation, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("VerifyToken failed: %v", err)
+			}
+		})
+	}
+
+	// The attestation expires after an hour by the Auth's clock, not the wall clock.
+	late := registry.newAuth(authtest.NewSigner(holder), auth.WithClock(clock.Offset(clock.System(), 2*time.Hour)))
+	token, err := late.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address,
+		auth.WithKeyAttestation(newKeyAttestation(t, attester, attesterKey, "iso_18045_high", &holder.Key.PublicKey)))
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	if _, err := late.VerifyToken(context.Background(), token, verifier); !errors.Is(err, auth.ErrKeyAttestation) {
+		t.Errorf("expected an attestation expired by the Auth clock to fail, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// languageTag loosely matches a BCP 47 language tag such as "en", "fr-CA" or "zh-Hant-TW".
+var languageTag = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{1,8})*$`)
+
+// LanguageMap holds the translations of a claim value keyed by BCP 47 language tag.
+// The empty key holds a value without a language.
+type LanguageMap map[string]string
+
+// Get returns the value for locale, falling back to a value in the same language, then the
+// untagged value, then the value of the alphabetically first tag.
+func (m LanguageMap) Get(locale string) (string, bool) {
+	if len(m) == 0 {
+		return "", false
+	}
+
+	tags := make([]string, 0, len(m))
+	for tag := range m {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	if i := matchLocale(tags, locale); i >= 0 {
+		return m[tags[i]], true
+	}
+	if value, ok := m[""]; ok {
+		return value, true
+	}
+	return m[tags[0]], true
+}
+
+// LanguageMap returns the translations of the named subject property. The property may be a plain
+// string, a JSON-LD value object ({"@value": ..., "@language": ...}), an array of those, or a
+// JSON-LD language map ({"en": ..., "fr": ...}). ok is false for properties of other shapes.
+func (s CredentialSubject) LanguageMap(name string) (LanguageMap, bool) {
+	value, ok := s.Claims[name]
+	if !ok {
+		return nil, false
+	}
+	return parseLanguageMap(value)
+}
+
+// Localized returns the named subject property in the language closest to locale, e.g. "vi-VN".
+// See LanguageMap for the accepted shapes and LanguageMap.Get for the fallback order.
+func (s CredentialSubject) Localized(name, locale string) (string, bool) {
+	translations, ok := s.LanguageMap(name)
+	if !ok {
+		return "", false
+	}
+	return translations.Get(locale)
+}
+
+// parseLanguageMap normalizes the JSON-LD forms of a possibly language-tagged string.
+func parseLanguageMap(value any) (LanguageMap, bool) {
+	translations := LanguageMap{}
+
+	switch v := value.(type) {
+	case string:
+		translations[""] = v
+
+	case []any:
+		for _, item := range v {
+			entry, ok := parseLanguageMap(item)
+			if !ok {
+				return nil, false
+			}
+			for tag, text := range entry {
+				if _, seen := translations[tag]; !seen {
+					translations[tag] = text
+				}
+			}
+		}
+
+	case map[string]any:
+		if text, ok := v["@value"].(string); ok {
+			language, _ := v["@language"].(string)
+			translations[language] = text
+			break
+		}
+
+		for key, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			switch {
+			case key == "@none":
+				translations[""] = text
+			case languageTag.MatchString(key):
+				translations[key] = text
+			default:
+				return nil, false
+			}
+		}
+
+	default:
+		return nil, false
+	}
+
+	return translations, len(translations) > 0
+}
+
+// matchLocale returns the index of the tag equal to locale, or else of the first tag in the same
+// language, or -1. Comparison is case-insensitive.
+func matchLocale(tags []string, locale string) int {
+	language, _, _ := strings.Cut(locale, "-")
+	languageMatch := -1
+	for i, tag := range tags {
+		if strings.EqualFold(tag, locale) {
+			return i
+		}
+		if tagLanguage, _, _ := strings.Cut(tag, "-"); languageMatch < 0 && tag != "" && strings.EqualFold(tagLanguage, language) {
+			languageMatch = i
+		}
+	}
+
+	return languageMatch
+}
@@ -0,0 +1,75 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestCredentialSubjectLocalized(t *testing.T) {
+	subject := auth.CredentialSubject{Claims: map[string]any{
+		"plain":  "Nguyen Van A",
+		"tagged": map[string]any{"@value": "Engineer", "@language": "en"},
+		"values": []any{
+			map[string]any{"@value": "Engineer", "@language": "en"},
+			map[string]any{"@value": "Kỹ sư", "@language": "vi"},
+		},
+		"map":    map[string]any{"en-US": "Color", "en-GB": "Colour", "fr": "Couleur", "@none": "Colour"},
+		"nested": map[string]any{"street": "1 Main St"},
+		"number": 42.0,
+	}}
+
+	tests := []struct {
+		name, claim, locale, want string
+		ok                        bool
+	}{
+		{"plain string", "plain", "vi", "Nguyen Van A", true},
+		{"value object", "tagged", "vi", "Engineer", true},
+		{"exact language", "values", "vi", "Kỹ sư", true},
+		{"language of a regional locale", "values", "vi-VN", "Kỹ sư", true},
+		{"exact region", "map", "en-GB", "Colour", true},
+		{"language match", "map", "fr-CA", "Couleur", true},
+		{"untagged fallback", "map", "de", "Colour", true},
+		{"object that is not a language map", "nested", "en", "", false},
+		{"number", "number", "en", "", false},
+		{"missing", "missing", "en", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := subject.Localized(tt.claim, tt.locale)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Localized(%q, %q) = %q, %v; want %q, %v", tt.claim, tt.locale, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenPreservesLanguageMaps(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{
+		"degree": []any{
+			map[string]any{"@value": "Bachelor of Science", "@language": "en"},
+			map[string]any{"@value": "Cử nhân Khoa học", "@language": "vi"},
+		},
+	})
+
+	claims, err := a.VerifyCredential(context.Background(), vcJwt)
+	if err != nil {
+		t.Fatalf("VerifyCredential failed: %v", err)
+	}
+
+	translations, ok := claims.Subject().LanguageMap("degree")
+	if !ok || len(translations) != 2 {
+		t.Fatalf("expected both translations to be preserved, got %v", claims.Subject().Claims["degree"])
+	}
+	if degree, _ := translations.Get("vi"); degree != "Cử nhân Khoa học" {
+		t.Errorf("unexpected Vietnamese value %q", degree)
+	}
+}
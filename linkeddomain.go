@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// didConfigurationPath is the well-known location of a DID configuration resource.
+const didConfigurationPath = "/.well-known/did-configuration.json"
+
+// ErrDomainNotLinked is returned when a domain's DID configuration holds no valid
+// domain linkage credential for the DID.
+var ErrDomainNotLinked = errors.New("DID is not linked to domain")
+
+// didConfiguration is the DIF well-known DID configuration resource.
+type didConfiguration struct {
+	LinkedDIDs []any `json:"linked_dids"`
+}
+
+// VerifyDomainLinkage confirms that didStr is linked to the domain, following the DIF Well Known
+// DID Configuration specification. domain is a host such as "example.com", or an origin with an
+// explicit scheme. The DID configuration must contain a valid JWT domain linkage credential issued
+// by the DID itself for the domain's origin.
+func (a *Service) VerifyDomainLinkage(ctx context.Context, didStr, domain string) error {
+	return a.verifyDomainLinkage(ctx, domain, didStr)
+}
+
+// verifyDomainLinkage succeeds if at least one of dids is linked to the domain.
+// The DID configuration is fetched once for all of them.
+func (a *Service) verifyDomainLinkage(ctx context.Context, domain string, dids ...string) error {
+	origin, err := domainOrigin(domain)
+	if err != nil {
+		return err
+	}
+
+	config, err := a.fetchDIDConfiguration(ctx, origin)
+	if err != nil {
+		return fmt.Errorf("failed to fetch DID configuration of %s: %w", origin, err)
+	}
+
+	var errs []error
+	for _, linked := range config.LinkedDIDs {
+		linkedJwt, ok := linked.(string)
+		if !ok {
+			// Only the JWT proof format is supported; JSON-LD linkage credentials are skipped.
+			continue
+		}
+
+		token, err := parseJWT(linkedJwt)
+		if err != nil {
+			continue
+		}
+
+		iss := stringField(token.payload, "iss")
+		if !containsString(dids, iss) {
+			continue
+		}
+
+		if err := a.verifyDomainLinkageCredential(ctx, token, iss, origin, a.clock.Now()); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		return nil
+	}
+
+	names := strings.Join(dids, ", ")
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %s, %s: %w", ErrDomainNotLinked, names, origin, errors.Join(errs...))
+	}
+	return fmt.Errorf("%w: %s, %s", ErrDomainNotLinked, names, origin)
+}
+
+// verifyDomainLinkageCredential checks one JWT domain linkage credential. It must be signed with a key
+// of didStr and issued by didStr to itself.
+func (a *Service) verifyDomainLinkageCredential(ctx context.Context, token *jwtToken, didStr, origin string, now time.Time) error {
+	if err := a.verifyJWT(ctx, token); err != nil {
+		return fmt.Errorf("failed to verify domain linkage credential: %w", err)
+	}
+
+	if signer, _ := did.SplitDIDURL(stringField(token.header, "kid")); signer != didStr {
+		return fmt.Errorf("domain linkage credential is signed by %q, not by the DID", signer)
+	}
+	if iss := stringField(token.payload, "iss"); iss != didStr {
+		return fmt.Errorf("domain linkage credential issuer %q does not match DID", iss)
+	}
+
+	if sub := stringField(token.payload, "sub"); sub != didStr {
+		return fmt.Errorf("domain linkage credential subject %q does not match DID", sub)
+	}
+
+	if exp, ok := token.payload["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0)) {
+		return errors.New("domain linkage credential has expired")
+	}
+	if nbf, ok := token.payload["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("domain linkage credential is not yet valid")
+	}
+
+	vcClaim, ok := token.payload["vc"].(map[string]any)
+	if !ok {
+		return errors.New("vc claim not found in domain linkage credential")
+	}
+
+	if !containsString(stringList(vcClaim["type"]), "DomainLinkageCredential") {
+		return errors.New("credential is not a DomainLinkageCredential")
+	}
+	if issuer := issuerID(vcClaim["issuer"]); issuer != didStr {
+		return fmt.Errorf("domain linkage credential issuer %q does not match DID", issuer)
+	}
+
+	subject, _ := vcClaim["credentialSubject"].(map[string]any)
+	if id := stringField(subject, "id"); id != didStr {
+		return fmt.Errorf("domain linkage credential subject id %q does not match DID", id)
+	}
+	if linkedOrigin := strings.TrimSuffix(stringField(subject, "origin"), "/"); linkedOrigin != origin {
+		return fmt.Errorf("domain linkage credential origin %q does not match %s", linkedOrigin, origin)
+	}
+
+	return nil
+}
+
+// fetchDIDConfiguration downloads the DID configuration resource of origin. Redirects are not followed.
+func (a *Service) fetchDIDConfiguration(ctx context.Context, origin string) (*didConfiguration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+didConfigurationPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	client := *a.httpClient
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var config didConfiguration
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, fmt.Errorf("failed to decode DID configuration: %w", err)
+	}
+
+	return &config, nil
+}
+
+// domainOrigin turns a host or origin into a normalized origin, defaulting to https.
+func domainOrigin(domain string) (string, error) {
+	if !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+
+	u, err := url.Parse(domain)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid domain %q", domain)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return "", fmt.Errorf("domain %q must not contain a path", domain)
+	}
+
+	return u.Scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
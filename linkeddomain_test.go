@@ -0,0 +1,150 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+// newDomainLinkageCredential issues a JWT domain linkage credential linking id to origin.
+func newDomainLinkageCredential(t *testing.T, id *authtest.Identity, origin string, expiresAt time.Time) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]any{"alg": "ES256K", "kid": id.DID + "#key-1", "typ": "JWT"})
+	payload, _ := json.Marshal(map[string]any{
+		"iss": id.DID,
+		"sub": id.DID,
+		"nbf": time.Now().Add(-time.Minute).Unix(),
+		"exp": expiresAt.Unix(),
+		"vc": map[string]any{
+			"@context":          []string{"https://www.w3.org/2018/credentials/v1", "https://identity.foundation/.well-known/did-configuration/v1"},
+			"type":              []string{"VerifiableCredential", "DomainLinkageCredential"},
+			"issuer":            id.DID,
+			"credentialSubject": map[string]any{"id": id.DID, "origin": origin},
+		},
+	})
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signJWT(t, signingInput, id.Key)
+}
+
+// serveDIDConfiguration serves a well-known DID configuration built by linkedDIDs for the server's origin.
+func serveDIDConfiguration(t *testing.T, linkedDIDs func(origin string) []string) string {
+	t.Helper()
+
+	var origin string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/did-configuration.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"@context":    "https://identity.foundation/.well-known/did-configuration/v1",
+			"linked_dids": linkedDIDs(origin),
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	origin = server.URL
+	return origin
+}
+
+func TestVerifyDomainLinkage(t *testing.T) {
+	registry := newTestRegistry(t)
+	linked := registry.newIdentity(t)
+	expired := registry.newIdentity(t)
+	stranger := registry.newIdentity(t)
+
+	origin := serveDIDConfiguration(t, func(origin string) []string {
+		return []string{
+			newDomainLinkageCredential(t, linked, origin, time.Now().Add(time.Hour)),
+			newDomainLinkageCredential(t, expired, origin, time.Now().Add(-time.Hour)),
+			newDomainLinkageCredential(t, stranger, "https://elsewhere.example", time.Now().Add(time.Hour)),
+		}
+	})
+
+	// DID configurations are served over loopback HTTP.
+	a := registry.newAuth(authtest.NewSigner(linked), auth.WithHTTPClient(http.DefaultClient))
+
+	if err := a.VerifyDomainLinkage(context.Background(), linked.DID, origin); err != nil {
+		t.Fatalf("VerifyDomainLinkage failed: %v", err)
+	}
+
+	// A linkage credential naming a victim DID but signed with the attacker's own key must not link the victim.
+	victim := registry.newIdentity(t)
+	forgedOrigin := serveDIDConfiguration(t, func(origin string) []string {
+		return []string{craftJWT(t, stranger, nil, map[string]any{
+			"iss": victim.DID,
+			"sub": victim.DID,
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"vc": map[string]any{
+				"type":              []string{"VerifiableCredential", "DomainLinkageCredential"},
+				"issuer":            victim.DID,
+				"credentialSubject": map[string]any{"id": victim.DID, "origin": origin},
+			},
+		})}
+	})
+	if err := a.VerifyDomainLinkage(context.Background(), victim.DID, forgedOrigin); !errors.Is(err, auth.ErrDomainNotLinked) {
+		t.Errorf("forged signer: expected ErrDomainNotLinked, got %v", err)
+	}
+
+	for name, id := range map[string]*authtest.Identity{"expired": expired, "wrong origin": stranger} {
+		if err := a.VerifyDomainLinkage(context.Background(), id.DID, origin); !errors.Is(err, auth.ErrDomainNotLinked) {
+			t.Errorf("%s: expected ErrDomainNotLinked, got %v", name, err)
+		}
+	}
+
+	if err := a.VerifyDomainLinkage(context.Background(), "did:nda:testnet:0x0000000000000000000000000000000000000000", origin); !errors.Is(err, auth.ErrDomainNotLinked) {
+		t.Errorf("unlisted DID: expected ErrDomainNotLinked, got %v", err)
+	}
+}
+
+func TestVerifyTokenRequireLinkedDomain(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	other := registry.newIdentity(t)
+
+	holderOrigin := serveDIDConfiguration(t, func(origin string) []string {
+		return []string{newDomainLinkageCredential(t, holder, origin, time.Now().Add(time.Hour))}
+	})
+	issuerOrigin := serveDIDConfiguration(t, func(origin string) []string {
+		return []string{newDomainLinkageCredential(t, issuer, origin, time.Now().Add(time.Hour))}
+	})
+	otherOrigin := serveDIDConfiguration(t, func(origin string) []string {
+		return []string{newDomainLinkageCredential(t, other, origin, time.Now().Add(time.Hour))}
+	})
+
+	a := registry.newAuth(authtest.NewSigner(holder), auth.WithHTTPClient(http.DefaultClient))
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	for _, origin := range []string{holderOrigin, issuerOrigin} {
+		if _, err := a.VerifyToken(context.Background(), token, auth.WithRequireLinkedDomain(origin)); err != nil {
+			t.Errorf("VerifyToken with linked domain %s failed: %v", origin, err)
+		}
+	}
+
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithRequireLinkedDomain(otherOrigin)); !errors.Is(err, auth.ErrDomainNotLinked) {
+		t.Errorf("expected ErrDomainNotLinked, got %v", err)
+	}
+	// A presentation signed by another DID cannot borrow the holder's linked domain through iss or holder.
+	forged := craftJWT(t, other, nil, map[string]any{
+		"iss": holder.DID,
+		"vp":  map[string]any{"holder": holder.DID, "verifiableCredential": []string{vcJwt}},
+	})
+	if _, err := a.VerifyToken(context.Background(), forged, auth.WithRequireLinkedDomain(holderOrigin)); !errors.Is(err, auth.ErrIssuerMismatch) {
+		t.Errorf("expected ErrIssuerMismatch, got %v", err)
+	}
+}
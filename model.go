@@ -1,8 +1,12 @@
 package auth
 
 import (
+	"time"
+
 	"github.com/pilacorp/go-credential-sdk/credential/vc"
 	"github.com/pilacorp/go-credential-sdk/credential/vp"
+
+	"github/hovanhoa/go-vc-auth/anchor"
 )
 
 // CredentialContent represents the credential content for token creation
@@ -17,6 +21,79 @@ type PresentationContents struct {
 
 // VcClaims represents the claims for a Verifiable Credential.
 type VcClaims struct {
-	Issuer            string         `json:"issuer"`
-	CredentialSubject map[string]any `json:"credentialSubject"`
+	ID                string              `json:"id,omitempty"`
+	Types             []string            `json:"type,omitempty"`
+	Issuer            string              `json:"issuer"`
+	ValidFrom         time.Time           `json:"validFrom,omitzero"`
+	ValidUntil        time.Time           `json:"validUntil,omitzero"`
+	Status            []CredentialStatus  `json:"credentialStatus,omitempty"`
+	Schemas           []CredentialSchema  `json:"credentialSchema,omitempty"`
+	RefreshServices   []RefreshService    `json:"refreshService,omitempty"`
+	RelatedResources  []RelatedResource   `json:"relatedResource,omitempty"`
+	ConfidenceMethods []ConfidenceMethod  `json:"confidenceMethod,omitempty"`
+	AssuranceLevel    AssuranceLevel      `json:"assuranceLevel,omitzero"` // See ConfidenceMethod and MinAssurance
+	Proof             *ProofMetadata      `json:"proof,omitempty"`
+	CredentialSubject []CredentialSubject `json:"credentialSubject"`
+	Display           *CredentialDisplay  `json:"display,omitempty"` // Set when VerifyToken is given WithDisplay
+	Anchor            *anchor.Anchor      `json:"anchor,omitempty"`  // Set when VerifyToken is given WithAnchorCheck
+	Typed             any                 `json:"-"`                 // Subject decoded into the Go type registered with RegisterCredentialType
+	raw               string              // Credential as presented, see Raw
+}
+
+// Subject returns the first credential subject, or the zero subject when there is none.
+func (c VcClaims) Subject() CredentialSubject {
+	if len(c.CredentialSubject) == 0 {
+		return CredentialSubject{}
+	}
+	return c.CredentialSubject[0]
+}
+
+// HasType reports whether the credential declares the given type.
+func (c VcClaims) HasType(credentialType string) bool {
+	for _, t := range c.Types {
+		if t == credentialType {
+			return true
+		}
+	}
+	return false
+}
+
+// CredentialStatus represents a credentialStatus entry of a Verifiable Credential.
+type CredentialStatus struct {
+	ID                   string `json:"id,omitempty"`
+	Type                 string `json:"type"`
+	StatusPurpose        string `json:"statusPurpose,omitempty"`
+	StatusListIndex      string `json:"statusListIndex,omitempty"`
+	StatusListCredential string `json:"statusListCredential,omitempty"`
+}
+
+// CredentialSchema represents a credentialSchema entry of a Verifiable Credential.
+type CredentialSchema struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+// RefreshService represents a refreshService entry of a Verifiable Credential: where the holder
+// can obtain an updated copy of the credential.
+type RefreshService struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// RelatedResource represents a relatedResource entry of a Verifiable Credential: a linked resource,
+// e.g. an image or a document, pinned by one or more digests.
+type RelatedResource struct {
+	ID              string `json:"id"`
+	MediaType       string `json:"mediaType,omitempty"`
+	DigestSRI       string `json:"digestSRI,omitempty"`       // Subresource Integrity metadata, e.g. "sha384-..."
+	DigestMultibase string `json:"digestMultibase,omitempty"` // Multibase-encoded multihash
+	Verified        bool   `json:"verified,omitempty"`        // Set when WithRelatedResources fetched and matched the resource
+}
+
+// ProofMetadata describes the proof that secured a Verifiable Credential.
+type ProofMetadata struct {
+	Format             string `json:"format"`                       // Envelope format, e.g. "JWT"
+	Algorithm          string `json:"alg,omitempty"`                // JWS algorithm, e.g. "ES256K"
+	VerificationMethod string `json:"verificationMethod,omitempty"` // DID URL of the signing key
+	Purpose            string `json:"proofPurpose,omitempty"`       // Relationship the key was checked against, set with WithProofPurposes
 }
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto/ecdh"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/anchor"
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/pe"
+	"github/hovanhoa/go-vc-auth/trust"
+)
+
+// JWT "typ" header values for VP tokens.
+const (
+	TokenTypeJWT   = "JWT"    // Generic JWT, the default
+	TokenTypeVPJWT = "vp+jwt" // Media type registered for VC-JOSE presentations
+)
+
+// CreateOpt configures a CreateToken call.
+// CreateOpt values can be mixed with provider options in CreateToken's opts; every argument that is
+// not a CreateOpt is forwarded to the provider unchanged.
+type CreateOpt func(*createOptions)
+
+// createOptions holds configuration for token creation.
+type createOptions struct {
+	tokenType             string
+	verificationMethodKey string
+	headers               map[string]any
+	encryptionKey         *ecdh.PublicKey
+	encryptionKeyID       string
+	outputFormat          OutputFormat
+	nonce                 string
+	audience              string
+	consentSink           ConsentSink
+	consentPurpose        string
+	disclosureDefinition  *pe.PresentationDefinition
+	disclosureHandler     DisclosureHandler
+	lifetime              time.Duration
+	singleUse             bool
+	tokenID               string    // VP "jti", set when the Auth has an issued-token registry
+	issuedAt              time.Time // VP "iat"
+	expiresAt             time.Time // VP "exp"
+	additionalProofs      []ProofSigner
+	certificateThumbprint string // VP "cnf" certificate binding
+	contexts              []any  // VP "@context", presentationContexts when empty
+	algorithm             string // VP "alg", the provider's algorithm; ES256K when empty
+}
+
+// WithExpiry sets the "iat" and "exp" claims of the VP so it expires after lifetime.
+func WithExpiry(lifetime time.Duration) CreateOpt {
+	return func(o *createOptions) {
+		o.lifetime = lifetime
+	}
+}
+
+// WithSingleUse makes the VP single use: VerifyToken accepts it once and then fails with
+// ErrPresentationUsed, e.g. for a ticket or door access QR code. It requires an issued-token registry,
+// see WithTokenRegistry, and WithExpiry, which bounds how long the unused VP stays valid.
+func WithSingleUse() CreateOpt {
+	return func(o *createOptions) {
+		o.singleUse = true
+	}
+}
+
+// WithNonce sets the "nonce" claim of the VP, binding it to a verifier challenge.
+func WithNonce(nonce string) CreateOpt {
+	return func(o *createOptions) {
+		o.nonce = nonce
+	}
+}
+
+// WithAudience sets the "aud" claim of the VP to the intended verifier.
+func WithAudience(audience string) CreateOpt {
+	return func(o *createOptions) {
+		o.audience = audience
+	}
+}
+
+// WithTokenType sets the "typ" header of the VP JWT (default: TokenTypeJWT).
+func WithTokenType(typ string) CreateOpt {
+	return func(o *createOptions) {
+		o.tokenType = typ
+	}
+}
+
+// WithKeyID sets the verification method fragment used to build the "kid" header (default: "key-1").
+func WithKeyID(fragment string) CreateOpt {
+	return func(o *createOptions) {
+		o.verificationMethodKey = fragment
+	}
+}
+
+// WithHeader adds a custom header to the VP JWT, e.g. "x5c" for verifiers requiring a certificate chain.
+// The "alg", "typ" and "kid" headers cannot be set this way.
+func WithHeader(name string, value any) CreateOpt {
+	return func(o *createOptions) {
+		if o.headers == nil {
+			o.headers = map[string]any{}
+		}
+		o.headers[name] = value
+	}
+}
+
+// splitCreateOpts applies the CreateOpt values found in opts and returns the remaining provider options.
+func splitCreateOpts(opts []any) (*createOptions, []any, error) {
+	options := &createOptions{
+		tokenType:             TokenTypeJWT,
+		verificationMethodKey: defaultVerificationMethodKey,
+	}
+
+	var providerOpts []any
+	for _, opt := range opts {
+		if createOpt, ok := opt.(CreateOpt); ok {
+			createOpt(options)
+			continue
+		}
+		providerOpts = append(providerOpts, opt)
+	}
+
+	switch options.outputFormat {
+	case OutputQuoted, OutputCompact:
+	case OutputJWSJSON, OutputEnvelopedVP:
+		if options.encryptionKey != nil {
+			return nil, nil, errors.New("encrypted presentations only support quoted and compact output")
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown output format %d", options.outputFormat)
+	}
+	if len(options.additionalProofs) > 0 && options.outputFormat != OutputJWSJSON {
+		return nil, nil, errors.New("presentations with several proofs require OutputJWSJSON")
+	}
+
+	for name := range options.headers {
+		switch name {
+		case "alg", "typ", "kid":
+			return nil, nil, fmt.Errorf("header %q cannot be overridden", name)
+		}
+	}
+
+	return options, providerOpts, nil
+}
+
+// VerifyOpt configures a VerifyToken call.
+type VerifyOpt func(*verifyOptions)
+
+// verifyOptions holds configuration for token verification.
+type verifyOptions struct {
+	x509Roots             *x509.CertPool
+	issuerRegistry        trust.IssuerRegistry
+	displaySources        []DisplaySource
+	linkedDomain          string
+	decryptionKeys        []*ecdh.PrivateKey
+	nonce                 string
+	audience              string
+	policy                string
+	keyAttestation        KeyAttestationVerifier
+	algorithms            []string
+	trace                 *VerificationTrace
+	proofPurposes         bool
+	allProofs             bool
+	rawPresentation       *RawPresentation
+	degradation           *Degradation
+	timeouts              VerifyTimeouts
+	relatedResources      bool
+	certificateThumbprint string
+	anchors               anchor.Finder
+}
+
+// Proof purposes of the JWT proofs checked by WithProofPurposes: a presentation proof authenticates
+// its holder, a credential proof asserts the issuer's claims, a capability proof delegates authority.
+const (
+	ProofPurposeAuthentication       = did.Authentication
+	ProofPurposeAssertionMethod      = did.AssertionMethod
+	ProofPurposeCapabilityDelegation = did.CapabilityDelegation
+)
+
+// WithProofPurposes checks every proof against its purpose: the key signing the VP must be listed under
+// "authentication" in the holder's DID document, and the keys signing the VCs under "assertionMethod" in
+// their issuers' documents; VerifyCapability checks the keys signing each link under "capabilityDelegation".
+// Verification fails with ErrProofPurpose otherwise. Credentials handled by a
+// CredentialParser are left to the parser.
+func WithProofPurposes() VerifyOpt {
+	return func(o *verifyOptions) {
+		o.proofPurposes = true
+	}
+}
+
+// purpose returns the verification relationship to check a proof against, or "" when unchecked.
+func (o *verifyOptions) purpose(purpose string) string {
+	if !o.proofPurposes {
+		return ""
+	}
+	return purpose
+}
+
+// WithExpectedNonce fails verification unless the VP "nonce" claim equals nonce.
+func WithExpectedNonce(nonce string) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.nonce = nonce
+	}
+}
+
+// WithExpectedAudience fails verification unless the VP "aud" claim is, or contains, audience.
+func WithExpectedAudience(audience string) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.audience = audience
+	}
+}
+
+// WithRequireLinkedDomain fails verification unless the holder DID, or the DID of one of the
+// credential issuers, is linked to domain through its well-known DID configuration.
+// See Service.VerifyDomainLinkage.
+func WithRequireLinkedDomain(domain string) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.linkedDomain = domain
+	}
+}
+
+// WithIssuerRegistry only accepts credentials whose issuer is trusted by registry.
+func WithIssuerRegistry(registry trust.IssuerRegistry) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.issuerRegistry = registry
+	}
+}
+
+// getVerifyOptions returns the verification options.
+func getVerifyOptions(opts ...VerifyOpt) *verifyOptions {
+	options := &verifyOptions{algorithms: defaultAlgorithms}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OutputFormat selects how CreateToken serializes the VP token.
+type OutputFormat int
+
+const (
+	// OutputQuoted is the compact JWS encoded as a JSON string, including the surrounding quotes.
+	// It is the default, for compatibility with earlier releases.
+	OutputQuoted OutputFormat = iota
+	// OutputCompact is the bare compact JWS: header.payload.signature.
+	OutputCompact
+	// OutputJWSJSON is the flattened JWS JSON serialization (RFC 7515 section 7.2.2).
+	OutputJWSJSON
+	// OutputEnvelopedVP is a W3C EnvelopedVerifiablePresentation object carrying the compact JWS
+	// as a data: URL, as defined by VC-JOSE-COSE.
+	OutputEnvelopedVP
+)
+
+// Media types of the data: URLs in enveloped presentations and credentials.
+const (
+	envelopedPresentationMediaType = "application/vp+jwt"
+	envelopedCredentialMediaType   = "application/vc+jwt"
+)
+
+// WithOutputFormat sets the serialization of the token returned by CreateToken (default: OutputQuoted).
+// Encrypted tokens only support OutputQuoted and OutputCompact.
+func WithOutputFormat(format OutputFormat) CreateOpt {
+	return func(o *createOptions) {
+		o.outputFormat = format
+	}
+}
+
+// jwsJSON is the flattened JWS JSON serialization. Signatures holds the general serialization.
+type jwsJSON struct {
+	Protected  string         `json:"protected,omitempty"`
+	Payload    string         `json:"payload"`
+	Signature  string         `json:"signature,omitempty"`
+	Signatures []jwsSignature `json:"signatures,omitempty"`
+}
+
+// jwsSignature is one signature of the general JWS JSON serialization.
+type jwsSignature struct {
+	Protected string `json:"protected"`
+	Signature string `json:"signature"`
+}
+
+// envelopedPresentation is a W3C EnvelopedVerifiablePresentation or EnvelopedVerifiableCredential.
+type envelopedPresentation struct {
+	Context []string `json:"@context"`
+	ID      string   `json:"id"`
+	Type    string   `json:"type"`
+}
+
+// serializeToken encodes a compact JWS or JWE in the requested output format.
+// Format combinations are validated by splitCreateOpts.
+func serializeToken(compact string, format OutputFormat) (string, error) {
+	switch format {
+	case OutputQuoted:
+		quoted, err := json.Marshal(compact)
+		if err != nil {
+			return "", err
+		}
+		return string(quoted), nil
+
+	case OutputCompact:
+		return compact, nil
+
+	case OutputJWSJSON:
+		parts := strings.Split(compact, ".")
+		if len(parts) != 3 {
+			return "", errors.New("invalid JWT format")
+		}
+		out, err := json.Marshal(jwsJSON{Protected: parts[0], Payload: parts[1], Signature: parts[2]})
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+
+	case OutputEnvelopedVP:
+		out, err := json.Marshal(envelopedPresentation{
+			Context: []string{"https://www.w3.org/ns/credentials/v2"},
+			ID:      "data:" + envelopedPresentationMediaType + "," + compact,
+			Type:    "EnvelopedVerifiablePresentation",
+		})
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+
+	return "", fmt.Errorf("unknown output format %d", format)
+}
+
+// normalizeToken accepts a token in any OutputFormat and returns its compact form, one compact JWS
+// per signature when a JWS JSON serialization carries several.
+func normalizeToken(token string) ([]string, error) {
+	token = strings.TrimSpace(token)
+	if !strings.HasPrefix(token, "{") {
+		return []string{strings.Trim(token, "\"")}, nil
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(token), &object); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if _, ok := object["payload"]; ok {
+		var jws jwsJSON
+		if err := json.Unmarshal([]byte(token), &jws); err != nil {
+			return nil, fmt.Errorf("invalid JWS JSON serialization: %w", err)
+		}
+
+		signatures := jws.Signatures
+		if len(signatures) == 0 {
+			signatures = append(signatures, jwsSignature{Protected: jws.Protected, Signature: jws.Signature})
+		}
+		compacts := make([]string, len(signatures))
+		for i, signature := range signatures {
+			if signature.Protected == "" || signature.Signature == "" {
+				return nil, errors.New("JWS JSON serialization is missing protected header or signature")
+			}
+			compacts[i] = signature.Protected + "." + jws.Payload + "." + signature.Signature
+		}
+
+		return compacts, nil
+	}
+
+	var enveloped envelopedPresentation
+	if err := json.Unmarshal([]byte(token), &enveloped); err != nil {
+		return nil, fmt.Errorf("invalid enveloped presentation: %w", err)
+	}
+	if enveloped.Type != "EnvelopedVerifiablePresentation" {
+		return nil, fmt.Errorf("unsupported token object type %q", enveloped.Type)
+	}
+
+	compact, ok := strings.CutPrefix(enveloped.ID, "data:"+envelopedPresentationMediaType+",")
+	if !ok {
+		return nil, fmt.Errorf("enveloped presentation id is not a %s data URL", envelopedPresentationMediaType)
+	}
+
+	return []string{compact}, nil
+}
+
+// normalizeCredential accepts a compact JWT VC, optionally JSON-quoted, or an
+// EnvelopedVerifiableCredential and returns the compact form.
+func normalizeCredential(credential string) (string, error) {
+	credential = strings.TrimSpace(credential)
+	if !strings.HasPrefix(credential, "{") {
+		return strings.Trim(credential, "\""), nil
+	}
+
+	var enveloped envelopedPresentation
+	if err := json.Unmarshal([]byte(credential), &enveloped); err != nil {
+		return "", fmt.Errorf("invalid enveloped credential: %w", err)
+	}
+	if enveloped.Type != "EnvelopedVerifiableCredential" {
+		return "", fmt.Errorf("unsupported credential object type %q", enveloped.Type)
+	}
+
+	compact, ok := strings.CutPrefix(enveloped.ID, "data:"+envelopedCredentialMediaType+",")
+	if !ok {
+		return "", fmt.Errorf("enveloped credential id is not a %s data URL", envelopedCredentialMediaType)
+	}
+
+	return compact, nil
+}
@@ -0,0 +1,75 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestCreateTokenOutputFormats(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+
+	tests := []struct {
+		name   string
+		format auth.OutputFormat
+		check  func(t *testing.T, token string)
+	}{
+		{"quoted", auth.OutputQuoted, func(t *testing.T, token string) {
+			var compact string
+			if err := json.Unmarshal([]byte(token), &compact); err != nil || strings.Count(compact, ".") != 2 {
+				t.Errorf("expected a JSON string holding a compact JWS, got %s", token)
+			}
+		}},
+		{"compact", auth.OutputCompact, func(t *testing.T, token string) {
+			if strings.HasPrefix(token, "\"") || strings.Count(token, ".") != 2 {
+				t.Errorf("expected a bare compact JWS, got %s", token)
+			}
+		}},
+		{"JWS JSON", auth.OutputJWSJSON, func(t *testing.T, token string) {
+			var jws map[string]string
+			if err := json.Unmarshal([]byte(token), &jws); err != nil || jws["protected"] == "" || jws["payload"] == "" || jws["signature"] == "" {
+				t.Errorf("expected a flattened JWS JSON serialization, got %s", token)
+			}
+		}},
+		{"enveloped VP", auth.OutputEnvelopedVP, func(t *testing.T, token string) {
+			var vp map[string]any
+			if err := json.Unmarshal([]byte(token), &vp); err != nil || vp["type"] != "EnvelopedVerifiablePresentation" ||
+				!strings.HasPrefix(vp["id"].(string), "data:application/vp+jwt,") {
+				t.Errorf("expected an enveloped presentation, got %s", token)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address, auth.WithOutputFormat(tt.format))
+			if err != nil {
+				t.Fatalf("CreateToken failed: %v", err)
+			}
+
+			tt.check(t, token)
+
+			if _, err := a.VerifyToken(context.Background(), token); err != nil {
+				t.Fatalf("VerifyToken failed: %v", err)
+			}
+		})
+	}
+
+	verifierKey, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	_, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address,
+		auth.WithOutputFormat(auth.OutputEnvelopedVP), auth.WithEncryption(verifierKey.PublicKey(), ""))
+	if err == nil {
+		t.Error("expected enveloped output of an encrypted token to be rejected")
+	}
+}
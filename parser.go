@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/trust"
+)
+
+// ErrUnsupportedFormat is returned for credentials that are neither JWTs nor detected by a registered CredentialParser.
+var ErrUnsupportedFormat = errors.New("unsupported credential format")
+
+// CredentialParser decodes and verifies credentials of one envelope format, e.g. SD-JWT, JSON-LD with
+// Data Integrity proofs, CWT or mdoc. Compact JWT credentials are handled by the package itself.
+type CredentialParser interface {
+	// Detect reports whether credential, an entry of a presentation's verifiableCredential array
+	// or the string given to VerifyCredential, is in the parser's format. It must not block.
+	Detect(credential any) bool
+
+	// Parse verifies the credential's proof, resolving issuer keys with resolver, and returns its contents.
+	Parse(ctx context.Context, credential any, resolver did.Resolver) (*ParsedCredential, error)
+}
+
+// ParsedCredential is a credential whose proof has been verified by a CredentialParser.
+// Schema validation, issuer trust, validity and policy checks are then applied as for JWT credentials.
+type ParsedCredential struct {
+	Contents     map[string]any      // Credential in the W3C data model
+	Issuer       string              // DID whose key signed the credential
+	Certificates []*x509.Certificate // Issuer certificate chain, leaf first; may be empty
+	PublicKey    crypto.PublicKey    // Key that verified the proof, to which Certificates must be bound
+	Proof        *ProofMetadata      // Reported as VcClaims.Proof; may be nil
+	NotBefore    time.Time           // Envelope validity start, zero if none
+	NotAfter     time.Time           // Envelope expiry, zero if none
+}
+
+// registeredParser is a CredentialParser and the format name it was registered under.
+type registeredParser struct {
+	format string
+	parser CredentialParser
+}
+
+var (
+	parsersMu sync.RWMutex
+	parsers   []registeredParser
+)
+
+// RegisterCredentialParser registers the parser for the named credential format, replacing any parser
+// previously registered under that name. Parsers are asked to detect a credential in registration order;
+// credentials none of them detect are treated as JWTs.
+func RegisterCredentialParser(format string, parser CredentialParser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+
+	for i := range parsers {
+		if parsers[i].format == format {
+			parsers[i].parser = parser
+			return
+		}
+	}
+	parsers = append(parsers, registeredParser{format: format, parser: parser})
+}
+
+// detectCredentialParser returns the first registered parser that detects credential, or a nil parser.
+func detectCredentialParser(credential any) (string, CredentialParser) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+
+	for _, p := range parsers {
+		if p.parser != nil && p.parser.Detect(credential) {
+			return p.format, p.parser
+		}
+	}
+	return "", nil
+}
+
+// verifyParsedCredential verifies a credential in a format handled by a registered CredentialParser.
+func (a *Service) verifyParsedCredential(ctx context.Context, format string, parser CredentialParser, vcItem any, options *verifyOptions) (VcClaims, error) {
+	parsed, err := parser.Parse(ctx, vcItem, a.resolver)
+	if err == nil && parsed == nil {
+		err = errors.New("parser returned no credential")
+	}
+	traceStep(ctx, StepDecode, err, "format", format)
+	if err != nil {
+		return VcClaims{}, fmt.Errorf("failed to verify %s credential: %w", format, err)
+	}
+
+	err = a.validateSchema(ctx, parsed.Contents)
+	traceStep(ctx, StepSchema, err)
+	if err != nil {
+		return VcClaims{}, fmt.Errorf("failed to validate credential: %w", err)
+	}
+
+	if options.x509Roots != nil {
+		err := errors.New("certificate chain is required")
+		if len(parsed.Certificates) > 0 {
+			err = verifyCertificateChain(parsed.Certificates, parsed.Issuer, options.x509Roots, a.clock.Now())
+		}
+		traceStep(ctx, StepCertificate, err)
+		if err != nil {
+			return VcClaims{}, fmt.Errorf("failed to verify issuer certificate: %w", err)
+		}
+	}
+
+	if options.issuerRegistry != nil {
+		err := checkIssuer(ctx, options.issuerRegistry, trust.Issuer{DID: parsed.Issuer, Certificates: parsed.Certificates, PublicKey: parsed.PublicKey})
+		traceStep(ctx, StepIssuerTrust, err, "issuer", parsed.Issuer)
+		if err != nil {
+			return VcClaims{}, err
+		}
+	}
+
+	claims, err := newVcClaims(parsed.Contents, nil)
+	if err == nil {
+		err = checkIssuerBinding(claims, "", parsed.Issuer)
+	}
+	traceStep(ctx, StepClaims, err)
+	if err != nil {
+		return VcClaims{}, err
+	}
+	claims.Proof = parsed.Proof
+
+	err = checkValidity(claims, parsed.NotBefore, parsed.NotAfter, a.clock.Now())
+	traceStep(ctx, StepValidity, err)
+	if err != nil {
+		return VcClaims{}, err
+	}
+
+	claims.Display = lookupDisplay(options.displaySources, claims)
+
+	return claims, nil
+}
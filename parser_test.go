@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// testLDParser handles JSON-LD credential objects carrying a "TestProof" proof whose value must be "valid".
+type testLDParser struct{}
+
+func (testLDParser) Detect(credential any) bool {
+	object, ok := credential.(map[string]any)
+	if !ok {
+		return false
+	}
+	proof, _ := object["proof"].(map[string]any)
+	return proof["type"] == "TestProof"
+}
+
+func (testLDParser) Parse(ctx context.Context, credential any, resolver did.Resolver) (*auth.ParsedCredential, error) {
+	object := credential.(map[string]any)
+	proof := object["proof"].(map[string]any)
+	if proof["proofValue"] != "valid" {
+		return nil, errors.New("invalid proof")
+	}
+
+	issuer, _ := object["issuer"].(string)
+	if _, err := resolver.Resolve(ctx, issuer); err != nil {
+		return nil, err
+	}
+
+	contents := make(map[string]any, len(object))
+	for name, value := range object {
+		if name != "proof" {
+			contents[name] = value
+		}
+	}
+	return &auth.ParsedCredential{
+		Contents: contents,
+		Issuer:   issuer,
+		Proof:    &auth.ProofMetadata{Format: "test_ldp", VerificationMethod: issuer + "#key-1"},
+	}, nil
+}
+
+func init() {
+	auth.RegisterCredentialParser("test_ldp", testLDParser{})
+}
+
+func TestVerifyTokenRegisteredParser(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	ldCredential := func(proofValue string) map[string]any {
+		return map[string]any{
+			"@context":          []any{"https://www.w3.org/ns/credentials/v2"},
+			"type":              []any{"VerifiableCredential"},
+			"issuer":            issuer.DID,
+			"credentialSchema":  map[string]any{"id": authtest.SchemaURL, "type": "JsonSchema"},
+			"credentialSubject": map[string]any{"id": holder.DID, "role": "editor"},
+			"proof":             map[string]any{"type": "TestProof", "proofValue": proofValue},
+		}
+	}
+	presentation := func(credentials ...any) string {
+		return craftJWT(t, holder, nil, map[string]any{
+			"iss": holder.DID,
+			"vp":  map[string]any{"holder": holder.DID, "verifiableCredential": credentials},
+		})
+	}
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+
+	t.Run("mixed formats", func(t *testing.T) {
+		var trace auth.VerificationTrace
+		claims, err := a.VerifyToken(context.Background(), presentation(ldCredential("valid"), vcJwt), auth.WithTrace(&trace))
+		if err != nil {
+			t.Fatalf("VerifyToken failed: %v", err)
+		}
+		if len(claims) != 2 || claims[0].Proof.Format != "test_ldp" || claims[1].Proof.Format != "JWT" {
+			t.Fatalf("unexpected claims: %+v", claims)
+		}
+		if role, _ := claims[0].Subject().Get("role"); role != "editor" {
+			t.Fatalf("unexpected subject: %+v", claims[0].Subject())
+		}
+		for _, step := range trace.Steps() {
+			if step.Target == "credential[0]" && step.Step == auth.StepDecode && step.Details["format"] != "test_ldp" {
+				t.Fatalf("decode step does not record the format: %+v", step)
+			}
+		}
+	})
+
+	t.Run("invalid proof", func(t *testing.T) {
+		_, err := a.VerifyToken(context.Background(), presentation(ldCredential("forged")))
+		var credErr *auth.CredentialError
+		if !errors.As(err, &credErr) || credErr.Index != 0 {
+			t.Fatalf("expected CredentialError for credential 0, got %v", err)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		unknown := ldCredential("valid")
+		delete(unknown, "proof")
+		if _, err := a.VerifyToken(context.Background(), presentation(unknown)); !errors.Is(err, auth.ErrUnsupportedFormat) {
+			t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package pe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Evaluate returns the values selected by a JSONPath expression in document.
+// The subset used by Presentation Exchange definitions is supported: "$" followed by
+// ".name", "['name']", "[index]", "[*]" and ".*" segments. Missing members select nothing.
+func Evaluate(document any, path string) ([]any, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []any{document}
+	for _, segment := range segments {
+		var next []any
+		for _, value := range current {
+			next = append(next, segment.apply(value)...)
+		}
+		current = next
+	}
+
+	return current, nil
+}
+
+// pathSegment selects a member by name, an element by index, or every child when wildcard is set.
+type pathSegment struct {
+	name     string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func (s pathSegment) apply(value any) []any {
+	switch v := value.(type) {
+	case map[string]any:
+		if s.wildcard {
+			children := make([]any, 0, len(v))
+			for _, child := range v {
+				children = append(children, child)
+			}
+			return children
+		}
+		if child, ok := v[s.name]; ok && !s.isIndex {
+			return []any{child}
+		}
+	case []any:
+		if s.wildcard {
+			return v
+		}
+		if s.isIndex && s.index >= 0 && s.index < len(v) {
+			return []any{v[s.index]}
+		}
+	}
+	return nil
+}
+
+// parsePath splits a JSONPath expression into segments.
+func parsePath(path string) ([]pathSegment, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(path), "$")
+	if !ok {
+		return nil, fmt.Errorf("JSONPath %q must start with $", path)
+	}
+
+	var segments []pathSegment
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return nil, fmt.Errorf("JSONPath %q: recursive descent is not supported", path)
+
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("JSONPath %q: empty member name", path)
+			}
+			segments = append(segments, pathSegment{name: name, wildcard: name == "*"})
+			rest = rest[end:]
+
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath %q: unterminated bracket", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				segments = append(segments, pathSegment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, pathSegment{name: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("JSONPath %q: unsupported selector [%s]", path, inner)
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			}
+
+		default:
+			return nil, fmt.Errorf("JSONPath %q: unexpected %q", path, rest[:1])
+		}
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,43 @@
+package pe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	document := map[string]any{
+		"vc": map[string]any{
+			"type":              []any{"VerifiableCredential", "EmployeeCredential"},
+			"credentialSubject": map[string]any{"given name": "Ada"},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want []any
+	}{
+		{"$.vc.type", []any{[]any{"VerifiableCredential", "EmployeeCredential"}}},
+		{"$.vc.type[1]", []any{"EmployeeCredential"}},
+		{"$.vc.type[*]", []any{"VerifiableCredential", "EmployeeCredential"}},
+		{"$['vc'].credentialSubject['given name']", []any{"Ada"}},
+		{"$.vc.missing", nil},
+		{"$.vc.type[5]", nil},
+	}
+
+	for _, tt := range tests {
+		got, err := Evaluate(document, tt.path)
+		if err != nil {
+			t.Fatalf("Evaluate(%s) failed: %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Evaluate(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	for _, path := range []string{"vc.type", "$..type", "$.vc[", "$.vc[?(@.a)]"} {
+		if _, err := Evaluate(document, path); err == nil {
+			t.Errorf("Evaluate(%s) should fail", path)
+		}
+	}
+}
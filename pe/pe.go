@@ -0,0 +1,169 @@
+// Package pe implements the parts of DIF Presentation Exchange v2 used to select credentials for a
+// presentation: presentation definitions, input descriptors and their field constraints.
+package pe
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// PresentationDefinition describes the credentials a verifier asks for.
+type PresentationDefinition struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name,omitempty"`
+	Purpose          string            `json:"purpose,omitempty"`
+	InputDescriptors []InputDescriptor `json:"input_descriptors"`
+}
+
+// InputDescriptor describes one credential the verifier requires.
+type InputDescriptor struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name,omitempty"`
+	Purpose     string      `json:"purpose,omitempty"`
+	Constraints Constraints `json:"constraints"`
+}
+
+// Constraints holds the field constraints a credential must satisfy.
+type Constraints struct {
+	LimitDisclosure string  `json:"limit_disclosure,omitempty"` // "required" or "preferred"
+	Fields          []Field `json:"fields,omitempty"`
+}
+
+// Field constrains the value found at one of Path. Filter is a JSON Schema the value must satisfy.
+type Field struct {
+	ID       string          `json:"id,omitempty"`
+	Path     []string        `json:"path"`
+	Purpose  string          `json:"purpose,omitempty"`
+	Filter   json.RawMessage `json:"filter,omitempty"`
+	Optional bool            `json:"optional,omitempty"`
+}
+
+// Parse decodes a presentation definition.
+func Parse(data []byte) (*PresentationDefinition, error) {
+	var definition PresentationDefinition
+	if err := json.Unmarshal(data, &definition); err != nil {
+		return nil, fmt.Errorf("failed to parse presentation definition: %w", err)
+	}
+
+	if definition.ID == "" {
+		return nil, fmt.Errorf("presentation definition id is required")
+	}
+	if len(definition.InputDescriptors) == 0 {
+		return nil, fmt.Errorf("presentation definition has no input descriptors")
+	}
+
+	return &definition, nil
+}
+
+// Match reports whether the credential satisfies every required field of the descriptor.
+// credential is the decoded JWT payload of a JWT VC; paths are evaluated against the payload and,
+// so that both "$.vc.type" and "$.type" styles work, against its "vc" claim.
+func (d InputDescriptor) Match(credential map[string]any) (bool, error) {
+	roots := []any{credential}
+	if vc, ok := credential["vc"].(map[string]any); ok {
+		roots = append(roots, vc)
+	}
+
+	for _, field := range d.Constraints.Fields {
+		ok, err := field.match(roots)
+		if err != nil {
+			return false, fmt.Errorf("input descriptor %s: %w", d.ID, err)
+		}
+		if !ok && !field.Optional {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// match reports whether one of the field's paths resolves, in one of roots, to a value accepted by the filter.
+func (f Field) match(roots []any) (bool, error) {
+	var schema gojsonschema.JSONLoader
+	if len(f.Filter) > 0 {
+		schema = gojsonschema.NewBytesLoader(f.Filter)
+	}
+
+	for _, path := range f.Path {
+		for _, root := range roots {
+			values, err := Evaluate(root, path)
+			if err != nil {
+				return false, err
+			}
+
+			for _, value := range values {
+				if schema == nil {
+					return true, nil
+				}
+
+				result, err := gojsonschema.Validate(schema, gojsonschema.NewGoLoader(value))
+				if err != nil {
+					return false, fmt.Errorf("invalid filter: %w", err)
+				}
+				if result.Valid() {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// Submission is a presentation_submission describing where each input descriptor is satisfied.
+type Submission struct {
+	ID            string       `json:"id"`
+	DefinitionID  string       `json:"definition_id"`
+	DescriptorMap []Descriptor `json:"descriptor_map"`
+}
+
+// Descriptor maps one input descriptor to a location in the presentation.
+type Descriptor struct {
+	ID         string      `json:"id"`
+	Format     string      `json:"format"`
+	Path       string      `json:"path"`
+	PathNested *Descriptor `json:"path_nested,omitempty"`
+}
+
+// RequestedClaims returns the credentialSubject claims the descriptor's fields refer to, by
+// top-level claim name. all is true when a field selects the whole credentialSubject.
+// Paths may address the claims directly ("$.credentialSubject.x") or through the "vc" claim.
+func (d InputDescriptor) RequestedClaims() (claims []string, all bool, err error) {
+	seen := map[string]bool{}
+	for _, field := range d.Constraints.Fields {
+		for _, path := range field.Path {
+			segments, err := parsePath(path)
+			if err != nil {
+				return nil, false, fmt.Errorf("input descriptor %s: %w", d.ID, err)
+			}
+
+			if len(segments) > 0 && segments[0].name == "vc" && !segments[0].wildcard {
+				segments = segments[1:]
+			}
+			if len(segments) == 0 || segments[0].wildcard {
+				return nil, true, nil
+			}
+			if segments[0].name != "credentialSubject" {
+				continue
+			}
+
+			// Skip array selectors of multi-subject credentials.
+			segments = segments[1:]
+			for len(segments) > 1 && (segments[0].isIndex || segments[0].wildcard) {
+				segments = segments[1:]
+			}
+			if len(segments) == 0 || segments[0].wildcard || segments[0].isIndex {
+				return nil, true, nil
+			}
+
+			if name := segments[0].name; !seen[name] {
+				seen[name] = true
+				claims = append(claims, name)
+			}
+		}
+	}
+
+	return claims, false, nil
+}
@@ -0,0 +1,221 @@
+// Package pii encrypts personal data before the SDK's stores persist it: claims of cross-device
+// sessions, the holder and credentials of issued-token records, and wallet credentials. Encryption is
+// field-level: identifiers and timestamps the stores look up and expire by stay in clear, the personal
+// data is sealed with an Encryptor, either a local AES-GCM key or envelope encryption under a KMS key.
+package pii
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github/hovanhoa/go-vc-auth/secret"
+	"github/hovanhoa/go-vc-auth/vault"
+)
+
+// ErrDecrypt is returned when a ciphertext cannot be decrypted: it is corrupt, was sealed for other
+// associated data, or under another key.
+var ErrDecrypt = errors.New("pii: decryption failed")
+
+// Formats of the ciphertexts, in their first byte.
+const (
+	formatAESGCM   = 1
+	formatEnvelope = 2
+)
+
+// dataKeySize is the size of the AES-256 data keys of envelope encryption.
+const dataKeySize = 32
+
+// Encryptor seals personal data. associatedData binds a ciphertext to its record, e.g. a session id,
+// so it cannot be moved to another record; it is not encrypted and must be given again to Decrypt.
+// Implementations must be safe for concurrent use.
+type Encryptor interface {
+	Encrypt(ctx context.Context, plaintext, associatedData []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error)
+}
+
+// Prefix starts the string fields sealed by SealString.
+const Prefix = "pii:"
+
+// SealString encrypts plaintext into a string field: Prefix followed by the base64 ciphertext, e.g. to
+// store in a text column.
+func SealString(ctx context.Context, enc Encryptor, plaintext, associatedData []byte) (string, error) {
+	ciphertext, err := enc.Encrypt(ctx, plaintext, associatedData)
+	if err != nil {
+		return "", err
+	}
+	return Prefix + base64.RawStdEncoding.EncodeToString(ciphertext), nil
+}
+
+// OpenString decrypts a field sealed by SealString. ok is false, and field is left to the caller, when
+// the field is not sealed, e.g. it was written before encryption was enabled.
+func OpenString(ctx context.Context, enc Encryptor, field string, associatedData []byte) (plaintext []byte, ok bool, err error) {
+	encoded, ok := strings.CutPrefix(field, Prefix)
+	if !ok {
+		return nil, false, nil
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, true, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	plaintext, err = enc.Decrypt(ctx, ciphertext, associatedData)
+	return plaintext, true, err
+}
+
+// aesGCM encrypts with a local AES-GCM key.
+type aesGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM creates an Encryptor using key, of 16, 24 or 32 bytes, directly. Prefer NewEnvelope when
+// the key can be kept in a KMS.
+func NewAESGCM(key secret.Secret) (Encryptor, error) {
+	aead, err := newAEAD(key.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &aesGCM{aead: aead}, nil
+}
+
+func (e *aesGCM) Encrypt(ctx context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	return seal(e.aead, []byte{formatAESGCM}, plaintext, associatedData)
+}
+
+func (e *aesGCM) Decrypt(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error) {
+	if len(ciphertext) == 0 || ciphertext[0] != formatAESGCM {
+		return nil, fmt.Errorf("%w: not an AES-GCM ciphertext", ErrDecrypt)
+	}
+	return open(e.aead, ciphertext[:1], ciphertext[1:], associatedData)
+}
+
+// KeyWrapper encrypts and decrypts data keys with a key kept in a KMS, e.g. VaultTransit.
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// envelope encrypts every plaintext under a fresh data key wrapped by a KeyWrapper.
+type envelope struct {
+	wrapper KeyWrapper
+}
+
+// NewEnvelope creates an Encryptor using envelope encryption: each plaintext is encrypted with a fresh
+// AES-256-GCM data key, which is wrapped by wrapper and stored with the ciphertext. The KMS key never
+// leaves the KMS, and every Encrypt and Decrypt makes one call to it.
+func NewEnvelope(wrapper KeyWrapper) Encryptor {
+	return &envelope{wrapper: wrapper}
+}
+
+func (e *envelope) Encrypt(ctx context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	defer clear(key)
+
+	wrapped, err := e.wrapper.WrapKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	if len(wrapped) > 0xffff {
+		return nil, fmt.Errorf("wrapped data key exceeds %d bytes", 0xffff)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header, with the wrapped key, is authenticated along with the associated data.
+	header := binary.BigEndian.AppendUint16([]byte{formatEnvelope}, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+	return seal(aead, header, plaintext, associatedData)
+}
+
+func (e *envelope) Decrypt(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error) {
+	if len(ciphertext) < 3 || ciphertext[0] != formatEnvelope {
+		return nil, fmt.Errorf("%w: not an envelope ciphertext", ErrDecrypt)
+	}
+	end := 3 + int(binary.BigEndian.Uint16(ciphertext[1:3]))
+	if len(ciphertext) < end {
+		return nil, fmt.Errorf("%w: truncated ciphertext", ErrDecrypt)
+	}
+
+	key, err := e.wrapper.UnwrapKey(ctx, ciphertext[3:end])
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	defer clear(key)
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	return open(aead, ciphertext[:end], ciphertext[end:], associatedData)
+}
+
+// vaultTransit wraps data keys with a Vault transit encryption key.
+type vaultTransit struct {
+	vault *vault.Vault
+	name  string
+}
+
+// VaultTransit returns a KeyWrapper encrypting data keys with the named key of Vault's transit
+// engine, e.g. an aes256-gcm96 key. Rotating the transit key keeps older data readable.
+func VaultTransit(v *vault.Vault, name string) KeyWrapper {
+	return &vaultTransit{vault: v, name: name}
+}
+
+func (w *vaultTransit) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	ciphertext, err := w.vault.TransitEncrypt(ctx, w.name, key)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(ciphertext), nil
+}
+
+func (w *vaultTransit) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return w.vault.TransitDecrypt(ctx, w.name, string(wrapped))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns header || nonce || ciphertext, authenticating header with associatedData.
+func seal(aead cipher.AEAD, header, plaintext, associatedData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := append(header[:len(header):len(header)], nonce...)
+	return aead.Seal(out, nonce, plaintext, additionalData(header, associatedData)), nil
+}
+
+// open reverses seal; body is what follows header.
+func open(aead cipher.AEAD, header, body, associatedData []byte) ([]byte, error) {
+	if len(body) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: truncated ciphertext", ErrDecrypt)
+	}
+	plaintext, err := aead.Open(nil, body[:aead.NonceSize()], body[aead.NonceSize():], additionalData(header, associatedData))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// additionalData is the length-prefixed header followed by the caller's associated data, so the two
+// cannot be shifted into each other.
+func additionalData(header, associatedData []byte) []byte {
+	data := binary.BigEndian.AppendUint32(nil, uint32(len(header)))
+	data = append(data, header...)
+	return append(data, associatedData...)
+}
@@ -0,0 +1,110 @@
+package pii_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github/hovanhoa/go-vc-auth/pii"
+	"github/hovanhoa/go-vc-auth/secret"
+	"github/hovanhoa/go-vc-auth/vault"
+)
+
+// transitServer fakes the encrypt and decrypt endpoints of a transit key, "encrypting" by base64
+// encoding with a marker.
+func transitServer(t *testing.T, calls *int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/transit/encrypt/pii", func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		var in struct{ Plaintext string }
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		_, _ = w.Write([]byte(`{"data":{"ciphertext":"vault:v1:` + in.Plaintext + `"}}`))
+	})
+	mux.HandleFunc("POST /v1/transit/decrypt/pii", func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		var in struct{ Ciphertext string }
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		_, _ = w.Write([]byte(`{"data":{"plaintext":"` + strings.TrimPrefix(in.Ciphertext, "vault:v1:") + `"}}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEncryptors(t *testing.T) {
+	ctx := context.Background()
+	var calls int
+	srv := transitServer(t, &calls)
+
+	local, err := pii.NewAESGCM(secret.FromBytes(bytes.Repeat([]byte{7}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptors := map[string]pii.Encryptor{
+		"AES-GCM":        local,
+		"Vault envelope": pii.NewEnvelope(pii.VaultTransit(vault.NewVault(srv.URL, "token", 0), "pii")),
+	}
+
+	for name, enc := range encryptors {
+		t.Run(name, func(t *testing.T) {
+			plaintext := []byte(`{"name":"Nguyen Van A"}`)
+			ciphertext, err := enc.Encrypt(ctx, plaintext, []byte("session-1"))
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if bytes.Contains(ciphertext, []byte("Nguyen")) {
+				t.Fatal("ciphertext contains the plaintext")
+			}
+
+			got, err := enc.Decrypt(ctx, ciphertext, []byte("session-1"))
+			if err != nil || !bytes.Equal(got, plaintext) {
+				t.Fatalf("Decrypt = %q, %v", got, err)
+			}
+			if _, err := enc.Decrypt(ctx, ciphertext, []byte("session-2")); !errors.Is(err, pii.ErrDecrypt) {
+				t.Errorf("Decrypt with other associated data: %v, want ErrDecrypt", err)
+			}
+			tampered := bytes.Clone(ciphertext)
+			tampered[len(tampered)-1] ^= 1
+			if _, err := enc.Decrypt(ctx, tampered, []byte("session-1")); !errors.Is(err, pii.ErrDecrypt) {
+				t.Errorf("Decrypt of tampered ciphertext: %v, want ErrDecrypt", err)
+			}
+		})
+	}
+	if calls == 0 {
+		t.Error("the envelope encryptor never called Vault")
+	}
+
+	if _, err := pii.NewAESGCM(secret.New("short")); err == nil {
+		t.Error("expected an error for a 5-byte key")
+	}
+}
+
+func TestSealString(t *testing.T) {
+	ctx := context.Background()
+	enc, _ := pii.NewAESGCM(secret.FromBytes(bytes.Repeat([]byte{1}, 16)))
+
+	field, err := pii.SealString(ctx, enc, []byte("did:nda:testnet:0x1"), []byte("jti"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(field, pii.Prefix) {
+		t.Fatalf("field %q lacks the prefix", field)
+	}
+	if _, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(field, pii.Prefix)); err != nil {
+		t.Errorf("field is not base64: %v", err)
+	}
+
+	plaintext, ok, err := pii.OpenString(ctx, enc, field, []byte("jti"))
+	if err != nil || !ok || string(plaintext) != "did:nda:testnet:0x1" {
+		t.Errorf("OpenString = %q, %v, %v", plaintext, ok, err)
+	}
+	if _, ok, err := pii.OpenString(ctx, enc, "did:nda:testnet:0x1", nil); ok || err != nil {
+		t.Errorf("OpenString of a clear field = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// ErrKeyNotPinned is returned when a DID with pinned keys signs with a key that is not pinned.
+var ErrKeyNotPinned = errors.New("signing key is not pinned for DID")
+
+// WithPinnedKeys pins the keys id may sign with, given as RFC 7638 JWK thumbprints (see did.Thumbprint).
+// Proofs by id then verify only when the key resolved from its DID document is pinned, so a compromised
+// registry cannot substitute a key for a high-value issuer. It can be given several times; DIDs without
+// pins are unaffected. Credentials handled by a CredentialParser are left to the parser.
+func WithPinnedKeys(id string, thumbprints ...string) Option {
+	return func(a *Service) {
+		if a.pinnedKeys == nil {
+			a.pinnedKeys = map[string]map[string]bool{}
+		}
+		if a.pinnedKeys[id] == nil {
+			a.pinnedKeys[id] = map[string]bool{}
+		}
+		for _, thumbprint := range thumbprints {
+			a.pinnedKeys[id][thumbprint] = true
+		}
+	}
+}
+
+// checkPinnedKey fails with ErrKeyNotPinned when id has pinned keys and key is not one of them.
+func (a *Service) checkPinnedKey(id string, key crypto.PublicKey) error {
+	pins, ok := a.pinnedKeys[id]
+	if !ok {
+		return nil
+	}
+
+	thumbprint, err := did.Thumbprint(key)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrKeyNotPinned, id, err)
+	}
+	if !pins[thumbprint] {
+		return fmt.Errorf("%w: %s has no pinned key %s", ErrKeyNotPinned, id, thumbprint)
+	}
+	return nil
+}
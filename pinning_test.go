@@ -0,0 +1,61 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/did"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestPinnedKeys(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	other := registry.newIdentity(t)
+	ctx := context.Background()
+
+	issuerPin, err := did.Thumbprint(&issuer.Key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPin, err := did.Thumbprint(&other.Key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := registry.newAuth(authtest.NewSigner(holder)).CreateToken(ctx, []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+
+	pinned := registry.newAuth(nil, auth.WithPinnedKeys(issuer.DID, otherPin, issuerPin))
+	if _, err := pinned.VerifyToken(ctx, token); err != nil {
+		t.Fatalf("VerifyToken with the issuer key pinned: %v", err)
+	}
+
+	mispinned := registry.newAuth(nil, auth.WithPinnedKeys(issuer.DID, otherPin))
+	if _, err := mispinned.VerifyToken(ctx, token); !errors.Is(err, auth.ErrKeyNotPinned) {
+		t.Errorf("VerifyToken with another key pinned = %v, want ErrKeyNotPinned", err)
+	}
+
+	// A compromised registry replaces the issuer's key and re-signs the credential with its own.
+	attacker, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Publish(did.NewSecp256k1Document(issuer.DID, &attacker.PublicKey))
+	forged := registry.issueCredential(t, &authtest.Identity{DID: issuer.DID, Address: issuer.Address, Key: attacker}, holder, map[string]any{"role": "admin"})
+
+	if _, err := pinned.VerifyCredential(ctx, forged); !errors.Is(err, auth.ErrKeyNotPinned) {
+		t.Errorf("VerifyCredential of a credential signed with a substituted key = %v, want ErrKeyNotPinned", err)
+	}
+	if _, err := registry.newAuth(nil).VerifyCredential(ctx, forged); err != nil {
+		t.Errorf("VerifyCredential without pins: %v", err)
+	}
+}
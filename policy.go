@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// ErrPolicyViolation is returned when verified credentials do not satisfy the selected policy.
+var ErrPolicyViolation = errors.New("policy violation")
+
+// Policy is a named bundle of verifier requirements, applied after every credential has been
+// verified. Zero fields impose no requirement.
+type Policy struct {
+	RequiredTypes  []string       // Each type must be declared by at least one credential
+	TrustedIssuers []string       // Every credential must be signed by the key of one of these DIDs
+	MaxAge         time.Duration  // Every credential's validFrom must be at most this old
+	RequiredClaims []string       // Each credentialSubject claim must be present in at least one credential
+	MinAssurance   AssuranceLevel // Every credential must state at least this assurance level
+}
+
+// WithNamedPolicy makes policy selectable under name with WithPolicy, e.g. from shared config at startup.
+// Policies are scoped to the Auth they are configured on.
+func WithNamedPolicy(name string, policy Policy) Option {
+	return func(a *Service) {
+		if a.policies == nil {
+			a.policies = map[string]Policy{}
+		}
+		a.policies[name] = policy
+	}
+}
+
+// WithPolicy enforces the policy configured under name with WithNamedPolicy, e.g. per route or scope.
+// Verification fails if the Auth has no such policy.
+func WithPolicy(name string) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.policy = name
+	}
+}
+
+// check reports the first requirement the credentials fail, wrapped in ErrPolicyViolation.
+func (p Policy) check(credentials []VcClaims, now time.Time) error {
+	for _, credentialType := range p.RequiredTypes {
+		if !anyCredential(credentials, func(c VcClaims) bool { return c.HasType(credentialType) }) {
+			return fmt.Errorf("%w: no credential of type %s", ErrPolicyViolation, credentialType)
+		}
+	}
+
+	for _, claim := range p.RequiredClaims {
+		if !anyCredential(credentials, func(c VcClaims) bool { return hasSubjectClaim(c, claim) }) {
+			return fmt.Errorf("%w: claim %s not presented", ErrPolicyViolation, claim)
+		}
+	}
+
+	for _, c := range credentials {
+		if signer := signerDID(c); len(p.TrustedIssuers) > 0 && !containsString(p.TrustedIssuers, signer) {
+			return fmt.Errorf("%w: issuer %s is not trusted", ErrPolicyViolation, signer)
+		}
+
+		if c.AssuranceLevel < p.MinAssurance {
+			return fmt.Errorf("%w: %w: credential %s is at %s, %s required", ErrPolicyViolation, ErrInsufficientAssurance, c.ID, c.AssuranceLevel, p.MinAssurance)
+		}
+
+		if p.MaxAge > 0 && (c.ValidFrom.IsZero() || now.Sub(c.ValidFrom) > p.MaxAge) {
+			return fmt.Errorf("%w: credential %s is older than %s", ErrPolicyViolation, c.ID, p.MaxAge)
+		}
+	}
+
+	return nil
+}
+
+// signerDID returns the DID whose key verified the credential: the DID of its proof's verification
+// method, or else its issuer, which verification has already bound to the signing key.
+func signerDID(c VcClaims) string {
+	if c.Proof != nil && c.Proof.VerificationMethod != "" {
+		signer, _ := did.SplitDIDURL(c.Proof.VerificationMethod)
+		return signer
+	}
+	return c.Issuer
+}
+
+// anyCredential reports whether match holds for at least one credential.
+func anyCredential(credentials []VcClaims, match func(VcClaims) bool) bool {
+	for _, c := range credentials {
+		if match(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSubjectClaim reports whether any subject of the credential carries the claim.
+func hasSubjectClaim(c VcClaims, claim string) bool {
+	for _, subject := range c.CredentialSubject {
+		if _, ok := subject.Get(claim); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPolicy checks credentials against the policy selected with WithPolicy, if any.
+func (a *Service) applyPolicy(credentials []VcClaims, options *verifyOptions) error {
+	if options.policy == "" {
+		return nil
+	}
+
+	policy, ok := a.policies[options.policy]
+	if !ok {
+		return fmt.Errorf("unknown policy %q", options.policy)
+	}
+	return policy.check(credentials, a.clock.Now())
+}
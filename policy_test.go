@@ -0,0 +1,85 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestVerifyTokenWithPolicy(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(holder),
+		auth.WithNamedPolicy("test-payments", auth.Policy{
+			RequiredTypes:  []string{"VerifiableCredential"},
+			TrustedIssuers: []string{issuer.DID},
+			MaxAge:         time.Hour,
+			RequiredClaims: []string{"role"},
+		}),
+		auth.WithNamedPolicy("test-admin", auth.Policy{RequiredClaims: []string{"clearance"}}),
+		auth.WithNamedPolicy("test-other-issuer", auth.Policy{TrustedIssuers: []string{holder.DID}}),
+		auth.WithNamedPolicy("test-fresh", auth.Policy{MaxAge: time.Second}),
+	)
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithPolicy("test-payments")); err != nil {
+		t.Errorf("expected the payments policy to pass, got %v", err)
+	}
+
+	for _, name := range []string{"test-admin", "test-other-issuer", "test-fresh"} {
+		if _, err := a.VerifyToken(context.Background(), token, auth.WithPolicy(name)); !errors.Is(err, auth.ErrPolicyViolation) {
+			t.Errorf("%s: expected ErrPolicyViolation, got %v", name, err)
+		}
+	}
+
+	if _, err := a.VerifyCredential(context.Background(), vcJwt, auth.WithPolicy("test-admin")); !errors.Is(err, auth.ErrPolicyViolation) {
+		t.Errorf("expected VerifyCredential to enforce the policy, got %v", err)
+	}
+
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithPolicy("unregistered")); err == nil {
+		t.Error("expected an unknown policy to fail verification")
+	}
+
+	other := registry.newAuth(authtest.NewSigner(holder))
+	if _, err := other.VerifyToken(context.Background(), token, auth.WithPolicy("test-payments")); err == nil {
+		t.Error("expected policies to be scoped to the Auth they are configured on")
+	}
+}
+
+func TestVerifyCredentialIssuerMismatch(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	attacker := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(attacker),
+		auth.WithNamedPolicy("trusted", auth.Policy{TrustedIssuers: []string{issuer.DID}}))
+
+	// The attacker signs with their own key but names the trusted issuer in the payload.
+	parts := strings.Split(registry.issueCredential(t, attacker, attacker, map[string]any{"role": "admin"}), ".")
+	var payload map[string]any
+	payloadJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	payload["iss"] = issuer.DID
+	payload["vc"].(map[string]any)["issuer"] = issuer.DID
+	payloadJSON, _ = json.Marshal(payload)
+	forged := signJWT(t, parts[0]+"."+base64.RawURLEncoding.EncodeToString(payloadJSON), attacker.Key)
+
+	if _, err := a.VerifyCredential(context.Background(), forged, auth.WithPolicy("trusted")); !errors.Is(err, auth.ErrIssuerMismatch) {
+		t.Errorf("expected ErrIssuerMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// Proof-of-possession JWT parameters, following the DPoP profile (RFC 9449).
+const (
+	ProofHeader     = "DPoP"     // HTTP header carrying the proof JWT
+	proofTokenType  = "dpop+jwt" // "typ" header of proof JWTs
+	proofMaxAge     = 5 * time.Minute
+	proofClockSkew  = 30 * time.Second
+	proofAuthScheme = "DPoP "
+)
+
+// ErrInvalidProof is returned when a proof-of-possession JWT is missing, malformed or does not
+// match the request it accompanies.
+var ErrInvalidProof = errors.New("invalid proof of possession")
+
+// ProofRequest describes the HTTP request a proof-of-possession JWT is bound to.
+type ProofRequest struct {
+	Method      string // HTTP method, e.g. "GET"
+	URL         string // Target URL; query and fragment are ignored
+	AccessToken string // Optional token the proof is bound to through the "ath" claim, e.g. the VP token
+}
+
+// CreateProof creates a DPoP-style proof JWT, signed with the holder key through the provider, binding
+// one API request to the holder of a verified presentation. opts are handled as in CreateToken.
+func (a *Service) CreateProof(ctx context.Context, holderDid string, req ProofRequest, opts ...any) (string, error) {
+	options, providerOpts, err := splitCreateOpts(opts)
+	if err != nil {
+		return "", err
+	}
+
+	if a.provider == nil {
+		return "", ErrNilProvider
+	}
+
+	if _, err := did.Parse(holderDid); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidHolderDID, err)
+	}
+
+	htu, err := proofTargetURI(req.URL)
+	if err != nil {
+		return "", err
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("failed to generate jti: %w", err)
+	}
+
+	header := map[string]any{
+		"typ": proofTokenType,
+		"alg": "ES256K",
+		"kid": fmt.Sprintf("%s#%s", holderDid, options.verificationMethodKey),
+	}
+	payload := map[string]any{
+		"jti": hex.EncodeToString(jti),
+		"htm": strings.ToUpper(req.Method),
+		"htu": htu,
+		"iat": a.clock.Now().Unix(),
+	}
+	if req.AccessToken != "" {
+		payload["ath"] = accessTokenHash(req.AccessToken)
+	}
+
+	signingInput, err := encodeSigningInput(header, payload)
+	if err != nil {
+		return "", err
+	}
+
+	return a.signJWT(ctx, signingInput, providerOpts...)
+}
+
+// VerifyProof verifies a proof JWT created by CreateProof: it must be signed by a key of holderDid,
+// be recent, and match the method, URL and access token of req. It returns the proof's jti so
+// callers can reject replays; ProofMiddleware does this itself.
+func (a *Service) VerifyProof(ctx context.Context, proof, holderDid string, req ProofRequest) (_ string, err error) {
+	defer recoverPanic(&err)
+
+	token, err := parseJWT(proof)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+
+	if typ := stringField(token.header, "typ"); typ != proofTokenType {
+		return "", fmt.Errorf("%w: unexpected typ %q", ErrInvalidProof, typ)
+	}
+
+	if kidDid, _ := did.SplitDIDURL(stringField(token.header, "kid")); kidDid != holderDid {
+		return "", fmt.Errorf("%w: proof is not signed by holder %s", ErrInvalidProof, holderDid)
+	}
+
+	if err := a.verifyJWT(ctx, token); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+
+	if htm := stringField(token.payload, "htm"); !strings.EqualFold(htm, req.Method) {
+		return "", fmt.Errorf("%w: proof is for method %q", ErrInvalidProof, htm)
+	}
+
+	htu, err := proofTargetURI(req.URL)
+	if err != nil {
+		return "", err
+	}
+	if proofHtu := stringField(token.payload, "htu"); proofHtu != htu {
+		return "", fmt.Errorf("%w: proof is for URL %q", ErrInvalidProof, proofHtu)
+	}
+
+	iat, ok := token.payload["iat"].(float64)
+	if !ok {
+		return "", fmt.Errorf("%w: iat claim is missing", ErrInvalidProof)
+	}
+	issuedAt := time.Unix(int64(iat), 0)
+	if now := a.clock.Now(); issuedAt.After(now.Add(proofClockSkew)) || now.Sub(issuedAt) > proofMaxAge {
+		return "", fmt.Errorf("%w: proof issued at %s is outside the accepted window", ErrInvalidProof, issuedAt.UTC().Format(time.RFC3339))
+	}
+
+	if req.AccessToken != "" && stringField(token.payload, "ath") != accessTokenHash(req.AccessToken) {
+		return "", fmt.Errorf("%w: proof is not bound to the access token", ErrInvalidProof)
+	}
+
+	jti := stringField(token.payload, "jti")
+	if jti == "" {
+		return "", fmt.Errorf("%w: jti claim is missing", ErrInvalidProof)
+	}
+
+	return jti, nil
+}
+
+// HolderFunc returns the holder DID an incoming request was authenticated as, e.g. from the session
+// established after VerifyToken.
+type HolderFunc func(r *http.Request) (string, error)
+
+// ProofMiddleware rejects requests that do not carry a valid proof-of-possession JWT, in the DPoP
+// header, signed by the holder returned by holderOf. When the request has an "Authorization: DPoP"
+// header its token must be bound to the proof. Proofs are single use: a replayed jti is rejected.
+// The request URL is rebuilt from r.Host and r.URL.Path, with https when r.TLS is set.
+func ProofMiddleware(a *Service, holderOf HolderFunc) func(http.Handler) http.Handler {
+	replay := &jtiCache{seen: map[string]time.Time{}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			holderDid, err := holderOf(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			proof := r.Header.Get(ProofHeader)
+			if proof == "" {
+				http.Error(w, "missing DPoP proof", http.StatusUnauthorized)
+				return
+			}
+
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+
+			req := ProofRequest{
+				Method: r.Method,
+				URL:    scheme + "://" + r.Host + r.URL.Path,
+			}
+			if authorization := r.Header.Get("Authorization"); strings.HasPrefix(authorization, proofAuthScheme) {
+				req.AccessToken = strings.TrimPrefix(authorization, proofAuthScheme)
+			}
+
+			jti, err := a.VerifyProof(r.Context(), proof, holderDid, req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			if !replay.add(holderDid+"|"+jti, a.clock.Now()) {
+				http.Error(w, "DPoP proof has already been used", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// jtiCache remembers proof ids for the proof lifetime to detect replays.
+type jtiCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// add records jti and reports whether it was unseen.
+func (c *jtiCache) add(jti string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, seenAt := range c.seen {
+		if now.Sub(seenAt) > proofMaxAge+proofClockSkew {
+			delete(c.seen, id)
+		}
+	}
+
+	if _, ok := c.seen[jti]; ok {
+		return false
+	}
+	c.seen[jti] = now
+	return true
+}
+
+// proofTargetURI normalizes a URL to the htu form: no query or fragment.
+func proofTargetURI(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%w: invalid target URL %q", ErrInvalidProof, rawURL)
+	}
+
+	return u.Scheme + "://" + strings.ToLower(u.Host) + u.EscapedPath(), nil
+}
+
+// accessTokenHash is the "ath" value binding a proof to an access token.
+func accessTokenHash(token string) string {
+	hash := sha256.Sum256([]byte(strings.Trim(token, "\"")))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
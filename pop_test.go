@@ -0,0 +1,88 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestProofOfPossession(t *testing.T) {
+	registry := newTestRegistry(t)
+	holder := registry.newIdentity(t)
+	other := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(holder, other))
+	ctx := context.Background()
+
+	req := auth.ProofRequest{Method: "POST", URL: "https://api.example.com/orders?page=2", AccessToken: "vp-token"}
+	proof, err := a.CreateProof(ctx, holder.DID, req, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateProof failed: %v", err)
+	}
+
+	if _, err := a.VerifyProof(ctx, proof, holder.DID, auth.ProofRequest{Method: "POST", URL: "https://api.example.com/orders", AccessToken: "vp-token"}); err != nil {
+		t.Fatalf("VerifyProof failed: %v", err)
+	}
+
+	mismatches := map[string]struct {
+		holderDid string
+		req       auth.ProofRequest
+	}{
+		"other holder": {other.DID, req},
+		"other method": {holder.DID, auth.ProofRequest{Method: "GET", URL: req.URL, AccessToken: req.AccessToken}},
+		"other URL":    {holder.DID, auth.ProofRequest{Method: req.Method, URL: "https://api.example.com/admin", AccessToken: req.AccessToken}},
+		"other token":  {holder.DID, auth.ProofRequest{Method: req.Method, URL: req.URL, AccessToken: "stolen"}},
+	}
+	for name, tt := range mismatches {
+		if _, err := a.VerifyProof(ctx, proof, tt.holderDid, tt.req); !errors.Is(err, auth.ErrInvalidProof) {
+			t.Errorf("%s: expected ErrInvalidProof, got %v", name, err)
+		}
+	}
+}
+
+func TestProofMiddleware(t *testing.T) {
+	registry := newTestRegistry(t)
+	holder := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+	handler := auth.ProofMiddleware(a, func(r *http.Request) (string, error) {
+		return holder.DID, nil
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	proof, err := a.CreateProof(context.Background(), holder.DID, auth.ProofRequest{
+		Method:      "GET",
+		URL:         "http://api.example.com/me",
+		AccessToken: "vp-token",
+	}, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateProof failed: %v", err)
+	}
+
+	send := func(proof string) int {
+		r := httptest.NewRequest(http.MethodGet, "http://api.example.com/me", nil)
+		r.Header.Set("Authorization", "DPoP vp-token")
+		if proof != "" {
+			r.Header.Set(auth.ProofHeader, proof)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	if code := send(proof); code != http.StatusNoContent {
+		t.Fatalf("expected request with proof to pass, got %d", code)
+	}
+	if code := send(proof); code != http.StatusUnauthorized {
+		t.Errorf("expected replayed proof to be rejected, got %d", code)
+	}
+	if code := send(""); code != http.StatusUnauthorized {
+		t.Errorf("expected request without proof to be rejected, got %d", code)
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// defaultVerificationMethodKey is the fragment used to build the kid of the VP JWT header.
+const defaultVerificationMethodKey = "key-1"
+
+// presentationContexts are the JSON-LD contexts set on VPs created without a profile, see WithProfile.
+var presentationContexts = []any{
+	"https://www.w3.org/ns/credentials/v2",
+	"https://www.w3.org/ns/credentials/examples/v2",
+}
+
+// buildPresentationSigningInput builds the unsigned "header.payload" part of a VP JWT.
+// Header fields are taken from options.
+//
+// The presentation is assembled here rather than with vp.NewJWTPresentation because the SDK
+// re-verifies every embedded credential against its process-global DID registry while serializing.
+func buildPresentationSigningInput(holderDid string, vcsJwt []string, options *createOptions) (string, error) {
+	header := make(map[string]any, len(options.headers)+3)
+	for name, value := range options.headers {
+		header[name] = value
+	}
+	header["typ"] = options.tokenType
+	header["alg"] = cmp.Or(options.algorithm, did.AlgES256K)
+	header["kid"] = fmt.Sprintf("%s#%s", holderDid, options.verificationMethodKey)
+
+	contexts := options.contexts
+	if len(contexts) == 0 {
+		contexts = presentationContexts
+	}
+	vpData := map[string]any{
+		"@context": contexts,
+		"type":     "VerifiablePresentation",
+		"holder":   holderDid,
+	}
+	if len(vcsJwt) > 0 {
+		credentials := make([]any, len(vcsJwt))
+		for i, vcJwt := range vcsJwt {
+			credentials[i] = vcJwt
+		}
+		vpData["verifiableCredential"] = credentials
+	}
+
+	payload := map[string]any{
+		"iss": holderDid,
+		"sub": holderDid,
+		"vp":  vpData,
+	}
+	if options.nonce != "" {
+		payload["nonce"] = options.nonce
+	}
+	if options.audience != "" {
+		payload["aud"] = options.audience
+	}
+	if options.certificateThumbprint != "" {
+		payload["cnf"] = map[string]any{certificateThumbprintClaim: options.certificateThumbprint}
+	}
+	if options.tokenID != "" {
+		payload["jti"] = options.tokenID
+	}
+	if !options.issuedAt.IsZero() {
+		payload["iat"] = options.issuedAt.Unix()
+	}
+	if !options.expiresAt.IsZero() {
+		payload["exp"] = options.expiresAt.Unix()
+	}
+
+	return encodeSigningInput(header, payload)
+}
+
+// checkPresentationBinding checks the validity period, nonce and audience of a VP against the verifier's
+// expectations.
+func checkPresentationBinding(vpToken *jwtToken, options *verifyOptions, now time.Time) error {
+	notBefore, err := numericDateClaim(vpToken.payload, "nbf")
+	if err != nil {
+		return err
+	}
+	if !notBefore.IsZero() && now.Before(notBefore) {
+		return errors.New("presentation is not yet valid")
+	}
+	expiresAt, err := numericDateClaim(vpToken.payload, "exp")
+	if err != nil {
+		return err
+	}
+	if !expiresAt.IsZero() && now.After(expiresAt) {
+		return errors.New("presentation has expired")
+	}
+
+	if options.nonce != "" && stringField(vpToken.payload, "nonce") != options.nonce {
+		return errors.New("presentation nonce does not match")
+	}
+
+	if options.audience != "" {
+		audiences := stringList(vpToken.payload["aud"])
+		if !containsString(audiences, options.audience) {
+			return errors.New("presentation audience does not match")
+		}
+	}
+
+	if options.certificateThumbprint != "" {
+		if err := checkChannelBinding(vpToken, options.certificateThumbprint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// numericDateClaim returns the NumericDate claim name of a JWT payload, zero when absent. A claim that is
+// present but not a number is an error rather than ignored, so it cannot switch the check off.
+func numericDateClaim(payload map[string]any, name string) (time.Time, error) {
+	value, ok := payload[name]
+	if !ok {
+		return time.Time{}, nil
+	}
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("presentation %q claim is not a number", name)
+	}
+	return time.Unix(int64(seconds), 0), nil
+}
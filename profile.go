@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/ethaddr"
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+const (
+	// EnvProfile names the environment variable selecting the profile returned by ProfileFromEnv.
+	EnvProfile = "VC_AUTH_PROFILE"
+	// EnvRegistryURL names the environment variable overriding the registry URL of that profile.
+	EnvRegistryURL = "VC_AUTH_REGISTRY_URL"
+)
+
+// Profile groups the settings that differ between did:nda networks, so code selects a network once
+// instead of hard-coding "did:nda:testnet:" and registry URLs.
+type Profile struct {
+	Name        string // e.g. "testnet"
+	DIDPrefix   string // Prefix of the DIDs of the network, e.g. "did:nda:testnet:"
+	RegistryURL string // DID registry used when NewAuth is given no DID URL; empty when not public
+	Contexts    []any  // JSON-LD contexts of created presentations
+}
+
+// Predefined profiles. Only the testnet registry is public: the others need the registry URL of the
+// deployment, set with WithRegistryURL or VC_AUTH_REGISTRY_URL.
+var (
+	ProfileTestnet = Profile{
+		Name:        "testnet",
+		DIDPrefix:   "did:nda:testnet:",
+		RegistryURL: "https://auth-dev.pila.vn/api/v1/did",
+		Contexts:    presentationContexts,
+	}
+	ProfileStaging = Profile{
+		Name:      "staging",
+		DIDPrefix: "did:nda:staging:",
+		Contexts:  []any{"https://www.w3.org/ns/credentials/v2"},
+	}
+	ProfileMainnet = Profile{
+		Name:      "mainnet",
+		DIDPrefix: "did:nda:mainnet:",
+		Contexts:  []any{"https://www.w3.org/ns/credentials/v2"},
+	}
+)
+
+// profiles are the predefined profiles, by name.
+var profiles = map[string]Profile{
+	ProfileTestnet.Name: ProfileTestnet,
+	ProfileStaging.Name: ProfileStaging,
+	ProfileMainnet.Name: ProfileMainnet,
+}
+
+// LookupProfile returns the predefined profile named name ("testnet", "staging" or "mainnet", in any case).
+func LookupProfile(name string) (Profile, error) {
+	profile, ok := profiles[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return profile, nil
+}
+
+// ProfileFromEnv returns the profile named by VC_AUTH_PROFILE, testnet when it is unset, with the
+// registry URL of VC_AUTH_REGISTRY_URL when set. It fails when the profile has no registry URL.
+func ProfileFromEnv() (Profile, error) {
+	profile := ProfileTestnet
+	if name := os.Getenv(EnvProfile); name != "" {
+		var err error
+		if profile, err = LookupProfile(name); err != nil {
+			return Profile{}, fmt.Errorf("%s: %w", EnvProfile, err)
+		}
+	}
+	if registryURL := os.Getenv(EnvRegistryURL); registryURL != "" {
+		profile = profile.WithRegistryURL(registryURL)
+	}
+	if profile.RegistryURL == "" {
+		return Profile{}, fmt.Errorf("profile %s has no public registry: set %s", profile.Name, EnvRegistryURL)
+	}
+	return profile, nil
+}
+
+// WithRegistryURL returns a copy of the profile using the DID registry at registryURL.
+func (p Profile) WithRegistryURL(registryURL string) Profile {
+	p.RegistryURL = registryURL
+	return p
+}
+
+// DID returns the DID of address on the profile's network, e.g. "did:nda:testnet:0x…". The address must
+// be valid; it is written in its lowercase canonical form.
+func (p Profile) DID(address string) (string, error) {
+	normalized, err := ethaddr.Normalize(address)
+	if err != nil {
+		return "", err
+	}
+	return p.DIDPrefix + normalized, nil
+}
+
+// Owns reports whether id is a DID of the profile's network.
+func (p Profile) Owns(id string) bool {
+	return p.DIDPrefix != "" && strings.HasPrefix(id, p.DIDPrefix)
+}
+
+// WithProfile configures the Auth for a network: DIDs resolve against the profile's registry when NewAuth
+// is given no DID URL, presentations get its contexts, and CreateToken rejects
+// holder DIDs of other networks with ErrInvalidHolderDID.
+func WithProfile(profile Profile) Option {
+	return func(a *Service) {
+		a.profile = &profile
+	}
+}
+
+// Profile returns the profile configured with WithProfile, if any.
+func (a *Service) Profile() (Profile, bool) {
+	if a.profile == nil {
+		return Profile{}, false
+	}
+	return *a.profile, true
+}
+
+// ProviderKeyRef selects a key of a provider: Opts are provider options as passed to CreateToken, usually the
+// signer address. They may be empty for a provider holding a single key, such as provider.LocalProvider.
+type ProviderKeyRef struct {
+	Provider provider.Provider
+	Opts     []any
+}
+
+// DeriveHolderDID asks the provider for the public key of ref, derives its address and returns its DID on
+// the network of profile, instead of assembling holder DIDs by hand. The key is read from providers
+// implementing provider.PublicKeyExporter; for other providers, Opts must give the signer address,
+// which is checked against a signature of the key.
+func DeriveHolderDID(ctx context.Context, ref ProviderKeyRef, profile Profile) (string, error) {
+	if ref.Provider == nil {
+		return "", ErrNilProvider
+	}
+
+	options, err := provider.NewProviderOption(ref.Opts...)
+	if err != nil {
+		return "", err
+	}
+
+	var publicKey *ecdsa.PublicKey
+	if exporter, ok := ref.Provider.(provider.PublicKeyExporter); ok {
+		publicKey, err = exporter.PublicKey(ctx, options)
+	} else {
+		if options.Signer == "" {
+			return "", errors.New("the key of a provider that cannot export public keys must be selected by signer address")
+		}
+		publicKey, err = providerPublicKey(ctx, ref.Provider, ref.Provider.Sign, options.Signer)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get the holder public key: %w", err)
+	}
+
+	return profile.DID(crypto.PubkeyToAddress(*publicKey).Hex())
+}
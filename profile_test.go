@@ -0,0 +1,97 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+func TestProfile(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+
+	if id, err := auth.ProfileTestnet.DID(strings.ToUpper(holder.Address[2:])); err != nil || id != holder.DID {
+		t.Errorf("DID = %q, %v, want %s", id, err, holder.DID)
+	}
+
+	registryURL := registry.serve(t)
+	staging := auth.ProfileStaging.WithRegistryURL(registryURL)
+	staging.DIDPrefix = auth.ProfileTestnet.DIDPrefix // The test registry publishes testnet DIDs
+	a := auth.NewAuth(authtest.NewSigner(holder), "", auth.WithProfile(staging), auth.WithSchemaSource(authtest.Schemas()))
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	payloadJSON, _ := base64.RawURLEncoding.DecodeString(strings.Split(strings.Trim(token, `"`), ".")[1])
+	var payload struct {
+		VP struct {
+			Context []any `json:"@context"`
+		} `json:"vp"`
+	}
+	if err := json.Unmarshal(payloadJSON, &payload); err != nil || !reflect.DeepEqual(payload.VP.Context, staging.Contexts) {
+		t.Errorf("unexpected presentation contexts: %s", payloadJSON)
+	}
+	if _, err := a.VerifyToken(context.Background(), token); err != nil {
+		t.Errorf("VerifyToken failed: %v", err)
+	}
+
+	mainnet := auth.NewAuth(authtest.NewSigner(holder), "", auth.WithProfile(auth.ProfileMainnet.WithRegistryURL(registryURL)))
+	if _, err := mainnet.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address); !errors.Is(err, auth.ErrInvalidHolderDID) {
+		t.Errorf("expected a testnet holder to be rejected on mainnet, got %v", err)
+	}
+}
+
+func TestProfileFromEnv(t *testing.T) {
+	t.Setenv(auth.EnvProfile, "")
+	t.Setenv(auth.EnvRegistryURL, "")
+	if profile, err := auth.ProfileFromEnv(); err != nil || profile.Name != "testnet" {
+		t.Errorf("ProfileFromEnv = %+v, %v, want testnet by default", profile, err)
+	}
+
+	t.Setenv(auth.EnvProfile, "Mainnet")
+	if _, err := auth.ProfileFromEnv(); err == nil {
+		t.Error("expected mainnet without a registry URL to be rejected")
+	}
+	t.Setenv(auth.EnvRegistryURL, "https://registry.example/did")
+	if profile, err := auth.ProfileFromEnv(); err != nil || profile.DIDPrefix != "did:nda:mainnet:" || profile.RegistryURL != "https://registry.example/did" {
+		t.Errorf("ProfileFromEnv = %+v, %v", profile, err)
+	}
+
+	t.Setenv(auth.EnvProfile, "devnet")
+	if _, err := auth.ProfileFromEnv(); err == nil {
+		t.Error("expected an unknown profile to be rejected")
+	}
+}
+
+func TestDeriveHolderDID(t *testing.T) {
+	registry := newTestRegistry(t)
+	holder := registry.newIdentity(t)
+
+	local, err := provider.NewLocalProvider(holder.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := auth.DeriveHolderDID(context.Background(), auth.ProviderKeyRef{Provider: local}, auth.ProfileTestnet); err != nil || id != holder.DID {
+		t.Errorf("DeriveHolderDID = %q, %v, want %s", id, err, holder.DID)
+	}
+
+	// With the signer's key export hidden, the address is checked against a signature.
+	signer := struct{ provider.Provider }{authtest.NewSigner(holder)}
+	ref := auth.ProviderKeyRef{Provider: signer, Opts: []any{holder.Address}}
+	if id, err := auth.DeriveHolderDID(context.Background(), ref, auth.ProfileMainnet); err != nil || id != "did:nda:mainnet:"+holder.Address {
+		t.Errorf("DeriveHolderDID = %q, %v", id, err)
+	}
+	if _, err := auth.DeriveHolderDID(context.Background(), auth.ProviderKeyRef{Provider: signer}, auth.ProfileTestnet); err == nil {
+		t.Error("expected a key without signer address to be rejected")
+	}
+}
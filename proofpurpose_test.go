@@ -0,0 +1,67 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+func TestVerifyTokenProofPurposes(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+
+	setRelationships := func(id string, authentication, assertion []string) {
+		registry.update(t, id, func(doc *did.Document) {
+			doc.Authentication = authentication
+			doc.AssertionMethod = assertion
+		})
+	}
+
+	claims, err := a.VerifyToken(context.Background(), token, auth.WithProofPurposes())
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	if got := claims[0].Proof.Purpose; got != auth.ProofPurposeAssertionMethod {
+		t.Errorf("proof purpose = %q, want %q", got, auth.ProofPurposeAssertionMethod)
+	}
+
+	// Relative references are resolved against the document ID.
+	setRelationships(holder.DID, []string{"#key-1"}, nil)
+	setRelationships(issuer.DID, nil, []string{"#key-1"})
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithProofPurposes()); err != nil {
+		t.Errorf("relative references: %v", err)
+	}
+
+	tests := []struct {
+		name                   string
+		holderAuth, issuerAsrt []string
+	}{
+		{"holder key only for assertions", nil, []string{issuer.DID + "#key-1"}},
+		{"issuer key only for authentication", []string{holder.DID + "#key-1"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRelationships(holder.DID, tt.holderAuth, []string{holder.DID + "#key-1"})
+			setRelationships(issuer.DID, []string{issuer.DID + "#key-1"}, tt.issuerAsrt)
+
+			if _, err := a.VerifyToken(context.Background(), token, auth.WithProofPurposes()); !errors.Is(err, auth.ErrProofPurpose) {
+				t.Errorf("got %v, want ErrProofPurpose", err)
+			}
+			if _, err := a.VerifyToken(context.Background(), token); err != nil {
+				t.Errorf("without WithProofPurposes: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// ProofSigner adds a proof to a presentation next to the one made by the Auth provider, e.g. an EdDSA
+// signature for verifiers that no longer accept ES256K. Its key must belong to the holder DID.
+type ProofSigner interface {
+	// Algorithm returns the JWS "alg" of the proof, e.g. did.AlgEdDSA.
+	Algorithm() string
+	// KeyID returns the DID URL of the verification method, the "kid" of the proof.
+	KeyID() string
+	// Sign signs the JWS signing input as required by Algorithm, hashing it first where the algorithm does.
+	Sign(ctx context.Context, signingInput []byte) ([]byte, error)
+}
+
+// WithAdditionalProof makes CreateToken sign the presentation a second time with signer. It can be given
+// several times; each adds one signature. Presentations with several proofs use the general JWS JSON
+// serialization, so OutputJWSJSON must be selected.
+func WithAdditionalProof(signer ProofSigner) CreateOpt {
+	return func(o *createOptions) {
+		o.additionalProofs = append(o.additionalProofs, signer)
+	}
+}
+
+// WithAllProofs makes VerifyToken require every proof of a presentation with several proofs to verify.
+// By default one verifying proof is enough, so verifiers can ignore algorithms they do not accept.
+func WithAllProofs() VerifyOpt {
+	return func(o *verifyOptions) {
+		o.allProofs = true
+	}
+}
+
+// signAdditionalProofs signs the payload of the compact JWS document with every additional proof signer
+// and returns the general JWS JSON serialization carrying all signatures.
+func signAdditionalProofs(ctx context.Context, document string, signers []ProofSigner) (string, error) {
+	parts := strings.Split(document, ".")
+	if len(parts) != 3 {
+		return "", errors.New("invalid JWT format")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid header: %w", err)
+	}
+	holderDid, _ := did.SplitDIDURL(headerKid(headerJSON))
+
+	out := jwsJSON{Payload: parts[1], Signatures: []jwsSignature{{Protected: parts[0], Signature: parts[2]}}}
+	for _, signer := range signers {
+		if kidDid, _ := did.SplitDIDURL(signer.KeyID()); kidDid != holderDid {
+			return "", fmt.Errorf("additional proof key %s does not belong to holder %s", signer.KeyID(), holderDid)
+		}
+
+		var header map[string]any
+		if err := json.Unmarshal(headerJSON, &header); err != nil {
+			return "", fmt.Errorf("invalid header: %w", err)
+		}
+		header["alg"], header["kid"] = signer.Algorithm(), signer.KeyID()
+		protectedJSON, err := json.Marshal(header)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal header: %w", err)
+		}
+		protected := base64.RawURLEncoding.EncodeToString(protectedJSON)
+
+		signature, err := signer.Sign(ctx, []byte(protected+"."+parts[1]))
+		if err != nil {
+			return "", fmt.Errorf("failed to sign additional proof %s: %w", signer.KeyID(), err)
+		}
+		out.Signatures = append(out.Signatures, jwsSignature{
+			Protected: protected,
+			Signature: base64.RawURLEncoding.EncodeToString(signature),
+		})
+	}
+
+	encoded, err := json.Marshal(out)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// headerKid returns the "kid" of a JSON JOSE header, or "".
+func headerKid(headerJSON []byte) string {
+	var header map[string]any
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return ""
+	}
+	return stringField(header, "kid")
+}
+
+// verifyPresentationProofs verifies the proofs of a presentation, one compact JWS per proof, and
+// returns the first verified one with its key. With several proofs, each is traced as "proof[i]"
+// and must be signed by the presentation's issuer; one verified proof is enough unless WithAllProofs
+// is given.
+func (a *Service) verifyPresentationProofs(ctx context.Context, compacts []string, options *verifyOptions) (*jwtToken, crypto.PublicKey, error) {
+	if len(compacts) == 1 {
+		vpToken, err := parseJWT(compacts[0])
+		traceStep(ctx, StepDecode, err)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		holderKey, err := a.verifyJWTKey(ctx, vpToken, options.algorithms, options.purpose(ProofPurposeAuthentication))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to verify presentation: %w", err)
+		}
+		return vpToken, holderKey, nil
+	}
+
+	var verified *jwtToken
+	var verifiedKey crypto.PublicKey
+	var errs []error
+	for i, compact := range compacts {
+		proofCtx := withTraceTarget(ctx, options.trace, fmt.Sprintf("proof[%d]", i))
+		vpToken, key, err := a.verifyPresentationProof(proofCtx, compact, options)
+		if err != nil {
+			if options.allProofs {
+				return nil, nil, fmt.Errorf("failed to verify presentation proof %d: %w", i, err)
+			}
+			errs = append(errs, fmt.Errorf("proof %d: %w", i, err))
+			continue
+		}
+		if verified == nil {
+			verified, verifiedKey = vpToken, key
+		}
+	}
+
+	if verified == nil {
+		return nil, nil, fmt.Errorf("failed to verify presentation: no proof verified: %w", errors.Join(errs...))
+	}
+	return verified, verifiedKey, nil
+}
+
+// verifyPresentationProof verifies one proof of a presentation with several proofs.
+func (a *Service) verifyPresentationProof(ctx context.Context, compact string, options *verifyOptions) (*jwtToken, crypto.PublicKey, error) {
+	vpToken, err := parseJWT(compact)
+	traceStep(ctx, StepDecode, err)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Every proof must come from the holder, so one signer cannot stand in for another.
+	if kidDid, _ := did.SplitDIDURL(stringField(vpToken.header, "kid")); kidDid != stringField(vpToken.payload, "iss") {
+		err := fmt.Errorf("%w: proof key %s is not the holder's", ErrIssuerMismatch, stringField(vpToken.header, "kid"))
+		traceStep(ctx, StepKeySelection, err)
+		return nil, nil, err
+	}
+
+	key, err := a.verifyJWTKey(ctx, vpToken, options.algorithms, options.purpose(ProofPurposeAuthentication))
+	if err != nil {
+		return nil, nil, err
+	}
+	return vpToken, key, nil
+}
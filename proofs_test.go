@@ -0,0 +1,137 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// edProofSigner signs additional proofs with an Ed25519 key.
+type edProofSigner struct {
+	kid string
+	key ed25519.PrivateKey
+}
+
+func (s *edProofSigner) Algorithm() string { return did.AlgEdDSA }
+func (s *edProofSigner) KeyID() string     { return s.kid }
+func (s *edProofSigner) Sign(_ context.Context, signingInput []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, signingInput), nil
+}
+
+func TestMultipleProofs(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	stranger := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edSigner := &edProofSigner{kid: registry.publishMethod(t, holder.DID, "ed25519", "Ed25519VerificationKey2020", edPublic), key: edPrivate}
+	strangerPublic, strangerPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	strangerSigner := &edProofSigner{kid: registry.publishMethod(t, stranger.DID, "ed25519", "Ed25519VerificationKey2020", strangerPublic), key: strangerPrivate}
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	ctx := context.Background()
+	token, err := a.CreateToken(ctx, []string{vcJwt}, holder.DID, holder.Address,
+		auth.WithAdditionalProof(edSigner), auth.WithOutputFormat(auth.OutputJWSJSON))
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+
+	var jws struct {
+		Signatures []json.RawMessage `json:"signatures"`
+	}
+	if err := json.Unmarshal([]byte(token), &jws); err != nil || len(jws.Signatures) != 2 {
+		t.Fatalf("expected a general JWS JSON serialization with 2 signatures, got %s", token)
+	}
+
+	// The same presentation with a broken ES256K signature, so only the EdDSA proof holds.
+	var broken map[string]any
+	_ = json.Unmarshal([]byte(token), &broken)
+	broken["signatures"].([]any)[0].(map[string]any)["signature"] = base64.RawURLEncoding.EncodeToString(make([]byte, 64))
+	brokenJSON, _ := json.Marshal(broken)
+
+	all := auth.WithAllowedAlgorithms(did.AlgES256K, did.AlgEdDSA)
+	tests := []struct {
+		name    string
+		token   string
+		opts    []auth.VerifyOpt
+		wantErr bool
+	}{
+		{"legacy verifier uses the ES256K proof", token, nil, false},
+		{"new verifier uses the EdDSA proof", string(brokenJSON), []auth.VerifyOpt{all}, false},
+		{"all proofs with both algorithms", token, []auth.VerifyOpt{all, auth.WithAllProofs()}, false},
+		{"all proofs with EdDSA not allowed", token, []auth.VerifyOpt{auth.WithAllProofs()}, true},
+		{"all proofs with one broken", string(brokenJSON), []auth.VerifyOpt{all, auth.WithAllProofs()}, true},
+		{"no proof verifies", string(brokenJSON), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.VerifyToken(ctx, tt.token, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyToken error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("proof from another DID", func(t *testing.T) {
+		if _, err := a.CreateToken(ctx, []string{vcJwt}, holder.DID, holder.Address,
+			auth.WithAdditionalProof(strangerSigner), auth.WithOutputFormat(auth.OutputJWSJSON)); err == nil {
+			t.Error("expected CreateToken to reject a proof key of another DID")
+		}
+
+		// A signature by the wrong key under the holder's kid fails.
+		forged, err := a.CreateToken(ctx, []string{vcJwt}, holder.DID, holder.Address,
+			auth.WithAdditionalProof(&edProofSigner{kid: holder.DID + "#ed25519", key: strangerPrivate}), auth.WithOutputFormat(auth.OutputJWSJSON))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := a.VerifyToken(ctx, forged, all, auth.WithAllProofs()); err == nil {
+			t.Error("expected a proof with the wrong key to fail under WithAllProofs")
+		}
+	})
+
+	t.Run("requires JWS JSON output", func(t *testing.T) {
+		if _, err := a.CreateToken(ctx, []string{vcJwt}, holder.DID, holder.Address, auth.WithAdditionalProof(edSigner)); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("stranger's proof spliced in", func(t *testing.T) {
+		_, err := a.VerifyToken(ctx, addSignature(t, token, strangerSigner), all, auth.WithAllProofs())
+		if !errors.Is(err, auth.ErrIssuerMismatch) {
+			t.Errorf("got %v, want ErrIssuerMismatch", err)
+		}
+	})
+}
+
+// addSignature appends a signature by signer to a general JWS JSON serialization.
+func addSignature(t *testing.T, token string, signer auth.ProofSigner) string {
+	t.Helper()
+
+	var jws struct {
+		Payload    string              `json:"payload"`
+		Signatures []map[string]string `json:"signatures"`
+	}
+	if err := json.Unmarshal([]byte(token), &jws); err != nil {
+		t.Fatal(err)
+	}
+	headerJSON, _ := json.Marshal(map[string]any{"alg": signer.Algorithm(), "kid": signer.KeyID(), "typ": "JWT"})
+	protected := base64.RawURLEncoding.EncodeToString(headerJSON)
+	signature, _ := signer.Sign(context.Background(), []byte(protected+"."+jws.Payload))
+	jws.Signatures = append(jws.Signatures, map[string]string{
+		"protected": protected,
+		"signature": base64.RawURLEncoding.EncodeToString(signature),
+	})
+
+	out, _ := json.Marshal(jws)
+	return string(out)
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// oidECPublicKey and oidSecp256k1 identify an elliptic curve public key on secp256k1 (RFC 5480, SEC 2).
+	oidECPublicKey = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidSecp256k1   = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+// ecdsaSignature is the ASN.1 ECDSA-Sig-Value of a DER signature (RFC 3279).
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// subjectPublicKeyInfo is the ASN.1 SubjectPublicKeyInfo of a DER public key (RFC 5280), with the
+// named-curve parameters of an elliptic curve key.
+type subjectPublicKeyInfo struct {
+	Algorithm struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters asn1.ObjectIdentifier
+	}
+	PublicKey asn1.BitString
+}
+
+// derToRawSignature converts a DER ECDSA signature, as returned by cloud key management services, to the
+// 64-byte r || s with a low s that the Vault provider returns.
+func derToRawSignature(der []byte) ([]byte, error) {
+	var sig ecdsaSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DER signature: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("invalid DER signature: trailing data")
+	}
+	for _, v := range []*big.Int{sig.R, sig.S} {
+		if v == nil || v.Sign() <= 0 || v.Cmp(secp256k1Order) >= 0 {
+			return nil, errors.New("invalid DER signature: value out of range")
+		}
+	}
+
+	raw := make([]byte, 64)
+	sig.R.FillBytes(raw[:32])
+	sig.S.FillBytes(raw[32:])
+	return NormalizeLowS(raw)
+}
+
+// parseSecp256k1PublicKey parses a DER SubjectPublicKeyInfo holding a secp256k1 key, which crypto/x509
+// does not support.
+func parseSecp256k1PublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &spki)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DER public key: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("invalid DER public key: trailing data")
+	}
+	if !spki.Algorithm.Algorithm.Equal(oidECPublicKey) || !spki.Algorithm.Parameters.Equal(oidSecp256k1) {
+		return nil, fmt.Errorf("public key is not a secp256k1 key: %v %v", spki.Algorithm.Algorithm, spki.Algorithm.Parameters)
+	}
+
+	point := spki.PublicKey.RightAlign()
+	if len(point) == 33 {
+		return crypto.DecompressPubkey(point)
+	}
+	return crypto.UnmarshalPubkey(point)
+}
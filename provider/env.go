@@ -0,0 +1,233 @@
+package provider
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/secret"
+	"github/hovanhoa/go-vc-auth/vault"
+)
+
+// EnvProvider names the environment variable that selects a provider explicitly, bypassing detection.
+const EnvProvider = "VC_AUTH_PROVIDER"
+
+// ErrNoProviderConfigured is returned by FromEnv when no provider's environment variables are set.
+var ErrNoProviderConfigured = errors.New("no signing provider configured in the environment")
+
+// envSource describes how one provider is detected and built from the environment.
+type envSource struct {
+	name   string
+	vars   []string // any of these being set selects the source during detection
+	build  func(opts []any) (Provider, error)
+	active bool // false while the SDK has no implementation for the source
+}
+
+// envSources lists the providers FromEnv knows about, in detection precedence order.
+var envSources = []envSource{
+	{name: "vault", vars: []string{"VAULT_ADDR"}, build: vaultFromEnv, active: true},
+	{name: "aws", vars: []string{"AWS_KMS_KEY_ID", "AWS_ACCESS_KEY_ID", "AWS_PROFILE", "AWS_ROLE_ARN"}, build: awsFromEnv, active: true},
+	{name: "gcp", vars: []string{"GOOGLE_APPLICATION_CREDENTIALS"}, build: gcpFromEnv, active: true},
+	{name: "azure", vars: []string{"AZURE_KEYVAULT_URL"}, build: azureFromEnv, active: true},
+}
+
+// FromEnv builds a provider from environment variables, so every deployment wires signing the same way.
+// opts are passed on to the selected provider's constructor, e.g. VaultOpt values.
+//
+// The provider is chosen in this order:
+//
+//  1. VC_AUTH_PROVIDER, when set, names the provider ("vault", "aws", "gcp" or "azure") and nothing else is consulted.
+//  2. Vault, when VAULT_ADDR is set. VAULT_TOKEN holds the token, VAULT_MOUNT_PATH the optional mount path of
+//     the ethsign plugin and VAULT_MAX_RETRIES the optional retry count. With VAULT_K8S_ROLE set instead of a
+//     token, the provider logs in with the pod's service account under that role, through the Kubernetes
+//     auth method mounted at VAULT_K8S_AUTH_PATH (default "kubernetes"). VAULT_CACERT names a PEM CA bundle,
+//     VAULT_CLIENT_CERT and VAULT_CLIENT_KEY a client certificate and key for mTLS, and VAULT_SKIP_VERIFY=true
+//     disables server certificate checks, for development only. VAULT_CLIENT_TIMEOUT sets the timeout of each
+//     request, as a duration ("30s") or in seconds.
+//  3. AWS KMS, when AWS_KMS_KEY_ID, AWS_ACCESS_KEY_ID, AWS_PROFILE or AWS_ROLE_ARN is set. AWS_REGION (or
+//     AWS_DEFAULT_REGION) names the region; AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and the optional
+//     AWS_SESSION_TOKEN hold the credentials, and AWS_KMS_KEY_ID the key used for every signer.
+//  4. Google Cloud KMS, when GOOGLE_APPLICATION_CREDENTIALS is set. It points to a service account key file,
+//     and GOOGLE_KMS_KEY_VERSION holds the resource name of the key version used for every signer.
+//  5. Azure Key Vault, when AZURE_KEYVAULT_URL is set. The host's managed identity authenticates, the
+//     user-assigned one named by AZURE_CLIENT_ID when set, and AZURE_KEYVAULT_KEY holds the key ("name" or
+//     "name/version") used for every signer.
+//
+// ErrNoProviderConfigured is returned when none applies.
+func FromEnv(opts ...any) (Provider, error) {
+	if name := strings.TrimSpace(os.Getenv(EnvProvider)); name != "" {
+		for _, source := range envSources {
+			if strings.EqualFold(source.name, name) {
+				return source.fromEnv(opts)
+			}
+		}
+		return nil, fmt.Errorf("%s names unknown provider %q", EnvProvider, name)
+	}
+
+	for _, source := range envSources {
+		for _, v := range source.vars {
+			if os.Getenv(v) != "" {
+				return source.fromEnv(opts)
+			}
+		}
+	}
+	return nil, ErrNoProviderConfigured
+}
+
+// fromEnv builds the source's provider, or reports that the SDK cannot build it yet.
+func (s envSource) fromEnv(opts []any) (Provider, error) {
+	if !s.active {
+		return nil, fmt.Errorf("provider %q is configured in the environment but not supported by this SDK", s.name)
+	}
+	return s.build(opts)
+}
+
+// vaultFromEnv builds a Vault provider from VAULT_ADDR, VAULT_TOKEN, VAULT_K8S_ROLE, VAULT_K8S_AUTH_PATH,
+// VAULT_MOUNT_PATH, VAULT_MAX_RETRIES, VAULT_CLIENT_TIMEOUT and the TLS variables.
+func vaultFromEnv(opts []any) (Provider, error) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is required for the vault provider")
+	}
+
+	args := []any{WithVaultToken(secret.New(os.Getenv("VAULT_TOKEN")))}
+	if role := os.Getenv("VAULT_K8S_ROLE"); role != "" {
+		args = append(args, WithVaultAuth(vault.KubernetesAuth{Role: role, Mount: os.Getenv("VAULT_K8S_AUTH_PATH")}))
+	}
+	tlsOpts, err := vaultTLSFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, tlsOpts...)
+	if mountPath := os.Getenv("VAULT_MOUNT_PATH"); mountPath != "" {
+		args = append(args, WithVaultMountPath(mountPath))
+	}
+	if retries := os.Getenv("VAULT_MAX_RETRIES"); retries != "" {
+		n, err := strconv.Atoi(retries)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("VAULT_MAX_RETRIES must be a non-negative integer, got %q", retries)
+		}
+		args = append(args, n)
+	}
+	if timeout := os.Getenv("VAULT_CLIENT_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if seconds, convErr := strconv.Atoi(timeout); convErr == nil {
+			d, err = time.Duration(seconds)*time.Second, nil
+		}
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("VAULT_CLIENT_TIMEOUT must be a positive duration, got %q", timeout)
+		}
+		args = append(args, WithVaultTimeout(d))
+	}
+	// Caller options come last so they override the environment.
+	return NewVaultProvider(address, "", append(args, opts...)...), nil
+}
+
+// vaultTLSFromEnv returns the TLS options set by VAULT_CACERT, VAULT_CLIENT_CERT, VAULT_CLIENT_KEY and
+// VAULT_SKIP_VERIFY.
+func vaultTLSFromEnv() ([]any, error) {
+	var opts []any
+	if caFile := os.Getenv("VAULT_CACERT"); caFile != "" {
+		pool, err := vault.LoadCACert(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("VAULT_CACERT: %w", err)
+		}
+		opts = append(opts, WithVaultCACert(pool))
+	}
+
+	certFile, keyFile := os.Getenv("VAULT_CLIENT_CERT"), os.Getenv("VAULT_CLIENT_KEY")
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("VAULT_CLIENT_CERT and VAULT_CLIENT_KEY must be set together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Vault client certificate: %w", err)
+		}
+		opts = append(opts, WithVaultClientCert(cert))
+	}
+
+	if skip := os.Getenv("VAULT_SKIP_VERIFY"); skip != "" {
+		insecure, err := strconv.ParseBool(skip)
+		if err != nil {
+			return nil, fmt.Errorf("VAULT_SKIP_VERIFY must be a boolean, got %q", skip)
+		}
+		if insecure {
+			opts = append(opts, VaultOpt(func(v *vault.Vault) {
+				vaultTLS(v).InsecureSkipVerify = true
+			}))
+		}
+	}
+	return opts, nil
+}
+
+// awsFromEnv builds an AWS KMS provider from the standard AWS variables and AWS_KMS_KEY_ID. Only static
+// credentials are read: profiles and role assumption need the AWS SDK, whose credentials the caller can
+// pass with WithKMSCredentials.
+func awsFromEnv(opts []any) (Provider, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	var args []any
+	if accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyID != "" {
+		args = append(args, WithKMSCredentials(AWSCredentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secret.New(os.Getenv("AWS_SECRET_ACCESS_KEY")),
+			SessionToken:    secret.New(os.Getenv("AWS_SESSION_TOKEN")),
+		}))
+	}
+	if keyID := os.Getenv("AWS_KMS_KEY_ID"); keyID != "" {
+		args = append(args, WithKMSKeyID(keyID))
+	}
+	// Caller options come last so they override the environment.
+	return NewKMSProvider(region, append(args, opts...)...)
+}
+
+// gcpFromEnv builds a Cloud KMS provider from GOOGLE_APPLICATION_CREDENTIALS and GOOGLE_KMS_KEY_VERSION.
+func gcpFromEnv(opts []any) (Provider, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		return nil, fmt.Errorf("GOOGLE_APPLICATION_CREDENTIALS is required for the gcp provider")
+	}
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+	}
+	tokens, err := GCPServiceAccount(keyJSON, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{WithGCPTokenSource(tokens)}
+	if name := os.Getenv("GOOGLE_KMS_KEY_VERSION"); name != "" {
+		key, err := ParseGCPKeyVersion(name)
+		if err != nil {
+			return nil, fmt.Errorf("GOOGLE_KMS_KEY_VERSION: %w", err)
+		}
+		args = append(args, WithGCPKeyVersion(key))
+	}
+	// Caller options come last so they override the environment.
+	return NewGCPKMSProvider(append(args, opts...)...)
+}
+
+// azureFromEnv builds a Key Vault provider from AZURE_KEYVAULT_URL, AZURE_CLIENT_ID and AZURE_KEYVAULT_KEY.
+func azureFromEnv(opts []any) (Provider, error) {
+	vaultURL := os.Getenv("AZURE_KEYVAULT_URL")
+	if vaultURL == "" {
+		return nil, fmt.Errorf("AZURE_KEYVAULT_URL is required for the azure provider")
+	}
+
+	args := []any{WithAzureTokenSource(AzureManagedIdentity(os.Getenv("AZURE_CLIENT_ID"), nil, nil))}
+	if key := os.Getenv("AZURE_KEYVAULT_KEY"); key != "" {
+		name, version, _ := strings.Cut(key, "/")
+		args = append(args, WithAzureKey(AzureKey{Name: name, Version: version}))
+	}
+	// Caller options come last so they override the environment.
+	return NewAzureKeyVaultProvider(vaultURL, append(args, opts...)...)
+}
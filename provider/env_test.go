@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/vault"
+)
+
+// clearProviderEnv unsets every variable FromEnv consults.
+func clearProviderEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv(EnvProvider, "")
+	for _, source := range envSources {
+		for _, v := range source.vars {
+			t.Setenv(v, "")
+		}
+	}
+	for _, v := range []string{"VAULT_TOKEN", "VAULT_K8S_ROLE", "VAULT_K8S_AUTH_PATH", "VAULT_CACERT", "VAULT_CLIENT_CERT", "VAULT_CLIENT_KEY", "VAULT_SKIP_VERIFY", "VAULT_MOUNT_PATH", "VAULT_MAX_RETRIES", "VAULT_CLIENT_TIMEOUT", "AWS_REGION", "AWS_DEFAULT_REGION", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "GOOGLE_KMS_KEY_VERSION", "AZURE_CLIENT_ID", "AZURE_KEYVAULT_KEY"} {
+		t.Setenv(v, "")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Run("vault", func(t *testing.T) {
+		clearProviderEnv(t)
+		t.Setenv("VAULT_ADDR", "http://vault:8200")
+		t.Setenv("VAULT_TOKEN", "s.token")
+		t.Setenv("VAULT_MAX_RETRIES", "5")
+		t.Setenv("VAULT_MOUNT_PATH", "ethsign")
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "/etc/gcp.json")
+
+		p, err := FromEnv()
+		if err != nil {
+			t.Fatalf("FromEnv: %v", err)
+		}
+		v := p.(*vaultProvider).vault
+		if v.Address != "http://vault:8200" || v.Token.Reveal() != "s.token" || v.MaxRetries != 5 || v.MountPath != "ethsign" {
+			t.Errorf("unexpected vault config: %s %d", v.Address, v.MaxRetries)
+		}
+
+		p, err = FromEnv(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.(*vaultProvider).vault.MaxRetries; got != 1 {
+			t.Errorf("caller option did not override the environment: MaxRetries = %d", got)
+		}
+
+		t.Setenv("VAULT_TOKEN", "")
+		t.Setenv("VAULT_K8S_ROLE", "vc-auth")
+		t.Setenv("VAULT_K8S_AUTH_PATH", "k8s-prod")
+		p, err = FromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.(*vaultProvider).vault.Auth; got != (vault.KubernetesAuth{Role: "vc-auth", Mount: "k8s-prod"}) {
+			t.Errorf("unexpected vault auth: %#v", got)
+		}
+
+		t.Setenv("VAULT_CLIENT_TIMEOUT", "45")
+		p, err = FromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.(*vaultProvider).vault.Conn.Timeout; got != 45*time.Second {
+			t.Errorf("VAULT_CLIENT_TIMEOUT was not applied: %v", got)
+		}
+		t.Setenv("VAULT_CLIENT_TIMEOUT", "soon")
+		if _, err := FromEnv(); err == nil {
+			t.Error("expected an error for an invalid VAULT_CLIENT_TIMEOUT")
+		}
+		t.Setenv("VAULT_CLIENT_TIMEOUT", "")
+
+		t.Setenv("VAULT_SKIP_VERIFY", "true")
+		p, err = FromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tlsConfig := p.(*vaultProvider).vault.TLS; tlsConfig == nil || !tlsConfig.InsecureSkipVerify {
+			t.Errorf("VAULT_SKIP_VERIFY was not applied: %+v", tlsConfig)
+		}
+		t.Setenv("VAULT_CLIENT_CERT", "/etc/vault/client.pem")
+		if _, err := FromEnv(); err == nil {
+			t.Error("expected an error for VAULT_CLIENT_CERT without VAULT_CLIENT_KEY")
+		}
+	})
+
+	t.Run("aws", func(t *testing.T) {
+		clearProviderEnv(t)
+		t.Setenv("AWS_REGION", "eu-west-1")
+		t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+		t.Setenv("AWS_KMS_KEY_ID", "alias/holder")
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "/etc/gcp.json")
+
+		p, err := FromEnv()
+		if err != nil {
+			t.Fatalf("FromEnv: %v", err)
+		}
+		kms := p.(*kmsProvider)
+		if kms.region != "eu-west-1" || kms.defaultKey != "alias/holder" || kms.credentials.SecretAccessKey.Reveal() != "secret" {
+			t.Errorf("unexpected KMS config: %s %s", kms.region, kms.defaultKey)
+		}
+
+		t.Setenv("AWS_ACCESS_KEY_ID", "")
+		t.Setenv("AWS_PROFILE", "prod")
+		if _, err := FromEnv(); err == nil {
+			t.Error("expected an error without static credentials")
+		}
+	})
+
+	t.Run("gcp", func(t *testing.T) {
+		clearProviderEnv(t)
+		keyFile := filepath.Join(t.TempDir(), "key.json")
+		if err := os.WriteFile(keyFile, newServiceAccountKey(t, "https://oauth2.googleapis.com/token"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", keyFile)
+		t.Setenv("GOOGLE_KMS_KEY_VERSION", "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1")
+
+		p, err := FromEnv()
+		if err != nil {
+			t.Fatalf("FromEnv: %v", err)
+		}
+		if got := p.(*gcpProvider).defaultKey; got != "projects/p/locations/global/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1" {
+			t.Errorf("unexpected key version %s", got)
+		}
+
+		t.Setenv("GOOGLE_KMS_KEY_VERSION", "projects/p/keyRings/r")
+		if _, err := FromEnv(); err == nil {
+			t.Error("expected an invalid key version to be rejected")
+		}
+	})
+
+	t.Run("azure", func(t *testing.T) {
+		clearProviderEnv(t)
+		t.Setenv("AZURE_KEYVAULT_URL", "https://my-vault.vault.azure.net")
+		t.Setenv("AZURE_CLIENT_ID", "client")
+		t.Setenv("AZURE_KEYVAULT_KEY", "holder/v1")
+
+		p, err := FromEnv()
+		if err != nil {
+			t.Fatalf("FromEnv: %v", err)
+		}
+		azure := p.(*azureProvider)
+		if azure.vaultURL != "https://my-vault.vault.azure.net" || *azure.defaultKey != (AzureKey{Name: "holder", Version: "v1"}) {
+			t.Errorf("unexpected azure config: %s %v", azure.vaultURL, azure.defaultKey)
+		}
+		if got := azure.tokens.(*managedIdentityTokens).clientID; got != "client" {
+			t.Errorf("unexpected client id %q", got)
+		}
+	})
+
+	t.Run("explicit selection", func(t *testing.T) {
+		clearProviderEnv(t)
+		t.Setenv("VAULT_ADDR", "http://vault:8200")
+		t.Setenv(EnvProvider, "gcp")
+		if _, err := FromEnv(); err == nil {
+			t.Error("expected the explicitly selected provider to be used")
+		}
+		t.Setenv(EnvProvider, "nope")
+		if _, err := FromEnv(); err == nil {
+			t.Error("expected an unknown provider to be rejected")
+		}
+	})
+
+	t.Run("invalid retries", func(t *testing.T) {
+		clearProviderEnv(t)
+		t.Setenv("VAULT_ADDR", "http://vault:8200")
+		t.Setenv("VAULT_MAX_RETRIES", "many")
+		if _, err := FromEnv(); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("nothing configured", func(t *testing.T) {
+		clearProviderEnv(t)
+		if _, err := FromEnv(); !errors.Is(err, ErrNoProviderConfigured) {
+			t.Errorf("got %v, want ErrNoProviderConfigured", err)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// secp256k1Order is the order n of the secp256k1 group, and secp256k1HalfOrder n/2.
+	secp256k1Order     = crypto.S256().Params().N
+	secp256k1HalfOrder = new(big.Int).Rsh(secp256k1Order, 1)
+)
+
+// ErrHighS is returned for secp256k1 signatures whose s value is in the upper half of the group order.
+var ErrHighS = errors.New("signature s value is not canonical (high s)")
+
+// NormalizeLowS returns a secp256k1 signature, r||s (64 bytes) or r||s||v (65 bytes), in the low-s form
+// of BIP-62 and EIP-2: a high s is replaced by n-s, and v flipped, which yields an equally valid
+// signature that strict verifiers accept. Some Vault plugins return high-s signatures. Low-s
+// signatures are returned unchanged.
+func NormalizeLowS(signature []byte) ([]byte, error) {
+	if len(signature) != 64 && len(signature) != 65 {
+		return nil, fmt.Errorf("secp256k1 signature must be 64 or 65 bytes, got %d", len(signature))
+	}
+
+	s := new(big.Int).SetBytes(signature[32:64])
+	if s.Cmp(secp256k1HalfOrder) <= 0 {
+		return signature, nil
+	}
+
+	normalized := make([]byte, len(signature))
+	copy(normalized, signature[:32])
+	new(big.Int).Sub(secp256k1Order, s).FillBytes(normalized[32:64])
+	if len(signature) == 65 {
+		normalized[64] = signature[64] ^ 1 // v is 0/1 or 27/28; flipping the low bit flips either
+	}
+	return normalized, nil
+}
+
+// IsLowS reports whether the s value of a secp256k1 signature r||s or r||s||v is in the lower half of
+// the group order.
+func IsLowS(signature []byte) bool {
+	if len(signature) < 64 {
+		return false
+	}
+	return new(big.Int).SetBytes(signature[32:64]).Cmp(secp256k1HalfOrder) <= 0
+}
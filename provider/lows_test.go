@@ -0,0 +1,42 @@
+package provider_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+func TestNormalizeLowS(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	hash := crypto.Keccak256([]byte("payload"))
+	signature, err := crypto.Sign(hash, key) // r||s||v, low s
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	high := append([]byte(nil), signature...)
+	s := new(big.Int).SetBytes(signature[32:64])
+	new(big.Int).Sub(crypto.S256().Params().N, s).FillBytes(high[32:64])
+	high[64] ^= 1
+	if provider.IsLowS(high) || !provider.IsLowS(signature) {
+		t.Fatal("IsLowS misclassified the signatures")
+	}
+
+	if normalized, err := provider.NormalizeLowS(signature); err != nil || !bytes.Equal(normalized, signature) {
+		t.Errorf("NormalizeLowS(low s) = %x, %v; want it unchanged", normalized, err)
+	}
+	normalized, err := provider.NormalizeLowS(high)
+	if err != nil || !bytes.Equal(normalized, signature) {
+		t.Fatalf("NormalizeLowS(high s) = %x, %v; want %x", normalized, err, signature)
+	}
+	if normalized, _ := provider.NormalizeLowS(high[:64]); !bytes.Equal(normalized, signature[:64]) {
+		t.Errorf("NormalizeLowS(r||s) = %x, want %x", normalized, signature[:64])
+	}
+	if _, err := provider.NormalizeLowS(signature[:10]); err == nil {
+		t.Error("NormalizeLowS accepted a truncated signature")
+	}
+}
@@ -0,0 +1,265 @@
+//go:build pkcs11 && cgo
+
+package provider
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+// The subset of the PKCS#11 v2.40 ABI used here, declared so no vendor header is needed.
+typedef unsigned long CK_ULONG;
+typedef CK_ULONG CK_RV;
+typedef CK_ULONG CK_SLOT_ID;
+typedef CK_ULONG CK_SESSION_HANDLE;
+typedef CK_ULONG CK_OBJECT_HANDLE;
+
+typedef struct { unsigned char major, minor; } CK_VERSION;
+typedef struct { CK_ULONG type; void *pValue; CK_ULONG ulValueLen; } CK_ATTRIBUTE;
+typedef struct { CK_ULONG mechanism; void *pParameter; CK_ULONG ulParameterLen; } CK_MECHANISM;
+typedef struct {
+	void *CreateMutex, *DestroyMutex, *LockMutex, *UnlockMutex;
+	CK_ULONG flags;
+	void *pReserved;
+} CK_C_INITIALIZE_ARGS;
+
+// CK_FUNCTION_LIST: the version followed by the functions in specification order.
+typedef struct {
+	CK_VERSION version;
+	void *fn[68];
+} CK_FUNCTION_LIST;
+
+enum {
+	FN_INITIALIZE = 0, FN_FINALIZE = 1, FN_OPEN_SESSION = 12, FN_CLOSE_SESSION = 13, FN_LOGIN = 18,
+	FN_LOGOUT = 19, FN_GET_ATTRIBUTE_VALUE = 24, FN_FIND_OBJECTS_INIT = 26, FN_FIND_OBJECTS = 27,
+	FN_FIND_OBJECTS_FINAL = 28, FN_SIGN_INIT = 42, FN_SIGN = 43,
+};
+
+#define CKR_OK 0x0
+#define CKR_USER_ALREADY_LOGGED_IN 0x100
+#define CKR_CRYPTOKI_ALREADY_INITIALIZED 0x191
+#define CKF_OS_LOCKING_OK 0x2
+#define CKF_SERIAL_SESSION 0x4
+#define CKU_USER 1
+#define CKA_CLASS 0x0
+#define CKA_LABEL 0x3
+#define CKA_EC_POINT 0x181
+#define CKM_ECDSA 0x1041
+
+static CK_RV p11_open(const char *path, void **module, CK_FUNCTION_LIST **fl) {
+	*module = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (*module == NULL) {
+		return (CK_RV)-1;
+	}
+	CK_RV (*getFunctionList)(CK_FUNCTION_LIST **) = dlsym(*module, "C_GetFunctionList");
+	if (getFunctionList == NULL) {
+		dlclose(*module);
+		return (CK_RV)-1;
+	}
+	CK_RV rv = getFunctionList(fl);
+	if (rv != CKR_OK) {
+		dlclose(*module);
+		return rv;
+	}
+
+	CK_C_INITIALIZE_ARGS args;
+	memset(&args, 0, sizeof(args));
+	args.flags = CKF_OS_LOCKING_OK;
+	rv = ((CK_RV (*)(void *))(*fl)->fn[FN_INITIALIZE])(&args);
+	if (rv != CKR_OK && rv != CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		dlclose(*module);
+	}
+	return rv == CKR_CRYPTOKI_ALREADY_INITIALIZED ? CKR_OK : rv;
+}
+
+static CK_RV p11_login(CK_FUNCTION_LIST *fl, CK_SLOT_ID slot, char *pin, CK_ULONG pinLen, CK_SESSION_HANDLE *session) {
+	CK_RV rv = ((CK_RV (*)(CK_SLOT_ID, CK_ULONG, void *, void *, CK_SESSION_HANDLE *))fl->fn[FN_OPEN_SESSION])(
+		slot, CKF_SERIAL_SESSION, NULL, NULL, session);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	rv = ((CK_RV (*)(CK_SESSION_HANDLE, CK_ULONG, char *, CK_ULONG))fl->fn[FN_LOGIN])(*session, CKU_USER, pin, pinLen);
+	if (rv != CKR_OK && rv != CKR_USER_ALREADY_LOGGED_IN) {
+		((CK_RV (*)(CK_SESSION_HANDLE))fl->fn[FN_CLOSE_SESSION])(*session);
+		return rv;
+	}
+	return CKR_OK;
+}
+
+static void p11_finalize(CK_FUNCTION_LIST *fl, void *module) {
+	((CK_RV (*)(void *))fl->fn[FN_FINALIZE])(NULL);
+	dlclose(module);
+}
+
+static CK_RV p11_close(CK_FUNCTION_LIST *fl, void *module, CK_SESSION_HANDLE session) {
+	((CK_RV (*)(CK_SESSION_HANDLE))fl->fn[FN_LOGOUT])(session);
+	CK_RV rv = ((CK_RV (*)(CK_SESSION_HANDLE))fl->fn[FN_CLOSE_SESSION])(session);
+	p11_finalize(fl, module);
+	return rv;
+}
+
+// p11_find finds the objects of class labelled label, up to two so ambiguous labels are noticed.
+static CK_RV p11_find(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_ULONG class, char *label, CK_ULONG labelLen,
+	CK_OBJECT_HANDLE *objects, CK_ULONG *count) {
+	CK_ATTRIBUTE template[2] = {
+		{CKA_CLASS, &class, sizeof(class)},
+		{CKA_LABEL, label, labelLen},
+	};
+	CK_RV rv = ((CK_RV (*)(CK_SESSION_HANDLE, CK_ATTRIBUTE *, CK_ULONG))fl->fn[FN_FIND_OBJECTS_INIT])(session, template, 2);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	rv = ((CK_RV (*)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE *, CK_ULONG, CK_ULONG *))fl->fn[FN_FIND_OBJECTS])(session, objects, 2, count);
+	CK_RV final = ((CK_RV (*)(CK_SESSION_HANDLE))fl->fn[FN_FIND_OBJECTS_FINAL])(session);
+	return rv != CKR_OK ? rv : final;
+}
+
+// p11_attribute reads an attribute into a buffer allocated with malloc.
+static CK_RV p11_attribute(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_OBJECT_HANDLE object, CK_ULONG type,
+	void **value, CK_ULONG *valueLen) {
+	CK_RV (*getAttributeValue)(CK_SESSION_HANDLE, CK_OBJECT_HANDLE, CK_ATTRIBUTE *, CK_ULONG) = fl->fn[FN_GET_ATTRIBUTE_VALUE];
+	CK_ATTRIBUTE attribute = {type, NULL, 0};
+	CK_RV rv = getAttributeValue(session, object, &attribute, 1);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	attribute.pValue = malloc(attribute.ulValueLen);
+	rv = getAttributeValue(session, object, &attribute, 1);
+	if (rv != CKR_OK) {
+		free(attribute.pValue);
+		return rv;
+	}
+	*value = attribute.pValue;
+	*valueLen = attribute.ulValueLen;
+	return CKR_OK;
+}
+
+static CK_RV p11_sign(CK_FUNCTION_LIST *fl, CK_SESSION_HANDLE session, CK_OBJECT_HANDLE key,
+	unsigned char *digest, CK_ULONG digestLen, unsigned char *signature, CK_ULONG *signatureLen) {
+	CK_MECHANISM mechanism = {CKM_ECDSA, NULL, 0};
+	CK_RV rv = ((CK_RV (*)(CK_SESSION_HANDLE, CK_MECHANISM *, CK_OBJECT_HANDLE))fl->fn[FN_SIGN_INIT])(session, &mechanism, key);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	return ((CK_RV (*)(CK_SESSION_HANDLE, unsigned char *, CK_ULONG, unsigned char *, CK_ULONG *))fl->fn[FN_SIGN])(
+		session, digest, digestLen, signature, signatureLen);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github/hovanhoa/go-vc-auth/secret"
+)
+
+// PKCS#11 object classes.
+const (
+	ckoPublicKey  = 2
+	ckoPrivateKey = 3
+)
+
+// cgoPKCS11Token is a session on a token of a module loaded with dlopen.
+type cgoPKCS11Token struct {
+	module      unsafe.Pointer
+	functions   *C.CK_FUNCTION_LIST
+	session     C.CK_SESSION_HANDLE
+	privateKeys map[string]C.CK_OBJECT_HANDLE // Label to private key
+}
+
+// openPKCS11 loads and initializes the module at modulePath, then opens a session on slot and logs in.
+func openPKCS11(modulePath string, slot uint, pin secret.Secret) (pkcs11Token, error) {
+	path := C.CString(modulePath)
+	defer C.free(unsafe.Pointer(path))
+
+	t := &cgoPKCS11Token{privateKeys: map[string]C.CK_OBJECT_HANDLE{}}
+	if rv := C.p11_open(path, &t.module, &t.functions); rv != 0 {
+		if rv == ^C.CK_RV(0) {
+			return nil, fmt.Errorf("failed to load PKCS#11 module %s", modulePath)
+		}
+		return nil, pkcs11Error("C_Initialize", rv)
+	}
+
+	pinBytes := pin.Bytes()
+	cPin := (*C.char)(C.CBytes(pinBytes))
+	rv := C.p11_login(t.functions, C.CK_SLOT_ID(slot), cPin, C.CK_ULONG(len(pinBytes)), &t.session)
+	C.memset(unsafe.Pointer(cPin), 0, C.size_t(len(pinBytes)))
+	C.free(unsafe.Pointer(cPin))
+	if rv != 0 {
+		C.p11_finalize(t.functions, t.module)
+		return nil, pkcs11Error("login", rv)
+	}
+	return t, nil
+}
+
+func (t *cgoPKCS11Token) ecPoint(label string) ([]byte, error) {
+	object, err := t.find(ckoPublicKey, label)
+	if err != nil {
+		return nil, err
+	}
+
+	var value unsafe.Pointer
+	var valueLen C.CK_ULONG
+	if rv := C.p11_attribute(t.functions, t.session, object, C.CKA_EC_POINT, &value, &valueLen); rv != 0 {
+		return nil, pkcs11Error("C_GetAttributeValue", rv)
+	}
+	defer C.free(value)
+	return C.GoBytes(value, C.int(valueLen)), nil
+}
+
+func (t *cgoPKCS11Token) sign(label string, digest []byte) ([]byte, error) {
+	key, ok := t.privateKeys[label]
+	if !ok {
+		var err error
+		if key, err = t.find(ckoPrivateKey, label); err != nil {
+			return nil, err
+		}
+		t.privateKeys[label] = key
+	}
+
+	signature := make([]byte, 128)
+	signatureLen := C.CK_ULONG(len(signature))
+	rv := C.p11_sign(t.functions, t.session, key,
+		(*C.uchar)(unsafe.Pointer(&digest[0])), C.CK_ULONG(len(digest)),
+		(*C.uchar)(unsafe.Pointer(&signature[0])), &signatureLen)
+	if rv != 0 {
+		return nil, pkcs11Error("C_Sign", rv)
+	}
+	return signature[:signatureLen], nil
+}
+
+func (t *cgoPKCS11Token) close() error {
+	if rv := C.p11_close(t.functions, t.module, t.session); rv != 0 {
+		return pkcs11Error("C_CloseSession", rv)
+	}
+	return nil
+}
+
+// find returns the only object of class labelled label.
+func (t *cgoPKCS11Token) find(class C.CK_ULONG, label string) (C.CK_OBJECT_HANDLE, error) {
+	cLabel := C.CString(label)
+	defer C.free(unsafe.Pointer(cLabel))
+
+	objects := (*[2]C.CK_OBJECT_HANDLE)(C.malloc(C.size_t(unsafe.Sizeof(C.CK_OBJECT_HANDLE(0))) * 2))
+	defer C.free(unsafe.Pointer(objects))
+	var count C.CK_ULONG
+	if rv := C.p11_find(t.functions, t.session, class, cLabel, C.CK_ULONG(len(label)), &objects[0], &count); rv != 0 {
+		return 0, pkcs11Error("C_FindObjects", rv)
+	}
+
+	switch count {
+	case 0:
+		return 0, fmt.Errorf("PKCS#11 key %s not found", label)
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, fmt.Errorf("PKCS#11 label %s names several keys", label)
+	}
+}
+
+// pkcs11Error reports a failed PKCS#11 call with its CKR_ return value.
+func pkcs11Error(operation string, rv C.CK_RV) error {
+	return fmt.Errorf("PKCS#11 %s failed: CKR 0x%X", operation, uint64(rv))
+}
@@ -0,0 +1,10 @@
+//go:build !pkcs11 || !cgo
+
+package provider
+
+import "github/hovanhoa/go-vc-auth/secret"
+
+// openPKCS11 reports that this build has no PKCS#11 support.
+func openPKCS11(modulePath string, slot uint, pin secret.Secret) (pkcs11Token, error) {
+	return nil, ErrPKCS11Unsupported
+}
@@ -1,7 +1,146 @@
 package provider
 
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github/hovanhoa/go-vc-auth/ethaddr"
+)
+
 // Provider defines the signing capability used by the auth service.
-// Sign should take an arbitrary payload and return the signed token bytes.
+// Sign should take an arbitrary payload and return the signed token bytes. ctx carries the deadline and
+// cancellation of the caller's request, e.g. of CreateToken, down to the signing backend; options selects
+// the key and may be nil.
+// Implementations are called concurrently unless they declare Serial via ConcurrencyDeclarer.
 type Provider interface {
-	Sign(payload []byte, opts ...any) ([]byte, error)
+	Sign(ctx context.Context, payload []byte, options *ProviderOption) ([]byte, error)
+}
+
+// ProviderOption holds the options of one signing request.
+type ProviderOption struct {
+	// Signer selects the key to sign with: the signer address, or the key name for backends that name
+	// keys, such as the Vault transit engine. Providers holding a single key accept it empty.
+	Signer string
+}
+
+// NewProviderOption builds the ProviderOption of the provider options passed to the auth service, e.g. the
+// trailing options of CreateToken: a string is the signer, and a ProviderOption is used as is.
+func NewProviderOption(opts ...any) (*ProviderOption, error) {
+	options := &ProviderOption{}
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case string:
+			options.Signer = opt
+		case ProviderOption:
+			*options = opt
+		case *ProviderOption:
+			if opt != nil {
+				*options = *opt
+			}
+		default:
+			return nil, fmt.Errorf("unsupported provider option %T", opt)
+		}
+	}
+	return options, nil
+}
+
+// signer returns the Signer of options, empty when options is nil.
+func (o *ProviderOption) signer() string {
+	if o == nil {
+		return ""
+	}
+	return o.Signer
+}
+
+// SignFunc adapts a function to a Provider, so a signing backend can be plugged in without declaring
+// a type.
+type SignFunc func(ctx context.Context, payload []byte, options *ProviderOption) ([]byte, error)
+
+var _ Provider = SignFunc(nil)
+
+// Sign calls f.
+func (f SignFunc) Sign(ctx context.Context, payload []byte, options *ProviderOption) ([]byte, error) {
+	return f(ctx, payload, options)
+}
+
+// TokenSource returns OAuth 2.0 access tokens for a cloud signing service, e.g. an adapter over
+// golang.org/x/oauth2.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc adapts a function to a TokenSource.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token calls f.
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// signerAddress returns the normalized signer address of options.
+func signerAddress(options *ProviderOption) (string, error) {
+	address := options.signer()
+	if address == "" {
+		return "", fmt.Errorf("signer address is required")
+	}
+	normalized, err := ethaddr.Normalize(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid signer address: %w", err)
+	}
+	return normalized, nil
+}
+
+// Concurrency describes whether a provider may be called from several goroutines at once.
+type Concurrency int
+
+const (
+	// Concurrent providers may be called from multiple goroutines simultaneously.
+	Concurrent Concurrency = iota
+	// Serial providers must not be called concurrently; callers serialize access to them.
+	Serial
+)
+
+// ConcurrencyDeclarer is implemented by providers that declare their concurrency model.
+// Providers that do not implement it are expected to be safe for concurrent use.
+type ConcurrencyDeclarer interface {
+	Concurrency() Concurrency
+}
+
+// ConcurrencyOf returns the concurrency model declared by p.
+func ConcurrencyOf(p Provider) Concurrency {
+	if d, ok := p.(ConcurrencyDeclarer); ok {
+		return d.Concurrency()
+	}
+	return Concurrent
+}
+
+// JWS algorithms of provider signatures.
+const (
+	AlgES256K = "ES256K" // ECDSA over secp256k1 with SHA-256, the default
+	AlgES256  = "ES256"  // ECDSA over P-256 with SHA-256
+	AlgES384  = "ES384"  // ECDSA over P-384 with SHA-384
+	AlgEdDSA  = "EdDSA"  // Ed25519
+)
+
+// AlgorithmDeclarer is implemented by providers whose signatures are not ES256K. The auth service writes
+// the declared algorithm in the headers of the tokens it signs, and passes Sign the SHA-256 digest of the
+// signing input for ES256K and ES256, its SHA-384 digest for ES384 and the signing input itself for EdDSA.
+type AlgorithmDeclarer interface {
+	Algorithm() string
+}
+
+// AlgorithmOf returns the JWS algorithm declared by p, AlgES256K when it declares none.
+func AlgorithmOf(p Provider) string {
+	if d, ok := p.(AlgorithmDeclarer); ok {
+		return d.Algorithm()
+	}
+	return AlgES256K
+}
+
+// PublicKeyExporter is implemented by providers that can return the public key of a signer directly.
+// options are the same provider options passed to Sign, e.g. the signer address.
+// Providers without it have their public key recovered from a probe signature instead.
+type PublicKeyExporter interface {
+	PublicKey(ctx context.Context, options *ProviderOption) (*ecdsa.PublicKey, error)
 }
@@ -0,0 +1,270 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/ethaddr"
+	"github/hovanhoa/go-vc-auth/secret"
+)
+
+const (
+	// kmsKeySpec is the AWS KMS key spec of secp256k1 keys.
+	kmsKeySpec = "ECC_SECG_P256K1"
+	// defaultKMSAliasPrefix starts the alias under which the KMS key of an address is looked up by default.
+	defaultKMSAliasPrefix = "alias/vc-auth-"
+
+	defaultKMSTimeout  = 10 * time.Second
+	maxKMSResponseSize = 1 << 20
+)
+
+// ErrKeyMismatch is returned when a cloud KMS key does not hold the key of the requested signer address.
+var ErrKeyMismatch = errors.New("KMS key does not match the signer address")
+
+// AWSCredentials are the static credentials used to sign AWS requests.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey secret.Secret
+	SessionToken    secret.Secret // Set for temporary credentials only
+}
+
+// KMSOpt configures the provider created by NewKMSProvider.
+type KMSOpt func(*kmsProvider)
+
+// WithKMSCredentials sets the credentials used to call AWS KMS.
+func WithKMSCredentials(creds AWSCredentials) KMSOpt {
+	return func(p *kmsProvider) {
+		p.credentials = creds
+	}
+}
+
+// WithKMSEndpoint replaces the regional KMS endpoint, e.g. with a VPC endpoint or a local emulator.
+func WithKMSEndpoint(endpoint string) KMSOpt {
+	return func(p *kmsProvider) {
+		p.endpoint = endpoint
+	}
+}
+
+// WithKMSHTTPClient sets the HTTP client used to call KMS (default: a client with a 10s timeout).
+func WithKMSHTTPClient(client *http.Client) KMSOpt {
+	return func(p *kmsProvider) {
+		p.httpClient = client
+	}
+}
+
+// WithKMSClock sets the time source used to date signed requests.
+func WithKMSClock(c clock.Clock) KMSOpt {
+	return func(p *kmsProvider) {
+		p.clock = clock.OrSystem(c)
+	}
+}
+
+// WithKMSKeys maps signer addresses to KMS key ids, ARNs or alias names.
+func WithKMSKeys(keys map[string]string) KMSOpt {
+	return func(p *kmsProvider) {
+		for address, keyID := range keys {
+			if normalized, err := ethaddr.Normalize(address); err == nil {
+				p.keys[normalized] = keyID
+			}
+		}
+	}
+}
+
+// WithKMSKeyID sets the KMS key used for addresses missing from WithKMSKeys, for deployments signing
+// with a single key.
+func WithKMSKeyID(keyID string) KMSOpt {
+	return func(p *kmsProvider) {
+		p.defaultKey = keyID
+	}
+}
+
+// WithKMSAliasPrefix sets the prefix of the alias under which the key of an address is looked up when
+// neither WithKMSKeys nor WithKMSKeyID names it (default: "alias/vc-auth-", giving e.g.
+// "alias/vc-auth-0x2af7...").
+func WithKMSAliasPrefix(prefix string) KMSOpt {
+	return func(p *kmsProvider) {
+		p.aliasPrefix = prefix
+	}
+}
+
+// kmsProvider is the provider implementation that signs with AWS KMS asymmetric secp256k1 keys.
+type kmsProvider struct {
+	region      string
+	endpoint    string
+	credentials AWSCredentials
+	httpClient  *http.Client
+	clock       clock.Clock
+	keys        map[string]string // Normalized address to key id
+	defaultKey  string
+	aliasPrefix string
+
+	mu         sync.Mutex
+	publicKeys map[string]*ecdsa.PublicKey // Key id to its public key, checked against the address
+}
+
+// NewKMSProvider creates a provider signing with AWS KMS keys of spec ECC_SECG_P256K1 in region. opts are
+// KMSOpt values; credentials must be given with WithKMSCredentials. The signer address passed to Sign
+// selects the key, see WithKMSKeys; the key's public key must derive that address.
+func NewKMSProvider(region string, opts ...any) (Provider, error) {
+	if region == "" {
+		return nil, errors.New("AWS region is required for the KMS provider")
+	}
+
+	p := &kmsProvider{
+		region:      region,
+		endpoint:    "https://kms." + region + ".amazonaws.com",
+		httpClient:  &http.Client{Timeout: defaultKMSTimeout},
+		clock:       clock.System(),
+		keys:        map[string]string{},
+		aliasPrefix: defaultKMSAliasPrefix,
+		publicKeys:  map[string]*ecdsa.PublicKey{},
+	}
+	for _, opt := range opts {
+		if opt, ok := opt.(KMSOpt); ok {
+			opt(p)
+		}
+	}
+
+	if p.credentials.AccessKeyID == "" || p.credentials.SecretAccessKey.IsEmpty() {
+		return nil, errors.New("AWS credentials are required for the KMS provider")
+	}
+	return p, nil
+}
+
+// Concurrency reports that the KMS provider is safe for concurrent use.
+func (p *kmsProvider) Concurrency() Concurrency {
+	return Concurrent
+}
+
+// Sign signs the 32-byte digest payload with the KMS key of the signer address in options and
+// returns r || s with a low s, like the Vault provider.
+func (p *kmsProvider) Sign(ctx context.Context, payload []byte, options *ProviderOption) ([]byte, error) {
+	if len(payload) != 32 {
+		return nil, fmt.Errorf("payload must be 32 bytes")
+	}
+	address, err := signerAddress(options)
+	if err != nil {
+		return nil, err
+	}
+	keyID := p.keyOf(address)
+	// Checks, once per key, that it is the key of the address: never sign under the wrong identity.
+	if _, err := p.PublicKey(ctx, options); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Signature []byte
+	}
+	err = p.call(ctx, "Sign", map[string]any{
+		"KeyId":            keyID,
+		"Message":          payload,
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": "ECDSA_SHA_256",
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return derToRawSignature(response.Signature)
+}
+
+// PublicKey returns the public key of the KMS key of the signer address in options, checking that it
+// derives the address.
+func (p *kmsProvider) PublicKey(ctx context.Context, options *ProviderOption) (*ecdsa.PublicKey, error) {
+	address, err := signerAddress(options)
+	if err != nil {
+		return nil, err
+	}
+	keyID := p.keyOf(address)
+
+	p.mu.Lock()
+	publicKey, ok := p.publicKeys[keyID]
+	p.mu.Unlock()
+
+	if !ok {
+		var response struct {
+			KeySpec   string
+			PublicKey []byte
+		}
+		if err := p.call(ctx, "GetPublicKey", map[string]any{"KeyId": keyID}, &response); err != nil {
+			return nil, err
+		}
+		if response.KeySpec != kmsKeySpec {
+			return nil, fmt.Errorf("KMS key %s has spec %s, want %s", keyID, response.KeySpec, kmsKeySpec)
+		}
+		publicKey, err = parseSecp256k1PublicKey(response.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("KMS key %s: %w", keyID, err)
+		}
+
+		p.mu.Lock()
+		p.publicKeys[keyID] = publicKey
+		p.mu.Unlock()
+	}
+
+	if !ethaddr.Equal(crypto.PubkeyToAddress(*publicKey).Hex(), address) {
+		return nil, fmt.Errorf("%w: %s is not the key of %s", ErrKeyMismatch, keyID, address)
+	}
+	return publicKey, nil
+}
+
+// keyOf returns the KMS key id of a normalized signer address.
+func (p *kmsProvider) keyOf(address string) string {
+	if keyID, ok := p.keys[address]; ok {
+		return keyID
+	}
+	if p.defaultKey != "" {
+		return p.defaultKey
+	}
+	return p.aliasPrefix + address
+}
+
+// call invokes a KMS JSON API action with a SigV4-signed request.
+func (p *kmsProvider) call(ctx context.Context, action string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	signV4(req, body, p.credentials, p.region, "kms", p.clock.Now())
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxKMSResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var kmsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(respBody, &kmsErr)
+		return fmt.Errorf("KMS %s failed with status %d: %s %s", action, resp.StatusCode, kmsErr.Type, kmsErr.Message)
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode KMS %s response: %w", action, err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/secret"
+)
+
+// TestSignV4 checks the get-vanilla case of the AWS Signature Version 4 test suite.
+func TestSignV4(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: secret.New("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")}
+	signV4(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s\nwant %s", got, want)
+	}
+}
+
+// newFakeKMS serves the KMS GetPublicKey and Sign actions for key, answering with high-s signatures.
+func newFakeKMS(t *testing.T, key *ecdsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	point := crypto.FromECDSAPub(&key.PublicKey)
+	var spki subjectPublicKeyInfo
+	spki.Algorithm.Algorithm, spki.Algorithm.Parameters = oidECPublicKey, oidSecp256k1
+	spki.PublicKey = asn1.BitString{Bytes: point, BitLength: 8 * len(point)}
+	publicKeyDER, _ := asn1.Marshal(spki)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+			t.Errorf("request is not signed: %v", r.Header)
+		}
+		var in struct {
+			KeyId   string
+			Message []byte
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		if in.KeyId != "alias/vc-auth-"+strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex()) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"NotFoundException","message":"Alias is not found."}`))
+			return
+		}
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			_ = json.NewEncoder(w).Encode(map[string]any{"KeySpec": kmsKeySpec, "PublicKey": publicKeyDER})
+		case "TrentService.Sign":
+			signature, _ := crypto.Sign(in.Message, key)
+			s := new(big.Int).Sub(secp256k1Order, new(big.Int).SetBytes(signature[32:64]))
+			der, _ := asn1.Marshal(ecdsaSignature{R: new(big.Int).SetBytes(signature[:32]), S: s})
+			_ = json.NewEncoder(w).Encode(map[string]any{"Signature": der})
+		default:
+			t.Errorf("unexpected action %s", r.Header.Get("X-Amz-Target"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestKMSProvider(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	srv := newFakeKMS(t, key)
+
+	p, err := NewKMSProvider("us-east-1",
+		WithKMSEndpoint(srv.URL),
+		WithKMSCredentials(AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: secret.New("secret")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := crypto.Keccak256([]byte("payload"))
+	signature, err := p.Sign(context.Background(), digest, &ProviderOption{Signer: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signature) != 64 || !IsLowS(signature) || !crypto.VerifySignature(crypto.FromECDSAPub(&key.PublicKey), digest, signature) {
+		t.Fatalf("invalid signature %x", signature)
+	}
+	local, _ := NewLocalProvider(key)
+	if want, _ := local.Sign(context.Background(), digest, nil); string(signature) != string(want) {
+		t.Errorf("KMS signature %x differs from the local one %x", signature, want)
+	}
+
+	if _, err := p.Sign(context.Background(), digest, &ProviderOption{Signer: "0x" + strings.Repeat("00", 20)}); err == nil || !strings.Contains(err.Error(), "NotFoundException") {
+		t.Errorf("unknown alias: %v", err)
+	}
+
+	other, _ := crypto.GenerateKey()
+	mismatched, _ := NewKMSProvider("us-east-1",
+		WithKMSEndpoint(srv.URL),
+		WithKMSCredentials(AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: secret.New("secret")}),
+		WithKMSKeys(map[string]string{crypto.PubkeyToAddress(other.PublicKey).Hex(): "alias/vc-auth-" + strings.ToLower(address)}))
+	if _, err := mismatched.Sign(context.Background(), digest, &ProviderOption{Signer: crypto.PubkeyToAddress(other.PublicKey).Hex()}); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("expected ErrKeyMismatch, got %v", err)
+	}
+}
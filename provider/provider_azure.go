@@ -0,0 +1,415 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/ethaddr"
+	"github/hovanhoa/go-vc-auth/vault"
+)
+
+const (
+	// azureAPIVersion is the Key Vault REST API version used.
+	azureAPIVersion = "7.4"
+	// azureKeyVaultResource is the resource managed identity tokens are requested for.
+	azureKeyVaultResource = "https://vault.azure.net"
+	// defaultAzureKeyPrefix starts the name under which the key of an address is looked up by default.
+	defaultAzureKeyPrefix = "vc-auth-"
+	// azureIMDSEndpoint is the Azure Instance Metadata Service token endpoint.
+	azureIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+	defaultAzureTimeout  = 10 * time.Second
+	defaultAzureRetries  = 3
+	maxAzureResponseSize = 1 << 20
+)
+
+// AzureKey names a Key Vault key; an empty Version means its current version.
+type AzureKey struct {
+	Name    string
+	Version string
+}
+
+// AzureOpt configures the provider created by NewAzureKeyVaultProvider.
+type AzureOpt func(*azureProvider)
+
+// WithAzureTokenSource sets where the access tokens used to call Key Vault come from (default: the managed
+// identity of the host, see AzureManagedIdentity).
+func WithAzureTokenSource(tokens TokenSource) AzureOpt {
+	return func(p *azureProvider) {
+		p.tokens = tokens
+	}
+}
+
+// WithAzureKeys maps signer addresses to Key Vault keys.
+func WithAzureKeys(keys map[string]AzureKey) AzureOpt {
+	return func(p *azureProvider) {
+		for address, key := range keys {
+			if normalized, err := ethaddr.Normalize(address); err == nil {
+				p.keys[normalized] = key
+			}
+		}
+	}
+}
+
+// WithAzureKey sets the key used for addresses missing from WithAzureKeys, for deployments signing with a
+// single key. Without it, the key of an address is named "vc-auth-<address>".
+func WithAzureKey(key AzureKey) AzureOpt {
+	return func(p *azureProvider) {
+		p.defaultKey = &key
+	}
+}
+
+// WithAzureHTTPClient sets the HTTP client used to call Key Vault (default: a client with a 10s timeout).
+func WithAzureHTTPClient(client *http.Client) AzureOpt {
+	return func(p *azureProvider) {
+		p.httpClient = client
+	}
+}
+
+// WithAzureClock sets the time source used for retry backoff.
+func WithAzureClock(c clock.Clock) AzureOpt {
+	return func(p *azureProvider) {
+		p.clock = c
+	}
+}
+
+// WithAzureMaxRetries sets how many times a Key Vault request answered with 429 or 503, or failing with a
+// transient network error, is retried (default: 3), with the backoff and vault.RetryBudget of the Vault client.
+func WithAzureMaxRetries(n int) AzureOpt {
+	return func(p *azureProvider) {
+		if n >= 0 {
+			p.maxRetries = n
+		}
+	}
+}
+
+// azureProvider is the provider implementation that signs with Azure Key Vault secp256k1 (P-256K) keys.
+type azureProvider struct {
+	vaultURL   string
+	tokens     TokenSource
+	httpClient *http.Client
+	clock      clock.Clock
+	maxRetries int
+	keys       map[string]AzureKey // Normalized address to key
+	defaultKey *AzureKey
+
+	mu       sync.Mutex
+	resolved map[AzureKey]azureResolvedKey
+}
+
+// azureResolvedKey is a key fetched from Key Vault: its versioned id and public key.
+type azureResolvedKey struct {
+	kid       string
+	publicKey *ecdsa.PublicKey
+}
+
+// NewAzureKeyVaultProvider creates a provider signing with the EC keys on curve P-256K of the Key Vault at
+// vaultURL, e.g. "https://my-vault.vault.azure.net". opts are AzureOpt values. The key's public key must
+// derive the signer address passed to Sign.
+func NewAzureKeyVaultProvider(vaultURL string, opts ...any) (Provider, error) {
+	parsed, err := url.Parse(vaultURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Key Vault URL %q", vaultURL)
+	}
+
+	p := &azureProvider{
+		vaultURL:   strings.TrimSuffix(vaultURL, "/"),
+		httpClient: &http.Client{Timeout: defaultAzureTimeout},
+		maxRetries: defaultAzureRetries,
+		keys:       map[string]AzureKey{},
+		resolved:   map[AzureKey]azureResolvedKey{},
+	}
+	for _, opt := range opts {
+		if opt, ok := opt.(AzureOpt); ok {
+			opt(p)
+		}
+	}
+	if p.tokens == nil {
+		p.tokens = AzureManagedIdentity("", nil, p.clock)
+	}
+	return p, nil
+}
+
+// Concurrency reports that the Key Vault provider is safe for concurrent use.
+func (p *azureProvider) Concurrency() Concurrency {
+	return Concurrent
+}
+
+// Sign signs the 32-byte digest payload with the key of the signer address in options and
+// returns r || s with a low s, like the Vault provider.
+func (p *azureProvider) Sign(ctx context.Context, payload []byte, options *ProviderOption) ([]byte, error) {
+	if len(payload) != 32 {
+		return nil, fmt.Errorf("payload must be 32 bytes")
+	}
+	key, err := p.resolve(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Value string `json:"value"`
+	}
+	request := map[string]string{"alg": "ES256K", "value": base64.RawURLEncoding.EncodeToString(payload)}
+	if err := p.call(ctx, http.MethodPost, key.kid+"/sign", request, &response); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(response.Value, "="))
+	if err != nil || len(signature) != 64 {
+		return nil, fmt.Errorf("Key Vault returned an invalid signature")
+	}
+	return NormalizeLowS(signature)
+}
+
+// PublicKey returns the public key of the key of the signer address in options, checking that it derives the
+// address.
+func (p *azureProvider) PublicKey(ctx context.Context, options *ProviderOption) (*ecdsa.PublicKey, error) {
+	key, err := p.resolve(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return key.publicKey, nil
+}
+
+// resolve returns the key of the signer address in options, fetching it once, and checks that it is the key
+// of the address: never sign under the wrong identity.
+func (p *azureProvider) resolve(ctx context.Context, options *ProviderOption) (azureResolvedKey, error) {
+	address, err := signerAddress(options)
+	if err != nil {
+		return azureResolvedKey{}, err
+	}
+	key := p.keyOf(address)
+
+	p.mu.Lock()
+	resolved, ok := p.resolved[key]
+	p.mu.Unlock()
+
+	if !ok {
+		var response struct {
+			Key struct {
+				Kid string `json:"kid"`
+				Kty string `json:"kty"`
+				Crv string `json:"crv"`
+				X   string `json:"x"`
+				Y   string `json:"y"`
+			} `json:"key"`
+		}
+		path := p.vaultURL + "/keys/" + url.PathEscape(key.Name)
+		if key.Version != "" {
+			path += "/" + url.PathEscape(key.Version)
+		}
+		if err := p.call(ctx, http.MethodGet, path, nil, &response); err != nil {
+			return azureResolvedKey{}, err
+		}
+
+		jwk := response.Key
+		if (jwk.Kty != "EC" && jwk.Kty != "EC-HSM") || jwk.Crv != "P-256K" {
+			return azureResolvedKey{}, fmt.Errorf("Key Vault key %s is %s %s, want an EC key on P-256K", key.Name, jwk.Kty, jwk.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil || len(x) > 32 || len(y) > 32 {
+			return azureResolvedKey{}, fmt.Errorf("Key Vault key %s has invalid coordinates", key.Name)
+		}
+		publicKey := &ecdsa.PublicKey{Curve: crypto.S256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return azureResolvedKey{}, fmt.Errorf("Key Vault key %s is not on secp256k1", key.Name)
+		}
+		if !strings.HasPrefix(jwk.Kid, p.vaultURL+"/keys/") {
+			return azureResolvedKey{}, fmt.Errorf("Key Vault key %s has unexpected id %q", key.Name, jwk.Kid)
+		}
+
+		resolved = azureResolvedKey{kid: jwk.Kid, publicKey: publicKey}
+		p.mu.Lock()
+		p.resolved[key] = resolved
+		p.mu.Unlock()
+	}
+
+	if !ethaddr.Equal(crypto.PubkeyToAddress(*resolved.publicKey).Hex(), address) {
+		return azureResolvedKey{}, fmt.Errorf("%w: %s is not the key of %s", ErrKeyMismatch, key.Name, address)
+	}
+	return resolved, nil
+}
+
+// keyOf returns the key of a normalized signer address.
+func (p *azureProvider) keyOf(address string) AzureKey {
+	if key, ok := p.keys[address]; ok {
+		return key
+	}
+	if p.defaultKey != nil {
+		return *p.defaultKey
+	}
+	return AzureKey{Name: defaultAzureKeyPrefix + address}
+}
+
+// call invokes a Key Vault REST operation on endpoint, retrying like the Vault client.
+func (p *azureProvider) call(ctx context.Context, method, endpoint string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		status, respBody, err := p.send(ctx, method, endpoint, body)
+		retryable := status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable ||
+			(err != nil && ctx.Err() == nil && vault.IsTransient(err))
+		if retryable && attempt < p.maxRetries {
+			if err := vault.Backoff(ctx, p.clock, attempt); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if status != http.StatusOK {
+			var azureErr struct {
+				Error struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			_ = json.Unmarshal(respBody, &azureErr)
+			return fmt.Errorf("Key Vault request failed with status %d: %s %s", status, azureErr.Error.Code, azureErr.Error.Message)
+		}
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to decode Key Vault response: %w", err)
+		}
+		return nil
+	}
+}
+
+// send sends one Key Vault request with a fresh access token and reads the whole answer.
+func (p *azureProvider) send(ctx context.Context, method, endpoint string, body []byte) (int, []byte, error) {
+	token, err := p.tokens.Token(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get a Key Vault access token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint+"?api-version="+azureAPIVersion, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxAzureResponseSize))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// managedIdentityTokens gets Key Vault access tokens for the managed identity of the host.
+type managedIdentityTokens struct {
+	clientID   string
+	httpClient *http.Client
+	clock      clock.Clock
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// AzureManagedIdentity returns a token source for the managed identity of the host: the system-assigned
+// identity, or the user-assigned one with the given client id. It asks the App Service identity endpoint
+// when IDENTITY_ENDPOINT and IDENTITY_HEADER are set, the Instance Metadata Service otherwise. Tokens are
+// cached until a minute before they expire. client may be nil for a client with a 10s timeout, c nil for
+// the system clock.
+func AzureManagedIdentity(clientID string, client *http.Client, c clock.Clock) TokenSource {
+	if client == nil {
+		client = &http.Client{Timeout: defaultAzureTimeout}
+	}
+	return &managedIdentityTokens{clientID: clientID, httpClient: client, clock: clock.OrSystem(c)}
+}
+
+// Token returns the cached access token, or requests a new one.
+func (m *managedIdentityTokens) Token(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	if m.token != "" && now.Before(m.expires.Add(-time.Minute)) {
+		return m.token, nil
+	}
+
+	query := url.Values{"resource": {azureKeyVaultResource}}
+	endpoint, header := os.Getenv("IDENTITY_ENDPOINT"), os.Getenv("IDENTITY_HEADER")
+	if endpoint != "" && header != "" {
+		query.Set("api-version", "2019-08-01")
+	} else {
+		endpoint, header = azureIMDSEndpoint, ""
+		query.Set("api-version", "2018-02-01")
+	}
+	if m.clientID != "" {
+		query.Set("client_id", m.clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	if header != "" {
+		req.Header.Set("X-IDENTITY-HEADER", header)
+	} else {
+		req.Header.Set("Metadata", "true")
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send token request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("managed identity endpoint returned status %d", resp.StatusCode)
+	}
+
+	// Both endpoints send the numbers as strings; expires_on is a Unix time.
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxAzureResponseSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("managed identity endpoint returned no access token")
+	}
+	expiresOn, err := strconv.ParseInt(token.ExpiresOn, 10, 64)
+	if err != nil {
+		expiresOn = now.Add(5 * time.Minute).Unix() // Unknown expiry: refresh soon
+	}
+
+	m.token = token.AccessToken
+	m.expires = time.Unix(expiresOn, 0)
+	return m.token, nil
+}
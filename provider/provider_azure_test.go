@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+func TestAzureKeyVaultProvider(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	var signRequests atomic.Int32
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer azure-token" || r.URL.Query().Get("api-version") != azureAPIVersion {
+			t.Errorf("unexpected request %s %s", r.Header.Get("Authorization"), r.URL)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /keys/holder/v1":
+			point := crypto.FromECDSAPub(&key.PublicKey)
+			_ = json.NewEncoder(w).Encode(map[string]any{"key": map[string]string{
+				"kid": srv.URL + "/keys/holder/v1",
+				"kty": "EC-HSM",
+				"crv": "P-256K",
+				"x":   base64.RawURLEncoding.EncodeToString(point[1:33]),
+				"y":   base64.RawURLEncoding.EncodeToString(point[33:]),
+			}})
+		case "POST /keys/holder/v1/sign":
+			if signRequests.Add(1) == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			var in struct{ Alg, Value string }
+			_ = json.NewDecoder(r.Body).Decode(&in)
+			digest, _ := base64.RawURLEncoding.DecodeString(in.Value)
+			signature, _ := crypto.Sign(digest, key)
+			if in.Alg != "ES256K" {
+				t.Errorf("unexpected algorithm %q", in.Alg)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"value": base64.RawURLEncoding.EncodeToString(signature[:64])})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"KeyNotFound","message":"key not found"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	tokens := TokenSourceFunc(func(ctx context.Context) (string, error) { return "azure-token", nil })
+	fake := clock.NewFake(time.Now())
+	p, err := NewAzureKeyVaultProvider(srv.URL,
+		WithAzureTokenSource(tokens), WithAzureHTTPClient(srv.Client()), WithAzureClock(fake),
+		WithAzureKeys(map[string]AzureKey{address: {Name: "holder", Version: "v1"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for fake.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		fake.Advance(time.Minute)
+	}()
+
+	digest := crypto.Keccak256([]byte("payload"))
+	for range 2 {
+		signature, err := p.Sign(context.Background(), digest, &ProviderOption{Signer: address})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(signature) != 64 || !crypto.VerifySignature(crypto.FromECDSAPub(&key.PublicKey), digest, signature) {
+			t.Fatalf("invalid signature %x", signature)
+		}
+	}
+	if n := signRequests.Load(); n != 3 {
+		t.Errorf("%d sign requests, want the throttled one retried once", n)
+	}
+
+	if _, err := p.Sign(context.Background(), digest, &ProviderOption{Signer: "0x" + strings.Repeat("00", 20)}); err == nil || !strings.Contains(err.Error(), "KeyNotFound") {
+		t.Errorf("expected the missing key to be reported, got %v", err)
+	}
+	other, _ := crypto.GenerateKey()
+	otherAddress := crypto.PubkeyToAddress(other.PublicKey).Hex()
+	mismatched, _ := NewAzureKeyVaultProvider(srv.URL,
+		WithAzureTokenSource(tokens), WithAzureHTTPClient(srv.Client()), WithAzureKey(AzureKey{Name: "holder", Version: "v1"}))
+	if _, err := mismatched.Sign(context.Background(), digest, &ProviderOption{Signer: otherAddress}); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("expected ErrKeyMismatch, got %v", err)
+	}
+
+	if _, err := NewAzureKeyVaultProvider("http://my-vault.vault.azure.net"); err == nil {
+		t.Error("expected a plain HTTP vault URL to be rejected")
+	}
+}
+
+func TestAzureManagedIdentity(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		q := r.URL.Query()
+		if r.Header.Get("X-IDENTITY-HEADER") != "secret" || q.Get("resource") != azureKeyVaultResource || q.Get("client_id") != "client" {
+			t.Errorf("unexpected token request %v %v", r.Header, q)
+		}
+		_, _ = w.Write([]byte(`{"access_token":"azure-token","expires_on":"` + strconv.FormatInt(time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC).Unix(), 10) + `"}`))
+	}))
+	defer srv.Close()
+	t.Setenv("IDENTITY_ENDPOINT", srv.URL)
+	t.Setenv("IDENTITY_HEADER", "secret")
+
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	tokens := AzureManagedIdentity("client", nil, fake)
+	for range 2 {
+		if token, err := tokens.Token(context.Background()); err != nil || token != "azure-token" {
+			t.Fatalf("Token = %q, %v", token, err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%d token requests, want the token cached", n)
+	}
+	fake.Advance(time.Hour)
+	if _, err := tokens.Token(context.Background()); err != nil || requests.Load() != 2 {
+		t.Errorf("expired token not refreshed: %v, %d requests", err, requests.Load())
+	}
+}
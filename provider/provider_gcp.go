@@ -0,0 +1,407 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/ethaddr"
+)
+
+const (
+	// gcpKeyAlgorithm is the Cloud KMS algorithm of secp256k1 signing keys.
+	gcpKeyAlgorithm = "EC_SIGN_SECP256K1_SHA256"
+	// gcpKMSScope is the OAuth 2.0 scope needed to call Cloud KMS.
+	gcpKMSScope = "https://www.googleapis.com/auth/cloudkms"
+
+	defaultGCPEndpoint = "https://cloudkms.googleapis.com"
+	defaultGCPTimeout  = 10 * time.Second
+	maxGCPResponseSize = 1 << 20
+)
+
+// GCPKeyVersion names a Cloud KMS key version.
+type GCPKeyVersion struct {
+	Project  string
+	Location string
+	KeyRing  string
+	Key      string
+	Version  string
+}
+
+// Name returns the resource name of the key version,
+// "projects/{project}/locations/{location}/keyRings/{keyRing}/cryptoKeys/{key}/cryptoKeyVersions/{version}".
+func (k GCPKeyVersion) Name() string {
+	return "projects/" + k.Project + "/locations/" + k.Location + "/keyRings/" + k.KeyRing +
+		"/cryptoKeys/" + k.Key + "/cryptoKeyVersions/" + k.Version
+}
+
+// ParseGCPKeyVersion parses the resource name of a Cloud KMS key version, see GCPKeyVersion.Name.
+func ParseGCPKeyVersion(name string) (GCPKeyVersion, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 10 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "keyRings" ||
+		parts[6] != "cryptoKeys" || parts[8] != "cryptoKeyVersions" {
+		return GCPKeyVersion{}, fmt.Errorf("invalid Cloud KMS key version name %q", name)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return GCPKeyVersion{}, fmt.Errorf("invalid Cloud KMS key version name %q", name)
+		}
+	}
+	return GCPKeyVersion{Project: parts[1], Location: parts[3], KeyRing: parts[5], Key: parts[7], Version: parts[9]}, nil
+}
+
+// GCPOpt configures the provider created by NewGCPKMSProvider.
+type GCPOpt func(*gcpProvider)
+
+// WithGCPTokenSource sets where the access tokens used to call Cloud KMS come from, see GCPServiceAccount.
+func WithGCPTokenSource(tokens TokenSource) GCPOpt {
+	return func(p *gcpProvider) {
+		p.tokens = tokens
+	}
+}
+
+// WithGCPKeyVersion sets the key version used for addresses missing from WithGCPKeys, for deployments
+// signing with a single key.
+func WithGCPKeyVersion(key GCPKeyVersion) GCPOpt {
+	return func(p *gcpProvider) {
+		p.defaultKey = key.Name()
+	}
+}
+
+// WithGCPKeys maps signer addresses to Cloud KMS key versions.
+func WithGCPKeys(keys map[string]GCPKeyVersion) GCPOpt {
+	return func(p *gcpProvider) {
+		for address, key := range keys {
+			if normalized, err := ethaddr.Normalize(address); err == nil {
+				p.keys[normalized] = key.Name()
+			}
+		}
+	}
+}
+
+// WithGCPEndpoint replaces the Cloud KMS endpoint, e.g. with a regional or private endpoint.
+func WithGCPEndpoint(endpoint string) GCPOpt {
+	return func(p *gcpProvider) {
+		p.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// WithGCPHTTPClient sets the HTTP client used to call Cloud KMS (default: a client with a 10s timeout).
+func WithGCPHTTPClient(client *http.Client) GCPOpt {
+	return func(p *gcpProvider) {
+		p.httpClient = client
+	}
+}
+
+// gcpProvider is the provider implementation that signs with Google Cloud KMS secp256k1 keys.
+type gcpProvider struct {
+	endpoint   string
+	tokens     TokenSource
+	httpClient *http.Client
+	keys       map[string]string // Normalized address to key version name
+	defaultKey string
+
+	mu         sync.Mutex
+	publicKeys map[string]*ecdsa.PublicKey // Key version name to its public key
+}
+
+// NewGCPKMSProvider creates a provider signing with Cloud KMS key versions of algorithm
+// EC_SIGN_SECP256K1_SHA256. opts are GCPOpt values; a token source and at least one key version, given
+// with WithGCPKeyVersion or WithGCPKeys, are required. The key version's public key must derive the
+// signer address passed to Sign.
+func NewGCPKMSProvider(opts ...any) (Provider, error) {
+	p := &gcpProvider{
+		endpoint:   defaultGCPEndpoint,
+		httpClient: &http.Client{Timeout: defaultGCPTimeout},
+		keys:       map[string]string{},
+		publicKeys: map[string]*ecdsa.PublicKey{},
+	}
+	for _, opt := range opts {
+		if opt, ok := opt.(GCPOpt); ok {
+			opt(p)
+		}
+	}
+
+	if p.tokens == nil {
+		return nil, errors.New("a token source is required for the Cloud KMS provider")
+	}
+	if p.defaultKey == "" && len(p.keys) == 0 {
+		return nil, errors.New("no Cloud KMS key version configured")
+	}
+	return p, nil
+}
+
+// Concurrency reports that the Cloud KMS provider is safe for concurrent use.
+func (p *gcpProvider) Concurrency() Concurrency {
+	return Concurrent
+}
+
+// Sign signs the 32-byte digest payload with the key version of the signer address in options
+// and returns r || s with a low s, like the Vault provider.
+func (p *gcpProvider) Sign(ctx context.Context, payload []byte, options *ProviderOption) ([]byte, error) {
+	if len(payload) != 32 {
+		return nil, fmt.Errorf("payload must be 32 bytes")
+	}
+	// Checks, once per key, that it is the key of the address: never sign under the wrong identity.
+	if _, err := p.PublicKey(ctx, options); err != nil {
+		return nil, err
+	}
+	address, _ := signerAddress(options) // Validated by PublicKey
+	name, _ := p.keyOf(address)
+
+	var response struct {
+		Signature []byte `json:"signature"`
+	}
+	request := map[string]any{"digest": map[string][]byte{"sha256": payload}}
+	if err := p.call(ctx, http.MethodPost, name+":asymmetricSign", request, &response); err != nil {
+		return nil, err
+	}
+	return derToRawSignature(response.Signature)
+}
+
+// PublicKey returns the public key of the key version of the signer address in options, checking that it
+// derives the address.
+func (p *gcpProvider) PublicKey(ctx context.Context, options *ProviderOption) (*ecdsa.PublicKey, error) {
+	address, err := signerAddress(options)
+	if err != nil {
+		return nil, err
+	}
+	name, err := p.keyOf(address)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	publicKey, ok := p.publicKeys[name]
+	p.mu.Unlock()
+
+	if !ok {
+		var response struct {
+			PEM       string `json:"pem"`
+			Algorithm string `json:"algorithm"`
+		}
+		if err := p.call(ctx, http.MethodGet, name+"/publicKey", nil, &response); err != nil {
+			return nil, err
+		}
+		if response.Algorithm != gcpKeyAlgorithm {
+			return nil, fmt.Errorf("Cloud KMS key %s has algorithm %s, want %s", name, response.Algorithm, gcpKeyAlgorithm)
+		}
+		block, _ := pem.Decode([]byte(response.PEM))
+		if block == nil {
+			return nil, fmt.Errorf("Cloud KMS key %s: public key is not PEM", name)
+		}
+		publicKey, err = parseSecp256k1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Cloud KMS key %s: %w", name, err)
+		}
+
+		p.mu.Lock()
+		p.publicKeys[name] = publicKey
+		p.mu.Unlock()
+	}
+
+	if !ethaddr.Equal(ethcrypto.PubkeyToAddress(*publicKey).Hex(), address) {
+		return nil, fmt.Errorf("%w: %s is not the key of %s", ErrKeyMismatch, name, address)
+	}
+	return publicKey, nil
+}
+
+// keyOf returns the key version name of a normalized signer address.
+func (p *gcpProvider) keyOf(address string) (string, error) {
+	if name, ok := p.keys[address]; ok {
+		return name, nil
+	}
+	if p.defaultKey != "" {
+		return p.defaultKey, nil
+	}
+	return "", fmt.Errorf("no Cloud KMS key version configured for %s", address)
+}
+
+// call invokes a Cloud KMS REST method on a resource path, e.g. "projects/.../cryptoKeyVersions/1/publicKey".
+func (p *gcpProvider) call(ctx context.Context, method, path string, in, out any) error {
+	token, err := p.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a Cloud KMS access token: %w", err)
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.endpoint+"/v1/"+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxGCPResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var gcpErr struct {
+			Error struct {
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(respBody, &gcpErr)
+		return fmt.Errorf("Cloud KMS %s failed with status %d: %s %s", path, resp.StatusCode, gcpErr.Error.Status, gcpErr.Error.Message)
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode Cloud KMS response: %w", err)
+	}
+	return nil
+}
+
+// serviceAccountKey holds the fields of a service account key file used to get access tokens.
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// serviceAccountTokens exchanges JWTs signed with a service account key for access tokens (RFC 7523).
+type serviceAccountTokens struct {
+	key        serviceAccountKey
+	signer     *rsa.PrivateKey
+	httpClient *http.Client
+	clock      clock.Clock
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// GCPServiceAccount returns a token source for the service account whose JSON key file is keyJSON, as
+// pointed to by GOOGLE_APPLICATION_CREDENTIALS. Tokens are cached until a minute before they expire.
+// client may be nil for a client with a 10s timeout, c nil for the system clock.
+func GCPServiceAccount(keyJSON []byte, client *http.Client, c clock.Clock) (TokenSource, error) {
+	var key serviceAccountKey
+	if err := json.Unmarshal(keyJSON, &key); err != nil {
+		return nil, fmt.Errorf("invalid service account key file: %w", err)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.TokenURI == "" {
+		return nil, errors.New("invalid service account key file: not a service account key")
+	}
+
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, errors.New("invalid service account key file: private key is not PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account private key: %w", err)
+	}
+	signer, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("service account private key must be RSA, got %T", parsed)
+	}
+
+	if client == nil {
+		client = &http.Client{Timeout: defaultGCPTimeout}
+	}
+	return &serviceAccountTokens{key: key, signer: signer, httpClient: client, clock: clock.OrSystem(c)}, nil
+}
+
+// Token returns the cached access token, or requests a new one.
+func (s *serviceAccountTokens) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if s.token != "" && now.Before(s.expires.Add(-time.Minute)) {
+		return s.token, nil
+	}
+
+	assertion, err := s.assertion(now)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send token request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxGCPResponseSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token endpoint returned no access token")
+	}
+
+	s.token = token.AccessToken
+	s.expires = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// assertion returns the RS256 JWT asking the token endpoint for a Cloud KMS access token.
+func (s *serviceAccountTokens) assertion(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.key.PrivateKeyID})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   s.key.ClientEmail,
+		"scope": gcpKMSScope,
+		"aud":   s.key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.signer, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token assertion: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// newServiceAccountKey returns a service account key file with a fresh RSA key.
+func newServiceAccountKey(t *testing.T, tokenURI string) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	keyJSON, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "signer@project.iam.gserviceaccount.com",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenURI,
+	})
+	return keyJSON
+}
+
+func TestGCPKMSProvider(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	keyVersion := GCPKeyVersion{Project: "p", Location: "global", KeyRing: "r", Key: "holder", Version: "1"}
+
+	point := crypto.FromECDSAPub(&key.PublicKey)
+	var spki subjectPublicKeyInfo
+	spki.Algorithm.Algorithm, spki.Algorithm.Parameters = oidECPublicKey, oidSecp256k1
+	spki.PublicKey = asn1.BitString{Bytes: point, BitLength: 8 * len(point)}
+	publicKeyDER, _ := asn1.Marshal(spki)
+
+	var tokenRequests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests.Add(1)
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || strings.Count(r.FormValue("assertion"), ".") != 2 {
+			t.Errorf("unexpected token request: %v", r.Form)
+		}
+		_, _ = w.Write([]byte(`{"access_token":"ya29.token","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v1/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ya29.token" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/v1/" + keyVersion.Name() + "/publicKey":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"algorithm": gcpKeyAlgorithm,
+				"pem":       string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER})),
+			})
+		case "/v1/" + keyVersion.Name() + ":asymmetricSign":
+			var in struct {
+				Digest struct{ SHA256 []byte }
+			}
+			_ = json.NewDecoder(r.Body).Decode(&in)
+			signature, _ := crypto.Sign(in.Digest.SHA256, key)
+			der, _ := asn1.Marshal(ecdsaSignature{R: new(big.Int).SetBytes(signature[:32]), S: new(big.Int).SetBytes(signature[32:64])})
+			_ = json.NewEncoder(w).Encode(map[string]any{"signature": der})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"status":"NOT_FOUND","message":"key not found"}}`))
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fake := clock.NewFake(time.Now())
+	tokens, err := GCPServiceAccount(newServiceAccountKey(t, srv.URL+"/token"), nil, fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewGCPKMSProvider(WithGCPTokenSource(tokens), WithGCPEndpoint(srv.URL), WithGCPKeys(map[string]GCPKeyVersion{address: keyVersion}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := crypto.Keccak256([]byte("payload"))
+	for range 2 {
+		signature, err := p.Sign(context.Background(), digest, &ProviderOption{Signer: address})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(signature) != 64 || !crypto.VerifySignature(crypto.FromECDSAPub(&key.PublicKey), digest, signature) {
+			t.Fatalf("invalid signature %x", signature)
+		}
+	}
+	if n := tokenRequests.Load(); n != 1 {
+		t.Errorf("%d token requests, want the token cached", n)
+	}
+	fake.Advance(time.Hour)
+	if _, err := p.Sign(context.Background(), digest, &ProviderOption{Signer: address}); err != nil || tokenRequests.Load() != 2 {
+		t.Errorf("expired token not refreshed: %v, %d requests", err, tokenRequests.Load())
+	}
+
+	if _, err := p.Sign(context.Background(), digest, &ProviderOption{Signer: "0x" + strings.Repeat("00", 20)}); err == nil {
+		t.Error("signed for an address without a key version")
+	}
+	other, _ := crypto.GenerateKey()
+	otherAddress := crypto.PubkeyToAddress(other.PublicKey).Hex()
+	mismatched, _ := NewGCPKMSProvider(WithGCPTokenSource(tokens), WithGCPEndpoint(srv.URL), WithGCPKeyVersion(keyVersion))
+	if _, err := mismatched.Sign(context.Background(), digest, &ProviderOption{Signer: otherAddress}); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("expected ErrKeyMismatch, got %v", err)
+	}
+
+	if parsed, err := ParseGCPKeyVersion(keyVersion.Name()); err != nil || parsed != keyVersion {
+		t.Errorf("ParseGCPKeyVersion = %+v, %v", parsed, err)
+	}
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/ethaddr"
+	"github/hovanhoa/go-vc-auth/vault"
+)
+
+// LocalProvider signs with a secp256k1 private key held in memory, so the full CreateToken/VerifyToken
+// flow runs without a Vault deployment. It is meant for development and tests: the key is only as safe
+// as the process memory. A LocalProvider is safe for concurrent use.
+type LocalProvider struct {
+	key     *ecdsa.PrivateKey
+	address string // Normalized address of key
+}
+
+var (
+	_ Provider          = (*LocalProvider)(nil)
+	_ PublicKeyExporter = (*LocalProvider)(nil)
+)
+
+// NewLocalProvider creates a LocalProvider signing with privateKey: a go-ethereum *ecdsa.PrivateKey, or
+// 32 raw bytes, a hex string (with or without 0x) or a secret.Secret holding either.
+func NewLocalProvider(privateKey any) (*LocalProvider, error) {
+	key, ok := privateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		parsed, err := vault.ParsePrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		key = parsed
+	} else if key == nil || key.Curve != crypto.S256() {
+		return nil, fmt.Errorf("private key must be a secp256k1 key")
+	}
+
+	address, err := ethaddr.Normalize(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	if err != nil {
+		return nil, err
+	}
+	return &LocalProvider{key: key, address: address}, nil
+}
+
+// Address returns the address of the key, to pass as the signer address and to build the holder DID.
+func (p *LocalProvider) Address() string {
+	return p.address
+}
+
+// Concurrency reports that the local provider is safe for concurrent use.
+func (p *LocalProvider) Concurrency() Concurrency {
+	return Concurrent
+}
+
+// Sign signs the 32-byte digest payload and returns r || s with a low s, byte for byte what the Vault
+// ethsign plugin returns for the same key and digest. The signer address, when given in options like for
+// the Vault provider, must be the key's address.
+func (p *LocalProvider) Sign(ctx context.Context, payload []byte, options *ProviderOption) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.checkSigner(options); err != nil {
+		return nil, err
+	}
+	if len(payload) != 32 {
+		return nil, fmt.Errorf("payload must be 32 bytes")
+	}
+
+	signature, err := crypto.Sign(payload, p.key)
+	if err != nil {
+		return nil, err
+	}
+	return signature[:64], nil
+}
+
+// PublicKey returns the public key of the provider's key.
+func (p *LocalProvider) PublicKey(ctx context.Context, options *ProviderOption) (*ecdsa.PublicKey, error) {
+	if err := p.checkSigner(options); err != nil {
+		return nil, err
+	}
+	return &p.key.PublicKey, nil
+}
+
+// checkSigner checks that the signer address in options, if any, is the key's address.
+func (p *LocalProvider) checkSigner(options *ProviderOption) error {
+	if options.signer() == "" {
+		return nil
+	}
+	address, err := signerAddress(options)
+	if err != nil {
+		return err
+	}
+	if address != p.address {
+		return fmt.Errorf("local provider holds the key of %s, not %s", p.address, address)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package provider_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+func TestLocalProvider(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	local, err := provider.NewLocalProvider("0x" + hex.EncodeToString(crypto.FromECDSA(key)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	if local.Address() != strings.ToLower(address) {
+		t.Fatalf("Address = %s, want %s", local.Address(), address)
+	}
+
+	// A fake ethsign plugin answering like Vault: the 65-byte go-ethereum signature, hex encoded.
+	ethsign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Payload string }
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		digest, _ := hex.DecodeString(strings.TrimPrefix(req.Payload, "0x"))
+		signature, _ := crypto.Sign(digest, key)
+		_, _ = w.Write([]byte(`{"data":{"signature":"0x` + hex.EncodeToString(signature) + `"}}`))
+	}))
+	defer ethsign.Close()
+	vaultProvider := provider.NewVaultProvider(ethsign.URL, "token", 0)
+
+	digest := crypto.Keccak256([]byte("payload"))
+	signature, err := local.Sign(context.Background(), digest, &provider.ProviderOption{Signer: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromVault, err := vaultProvider.Sign(context.Background(), digest, &provider.ProviderOption{Signer: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(signature, fromVault) {
+		t.Fatalf("local signature %x differs from Vault's %x", signature, fromVault)
+	}
+	if !provider.IsLowS(signature) || !crypto.VerifySignature(crypto.FromECDSAPub(&key.PublicKey), digest, signature) {
+		t.Fatal("signature does not verify with a low s")
+	}
+
+	if _, err := local.Sign(context.Background(), digest, &provider.ProviderOption{Signer: "0x" + strings.Repeat("00", 20)}); err == nil {
+		t.Error("signed for another address")
+	}
+	if publicKey, err := local.PublicKey(context.Background(), nil); err != nil || !publicKey.Equal(&key.PublicKey) {
+		t.Errorf("PublicKey = %v, %v", publicKey, err)
+	}
+	if fromKey, err := provider.NewLocalProvider(key); err != nil || fromKey.Address() != local.Address() {
+		t.Errorf("NewLocalProvider(*ecdsa.PrivateKey) = %v, %v", fromKey, err)
+	}
+	if _, err := provider.NewLocalProvider("0x1234"); err == nil {
+		t.Error("accepted a short key")
+	}
+}
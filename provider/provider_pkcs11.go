@@ -0,0 +1,207 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/ethaddr"
+	"github/hovanhoa/go-vc-auth/secret"
+)
+
+// defaultPKCS11LabelPrefix starts the label under which the key of an address is looked up by default.
+const defaultPKCS11LabelPrefix = "vc-auth-"
+
+// ErrPKCS11Unsupported is returned by NewPKCS11Provider when the module was built without cgo or the
+// pkcs11 build tag.
+var ErrPKCS11Unsupported = errors.New("PKCS#11 support requires cgo and the pkcs11 build tag")
+
+// PKCS11Opt configures the provider created by NewPKCS11Provider.
+type PKCS11Opt func(*pkcs11Provider)
+
+// WithPKCS11Keys maps signer addresses to the CKA_LABEL of their key pairs on the token.
+func WithPKCS11Keys(labels map[string]string) PKCS11Opt {
+	return func(p *pkcs11Provider) {
+		for address, label := range labels {
+			if normalized, err := ethaddr.Normalize(address); err == nil {
+				p.labels[normalized] = label
+			}
+		}
+	}
+}
+
+// WithPKCS11KeyLabel sets the label of the key pair used for addresses missing from WithPKCS11Keys, for
+// deployments signing with a single key. Without it, the key of an address is labelled "vc-auth-<address>".
+func WithPKCS11KeyLabel(label string) PKCS11Opt {
+	return func(p *pkcs11Provider) {
+		p.defaultLabel = label
+	}
+}
+
+// pkcs11Token is a logged-in session on a PKCS#11 token. Its methods are not safe for concurrent use.
+type pkcs11Token interface {
+	// ecPoint returns the CKA_EC_POINT of the public key labelled label.
+	ecPoint(label string) ([]byte, error)
+	// sign signs digest with CKM_ECDSA and the private key labelled label, returning r || s.
+	sign(label string, digest []byte) ([]byte, error)
+	// close logs out, closes the session and unloads the module.
+	close() error
+}
+
+// pkcs11Provider is the provider implementation that signs with secp256k1 key pairs on an HSM.
+type pkcs11Provider struct {
+	labels       map[string]string // Normalized address to key label
+	defaultLabel string
+
+	mu         sync.Mutex // Serializes use of token, whose session is single-threaded
+	token      pkcs11Token
+	publicKeys map[string]*ecdsa.PublicKey // Label to public key
+}
+
+// NewPKCS11Provider loads the PKCS#11 module at modulePath, e.g. a vendor HSM library or SoftHSM, and
+// logs in to the token in slot with pin. opts are PKCS11Opt values. Keys are EC key pairs on secp256k1
+// whose public and private objects share a label; the public key must derive the signer address passed
+// to Sign. Close logs out and unloads the module.
+//
+// PKCS#11 needs cgo: the module must be built with the pkcs11 tag, otherwise ErrPKCS11Unsupported is
+// returned.
+func NewPKCS11Provider(modulePath string, slot uint, pin secret.Secret, opts ...any) (Provider, error) {
+	token, err := openPKCS11(modulePath, slot, pin)
+	if err != nil {
+		return nil, err
+	}
+	return newPKCS11Provider(token, opts), nil
+}
+
+// newPKCS11Provider creates the provider over an open token.
+func newPKCS11Provider(token pkcs11Token, opts []any) *pkcs11Provider {
+	p := &pkcs11Provider{
+		labels:     map[string]string{},
+		token:      token,
+		publicKeys: map[string]*ecdsa.PublicKey{},
+	}
+	for _, opt := range opts {
+		if opt, ok := opt.(PKCS11Opt); ok {
+			opt(p)
+		}
+	}
+	return p
+}
+
+// Concurrency reports that the provider is safe for concurrent use: it serializes access to its session.
+func (p *pkcs11Provider) Concurrency() Concurrency {
+	return Concurrent
+}
+
+// Sign signs the 32-byte digest payload with the key of the signer address in options and
+// returns r || s with a low s, like the Vault provider. ctx is only checked before signing: a PKCS#11
+// call cannot be interrupted.
+func (p *pkcs11Provider) Sign(ctx context.Context, payload []byte, options *ProviderOption) ([]byte, error) {
+	if len(payload) != 32 {
+		return nil, fmt.Errorf("payload must be 32 bytes")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	label, err := p.resolve(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	signature, err := p.token.sign(label, payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) != 64 {
+		return nil, fmt.Errorf("PKCS#11 token returned a %d-byte signature", len(signature))
+	}
+	return NormalizeLowS(signature)
+}
+
+// PublicKey returns the public key of the key of the signer address in options, checking that it derives the
+// address.
+func (p *pkcs11Provider) PublicKey(ctx context.Context, options *ProviderOption) (*ecdsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	label, err := p.resolve(options)
+	if err != nil {
+		return nil, err
+	}
+	return p.publicKeys[label], nil
+}
+
+// Close logs out of the token and unloads the module.
+func (p *pkcs11Provider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token == nil {
+		return nil
+	}
+	err := p.token.close()
+	p.token = nil
+	return err
+}
+
+// resolve returns the label of the key of the signer address in options, reading its public key once, and
+// checks that it is the key of the address: never sign under the wrong identity. p.mu must be held.
+func (p *pkcs11Provider) resolve(options *ProviderOption) (string, error) {
+	if p.token == nil {
+		return "", errors.New("PKCS#11 provider is closed")
+	}
+	address, err := signerAddress(options)
+	if err != nil {
+		return "", err
+	}
+
+	label, ok := p.labels[address]
+	if !ok {
+		label = p.defaultLabel
+	}
+	if label == "" {
+		label = defaultPKCS11LabelPrefix + address
+	}
+
+	publicKey, ok := p.publicKeys[label]
+	if !ok {
+		point, err := p.token.ecPoint(label)
+		if err != nil {
+			return "", err
+		}
+		if publicKey, err = parseECPoint(point); err != nil {
+			return "", fmt.Errorf("PKCS#11 key %s: %w", label, err)
+		}
+		p.publicKeys[label] = publicKey
+	}
+
+	if !ethaddr.Equal(crypto.PubkeyToAddress(*publicKey).Hex(), address) {
+		return "", fmt.Errorf("%w: %s is not the key of %s", ErrKeyMismatch, label, address)
+	}
+	return label, nil
+}
+
+// parseECPoint parses a CKA_EC_POINT: a DER OCTET STRING holding the uncompressed secp256k1 point, or the
+// bare point as some tokens return it.
+func parseECPoint(value []byte) (*ecdsa.PublicKey, error) {
+	point := value
+	if len(value) != 65 { // A bare uncompressed point also starts with 0x04, the OCTET STRING tag
+		if rest, err := asn1.Unmarshal(value, &point); err != nil || len(rest) > 0 {
+			return nil, errors.New("invalid EC point encoding")
+		}
+	}
+	publicKey, err := crypto.UnmarshalPubkey(point)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secp256k1 EC point: %w", err)
+	}
+	return publicKey, nil
+}
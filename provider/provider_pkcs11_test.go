@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/secret"
+)
+
+// fakePKCS11Token holds key pairs in memory by label.
+type fakePKCS11Token struct {
+	keys   map[string]*ecdsa.PrivateKey
+	closed bool
+}
+
+func (t *fakePKCS11Token) ecPoint(label string) ([]byte, error) {
+	key, ok := t.keys[label]
+	if !ok {
+		return nil, fmt.Errorf("PKCS#11 key %s not found", label)
+	}
+	return asn1.Marshal(crypto.FromECDSAPub(&key.PublicKey))
+}
+
+func (t *fakePKCS11Token) sign(label string, digest []byte) ([]byte, error) {
+	signature, err := crypto.Sign(digest, t.keys[label])
+	if err != nil {
+		return nil, err
+	}
+	return signature[:64], nil
+}
+
+func (t *fakePKCS11Token) close() error {
+	t.closed = true
+	return nil
+}
+
+func TestPKCS11Provider(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	other, _ := crypto.GenerateKey()
+	otherAddress := crypto.PubkeyToAddress(other.PublicKey).Hex()
+
+	token := &fakePKCS11Token{keys: map[string]*ecdsa.PrivateKey{"holder": key, "vc-auth-" + strings.ToLower(otherAddress): other}}
+	p := newPKCS11Provider(token, []any{WithPKCS11Keys(map[string]string{address: "holder"})})
+
+	digest := crypto.Keccak256([]byte("payload"))
+	for _, tc := range []struct {
+		address string
+		key     *ecdsa.PrivateKey
+	}{{address, key}, {otherAddress, other}} {
+		signature, err := p.Sign(context.Background(), digest, &ProviderOption{Signer: tc.address})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !crypto.VerifySignature(crypto.FromECDSAPub(&tc.key.PublicKey), digest, signature) {
+			t.Fatalf("invalid signature %x", signature)
+		}
+	}
+
+	mismatched := newPKCS11Provider(token, []any{WithPKCS11KeyLabel("holder")})
+	if _, err := mismatched.Sign(context.Background(), digest, &ProviderOption{Signer: otherAddress}); !errors.Is(err, ErrKeyMismatch) {
+		t.Errorf("expected ErrKeyMismatch, got %v", err)
+	}
+
+	if err := p.Close(); err != nil || !token.closed {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := p.Sign(context.Background(), digest, &ProviderOption{Signer: address}); err == nil {
+		t.Error("signed after Close")
+	}
+
+	bare, err := parseECPoint(crypto.FromECDSAPub(&key.PublicKey))
+	if err != nil || !bare.Equal(&key.PublicKey) {
+		t.Errorf("parseECPoint of a bare point = %v, %v", bare, err)
+	}
+}
+
+func TestOpenPKCS11MissingModule(t *testing.T) {
+	if _, err := openPKCS11("/nonexistent/libpkcs11.so", 0, secret.New("1234")); err == nil {
+		t.Error("expected a missing module to be rejected")
+	}
+}
@@ -0,0 +1,53 @@
+package provider_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+func TestSignFunc(t *testing.T) {
+	type ctxKey struct{}
+	var p provider.Provider = provider.SignFunc(func(ctx context.Context, payload []byte, options *provider.ProviderOption) ([]byte, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if ctx.Value(ctxKey{}) != "request" || options.Signer != "0xsigner" {
+			t.Errorf("unexpected context or options: %+v", options)
+		}
+		return append([]byte("signed:"), payload...), nil
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+	options := &provider.ProviderOption{Signer: "0xsigner"}
+	if signature, err := p.Sign(ctx, []byte("payload"), options); err != nil || !bytes.Equal(signature, []byte("signed:payload")) {
+		t.Fatalf("Sign = %q, %v", signature, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := p.Sign(cancelled, []byte("payload"), options); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNewProviderOption(t *testing.T) {
+	for _, opts := range [][]any{
+		{"0xsigner"},
+		{provider.ProviderOption{Signer: "0xsigner"}},
+		{&provider.ProviderOption{Signer: "0xsigner"}},
+	} {
+		if options, err := provider.NewProviderOption(opts...); err != nil || options.Signer != "0xsigner" {
+			t.Errorf("NewProviderOption(%v) = %+v, %v", opts, options, err)
+		}
+	}
+	if options, err := provider.NewProviderOption(); err != nil || options.Signer != "" {
+		t.Errorf("NewProviderOption() = %+v, %v", options, err)
+	}
+	if _, err := provider.NewProviderOption(42); err == nil {
+		t.Error("expected an unsupported option to be rejected")
+	}
+}
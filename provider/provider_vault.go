@@ -2,7 +2,14 @@ package provider
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net/http"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/secret"
 	"github/hovanhoa/go-vc-auth/vault"
 )
 
@@ -11,20 +18,185 @@ type vaultProvider struct {
 	vault *vault.Vault
 }
 
+// VaultOpt configures the Vault client created by NewVaultProvider.
+type VaultOpt func(*vault.Vault)
+
+// WithVaultMaxRetries sets how many times a Vault request answered with 429 or 503 is retried (default: 3).
+func WithVaultMaxRetries(n int) VaultOpt {
+	return func(v *vault.Vault) {
+		if n >= 0 {
+			v.MaxRetries = n
+		}
+	}
+}
+
+// WithVaultClock sets the time source used for the Vault client's retry backoff (default: the system clock).
+func WithVaultClock(c clock.Clock) VaultOpt {
+	return func(v *vault.Vault) {
+		v.Clock = c
+	}
+}
+
+// WithVaultRetryPolicy sets which failed Vault requests are retried and how long to wait first (default:
+// vault.ExponentialBackoff{}, honoring Retry-After).
+func WithVaultRetryPolicy(policy vault.RetryPolicy) VaultOpt {
+	return func(v *vault.Vault) {
+		v.Retry = policy
+	}
+}
+
+// WithVaultToken sets the Vault token from a secret.Secret, replacing the token string given to
+// NewVaultProvider, so the token never has to exist as a plain string in the caller's configuration.
+func WithVaultToken(token secret.Secret) VaultOpt {
+	return func(v *vault.Vault) {
+		v.Token = token
+	}
+}
+
+// WithVaultHTTPClient sets the HTTP client used to call Vault (default: a client with a 10s timeout). The
+// TLS options have no effect with a custom client; configure its Transport instead.
+func WithVaultHTTPClient(client *http.Client) VaultOpt {
+	return func(v *vault.Vault) {
+		v.SetHTTPClient(client)
+	}
+}
+
+// WithVaultMountPath sets the path the ethsign plugin is mounted at, e.g. "ethsign" for
+// /v1/ethsign/accounts (default: vault.DefaultMountPath).
+func WithVaultMountPath(path string) VaultOpt {
+	return func(v *vault.Vault) {
+		v.MountPath = path
+	}
+}
+
+// WithVaultTimeout sets the timeout of each request to Vault (default: 10s). Retries get a fresh timeout;
+// the context given to the provider bounds the whole call.
+func WithVaultTimeout(timeout time.Duration) VaultOpt {
+	return func(v *vault.Vault) {
+		v.Conn.Timeout = timeout
+	}
+}
+
+// WithVaultConnConfig tunes the timeouts and connection pool of the Vault client, e.g. a MaxIdleConnsPerHost
+// matching the signing concurrency. It replaces the timeout set by WithVaultTimeout.
+func WithVaultConnConfig(config vault.ConnConfig) VaultOpt {
+	return func(v *vault.Vault) {
+		v.Conn = config
+	}
+}
+
+// WithVaultTLSConfig sets the TLS configuration of the connections to Vault. It replaces the settings of
+// earlier TLS options; later ones add to it.
+func WithVaultTLSConfig(config *tls.Config) VaultOpt {
+	return func(v *vault.Vault) {
+		v.TLS = config.Clone()
+	}
+}
+
+// WithVaultCACert makes the client trust the CAs in pool instead of the system roots, e.g. the pool
+// returned by vault.LoadCACert for a Vault with a private CA.
+func WithVaultCACert(pool *x509.CertPool) VaultOpt {
+	return func(v *vault.Vault) {
+		vaultTLS(v).RootCAs = pool
+	}
+}
+
+// WithVaultClientCert presents cert to Vault, for mTLS or the cert auth method. Load it with
+// tls.LoadX509KeyPair.
+func WithVaultClientCert(cert tls.Certificate) VaultOpt {
+	return func(v *vault.Vault) {
+		config := vaultTLS(v)
+		config.Certificates = append(config.Certificates[:len(config.Certificates):len(config.Certificates)], cert)
+	}
+}
+
+// vaultTLS returns the TLS configuration of v, creating it when unset.
+func vaultTLS(v *vault.Vault) *tls.Config {
+	if v.TLS == nil {
+		v.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return v.TLS
+}
+
+// WithVaultAuth makes the provider log in to Vault for its token with auth, e.g. vault.KubernetesAuth,
+// and log in again before the token expires, instead of using a static token.
+func WithVaultAuth(auth vault.Authenticator) VaultOpt {
+	return func(v *vault.Vault) {
+		v.Auth = auth
+	}
+}
+
+// WithVaultTokenRenewal renews the Vault token in the background from the first request until the
+// provider is closed, logging in again with the WithVaultAuth method when renewal fails.
+func WithVaultTokenRenewal() VaultOpt {
+	return func(v *vault.Vault) {
+		v.AutoRenew = true
+	}
+}
+
+// WithVaultBackend selects the secrets engine the provider signs with (default: vault.BackendSecp). With
+// vault.BackendTransit the Signer of the provider options names the transit key instead of a signer address, and
+// signatures are ES256 over P-256, see vault.Vault.TransitSign.
+func WithVaultBackend(backend vault.Backend) VaultOpt {
+	return func(v *vault.Vault) {
+		v.Backend = backend
+	}
+}
+
 // NewVaultProvider creates a new vaultProvider instance.
-// It connects to Vault using the provided address and token and optional max retries.
-func NewVaultProvider(address, token string, maxRetries ...int) Provider {
-	return &vaultProvider{
-		vault: vault.NewVault(address, token, maxRetries...),
+// It connects to Vault using the provided address and token. opts are VaultOpt values; a bare int is
+// accepted as the max retries, like WithVaultMaxRetries. Later values override earlier ones.
+func NewVaultProvider(address, token string, opts ...any) Provider {
+	var maxRetries []int
+	var vaultOpts []VaultOpt
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case int:
+			maxRetries = []int{opt}
+		case VaultOpt:
+			vaultOpts = append(vaultOpts, opt)
+		}
+	}
+
+	v := vault.NewVault(address, token, maxRetries...)
+	for _, opt := range vaultOpts {
+		opt(v)
+	}
+
+	return &vaultProvider{vault: v}
+}
+
+// Concurrency reports that the Vault provider is safe for concurrent use.
+func (v *vaultProvider) Concurrency() Concurrency {
+	return Concurrent
+}
+
+// Algorithm returns the JWS algorithm of the configured backend: ES256 for transit, ES256K otherwise.
+func (v *vaultProvider) Algorithm() string {
+	if v.vault.Backend == vault.BackendTransit {
+		return AlgES256
 	}
+	return AlgES256K
 }
 
-// Sign signs the payload using Vault.
-func (v *vaultProvider) Sign(payload []byte, opts ...any) ([]byte, error) {
-	if len(opts) == 0 {
-		return nil, fmt.Errorf("signer address is required")
+// Close stops the token renewal and releases the connections held by the Vault client.
+func (v *vaultProvider) Close() error {
+	return v.vault.Close()
+}
+
+// Sign signs the payload using Vault, aborting the request when ctx is done.
+func (v *vaultProvider) Sign(ctx context.Context, payload []byte, options *ProviderOption) ([]byte, error) {
+	if v.vault.Backend == vault.BackendTransit {
+		name := options.signer()
+		if name == "" {
+			return nil, fmt.Errorf("transit key name is required")
+		}
+		return v.vault.TransitSign(ctx, name, payload)
 	}
 
-	signerAddress := opts[0]
-	return v.vault.SignMessage(context.Background(), payload, signerAddress.(string))
+	address, err := signerAddress(options)
+	if err != nil {
+		return nil, err
+	}
+	return v.vault.SignMessage(ctx, payload, address)
 }
@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/ethaddr"
+	"github/hovanhoa/go-vc-auth/shamir"
+)
+
+// recoverySharePrefix starts the text form of a RecoveryShare.
+const recoverySharePrefix = "vcshare1:"
+
+var (
+	// ErrShareMismatch is returned by Recovery.Add for a share of another key or backup.
+	ErrShareMismatch = errors.New("share belongs to another key backup")
+	// ErrNotEnoughShares is returned by Recovery.Key before the threshold of shares has been added.
+	ErrNotEnoughShares = errors.New("not enough shares to recover the key")
+)
+
+// RecoveryShare is one share of a holder key backed up with BackupKey, held by a guardian: a
+// trusted person or device. Fewer than Threshold shares reveal nothing about the key.
+type RecoveryShare struct {
+	Guardian  string `json:"guardian"`  // Who holds the share
+	Address   string `json:"address"`   // Address of the backed-up key, checked on recovery
+	Threshold int    `json:"threshold"` // Shares needed to recover the key
+	Share     []byte `json:"share"`     // Shamir share of the raw private key
+}
+
+// String returns the share as text, for printing, a QR code or a message to the guardian.
+func (s RecoveryShare) String() string {
+	data, _ := json.Marshal(s)
+	return recoverySharePrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseRecoveryShare parses the text form of a share returned by RecoveryShare.String.
+func ParseRecoveryShare(text string) (RecoveryShare, error) {
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(text), recoverySharePrefix)
+	if !ok {
+		return RecoveryShare{}, fmt.Errorf("not a recovery share")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return RecoveryShare{}, fmt.Errorf("invalid recovery share: %w", err)
+	}
+
+	var share RecoveryShare
+	if err := json.Unmarshal(data, &share); err != nil {
+		return RecoveryShare{}, fmt.Errorf("invalid recovery share: %w", err)
+	}
+	return share, nil
+}
+
+// BackupKey splits a holder private key into one share per guardian, any threshold of which recover
+// it with a Recovery, so the key is not lost with the device that holds it.
+func BackupKey(key *ecdsa.PrivateKey, threshold int, guardians ...string) ([]RecoveryShare, error) {
+	if key == nil {
+		return nil, fmt.Errorf("private key is required")
+	}
+	seen := map[string]bool{}
+	for _, guardian := range guardians {
+		if guardian == "" || seen[guardian] {
+			return nil, fmt.Errorf("guardians must be named and distinct")
+		}
+		seen[guardian] = true
+	}
+
+	raw := crypto.FromECDSA(key)
+	defer clear(raw)
+	parts, err := shamir.Split(raw, len(guardians), threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	shares := make([]RecoveryShare, len(guardians))
+	for i, guardian := range guardians {
+		shares[i] = RecoveryShare{Guardian: guardian, Address: address, Threshold: threshold, Share: parts[i]}
+	}
+	return shares, nil
+}
+
+// Recovery collects the shares of a backed-up key one guardian at a time and recovers the key once
+// enough have been added. It is not safe for concurrent use.
+type Recovery struct {
+	address   string
+	threshold int
+	shares    map[string][]byte // Guardian to share
+}
+
+// NewRecovery starts the recovery of the key with the given address. The address, e.g. taken from
+// the holder DID, guards against mixing shares of different keys.
+func NewRecovery(address string) *Recovery {
+	return &Recovery{address: address, shares: map[string][]byte{}}
+}
+
+// Add adds a guardian's share and returns how many more are needed. A guardian's second share
+// replaces the first.
+func (r *Recovery) Add(share RecoveryShare) (remaining int, err error) {
+	if !ethaddr.Equal(share.Address, r.address) {
+		return r.Remaining(), fmt.Errorf("%w: share is for %s, recovering %s", ErrShareMismatch, share.Address, r.address)
+	}
+	if share.Threshold < 2 || (r.threshold != 0 && share.Threshold != r.threshold) {
+		return r.Remaining(), fmt.Errorf("%w: threshold %d", ErrShareMismatch, share.Threshold)
+	}
+	if len(share.Share) < 2 {
+		return r.Remaining(), fmt.Errorf("%w: share is empty", shamir.ErrInvalidShares)
+	}
+
+	r.threshold = share.Threshold
+	r.shares[share.Guardian] = share.Share
+	return r.Remaining(), nil
+}
+
+// Remaining returns how many more shares are needed, or 0 once Key can be called. Before the
+// first share is added the threshold is unknown and Remaining returns 1.
+func (r *Recovery) Remaining() int {
+	if r.threshold == 0 {
+		return 1
+	}
+	return max(r.threshold-len(r.shares), 0)
+}
+
+// Guardians returns the guardians whose shares have been added.
+func (r *Recovery) Guardians() []string {
+	guardians := make([]string, 0, len(r.shares))
+	for guardian := range r.shares {
+		guardians = append(guardians, guardian)
+	}
+	return guardians
+}
+
+// Key recovers the private key, checking that it has the expected address.
+func (r *Recovery) Key() (*ecdsa.PrivateKey, error) {
+	if r.Remaining() > 0 {
+		return nil, fmt.Errorf("%w: %d more needed", ErrNotEnoughShares, r.Remaining())
+	}
+
+	shares := make([][]byte, 0, len(r.shares))
+	for _, share := range r.shares {
+		shares = append(shares, share)
+	}
+	raw, err := shamir.Combine(shares)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(raw)
+
+	key, err := crypto.ToECDSA(raw)
+	if err != nil {
+		return nil, fmt.Errorf("shares do not recover a valid key: %w", err)
+	}
+	if address := crypto.PubkeyToAddress(key.PublicKey).Hex(); !ethaddr.Equal(address, r.address) {
+		return nil, fmt.Errorf("%w: shares recover %s, not %s", ErrShareMismatch, address, r.address)
+	}
+	return key, nil
+}
@@ -0,0 +1,54 @@
+package provider_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+func TestKeyRecovery(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	shares, err := provider.BackupKey(key, 2, "alice", "bob", "laptop")
+	if err != nil || len(shares) != 3 {
+		t.Fatalf("BackupKey = %d shares, %v", len(shares), err)
+	}
+	if _, err := provider.BackupKey(key, 2, "alice", "alice"); err == nil {
+		t.Error("BackupKey accepted duplicate guardians")
+	}
+
+	other, _ := crypto.GenerateKey()
+	otherShares, _ := provider.BackupKey(other, 2, "alice", "bob")
+
+	recovery := provider.NewRecovery(address)
+	if _, err := recovery.Key(); !errors.Is(err, provider.ErrNotEnoughShares) {
+		t.Errorf("Key without shares error = %v, want ErrNotEnoughShares", err)
+	}
+	if _, err := recovery.Add(otherShares[0]); !errors.Is(err, provider.ErrShareMismatch) {
+		t.Errorf("Add(share of another key) error = %v, want ErrShareMismatch", err)
+	}
+
+	// Shares travel as text, e.g. printed or in a QR code.
+	parsed, err := provider.ParseRecoveryShare(shares[2].String())
+	if err != nil || parsed.Guardian != "laptop" {
+		t.Fatalf("ParseRecoveryShare = %+v, %v", parsed, err)
+	}
+	if remaining, err := recovery.Add(parsed); err != nil || remaining != 1 {
+		t.Fatalf("Add = %d, %v; want 1 remaining", remaining, err)
+	}
+	if remaining, err := recovery.Add(shares[0]); err != nil || remaining != 0 {
+		t.Fatalf("Add = %d, %v; want 0 remaining", remaining, err)
+	}
+
+	recovered, err := recovery.Key()
+	if err != nil || recovered.D.Cmp(key.D) != 0 {
+		t.Fatalf("Key = %v, %v; want the backed-up key", recovered, err)
+	}
+}
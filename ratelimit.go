@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// ErrRateLimited is returned by Limiter.Allow when a client has exceeded its request or failure budget.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimit bounds what one client may do in each fixed Window.
+type RateLimit struct {
+	Requests int           // Requests allowed per window; 0 means unlimited
+	Failures int           // Failed verifications allowed per window before the client is locked out; 0 means untracked
+	Window   time.Duration // Length of the counting window
+}
+
+// RateLimitStore counts events per key and window. The in-memory store suits a single instance;
+// implement it over a shared store such as Redis to apply limits across instances.
+type RateLimitStore interface {
+	// Add increments the counter of key for the window starting at window and returns the new count.
+	// The counter may be discarded once ttl has elapsed.
+	Add(ctx context.Context, key string, window time.Time, ttl time.Duration) (int, error)
+	// Count returns the counter of key for the window starting at window.
+	Count(ctx context.Context, key string, window time.Time) (int, error)
+}
+
+// Limiter applies a RateLimit to clients identified by string keys, e.g. "ip:203.0.113.7" or "holder:" followed by a DID.
+// It is transport agnostic: RateLimitMiddleware uses it for HTTP, and other servers can call Allow
+// and Fail directly. A Limiter is safe for concurrent use.
+type Limiter struct {
+	limit RateLimit
+	store RateLimitStore
+	clock clock.Clock
+}
+
+// LimiterOpt configures a Limiter.
+type LimiterOpt func(*Limiter)
+
+// WithRateLimitStore sets the store holding the counters (default: in memory).
+func WithRateLimitStore(store RateLimitStore) LimiterOpt {
+	return func(l *Limiter) {
+		l.store = store
+	}
+}
+
+// WithLimiterClock sets the time source of the windows (default: the system clock).
+func WithLimiterClock(c clock.Clock) LimiterOpt {
+	return func(l *Limiter) {
+		l.clock = clock.OrSystem(c)
+	}
+}
+
+// NewLimiter creates a Limiter enforcing limit.
+func NewLimiter(limit RateLimit, opts ...LimiterOpt) (*Limiter, error) {
+	if limit.Window <= 0 {
+		return nil, errors.New("rate limit window must be positive")
+	}
+
+	l := &Limiter{limit: limit, clock: clock.System()}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.store == nil {
+		l.store = NewMemoryRateLimitStore()
+	}
+	return l, nil
+}
+
+// Allow counts a request by key. It returns ErrRateLimited, with the time until the window resets,
+// when the key has used up its requests or failures for the current window.
+func (l *Limiter) Allow(ctx context.Context, key string) (time.Duration, error) {
+	window, retryAfter := l.window()
+
+	if l.limit.Failures > 0 {
+		failures, err := l.store.Count(ctx, "fail|"+key, window)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read rate limit store: %w", err)
+		}
+		if failures >= l.limit.Failures {
+			return retryAfter, fmt.Errorf("%w: too many failed verifications", ErrRateLimited)
+		}
+	}
+
+	if l.limit.Requests > 0 {
+		requests, err := l.store.Add(ctx, "req|"+key, window, l.limit.Window)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update rate limit store: %w", err)
+		}
+		if requests > l.limit.Requests {
+			return retryAfter, fmt.Errorf("%w: too many requests", ErrRateLimited)
+		}
+	}
+
+	return 0, nil
+}
+
+// Fail records a failed verification by key, counting towards RateLimit.Failures.
+func (l *Limiter) Fail(ctx context.Context, key string) error {
+	if l.limit.Failures <= 0 {
+		return nil
+	}
+	window, _ := l.window()
+	_, err := l.store.Add(ctx, "fail|"+key, window, l.limit.Window)
+	return err
+}
+
+// window returns the start of the current window and the time left in it.
+func (l *Limiter) window() (time.Time, time.Duration) {
+	now := l.clock.Now()
+	start := now.Truncate(l.limit.Window)
+	return start, start.Add(l.limit.Window).Sub(now)
+}
+
+// ClientKeyFunc identifies the client of a request for rate limiting. An empty key is not limited.
+type ClientKeyFunc func(r *http.Request) string
+
+// RemoteIP keys clients by the IP address of the connection. Behind a proxy, use a ClientKeyFunc
+// that reads the address the proxy forwards instead.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// HolderKey keys clients by the holder DID returned by holderOf, so a DID is limited whatever
+// address it comes from. Requests whose holder cannot be determined are not limited by it.
+func HolderKey(holderOf HolderFunc) ClientKeyFunc {
+	return func(r *http.Request) string {
+		holderDid, err := holderOf(r)
+		if err != nil || holderDid == "" {
+			return ""
+		}
+		return "holder:" + holderDid
+	}
+}
+
+// RateLimitMiddleware limits each client, identified by every one of keys (default: RemoteIP),
+// with limiter. Limited requests get 429 Too Many Requests with a Retry-After header. Responses
+// with status 401 or 403 from next, e.g. from ProofMiddleware, count as failed verifications.
+// When the store fails the request is refused with 503 rather than let through unlimited.
+func RateLimitMiddleware(limiter *Limiter, keys ...ClientKeyFunc) func(http.Handler) http.Handler {
+	if len(keys) == 0 {
+		keys = []ClientKeyFunc{RemoteIP}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var clients []string
+			for _, keyOf := range keys {
+				if key := keyOf(r); key != "" {
+					clients = append(clients, key)
+				}
+			}
+
+			for _, client := range clients {
+				retryAfter, err := limiter.Allow(r.Context(), client)
+				if errors.Is(err, ErrRateLimited) {
+					w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+					http.Error(w, err.Error(), http.StatusTooManyRequests)
+					return
+				}
+				if err != nil {
+					http.Error(w, "rate limiting unavailable", http.StatusServiceUnavailable)
+					return
+				}
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status == http.StatusUnauthorized || recorder.status == http.StatusForbidden {
+				for _, client := range clients {
+					_ = limiter.Fail(r.Context(), client)
+				}
+			}
+		})
+	}
+}
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// memoryRateLimitStore keeps counters in memory, dropping them once expired.
+type memoryRateLimitStore struct {
+	mu       sync.Mutex
+	counters map[string]memoryCounter
+	swept    time.Time // Window of the last sweep of expired counters
+}
+
+type memoryCounter struct {
+	window  time.Time
+	count   int
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore returns a RateLimitStore keeping its counters in process memory.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{counters: make(map[string]memoryCounter)}
+}
+
+func (m *memoryRateLimitStore) Add(_ context.Context, key string, window time.Time, ttl time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Expired counters are swept once per window so the map stays bounded by the active clients.
+	if window.After(m.swept) {
+		for k, counter := range m.counters {
+			if !counter.expires.After(window) {
+				delete(m.counters, k)
+			}
+		}
+		m.swept = window
+	}
+
+	counter := m.counters[key]
+	if !counter.window.Equal(window) {
+		counter = memoryCounter{window: window, expires: window.Add(ttl)}
+	}
+	counter.count++
+	m.counters[key] = counter
+	return counter.count, nil
+}
+
+func (m *memoryRateLimitStore) Count(_ context.Context, key string, window time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counter, ok := m.counters[key]
+	if !ok || !counter.window.Equal(window) {
+		return 0, nil
+	}
+	return counter.count, nil
+}
@@ -0,0 +1,117 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter, err := auth.NewLimiter(auth.RateLimit{Requests: 3, Failures: 2, Window: time.Minute}, auth.WithLimiterClock(fake))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := auth.RateLimitMiddleware(limiter, auth.RemoteIP)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "ok" {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+		}
+	}))
+	call := func(ip, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/verify", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("request limit", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			if rec := call("198.51.100.1", "ok"); rec.Code != http.StatusOK {
+				t.Fatalf("request %d: status %d", i, rec.Code)
+			}
+		}
+		rec := call("198.51.100.1", "ok")
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+			t.Errorf("got %d with Retry-After %q, want 429 after 60s", rec.Code, rec.Header().Get("Retry-After"))
+		}
+		if rec := call("198.51.100.2", "ok"); rec.Code != http.StatusOK {
+			t.Errorf("other client: status %d", rec.Code)
+		}
+	})
+
+	t.Run("failure lockout", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if rec := call("198.51.100.3", "guess"); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("attempt %d: status %d", i, rec.Code)
+			}
+		}
+		if rec := call("198.51.100.3", "ok"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("after failures: status %d, want 429", rec.Code)
+		}
+	})
+
+	t.Run("window reset", func(t *testing.T) {
+		fake.Advance(time.Minute)
+		if rec := call("198.51.100.1", "ok"); rec.Code != http.StatusOK {
+			t.Errorf("next window: status %d", rec.Code)
+		}
+		if rec := call("198.51.100.3", "ok"); rec.Code != http.StatusOK {
+			t.Errorf("lockout after the window: status %d", rec.Code)
+		}
+	})
+}
+
+// failingStore is a RateLimitStore that is down.
+type failingStore struct{}
+
+func (failingStore) Add(context.Context, string, time.Time, time.Duration) (int, error) {
+	return 0, errors.New("store down")
+}
+func (failingStore) Count(context.Context, string, time.Time) (int, error) {
+	return 0, errors.New("store down")
+}
+
+func TestRateLimitStoreFailureRefuses(t *testing.T) {
+	limiter, err := auth.NewLimiter(auth.RateLimit{Requests: 10, Window: time.Minute}, auth.WithRateLimitStore(failingStore{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := auth.RateLimitMiddleware(limiter)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("request reached the handler")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want 503", rec.Code)
+	}
+}
+
+func TestLimiterByHolder(t *testing.T) {
+	limiter, err := auth.NewLimiter(auth.RateLimit{Requests: 1, Window: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.Allow(context.Background(), "did:example:alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.Allow(context.Background(), "did:example:alice"); !errors.Is(err, auth.ErrRateLimited) {
+		t.Errorf("got %v, want ErrRateLimited", err)
+	}
+
+	holderOf := func(r *http.Request) (string, error) { return r.Header.Get("X-Holder"), nil }
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Holder", "did:example:bob")
+	if got := auth.HolderKey(holderOf)(req); got != "holder:did:example:bob" {
+		t.Errorf("HolderKey = %q", got)
+	}
+}
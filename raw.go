@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// RawPresentation holds the exact artifacts of a verified presentation, so relying parties can archive
+// what was presented without parsing the token themselves. See WithRawPresentation.
+type RawPresentation struct {
+	Token   string          // Token exactly as passed to VerifyToken
+	JWT     string          // Compact JWS of the verified proof, after decryption of an encrypted token
+	Payload json.RawMessage // JWT payload of the presentation, as signed
+}
+
+// WithRawPresentation makes VerifyToken fill raw with the presented artifacts once verification succeeds.
+// Each credential's own document is available from VcClaims.Raw.
+func WithRawPresentation(raw *RawPresentation) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.rawPresentation = raw
+	}
+}
+
+// Raw returns the credential exactly as it was presented: the compact JWT, or the JSON encoding of
+// a credential handled by a CredentialParser.
+func (c VcClaims) Raw() string {
+	return c.raw
+}
+
+// rawCredential returns the presented form of a verifiableCredential entry.
+func rawCredential(vcItem any) string {
+	if s, ok := vcItem.(string); ok {
+		return s
+	}
+	encoded, err := json.Marshal(vcItem)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// newRawPresentation captures the artifacts of the verified presentation vpToken presented as token.
+func newRawPresentation(token string, vpToken *jwtToken) RawPresentation {
+	raw := RawPresentation{Token: token, JWT: vpToken.raw}
+	if _, payload, ok := strings.Cut(vpToken.signingInput, "."); ok {
+		if decoded, err := base64.RawURLEncoding.DecodeString(payload); err == nil {
+			raw.Payload = decoded
+		}
+	}
+	return raw
+}
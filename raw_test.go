@@ -0,0 +1,82 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestRawDocuments(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+	ctx := context.Background()
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	recipient, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		createOpts []any
+		verifyOpts []auth.VerifyOpt
+	}{
+		{"compact", []any{auth.WithOutputFormat(auth.OutputCompact)}, nil},
+		{"enveloped", []any{auth.WithOutputFormat(auth.OutputEnvelopedVP)}, nil},
+		{"encrypted", []any{auth.WithEncryption(recipient.PublicKey(), "k1")}, []auth.VerifyOpt{auth.WithDecryptionKeys(recipient)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := a.CreateToken(ctx, []string{vcJwt}, holder.DID, append([]any{holder.Address}, tt.createOpts...)...)
+			if err != nil {
+				t.Fatalf("CreateToken: %v", err)
+			}
+
+			var raw auth.RawPresentation
+			claims, err := a.VerifyToken(ctx, token, append(tt.verifyOpts, auth.WithRawPresentation(&raw))...)
+			if err != nil {
+				t.Fatalf("VerifyToken: %v", err)
+			}
+
+			if claims[0].Raw() != vcJwt {
+				t.Errorf("Raw() = %q, want the presented credential", claims[0].Raw())
+			}
+			if raw.Token != token {
+				t.Error("RawPresentation.Token is not the token as presented")
+			}
+			if strings.Count(raw.JWT, ".") != 2 {
+				t.Errorf("RawPresentation.JWT = %q, want a compact JWS", raw.JWT)
+			}
+
+			var payload struct {
+				Iss string `json:"iss"`
+				VP  struct {
+					VerifiableCredential []string `json:"verifiableCredential"`
+				} `json:"vp"`
+			}
+			if err := json.Unmarshal(raw.Payload, &payload); err != nil {
+				t.Fatalf("payload: %v", err)
+			}
+			if payload.Iss != holder.DID || len(payload.VP.VerifiableCredential) != 1 || payload.VP.VerifiableCredential[0] != vcJwt {
+				t.Errorf("unexpected payload %s", raw.Payload)
+			}
+		})
+	}
+
+	claims, err := a.VerifyCredential(ctx, vcJwt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Raw() != vcJwt {
+		t.Error("VerifyCredential: Raw() is not the presented credential")
+	}
+}
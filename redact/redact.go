@@ -0,0 +1,188 @@
+// Package redact removes subject data from what the SDK and its callers hand to observability
+// systems: log records, trace steps, audit events and webhook payloads. A Policy decides, by dotted
+// field path such as "credentialSubject.email", which values are kept and which are masked, so one
+// policy applies the same way everywhere.
+package redact
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Mask replaces redacted values unless WithMask sets another.
+const Mask = "[REDACTED]"
+
+// Policy selects the fields to redact. A field is redacted when it matches a denied pattern, or when
+// allowed patterns are set and it matches none of them. Patterns are dotted paths whose segments may
+// be "*", matching any one segment, or "**", matching any number of segments. A pattern matching a
+// field also matches the fields nested in it, so "credentialSubject.address" covers
+// "credentialSubject.address.city". Array elements share the path of the array.
+//
+// A nil *Policy redacts nothing. A Policy is safe for concurrent use.
+type Policy struct {
+	allow, deny [][]string
+	mask        string
+}
+
+// Opt configures a Policy.
+type Opt func(*Policy)
+
+// Allow adds patterns of fields to keep; once any is set, every other field is redacted.
+func Allow(patterns ...string) Opt {
+	return func(p *Policy) {
+		p.allow = append(p.allow, splitAll(patterns)...)
+	}
+}
+
+// Deny adds patterns of fields to redact, whether allowed or not.
+func Deny(patterns ...string) Opt {
+	return func(p *Policy) {
+		p.deny = append(p.deny, splitAll(patterns)...)
+	}
+}
+
+// WithMask sets the value redacted fields are replaced with (default: Mask).
+func WithMask(mask string) Opt {
+	return func(p *Policy) {
+		p.mask = mask
+	}
+}
+
+// New creates a Policy. Without options it redacts nothing.
+func New(opts ...Opt) *Policy {
+	p := &Policy{mask: Mask}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Allowed reports whether the field at path is kept.
+func (p *Policy) Allowed(path string) bool {
+	if p == nil {
+		return true
+	}
+	segments := strings.Split(path, ".")
+	if matchAny(p.deny, segments) {
+		return false
+	}
+	return len(p.allow) == 0 || matchAny(p.allow, segments)
+}
+
+// Value returns v, or the mask when the field at path is redacted. Maps and slices are redacted field
+// by field, see Map.
+func (p *Policy) Value(path string, v any) any {
+	if p == nil {
+		return v
+	}
+	segments := strings.Split(path, ".")
+	if matchAny(p.deny, segments) {
+		return p.mask
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		return p.Map(path, v)
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			out[i] = p.Value(path, elem)
+		}
+		return out
+	}
+	if len(p.allow) > 0 && !matchAny(p.allow, segments) {
+		return p.mask
+	}
+	return v
+}
+
+// Map returns a copy of m with its redacted fields masked; prefix is the path of m itself, empty for
+// a top-level object. m is not modified.
+func (p *Policy) Map(prefix string, m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]any, len(m))
+	for key, v := range m {
+		out[key] = p.Value(join(prefix, key), v)
+	}
+	return out
+}
+
+// String returns s, or the mask when the field at path is redacted.
+func (p *Policy) String(path, s string) string {
+	if p.Allowed(path) {
+		return s
+	}
+	return p.mask
+}
+
+// ReplaceAttr redacts log attributes, for slog.HandlerOptions.ReplaceAttr. The path of an attribute
+// is its group names followed by its key; the built-in time, level, message and source attributes
+// are kept.
+func (p *Policy) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if p == nil {
+		return a
+	}
+	if len(groups) == 0 {
+		switch a.Key {
+		case slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey:
+			return a
+		}
+	}
+
+	path := strings.Join(append(groups[:len(groups):len(groups)], a.Key), ".")
+	if m, ok := a.Value.Any().(map[string]any); ok && a.Value.Kind() == slog.KindAny {
+		return slog.Any(a.Key, p.Map(path, m))
+	}
+	if !p.Allowed(path) {
+		return slog.String(a.Key, p.mask)
+	}
+	return a
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func splitAll(patterns []string) [][]string {
+	out := make([][]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		out = append(out, strings.Split(pattern, "."))
+	}
+	return out
+}
+
+// matchAny reports whether a pattern matches path or one of its ancestors.
+func matchAny(patterns [][]string, path []string) bool {
+	for _, pattern := range patterns {
+		for n := 1; n <= len(path); n++ {
+			if match(pattern, path[:n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// match reports whether pattern matches every segment of path.
+func match(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if match(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 || (pattern[0] != "*" && pattern[0] != path[0]) {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
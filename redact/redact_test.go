@@ -0,0 +1,80 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	p := New(Allow("credentialSubject.id", "credentialSubject.address", "**.type"), Deny("credentialSubject.address.street"))
+	for path, want := range map[string]bool{
+		"credentialSubject.id":             true,
+		"credentialSubject.email":          false,
+		"credentialSubject.address.city":   true,
+		"credentialSubject.address.street": false,
+		"credentialSubject.degree.type":    true,
+		"type":                             true,
+	} {
+		if got := p.Allowed(path); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	claims := map[string]any{
+		"email":   "alice@example.com",
+		"address": map[string]any{"city": "Hanoi", "street": "1 Main St"},
+		"degrees": []any{map[string]any{"type": "BachelorDegree", "name": "BSc"}},
+	}
+	got := p.Map("credentialSubject", claims)
+	want := map[string]any{
+		"email":   Mask,
+		"address": map[string]any{"city": "Hanoi", "street": Mask},
+		"degrees": []any{map[string]any{"type": "BachelorDegree", "name": Mask}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Map = %v, want %v", got, want)
+	}
+	if claims["email"] != "alice@example.com" {
+		t.Error("Map modified its input")
+	}
+
+	denyOnly := New(Deny("*.email"), WithMask("***"))
+	if got := denyOnly.String("credentialSubject.email", "a@b.c"); got != "***" {
+		t.Errorf("String = %q", got)
+	}
+	if got := denyOnly.String("credentialSubject.name", "Alice"); got != "Alice" {
+		t.Errorf("String = %q", got)
+	}
+
+	var nilPolicy *Policy
+	if !nilPolicy.Allowed("anything") || nilPolicy.Value("x", 1) != 1 {
+		t.Error("a nil policy redacted a value")
+	}
+}
+
+func TestReplaceAttr(t *testing.T) {
+	p := New(Deny("request.subject", "claims.email"))
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: p.ReplaceAttr}))
+	logger.Info("verified",
+		slog.Group("request", slog.String("subject", "did:example:alice"), slog.String("jti", "123")),
+		slog.Any("claims", map[string]any{"email": "alice@example.com", "role": "viewer"}),
+	)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["msg"] != "verified" {
+		t.Errorf("msg = %v", record["msg"])
+	}
+	if got := record["request"]; !reflect.DeepEqual(got, map[string]any{"subject": Mask, "jti": "123"}) {
+		t.Errorf("request = %v", got)
+	}
+	if got := record["claims"]; !reflect.DeepEqual(got, map[string]any{"email": Mask, "role": "viewer"}) {
+		t.Errorf("claims = %v", got)
+	}
+}
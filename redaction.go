@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"github/hovanhoa/go-vc-auth/redact"
+)
+
+// Redacted returns a copy of the claims for logs, audit events and webhooks, with the subject fields
+// policy redacts masked. Subject fields have the paths "credentialSubject.id" and
+// "credentialSubject.<claim>", e.g. "credentialSubject.address.city". The raw credential and the typed
+// subject, which hold every claim, are dropped.
+func (c VcClaims) Redacted(policy *redact.Policy) VcClaims {
+	out := c
+	out.raw, out.Typed = "", nil
+	out.CredentialSubject = make([]CredentialSubject, len(c.CredentialSubject))
+	for i, subject := range c.CredentialSubject {
+		out.CredentialSubject[i] = CredentialSubject{
+			ID:     policy.String("credentialSubject.id", subject.ID),
+			Claims: policy.Map("credentialSubject", subject.Claims),
+		}
+	}
+	return out
+}
+
+// Redacted returns a copy of the result with the claims of its credentials redacted, see
+// VcClaims.Redacted.
+func (r VerificationResult) Redacted(policy *redact.Policy) VerificationResult {
+	out := r
+	out.Credentials = make([]VcClaims, len(r.Credentials))
+	for i, claims := range r.Credentials {
+		out.Credentials[i] = claims.Redacted(policy)
+	}
+	return out
+}
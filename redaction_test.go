@@ -0,0 +1,54 @@
+package auth_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/redact"
+)
+
+func TestRedaction(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer", "email": "alice@example.com"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	policy := redact.New(redact.Deny("credentialSubject.id", "credentialSubject.email", "resolve.did"))
+	trace := auth.VerificationTrace{Redaction: policy}
+	claims, err := a.VerifyToken(context.Background(), token, auth.WithTrace(&trace))
+	if err != nil {
+		t.Fatalf("VerifyToken failed: %v", err)
+	}
+	for _, step := range trace.Steps() {
+		if did, ok := step.Details["did"]; ok && did != redact.Mask {
+			t.Errorf("%s:%s recorded did %q", step.Target, step.Step, did)
+		}
+	}
+
+	redacted := claims[0].Redacted(policy)
+	subject := redacted.Subject()
+	if subject.ID != redact.Mask || subject.Claims["email"] != redact.Mask || subject.Claims["role"] != "viewer" {
+		t.Errorf("unexpected redacted subject: %+v", subject)
+	}
+	if redacted.Raw() != "" || redacted.Issuer != claims[0].Issuer {
+		t.Error("Redacted kept the raw credential or dropped the issuer")
+	}
+	if claims[0].Subject().Claims["email"] != "alice@example.com" {
+		t.Error("Redacted modified the claims")
+	}
+
+	result := auth.NewVerificationResult(claims, nil).Redacted(policy)
+	data, err := auth.MarshalVerificationResult(result)
+	if err != nil || strings.Contains(string(data), "alice@example.com") || strings.Contains(string(data), holder.DID) {
+		t.Errorf("redacted result leaks subject data: %s, %v", data, err)
+	}
+}
@@ -0,0 +1,445 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/pii"
+	"github/hovanhoa/go-vc-auth/state"
+)
+
+var (
+	// ErrNoTokenRegistry is returned by RevokePresentation when the Auth was created without WithTokenRegistry.
+	ErrNoTokenRegistry = errors.New("no issued-token registry configured")
+	// ErrPresentationNotFound is returned when a jti is not in the issued-token registry.
+	ErrPresentationNotFound = errors.New("presentation not found")
+	// ErrPresentationRevoked is returned by VerifyToken for presentations revoked with RevokePresentation.
+	ErrPresentationRevoked = errors.New("presentation revoked")
+	// ErrPresentationUsed is returned by VerifyToken for single-use presentations already accepted once.
+	ErrPresentationUsed = errors.New("presentation already used")
+)
+
+// PresentationRecord describes a VP token created by an Auth with an issued-token registry.
+type PresentationRecord struct {
+	JTI         string    `json:"jti"`
+	Holder      string    `json:"holder"`
+	Credentials []string  `json:"credentials"`         // Ids (or jti) of the embedded credentials
+	IssuedAt    time.Time `json:"issuedAt"`            // Value of the VP "iat" claim
+	ExpiresAt   time.Time `json:"expiresAt,omitzero"`  // Value of the VP "exp" claim; zero when the VP does not expire
+	RevokedAt   time.Time `json:"revokedAt,omitzero"`  // Set by RevokePresentation
+	SingleUse   bool      `json:"singleUse,omitempty"` // Created with WithSingleUse
+	UsedAt      time.Time `json:"usedAt,omitzero"`     // When VerifyToken accepted a single-use presentation
+}
+
+// TokenStore persists PresentationRecords. Get returns ErrPresentationNotFound for unknown jtis.
+// Implementations must be safe for concurrent use.
+type TokenStore interface {
+	Put(ctx context.Context, record PresentationRecord) error
+	Get(ctx context.Context, jti string) (PresentationRecord, error)
+}
+
+// TokenRedeemer is implemented by TokenStores that can redeem single-use presentations. Redeem sets
+// UsedAt of the record of jti atomically, so concurrent verifications of one presentation cannot both
+// succeed, and returns ErrPresentationUsed when it was already set. The stores of this package
+// implement it.
+type TokenRedeemer interface {
+	Redeem(ctx context.Context, jti string, at time.Time) error
+}
+
+// Option configures an Auth created by NewAuth.
+type Option func(*Service)
+
+// WithTokenRegistry records every VP token the Auth creates in store and makes VerifyToken reject
+// presentations revoked with RevokePresentation. Created VPs carry a "jti" and "iat" claim.
+// Presentations whose jti is not in the store, e.g. minted by another party, are not affected.
+func WithTokenRegistry(store TokenStore) Option {
+	return func(a *Service) {
+		a.tokenStore = store
+	}
+}
+
+// RevokePresentation marks a presentation created by this Auth as revoked, e.g. on logout.
+func (a *Service) RevokePresentation(ctx context.Context, jti string) error {
+	if a.tokenStore == nil {
+		return ErrNoTokenRegistry
+	}
+
+	record, err := a.tokenStore.Get(ctx, jti)
+	if err != nil {
+		return err
+	}
+	if !record.RevokedAt.IsZero() {
+		return nil
+	}
+
+	record.RevokedAt = a.clock.Now().UTC()
+	return a.tokenStore.Put(ctx, record)
+}
+
+// newTokenID returns a random jti.
+func newTokenID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate jti: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// recordPresentation stores the record of a VP just created.
+func (a *Service) recordPresentation(ctx context.Context, holderDid string, vcTokens []*jwtToken, options *createOptions) error {
+	record := PresentationRecord{
+		JTI:       options.tokenID,
+		Holder:    holderDid,
+		IssuedAt:  options.issuedAt,
+		ExpiresAt: options.expiresAt,
+		SingleUse: options.singleUse,
+	}
+	for _, vcToken := range vcTokens {
+		vcData, _ := vcToken.payload["vc"].(map[string]any)
+		id := stringField(vcData, "id")
+		if id == "" {
+			id = stringField(vcToken.payload, "jti")
+		}
+		record.Credentials = append(record.Credentials, id)
+	}
+
+	if err := a.tokenStore.Put(ctx, record); err != nil {
+		return fmt.Errorf("failed to record presentation: %w", err)
+	}
+	return nil
+}
+
+// checkRevocation rejects a VP revoked in the issued-token registry, or single use and already used.
+// singleUse reports whether the VP must be redeemed once verified.
+func (a *Service) checkRevocation(ctx context.Context, vpToken *jwtToken) (singleUse bool, err error) {
+	jti := stringField(vpToken.payload, "jti")
+	if a.tokenStore == nil || jti == "" {
+		return false, nil
+	}
+
+	var record PresentationRecord
+	err = runStep(ctx, "revocation check", statusBudget, func(ctx context.Context) (err error) {
+		record, err = a.tokenStore.Get(ctx, jti)
+		return err
+	})
+	if errors.Is(err, ErrPresentationNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up presentation: %w", err)
+	}
+
+	if !record.RevokedAt.IsZero() {
+		return false, fmt.Errorf("%w: %s", ErrPresentationRevoked, jti)
+	}
+	if !record.UsedAt.IsZero() {
+		return false, fmt.Errorf("%w: %s", ErrPresentationUsed, jti)
+	}
+	return record.SingleUse, nil
+}
+
+// redeemPresentation marks a verified single-use VP used, failing when another verification did first.
+func (a *Service) redeemPresentation(ctx context.Context, jti string) error {
+	redeemer, ok := a.tokenStore.(TokenRedeemer)
+	if !ok {
+		return errors.New("issued-token registry cannot redeem single-use presentations")
+	}
+	err := runStep(ctx, "redemption", statusBudget, func(ctx context.Context) error {
+		return redeemer.Redeem(ctx, jti, a.clock.Now().UTC())
+	})
+	if errors.Is(err, ErrPresentationUsed) {
+		return fmt.Errorf("%w: %s", ErrPresentationUsed, jti)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to redeem presentation: %w", err)
+	}
+	return nil
+}
+
+// memoryTokenStore keeps presentation records in memory.
+type memoryTokenStore struct {
+	mu      sync.RWMutex
+	records map[string]PresentationRecord
+}
+
+// NewMemoryTokenStore creates an in-memory TokenStore. Records are lost on restart, so it suits tests
+// and single-process deployments. Records are kept until removed by its DeleteExpired method, e.g. by
+// registering the store with a state.GC.
+func NewMemoryTokenStore() TokenStore {
+	return &memoryTokenStore{records: map[string]PresentationRecord{}}
+}
+
+func (s *memoryTokenStore) Put(ctx context.Context, record PresentationRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[record.JTI] = record
+	return nil
+}
+
+func (s *memoryTokenStore) Get(ctx context.Context, jti string) (PresentationRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[jti]
+	if !ok {
+		return PresentationRecord{}, ErrPresentationNotFound
+	}
+	return record, nil
+}
+
+func (s *memoryTokenStore) Redeem(ctx context.Context, jti string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[jti]
+	if !ok {
+		return ErrPresentationNotFound
+	}
+	if !record.UsedAt.IsZero() {
+		return ErrPresentationUsed
+	}
+	record.UsedAt = at
+	s.records[jti] = record
+	return nil
+}
+
+// DeleteExpired removes the records of presentations that expired at or before before.
+func (s *memoryTokenStore) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int
+	for jti, record := range s.records {
+		if !record.ExpiresAt.IsZero() && !record.ExpiresAt.After(before) {
+			delete(s.records, jti)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// SQL placeholder styles for NewSQLTokenStore.
+const (
+	PlaceholderQuestion = iota // "?", used by MySQL and SQLite
+	PlaceholderDollar          // "$1", used by PostgreSQL
+)
+
+// sqlIdentifier matches table names accepted by NewSQLTokenStore.
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// sqlTokenStore keeps presentation records in a database/sql table.
+type sqlTokenStore struct {
+	db     *sql.DB
+	get    string
+	insert string
+	update string
+	redeem string
+	sweep  string
+}
+
+// NewSQLTokenStore creates a TokenStore backed by table in db, which must have been created as:
+//
+//	CREATE TABLE <table> (
+//	    jti         VARCHAR(64) PRIMARY KEY,
+//	    holder      VARCHAR(255) NOT NULL,
+//	    credentials TEXT NOT NULL,
+//	    issued_at   BIGINT NOT NULL,
+//	    expires_at  BIGINT NOT NULL,
+//	    revoked_at  BIGINT NOT NULL,
+//	    single_use  SMALLINT NOT NULL DEFAULT 0,
+//	    used_at     BIGINT NOT NULL DEFAULT 0
+//	)
+//
+// Times are stored as Unix seconds, 0 meaning unset, and single_use as 0 or 1. Tables created before
+// single-use presentations need the last two columns added. placeholder is PlaceholderQuestion or
+// PlaceholderDollar.
+func NewSQLTokenStore(db *sql.DB, table string, placeholder int) (TokenStore, error) {
+	if !sqlIdentifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+
+	p := func(n int) string {
+		if placeholder == PlaceholderDollar {
+			return fmt.Sprintf("$%d", n)
+		}
+		return "?"
+	}
+
+	return &sqlTokenStore{
+		db:  db,
+		get: fmt.Sprintf("SELECT holder, credentials, issued_at, expires_at, revoked_at, single_use, used_at FROM %s WHERE jti = %s", table, p(1)),
+		insert: fmt.Sprintf("INSERT INTO %s (jti, holder, credentials, issued_at, expires_at, revoked_at, single_use, used_at) VALUES (%s, %s, %s, %s, %s, %s, %s, %s)",
+			table, p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8)),
+		update: fmt.Sprintf("UPDATE %s SET holder = %s, credentials = %s, issued_at = %s, expires_at = %s, revoked_at = %s, single_use = %s, used_at = %s WHERE jti = %s",
+			table, p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8)),
+		redeem: fmt.Sprintf("UPDATE %s SET used_at = %s WHERE jti = %s AND used_at = 0", table, p(1), p(2)),
+		sweep:  fmt.Sprintf("DELETE FROM %s WHERE expires_at <> 0 AND expires_at <= %s", table, p(1)),
+	}, nil
+}
+
+func (s *sqlTokenStore) Put(ctx context.Context, record PresentationRecord) error {
+	credentials, err := json.Marshal(record.Credentials)
+	if err != nil {
+		return err
+	}
+
+	holder, issued, expires, revoked := record.Holder, unixOrZero(record.IssuedAt), unixOrZero(record.ExpiresAt), unixOrZero(record.RevokedAt)
+	singleUse, used := 0, unixOrZero(record.UsedAt)
+	if record.SingleUse {
+		singleUse = 1
+	}
+	result, err := s.db.ExecContext(ctx, s.update, holder, string(credentials), issued, expires, revoked, singleUse, used, record.JTI)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+
+	_, err = s.db.ExecContext(ctx, s.insert, record.JTI, holder, string(credentials), issued, expires, revoked, singleUse, used)
+	return err
+}
+
+func (s *sqlTokenStore) Get(ctx context.Context, jti string) (PresentationRecord, error) {
+	var (
+		record                         = PresentationRecord{JTI: jti}
+		credentials                    string
+		issued, expires, revoked, used int64
+		singleUse                      int
+	)
+	err := s.db.QueryRowContext(ctx, s.get, jti).Scan(&record.Holder, &credentials, &issued, &expires, &revoked, &singleUse, &used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PresentationRecord{}, ErrPresentationNotFound
+	}
+	if err != nil {
+		return PresentationRecord{}, err
+	}
+
+	if err := json.Unmarshal([]byte(credentials), &record.Credentials); err != nil {
+		return PresentationRecord{}, fmt.Errorf("invalid credentials column: %w", err)
+	}
+	record.IssuedAt, record.ExpiresAt, record.RevokedAt = timeOrZero(issued), timeOrZero(expires), timeOrZero(revoked)
+	record.SingleUse, record.UsedAt = singleUse != 0, timeOrZero(used)
+
+	return record, nil
+}
+
+func (s *sqlTokenStore) Redeem(ctx context.Context, jti string, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, s.redeem, at.Unix(), jti)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	// No unused record matched: tell an unknown jti from a used one.
+	if _, err := s.Get(ctx, jti); err != nil {
+		return err
+	}
+	return ErrPresentationUsed
+}
+
+// DeleteExpired removes the records of presentations that expired at or before before.
+func (s *sqlTokenStore) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, s.sweep, before.Unix())
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// unixOrZero returns t as Unix seconds, or 0 for the zero time.
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// timeOrZero is the inverse of unixOrZero.
+func timeOrZero(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
+// sealedRecord holds the personal data of a PresentationRecord sealed by NewEncryptedTokenStore.
+type sealedRecord struct {
+	Holder      string   `json:"holder"`
+	Credentials []string `json:"credentials"`
+}
+
+// encryptedTokenStore seals the personal data of records before they reach the underlying store.
+type encryptedTokenStore struct {
+	store     TokenStore
+	encryptor pii.Encryptor
+}
+
+// NewEncryptedTokenStore wraps store so the holder and credential ids of every record are encrypted
+// with encryptor, bound to the record's jti: the stored Holder is a pii.SealString field and
+// Credentials is empty. Timestamps stay in clear for revocation checks and expiry. Records written
+// without encryption are still read. With NewSQLTokenStore, make the holder column a TEXT.
+func NewEncryptedTokenStore(store TokenStore, encryptor pii.Encryptor) TokenStore {
+	return &encryptedTokenStore{store: store, encryptor: encryptor}
+}
+
+func (s *encryptedTokenStore) Put(ctx context.Context, record PresentationRecord) error {
+	data, err := json.Marshal(sealedRecord{Holder: record.Holder, Credentials: record.Credentials})
+	if err != nil {
+		return err
+	}
+	if record.Holder, err = pii.SealString(ctx, s.encryptor, data, []byte(record.JTI)); err != nil {
+		return fmt.Errorf("failed to encrypt presentation record: %w", err)
+	}
+	record.Credentials = nil
+	return s.store.Put(ctx, record)
+}
+
+func (s *encryptedTokenStore) Get(ctx context.Context, jti string) (PresentationRecord, error) {
+	record, err := s.store.Get(ctx, jti)
+	if err != nil {
+		return PresentationRecord{}, err
+	}
+
+	data, sealed, err := pii.OpenString(ctx, s.encryptor, record.Holder, []byte(record.JTI))
+	if err != nil {
+		return PresentationRecord{}, fmt.Errorf("failed to decrypt presentation record: %w", err)
+	}
+	if sealed {
+		var fields sealedRecord
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return PresentationRecord{}, fmt.Errorf("invalid presentation record: %w", err)
+		}
+		record.Holder, record.Credentials = fields.Holder, fields.Credentials
+	}
+	return record, nil
+}
+
+// Redeem redeems the record in the underlying store, whose timestamps are in clear.
+func (s *encryptedTokenStore) Redeem(ctx context.Context, jti string, at time.Time) error {
+	redeemer, ok := s.store.(TokenRedeemer)
+	if !ok {
+		return errors.New("underlying token store cannot redeem single-use presentations")
+	}
+	return redeemer.Redeem(ctx, jti, at)
+}
+
+// DeleteExpired removes expired records from the underlying store, when it supports it.
+func (s *encryptedTokenStore) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	if sweeper, ok := s.store.(state.Sweeper); ok {
+		return sweeper.DeleteExpired(ctx, before)
+	}
+	return 0, nil
+}
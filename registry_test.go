@@ -0,0 +1,190 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/pii"
+	"github/hovanhoa/go-vc-auth/secret"
+	"github/hovanhoa/go-vc-auth/state"
+)
+
+func TestRevokePresentation(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	store := auth.NewMemoryTokenStore()
+	a := registry.newAuth(authtest.NewSigner(holder), auth.WithTokenRegistry(store))
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address,
+		auth.WithOutputFormat(auth.OutputCompact), auth.WithExpiry(time.Hour))
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	var payload struct {
+		JTI string `json:"jti"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	payloadJSON, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if err := json.Unmarshal(payloadJSON, &payload); err != nil || payload.JTI == "" || payload.Exp-payload.Iat != 3600 {
+		t.Fatalf("expected jti, iat and exp claims, got %s", payloadJSON)
+	}
+
+	record, err := store.Get(context.Background(), payload.JTI)
+	if err != nil {
+		t.Fatalf("presentation was not recorded: %v", err)
+	}
+	if record.Holder != holder.DID || len(record.Credentials) != 1 || record.ExpiresAt.Unix() != payload.Exp {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	if _, err := a.VerifyToken(context.Background(), token); err != nil {
+		t.Fatalf("VerifyToken failed: %v", err)
+	}
+
+	if err := a.RevokePresentation(context.Background(), payload.JTI); err != nil {
+		t.Fatalf("RevokePresentation failed: %v", err)
+	}
+	if _, err := a.VerifyToken(context.Background(), token); !errors.Is(err, auth.ErrPresentationRevoked) {
+		t.Errorf("expected ErrPresentationRevoked, got %v", err)
+	}
+
+	if err := a.RevokePresentation(context.Background(), "unknown"); !errors.Is(err, auth.ErrPresentationNotFound) {
+		t.Errorf("expected ErrPresentationNotFound, got %v", err)
+	}
+
+	plain := registry.newAuth(authtest.NewSigner(holder))
+	if err := plain.RevokePresentation(context.Background(), payload.JTI); !errors.Is(err, auth.ErrNoTokenRegistry) {
+		t.Errorf("expected ErrNoTokenRegistry, got %v", err)
+	}
+}
+
+func TestSingleUsePresentation(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "visitor"})
+
+	stores := map[string]auth.TokenStore{
+		"memory": auth.NewMemoryTokenStore(),
+		"state":  auth.NewStateTokenStore(state.NewMemoryStore()),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			a := registry.newAuth(authtest.NewSigner(holder), auth.WithTokenRegistry(store))
+			token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address,
+				auth.WithSingleUse(), auth.WithExpiry(time.Minute))
+			if err != nil {
+				t.Fatalf("CreateToken failed: %v", err)
+			}
+
+			// Concurrent verifications of one presentation: exactly one is accepted.
+			var wg sync.WaitGroup
+			var accepted atomic.Int32
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := a.VerifyToken(context.Background(), token)
+					switch {
+					case err == nil:
+						accepted.Add(1)
+					case !errors.Is(err, auth.ErrPresentationUsed):
+						t.Errorf("expected ErrPresentationUsed, got %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if n := accepted.Load(); n != 1 {
+				t.Errorf("presentation accepted %d times", n)
+			}
+
+			result := auth.NewVerificationResult(a.VerifyToken(context.Background(), token))
+			if result.Error == nil || result.Error.Code != auth.ResultCodePresentationUsed {
+				t.Errorf("unexpected result: %+v", result.Error)
+			}
+		})
+	}
+
+	a := registry.newAuth(authtest.NewSigner(holder), auth.WithTokenRegistry(auth.NewMemoryTokenStore()))
+	if _, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address, auth.WithSingleUse()); err == nil {
+		t.Error("created a single-use presentation without expiry")
+	}
+	plain := registry.newAuth(authtest.NewSigner(holder))
+	_, err := plain.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address, auth.WithSingleUse(), auth.WithExpiry(time.Minute))
+	if !errors.Is(err, auth.ErrNoTokenRegistry) {
+		t.Errorf("expected ErrNoTokenRegistry, got %v", err)
+	}
+}
+
+func TestTokenStoreDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	store := auth.NewMemoryTokenStore()
+	now := time.Now()
+	_ = store.Put(ctx, auth.PresentationRecord{JTI: "expired", ExpiresAt: now.Add(-time.Minute)})
+	_ = store.Put(ctx, auth.PresentationRecord{JTI: "live", ExpiresAt: now.Add(time.Minute)})
+	_ = store.Put(ctx, auth.PresentationRecord{JTI: "forever"})
+
+	removed, err := store.(state.Sweeper).DeleteExpired(ctx, now)
+	if err != nil || removed != 1 {
+		t.Fatalf("DeleteExpired = %d, %v, want 1", removed, err)
+	}
+	if _, err := store.Get(ctx, "expired"); !errors.Is(err, auth.ErrPresentationNotFound) {
+		t.Errorf("expired record kept: %v", err)
+	}
+	for _, jti := range []string{"live", "forever"} {
+		if _, err := store.Get(ctx, jti); err != nil {
+			t.Errorf("record %s removed: %v", jti, err)
+		}
+	}
+}
+
+func TestEncryptedTokenStore(t *testing.T) {
+	ctx := context.Background()
+	inner := auth.NewMemoryTokenStore()
+	enc, err := pii.NewAESGCM(secret.FromBytes(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := auth.NewEncryptedTokenStore(inner, enc)
+
+	record := auth.PresentationRecord{JTI: "j1", Holder: "did:nda:testnet:0x1", Credentials: []string{"urn:uuid:c1"}, IssuedAt: time.Unix(1700000000, 0).UTC()}
+	if err := store.Put(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := inner.Get(ctx, "j1")
+	if !strings.HasPrefix(stored.Holder, pii.Prefix) || len(stored.Credentials) != 0 || !stored.IssuedAt.Equal(record.IssuedAt) {
+		t.Errorf("stored record is not sealed: %+v", stored)
+	}
+	got, err := store.Get(ctx, "j1")
+	if err != nil || got.Holder != record.Holder || len(got.Credentials) != 1 || got.Credentials[0] != "urn:uuid:c1" {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+
+	// Records written before encryption was enabled are read as they are.
+	_ = inner.Put(ctx, auth.PresentationRecord{JTI: "old", Holder: "did:nda:testnet:0x2"})
+	if got, err := store.Get(ctx, "old"); err != nil || got.Holder != "did:nda:testnet:0x2" {
+		t.Errorf("Get(old) = %+v, %v", got, err)
+	}
+
+	// A sealed record moved to another jti does not decrypt.
+	stored.JTI = "moved"
+	_ = inner.Put(ctx, stored)
+	if _, err := store.Get(ctx, "moved"); !errors.Is(err, pii.ErrDecrypt) {
+		t.Errorf("Get(moved) error = %v, want pii.ErrDecrypt", err)
+	}
+}
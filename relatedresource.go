@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github/hovanhoa/go-vc-auth/schema"
+)
+
+// maxRelatedResourceSize is the largest related resource the default fetcher downloads.
+const maxRelatedResourceSize = 10 << 20
+
+// ErrRelatedResource is returned when a relatedResource of a credential cannot be fetched, has no
+// digest, or does not match its digests.
+var ErrRelatedResource = errors.New("related resource integrity check failed")
+
+// ResourceFetcher returns the content of a resource linked from a credential's relatedResource.
+type ResourceFetcher interface {
+	Fetch(ctx context.Context, id string) ([]byte, error)
+}
+
+// ResourceFetcherFunc adapts a function to a ResourceFetcher.
+type ResourceFetcherFunc func(ctx context.Context, id string) ([]byte, error)
+
+// Fetch calls f.
+func (f ResourceFetcherFunc) Fetch(ctx context.Context, id string) ([]byte, error) {
+	return f(ctx, id)
+}
+
+// WithResourceFetcher sets where the resources checked by WithRelatedResources are loaded from, e.g.
+// a cache or an offline bundle. By default they are downloaded over HTTP(S) on use.
+func WithResourceFetcher(fetcher ResourceFetcher) Option {
+	return func(a *Service) {
+		a.resources = fetcher
+	}
+}
+
+// WithRelatedResources fetches every relatedResource of the verified credentials and checks it against
+// its digestSRI and digestMultibase. A resource that cannot be fetched or does not match fails
+// verification with ErrRelatedResource; matched resources are marked Verified in the returned claims and
+// each check is traced as StepRelatedResource.
+func WithRelatedResources() VerifyOpt {
+	return func(o *verifyOptions) {
+		o.relatedResources = true
+	}
+}
+
+// checkRelatedResources fetches and checks every related resource of claims, marking those that match.
+func (a *Service) checkRelatedResources(ctx context.Context, claims *VcClaims) error {
+	for i := range claims.RelatedResources {
+		resource := &claims.RelatedResources[i]
+		err := a.checkRelatedResource(ctx, *resource)
+		traceStep(ctx, StepRelatedResource, err, "id", resource.ID)
+		if err != nil {
+			return err
+		}
+		resource.Verified = true
+	}
+	return nil
+}
+
+// checkRelatedResource fetches one resource and compares it with all of its digests.
+func (a *Service) checkRelatedResource(ctx context.Context, resource RelatedResource) error {
+	if resource.ID == "" {
+		return fmt.Errorf("%w: relatedResource has no id", ErrRelatedResource)
+	}
+	if resource.DigestSRI == "" && resource.DigestMultibase == "" {
+		return fmt.Errorf("%w: %s has no digest", ErrRelatedResource, resource.ID)
+	}
+
+	fetcher := a.resources
+	if fetcher == nil {
+		fetcher = ResourceFetcherFunc(a.fetchResource)
+	}
+	data, err := fetcher.Fetch(ctx, resource.ID)
+	if err != nil {
+		return fmt.Errorf("%w: failed to fetch %s: %w", ErrRelatedResource, resource.ID, err)
+	}
+
+	if resource.DigestSRI != "" {
+		if err := checkDigestSRI(data, resource.DigestSRI); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrRelatedResource, resource.ID, err)
+		}
+	}
+	if resource.DigestMultibase != "" {
+		if err := schema.CheckDigestMultibase(data, resource.DigestMultibase); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrRelatedResource, resource.ID, err)
+		}
+	}
+	return nil
+}
+
+// fetchResource downloads a related resource with the service's HTTP client.
+func (a *Service) fetchResource(ctx context.Context, id string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRelatedResourceSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(body) > maxRelatedResourceSize {
+		return nil, fmt.Errorf("resource exceeds %d bytes", maxRelatedResourceSize)
+	}
+	return body, nil
+}
+
+// sriHashes are the Subresource Integrity hash algorithms, weakest first.
+var sriHashes = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{"sha256", sha256.New},
+	{"sha384", sha512.New384},
+	{"sha512", sha512.New},
+}
+
+// checkDigestSRI checks data against Subresource Integrity metadata: as in browsers, only the
+// digests of the strongest listed algorithm count, and any one of them matching is enough.
+func checkDigestSRI(data []byte, metadata string) error {
+	strongest := -1
+	digests := map[int][]string{}
+	for _, expression := range strings.Fields(metadata) {
+		name, digest, ok := strings.Cut(expression, "-")
+		if !ok {
+			continue
+		}
+		digest, _, _ = strings.Cut(digest, "?") // Options are reserved
+		for i, h := range sriHashes {
+			if h.name == name {
+				digests[i] = append(digests[i], digest)
+				strongest = max(strongest, i)
+			}
+		}
+	}
+	if strongest < 0 {
+		return fmt.Errorf("digestSRI %q has no supported hash", metadata)
+	}
+
+	h := sriHashes[strongest].new()
+	h.Write(data)
+	sum := h.Sum(nil)
+	for _, digest := range digests[strongest] {
+		expected, err := base64.StdEncoding.DecodeString(digest)
+		if err == nil && bytes.Equal(expected, sum) {
+			return nil
+		}
+	}
+	return fmt.Errorf("digestSRI %s does not match", sriHashes[strongest].name)
+}
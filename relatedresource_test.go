@@ -0,0 +1,74 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestRelatedResources(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	logo := []byte("\x89PNG logo")
+	sha384 := sha512.Sum384(logo)
+	sha256sum := sha256.Sum256(logo)
+	multihash := append([]byte{0x12, 0x20}, sha256sum[:]...)
+	resources := map[string][]byte{"https://example.com/logo.png": logo, "https://example.com/tampered.png": []byte("other")}
+	a := registry.newAuth(authtest.NewSigner(holder), auth.WithResourceFetcher(auth.ResourceFetcherFunc(
+		func(ctx context.Context, id string) ([]byte, error) { return resources[id], nil })))
+
+	credential := func(related ...map[string]any) string {
+		return craftJWT(t, issuer, nil, map[string]any{"iss": issuer.DID, "sub": holder.DID, "vc": map[string]any{
+			"type":              []any{"VerifiableCredential"},
+			"issuer":            issuer.DID,
+			"credentialSchema":  map[string]any{"id": authtest.SchemaURL, "type": "JsonSchema"},
+			"credentialSubject": map[string]any{"id": holder.DID},
+			"relatedResource":   related,
+		}})
+	}
+
+	valid := credential(map[string]any{
+		"id":              "https://example.com/logo.png",
+		"mediaType":       "image/png",
+		"digestSRI":       "sha256-bm90IHVzZWQ= sha384-" + base64.StdEncoding.EncodeToString(sha384[:]),
+		"digestMultibase": "u" + base64.RawURLEncoding.EncodeToString(multihash),
+	})
+	var trace auth.VerificationTrace
+	claims, err := a.VerifyCredential(context.Background(), valid, auth.WithRelatedResources(), auth.WithTrace(&trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claims.RelatedResources) != 1 || !claims.RelatedResources[0].Verified || claims.RelatedResources[0].MediaType != "image/png" {
+		t.Errorf("RelatedResources = %+v, want the logo verified", claims.RelatedResources)
+	}
+	var traced bool
+	for _, step := range trace.Steps() {
+		traced = traced || (step.Step == auth.StepRelatedResource && step.Outcome == auth.OutcomePass)
+	}
+	if !traced {
+		t.Errorf("trace has no passing %s step: %+v", auth.StepRelatedResource, trace.Steps())
+	}
+
+	if claims, err := a.VerifyCredential(context.Background(), valid); err != nil || claims.RelatedResources[0].Verified {
+		t.Errorf("without WithRelatedResources: %+v, %v, want the resource parsed but unchecked", claims.RelatedResources, err)
+	}
+
+	for name, resource := range map[string]map[string]any{
+		"sri mismatch":       {"id": "https://example.com/tampered.png", "digestSRI": "sha384-" + base64.StdEncoding.EncodeToString(sha384[:])},
+		"multibase mismatch": {"id": "https://example.com/tampered.png", "digestMultibase": "u" + base64.RawURLEncoding.EncodeToString(multihash)},
+		"no digest":          {"id": "https://example.com/logo.png"},
+	} {
+		_, err := a.VerifyCredential(context.Background(), credential(resource), auth.WithRelatedResources())
+		if !errors.Is(err, auth.ErrRelatedResource) {
+			t.Errorf("%s: error = %v, want ErrRelatedResource", name, err)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/pe"
+)
+
+// Presentation Exchange formats used in presentation submissions.
+const (
+	formatJWTVP = "jwt_vp_json"
+	formatJWTVC = "jwt_vc_json"
+)
+
+// clientIDPrefixDID prefixes DID client identifiers in OpenID4VP drafts that drop client_id_scheme.
+const clientIDPrefixDID = "decentralized_identifier:"
+
+// requestObjectType is the "typ" of signed request objects (RFC 9101).
+const requestObjectType = "oauth-authz-req+jwt"
+
+// selfIssuedAudience is the "aud" of request objects addressed to any wallet (OpenID4VP).
+const selfIssuedAudience = "https://self-issued.me/v2"
+
+// ErrNoMatchingCredential is returned when no held credential satisfies an input descriptor of a
+// presentation request.
+var ErrNoMatchingCredential = errors.New("no credential matches input descriptor")
+
+// ErrUnverifiedRequest is returned for presentation requests that are not signed by their client_id,
+// including unsigned requests unless WithUnsignedRequests is given.
+var ErrUnverifiedRequest = errors.New("presentation request is not signed by its client")
+
+// RequestOpt configures a RespondToRequest call.
+// RequestOpt values can be mixed with CreateToken options in RespondToRequest's opts.
+type RequestOpt func(*requestOptions)
+
+// requestOptions holds configuration for answering presentation requests.
+type requestOptions struct {
+	allowUnsigned bool
+}
+
+// WithUnsignedRequests accepts unsigned ("alg": "none") request objects, whose client_id cannot be
+// authenticated, e.g. for same-device flows where the request arrives over an authenticated channel.
+func WithUnsignedRequests() RequestOpt {
+	return func(o *requestOptions) {
+		o.allowUnsigned = true
+	}
+}
+
+// CredentialSource supplies candidate credentials for an input descriptor, best candidate first.
+// *wallet.Wallet implements it.
+type CredentialSource interface {
+	Select(ctx context.Context, descriptor pe.InputDescriptor) ([]string, error)
+}
+
+// PresentationRequest is an OpenID4VP authorization request asking for credentials.
+type PresentationRequest struct {
+	ClientID               string                     `json:"client_id"`
+	ClientIDScheme         string                     `json:"client_id_scheme,omitempty"`
+	ResponseType           string                     `json:"response_type,omitempty"`
+	ResponseMode           string                     `json:"response_mode,omitempty"`
+	ResponseURI            string                     `json:"response_uri,omitempty"`
+	RedirectURI            string                     `json:"redirect_uri,omitempty"`
+	Nonce                  string                     `json:"nonce"`
+	State                  string                     `json:"state,omitempty"`
+	PresentationDefinition *pe.PresentationDefinition `json:"presentation_definition"`
+	ClientMetadata         *ClientMetadata            `json:"client_metadata,omitempty"`
+}
+
+// PresentationResponse is the holder's answer to a PresentationRequest.
+// Post it as form or JSON fields to ResponseURI (direct_post) or return it to RedirectURI.
+type PresentationResponse struct {
+	VPToken                string        `json:"vp_token"`
+	PresentationSubmission pe.Submission `json:"presentation_submission"`
+	State                  string        `json:"state,omitempty"`
+	ResponseURI            string        `json:"-"`
+	ResponseMode           string        `json:"-"`
+}
+
+// RespondToRequest answers an OpenID4VP request object: it picks a credential from source for
+// every input descriptor of the presentation definition, builds a VP bound to the request's nonce
+// and client_id, and returns the response payload with its presentation submission.
+// Request objects must be signed with a key of the DID named by their client_id; unsigned ones are
+// only accepted with WithUnsignedRequests. Other opts are handled as in CreateToken.
+func (a *Service) RespondToRequest(ctx context.Context, requestJWT string, source CredentialSource, holderDid string, opts ...any) (*PresentationResponse, error) {
+	requestOpts := &requestOptions{}
+	var createOpts []any
+	for _, opt := range opts {
+		if requestOpt, ok := opt.(RequestOpt); ok {
+			requestOpt(requestOpts)
+			continue
+		}
+		createOpts = append(createOpts, opt)
+	}
+	opts = createOpts
+
+	request, err := a.parsePresentationRequest(ctx, requestJWT, requestOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		vcsJwt      []string
+		descriptors []pe.Descriptor
+		positions   = map[string]int{}
+	)
+	for _, descriptor := range request.PresentationDefinition.InputDescriptors {
+		candidates, err := source.Select(ctx, descriptor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select credentials for %s: %w", descriptor.ID, err)
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNoMatchingCredential, descriptor.ID)
+		}
+
+		// A credential satisfying several descriptors is presented once.
+		position, ok := positions[candidates[0]]
+		if !ok {
+			position = len(vcsJwt)
+			positions[candidates[0]] = position
+			vcsJwt = append(vcsJwt, candidates[0])
+		}
+
+		descriptors = append(descriptors, pe.Descriptor{
+			ID:     descriptor.ID,
+			Format: formatJWTVP,
+			Path:   "$",
+			PathNested: &pe.Descriptor{
+				ID:     descriptor.ID,
+				Format: formatJWTVC,
+				Path:   fmt.Sprintf("$.vp.verifiableCredential[%d]", position),
+			},
+		})
+	}
+
+	opts = append(opts, WithNonce(request.Nonce), WithAudience(request.ClientID), WithOutputFormat(OutputCompact))
+	vpToken, err := a.CreateToken(ctx, vcsJwt, holderDid, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	submissionID := make([]byte, 16)
+	if _, err := rand.Read(submissionID); err != nil {
+		return nil, fmt.Errorf("failed to generate submission id: %w", err)
+	}
+
+	responseURI := request.ResponseURI
+	if responseURI == "" {
+		responseURI = request.RedirectURI
+	}
+
+	return &PresentationResponse{
+		VPToken: vpToken,
+		PresentationSubmission: pe.Submission{
+			ID:            hex.EncodeToString(submissionID),
+			DefinitionID:  request.PresentationDefinition.ID,
+			DescriptorMap: descriptors,
+		},
+		State:        request.State,
+		ResponseURI:  responseURI,
+		ResponseMode: request.ResponseMode,
+	}, nil
+}
+
+// SignPresentationRequest signs request as a request object (a JAR-style JWT) with the verifier's key,
+// so holders can authenticate the verifier before answering. request.ClientID must be the verifier's DID,
+// optionally prefixed with "decentralized_identifier:"; the key is its "key-1" verification method
+// unless WithKeyID is given, and WithExpiry bounds how long the request can be answered. Other opts are
+// passed to the provider, e.g. the signer address.
+func (a *Service) SignPresentationRequest(ctx context.Context, request PresentationRequest, opts ...any) (string, error) {
+	if a.provider == nil {
+		return "", ErrNilProvider
+	}
+
+	options, providerOpts, err := splitCreateOpts(opts)
+	if err != nil {
+		return "", err
+	}
+
+	clientDID := strings.TrimPrefix(request.ClientID, clientIDPrefixDID)
+	if _, err := did.Parse(clientDID); err != nil {
+		return "", fmt.Errorf("client_id must be the verifier DID: %w", err)
+	}
+	if request.ClientIDScheme != "" && request.ClientIDScheme != "did" {
+		return "", fmt.Errorf("unsupported client_id_scheme %q", request.ClientIDScheme)
+	}
+	if request.Nonce == "" {
+		return "", errors.New("presentation request has no nonce")
+	}
+	if request.PresentationDefinition == nil || len(request.PresentationDefinition.InputDescriptors) == 0 {
+		return "", errors.New("presentation request has no presentation definition")
+	}
+	if request.ResponseType == "" {
+		request.ResponseType = "vp_token"
+	}
+
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal presentation request: %w", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(encoded, &payload); err != nil {
+		return "", fmt.Errorf("failed to marshal presentation request: %w", err)
+	}
+	issuedAt := a.clock.Now().UTC().Truncate(time.Second)
+	payload["iss"] = clientDID
+	payload["aud"] = selfIssuedAudience
+	payload["iat"] = issuedAt.Unix()
+	if options.lifetime > 0 {
+		payload["exp"] = issuedAt.Add(options.lifetime).Unix()
+	}
+
+	header := map[string]any{
+		"typ": requestObjectType,
+		"alg": "ES256K",
+		"kid": fmt.Sprintf("%s#%s", clientDID, options.verificationMethodKey),
+	}
+	signingInput, err := encodeSigningInput(header, payload)
+	if err != nil {
+		return "", err
+	}
+
+	requestJWT, err := a.signJWT(ctx, signingInput, providerOpts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign presentation request: %w", err)
+	}
+	return requestJWT, nil
+}
+
+// VerifyPresentationRequest authenticates a request object and returns the request, so a holder can
+// show who is asking, and decide whether to answer, before calling RespondToRequest. The request must be
+// signed with a key of the DID its client_id names and must not have expired; unsigned requests are only
+// accepted with WithUnsignedRequests.
+func (a *Service) VerifyPresentationRequest(ctx context.Context, requestJWT string, opts ...RequestOpt) (*PresentationRequest, error) {
+	options := &requestOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return a.parsePresentationRequest(ctx, requestJWT, options)
+}
+
+// parsePresentationRequest decodes a request object, checks it is signed by its client and asks for a vp_token.
+func (a *Service) parsePresentationRequest(ctx context.Context, requestJWT string, options *requestOptions) (*PresentationRequest, error) {
+	token, err := parseJWS(requestJWT, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid presentation request: %w", err)
+	}
+
+	signed := stringField(token.header, "alg") != "none"
+	if signed {
+		if err := a.verifyJWT(ctx, token); err != nil {
+			return nil, fmt.Errorf("failed to verify presentation request: %w", err)
+		}
+	} else if !options.allowUnsigned {
+		return nil, fmt.Errorf("%w: request object is unsigned", ErrUnverifiedRequest)
+	}
+
+	payload, err := json.Marshal(token.payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid presentation request: %w", err)
+	}
+
+	var request PresentationRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, fmt.Errorf("invalid presentation request: %w", err)
+	}
+
+	if request.ResponseType != "" && request.ResponseType != "vp_token" {
+		return nil, fmt.Errorf("unsupported response_type %q", request.ResponseType)
+	}
+	if request.Nonce == "" {
+		return nil, errors.New("presentation request has no nonce")
+	}
+	if request.ClientID == "" {
+		return nil, errors.New("presentation request has no client_id")
+	}
+	if signed {
+		if err := checkRequestClient(token, &request); err != nil {
+			return nil, err
+		}
+	}
+	if exp, ok := token.payload["exp"].(float64); ok && !a.clock.Now().Before(time.Unix(int64(exp), 0)) {
+		return nil, errors.New("presentation request has expired")
+	}
+	if request.PresentationDefinition == nil || len(request.PresentationDefinition.InputDescriptors) == 0 {
+		return nil, errors.New("presentation request has no presentation definition")
+	}
+
+	return &request, nil
+}
+
+// checkRequestClient ensures a signed request object was signed with a key of the DID its client_id
+// names. Only DID client identifiers can be authenticated this way.
+func checkRequestClient(token *jwtToken, request *PresentationRequest) error {
+	clientDID, hasPrefix := strings.CutPrefix(request.ClientID, clientIDPrefixDID)
+	if !hasPrefix && request.ClientIDScheme != "" && request.ClientIDScheme != "did" {
+		return fmt.Errorf("%w: unsupported client_id_scheme %q", ErrUnverifiedRequest, request.ClientIDScheme)
+	}
+
+	signer, _ := did.SplitDIDURL(stringField(token.header, "kid"))
+	if signer == "" || signer != clientDID {
+		return fmt.Errorf("%w: client_id %q, signed by %q", ErrUnverifiedRequest, request.ClientID, signer)
+	}
+	return nil
+}
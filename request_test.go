@@ -0,0 +1,221 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/pe"
+)
+
+// descriptorSource is a CredentialSource returning fixed candidates per input descriptor id.
+type descriptorSource map[string][]string
+
+func (s descriptorSource) Select(ctx context.Context, descriptor pe.InputDescriptor) ([]string, error) {
+	return s[descriptor.ID], nil
+}
+
+// newRequestObject encodes an OpenID4VP request object, signed by signer unless it is nil.
+func newRequestObject(t *testing.T, signer *authtest.Identity, payload map[string]any) string {
+	t.Helper()
+
+	header := map[string]any{"alg": "none", "typ": "oauth-authz-req+jwt"}
+	if signer != nil {
+		header["alg"] = "ES256K"
+		header["kid"] = signer.DID + "#key-1"
+	}
+
+	headerJSON, _ := json.Marshal(header)
+	payloadJSON, _ := json.Marshal(payload)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payloadJSON)
+
+	if signer == nil {
+		return signingInput + "."
+	}
+	return signJWT(t, signingInput, signer.Key)
+}
+
+func TestRespondToRequest(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	verifier := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+	employee := registry.issueCredential(t, issuer, holder, map[string]any{"role": "employee"})
+
+	request := map[string]any{
+		"client_id":     verifier.DID,
+		"response_type": "vp_token",
+		"response_mode": "direct_post",
+		"response_uri":  "https://verifier.example/response",
+		"nonce":         "n-0S6_WzA2Mj",
+		"state":         "af0ifjsldkj",
+		"presentation_definition": map[string]any{
+			"id": "employment",
+			"input_descriptors": []any{
+				map[string]any{"id": "employee"},
+				map[string]any{"id": "staff"},
+			},
+		},
+	}
+	source := descriptorSource{"employee": {employee}, "staff": {employee}}
+
+	for name, signer := range map[string]*authtest.Identity{"unsigned": nil, "signed": verifier} {
+		t.Run(name, func(t *testing.T) {
+			response, err := a.RespondToRequest(context.Background(), newRequestObject(t, signer, request), source, holder.DID, holder.Address, auth.WithUnsignedRequests())
+			if err != nil {
+				t.Fatalf("RespondToRequest failed: %v", err)
+			}
+
+			if response.State != "af0ifjsldkj" || response.ResponseURI != "https://verifier.example/response" || response.ResponseMode != "direct_post" {
+				t.Errorf("unexpected response routing: %+v", response)
+			}
+
+			submission := response.PresentationSubmission
+			if submission.DefinitionID != "employment" || len(submission.DescriptorMap) != 2 {
+				t.Fatalf("unexpected submission: %+v", submission)
+			}
+			for _, descriptor := range submission.DescriptorMap {
+				if descriptor.PathNested == nil || descriptor.PathNested.Path != "$.vp.verifiableCredential[0]" {
+					t.Errorf("expected both descriptors to share the deduplicated credential, got %+v", descriptor)
+				}
+			}
+
+			claims, err := a.VerifyToken(context.Background(), response.VPToken,
+				auth.WithExpectedNonce("n-0S6_WzA2Mj"), auth.WithExpectedAudience(verifier.DID))
+			if err != nil {
+				t.Fatalf("VerifyToken failed: %v", err)
+			}
+			if len(claims) != 1 {
+				t.Errorf("expected one credential, got %d", len(claims))
+			}
+
+			if _, err := a.VerifyToken(context.Background(), response.VPToken, auth.WithExpectedNonce("replayed")); err == nil {
+				t.Error("expected a nonce mismatch to be rejected")
+			}
+		})
+	}
+
+	t.Run("no match", func(t *testing.T) {
+		_, err := a.RespondToRequest(context.Background(), newRequestObject(t, verifier, request), descriptorSource{"employee": {employee}}, holder.DID, holder.Address)
+		if !errors.Is(err, auth.ErrNoMatchingCredential) {
+			t.Errorf("expected ErrNoMatchingCredential, got %v", err)
+		}
+	})
+
+	t.Run("tampered request", func(t *testing.T) {
+		signed := strings.Split(newRequestObject(t, verifier, request), ".")
+		redirectedRequest := map[string]any{}
+		for k, v := range request {
+			redirectedRequest[k] = v
+		}
+		redirectedRequest["response_uri"] = "https://attacker.example/response"
+		redirected := strings.Split(newRequestObject(t, nil, redirectedRequest), ".")
+		forged := signed[0] + "." + redirected[1] + "." + signed[2]
+		if _, err := a.RespondToRequest(context.Background(), forged, source, holder.DID, holder.Address); err == nil {
+			t.Error("expected a request with an invalid signature to be rejected")
+		}
+	})
+
+	t.Run("client binding", func(t *testing.T) {
+		withClient := func(clientID, scheme string) map[string]any {
+			bound := map[string]any{}
+			for k, v := range request {
+				bound[k] = v
+			}
+			bound["client_id"] = clientID
+			if scheme != "" {
+				bound["client_id_scheme"] = scheme
+			}
+			return bound
+		}
+
+		if _, err := a.RespondToRequest(context.Background(), newRequestObject(t, verifier, withClient("decentralized_identifier:"+verifier.DID, "")), source, holder.DID, holder.Address); err != nil {
+			t.Errorf("expected a prefixed DID client_id to be accepted, got %v", err)
+		}
+
+		rejected := map[string]string{
+			"unsigned":            newRequestObject(t, nil, request),
+			"signed by other DID": newRequestObject(t, issuer, request),
+			"non-DID scheme":      newRequestObject(t, verifier, withClient("https://verifier.example", "redirect_uri")),
+		}
+		for name, requestJWT := range rejected {
+			if _, err := a.RespondToRequest(context.Background(), requestJWT, source, holder.DID, holder.Address); !errors.Is(err, auth.ErrUnverifiedRequest) {
+				t.Errorf("%s: expected ErrUnverifiedRequest, got %v", name, err)
+			}
+		}
+	})
+}
+
+func TestSignPresentationRequest(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	verifier := registry.newIdentity(t)
+	ctx := context.Background()
+
+	fake := clock.NewFake(time.Now())
+	verifierAuth := registry.newAuth(authtest.NewSigner(verifier), auth.WithClock(fake))
+	wallet := registry.newAuth(authtest.NewSigner(holder), auth.WithClock(fake))
+	employee := registry.issueCredential(t, issuer, holder, map[string]any{"role": "employee"})
+
+	request := auth.PresentationRequest{
+		ClientID:    verifier.DID,
+		ResponseURI: "https://verifier.example/response",
+		Nonce:       "n-0S6_WzA2Mj",
+		PresentationDefinition: &pe.PresentationDefinition{
+			ID:               "employment",
+			InputDescriptors: []pe.InputDescriptor{{ID: "employee"}},
+		},
+	}
+	requestJWT, err := verifierAuth.SignPresentationRequest(ctx, request, verifier.Address, auth.WithExpiry(5*time.Minute))
+	if err != nil {
+		t.Fatalf("SignPresentationRequest: %v", err)
+	}
+
+	verified, err := wallet.VerifyPresentationRequest(ctx, requestJWT)
+	if err != nil {
+		t.Fatalf("VerifyPresentationRequest: %v", err)
+	}
+	if verified.ClientID != verifier.DID || verified.ResponseType != "vp_token" || verified.Nonce != request.Nonce {
+		t.Errorf("unexpected verified request: %+v", verified)
+	}
+
+	response, err := wallet.RespondToRequest(ctx, requestJWT, descriptorSource{"employee": {employee}}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("RespondToRequest: %v", err)
+	}
+	if _, err := verifierAuth.VerifyToken(ctx, response.VPToken, auth.WithExpectedNonce(request.Nonce), auth.WithExpectedAudience(verifier.DID)); err != nil {
+		t.Errorf("VerifyToken: %v", err)
+	}
+
+	// The verifier cannot sign on behalf of another client.
+	impersonating := request
+	impersonating.ClientID = issuer.DID
+	forged, err := verifierAuth.SignPresentationRequest(ctx, impersonating, verifier.Address)
+	if err != nil {
+		t.Fatalf("SignPresentationRequest: %v", err)
+	}
+	if _, err := wallet.VerifyPresentationRequest(ctx, forged); err == nil {
+		t.Error("request signed with another key than the client's was accepted")
+	}
+
+	fake.Advance(10 * time.Minute)
+	if _, err := wallet.VerifyPresentationRequest(ctx, requestJWT); err == nil {
+		t.Error("expired request was accepted")
+	}
+
+	invalid := request
+	invalid.ClientID = "https://verifier.example"
+	if _, err := verifierAuth.SignPresentationRequest(ctx, invalid, verifier.Address); err == nil {
+		t.Error("request with a non-DID client_id was signed")
+	}
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ResultVersion is the version of the VerificationResult schema. It is bumped when a field is removed
+// or changes meaning; fields added later are optional and leave it unchanged.
+const ResultVersion = 1
+
+// ResultMediaType is the media type of a JSON-encoded VerificationResult.
+const ResultMediaType = "application/vnd.vc-auth.verification-result+json"
+
+// ErrResultVersion is returned by ParseVerificationResult for a result of a newer, unknown version.
+var ErrResultVersion = errors.New("unsupported verification result version")
+
+// Stable error codes of a VerificationResult. Errors without a more specific code are reported as
+// ResultCodeInvalid.
+const (
+	ResultCodeInvalid               = "invalid"
+	ResultCodeMalformed             = "malformed"
+	ResultCodeInvalidHolder         = "invalid_holder"
+	ResultCodeIssuerMismatch        = "issuer_mismatch"
+	ResultCodeProofPurpose          = "proof_purpose"
+	ResultCodeNotValid              = "not_valid"
+	ResultCodePolicyViolation       = "policy_violation"
+	ResultCodeInsufficientAssurance = "insufficient_assurance"
+	ResultCodeExcessDisclosure      = "excess_disclosure"
+	ResultCodeChannelBinding        = "channel_binding"
+	ResultCodeKeyNotPinned          = "key_not_pinned"
+	ResultCodeKeyAttestation        = "key_attestation"
+	ResultCodeDomainNotLinked       = "domain_not_linked"
+	ResultCodeRelatedResource       = "related_resource"
+	ResultCodeNotAnchored           = "not_anchored"
+	ResultCodeUnsupportedFormat     = "unsupported_format"
+	ResultCodeEncryptedToken        = "encrypted_token"
+	ResultCodePresentationRevoked   = "presentation_revoked"
+	ResultCodePresentationUsed      = "presentation_used"
+	ResultCodeInvalidCapability     = "invalid_capability"
+	ResultCodePresentationNotFound  = "presentation_not_found"
+	ResultCodeProofOfPossession     = "invalid_proof_of_possession"
+	ResultCodeRateLimited           = "rate_limited"
+	ResultCodeTimeout               = "timeout"
+	ResultCodeClosed                = "closed"
+	ResultCodeInternal              = "internal"
+)
+
+// resultCodes maps errors to their codes, most specific first: an insufficient assurance level is
+// also a policy violation.
+var resultCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrInsufficientAssurance, ResultCodeInsufficientAssurance},
+	{ErrPolicyViolation, ResultCodePolicyViolation},
+	{ErrMalformedCredential, ResultCodeMalformed},
+	{ErrInvalidHolderDID, ResultCodeInvalidHolder},
+	{ErrIssuerMismatch, ResultCodeIssuerMismatch},
+	{ErrProofPurpose, ResultCodeProofPurpose},
+	{ErrCredentialNotValid, ResultCodeNotValid},
+	{ErrExcessDisclosure, ResultCodeExcessDisclosure},
+	{ErrChannelBinding, ResultCodeChannelBinding},
+	{ErrKeyNotPinned, ResultCodeKeyNotPinned},
+	{ErrKeyAttestation, ResultCodeKeyAttestation},
+	{ErrDomainNotLinked, ResultCodeDomainNotLinked},
+	{ErrRelatedResource, ResultCodeRelatedResource},
+	{ErrNotAnchored, ResultCodeNotAnchored},
+	{ErrUnsupportedFormat, ResultCodeUnsupportedFormat},
+	{ErrEncryptedToken, ResultCodeEncryptedToken},
+	{ErrPresentationRevoked, ResultCodePresentationRevoked},
+	{ErrPresentationUsed, ResultCodePresentationUsed},
+	{ErrInvalidCapability, ResultCodeInvalidCapability},
+	{ErrPresentationNotFound, ResultCodePresentationNotFound},
+	{ErrInvalidProof, ResultCodeProofOfPossession},
+	{ErrRateLimited, ResultCodeRateLimited},
+	{ErrStepTimeout, ResultCodeTimeout},
+	{ErrClosed, ResultCodeClosed},
+}
+
+// VerificationResult is the outcome of VerifyToken in a stable, versioned form, for services that
+// return verification outcomes over their own APIs. Its JSON field names are part of the schema
+// identified by Version.
+type VerificationResult struct {
+	Version     int          `json:"version"`
+	Verified    bool         `json:"verified"`
+	Credentials []VcClaims   `json:"credentials,omitempty"` // Claims of the verified credentials
+	Error       *ResultError `json:"error,omitempty"`       // Set when Verified is false
+}
+
+// ResultError describes why verification failed.
+type ResultError struct {
+	Code       string `json:"code"`                 // One of the ResultCode constants
+	Message    string `json:"message"`              // Error string, for humans
+	Credential *int   `json:"credential,omitempty"` // Index of the failing credential, when known
+}
+
+// NewVerificationResult builds the result of a VerifyToken call from its return values.
+// Timestamps are converted to UTC, so equal results encode identically.
+func NewVerificationResult(claims []VcClaims, err error) VerificationResult {
+	result := VerificationResult{Version: ResultVersion}
+	if err != nil {
+		result.Error = newResultError(err)
+		return result
+	}
+
+	result.Verified = true
+	result.Credentials = make([]VcClaims, len(claims))
+	for i, c := range claims {
+		c.ValidFrom = c.ValidFrom.UTC()
+		c.ValidUntil = c.ValidUntil.UTC()
+		result.Credentials[i] = c
+	}
+	return result
+}
+
+// newResultError classifies err by the first code in resultCodes it matches.
+func newResultError(err error) *ResultError {
+	resultErr := &ResultError{Code: ResultCodeInvalid, Message: err.Error()}
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		// The panic value and stack are for the operator's logs, not for API clients.
+		resultErr.Code = ResultCodeInternal
+		resultErr.Message = "internal error"
+		return resultErr
+	}
+
+	for _, rc := range resultCodes {
+		if errors.Is(err, rc.err) {
+			resultErr.Code = rc.code
+			break
+		}
+	}
+
+	var credErr *CredentialError
+	if errors.As(err, &credErr) {
+		index := credErr.Index
+		resultErr.Credential = &index
+	}
+	return resultErr
+}
+
+// MarshalVerificationResult encodes a result canonically: fields in declaration order, object keys of
+// claims sorted, HTML characters unescaped and no trailing newline, so equal results encode to the
+// same bytes, e.g. to be signed or compared.
+func MarshalVerificationResult(r VerificationResult) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// ParseVerificationResult decodes a JSON VerificationResult, failing with ErrResultVersion for a
+// version newer than ResultVersion.
+func ParseVerificationResult(data []byte) (VerificationResult, error) {
+	var result VerificationResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return VerificationResult{}, fmt.Errorf("invalid verification result: %w", err)
+	}
+	if result.Version < 1 || result.Version > ResultVersion {
+		return VerificationResult{}, fmt.Errorf("%w: %d", ErrResultVersion, result.Version)
+	}
+	return result, nil
+}
+
+// ResultEncoder serializes verification results for an API response, e.g. as JSON or protobuf.
+type ResultEncoder interface {
+	ContentType() string
+	Encode(result VerificationResult) ([]byte, error)
+}
+
+// jsonResultEncoder is the canonical JSON ResultEncoder.
+type jsonResultEncoder struct{}
+
+func (jsonResultEncoder) ContentType() string {
+	return ResultMediaType
+}
+
+func (jsonResultEncoder) Encode(result VerificationResult) ([]byte, error) {
+	return MarshalVerificationResult(result)
+}
+
+// JSONResultEncoder returns the ResultEncoder producing the canonical JSON form of a result.
+func JSONResultEncoder() ResultEncoder {
+	return jsonResultEncoder{}
+}
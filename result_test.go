@@ -0,0 +1,94 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestVerificationResult(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "<admin>"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	result := auth.NewVerificationResult(a.VerifyToken(context.Background(), token))
+	data, err := auth.JSONResultEncoder().Encode(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["version"] != float64(auth.ResultVersion) || fields["verified"] != true || fields["error"] != nil {
+		t.Errorf("unexpected result %s", data)
+	}
+	if again, _ := auth.MarshalVerificationResult(result); !bytes.Equal(data, again) {
+		t.Error("encoding is not deterministic")
+	}
+	if want := `"role":"<admin>"`; !bytes.Contains(data, []byte(want)) {
+		t.Errorf("expected %s unescaped in %s", want, data)
+	}
+
+	parsed, err := auth.ParseVerificationResult(data)
+	if err != nil {
+		t.Fatalf("ParseVerificationResult failed: %v", err)
+	}
+	if !parsed.Verified || len(parsed.Credentials) != 1 || parsed.Credentials[0].Issuer != issuer.DID ||
+		parsed.Credentials[0].Subject().Claims["role"] != "<admin>" {
+		t.Errorf("round trip lost claims: %+v", parsed)
+	}
+}
+
+func TestVerificationResultError(t *testing.T) {
+	err := fmt.Errorf("verification failed: %w", &auth.CredentialError{Index: 2, Err: auth.ErrCredentialNotValid})
+	result := auth.NewVerificationResult(nil, err)
+	if result.Verified || result.Error == nil || result.Error.Code != auth.ResultCodeNotValid ||
+		result.Error.Credential == nil || *result.Error.Credential != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	result = auth.NewVerificationResult(nil, fmt.Errorf("%w: %w", auth.ErrPolicyViolation, auth.ErrInsufficientAssurance))
+	if result.Error.Code != auth.ResultCodeInsufficientAssurance {
+		t.Errorf("code = %q, want %q", result.Error.Code, auth.ResultCodeInsufficientAssurance)
+	}
+
+	result = auth.NewVerificationResult(nil, &auth.PanicError{Value: "secret state", Stack: []byte("stack")})
+	if result.Error.Code != auth.ResultCodeInternal || result.Error.Message != "internal error" {
+		t.Errorf("panic details leaked: %+v", result.Error)
+	}
+
+	result = auth.NewVerificationResult(nil, errors.New("boom"))
+	if result.Error.Code != auth.ResultCodeInvalid {
+		t.Errorf("code = %q, want %q", result.Error.Code, auth.ResultCodeInvalid)
+	}
+}
+
+func TestParseVerificationResultVersion(t *testing.T) {
+	if _, err := auth.ParseVerificationResult([]byte(`{"version":2,"verified":true}`)); !errors.Is(err, auth.ErrResultVersion) {
+		t.Errorf("expected ErrResultVersion, got %v", err)
+	}
+
+	result, err := auth.ParseVerificationResult([]byte(`{"version":1,"verified":true,"credentials":[{"issuer":"did:nda:testnet:0x1","validFrom":"2025-01-01T07:00:00+07:00","credentialSubject":[{"id":"did:nda:testnet:0x2"}]}],"added":"later"}`))
+	if err != nil {
+		t.Fatalf("ParseVerificationResult failed: %v", err)
+	}
+	if !result.Credentials[0].ValidFrom.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("validFrom = %v", result.Credentials[0].ValidFrom)
+	}
+}
@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	auth "github/hovanhoa/go-vc-auth"
+)
+
+// maxResponseSize bounds the responses the Client reads.
+const maxResponseSize = 10 << 20
+
+// Client calls the AuthService of a Server.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a Client for the server at baseURL, e.g. "https://verifier.internal". A nil
+// httpClient uses http.DefaultClient.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}
+}
+
+// CreateChallenge issues a challenge for a holder to use as the nonce of its presentation.
+func (c *Client) CreateChallenge(ctx context.Context) (CreateChallengeResponse, error) {
+	var resp CreateChallengeResponse
+	err := c.call(ctx, "CreateChallenge", struct{}{}, &resp)
+	return resp, err
+}
+
+// CreateToken creates a VP token with the server's signing provider.
+func (c *Client) CreateToken(ctx context.Context, req CreateTokenRequest) (string, error) {
+	var resp CreateTokenResponse
+	if err := c.call(ctx, "CreateToken", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.Token, nil
+}
+
+// VerifyToken verifies a VP token. A presentation that fails verification is reported by the result,
+// not as an error; errors are failures of the call itself.
+func (c *Client) VerifyToken(ctx context.Context, req VerifyTokenRequest) (auth.VerificationResult, error) {
+	var data json.RawMessage
+	if err := c.call(ctx, "VerifyToken", req, &data); err != nil {
+		return auth.VerificationResult{}, err
+	}
+	return auth.ParseVerificationResult(data)
+}
+
+// call posts req to method and decodes the response into resp, or returns the server's *Error.
+func (c *Client) call(ctx context.Context, method string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+ServicePath+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		rpcErr := &Error{}
+		if err := json.Unmarshal(data, rpcErr); err != nil || rpcErr.Code == "" {
+			return fmt.Errorf("unexpected status code: %d", httpResp.StatusCode)
+		}
+		return rpcErr
+	}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return fmt.Errorf("invalid %s response: %w", method, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Connect error codes used by the Server.
+const (
+	CodeInvalidArgument    = "invalid_argument"
+	CodeFailedPrecondition = "failed_precondition"
+	CodeResourceExhausted  = "resource_exhausted"
+	CodeUnimplemented      = "unimplemented"
+	CodeInternal           = "internal"
+	CodeUnavailable        = "unavailable"
+)
+
+// codeStatus maps Connect error codes to HTTP statuses.
+var codeStatus = map[string]int{
+	CodeInvalidArgument:    http.StatusBadRequest,
+	CodeFailedPrecondition: http.StatusBadRequest,
+	CodeResourceExhausted:  http.StatusTooManyRequests,
+	CodeUnimplemented:      http.StatusNotImplemented,
+	CodeInternal:           http.StatusInternalServerError,
+	CodeUnavailable:        http.StatusServiceUnavailable,
+}
+
+// Error is a Connect error: the failure of a call, as opposed to a failed verification, which is a
+// successful VerifyToken call.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// writeError writes err as a Connect error. Errors other than *Error are internal, and their message
+// is not sent.
+func writeError(w http.ResponseWriter, err error) {
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) {
+		rpcErr = &Error{Code: CodeInternal}
+	}
+
+	status, ok := codeStatus[rpcErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(rpcErr)
+}
@@ -0,0 +1,119 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/rpc"
+)
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	registry := authtest.NewRegistry()
+	issuer, err := registry.NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	holder, err := registry.NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := auth.NewAuth(authtest.NewSigner(issuer, holder), "https://registry.authtest.invalid", registry.Options()...)
+	results, err := a.IssueCredentials(ctx, []auth.CredentialDocument{{
+		Issuer:  issuer.DID,
+		Schemas: []auth.CredentialSchema{{ID: authtest.SchemaURL, Type: "JsonSchema"}},
+		Subject: map[string]any{"id": holder.DID, "role": "viewer"},
+	}}, issuer.Address)
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("IssueCredentials = %+v, %v", results, err)
+	}
+
+	server := httptest.NewServer(rpc.NewServer(a, rpc.WithCreateToken()))
+	t.Cleanup(server.Close)
+	client := rpc.NewClient(server.URL, server.Client())
+
+	challenge, err := client.CreateChallenge(ctx)
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+	token, err := client.CreateToken(ctx, rpc.CreateTokenRequest{
+		Credentials: []string{results[0].Credential},
+		HolderDID:   holder.DID,
+		Signer:      holder.Address,
+		Nonce:       challenge.Challenge,
+	})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+
+	result, err := client.VerifyToken(ctx, rpc.VerifyTokenRequest{Token: token, Challenge: challenge.Challenge})
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	if !result.Verified || len(result.Credentials) != 1 || result.Credentials[0].Subject().Claims["role"] != "viewer" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	// The challenge is single-use.
+	var rpcErr *rpc.Error
+	_, err = client.VerifyToken(ctx, rpc.VerifyTokenRequest{Token: token, Challenge: challenge.Challenge})
+	if !errors.As(err, &rpcErr) || rpcErr.Code != rpc.CodeFailedPrecondition {
+		t.Errorf("replayed challenge error = %v, want failed_precondition", err)
+	}
+
+	// A failed verification is a result, not a call error.
+	other, err := client.CreateChallenge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = client.VerifyToken(ctx, rpc.VerifyTokenRequest{Token: token, Challenge: other.Challenge})
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	if result.Verified || result.Error == nil || result.Error.Code == "" {
+		t.Errorf("expected a failed result for the wrong nonce, got %+v", result)
+	}
+}
+
+func TestServerRejects(t *testing.T) {
+	server := httptest.NewServer(rpc.NewServer(auth.NewAuth(nil, "https://registry.authtest.invalid")))
+	t.Cleanup(server.Close)
+	client := rpc.NewClient(server.URL, server.Client())
+
+	var rpcErr *rpc.Error
+	if _, err := client.CreateToken(context.Background(), rpc.CreateTokenRequest{}); !errors.As(err, &rpcErr) || rpcErr.Code != rpc.CodeUnimplemented {
+		t.Errorf("CreateToken error = %v, want unimplemented", err)
+	}
+
+	tests := []struct {
+		name, method, path, contentType, body string
+		status                                int
+	}{
+		{"GET", http.MethodGet, "/vcauth.v1.AuthService/VerifyToken", "application/json", "", http.StatusNotImplemented},
+		{"unknown method", http.MethodPost, "/vcauth.v1.AuthService/Nope", "application/json", "{}", http.StatusNotImplemented},
+		{"binary body", http.MethodPost, "/vcauth.v1.AuthService/VerifyToken", "application/proto", "", http.StatusBadRequest},
+		{"unknown field", http.MethodPost, "/vcauth.v1.AuthService/VerifyToken", "application/json", `{"token":"x","nonce":"y"}`, http.StatusBadRequest},
+		{"no token", http.MethodPost, "/vcauth.v1.AuthService/VerifyToken", "application/json", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
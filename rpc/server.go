@@ -0,0 +1,289 @@
+// Package rpc serves an auth.Service as a verification microservice, following the AuthService
+// definition of proto/vcauth/v1/auth.proto over the Connect protocol: every call is an HTTP POST of a
+// JSON message to /vcauth.v1.AuthService/<Method>, answered with a JSON message or a Connect error.
+// Clients in other languages are generated from the .proto file; Client is the Go client.
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/state"
+)
+
+// ServicePath is the path prefix of the AuthService methods.
+const ServicePath = "/vcauth.v1.AuthService/"
+
+const (
+	defaultChallengeLifetime = 5 * time.Minute
+	maxRequestSize           = 1 << 20
+)
+
+// Challenge states, as stored in the state.Store.
+var (
+	challengeIssued = []byte("issued")
+	challengeUsed   = []byte("used")
+)
+
+// CreateChallengeResponse is the response of CreateChallenge.
+type CreateChallengeResponse struct {
+	Challenge string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CreateTokenRequest is the request of CreateToken.
+type CreateTokenRequest struct {
+	Credentials     []string `json:"credentials"`
+	HolderDID       string   `json:"holderDid"`
+	Signer          string   `json:"signer,omitempty"` // First provider option, e.g. the signer address
+	Nonce           string   `json:"nonce,omitempty"`
+	Audience        string   `json:"audience,omitempty"`
+	LifetimeSeconds int32    `json:"lifetimeSeconds,omitempty"`
+	KeyID           string   `json:"keyId,omitempty"`
+}
+
+// CreateTokenResponse is the response of CreateToken.
+type CreateTokenResponse struct {
+	Token string `json:"token"`
+}
+
+// VerifyTokenRequest is the request of VerifyToken.
+type VerifyTokenRequest struct {
+	Token     string `json:"token"`
+	Challenge string `json:"challenge,omitempty"` // Unused challenge the VP nonce must equal
+	Audience  string `json:"audience,omitempty"`  // Audience the VP must be addressed to
+}
+
+// Server serves the AuthService methods of a Service. It is an http.Handler, to mount at the root of
+// a server or under a prefix with http.StripPrefix. It is safe for concurrent use.
+type Server struct {
+	service           *auth.Service
+	store             state.Store
+	clock             clock.Clock
+	challengeLifetime time.Duration
+	createToken       bool
+	verifyOpts        []auth.VerifyOpt
+}
+
+// Opt configures a Server.
+type Opt func(*Server)
+
+// WithStore sets where issued challenges are kept (default: in memory). Share a Redis or SQL store
+// between instances behind a load balancer, so a challenge can be redeemed on any of them.
+func WithStore(store state.Store) Opt {
+	return func(s *Server) {
+		s.store = store
+	}
+}
+
+// WithChallengeLifetime sets how long a challenge can be redeemed (default: 5 minutes).
+func WithChallengeLifetime(lifetime time.Duration) Opt {
+	return func(s *Server) {
+		s.challengeLifetime = lifetime
+	}
+}
+
+// WithClock sets the time source of challenge expiry.
+func WithClock(c clock.Clock) Opt {
+	return func(s *Server) {
+		s.clock = clock.OrSystem(c)
+	}
+}
+
+// WithCreateToken enables the CreateToken method, which signs presentations with the service's
+// provider for any caller. Leave it disabled unless the network in front of the server authenticates
+// callers as holders.
+func WithCreateToken() Opt {
+	return func(s *Server) {
+		s.createToken = true
+	}
+}
+
+// WithVerifyOpts sets options applied to every VerifyToken call, e.g. a policy or allowed algorithms.
+func WithVerifyOpts(opts ...auth.VerifyOpt) Opt {
+	return func(s *Server) {
+		s.verifyOpts = opts
+	}
+}
+
+// NewServer creates a Server for service.
+func NewServer(service *auth.Service, opts ...Opt) *Server {
+	s := &Server{
+		service:           service,
+		clock:             clock.System(),
+		challengeLifetime: defaultChallengeLifetime,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.store == nil {
+		s.store = state.NewMemoryStore(state.WithMemoryClock(s.clock))
+	}
+	return s
+}
+
+// ServeHTTP dispatches a unary Connect call with a JSON body.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, &Error{Code: CodeUnimplemented, Message: "unary calls are POST requests"})
+		return
+	}
+	if mediaType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";"); strings.TrimSpace(mediaType) != "application/json" {
+		writeError(w, &Error{Code: CodeInvalidArgument, Message: "only application/json is supported"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize+1))
+	if err != nil {
+		writeError(w, &Error{Code: CodeInvalidArgument, Message: "failed to read request body"})
+		return
+	}
+	if len(body) > maxRequestSize {
+		writeError(w, &Error{Code: CodeResourceExhausted, Message: fmt.Sprintf("request exceeds %d bytes", maxRequestSize)})
+		return
+	}
+
+	var response any
+	switch strings.TrimPrefix(r.URL.Path, ServicePath) {
+	case "CreateChallenge":
+		response, err = s.createChallenge(r.Context())
+	case "CreateToken":
+		var req CreateTokenRequest
+		if err = decode(body, &req); err == nil {
+			response, err = s.createTokenCall(r.Context(), req)
+		}
+	case "VerifyToken":
+		var req VerifyTokenRequest
+		if err = decode(body, &req); err == nil {
+			response, err = s.verifyToken(r.Context(), req)
+		}
+	default:
+		err = &Error{Code: CodeUnimplemented, Message: "unknown method " + r.URL.Path}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var data []byte
+	if result, ok := response.(auth.VerificationResult); ok {
+		data, err = auth.MarshalVerificationResult(result)
+	} else {
+		data, err = json.Marshal(response)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func (s *Server) createChallenge(ctx context.Context) (CreateChallengeResponse, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return CreateChallengeResponse{}, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := s.store.Create(ctx, challengeKey(challenge), challengeIssued, s.challengeLifetime); err != nil {
+		return CreateChallengeResponse{}, err
+	}
+	return CreateChallengeResponse{Challenge: challenge, ExpiresAt: s.clock.Now().Add(s.challengeLifetime).UTC()}, nil
+}
+
+func (s *Server) createTokenCall(ctx context.Context, req CreateTokenRequest) (CreateTokenResponse, error) {
+	if !s.createToken {
+		return CreateTokenResponse{}, &Error{Code: CodeUnimplemented, Message: "CreateToken is disabled"}
+	}
+
+	var opts []any
+	if req.Signer != "" {
+		opts = append(opts, req.Signer)
+	}
+	if req.Nonce != "" {
+		opts = append(opts, auth.WithNonce(req.Nonce))
+	}
+	if req.Audience != "" {
+		opts = append(opts, auth.WithAudience(req.Audience))
+	}
+	if req.LifetimeSeconds < 0 {
+		return CreateTokenResponse{}, &Error{Code: CodeInvalidArgument, Message: "lifetimeSeconds must not be negative"}
+	}
+	if req.LifetimeSeconds > 0 {
+		opts = append(opts, auth.WithExpiry(time.Duration(req.LifetimeSeconds)*time.Second))
+	}
+	if req.KeyID != "" {
+		opts = append(opts, auth.WithKeyID(req.KeyID))
+	}
+	opts = append(opts, auth.WithOutputFormat(auth.OutputCompact))
+
+	token, err := s.service.CreateToken(ctx, req.Credentials, req.HolderDID, opts...)
+	if err != nil {
+		return CreateTokenResponse{}, createTokenError(err)
+	}
+	return CreateTokenResponse{Token: token}, nil
+}
+
+func (s *Server) verifyToken(ctx context.Context, req VerifyTokenRequest) (auth.VerificationResult, error) {
+	if req.Token == "" {
+		return auth.VerificationResult{}, &Error{Code: CodeInvalidArgument, Message: "token is required"}
+	}
+
+	opts := s.verifyOpts[:len(s.verifyOpts):len(s.verifyOpts)]
+	if req.Challenge != "" {
+		// Redeem the challenge before verifying, so concurrent replays of one presentation cannot
+		// both pass; a presentation that then fails verification has spent its challenge.
+		err := s.store.Swap(ctx, challengeKey(req.Challenge), challengeIssued, challengeUsed, s.challengeLifetime)
+		if errors.Is(err, state.ErrNotFound) || errors.Is(err, state.ErrConflict) {
+			return auth.VerificationResult{}, &Error{Code: CodeFailedPrecondition, Message: "unknown, expired or used challenge"}
+		}
+		if err != nil {
+			return auth.VerificationResult{}, err
+		}
+		opts = append(opts, auth.WithExpectedNonce(req.Challenge))
+	}
+	if req.Audience != "" {
+		opts = append(opts, auth.WithExpectedAudience(req.Audience))
+	}
+
+	return auth.NewVerificationResult(s.service.VerifyToken(ctx, req.Token, opts...)), nil
+}
+
+// createTokenError maps a CreateToken failure to a Connect error; input errors are the caller's.
+func createTokenError(err error) error {
+	var credErr *auth.CredentialError
+	switch {
+	case errors.Is(err, auth.ErrEmptyCredentialList), errors.Is(err, auth.ErrInvalidHolderDID), errors.As(err, &credErr):
+		return &Error{Code: CodeInvalidArgument, Message: err.Error()}
+	case errors.Is(err, auth.ErrClosed):
+		return &Error{Code: CodeUnavailable, Message: err.Error()}
+	}
+	return err
+}
+
+func challengeKey(challenge string) string {
+	return "rpc-challenge:" + challenge
+}
+
+// decode unmarshals a request message, rejecting unknown fields as a mistyped field would otherwise
+// be silently ignored.
+func decode(body []byte, v any) error {
+	dec := json.NewDecoder(strings.NewReader(string(body)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return &Error{Code: CodeInvalidArgument, Message: fmt.Sprintf("invalid request: %v", err)}
+	}
+	return nil
+}
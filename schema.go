@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github/hovanhoa/go-vc-auth/schema"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// WithSchemaSource sets where credential schemas are loaded from, e.g. a schema.Registry with
+// caching, pinned digests or an offline bundle. By default every schema is downloaded on use and
+// checked against the hashlink of its id, if any.
+func WithSchemaSource(source schema.Source) Option {
+	return func(a *Service) {
+		a.schemas = source
+	}
+}
+
+// validateSchema validates the credential contents against every schema listed in credentialSchema.
+// Schemas are loaded with ctx so a cancelled request stops the validation.
+func (a *Service) validateSchema(ctx context.Context, credContents map[string]any) error {
+	for _, key := range []string{"type", "credentialSchema", "credentialSubject"} {
+		if _, exists := credContents[key]; !exists {
+			return fmt.Errorf("%s is required", key)
+		}
+	}
+
+	schemas, ok := credContents["credentialSchema"].([]any)
+	if !ok {
+		schemas = []any{credContents["credentialSchema"]}
+	}
+
+	for _, entry := range schemas {
+		schemaMap, ok := entry.(map[string]any)
+		if !ok {
+			return errors.New("credentialSchema must be an object")
+		}
+
+		schemaID, ok := schemaMap["id"].(string)
+		if !ok || schemaID == "" {
+			return errors.New("credentialSchema.id must be a non-empty string")
+		}
+
+		schemaBytes, err := a.schemas.Schema(ctx, schemaID)
+		if err != nil {
+			return fmt.Errorf("failed to validate schema: %w", err)
+		}
+
+		result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schemaBytes), gojsonschema.NewGoLoader(credContents))
+		if err != nil {
+			return fmt.Errorf("failed to validate schema: %w", err)
+		}
+		if !result.Valid() {
+			return fmt.Errorf("credential validation failed: %v", result.Errors())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package schema
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/url"
+)
+
+// Multihash prefix of a SHA-256 digest: function code 0x12, length 32.
+var sha256Multihash = []byte{0x12, 0x20}
+
+// multihashes maps the multihash function codes accepted by CheckDigestMultibase to their hash.
+var multihashes = map[byte]func() hash.Hash{
+	0x12: sha256.New,
+	0x13: sha512.New,
+	0x20: sha512.New384,
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// Hashlink returns schemaURL with an "hl" query parameter holding the base58btc multihash of
+// the SHA-256 digest of schema, so that credentials referencing it pin the exact content.
+func Hashlink(schemaURL string, schema []byte) (string, error) {
+	u, err := url.Parse(schemaURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid schema URL: %w", err)
+	}
+
+	digest := sha256.Sum256(schema)
+	query := u.Query()
+	query.Set("hl", "z"+encodeBase58(append(append([]byte(nil), sha256Multihash...), digest[:]...)))
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// HashlinkDigest returns the SHA-256 digest pinned by the "hl" query parameter of id,
+// or nil when id carries no hashlink.
+func HashlinkDigest(id string) ([]byte, error) {
+	u, err := url.Parse(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	hl := u.Query().Get("hl")
+	if hl == "" {
+		return nil, nil
+	}
+	if hl[0] != 'z' {
+		return nil, fmt.Errorf("hashlink %q is not base58btc multibase", hl)
+	}
+
+	multihash, err := decodeBase58(hl[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hashlink %q: %w", hl, err)
+	}
+	if len(multihash) != len(sha256Multihash)+sha256.Size || !bytes.HasPrefix(multihash, sha256Multihash) {
+		return nil, fmt.Errorf("hashlink %q is not a SHA-256 multihash", hl)
+	}
+
+	return multihash[len(sha256Multihash):], nil
+}
+
+// CheckDigestMultibase reports whether digest, a multibase-encoded (base58btc "z" or base64url "u")
+// multihash of SHA-256, SHA-384 or SHA-512 such as the digestMultibase of a VC relatedResource, is the
+// digest of data. It returns an error wrapping ErrIntegrity on a mismatch.
+func CheckDigestMultibase(data []byte, digest string) error {
+	if digest == "" {
+		return fmt.Errorf("%w: empty digest", ErrIntegrity)
+	}
+
+	var multihash []byte
+	var err error
+	switch digest[0] {
+	case 'z':
+		multihash, err = decodeBase58(digest[1:])
+	case 'u':
+		multihash, err = base64.RawURLEncoding.DecodeString(digest[1:])
+	default:
+		return fmt.Errorf("%w: unsupported multibase prefix %q", ErrIntegrity, digest[0])
+	}
+	if err != nil {
+		return fmt.Errorf("%w: invalid digest %q: %v", ErrIntegrity, digest, err)
+	}
+
+	if len(multihash) < 2 || multihashes[multihash[0]] == nil {
+		return fmt.Errorf("%w: unsupported multihash in %q", ErrIntegrity, digest)
+	}
+	h := multihashes[multihash[0]]()
+	if int(multihash[1]) != h.Size() || len(multihash) != 2+h.Size() {
+		return fmt.Errorf("%w: invalid multihash length in %q", ErrIntegrity, digest)
+	}
+
+	h.Write(data)
+	if !bytes.Equal(h.Sum(nil), multihash[2:]) {
+		return fmt.Errorf("%w: digest %s does not match", ErrIntegrity, digest)
+	}
+	return nil
+}
+
+// encodeBase58 encodes data with the Bitcoin base58 alphabet.
+func encodeBase58(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix, mod := big.NewInt(58), new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// decodeBase58 decodes a Bitcoin base58 string.
+func decodeBase58(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range []byte(s) {
+		digit := bytes.IndexByte([]byte(base58Alphabet), c)
+		if digit < 0 {
+			return nil, errors.New("invalid base58 character")
+		}
+		n.Mul(n, radix).Add(n, big.NewInt(int64(digit)))
+	}
+
+	var leadingZeros int
+	for leadingZeros < len(s) && s[leadingZeros] == base58Alphabet[0] {
+		leadingZeros++
+	}
+
+	return append(make([]byte, leadingZeros), n.Bytes()...), nil
+}
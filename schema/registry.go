@@ -0,0 +1,230 @@
+// Package schema fetches, caches and pins the JSON Schemas referenced by credentialSchema.
+package schema
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+const (
+	defaultTTL         = time.Hour
+	defaultHTTPTimeout = 10 * time.Second
+	maxSchemaSize      = 1 << 20
+)
+
+var (
+	// ErrNotFound is returned for schemas that are neither bundled nor, in offline mode, cached.
+	ErrNotFound = errors.New("schema not found")
+	// ErrIntegrity is returned when a schema does not match its pinned digest or hashlink, and by
+	// CheckDigestMultibase.
+	ErrIntegrity = errors.New("schema integrity check failed")
+)
+
+// Source returns the JSON Schema document identified by id, usually the credentialSchema id.
+type Source interface {
+	Schema(ctx context.Context, id string) ([]byte, error)
+}
+
+// Opt configures a Registry.
+type Opt func(*Registry)
+
+// WithHTTPClient sets the HTTP client used to download schemas.
+func WithHTTPClient(client *http.Client) Opt {
+	return func(r *Registry) {
+		r.httpClient = client
+	}
+}
+
+// WithTTL sets how long downloaded schemas are cached (default: 1h); zero disables caching.
+// Bundled schemas never expire.
+func WithTTL(ttl time.Duration) Opt {
+	return func(r *Registry) {
+		r.ttl = ttl
+	}
+}
+
+// WithOffline makes the registry serve bundled schemas only, never touching the network.
+func WithOffline() Opt {
+	return func(r *Registry) {
+		r.offline = true
+	}
+}
+
+// WithClock sets the time source used for cache expiry (default: the system clock).
+func WithClock(c clock.Clock) Opt {
+	return func(r *Registry) {
+		r.clock = clock.OrSystem(c)
+	}
+}
+
+// cacheEntry is a cached schema document.
+type cacheEntry struct {
+	data      []byte
+	fetchedAt time.Time
+	bundled   bool
+}
+
+// Registry is a Source that downloads schemas over HTTP, caches them, serves offline bundles and
+// checks their integrity. A schema is rejected with ErrIntegrity when its SHA-256 digest differs from
+// the digest pinned with Pin or from the hashlink ("hl" query parameter) of its id.
+// A Registry is safe for concurrent use.
+type Registry struct {
+	httpClient *http.Client
+	ttl        time.Duration
+	offline    bool
+	clock      clock.Clock
+
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+	pins    map[string][]byte
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry(opts ...Opt) *Registry {
+	r := &Registry{
+		httpClient: &http.Client{Timeout: defaultHTTPTimeout},
+		ttl:        defaultTTL,
+		clock:      clock.System(),
+		entries:    map[string]cacheEntry{},
+		pins:       map[string][]byte{},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Pin requires the schema id to have the given SHA-256 digest, whether bundled or downloaded.
+func (r *Registry) Pin(id string, digest []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pins[id] = append([]byte(nil), digest...)
+}
+
+// Add bundles the schema document for id, so it is served without a download.
+func (r *Registry) Add(id string, schema []byte) error {
+	if err := r.checkIntegrity(id, schema); err != nil {
+		return err
+	}
+	if !json.Valid(schema) {
+		return fmt.Errorf("schema %s is not valid JSON", id)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[id] = cacheEntry{data: append([]byte(nil), schema...), bundled: true}
+	return nil
+}
+
+// LoadBundle adds every schema of an offline bundle: a JSON object mapping schema ids to schema documents.
+func (r *Registry) LoadBundle(bundle io.Reader) error {
+	var schemas map[string]json.RawMessage
+	if err := json.NewDecoder(bundle).Decode(&schemas); err != nil {
+		return fmt.Errorf("invalid schema bundle: %w", err)
+	}
+
+	for id, schema := range schemas {
+		if err := r.Add(id, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Schema returns the schema document for id from the bundle or cache, downloading it when needed.
+// The returned bytes must not be modified.
+func (r *Registry) Schema(ctx context.Context, id string) ([]byte, error) {
+	r.mu.RLock()
+	entry, ok := r.entries[id]
+	r.mu.RUnlock()
+
+	if ok && (entry.bundled || r.clock.Now().Sub(entry.fetchedAt) < r.ttl) {
+		return entry.data, nil
+	}
+	if r.offline {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	data, err := r.fetch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkIntegrity(id, data); err != nil {
+		return nil, err
+	}
+
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.entries[id] = cacheEntry{data: data, fetchedAt: r.clock.Now()}
+		r.mu.Unlock()
+	}
+
+	return data, nil
+}
+
+// checkIntegrity compares the digest of schema with the pin and hashlink of id, if any.
+func (r *Registry) checkIntegrity(id string, schema []byte) error {
+	digest := sha256.Sum256(schema)
+
+	r.mu.RLock()
+	pinned, ok := r.pins[id]
+	r.mu.RUnlock()
+	if ok && !bytes.Equal(pinned, digest[:]) {
+		return fmt.Errorf("%w: %s does not match its pinned digest", ErrIntegrity, id)
+	}
+
+	linked, err := HashlinkDigest(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIntegrity, err)
+	}
+	if linked != nil && !bytes.Equal(linked, digest[:]) {
+		return fmt.Errorf("%w: %s does not match its hashlink", ErrIntegrity, id)
+	}
+
+	return nil
+}
+
+// fetch downloads the schema at id.
+func (r *Registry) fetch(ctx context.Context, id string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSchemaSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(body) > maxSchemaSize {
+		return nil, fmt.Errorf("schema %s exceeds %d bytes", id, maxSchemaSize)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,107 @@
+package schema_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/schema"
+)
+
+const testSchema = `{"type":"object"}`
+
+// newSchemaServer serves body at every path and counts requests.
+func newSchemaServer(t *testing.T, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestRegistryCachesUntilTTL(t *testing.T) {
+	server, hits := newSchemaServer(t, testSchema)
+	fake := clock.NewFake(time.Now())
+	r := schema.NewRegistry(schema.WithTTL(time.Minute), schema.WithClock(fake))
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.Schema(context.Background(), server.URL+"/employee"); err != nil {
+			t.Fatalf("Schema failed: %v", err)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("expected one download, got %d", hits.Load())
+	}
+
+	fake.Advance(2 * time.Minute)
+	if _, err := r.Schema(context.Background(), server.URL+"/employee"); err != nil {
+		t.Fatalf("Schema failed: %v", err)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("expected a refresh after the TTL, got %d downloads", hits.Load())
+	}
+}
+
+func TestRegistryRejectsOversizedSchema(t *testing.T) {
+	server, _ := newSchemaServer(t, `{"description":"`+strings.Repeat("x", 2<<20)+`"}`)
+	r := schema.NewRegistry()
+
+	if _, err := r.Schema(context.Background(), server.URL+"/huge"); err == nil {
+		t.Fatal("expected an oversized schema to be rejected")
+	}
+}
+
+func TestRegistryOfflineBundle(t *testing.T) {
+	r := schema.NewRegistry(schema.WithOffline())
+	bundle := `{"https://schemas.example/employee": ` + testSchema + `}`
+	if err := r.LoadBundle(strings.NewReader(bundle)); err != nil {
+		t.Fatalf("LoadBundle failed: %v", err)
+	}
+
+	got, err := r.Schema(context.Background(), "https://schemas.example/employee")
+	if err != nil || string(got) != testSchema {
+		t.Fatalf("unexpected bundled schema %q, %v", got, err)
+	}
+
+	if _, err := r.Schema(context.Background(), "https://schemas.example/other"); !errors.Is(err, schema.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound offline, got %v", err)
+	}
+}
+
+func TestRegistryIntegrity(t *testing.T) {
+	server, _ := newSchemaServer(t, testSchema)
+	r := schema.NewRegistry()
+
+	linked, err := schema.Hashlink(server.URL+"/employee", []byte(testSchema))
+	if err != nil {
+		t.Fatalf("Hashlink failed: %v", err)
+	}
+	if _, err := r.Schema(context.Background(), linked); err != nil {
+		t.Fatalf("hashlinked schema rejected: %v", err)
+	}
+
+	tampered, _ := schema.Hashlink(server.URL+"/tampered", []byte(`{"type":"string"}`))
+	if _, err := r.Schema(context.Background(), tampered); !errors.Is(err, schema.ErrIntegrity) {
+		t.Fatalf("expected ErrIntegrity for hashlink mismatch, got %v", err)
+	}
+
+	digest := sha256.Sum256([]byte(`{"type":"array"}`))
+	r.Pin(server.URL+"/pinned", digest[:])
+	if _, err := r.Schema(context.Background(), server.URL+"/pinned"); !errors.Is(err, schema.ErrIntegrity) {
+		t.Fatalf("expected ErrIntegrity for pin mismatch, got %v", err)
+	}
+	if err := r.Add(server.URL+"/pinned", []byte(testSchema)); !errors.Is(err, schema.ErrIntegrity) {
+		t.Fatalf("expected bundling a mismatching schema to fail, got %v", err)
+	}
+}
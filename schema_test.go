@@ -0,0 +1,66 @@
+package auth_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/schema"
+)
+
+// TestVerifyCredentialSchemaSource ensures schemas come from the configured source rather than the network.
+func TestVerifyCredentialSchemaSource(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+
+	// The bundled schema is stricter than the one the test registry serves.
+	schemas := schema.NewRegistry(schema.WithOffline())
+	if err := schemas.Add(authtest.SchemaURL, []byte(`{"type":"object","required":["credentialSubject","evidence"]}`)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	a := registry.newAuth(authtest.NewSigner(holder), auth.WithSchemaSource(schemas))
+	if _, err := a.VerifyCredential(context.Background(), vcJwt); err == nil {
+		t.Fatal("expected the bundled schema to reject the credential")
+	}
+
+	if _, err := registry.newAuth(authtest.NewSigner(holder)).VerifyCredential(context.Background(), vcJwt); err != nil {
+		t.Fatalf("default schema source failed: %v", err)
+	}
+}
+
+// countingSource counts schema lookups.
+type countingSource struct {
+	schema.Source
+	lookups atomic.Int32
+}
+
+func (s *countingSource) Schema(ctx context.Context, id string) ([]byte, error) {
+	s.lookups.Add(1)
+	return s.Source.Schema(ctx, id)
+}
+
+// TestVerifyCredentialSchemaAfterSignature ensures forged credentials cannot trigger schema downloads.
+func TestVerifyCredentialSchemaAfterSignature(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	impostor := registry.newIdentity(t)
+
+	source := &countingSource{Source: schema.NewRegistry()}
+	a := registry.newAuth(authtest.NewSigner(holder), auth.WithSchemaSource(source))
+
+	parts := strings.Split(registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"}), ".")
+	forged := signJWT(t, parts[0]+"."+parts[1], impostor.Key)
+	if _, err := a.VerifyCredential(context.Background(), forged); err == nil {
+		t.Fatal("expected a forged credential to be rejected")
+	}
+	if n := source.lookups.Load(); n != 0 {
+		t.Errorf("expected no schema lookup for a forged credential, got %d", n)
+	}
+}
@@ -0,0 +1,84 @@
+// Package secret wraps sensitive values such as private keys and access tokens so they are
+// redacted when printed, logged or marshaled, and can be wiped from memory once used.
+package secret
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// redacted replaces the secret's value in every printed or encoded form.
+const redacted = "[REDACTED]"
+
+// Secret holds a sensitive value. Copies of a Secret share the same memory, so Zero on one
+// wipes them all. The zero Secret is empty.
+type Secret struct {
+	value []byte
+}
+
+// New returns a Secret holding a copy of s. The string itself cannot be wiped; prefer FromBytes
+// when the value is available as a byte slice.
+func New(s string) Secret {
+	return Secret{value: []byte(s)}
+}
+
+// FromBytes returns a Secret that takes ownership of b: Zero overwrites b.
+func FromBytes(b []byte) Secret {
+	return Secret{value: b}
+}
+
+// Bytes returns the secret's value. The slice is shared with the Secret and wiped by Zero.
+func (s Secret) Bytes() []byte {
+	return s.value
+}
+
+// Reveal returns the secret's value as a string, e.g. for an HTTP header. The returned string
+// is a copy that Zero cannot reach, so keep its lifetime short.
+func (s Secret) Reveal() string {
+	return string(s.value)
+}
+
+// IsEmpty reports whether the secret holds no value.
+func (s Secret) IsEmpty() bool {
+	return len(s.value) == 0
+}
+
+// Zero overwrites the secret's value with zeros. It is best effort: copies made by Reveal,
+// by the garbage collector or by callers are not reached.
+func (s Secret) Zero() {
+	clear(s.value)
+}
+
+// String returns a redaction marker instead of the value.
+func (s Secret) String() string {
+	return redacted
+}
+
+// GoString returns a redaction marker instead of the value, for the %#v verb.
+func (s Secret) GoString() string {
+	return "secret.Secret(" + redacted + ")"
+}
+
+// Format prints a redaction marker for every verb, so the value cannot leak through %x or %q.
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		_, _ = f.Write([]byte(s.GoString()))
+		return
+	}
+	_, _ = f.Write([]byte(redacted))
+}
+
+// LogValue redacts the secret in log/slog output.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+// MarshalJSON encodes a redaction marker instead of the value.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+// MarshalText encodes a redaction marker instead of the value.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
@@ -0,0 +1,51 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecretRedacted(t *testing.T) {
+	s := New("hunter2")
+	wrapped := struct {
+		Password Secret `json:"password"`
+	}{s}
+
+	var logged bytes.Buffer
+	slog.New(slog.NewTextHandler(&logged, nil)).Info("login", "password", s)
+	encoded, err := json.Marshal(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := []string{
+		s.String(),
+		fmt.Sprint(s), fmt.Sprintf("%v %+v %#v %s %q %x", s, wrapped, wrapped, s, s, s),
+		string(encoded), logged.String(),
+	}
+	for _, out := range outputs {
+		if strings.Contains(out, "hunter2") || strings.Contains(out, fmt.Sprintf("%x", "hunter2")) {
+			t.Errorf("secret leaked: %s", out)
+		}
+	}
+	if s.Reveal() != "hunter2" {
+		t.Errorf("Reveal() = %q", s.Reveal())
+	}
+}
+
+func TestSecretZero(t *testing.T) {
+	b := []byte("private")
+	s := FromBytes(b)
+	copied := s
+	s.Zero()
+	if !bytes.Equal(b, make([]byte, len(b))) || !bytes.Equal(copied.Bytes(), make([]byte, len(b))) {
+		t.Error("Zero did not wipe the value and its copies")
+	}
+	if !(Secret{}).IsEmpty() || s.IsEmpty() {
+		t.Error("unexpected IsEmpty result")
+	}
+}
@@ -0,0 +1,131 @@
+// Package shamir splits secrets into shares with Shamir's secret sharing over GF(2^8), so that
+// any threshold of them reassembles the secret and fewer reveal nothing about it.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidShares is returned by Combine when the shares are malformed or inconsistent.
+var ErrInvalidShares = errors.New("shamir: invalid shares")
+
+// Split divides secret into parts shares, any threshold of which reassemble it.
+// threshold must be between 2 and parts, and parts at most 255. Each share is one byte longer than
+// the secret: the trailing byte holds the share's x coordinate.
+func Split(secret []byte, parts, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("shamir: secret must not be empty")
+	}
+	if parts > 255 {
+		return nil, fmt.Errorf("shamir: parts must be at most 255, got %d", parts)
+	}
+	if threshold < 2 || threshold > parts {
+		return nil, fmt.Errorf("shamir: threshold must be between 2 and %d, got %d", parts, threshold)
+	}
+
+	shares := make([][]byte, parts)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+
+	// One random polynomial of degree threshold-1 per secret byte, with the byte as constant term.
+	coefficients := make([]byte, threshold)
+	defer clear(coefficients)
+	for b, s := range secret {
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, fmt.Errorf("shamir: failed to generate coefficients: %w", err)
+		}
+		coefficients[0] = s
+		for _, share := range shares {
+			share[b] = evaluate(coefficients, share[len(secret)])
+		}
+	}
+	return shares, nil
+}
+
+// Combine reassembles the secret from at least threshold shares produced by Split.
+// Too few shares yield a wrong secret rather than an error, as Shamir shares carry no threshold.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("%w: at least 2 shares are required", ErrInvalidShares)
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, fmt.Errorf("%w: share too short", ErrInvalidShares)
+	}
+
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, fmt.Errorf("%w: shares differ in length", ErrInvalidShares)
+		}
+		x := share[size-1]
+		if x == 0 || seen[x] {
+			return nil, fmt.Errorf("%w: duplicate or zero share index", ErrInvalidShares)
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-1)
+	ys := make([]byte, len(shares))
+	for b := range secret {
+		for i, share := range shares {
+			ys[i] = share[b]
+		}
+		secret[b] = interpolateAtZero(xs, ys)
+	}
+	return secret, nil
+}
+
+// evaluate returns the polynomial with the given coefficients (constant term first) at x.
+func evaluate(coefficients []byte, x byte) byte {
+	var result byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = mul(result, x) ^ coefficients[i]
+	}
+	return result
+}
+
+// interpolateAtZero returns the value at 0 of the Lagrange polynomial through the points (xs, ys).
+func interpolateAtZero(xs, ys []byte) byte {
+	var result byte
+	for i := range xs {
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			// (0 - x_j) / (x_i - x_j); subtraction is XOR in GF(2^8).
+			basis = mul(basis, div(xs[j], xs[i]^xs[j]))
+		}
+		result ^= mul(ys[i], basis)
+	}
+	return result
+}
+
+// mul multiplies in GF(2^8) with the AES polynomial x^8 + x^4 + x^3 + x + 1, without
+// data-dependent branches.
+func mul(a, b byte) byte {
+	var result byte
+	for i := 0; i < 8; i++ {
+		result ^= a & -(b & 1)
+		carry := -(a >> 7)
+		a = a<<1 ^ carry&0x1b
+		b >>= 1
+	}
+	return result
+}
+
+// div divides a by the non-zero b in GF(2^8), using b^254 as its inverse.
+func div(a, b byte) byte {
+	inverse := b
+	for i := 0; i < 6; i++ {
+		inverse = mul(mul(inverse, inverse), b)
+	}
+	return mul(a, mul(inverse, inverse))
+}
@@ -0,0 +1,76 @@
+package shamir
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("correct horse battery staple, 32")
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("got %d shares, want 5", len(shares))
+	}
+
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var picked [][]byte
+		for _, i := range subset {
+			picked = append(picked, shares[i])
+		}
+		got, err := Combine(picked)
+		if err != nil {
+			t.Fatalf("Combine %v: %v", subset, err)
+		}
+		if !bytes.Equal(got, secret) {
+			t.Errorf("Combine %v = %q, want %q", subset, got, secret)
+		}
+	}
+
+	got, err := Combine(shares[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(got, secret) {
+		t.Error("two shares below the threshold reassembled the secret")
+	}
+}
+
+func TestSplitRejectsInvalidParameters(t *testing.T) {
+	for name, tc := range map[string]struct{ parts, threshold int }{
+		"threshold one":        {3, 1},
+		"threshold over parts": {2, 3},
+		"too many parts":       {256, 2},
+	} {
+		if _, err := Split([]byte("secret"), tc.parts, tc.threshold); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCombineRejectsInvalidShares(t *testing.T) {
+	shares, err := Split([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, input := range map[string][][]byte{
+		"single":    shares[:1],
+		"duplicate": {shares[0], shares[0]},
+		"length":    {shares[0], shares[1][1:]},
+	} {
+		if _, err := Combine(input); !errors.Is(err, ErrInvalidShares) {
+			t.Errorf("%s: got %v, want ErrInvalidShares", name, err)
+		}
+	}
+}
+
+func TestFieldInverse(t *testing.T) {
+	for b := 1; b < 256; b++ {
+		if got := mul(byte(b), div(1, byte(b))); got != 1 {
+			t.Fatalf("%d * 1/%d = %d", b, b, got)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"fmt"
+
+	"github/hovanhoa/go-vc-auth/did"
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+// SignatureNormalizer rewrites a signature returned by the provider before it goes into a JWS.
+type SignatureNormalizer func(signature []byte) ([]byte, error)
+
+// WithSignatureNormalizer replaces the normalization applied to provider signatures
+// (default: provider.NormalizeLowS). A nil normalizer keeps signatures as the provider returns them.
+func WithSignatureNormalizer(normalize SignatureNormalizer) Option {
+	return func(a *Service) {
+		a.normalize = normalize
+	}
+}
+
+// WithStrictLowS makes verification reject ES256K signatures with a high s value, as strict
+// secp256k1 verifiers do, instead of accepting both forms of a malleable signature.
+func WithStrictLowS() Option {
+	return func(a *Service) {
+		a.strictLowS = true
+	}
+}
+
+// normalizeSignature applies the configured SignatureNormalizer.
+func (a *Service) normalizeSignature(signature []byte) ([]byte, error) {
+	if a.normalize == nil {
+		return signature, nil
+	}
+	normalized, err := a.normalize(signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize signature: %w", err)
+	}
+	return normalized, nil
+}
+
+// checkLowS rejects high-s ES256K signatures in strict mode.
+func (a *Service) checkLowS(alg string, signature []byte) error {
+	if !a.strictLowS || alg != did.AlgES256K || provider.IsLowS(signature) {
+		return nil
+	}
+	return provider.ErrHighS
+}
@@ -0,0 +1,70 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/provider"
+)
+
+// highS returns the high-s twin of a low-s signature r||s: r||(n-s), equally valid.
+func highS(signature []byte) []byte {
+	twin := append([]byte(nil), signature...)
+	s := new(big.Int).SetBytes(signature[32:64])
+	new(big.Int).Sub(crypto.S256().Params().N, s).FillBytes(twin[32:64])
+	return twin
+}
+
+// highSSigner returns high-s signatures, like some Vault plugins.
+type highSSigner struct{ *authtest.Signer }
+
+func (s highSSigner) Sign(ctx context.Context, payload []byte, options *provider.ProviderOption) ([]byte, error) {
+	signature, err := s.Signer.Sign(ctx, payload, options)
+	if err != nil {
+		return nil, err
+	}
+	return highS(signature), nil
+}
+
+func TestLowSNormalization(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	ctx := context.Background()
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+
+	signer := highSSigner{authtest.NewSigner(holder)}
+	token, err := registry.newAuth(signer).CreateToken(ctx, []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	compact := strings.Trim(token, `"`)
+	signature, _ := base64.RawURLEncoding.DecodeString(compact[strings.LastIndex(compact, ".")+1:])
+	if !provider.IsLowS(signature) {
+		t.Fatal("CreateToken kept the provider's high-s signature")
+	}
+
+	strict := registry.newAuth(nil, auth.WithStrictLowS())
+	if _, err := strict.VerifyToken(ctx, token); err != nil {
+		t.Fatalf("strict VerifyToken of a normalized token: %v", err)
+	}
+
+	raw, err := registry.newAuth(signer, auth.WithSignatureNormalizer(nil)).CreateToken(ctx, []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken without normalization: %v", err)
+	}
+	if _, err := registry.newAuth(nil).VerifyToken(ctx, raw); err != nil {
+		t.Errorf("lenient VerifyToken of a high-s token: %v", err)
+	}
+	if _, err := strict.VerifyToken(ctx, raw); !errors.Is(err, provider.ErrHighS) {
+		t.Errorf("strict VerifyToken of a high-s token = %v, want ErrHighS", err)
+	}
+}
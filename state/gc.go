@@ -0,0 +1,143 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// Sweeper is implemented by stores that keep expired entries until told to remove them: the memory
+// and SQL stores of this package, crossdevice.Store, and the memory and SQL auth.TokenStore. Redis
+// expires keys itself and needs no sweeping.
+type Sweeper interface {
+	// DeleteExpired removes the entries that expired at or before before and returns how many it removed.
+	DeleteExpired(ctx context.Context, before time.Time) (int, error)
+}
+
+// SweeperFunc adapts a function to a Sweeper.
+type SweeperFunc func(ctx context.Context, before time.Time) (int, error)
+
+// DeleteExpired calls f.
+func (f SweeperFunc) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	return f(ctx, before)
+}
+
+// GCStats are the counters of one store registered with a GC.
+type GCStats struct {
+	Runs       int64     // Sweeps run
+	Reaped     int64     // Entries removed over all sweeps
+	Errors     int64     // Sweeps that failed
+	LastRun    time.Time // Start of the last sweep
+	LastReaped int       // Entries removed by the last sweep
+	LastError  error     // Error of the last sweep, nil when it succeeded
+}
+
+// GC periodically removes expired entries from registered stores, so long-running verifiers do not
+// accumulate nonces, sessions and pending requests. It is safe for concurrent use.
+type GC struct {
+	interval time.Duration
+	clock    clock.Clock
+	observe  func(name string, reaped int, err error)
+
+	mu     sync.Mutex
+	stores []gcStore
+	stats  map[string]GCStats
+}
+
+type gcStore struct {
+	name      string
+	sweeper   Sweeper
+	retention time.Duration
+}
+
+// GCOpt configures a GC.
+type GCOpt func(*GC)
+
+// WithGCClock sets the time source of the GC (default: the system clock).
+func WithGCClock(c clock.Clock) GCOpt {
+	return func(g *GC) {
+		g.clock = clock.OrSystem(c)
+	}
+}
+
+// WithGCObserver calls observe after each sweep of a store, e.g. to feed a metrics system.
+func WithGCObserver(observe func(name string, reaped int, err error)) GCOpt {
+	return func(g *GC) {
+		g.observe = observe
+	}
+}
+
+// NewGC creates a GC sweeping its stores every interval once Run is called.
+func NewGC(interval time.Duration, opts ...GCOpt) *GC {
+	g := &GC{interval: interval, clock: clock.System(), stats: map[string]GCStats{}}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Register adds a store, named for its stats, whose entries are removed retention after they expire.
+func (g *GC) Register(name string, sweeper Sweeper, retention time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.stores = append(g.stores, gcStore{name: name, sweeper: sweeper, retention: retention})
+	g.stats[name] = GCStats{}
+}
+
+// Collect sweeps every registered store once and returns the number of entries removed. A failing
+// store does not stop the others; its error is recorded in its stats.
+func (g *GC) Collect(ctx context.Context) int {
+	g.mu.Lock()
+	stores := append([]gcStore(nil), g.stores...)
+	g.mu.Unlock()
+
+	var total int
+	for _, store := range stores {
+		start := g.clock.Now()
+		reaped, err := store.sweeper.DeleteExpired(ctx, start.Add(-store.retention))
+		total += reaped
+
+		g.mu.Lock()
+		stats := g.stats[store.name]
+		stats.Runs++
+		stats.Reaped += int64(reaped)
+		stats.LastRun, stats.LastReaped, stats.LastError = start, reaped, err
+		if err != nil {
+			stats.Errors++
+		}
+		g.stats[store.name] = stats
+		g.mu.Unlock()
+
+		if g.observe != nil {
+			g.observe(store.name, reaped, err)
+		}
+	}
+	return total
+}
+
+// Run calls Collect every interval until ctx is done.
+func (g *GC) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-g.clock.After(g.interval):
+			g.Collect(ctx)
+		}
+	}
+}
+
+// Stats returns the counters of every registered store by name.
+func (g *GC) Stats() map[string]GCStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stats := make(map[string]GCStats, len(g.stats))
+	for name, s := range g.stats {
+		stats[name] = s
+	}
+	return stats
+}
@@ -0,0 +1,130 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// memoryStore keeps values in process memory. Expired values are dropped when read or swept.
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	clock   clock.Clock
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time // zero for no expiry
+}
+
+// MemoryOpt configures a memory Store.
+type MemoryOpt func(*memoryStore)
+
+// WithMemoryClock sets the time source of expiry (default: the system clock).
+func WithMemoryClock(c clock.Clock) MemoryOpt {
+	return func(s *memoryStore) {
+		s.clock = clock.OrSystem(c)
+	}
+}
+
+// NewMemoryStore creates a Store keeping its values in process memory, suitable for a single instance.
+func NewMemoryStore(opts ...MemoryOpt) Store {
+	s := &memoryStore{entries: map[string]memoryEntry{}, clock: clock.System()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.live(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return bytes.Clone(entry.value), nil
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = s.entry(value, ttl)
+	return nil
+}
+
+func (s *memoryStore) Create(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.live(key); ok {
+		return ErrExists
+	}
+	s.entries[key] = s.entry(value, ttl)
+	return nil
+}
+
+func (s *memoryStore) Swap(ctx context.Context, key string, old, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.live(key)
+	if !ok {
+		return ErrNotFound
+	}
+	if !bytes.Equal(entry.value, old) {
+		return ErrConflict
+	}
+	s.entries[key] = s.entry(value, ttl)
+	return nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// DeleteExpired removes the entries that expired at or before before.
+func (s *memoryStore) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed int
+	for key, entry := range s.entries {
+		if !entry.expires.IsZero() && !entry.expires.After(before) {
+			delete(s.entries, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// live returns the entry of key unless it is missing or expired, dropping expired entries.
+func (s *memoryStore) live(key string) (memoryEntry, bool) {
+	entry, ok := s.entries[key]
+	if !ok {
+		return memoryEntry{}, false
+	}
+	if !entry.expires.IsZero() && !s.clock.Now().Before(entry.expires) {
+		delete(s.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, true
+}
+
+func (s *memoryStore) entry(value []byte, ttl time.Duration) memoryEntry {
+	entry := memoryEntry{value: bytes.Clone(value)}
+	if ttl > 0 {
+		entry.expires = s.clock.Now().Add(ttl)
+	}
+	return entry
+}
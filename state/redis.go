@@ -0,0 +1,112 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RedisDo sends one command to Redis and returns its reply: nil for a nil reply, a string or []byte
+// for bulk strings, an int64 for integers. It keeps this package free of a Redis client dependency;
+// with go-redis:
+//
+//	do := func(ctx context.Context, args ...any) (any, error) {
+//	    reply, err := rdb.Do(ctx, args...).Result()
+//	    if errors.Is(err, redis.Nil) {
+//	        return nil, nil
+//	    }
+//	    return reply, err
+//	}
+type RedisDo func(ctx context.Context, args ...any) (any, error)
+
+// swapScript sets KEYS[1] to ARGV[2] if it holds ARGV[1], with ARGV[3] milliseconds of expiry when
+// positive. It returns 1 when swapped, 0 on a different value and -1 when the key is missing.
+const swapScript = `
+local current = redis.call('GET', KEYS[1])
+if not current then return -1 end
+if current ~= ARGV[1] then return 0 end
+if tonumber(ARGV[3]) > 0 then
+  redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+  redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1`
+
+// redisStore keeps values in Redis, relying on its key expiry.
+type redisStore struct {
+	do     RedisDo
+	prefix string
+}
+
+// NewRedisStore creates a Store over Redis, prefixing every key with prefix, e.g. "vcauth:".
+func NewRedisStore(do RedisDo, prefix string) Store {
+	return &redisStore{do: do, prefix: prefix}
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := s.do(ctx, "GET", s.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, ErrNotFound
+	}
+	return replyBytes(reply)
+}
+
+func (s *redisStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := s.do(ctx, withExpiry([]any{"SET", s.prefix + key, value}, ttl)...)
+	return err
+}
+
+func (s *redisStore) Create(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	reply, err := s.do(ctx, append(withExpiry([]any{"SET", s.prefix + key, value}, ttl), "NX")...)
+	if err != nil {
+		return err
+	}
+	if reply == nil {
+		return ErrExists
+	}
+	return nil
+}
+
+func (s *redisStore) Swap(ctx context.Context, key string, old, value []byte, ttl time.Duration) error {
+	reply, err := s.do(ctx, "EVAL", swapScript, 1, s.prefix+key, old, value, max(ttl.Milliseconds(), 0))
+	if err != nil {
+		return err
+	}
+	switch reply {
+	case int64(1):
+		return nil
+	case int64(0):
+		return ErrConflict
+	case int64(-1):
+		return ErrNotFound
+	}
+	return fmt.Errorf("unexpected reply %v to swap script", reply)
+}
+
+func (s *redisStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", s.prefix+key)
+	return err
+}
+
+// withExpiry appends a PX argument to a SET command when ttl is positive.
+func withExpiry(args []any, ttl time.Duration) []any {
+	if ttl > 0 {
+		return append(args, "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	}
+	return args
+}
+
+func replyBytes(reply any) ([]byte, error) {
+	switch v := reply.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	}
+	return nil, errors.New("unexpected Redis reply type")
+}
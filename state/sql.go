@@ -0,0 +1,231 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// Dialect selects the SQL syntax of a database/sql Store.
+type Dialect int
+
+// Supported SQL dialects.
+const (
+	DialectPostgres Dialect = iota
+	DialectMySQL
+	DialectSQLite
+)
+
+// sqlIdentifier matches table names accepted by NewSQLStore.
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Migrations returns the statements creating table for dialect, as run by Migrate. They are
+// idempotent, so they can also be copied into an existing migration tool.
+func Migrations(table string, dialect Dialect) ([]string, error) {
+	if !sqlIdentifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+
+	var valueType string
+	switch dialect {
+	case DialectPostgres:
+		valueType = "BYTEA"
+	case DialectMySQL:
+		valueType = "LONGBLOB"
+	case DialectSQLite:
+		valueType = "BLOB"
+	default:
+		return nil, fmt.Errorf("unknown SQL dialect %d", dialect)
+	}
+
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n"+
+		"    k          VARCHAR(255) PRIMARY KEY,\n"+
+		"    v          %s NOT NULL,\n"+
+		"    expires_at BIGINT NOT NULL\n"+
+		")", table, valueType)
+	index := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_expires_at ON %s (expires_at)", indexPrefix(table), table)
+	if dialect == DialectMySQL {
+		// MySQL has no CREATE INDEX IF NOT EXISTS; declare the index with the table instead.
+		create = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n"+
+			"    k          VARCHAR(255) PRIMARY KEY,\n"+
+			"    v          %s NOT NULL,\n"+
+			"    expires_at BIGINT NOT NULL,\n"+
+			"    INDEX %s_expires_at (expires_at)\n"+
+			")", table, valueType, indexPrefix(table))
+		return []string{create}, nil
+	}
+	return []string{create, index}, nil
+}
+
+// Migrate creates table in db for dialect unless it exists.
+func Migrate(ctx context.Context, db *sql.DB, table string, dialect Dialect) error {
+	statements, err := Migrations(table, dialect)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to migrate %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// indexPrefix names indexes after table, without its schema qualifier.
+func indexPrefix(table string) string {
+	return table[strings.LastIndex(table, ".")+1:]
+}
+
+// sqlStore keeps values in a database/sql table created by Migrate.
+type sqlStore struct {
+	db    *sql.DB
+	clock clock.Clock
+
+	get, upsert, insert, purge, swap, remove, sweep string
+}
+
+// SQLOpt configures a database/sql Store.
+type SQLOpt func(*sqlStore)
+
+// WithSQLClock sets the time source of expiry (default: the system clock).
+func WithSQLClock(c clock.Clock) SQLOpt {
+	return func(s *sqlStore) {
+		s.clock = clock.OrSystem(c)
+	}
+}
+
+// NewSQLStore creates a Store backed by table in db, which Migrate creates. Expiry times are stored as
+// Unix milliseconds, 0 meaning none; expired rows are ignored when read and replaced by Create.
+func NewSQLStore(db *sql.DB, table string, dialect Dialect, opts ...SQLOpt) (Store, error) {
+	if _, err := Migrations(table, dialect); err != nil {
+		return nil, err
+	}
+
+	p := func(n int) string {
+		if dialect == DialectPostgres {
+			return fmt.Sprintf("$%d", n)
+		}
+		return "?"
+	}
+
+	s := &sqlStore{
+		db:    db,
+		clock: clock.System(),
+		get:   fmt.Sprintf("SELECT v FROM %s WHERE k = %s AND (expires_at = 0 OR expires_at > %s)", table, p(1), p(2)),
+		purge: fmt.Sprintf("DELETE FROM %s WHERE k = %s AND expires_at <> 0 AND expires_at <= %s", table, p(1), p(2)),
+		swap: fmt.Sprintf("UPDATE %s SET v = %s, expires_at = %s WHERE k = %s AND v = %s AND (expires_at = 0 OR expires_at > %s)",
+			table, p(1), p(2), p(3), p(4), p(5)),
+		remove: fmt.Sprintf("DELETE FROM %s WHERE k = %s", table, p(1)),
+		sweep:  fmt.Sprintf("DELETE FROM %s WHERE expires_at <> 0 AND expires_at <= %s", table, p(1)),
+	}
+	switch dialect {
+	case DialectMySQL:
+		s.upsert = fmt.Sprintf("INSERT INTO %s (k, v, expires_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE v = VALUES(v), expires_at = VALUES(expires_at)", table)
+		s.insert = fmt.Sprintf("INSERT IGNORE INTO %s (k, v, expires_at) VALUES (?, ?, ?)", table)
+	default:
+		s.upsert = fmt.Sprintf("INSERT INTO %s (k, v, expires_at) VALUES (%s, %s, %s) ON CONFLICT (k) DO UPDATE SET v = excluded.v, expires_at = excluded.expires_at",
+			table, p(1), p(2), p(3))
+		s.insert = fmt.Sprintf("INSERT INTO %s (k, v, expires_at) VALUES (%s, %s, %s) ON CONFLICT (k) DO NOTHING", table, p(1), p(2), p(3))
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+func (s *sqlStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRowContext(ctx, s.get, key, s.now()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (s *sqlStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx, s.upsert, key, value, s.expiresAt(ttl))
+	return err
+}
+
+func (s *sqlStore) Create(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if _, err := s.db.ExecContext(ctx, s.purge, key, s.now()); err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, s.insert, key, value, s.expiresAt(ttl))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrExists
+	}
+	return nil
+}
+
+func (s *sqlStore) Swap(ctx context.Context, key string, old, value []byte, ttl time.Duration) error {
+	now := s.now()
+	result, err := s.db.ExecContext(ctx, s.swap, value, s.expiresAt(ttl), key, old, now)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// No row changed: the key is missing, holds another value, or (MySQL counting changed rows only)
+	// already held the new value.
+	current, err := s.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, old) && bytes.Equal(old, value) {
+		return nil
+	}
+	return ErrConflict
+}
+
+func (s *sqlStore) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.remove, key)
+	return err
+}
+
+// DeleteExpired removes the rows that expired at or before before.
+func (s *sqlStore) DeleteExpired(ctx context.Context, before time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, s.sweep, before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (s *sqlStore) now() int64 {
+	return s.clock.Now().UnixMilli()
+}
+
+// expiresAt returns the expiry column for ttl.
+func (s *sqlStore) expiresAt(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return s.clock.Now().Add(ttl).UnixMilli()
+}
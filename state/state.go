@@ -0,0 +1,66 @@
+// Package state is the key/value storage layer shared by the SDK's stores (issued tokens, rate limit
+// counters, cross-device sessions), so a deployment can keep all of them in one backend: memory for a
+// single instance, or Redis or a SQL database for several.
+package state
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for keys that do not exist or have expired.
+	ErrNotFound = errors.New("state: key not found")
+	// ErrExists is returned by Create for keys that already exist.
+	ErrExists = errors.New("state: key already exists")
+	// ErrConflict is returned by Swap when the key no longer holds the expected value.
+	ErrConflict = errors.New("state: value changed concurrently")
+)
+
+// Store is a key/value store with per-key expiry. A ttl <= 0 stores the value without expiry.
+// Implementations must be safe for concurrent use, and Create and Swap must be atomic across every
+// client of the backend.
+type Store interface {
+	// Get returns the value of key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put stores value under key, replacing any value.
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Create stores value under key, failing with ErrExists when key holds a value.
+	Create(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Swap replaces the value of key with value if it still is old, failing with ErrConflict otherwise
+	// and with ErrNotFound when key holds no value.
+	Swap(ctx context.Context, key string, old, value []byte, ttl time.Duration) error
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// maxUpdateAttempts bounds the retries of Update under contention.
+const maxUpdateAttempts = 16
+
+// Update applies fn to the value of key and stores the result with ttl, retrying when another client
+// changes the value in between. fn must not have side effects, as it may run several times; an error
+// from fn aborts the update and is returned.
+func Update(ctx context.Context, s Store, key string, ttl time.Duration, fn func(value []byte) ([]byte, error)) ([]byte, error) {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		old, err := s.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := fn(bytes.Clone(old))
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.Swap(ctx, key, old, value, ttl)
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+	return nil, ErrConflict
+}
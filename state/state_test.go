@@ -0,0 +1,221 @@
+package state_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/state"
+)
+
+// fakeRedis implements the commands used by the Redis store over a map, on the given clock.
+type fakeRedis struct {
+	mu      sync.Mutex
+	clock   *clock.Fake
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newFakeRedis(c *clock.Fake) *fakeRedis {
+	return &fakeRedis{clock: c, values: map[string]string{}, expires: map[string]time.Time{}}
+}
+
+func (r *fakeRedis) get(key string) (string, bool) {
+	if exp, ok := r.expires[key]; ok && !r.clock.Now().Before(exp) {
+		delete(r.values, key)
+		delete(r.expires, key)
+	}
+	value, ok := r.values[key]
+	return value, ok
+}
+
+func (r *fakeRedis) set(key, value string, px string) {
+	r.values[key] = value
+	delete(r.expires, key)
+	if ms, _ := strconv.ParseInt(px, 10, 64); ms > 0 {
+		r.expires[key] = r.clock.Now().Add(time.Duration(ms) * time.Millisecond)
+	}
+}
+
+func (r *fakeRedis) Do(ctx context.Context, args ...any) (any, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	str := func(i int) string {
+		switch v := args[i].(type) {
+		case []byte:
+			return string(v)
+		case string:
+			return v
+		default:
+			return strconv.FormatInt(v.(int64), 10)
+		}
+	}
+
+	switch args[0] {
+	case "GET":
+		if value, ok := r.get(str(1)); ok {
+			return value, nil
+		}
+		return nil, nil
+	case "SET":
+		var px string
+		var nx bool
+		for i := 3; i < len(args); i++ {
+			switch args[i] {
+			case "PX":
+				px = str(i + 1)
+				i++
+			case "NX":
+				nx = true
+			}
+		}
+		if _, ok := r.get(str(1)); ok && nx {
+			return nil, nil
+		}
+		r.set(str(1), str(2), px)
+		return "OK", nil
+	case "DEL":
+		delete(r.values, str(1))
+		return int64(1), nil
+	case "EVAL":
+		current, ok := r.get(str(3))
+		switch {
+		case !ok:
+			return int64(-1), nil
+		case current != str(4):
+			return int64(0), nil
+		}
+		r.set(str(3), str(5), str(6))
+		return int64(1), nil
+	}
+	return nil, errors.New("unsupported command")
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(*clock.Fake) state.Store{
+		"memory": func(c *clock.Fake) state.Store { return state.NewMemoryStore(state.WithMemoryClock(c)) },
+		"redis":  func(c *clock.Fake) state.Store { return state.NewRedisStore(newFakeRedis(c).Do, "test:") },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			c := clock.NewFake(time.Unix(1700000000, 0))
+			s := newStore(c)
+
+			if _, err := s.Get(ctx, "a"); !errors.Is(err, state.ErrNotFound) {
+				t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+			}
+			if err := s.Create(ctx, "a", []byte("1"), time.Minute); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if err := s.Create(ctx, "a", []byte("2"), time.Minute); !errors.Is(err, state.ErrExists) {
+				t.Errorf("second Create error = %v, want ErrExists", err)
+			}
+
+			if err := s.Swap(ctx, "a", []byte("2"), []byte("3"), time.Minute); !errors.Is(err, state.ErrConflict) {
+				t.Errorf("Swap(stale) error = %v, want ErrConflict", err)
+			}
+			if err := s.Swap(ctx, "missing", nil, []byte("3"), time.Minute); !errors.Is(err, state.ErrNotFound) {
+				t.Errorf("Swap(missing) error = %v, want ErrNotFound", err)
+			}
+			value, err := state.Update(ctx, s, "a", time.Minute, func(value []byte) ([]byte, error) {
+				return append(value, '0'), nil
+			})
+			if err != nil || string(value) != "10" {
+				t.Fatalf("Update = %q, %v", value, err)
+			}
+
+			c.Advance(time.Minute)
+			if _, err := s.Get(ctx, "a"); !errors.Is(err, state.ErrNotFound) {
+				t.Errorf("Get(expired) error = %v, want ErrNotFound", err)
+			}
+			if err := s.Create(ctx, "a", []byte("4"), 0); err != nil {
+				t.Errorf("Create over an expired key: %v", err)
+			}
+
+			if err := s.Put(ctx, "b", []byte("5"), 0); err != nil {
+				t.Fatal(err)
+			}
+			c.Advance(24 * time.Hour)
+			if value, err := s.Get(ctx, "b"); err != nil || string(value) != "5" {
+				t.Errorf("Get(no expiry) = %q, %v", value, err)
+			}
+			if err := s.Delete(ctx, "b"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Get(ctx, "b"); !errors.Is(err, state.ErrNotFound) {
+				t.Errorf("Get(deleted) error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	statements, err := state.Migrations("app.vc_state", state.DialectPostgres)
+	if err != nil || len(statements) != 2 {
+		t.Fatalf("Migrations = %v, %v", statements, err)
+	}
+	if want := "CREATE INDEX IF NOT EXISTS vc_state_expires_at ON app.vc_state (expires_at)"; statements[1] != want {
+		t.Errorf("index statement = %q, want %q", statements[1], want)
+	}
+
+	if _, err := state.Migrations("state; DROP TABLE users", state.DialectMySQL); err == nil {
+		t.Error("Migrations accepted an invalid table name")
+	}
+	if _, err := state.NewSQLStore(nil, "vc_state", state.Dialect(42)); err == nil {
+		t.Error("NewSQLStore accepted an unknown dialect")
+	}
+}
+
+func TestGC(t *testing.T) {
+	ctx := context.Background()
+	c := clock.NewFake(time.Unix(1700000000, 0))
+	memory := state.NewMemoryStore(state.WithMemoryClock(c))
+	_ = memory.Put(ctx, "nonce:1", []byte("x"), time.Minute)
+	_ = memory.Put(ctx, "nonce:2", []byte("x"), time.Hour)
+	_ = memory.Put(ctx, "config", []byte("x"), 0)
+
+	var observed []string
+	broken := errors.New("database down")
+	gc := state.NewGC(time.Minute, state.WithGCClock(c), state.WithGCObserver(func(name string, reaped int, err error) {
+		observed = append(observed, name+":"+strconv.Itoa(reaped))
+	}))
+	gc.Register("nonces", memory.(state.Sweeper), 0)
+	gc.Register("sessions", state.SweeperFunc(func(context.Context, time.Time) (int, error) { return 0, broken }), 0)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		gc.Run(runCtx)
+		close(done)
+	}()
+	for c.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	c.Advance(2 * time.Minute)
+	for gc.Stats()["sessions"].Runs == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	stats := gc.Stats()
+	if s := stats["nonces"]; s.Runs != 1 || s.Reaped != 1 || s.LastError != nil {
+		t.Errorf("nonces stats = %+v, want one run reaping one entry", s)
+	}
+	if s := stats["sessions"]; s.Errors != 1 || !errors.Is(s.LastError, broken) {
+		t.Errorf("sessions stats = %+v, want the error recorded", s)
+	}
+	if len(observed) != 2 || observed[0] != "nonces:1" {
+		t.Errorf("observed = %v", observed)
+	}
+	if _, err := memory.Get(ctx, "nonce:2"); err != nil {
+		t.Errorf("unexpired entry swept: %v", err)
+	}
+}
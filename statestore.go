@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/state"
+)
+
+// tokenRecordGrace keeps presentation records past their expiry, so a revoked token still fails
+// while clock skew tolerance would accept it.
+const tokenRecordGrace = time.Hour
+
+// stateTokenStore keeps presentation records in a state.Store as JSON.
+type stateTokenStore struct {
+	store state.Store
+}
+
+// NewStateTokenStore creates a TokenStore over a state.Store, so issued tokens share the backend of
+// the other stores. Records expire an hour after the presentation; records of presentations without
+// expiry are kept.
+func NewStateTokenStore(store state.Store) TokenStore {
+	return &stateTokenStore{store: store}
+}
+
+func (s *stateTokenStore) Put(ctx context.Context, record PresentationRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Put(ctx, "token:"+record.JTI, data, recordTTL(record))
+}
+
+// Redeem sets UsedAt with a compare-and-swap of the stored record.
+func (s *stateTokenStore) Redeem(ctx context.Context, jti string, at time.Time) error {
+	// The expiry of a record never changes, so its ttl can be taken before the swap.
+	record, err := s.Get(ctx, jti)
+	if err != nil {
+		return err
+	}
+	_, err = state.Update(ctx, s.store, "token:"+jti, recordTTL(record), func(data []byte) ([]byte, error) {
+		var record PresentationRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("invalid presentation record: %w", err)
+		}
+		if !record.UsedAt.IsZero() {
+			return nil, ErrPresentationUsed
+		}
+		record.UsedAt = at
+		return json.Marshal(record)
+	})
+	if errors.Is(err, state.ErrNotFound) {
+		return ErrPresentationNotFound
+	}
+	return err
+}
+
+// recordTTL keeps a record until tokenRecordGrace after its presentation expires, or forever.
+func recordTTL(record PresentationRecord) time.Duration {
+	if record.ExpiresAt.IsZero() {
+		return 0
+	}
+	return max(time.Until(record.ExpiresAt)+tokenRecordGrace, time.Millisecond)
+}
+
+func (s *stateTokenStore) Get(ctx context.Context, jti string) (PresentationRecord, error) {
+	data, err := s.store.Get(ctx, "token:"+jti)
+	if errors.Is(err, state.ErrNotFound) {
+		return PresentationRecord{}, ErrPresentationNotFound
+	}
+	if err != nil {
+		return PresentationRecord{}, err
+	}
+
+	var record PresentationRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return PresentationRecord{}, fmt.Errorf("invalid presentation record: %w", err)
+	}
+	return record, nil
+}
+
+// stateRateLimitStore keeps rate limit counters in a state.Store as decimal strings.
+type stateRateLimitStore struct {
+	store state.Store
+}
+
+// NewStateRateLimitStore creates a RateLimitStore over a state.Store, so limits apply across every
+// instance sharing the backend.
+func NewStateRateLimitStore(store state.Store) RateLimitStore {
+	return &stateRateLimitStore{store: store}
+}
+
+func (s *stateRateLimitStore) Add(ctx context.Context, key string, window time.Time, ttl time.Duration) (int, error) {
+	key = counterKey(key, window)
+	err := s.store.Create(ctx, key, []byte("1"), ttl)
+	if err == nil {
+		return 1, nil
+	}
+	if !errors.Is(err, state.ErrExists) {
+		return 0, err
+	}
+
+	value, err := state.Update(ctx, s.store, key, ttl, func(value []byte) ([]byte, error) {
+		count, err := strconv.Atoi(string(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit counter: %w", err)
+		}
+		return strconv.AppendInt(nil, int64(count+1), 10), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(value))
+}
+
+func (s *stateRateLimitStore) Count(ctx context.Context, key string, window time.Time) (int, error) {
+	value, err := s.store.Get(ctx, counterKey(key, window))
+	if errors.Is(err, state.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(value))
+}
+
+// counterKey names the counter of key for the window starting at window.
+func counterKey(key string, window time.Time) string {
+	return "ratelimit:" + key + ":" + strconv.FormatInt(window.Unix(), 10)
+}
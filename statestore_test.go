@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/state"
+)
+
+func TestStateStores(t *testing.T) {
+	ctx := context.Background()
+	backend := state.NewMemoryStore()
+
+	tokens := NewStateTokenStore(backend)
+	if _, err := tokens.Get(ctx, "unknown"); !errors.Is(err, ErrPresentationNotFound) {
+		t.Errorf("Get(unknown) error = %v, want ErrPresentationNotFound", err)
+	}
+	record := PresentationRecord{JTI: "jti-1", Holder: "did:example:holder", Credentials: []string{"urn:uuid:1"},
+		IssuedAt: time.Unix(1700000000, 0).UTC(), ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Second).UTC()}
+	if err := tokens.Put(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := tokens.Get(ctx, "jti-1"); err != nil || got.Holder != record.Holder || !got.ExpiresAt.Equal(record.ExpiresAt) {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+
+	counters := NewStateRateLimitStore(backend)
+	window := time.Unix(1700000000, 0)
+	for want := 1; want <= 3; want++ {
+		if got, err := counters.Add(ctx, "ip:203.0.113.7", window, time.Minute); err != nil || got != want {
+			t.Fatalf("Add = %d, %v, want %d", got, err, want)
+		}
+	}
+	if got, err := counters.Count(ctx, "ip:203.0.113.7", window); err != nil || got != 3 {
+		t.Errorf("Count = %d, %v, want 3", got, err)
+	}
+	if got, err := counters.Count(ctx, "ip:203.0.113.7", window.Add(time.Minute)); err != nil || got != 0 {
+		t.Errorf("Count(next window) = %d, %v, want 0", got, err)
+	}
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CredentialSubject is a single entry of a credential's credentialSubject.
+// Subjects expressed as a bare id string have only ID set.
+type CredentialSubject struct {
+	ID     string         // Subject identifier, usually a DID
+	Claims map[string]any // Remaining subject properties
+}
+
+// Get returns the value of the named subject property.
+func (s CredentialSubject) Get(name string) (any, bool) {
+	value, ok := s.Claims[name]
+	return value, ok
+}
+
+// MarshalJSON encodes the subject as a flat JSON object, as it appears in the credential.
+func (s CredentialSubject) MarshalJSON() ([]byte, error) {
+	obj := make(map[string]any, len(s.Claims)+1)
+	for k, v := range s.Claims {
+		obj[k] = v
+	}
+	if s.ID != "" {
+		obj["id"] = s.ID
+	}
+
+	// HTML characters are left to the caller's encoder, which escapes them unless told otherwise.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(obj); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// UnmarshalJSON decodes a subject from either a JSON object or a bare id string.
+func (s *CredentialSubject) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	subjects, err := parseCredentialSubjects(raw)
+	if err != nil {
+		return err
+	}
+	if len(subjects) != 1 {
+		return fmt.Errorf("expected a single credential subject, got %d", len(subjects))
+	}
+
+	*s = subjects[0]
+	return nil
+}
+
+// parseCredentialSubjects normalizes every shape credentialSubject may take — a single object,
+// an array of objects, a bare id string, or an array mixing both — into a slice of subjects.
+func parseCredentialSubjects(raw any) ([]CredentialSubject, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []CredentialSubject{{ID: v}}, nil
+	case map[string]any:
+		claims := make(map[string]any, len(v))
+		var id string
+		for k, value := range v {
+			if k == "id" {
+				idStr, ok := value.(string)
+				if !ok {
+					return nil, fmt.Errorf("credentialSubject id must be a string, got %T", value)
+				}
+				id = idStr
+				continue
+			}
+			claims[k] = value
+		}
+		return []CredentialSubject{{ID: id, Claims: claims}}, nil
+	case []any:
+		subjects := make([]CredentialSubject, 0, len(v))
+		for i, item := range v {
+			if _, nested := item.([]any); nested {
+				return nil, fmt.Errorf("credentialSubject at index %d must not be an array", i)
+			}
+			parsed, err := parseCredentialSubjects(item)
+			if err != nil {
+				return nil, fmt.Errorf("credentialSubject at index %d: %w", i, err)
+			}
+			subjects = append(subjects, parsed...)
+		}
+		return subjects, nil
+	default:
+		return nil, fmt.Errorf("unsupported credentialSubject type: %T", raw)
+	}
+}
@@ -0,0 +1,39 @@
+package auth
+
+import "testing"
+
+// TestParseCredentialSubjects covers every credentialSubject shape allowed by the data model.
+func TestParseCredentialSubjects(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  any
+		want []string
+	}{
+		{"missing", nil, nil},
+		{"id string", "did:example:1", []string{"did:example:1"}},
+		{"object", map[string]any{"id": "did:example:1", "role": "viewer"}, []string{"did:example:1"}},
+		{"object without id", map[string]any{"role": "viewer"}, []string{""}},
+		{"array", []any{map[string]any{"id": "did:example:1"}, "did:example:2"}, []string{"did:example:1", "did:example:2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subjects, err := parseCredentialSubjects(tt.raw)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(subjects) != len(tt.want) {
+				t.Fatalf("expected %d subjects, got %d", len(tt.want), len(subjects))
+			}
+			for i, id := range tt.want {
+				if subjects[i].ID != id {
+					t.Fatalf("subject %d: expected id %q, got %q", i, id, subjects[i].ID)
+				}
+			}
+		})
+	}
+
+	if _, err := parseCredentialSubjects(42.0); err == nil {
+		t.Fatalf("expected error for numeric credentialSubject")
+	}
+}
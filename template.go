@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrMissingPlaceholder is returned by CredentialTemplate.Instantiate when a required placeholder has no value.
+var ErrMissingPlaceholder = errors.New("template placeholder not filled")
+
+// placeholderPattern matches {{name}} and optional {{name?}} placeholders.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.]*)(\?)?\s*\}\}`)
+
+// templateFields are the credential properties a template may set; they map onto CredentialDocument.
+var templateFields = map[string]bool{
+	"@context": true, "id": true, "type": true, "issuer": true, "validFrom": true, "validUntil": true,
+	"credentialSchema": true, "credentialStatus": true, "credentialSubject": true,
+}
+
+// CredentialTemplate is a credential layout in the W3C JSON form with {{name}} placeholders,
+// defined once and instantiated per subject.
+//
+// A string that is exactly one placeholder is replaced by the variable's value of any JSON type;
+// placeholders inside longer strings are replaced by the value's text. Placeholders are required
+// unless written {{name?}}: an unfilled optional placeholder removes its property or array item,
+// or is replaced by "" inside a longer string.
+type CredentialTemplate struct {
+	layout   map[string]any
+	required map[string]bool
+}
+
+// ParseCredentialTemplate parses a JSON credential layout such as
+//
+//	{"type": ["VerifiableCredential", "EmployeeCredential"], "issuer": "{{issuer}}",
+//	 "validUntil": "{{expires?}}", "credentialSubject": {"id": "{{subject}}", "name": "{{name}}"}}
+func ParseCredentialTemplate(layout []byte) (*CredentialTemplate, error) {
+	var parsed map[string]any
+	if err := json.Unmarshal(layout, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid credential template: %w", err)
+	}
+
+	for name := range parsed {
+		if !templateFields[name] {
+			return nil, fmt.Errorf("invalid credential template: unsupported property %q", name)
+		}
+	}
+
+	t := &CredentialTemplate{layout: parsed, required: map[string]bool{}}
+	t.collect(parsed)
+	return t, nil
+}
+
+// collect records the placeholders used in value; a name required anywhere is required.
+func (t *CredentialTemplate) collect(value any) {
+	switch v := value.(type) {
+	case string:
+		for _, match := range placeholderPattern.FindAllStringSubmatch(v, -1) {
+			t.required[match[1]] = t.required[match[1]] || match[2] == ""
+		}
+	case map[string]any:
+		for _, item := range v {
+			t.collect(item)
+		}
+	case []any:
+		for _, item := range v {
+			t.collect(item)
+		}
+	}
+}
+
+// Placeholders returns the sorted names of all placeholders in the template.
+func (t *CredentialTemplate) Placeholders() []string {
+	names := make([]string, 0, len(t.required))
+	for name := range t.required {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Instantiate fills the placeholders with vars and returns the resulting document.
+// It fails with ErrMissingPlaceholder, naming every missing variable, when a required placeholder is unfilled.
+func (t *CredentialTemplate) Instantiate(vars map[string]any) (CredentialDocument, error) {
+	var missing []string
+	for _, name := range t.Placeholders() {
+		if _, ok := vars[name]; !ok && t.required[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return CredentialDocument{}, fmt.Errorf("%w: %s", ErrMissingPlaceholder, strings.Join(missing, ", "))
+	}
+
+	filled, _ := substitute(t.layout, vars)
+
+	// Round-trip through JSON so variables of Go types, e.g. time.Time, take their JSON form.
+	data, err := json.Marshal(filled)
+	if err != nil {
+		return CredentialDocument{}, fmt.Errorf("failed to encode credential: %w", err)
+	}
+	var credential map[string]any
+	if err := json.Unmarshal(data, &credential); err != nil {
+		return CredentialDocument{}, fmt.Errorf("failed to decode credential: %w", err)
+	}
+
+	return documentFromJSON(credential)
+}
+
+// substitute returns value with its placeholders filled from vars; keep is false when value was
+// an unfilled optional placeholder and must be dropped.
+func substitute(value any, vars map[string]any) (result any, keep bool) {
+	switch v := value.(type) {
+	case string:
+		if match := placeholderPattern.FindStringSubmatch(v); match != nil && match[0] == v {
+			filled, ok := vars[match[1]]
+			return filled, ok
+		}
+		return placeholderPattern.ReplaceAllStringFunc(v, func(placeholder string) string {
+			filled, ok := vars[placeholderPattern.FindStringSubmatch(placeholder)[1]]
+			if !ok {
+				return ""
+			}
+			return templateText(filled)
+		}), true
+	case map[string]any:
+		object := make(map[string]any, len(v))
+		for name, item := range v {
+			if filled, ok := substitute(item, vars); ok {
+				object[name] = filled
+			}
+		}
+		return object, true
+	case []any:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			if filled, ok := substitute(item, vars); ok {
+				list = append(list, filled)
+			}
+		}
+		return list, true
+	}
+	return value, true
+}
+
+// templateText formats a variable embedded in a longer string.
+func templateText(value any) string {
+	if t, ok := value.(time.Time); ok {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+// ParseCredentialDocument converts an unsigned credential in the W3C JSON form, such as the body of a
+// VC-API issuance request, into a CredentialDocument for IssueCredentials.
+func ParseCredentialDocument(data []byte) (CredentialDocument, error) {
+	var credential map[string]any
+	if err := json.Unmarshal(data, &credential); err != nil {
+		return CredentialDocument{}, fmt.Errorf("invalid credential: %w", err)
+	}
+	return documentFromJSON(credential)
+}
+
+// documentFromJSON converts a credential in the W3C JSON form into a CredentialDocument.
+func documentFromJSON(credential map[string]any) (CredentialDocument, error) {
+	document := CredentialDocument{
+		ID:     stringField(credential, "id"),
+		Types:  stringList(credential["type"]),
+		Issuer: issuerID(credential["issuer"]),
+	}
+
+	switch contexts := credential["@context"].(type) {
+	case string:
+		document.Context = []any{contexts}
+	case []any:
+		document.Context = contexts
+	}
+
+	var err error
+	if document.ValidFrom, err = timeField(credential, "validFrom"); err != nil {
+		return CredentialDocument{}, err
+	}
+	if document.ValidUntil, err = timeField(credential, "validUntil"); err != nil {
+		return CredentialDocument{}, err
+	}
+
+	for _, raw := range objectList(credential["credentialSchema"]) {
+		document.Schemas = append(document.Schemas, CredentialSchema{
+			ID:   stringField(raw, "id"),
+			Type: stringField(raw, "type"),
+		})
+	}
+	for _, raw := range objectList(credential["credentialStatus"]) {
+		document.Status = append(document.Status, CredentialStatus{
+			ID:                   stringField(raw, "id"),
+			Type:                 stringField(raw, "type"),
+			StatusPurpose:        stringField(raw, "statusPurpose"),
+			StatusListIndex:      stringOrNumberField(raw, "statusListIndex"),
+			StatusListCredential: stringField(raw, "statusListCredential"),
+		})
+	}
+
+	if raw, ok := credential["credentialSubject"]; ok {
+		subject, ok := raw.(map[string]any)
+		if !ok {
+			return CredentialDocument{}, errors.New("credentialSubject must be a single object")
+		}
+		document.Subject = subject
+	}
+
+	return document, nil
+}
@@ -0,0 +1,108 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+const employeeTemplate = `{
+	"type": ["VerifiableCredential", "EmployeeCredential"],
+	"issuer": "{{issuer}}",
+	"validUntil": "{{expires?}}",
+	"credentialSchema": {"id": "{{schema}}", "type": "JsonSchema"},
+	"credentialSubject": {
+		"id": "{{subject}}",
+		"name": "{{name}}",
+		"level": "{{level}}",
+		"title": "{{role}} at Example Corp",
+		"nickname": "{{nickname?}}"
+	}
+}`
+
+func TestCredentialTemplate(t *testing.T) {
+	template, err := auth.ParseCredentialTemplate([]byte(employeeTemplate))
+	if err != nil {
+		t.Fatalf("ParseCredentialTemplate failed: %v", err)
+	}
+
+	want := []string{"expires", "issuer", "level", "name", "nickname", "role", "schema", "subject"}
+	if got := template.Placeholders(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected placeholders %v", got)
+	}
+
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	document, err := template.Instantiate(map[string]any{
+		"issuer":  "did:nda:testnet:0xissuer",
+		"expires": expires,
+		"schema":  "https://schemas.example/employee",
+		"subject": "did:nda:testnet:0xsubject",
+		"name":    "Alice",
+		"level":   3,
+		"role":    "Engineer",
+	})
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+
+	if document.Issuer != "did:nda:testnet:0xissuer" || !document.ValidUntil.Equal(expires) {
+		t.Fatalf("unexpected document: %+v", document)
+	}
+	if len(document.Schemas) != 1 || document.Schemas[0].ID != "https://schemas.example/employee" {
+		t.Fatalf("unexpected schemas: %+v", document.Schemas)
+	}
+	wantSubject := map[string]any{"id": "did:nda:testnet:0xsubject", "name": "Alice", "level": float64(3), "title": "Engineer at Example Corp"}
+	if !reflect.DeepEqual(document.Subject, wantSubject) {
+		t.Fatalf("unexpected subject %v", document.Subject)
+	}
+
+	_, err = template.Instantiate(map[string]any{"issuer": "did:nda:testnet:0xissuer", "name": "Alice"})
+	if !errors.Is(err, auth.ErrMissingPlaceholder) || !strings.Contains(err.Error(), "level, role, schema, subject") {
+		t.Fatalf("expected ErrMissingPlaceholder naming the missing variables, got %v", err)
+	}
+}
+
+func TestParseCredentialTemplateRejectsUnknownProperty(t *testing.T) {
+	if _, err := auth.ParseCredentialTemplate([]byte(`{"issuer": "{{issuer}}", "evidence": []}`)); err == nil {
+		t.Fatal("expected unsupported property to be rejected")
+	}
+}
+
+// TestIssueFromTemplate issues a credential instantiated from a template.
+func TestIssueFromTemplate(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(issuer))
+
+	template, err := auth.ParseCredentialTemplate([]byte(employeeTemplate))
+	if err != nil {
+		t.Fatalf("ParseCredentialTemplate failed: %v", err)
+	}
+	document, err := template.Instantiate(map[string]any{
+		"issuer": issuer.DID, "schema": authtest.SchemaURL, "subject": holder.DID,
+		"name": "Alice", "level": 3, "role": "Engineer",
+	})
+	if err != nil {
+		t.Fatalf("Instantiate failed: %v", err)
+	}
+
+	results, err := a.IssueCredentials(context.Background(), []auth.CredentialDocument{document}, issuer.Address)
+	if err != nil || results[0].Err != nil {
+		t.Fatalf("IssueCredentials failed: %v, %v", err, results[0].Err)
+	}
+
+	claims, err := a.VerifyCredential(context.Background(), results[0].Credential)
+	if err != nil {
+		t.Fatalf("VerifyCredential failed: %v", err)
+	}
+	if title, _ := claims.Subject().Get("title"); title != "Engineer at Example Corp" || !claims.HasType("EmployeeCredential") {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStepTimeout is returned when a verification step exceeds its budget set with WithVerifyTimeouts.
+var ErrStepTimeout = errors.New("verification step timed out")
+
+// VerifyTimeouts bounds the time a verification spends waiting on external dependencies, so one slow
+// dependency cannot consume the whole request deadline. Zero durations are not bounded beyond the
+// caller's context.
+type VerifyTimeouts struct {
+	Resolve time.Duration // Each DID resolution
+	Status  time.Duration // Each revocation or issuer trust lookup
+	Total   time.Duration // The whole VerifyToken or VerifyCredential call
+}
+
+// WithVerifyTimeouts enforces per-step timeouts with contexts derived from the caller's. A step that
+// exceeds its budget fails verification with ErrStepTimeout; the caller's own deadline is reported
+// as context.DeadlineExceeded as before.
+func WithVerifyTimeouts(timeouts VerifyTimeouts) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.timeouts = timeouts
+	}
+}
+
+// timeoutsKey is the context key of the VerifyTimeouts of the running verification.
+type timeoutsKey struct{}
+
+// withTimeouts returns ctx bounded by the total budget and carrying the step budgets, and the
+// function releasing its resources.
+func withTimeouts(ctx context.Context, timeouts VerifyTimeouts) (context.Context, context.CancelFunc) {
+	if timeouts == (VerifyTimeouts{}) {
+		return ctx, func() {}
+	}
+
+	ctx = context.WithValue(ctx, timeoutsKey{}, timeouts)
+	if timeouts.Total <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, timeouts.Total, fmt.Errorf("%w: verification exceeded %s", ErrStepTimeout, timeouts.Total))
+}
+
+// runStep runs step with a context bounded by the step budget that budget picks from the verification's timeouts.
+// When that budget, rather than the caller's context, expires, the error wraps ErrStepTimeout.
+func runStep(ctx context.Context, name string, budget func(VerifyTimeouts) time.Duration, step func(context.Context) error) error {
+	timeouts, _ := ctx.Value(timeoutsKey{}).(VerifyTimeouts)
+	d := budget(timeouts)
+	if d <= 0 {
+		return stepError(ctx, step(ctx))
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	err := step(stepCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s exceeded %s: %w", ErrStepTimeout, name, d, err)
+	}
+	return stepError(ctx, err)
+}
+
+// stepError reports a step failing because the total budget expired as ErrStepTimeout.
+func stepError(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, ErrStepTimeout) && !errors.Is(err, ErrStepTimeout) {
+		return fmt.Errorf("%w: %w", cause, err)
+	}
+	return err
+}
+
+func resolveBudget(t VerifyTimeouts) time.Duration { return t.Resolve }
+func statusBudget(t VerifyTimeouts) time.Duration  { return t.Status }
@@ -0,0 +1,66 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/trust"
+)
+
+// slowRegistry is an IssuerRegistry that never answers before its context is done.
+type slowRegistry struct{}
+
+func (slowRegistry) IsTrusted(ctx context.Context, issuer trust.Issuer) (bool, error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+func TestVerifyTimeouts(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	_, err = a.VerifyToken(ctx, token, auth.WithIssuerRegistry(slowRegistry{}),
+		auth.WithVerifyTimeouts(auth.VerifyTimeouts{Status: 20 * time.Millisecond}))
+	if !errors.Is(err, auth.ErrStepTimeout) {
+		t.Errorf("slow trust check: error = %v, want ErrStepTimeout", err)
+	}
+
+	_, err = a.VerifyToken(ctx, token, auth.WithIssuerRegistry(slowRegistry{}),
+		auth.WithVerifyTimeouts(auth.VerifyTimeouts{Total: 50 * time.Millisecond}))
+	if !errors.Is(err, auth.ErrStepTimeout) {
+		t.Errorf("total budget: error = %v, want ErrStepTimeout", err)
+	}
+
+	callerCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = a.VerifyToken(callerCtx, token, auth.WithIssuerRegistry(slowRegistry{}),
+		auth.WithVerifyTimeouts(auth.VerifyTimeouts{Status: time.Minute, Total: time.Minute}))
+	if errors.Is(err, auth.ErrStepTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("caller deadline: error = %v, want context.DeadlineExceeded only", err)
+	}
+
+	slowDIDs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slowDIDs.Close()
+	slow := auth.NewAuth(authtest.NewSigner(holder), slowDIDs.URL+"/did")
+	_, err = slow.VerifyToken(ctx, token, auth.WithVerifyTimeouts(auth.VerifyTimeouts{Resolve: 20 * time.Millisecond}))
+	if !errors.Is(err, auth.ErrStepTimeout) {
+		t.Errorf("slow resolution: error = %v, want ErrStepTimeout", err)
+	}
+}
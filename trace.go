@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github/hovanhoa/go-vc-auth/redact"
+)
+
+// Verification steps recorded in a VerificationTrace.
+const (
+	StepDecode          = "decode"           // Token parsing and format normalization
+	StepDecrypt         = "decrypt"          // JWE decryption
+	StepResolve         = "resolve"          // DID resolution of the signer
+	StepKeySelection    = "key_selection"    // Verification method lookup and algorithm binding
+	StepSignature       = "signature"        // Signature check
+	StepCertificate     = "certificate"      // X.509 chain binding
+	StepBinding         = "binding"          // Nonce, audience and expiry of the presentation
+	StepKeyAttestation  = "key_attestation"  // Holder key attestation
+	StepRevocation      = "revocation"       // Issued-token registry lookup
+	StepRedemption      = "redemption"       // Redemption of a single-use presentation
+	StepSchema          = "schema"           // Credential schema validation
+	StepIssuerTrust     = "issuer_trust"     // Issuer registry decision
+	StepClaims          = "claims"           // Claims extraction
+	StepValidity        = "validity"         // validFrom/validUntil and nbf/exp of a credential
+	StepPolicy          = "policy"           // Verifier policy evaluation
+	StepDomainLinkage   = "domain_linkage"   // Well-known DID configuration check
+	StepDegraded        = "degraded"         // Stale cached DID document used during a registry outage
+	StepRelatedResource = "related_resource" // Digest check of a credential's relatedResource
+	StepAnchor          = "anchor"           // Ledger anchor lookup of a credential
+)
+
+// Step outcomes.
+const (
+	OutcomePass = "pass"
+	OutcomeFail = "fail"
+)
+
+// TraceStep is one check performed during verification.
+type TraceStep struct {
+	Target  string            `json:"target"`            // "presentation" or "credential[i]"
+	Step    string            `json:"step"`              // One of the Step constants
+	Outcome string            `json:"outcome"`           // OutcomePass or OutcomeFail
+	Reason  string            `json:"reason,omitempty"`  // Error message of a failed step
+	Details map[string]string `json:"details,omitempty"` // Inputs of the step, e.g. the DID resolved or kid selected
+}
+
+// VerificationTrace records the steps of a verification for debugging and support tooling.
+// It is safe for concurrent use and encodes to JSON as {"steps": [...]}.
+type VerificationTrace struct {
+	// Redaction masks step details and reasons as they are recorded, by the paths "<step>.<detail>" and
+	// "<step>.reason", e.g. "issuer_trust.issuer"; nil records them as they are.
+	Redaction *redact.Policy
+
+	mu    sync.Mutex
+	steps []TraceStep
+}
+
+// Steps returns a copy of the recorded steps in order.
+func (t *VerificationTrace) Steps() []TraceStep {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]TraceStep(nil), t.steps...)
+}
+
+// MarshalJSON encodes the trace as {"steps": [...]}.
+func (t *VerificationTrace) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Steps []TraceStep `json:"steps"`
+	}{t.Steps()})
+}
+
+// Failed returns the first failed step, if any.
+func (t *VerificationTrace) Failed() (TraceStep, bool) {
+	for _, step := range t.Steps() {
+		if step.Outcome == OutcomeFail {
+			return step, true
+		}
+	}
+	return TraceStep{}, false
+}
+
+// WithTrace records every verification step in trace, for explaining why a token was accepted or
+// rejected. Tracing adds allocations to every step, so enable it for debugging, not by default.
+func WithTrace(trace *VerificationTrace) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.trace = trace
+	}
+}
+
+// traceKey is the context key of the active traceScope.
+type traceKey struct{}
+
+// traceScope is the trace and target that steps recorded through a context belong to.
+type traceScope struct {
+	trace  *VerificationTrace
+	target string
+}
+
+// withTraceTarget returns ctx recording steps for target into trace; it returns ctx unchanged when trace is nil.
+func withTraceTarget(ctx context.Context, trace *VerificationTrace, target string) context.Context {
+	if trace == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, traceScope{trace: trace, target: target})
+}
+
+// traceStep records the outcome of a step when ctx carries a trace. details are key/value pairs.
+func traceStep(ctx context.Context, step string, err error, details ...string) {
+	scope, ok := ctx.Value(traceKey{}).(traceScope)
+	if !ok {
+		return
+	}
+
+	entry := TraceStep{Target: scope.target, Step: step, Outcome: OutcomePass}
+	policy := scope.trace.Redaction
+	if err != nil {
+		entry.Outcome, entry.Reason = OutcomeFail, policy.String(step+".reason", err.Error())
+	}
+	if len(details) > 1 {
+		entry.Details = make(map[string]string, len(details)/2)
+		for i := 0; i+1 < len(details); i += 2 {
+			entry.Details[details[i]] = policy.String(step+"."+details[i], details[i+1])
+		}
+	}
+
+	scope.trace.mu.Lock()
+	scope.trace.steps = append(scope.trace.steps, entry)
+	scope.trace.mu.Unlock()
+}
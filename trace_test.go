@@ -0,0 +1,72 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+)
+
+func TestVerifyTokenTrace(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	impostor := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	var trace auth.VerificationTrace
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithTrace(&trace)); err != nil {
+		t.Fatalf("VerifyToken failed: %v", err)
+	}
+
+	var got []string
+	for _, step := range trace.Steps() {
+		got = append(got, step.Target+":"+step.Step+":"+step.Outcome)
+	}
+	want := []string{
+		"presentation:decode:pass",
+		"presentation:resolve:pass",
+		"presentation:key_selection:pass",
+		"presentation:signature:pass",
+		"presentation:binding:pass",
+		"presentation:claims:pass",
+		"credential[0]:decode:pass",
+		"credential[0]:resolve:pass",
+		"credential[0]:key_selection:pass",
+		"credential[0]:signature:pass",
+		"credential[0]:schema:pass",
+		"credential[0]:claims:pass",
+		"credential[0]:validity:pass",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected trace:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	encoded, err := json.Marshal(&trace)
+	if err != nil || !strings.Contains(string(encoded), `"steps":[{"target":"presentation","step":"decode","outcome":"pass"}`) {
+		t.Errorf("unexpected JSON trace: %s, %v", encoded, err)
+	}
+
+	// A credential whose signature is made by a different key fails at the signature step.
+	parts := strings.Split(vcJwt, ".")
+	forged := signJWT(t, parts[0]+"."+parts[1], impostor.Key)
+
+	var failed auth.VerificationTrace
+	if _, err := a.VerifyCredential(context.Background(), forged, auth.WithTrace(&failed)); err == nil {
+		t.Fatal("expected a forged credential to be rejected")
+	}
+
+	step, ok := failed.Failed()
+	if !ok || step.Step != auth.StepSignature || step.Target != "credential[0]" || step.Reason == "" {
+		t.Errorf("expected a failed signature step, got %+v", step)
+	}
+}
@@ -0,0 +1,410 @@
+package trust
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// Service types and statuses defined by ETSI TS 119 612.
+const (
+	ServiceTypeCAQC = "http://uri.etsi.org/TrstSvc/Svctype/CA/QC"
+
+	serviceStatusGranted            = "http://uri.etsi.org/TrstSvc/TrustedList/Svcstatus/granted"
+	serviceStatusUnderSupervision   = "http://uri.etsi.org/TrstSvc/TrustedList/Svcstatus/undersupervision"
+	serviceStatusAccredited         = "http://uri.etsi.org/TrstSvc/TrustedList/Svcstatus/accredited"
+	trustedListMimeType             = "application/vnd.etsi.tsl+xml"
+	defaultTrustedListRefresh       = 24 * time.Hour
+	defaultTrustedListHTTPTimeout   = 30 * time.Second
+	trustedListSignatureIdAttribute = "Id"
+)
+
+// tslDocument is the subset of an ETSI TrustServiceStatusList used for issuer trust decisions.
+type tslDocument struct {
+	XMLName  xml.Name     `xml:"TrustServiceStatusList"`
+	Pointers []tslPointer `xml:"SchemeInformation>PointersToOtherTSL>OtherTSLPointer"`
+	Services []tslService `xml:"TrustServiceProviderList>TrustServiceProvider>TSPServices>TSPService"`
+}
+
+// tslPointer points from the list of trusted lists (LOTL) to a national trusted list.
+type tslPointer struct {
+	Location   string        `xml:"TSLLocation"`
+	Identities []tslIdentity `xml:"ServiceDigitalIdentities>ServiceDigitalIdentity"`
+	MimeTypes  []string      `xml:"AdditionalInformation>OtherInformation>MimeType"`
+}
+
+// tslService is a trust service published by a trust service provider.
+type tslService struct {
+	Type     string      `xml:"ServiceInformation>ServiceTypeIdentifier"`
+	Status   string      `xml:"ServiceInformation>ServiceStatus"`
+	Identity tslIdentity `xml:"ServiceInformation>ServiceDigitalIdentity"`
+}
+
+// tslIdentity holds the base64 DER certificates of a service or list operator, and any other
+// identifiers such as DIDs published under DigitalId/Other.
+type tslIdentity struct {
+	Certificates []string `xml:"DigitalId>X509Certificate"`
+	Identifiers  []string `xml:"DigitalId>Other"`
+}
+
+// listConfig holds the settings shared by the trusted-list based registries.
+type listConfig struct {
+	serviceTypes    []string
+	refreshInterval time.Duration
+	httpClient      *http.Client
+	dnsResolver     DNSResolver
+	clock           clock.Clock
+}
+
+func defaultListConfig() listConfig {
+	return listConfig{
+		clock:           clock.System(),
+		refreshInterval: defaultTrustedListRefresh,
+		httpClient: &http.Client{
+			Timeout: defaultTrustedListHTTPTimeout,
+		},
+	}
+}
+
+// TrustedListOpt configures a TrustedListRegistry or TrainRegistry.
+type TrustedListOpt func(*listConfig)
+
+// WithRefreshInterval sets how often the trusted lists are reloaded (default: 24h).
+func WithRefreshInterval(interval time.Duration) TrustedListOpt {
+	return func(c *listConfig) {
+		c.refreshInterval = interval
+	}
+}
+
+// WithClock sets the time source used for list freshness (default: the system clock).
+func WithClock(c clock.Clock) TrustedListOpt {
+	return func(cfg *listConfig) {
+		cfg.clock = clock.OrSystem(c)
+	}
+}
+
+// WithServiceTypes restricts which service types may certify issuers.
+// TrustedListRegistry defaults to ServiceTypeCAQC; TrainRegistry accepts any type by default.
+func WithServiceTypes(types ...string) TrustedListOpt {
+	return func(c *listConfig) {
+		c.serviceTypes = types
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to download trusted lists.
+func WithHTTPClient(client *http.Client) TrustedListOpt {
+	return func(c *listConfig) {
+		c.httpClient = client
+	}
+}
+
+// TrustedListRegistry is an IssuerRegistry backed by the EU list of trusted lists (LOTL).
+// It trusts issuers whose x5c certificate chain is anchored in a granted service of a national
+// trusted list. The LOTL signature is checked against the configured signer certificates and every
+// national list against the signer certificates published for it in the LOTL.
+type TrustedListRegistry struct {
+	listConfig
+
+	lotlURL     string
+	lotlSigners []*x509.Certificate
+
+	mu       sync.RWMutex
+	anchors  *x509.CertPool
+	loadedAt time.Time
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewTrustedListRegistry creates a registry for the LOTL at lotlURL, signed by one of lotlSigners.
+// Call Refresh to load the lists, and Start to keep them up to date in the background.
+func NewTrustedListRegistry(lotlURL string, lotlSigners []*x509.Certificate, opts ...TrustedListOpt) *TrustedListRegistry {
+	r := &TrustedListRegistry{
+		listConfig:  defaultListConfig(),
+		lotlURL:     lotlURL,
+		lotlSigners: lotlSigners,
+		stop:        make(chan struct{}),
+	}
+	r.serviceTypes = []string{ServiceTypeCAQC}
+
+	for _, opt := range opts {
+		opt(&r.listConfig)
+	}
+
+	return r
+}
+
+// IsTrusted reports whether the issuer's certificate chain is anchored in a trusted list service.
+// The leaf certificate must certify the key that signed the credential, so a chain copied from
+// another issuer's credential is not trusted.
+func (r *TrustedListRegistry) IsTrusted(ctx context.Context, issuer Issuer) (bool, error) {
+	r.mu.RLock()
+	anchors := r.anchors
+	r.mu.RUnlock()
+
+	if anchors == nil {
+		return false, errors.New("trusted lists have not been loaded")
+	}
+
+	return certifiesKey(issuer) && chainsTo(issuer.Certificates, anchors, r.clock.Now()), nil
+}
+
+// certifiesKey reports whether the issuer's leaf certificate is for the key that signed the credential.
+func certifiesKey(issuer Issuer) bool {
+	if len(issuer.Certificates) == 0 || issuer.PublicKey == nil {
+		return false
+	}
+
+	leafKey, ok := issuer.Certificates[0].PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && leafKey.Equal(issuer.PublicKey)
+}
+
+// chainsTo reports whether the leaf-first certificate chain verifies against anchors at time now.
+func chainsTo(chain []*x509.Certificate, anchors *x509.CertPool, now time.Time) bool {
+	if len(chain) == 0 {
+		return false
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         anchors,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err == nil
+}
+
+// LoadedAt returns the time of the last successful refresh.
+func (r *TrustedListRegistry) LoadedAt() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.loadedAt
+}
+
+// Refresh downloads and validates the LOTL and every national trusted list it points to.
+// The previous state is kept if the LOTL cannot be loaded; national lists that fail are skipped.
+func (r *TrustedListRegistry) Refresh(ctx context.Context) error {
+	lotl, err := r.fetchList(ctx, r.lotlURL, r.lotlSigners)
+	if err != nil {
+		return fmt.Errorf("failed to load LOTL: %w", err)
+	}
+
+	anchors := x509.NewCertPool()
+	r.addServiceCertificates(anchors, lotl.Services)
+
+	var errs []error
+	for _, pointer := range lotl.Pointers {
+		if !pointer.isXML() {
+			continue
+		}
+
+		signers, err := parseCertificates(pointer.Identities)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", pointer.Location, err))
+			continue
+		}
+
+		list, err := r.fetchList(ctx, pointer.Location, signers)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", pointer.Location, err))
+			continue
+		}
+
+		r.addServiceCertificates(anchors, list.Services)
+	}
+
+	r.mu.Lock()
+	r.anchors = anchors
+	r.loadedAt = r.clock.Now()
+	r.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// Start refreshes the trusted lists every refresh interval until Close is called.
+func (r *TrustedListRegistry) Start() {
+	go func() {
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-r.clock.After(r.refreshInterval):
+				ctx, cancel := context.WithTimeout(context.Background(), r.refreshInterval)
+				_ = r.Refresh(ctx)
+				cancel()
+			}
+		}
+	}()
+}
+
+// Close stops the background refresh started by Start.
+func (r *TrustedListRegistry) Close() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
+
+// addServiceCertificates adds the certificates of every accepted service to pool.
+func (r *listConfig) addServiceCertificates(pool *x509.CertPool, services []tslService) {
+	for _, service := range services {
+		if !r.acceptsService(service) {
+			continue
+		}
+
+		certs, err := parseCertificates([]tslIdentity{service.Identity})
+		if err != nil {
+			continue
+		}
+
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+	}
+}
+
+// acceptsService reports whether the service is active and of an accepted type.
+func (r *listConfig) acceptsService(service tslService) bool {
+	switch strings.TrimSpace(service.Status) {
+	case serviceStatusGranted, serviceStatusUnderSupervision, serviceStatusAccredited:
+	default:
+		return false
+	}
+
+	if len(r.serviceTypes) == 0 {
+		return true
+	}
+
+	for _, t := range r.serviceTypes {
+		if strings.TrimSpace(service.Type) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchList downloads a trusted list, validates its enveloped XML signature against signers and
+// decodes the signed content.
+func (r *listConfig) fetchList(ctx context.Context, location string, signers []*x509.Certificate) (*tslDocument, error) {
+	body, err := r.download(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := validateListSignature(body, signers)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeList(signed)
+}
+
+// download fetches the raw trusted list at location.
+func (r *listConfig) download(ctx context.Context, location string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return body, nil
+}
+
+func decodeList(data []byte) (*tslDocument, error) {
+	var list tslDocument
+	if err := xml.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode trusted list: %w", err)
+	}
+
+	return &list, nil
+}
+
+// validateListSignature checks the enveloped signature of a trusted list and returns the signed
+// element only, so content outside the signature scope is never trusted.
+func validateListSignature(data []byte, signers []*x509.Certificate) ([]byte, error) {
+	if len(signers) == 0 {
+		return nil, errors.New("no trusted list signer certificates configured")
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, fmt.Errorf("failed to parse trusted list: %w", err)
+	}
+	if doc.Root() == nil {
+		return nil, errors.New("trusted list is empty")
+	}
+
+	validationCtx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: signers})
+	validationCtx.IdAttribute = trustedListSignatureIdAttribute
+
+	validated, err := validationCtx.Validate(doc.Root())
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted list signature: %w", err)
+	}
+
+	signedDoc := etree.NewDocument()
+	signedDoc.SetRoot(validated)
+	return signedDoc.WriteToBytes()
+}
+
+// isXML reports whether the pointer references the XML form of a trusted list.
+func (p tslPointer) isXML() bool {
+	for _, mimeType := range p.MimeTypes {
+		if strings.TrimSpace(mimeType) == trustedListMimeType {
+			return true
+		}
+	}
+	return len(p.MimeTypes) == 0 && strings.HasSuffix(strings.ToLower(p.Location), ".xml")
+}
+
+// parseCertificates decodes the certificates of the given digital identities.
+func parseCertificates(identities []tslIdentity) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for _, identity := range identities {
+		for _, encoded := range identity.Certificates {
+			der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode certificate: %w", err)
+			}
+
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse certificate: %w", err)
+			}
+			certs = append(certs, cert)
+		}
+	}
+	return certs, nil
+}
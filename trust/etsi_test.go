@@ -0,0 +1,275 @@
+package trust
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+type testSigner struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newTestSigner(t *testing.T, name string) testSigner {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	return testSigner{key: key, cert: cert}
+}
+
+// signList signs the XML trusted list with an enveloped signature.
+func (s testSigner) signList(t *testing.T, list string) []byte {
+	t.Helper()
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(list); err != nil {
+		t.Fatalf("failed to parse list: %v", err)
+	}
+
+	signingCtx, err := dsig.NewSigningContext(s.key, [][]byte{s.cert.Raw})
+	if err != nil {
+		t.Fatalf("failed to create signing context: %v", err)
+	}
+	signingCtx.IdAttribute = trustedListSignatureIdAttribute
+
+	signed, err := signingCtx.SignEnveloped(doc.Root())
+	if err != nil {
+		t.Fatalf("failed to sign list: %v", err)
+	}
+
+	out := etree.NewDocument()
+	out.SetRoot(signed)
+	data, err := out.WriteToBytes()
+	if err != nil {
+		t.Fatalf("failed to serialize list: %v", err)
+	}
+	return data
+}
+
+func newTestCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "Test QTSP CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+func newTestLeaf(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "Test Issuer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func b64(cert *x509.Certificate) string {
+	return base64.StdEncoding.EncodeToString(cert.Raw)
+}
+
+func TestTrustedListRegistry(t *testing.T) {
+	lotlSigner := newTestSigner(t, "LOTL operator")
+	tlSigner := newTestSigner(t, "TL operator")
+	ca, caKey := newTestCA(t)
+	leaf := newTestLeaf(t, ca, caKey)
+	otherCA, otherKey := newTestCA(t)
+	untrustedLeaf := newTestLeaf(t, otherCA, otherKey)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	lotl := fmt.Sprintf(`<TrustServiceStatusList Id="lotl">
+<SchemeInformation><PointersToOtherTSL><OtherTSLPointer>
+<ServiceDigitalIdentities><ServiceDigitalIdentity><DigitalId><X509Certificate>%s</X509Certificate></DigitalId></ServiceDigitalIdentity></ServiceDigitalIdentities>
+<TSLLocation>%s/tl.xml</TSLLocation>
+<AdditionalInformation><OtherInformation><MimeType>application/vnd.etsi.tsl+xml</MimeType></OtherInformation></AdditionalInformation>
+</OtherTSLPointer></PointersToOtherTSL></SchemeInformation>
+</TrustServiceStatusList>`, b64(tlSigner.cert), server.URL)
+
+	tl := fmt.Sprintf(`<TrustServiceStatusList Id="tl">
+<TrustServiceProviderList><TrustServiceProvider><TSPServices>
+<TSPService><ServiceInformation>
+<ServiceTypeIdentifier>%s</ServiceTypeIdentifier>
+<ServiceDigitalIdentity><DigitalId><X509Certificate>%s</X509Certificate></DigitalId></ServiceDigitalIdentity>
+<ServiceStatus>%s</ServiceStatus>
+</ServiceInformation></TSPService>
+<TSPService><ServiceInformation>
+<ServiceTypeIdentifier>%s</ServiceTypeIdentifier>
+<ServiceDigitalIdentity><DigitalId><X509Certificate>%s</X509Certificate></DigitalId></ServiceDigitalIdentity>
+<ServiceStatus>http://uri.etsi.org/TrstSvc/TrustedList/Svcstatus/withdrawn</ServiceStatus>
+</ServiceInformation></TSPService>
+</TSPServices></TrustServiceProvider></TrustServiceProviderList>
+</TrustServiceStatusList>`, ServiceTypeCAQC, b64(ca), serviceStatusGranted, ServiceTypeCAQC, b64(otherCA))
+
+	mux.HandleFunc("/lotl.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(lotlSigner.signList(t, lotl))
+	})
+	mux.HandleFunc("/tl.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tlSigner.signList(t, tl))
+	})
+
+	ctx := context.Background()
+
+	t.Run("trusted chain", func(t *testing.T) {
+		registry := NewTrustedListRegistry(server.URL+"/lotl.xml", []*x509.Certificate{lotlSigner.cert})
+		if err := registry.Refresh(ctx); err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+
+		trusted, err := registry.IsTrusted(ctx, Issuer{DID: "did:example:issuer", Certificates: []*x509.Certificate{leaf}, PublicKey: leaf.PublicKey})
+		if err != nil || !trusted {
+			t.Fatalf("IsTrusted(granted) = %v, %v; want true", trusted, err)
+		}
+
+		trusted, err = registry.IsTrusted(ctx, Issuer{DID: "did:example:issuer", Certificates: []*x509.Certificate{untrustedLeaf}, PublicKey: untrustedLeaf.PublicKey})
+		if err != nil || trusted {
+			t.Fatalf("IsTrusted(withdrawn) = %v, %v; want false", trusted, err)
+		}
+
+		trusted, err = registry.IsTrusted(ctx, Issuer{DID: "did:example:issuer"})
+		if err != nil || trusted {
+			t.Fatalf("IsTrusted(no chain) = %v, %v; want false", trusted, err)
+		}
+	})
+
+	t.Run("chain of another key", func(t *testing.T) {
+		registry := NewTrustedListRegistry(server.URL+"/lotl.xml", []*x509.Certificate{lotlSigner.cert})
+		if err := registry.Refresh(ctx); err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+
+		// A chain copied from another issuer's credential does not certify the attacker's signing key.
+		trusted, err := registry.IsTrusted(ctx, Issuer{DID: "did:example:attacker", Certificates: []*x509.Certificate{leaf}, PublicKey: untrustedLeaf.PublicKey})
+		if err != nil || trusted {
+			t.Fatalf("IsTrusted(copied chain) = %v, %v; want false", trusted, err)
+		}
+
+		trusted, err = registry.IsTrusted(ctx, Issuer{DID: "did:example:attacker", Certificates: []*x509.Certificate{leaf}})
+		if err != nil || trusted {
+			t.Fatalf("IsTrusted(no signing key) = %v, %v; want false", trusted, err)
+		}
+	})
+
+	t.Run("clock", func(t *testing.T) {
+		fake := clock.NewFake(time.Now().Add(2 * time.Hour))
+		registry := NewTrustedListRegistry(server.URL+"/lotl.xml", []*x509.Certificate{lotlSigner.cert},
+			WithClock(fake), WithRefreshInterval(time.Minute))
+		if err := registry.Refresh(ctx); err != nil {
+			t.Fatalf("Refresh: %v", err)
+		}
+
+		trusted, err := registry.IsTrusted(ctx, Issuer{DID: "did:example:issuer", Certificates: []*x509.Certificate{leaf}, PublicKey: leaf.PublicKey})
+		if err != nil || trusted {
+			t.Fatalf("IsTrusted(expired by clock) = %v, %v; want false", trusted, err)
+		}
+
+		loadedAt := registry.LoadedAt()
+		registry.Start()
+		defer registry.Close()
+		for fake.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		fake.Advance(time.Minute)
+		for registry.LoadedAt().Equal(loadedAt) {
+			time.Sleep(time.Millisecond)
+		}
+		if !registry.LoadedAt().Equal(loadedAt.Add(time.Minute)) {
+			t.Fatalf("LoadedAt() = %v after background refresh; want %v", registry.LoadedAt(), loadedAt.Add(time.Minute))
+		}
+	})
+
+	t.Run("wrong LOTL signer", func(t *testing.T) {
+		registry := NewTrustedListRegistry(server.URL+"/lotl.xml", []*x509.Certificate{tlSigner.cert})
+		if err := registry.Refresh(ctx); err == nil {
+			t.Fatal("expected Refresh to reject LOTL signed by an unknown certificate")
+		}
+
+		if _, err := registry.IsTrusted(ctx, Issuer{Certificates: []*x509.Certificate{leaf}}); err == nil {
+			t.Fatal("expected IsTrusted to fail before lists are loaded")
+		}
+	})
+}
+
+func TestStaticRegistry(t *testing.T) {
+	registry := NewStaticRegistry("did:example:a")
+
+	if trusted, _ := registry.IsTrusted(context.Background(), Issuer{DID: "did:example:a"}); !trusted {
+		t.Error("expected did:example:a to be trusted")
+	}
+	if trusted, _ := registry.IsTrusted(context.Background(), Issuer{DID: "did:example:b"}); trusted {
+		t.Error("expected did:example:b not to be trusted")
+	}
+}
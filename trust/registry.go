@@ -0,0 +1,49 @@
+package trust
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"sync"
+)
+
+// ErrUntrustedIssuer is returned when a credential issuer is not accepted by the configured registry.
+var ErrUntrustedIssuer = errors.New("untrusted issuer")
+
+// Issuer describes the issuer of a credential being verified.
+type Issuer struct {
+	DID          string              // Issuer DID, taken from the credential's kid
+	Certificates []*x509.Certificate // Chain from the credential's x5c header, leaf first; may be empty
+	PublicKey    crypto.PublicKey    // Key of the verification method that signed the credential
+}
+
+// IssuerRegistry decides whether a credential issuer is trusted.
+// IsTrusted returns an error only when the decision could not be made, e.g. the registry is unreachable.
+type IssuerRegistry interface {
+	IsTrusted(ctx context.Context, issuer Issuer) (bool, error)
+}
+
+// staticRegistry trusts a fixed set of issuer DIDs.
+type staticRegistry struct {
+	mu   sync.RWMutex
+	dids map[string]struct{}
+}
+
+// NewStaticRegistry creates an IssuerRegistry that trusts exactly the given issuer DIDs.
+func NewStaticRegistry(dids ...string) IssuerRegistry {
+	r := &staticRegistry{dids: make(map[string]struct{}, len(dids))}
+	for _, did := range dids {
+		r.dids[did] = struct{}{}
+	}
+	return r
+}
+
+// IsTrusted reports whether the issuer DID is in the allowlist.
+func (r *staticRegistry) IsTrusted(ctx context.Context, issuer Issuer) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.dids[issuer.DID]
+	return ok, nil
+}
@@ -0,0 +1,307 @@
+package trust
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DNS record types used by TRAIN trust scheme resolution.
+const (
+	DNSTypePTR uint16 = 12
+	DNSTypeURI uint16 = 256
+
+	trainSchemePrefix     = "_scheme._trust."
+	defaultDoHEndpoint    = "https://cloudflare-dns.com/dns-query"
+	dnsMessageContentType = "application/dns-json"
+)
+
+// DNSResolver looks up DNS records and returns their presentation-format data.
+// Implementations must only return records whose DNSSEC validation succeeded.
+type DNSResolver interface {
+	Lookup(ctx context.Context, name string, rrType uint16) ([]string, error)
+}
+
+// WithDNSResolver sets the DNS resolver used by TrainRegistry (default: DNS over HTTPS).
+func WithDNSResolver(resolver DNSResolver) TrustedListOpt {
+	return func(c *listConfig) {
+		c.dnsResolver = resolver
+	}
+}
+
+// dohResolver resolves records through a DNS-over-HTTPS JSON endpoint.
+type dohResolver struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+// dohResponse is the JSON form of a DNS answer, as served by DNS-over-HTTPS resolvers.
+type dohResponse struct {
+	Status int  `json:"Status"`
+	AD     bool `json:"AD"`
+	Answer []struct {
+		Name string `json:"name"`
+		Type uint16 `json:"type"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// NewDoHResolver creates a DNSResolver backed by a DNS-over-HTTPS JSON endpoint such as
+// https://cloudflare-dns.com/dns-query. Answers without the DNSSEC authenticated data flag are rejected.
+func NewDoHResolver(endpoint string, client *http.Client) DNSResolver {
+	if endpoint == "" {
+		endpoint = defaultDoHEndpoint
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultTrustedListHTTPTimeout}
+	}
+
+	return &dohResolver{endpoint: endpoint, httpClient: client}
+}
+
+// Lookup queries the DoH endpoint for records of rrType at name.
+func (r *dohResolver) Lookup(ctx context.Context, name string, rrType uint16) ([]string, error) {
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("type", strconv.Itoa(int(rrType)))
+	query.Set("do", "1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", dnsMessageContentType)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var answer dohResponse
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return nil, fmt.Errorf("failed to decode DNS response: %w", err)
+	}
+
+	// NOERROR and NXDOMAIN are both valid answers; anything else is a resolution failure.
+	switch answer.Status {
+	case 0:
+	case 3:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("DNS lookup of %s failed with rcode %d", name, answer.Status)
+	}
+
+	if !answer.AD {
+		return nil, fmt.Errorf("DNS answer for %s is not DNSSEC authenticated", name)
+	}
+
+	var records []string
+	for _, rr := range answer.Answer {
+		if rr.Type == rrType {
+			records = append(records, rr.Data)
+		}
+	}
+	return records, nil
+}
+
+// trainList is a cached trust list fetched from a TRAIN trust scheme.
+type trainList struct {
+	anchors     *x509.CertPool
+	identifiers map[string]struct{}
+	fetchedAt   time.Time
+}
+
+// TrainRegistry is an IssuerRegistry that resolves trust through DNS-anchored trust schemes (TRAIN).
+// Each trust framework pointer is a domain whose _scheme._trust PTR records name the trust schemes
+// it recognizes; each scheme publishes the location of its ETSI trusted list in a _scheme._trust
+// URI record. An issuer is trusted if its DID is listed under a service's DigitalId/Other, or its
+// x5c chain is anchored in a listed service certificate.
+type TrainRegistry struct {
+	listConfig
+
+	pointers    []string
+	listSigners []*x509.Certificate
+
+	mu    sync.Mutex
+	lists map[string]*trainList
+}
+
+// NewTrainRegistry creates a TRAIN registry for the given trust framework pointers.
+// When listSigners is non-empty every trust list must carry a valid enveloped signature by one of
+// them; otherwise lists are trusted on the strength of DNSSEC and HTTPS alone.
+// Trust lists are cached for the refresh interval (default: 24h).
+func NewTrainRegistry(pointers []string, listSigners []*x509.Certificate, opts ...TrustedListOpt) *TrainRegistry {
+	r := &TrainRegistry{
+		listConfig:  defaultListConfig(),
+		pointers:    pointers,
+		listSigners: listSigners,
+		lists:       make(map[string]*trainList),
+	}
+
+	for _, opt := range opts {
+		opt(&r.listConfig)
+	}
+
+	if r.dnsResolver == nil {
+		r.dnsResolver = NewDoHResolver("", r.httpClient)
+	}
+
+	return r
+}
+
+// IsTrusted reports whether any configured trust framework lists the issuer.
+func (r *TrainRegistry) IsTrusted(ctx context.Context, issuer Issuer) (bool, error) {
+	var (
+		errs      []error
+		consulted bool
+	)
+	for _, pointer := range r.pointers {
+		locations, err := r.resolveListLocations(ctx, pointer)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", pointer, err))
+			continue
+		}
+
+		for _, location := range locations {
+			list, err := r.list(ctx, location)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", location, err))
+				continue
+			}
+
+			consulted = true
+			if list.trusts(issuer, r.clock.Now()) {
+				return true, nil
+			}
+		}
+	}
+
+	// Only fail when no trust list could be consulted at all.
+	if !consulted && len(errs) > 0 {
+		return false, errors.Join(errs...)
+	}
+
+	return false, nil
+}
+
+// resolveListLocations follows the PTR records of a trust framework pointer to its trust schemes
+// and returns the trust list URLs published in their URI records.
+func (r *TrainRegistry) resolveListLocations(ctx context.Context, pointer string) ([]string, error) {
+	schemes, err := r.dnsResolver.Lookup(ctx, trainSchemePrefix+strings.TrimSuffix(pointer, "."), DNSTypePTR)
+	if err != nil {
+		return nil, err
+	}
+
+	var locations []string
+	for _, scheme := range schemes {
+		scheme = strings.TrimSuffix(scheme, ".")
+		if !strings.HasPrefix(scheme, trainSchemePrefix) {
+			scheme = trainSchemePrefix + scheme
+		}
+
+		records, err := r.dnsResolver.Lookup(ctx, scheme, DNSTypeURI)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			location, err := parseURIRecord(record)
+			if err != nil {
+				return nil, err
+			}
+			locations = append(locations, location)
+		}
+	}
+
+	if len(locations) == 0 {
+		return nil, errors.New("no trust list published for trust scheme")
+	}
+
+	return locations, nil
+}
+
+// list returns the trust list at location, downloading it if the cached copy is stale.
+func (r *TrainRegistry) list(ctx context.Context, location string) (*trainList, error) {
+	r.mu.Lock()
+	cached, ok := r.lists[location]
+	r.mu.Unlock()
+
+	if ok && r.clock.Now().Sub(cached.fetchedAt) < r.refreshInterval {
+		return cached, nil
+	}
+
+	var (
+		doc *tslDocument
+		err error
+	)
+	if len(r.listSigners) > 0 {
+		doc, err = r.fetchList(ctx, location, r.listSigners)
+	} else {
+		var body []byte
+		if body, err = r.download(ctx, location); err == nil {
+			doc, err = decodeList(body)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	list := &trainList{
+		anchors:     x509.NewCertPool(),
+		identifiers: make(map[string]struct{}),
+		fetchedAt:   r.clock.Now(),
+	}
+	r.addServiceCertificates(list.anchors, doc.Services)
+	for _, service := range doc.Services {
+		if !r.acceptsService(service) {
+			continue
+		}
+		for _, id := range service.Identity.Identifiers {
+			list.identifiers[strings.TrimSpace(id)] = struct{}{}
+		}
+	}
+
+	r.mu.Lock()
+	r.lists[location] = list
+	r.mu.Unlock()
+
+	return list, nil
+}
+
+// trusts reports whether the issuer DID, or a certificate chain for the issuer's signing key,
+// appears in the list at time now.
+func (l *trainList) trusts(issuer Issuer, now time.Time) bool {
+	if _, ok := l.identifiers[issuer.DID]; ok && issuer.DID != "" {
+		return true
+	}
+
+	return certifiesKey(issuer) && chainsTo(issuer.Certificates, l.anchors, now)
+}
+
+// parseURIRecord extracts the target of a URI record in presentation format: priority weight "target".
+func parseURIRecord(record string) (string, error) {
+	fields := strings.SplitN(strings.TrimSpace(record), " ", 3)
+	if len(fields) != 3 {
+		return "", fmt.Errorf("malformed URI record: %q", record)
+	}
+
+	target, err := strconv.Unquote(fields[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed URI record target: %q", record)
+	}
+
+	return target, nil
+}
@@ -0,0 +1,103 @@
+package trust
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDNS serves fixed records keyed by name and type.
+type fakeDNS map[string][]string
+
+func (f fakeDNS) Lookup(ctx context.Context, name string, rrType uint16) ([]string, error) {
+	return f[fmt.Sprintf("%s/%d", name, rrType)], nil
+}
+
+func TestTrainRegistry(t *testing.T) {
+	ca, caKey := newTestCA(t)
+	leaf := newTestLeaf(t, ca, caKey)
+	otherCA, otherKey := newTestCA(t)
+	untrustedLeaf := newTestLeaf(t, otherCA, otherKey)
+
+	list := fmt.Sprintf(`<TrustServiceStatusList Id="train">
+<TrustServiceProviderList><TrustServiceProvider><TSPServices>
+<TSPService><ServiceInformation>
+<ServiceTypeIdentifier>%s</ServiceTypeIdentifier>
+<ServiceDigitalIdentity>
+<DigitalId><X509Certificate>%s</X509Certificate></DigitalId>
+<DigitalId><Other>did:example:listed</Other></DigitalId>
+</ServiceDigitalIdentity>
+<ServiceStatus>%s</ServiceStatus>
+</ServiceInformation></TSPService>
+</TSPServices></TrustServiceProvider></TrustServiceProviderList>
+</TrustServiceStatusList>`, ServiceTypeCAQC, b64(ca), serviceStatusGranted)
+
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		w.Write([]byte(list))
+	}))
+	defer server.Close()
+
+	dns := fakeDNS{
+		"_scheme._trust.federation.example/12":  {"scheme.example."},
+		"_scheme._trust.scheme.example/256":     {fmt.Sprintf(`10 1 "%s/list.xml"`, server.URL)},
+		"_scheme._trust.unpublished.example/12": nil,
+	}
+
+	ctx := context.Background()
+	registry := NewTrainRegistry([]string{"federation.example"}, nil, WithDNSResolver(dns))
+
+	tests := []struct {
+		name   string
+		issuer Issuer
+		want   bool
+	}{
+		{"listed DID", Issuer{DID: "did:example:listed"}, true},
+		{"anchored chain", Issuer{DID: "did:example:other", Certificates: []*x509.Certificate{leaf}, PublicKey: leaf.PublicKey}, true},
+		{"unknown DID", Issuer{DID: "did:example:other"}, false},
+		{"foreign chain", Issuer{DID: "did:example:other", Certificates: []*x509.Certificate{untrustedLeaf}, PublicKey: untrustedLeaf.PublicKey}, false},
+		{"chain of another key", Issuer{DID: "did:example:other", Certificates: []*x509.Certificate{leaf}, PublicKey: untrustedLeaf.PublicKey}, false},
+		{"chain without signing key", Issuer{DID: "did:example:other", Certificates: []*x509.Certificate{leaf}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trusted, err := registry.IsTrusted(ctx, tt.issuer)
+			if err != nil {
+				t.Fatalf("IsTrusted: %v", err)
+			}
+			if trusted != tt.want {
+				t.Fatalf("IsTrusted = %v, want %v", trusted, tt.want)
+			}
+		})
+	}
+
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("expected trust list to be cached, downloaded %d times", n)
+	}
+
+	unpublished := NewTrainRegistry([]string{"unpublished.example"}, nil, WithDNSResolver(dns))
+	if _, err := unpublished.IsTrusted(ctx, Issuer{DID: "did:example:listed"}); err == nil {
+		t.Error("expected an error when no trust list is published")
+	}
+
+	signed := NewTrainRegistry([]string{"federation.example"}, []*x509.Certificate{ca}, WithDNSResolver(dns))
+	if _, err := signed.IsTrusted(ctx, Issuer{DID: "did:example:listed"}); err == nil {
+		t.Error("expected unsigned list to be rejected when signers are configured")
+	}
+}
+
+func TestParseURIRecord(t *testing.T) {
+	got, err := parseURIRecord(`10 1 "https://example.com/list.xml"`)
+	if err != nil || got != "https://example.com/list.xml" {
+		t.Fatalf("parseURIRecord = %q, %v", got, err)
+	}
+
+	if _, err := parseURIRecord("https://example.com/list.xml"); err == nil {
+		t.Fatal("expected malformed record to fail")
+	}
+}
@@ -2,15 +2,17 @@ package auth
 
 import (
 	"strings"
+
+	"github/hovanhoa/go-vc-auth/ethaddr"
 )
 
 // extractAddressFromDID extracts the Ethereum address from a DID string.
-// It returns the substring after the last colon.
-// Example: "did:nda:testnet:0x8b3b1dee8e00cb95f8b2a1d1a9a7cb8fe7d490ce" -> "0x8b3b1dee8e00cb95f8b2a1d1a9a7cb8fe7d490ce"
+// It returns the substring after the last colon, lowercased when it is a valid address.
+// Example: "did:nda:testnet:0x8B3b1dEE8e00cb95F8b2a1d1a9A7cb8fe7D490cE" -> "0x8b3b1dee8e00cb95f8b2a1d1a9a7cb8fe7d490ce"
 func extractAddressFromDID(did string) string {
-	lastColonIndex := strings.LastIndex(did, ":")
-	if lastColonIndex == -1 {
-		return did // Return original string if no colon found
+	segment := did[strings.LastIndex(did, ":")+1:] // The whole string if no colon found
+	if address, err := ethaddr.Normalize(segment); err == nil {
+		return address
 	}
-	return did[lastColonIndex+1:]
+	return segment
 }
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// ErrCredentialNotValid is returned for credentials used outside their validity period.
+var ErrCredentialNotValid = errors.New("credential is not valid at this time")
+
+// WithClock sets the time source used for token timestamps, expiry and validity checks
+// (default: the system clock). Use clock.Offset to compensate a skewed host clock and
+// clock.NewFake for deterministic tests.
+func WithClock(c clock.Clock) Option {
+	return func(a *Service) {
+		a.clock = clock.OrSystem(c)
+	}
+}
+
+// jwtValidity returns the nbf and exp claims of a JWT envelope, zero when absent.
+func jwtValidity(vcToken *jwtToken) (notBefore, notAfter time.Time) {
+	if nbf, ok := vcToken.payload["nbf"].(float64); ok {
+		notBefore = time.Unix(int64(nbf), 0)
+	}
+	if exp, ok := vcToken.payload["exp"].(float64); ok {
+		notAfter = time.Unix(int64(exp), 0)
+	}
+	return notBefore, notAfter
+}
+
+// checkValidity enforces the validFrom/validUntil period of a credential, narrowed by the
+// envelope's own validity (zero bounds are ignored), at time now.
+func checkValidity(claims VcClaims, envelopeNotBefore, envelopeNotAfter, now time.Time) error {
+	notBefore, notAfter := claims.ValidFrom, claims.ValidUntil
+	if envelopeNotBefore.After(notBefore) {
+		notBefore = envelopeNotBefore
+	}
+	if !envelopeNotAfter.IsZero() && (notAfter.IsZero() || envelopeNotAfter.Before(notAfter)) {
+		notAfter = envelopeNotAfter
+	}
+
+	if !notBefore.IsZero() && now.Before(notBefore) {
+		return fmt.Errorf("%w: valid from %s", ErrCredentialNotValid, notBefore.UTC().Format(time.RFC3339))
+	}
+	if !notAfter.IsZero() && now.After(notAfter) {
+		return fmt.Errorf("%w: expired at %s", ErrCredentialNotValid, notAfter.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
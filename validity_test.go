@@ -0,0 +1,88 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+func TestVerifyTokenWithClock(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	fake := clock.NewFake(time.Now())
+	a := registry.newAuth(authtest.NewSigner(holder), auth.WithClock(fake))
+
+	// issueCredential sets validFrom one minute before the real time.
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address, auth.WithExpiry(time.Hour))
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	if _, err := a.VerifyToken(context.Background(), token); err != nil {
+		t.Fatalf("VerifyToken failed: %v", err)
+	}
+
+	fake.Advance(2 * time.Hour)
+	if _, err := a.VerifyToken(context.Background(), token); err == nil {
+		t.Error("expected the presentation to have expired on the fake clock")
+	}
+
+	early := registry.newAuth(nil, auth.WithClock(clock.Offset(clock.System(), -time.Hour)))
+	if _, err := early.VerifyCredential(context.Background(), vcJwt); !errors.Is(err, auth.ErrCredentialNotValid) {
+		t.Errorf("expected ErrCredentialNotValid before validFrom, got %v", err)
+	}
+}
+
+func TestVerifyTokenPresentationValidity(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address, auth.WithExpiry(time.Hour))
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	parts := strings.Split(strings.Trim(token, `"`), ".")
+
+	// withClaims re-signs the VP with the holder key after changing its payload.
+	withClaims := func(claims map[string]any) string {
+		payloadJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var payload map[string]any
+		if err := json.Unmarshal(payloadJSON, &payload); err != nil {
+			t.Fatal(err)
+		}
+		maps.Copy(payload, claims)
+		payloadJSON, _ = json.Marshal(payload)
+		return signJWT(t, parts[0]+"."+base64.RawURLEncoding.EncodeToString(payloadJSON), holder.Key)
+	}
+
+	if _, err := a.VerifyToken(context.Background(), withClaims(map[string]any{"nbf": time.Now().Add(-time.Minute).Unix()})); err != nil {
+		t.Fatalf("VerifyToken of a valid presentation failed: %v", err)
+	}
+	for name, claims := range map[string]map[string]any{
+		"string exp": {"exp": "never"},
+		"null exp":   {"exp": nil},
+		"string nbf": {"nbf": "now"},
+		"future nbf": {"nbf": time.Now().Add(time.Hour).Unix()},
+		"past exp":   {"exp": time.Now().Add(-time.Minute).Unix()},
+		"object exp": {"exp": map[string]any{"seconds": 0}},
+	} {
+		if _, err := a.VerifyToken(context.Background(), withClaims(claims)); err == nil {
+			t.Errorf("%s: expected the presentation to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// ErrRetryBudgetExhausted is returned when a retry would exceed the context's retry budget or deadline.
+var ErrRetryBudgetExhausted = errors.New("vault retry budget exhausted")
+
+// RetryBudget bounds the retries and the total backoff of every Vault call made with a context
+// carrying it, so an operation issuing several calls (e.g. CreateToken) stays within its limits
+// end to end. A RetryBudget is safe for concurrent use.
+type RetryBudget struct {
+	mu      sync.Mutex
+	retries int
+	delay   time.Duration
+}
+
+// NewRetryBudget returns a budget allowing maxRetries retries in total and at most maxDelay of
+// cumulated backoff; maxDelay <= 0 leaves the backoff unbounded.
+func NewRetryBudget(maxRetries int, maxDelay time.Duration) *RetryBudget {
+	if maxDelay <= 0 {
+		maxDelay = time.Duration(1<<63 - 1)
+	}
+	return &RetryBudget{retries: maxRetries, delay: maxDelay}
+}
+
+// Remaining returns the retries and backoff left in the budget.
+func (b *RetryBudget) Remaining() (int, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.retries, b.delay
+}
+
+// take consumes one retry waiting d, reporting false when the budget cannot afford it.
+func (b *RetryBudget) take(d time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.retries <= 0 || d > b.delay {
+		return false
+	}
+	b.retries--
+	b.delay -= d
+	return true
+}
+
+type retryBudgetKey struct{}
+
+// WithRetryBudget returns a copy of ctx carrying budget. Vault calls made with the context, directly
+// or through a provider, draw their retries from it on top of their own MaxRetries limit.
+func WithRetryBudget(ctx context.Context, budget *RetryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetKey{}, budget)
+}
+
+// retryBudgetFrom returns the budget carried by ctx, or nil.
+func retryBudgetFrom(ctx context.Context) *RetryBudget {
+	budget, _ := ctx.Value(retryBudgetKey{}).(*RetryBudget)
+	return budget
+}
+
+// Backoff waits before retry attempt+1 of a call to a signing backend as the default ExponentialBackoff
+// does, see Wait.
+func Backoff(ctx context.Context, c clock.Clock, attempt int) error {
+	return Wait(ctx, c, ExponentialBackoff{}.delay(attempt))
+}
+
+// Wait waits delay before a retry of a call to a signing backend, timed by c (nil means the system clock).
+// It fails without waiting when the wait would overrun the context deadline or the context's retry budget,
+// so other providers retrying like the Vault client share its RetryBudget.
+func Wait(ctx context.Context, c clock.Clock, delay time.Duration) error {
+	// Context deadlines are wall-clock times, whatever c says.
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return fmt.Errorf("%w: backoff of %v would pass the context deadline", ErrRetryBudgetExhausted, delay)
+	}
+	if budget := retryBudgetFrom(ctx); budget != nil && !budget.take(delay) {
+		return ErrRetryBudgetExhausted
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clock.OrSystem(c).After(delay):
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+func TestRetryBudgetSharedAcrossCalls(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	v := NewVault(srv.URL, "token", 3)
+	v.Clock = fake
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if fake.Waiters() > 0 {
+				fake.Advance(time.Minute)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	budget := NewRetryBudget(2, 0)
+	ctx := WithRetryBudget(context.Background(), budget)
+	payload := make([]byte, 32)
+	address := "0x0000000000000000000000000000000000000001"
+
+	// The first call spends the whole budget on its two retries, the second gets none.
+	if _, err := v.SignMessage(ctx, payload, address); !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("first call: got %v, want ErrRetryBudgetExhausted", err)
+	}
+	if _, err := v.SignMessage(ctx, payload, address); !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("second call: got %v, want ErrRetryBudgetExhausted", err)
+	}
+	if got := requests.Load(); got != 4 {
+		t.Errorf("got %d requests, want 4 (3 for the first call, 1 for the second)", got)
+	}
+	if retries, _ := budget.Remaining(); retries != 0 {
+		t.Errorf("remaining retries = %d, want 0", retries)
+	}
+}
+
+func TestRetryBudgetDelayAndDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	v := NewVault(srv.URL, "token", 3)
+
+	// A second of backoff is more than the budget allows, so no wait happens at all.
+	ctx := WithRetryBudget(context.Background(), NewRetryBudget(5, 500*time.Millisecond))
+	if _, err := v.SignMessage(ctx, make([]byte, 32), "0x0000000000000000000000000000000000000001"); !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Errorf("delay budget: got %v, want ErrRetryBudgetExhausted", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := v.SignMessage(ctx, make([]byte, 32), "0x0000000000000000000000000000000000000001"); !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Errorf("deadline: got %v, want ErrRetryBudgetExhausted rather than waiting for the deadline", err)
+	}
+}
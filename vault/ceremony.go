@@ -0,0 +1,104 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/shamir"
+)
+
+// ErrDualControl is returned when a dual-control import lacks two distinct operator approvals.
+var ErrDualControl = errors.New("dual-control import requires shares from two distinct operators")
+
+// KeyShare is one operator's half of a private key split with SplitPrivateKey
+type KeyShare struct {
+	Operator string // Identity of the operator holding and approving with the share
+	Share    []byte // Shamir share of the private key
+}
+
+// ImportApproval records one operator's approval of a dual-control import
+type ImportApproval struct {
+	Operator   string    `json:"operator"`
+	ApprovedAt time.Time `json:"approvedAt"`
+}
+
+// ImportRecord is the audit record of a dual-control key import
+type ImportRecord struct {
+	Address   string           `json:"address"` // Address derived from the reassembled key
+	Approvals []ImportApproval `json:"approvals"`
+}
+
+// ImportAuditor stores the audit records of dual-control imports, e.g. in an append-only log.
+// The import is aborted when the record cannot be stored, so no key reaches Vault unaudited.
+type ImportAuditor interface {
+	RecordImport(ctx context.Context, record ImportRecord) error
+}
+
+// ImportAuditorFunc adapts a function to an ImportAuditor
+type ImportAuditorFunc func(ctx context.Context, record ImportRecord) error
+
+// RecordImport calls f
+func (f ImportAuditorFunc) RecordImport(ctx context.Context, record ImportRecord) error {
+	return f(ctx, record)
+}
+
+// SplitPrivateKey splits a private key (raw bytes or hex, as accepted by StorePrivateKey) into two
+// Shamir shares, one per operator. Both shares are needed to reassemble the key.
+func SplitPrivateKey(privateKey any) (first, second []byte, err error) {
+	key, err := ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zeroKey(key)
+	raw := crypto.FromECDSA(key)
+	defer clear(raw)
+
+	shares, err := shamir.Split(raw, 2, 2)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shares[0], shares[1], nil
+}
+
+// ImportDualControl reassembles a private key from two operators' shares and stores it with
+// StorePrivateKey. The audit record naming both approvals is handed to auditor before the key is sent;
+// the reassembled key only lives for the duration of the call.
+func (v *Vault) ImportDualControl(ctx context.Context, first, second KeyShare, auditor ImportAuditor) (*StoredKey, error) {
+	if auditor == nil {
+		return nil, fmt.Errorf("an import auditor is required")
+	}
+	if first.Operator == "" || second.Operator == "" || first.Operator == second.Operator {
+		return nil, ErrDualControl
+	}
+
+	raw, err := shamir.Combine([][]byte{first.Share, second.Share})
+	if err != nil {
+		return nil, err
+	}
+	defer clear(raw)
+
+	key, err := ParsePrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("shares do not reassemble a valid key: %w", err)
+	}
+	defer zeroKey(key)
+
+	now := clock.OrSystem(v.Clock).Now().UTC()
+	record := ImportRecord{
+		Address: crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		Approvals: []ImportApproval{
+			{Operator: first.Operator, ApprovedAt: now},
+			{Operator: second.Operator, ApprovedAt: now},
+		},
+	}
+	if err := auditor.RecordImport(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to record import: %w", err)
+	}
+
+	return v.StorePrivateKey(ctx, raw)
+}
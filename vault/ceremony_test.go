@@ -0,0 +1,83 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+func TestImportDualControl(t *testing.T) {
+	key, err := crypto.HexToECDSA(testKeystoreKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	first, second, err := SplitPrivateKey("0x" + testKeystoreKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("audited import", func(t *testing.T) {
+		v, submitted := newTestVault(t, address)
+		now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		v.Clock = clock.NewFake(now)
+
+		var records []ImportRecord
+		auditor := ImportAuditorFunc(func(_ context.Context, record ImportRecord) error {
+			if len(*submitted) != 0 {
+				t.Error("key was stored before the import was audited")
+			}
+			records = append(records, record)
+			return nil
+		})
+		stored, err := v.ImportDualControl(context.Background(),
+			KeyShare{Operator: "alice", Share: first}, KeyShare{Operator: "bob", Share: second}, auditor)
+		if err != nil {
+			t.Fatalf("ImportDualControl: %v", err)
+		}
+		if stored.Address != address || len(*submitted) != 1 || (*submitted)[0] != testKeystoreKey {
+			t.Errorf("stored %s with %v, want %s", stored.Address, *submitted, address)
+		}
+		if len(records) != 1 {
+			t.Fatalf("got %d audit records, want 1", len(records))
+		}
+		want := []ImportApproval{{Operator: "alice", ApprovedAt: now}, {Operator: "bob", ApprovedAt: now}}
+		if records[0].Address != address || len(records[0].Approvals) != 2 ||
+			records[0].Approvals[0] != want[0] || records[0].Approvals[1] != want[1] {
+			t.Errorf("audit record = %+v", records[0])
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		accept := ImportAuditorFunc(func(context.Context, ImportRecord) error { return nil })
+		failing := ImportAuditorFunc(func(context.Context, ImportRecord) error { return errors.New("log unavailable") })
+		cases := map[string]struct {
+			first, second KeyShare
+			auditor       ImportAuditor
+		}{
+			"same operator":   {KeyShare{"alice", first}, KeyShare{"alice", second}, accept},
+			"anonymous":       {KeyShare{"", first}, KeyShare{"bob", second}, accept},
+			"one share":       {KeyShare{"alice", first}, KeyShare{"bob", first}, accept},
+			"no auditor":      {KeyShare{"alice", first}, KeyShare{"bob", second}, nil},
+			"audit failure":   {KeyShare{"alice", first}, KeyShare{"bob", second}, failing},
+			"truncated share": {KeyShare{"alice", first[1:]}, KeyShare{"bob", second}, accept},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				v, submitted := newTestVault(t, address)
+				if _, err := v.ImportDualControl(context.Background(), tc.first, tc.second, tc.auditor); err == nil {
+					t.Fatal("expected an error")
+				}
+				if len(*submitted) != 0 {
+					t.Error("key was sent to Vault")
+				}
+			})
+		}
+	})
+}
@@ -1,5 +1,12 @@
 package vault
 
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
 type SignMessageRequest struct {
 	Payload string `json:"payload"`
 }
@@ -11,12 +18,36 @@ type SignMessageResponse struct {
 	} `json:"data"`
 }
 
+// ErrorResponse represents the Vault API response of a failed request
+type ErrorResponse struct {
+	Errors []string `json:"errors"`
+}
+
 // StorePrivateKeyRequest represents the JSON payload for storing a private key
 type StorePrivateKeyRequest struct {
 	PrivateKey string `json:"privateKey"`
 }
 
+// storePrivateKeyBody encodes the StorePrivateKeyRequest for key into a byte slice the caller can wipe
+func storePrivateKeyBody(key *ecdsa.PrivateKey) []byte {
+	raw := crypto.FromECDSA(key)
+	defer clear(raw)
+
+	const prefix, suffix = `{"privateKey":"`, `"}`
+	body := make([]byte, len(prefix)+hex.EncodedLen(len(raw))+len(suffix))
+	n := copy(body, prefix)
+	n += hex.Encode(body[n:], raw)
+	copy(body[n:], suffix)
+	return body
+}
+
 // StorePrivateKeyData contains the address field from the response
 type StorePrivateKeyData struct {
 	Address string `json:"address"`
 }
+
+// StoredKey describes a private key imported into Vault
+type StoredKey struct {
+	Address   string           // Ethereum address reported by Vault
+	PublicKey *ecdsa.PublicKey // Public key derived from the submitted private key
+}
@@ -0,0 +1,49 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// VaultError is returned when Vault answers a request with an error status. It carries the messages of
+// Vault's {"errors": [...]} body rather than the raw body, which may echo request data.
+type VaultError struct {
+	StatusCode int      // HTTP status of the answer
+	Method     string   // Request method
+	Path       string   // Request path, e.g. "/v1/secp/accounts"
+	Errors     []string // Error messages reported by Vault, if any
+}
+
+// Error returns the status and messages of the failed request.
+func (e *VaultError) Error() string {
+	msg := fmt.Sprintf("vault: %s %s returned status %d", e.Method, e.Path, e.StatusCode)
+	if len(e.Errors) > 0 {
+		msg += ": " + strings.Join(e.Errors, "; ")
+	}
+	return msg
+}
+
+// newVaultError describes the failed answer to a request with the given status and body.
+func newVaultError(method, path string, status int, body []byte) *VaultError {
+	var response ErrorResponse
+	_ = json.Unmarshal(body, &response) // Non-JSON bodies, e.g. from a proxy, leave Errors empty
+	return &VaultError{StatusCode: status, Method: method, Path: path, Errors: response.Errors}
+}
+
+// unknownAccount reports whether the error says the account does not exist: a 404 for the account path,
+// or an error message saying so, as ethsign plugins answer with various statuses. A 404 for a path no
+// plugin is mounted at is not an unknown account.
+func (e *VaultError) unknownAccount() bool {
+	for _, message := range e.Errors {
+		message = strings.ToLower(message)
+		switch {
+		case strings.Contains(message, "no handler for route"):
+			return false
+		case strings.Contains(message, "not found"), strings.Contains(message, "does not exist"), strings.Contains(message, "no such"):
+			return true
+		}
+	}
+	return e.StatusCode == http.StatusNotFound
+}
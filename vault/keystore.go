@@ -0,0 +1,198 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+
+	"github/hovanhoa/go-vc-auth/secret"
+)
+
+// ErrKeystorePassphrase is returned when a keystore's MAC does not match the passphrase.
+var ErrKeystorePassphrase = errors.New("could not decrypt keystore with given passphrase")
+
+// keystoreJSON is the subset of the Web3 Secret Storage (v3) format needed to decrypt a key.
+type keystoreJSON struct {
+	Version int            `json:"version"`
+	Crypto  keystoreCrypto `json:"crypto"` // matched case-insensitively, so older "Crypto" files decode too
+}
+
+type keystoreCrypto struct {
+	Cipher       string `json:"cipher"`
+	CipherText   string `json:"ciphertext"`
+	CipherParams struct {
+		IV string `json:"iv"`
+	} `json:"cipherparams"`
+	KDF       string          `json:"kdf"`
+	KDFParams json.RawMessage `json:"kdfparams"`
+	MAC       string          `json:"mac"`
+}
+
+type scryptParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+type pbkdf2Params struct {
+	C     int    `json:"c"`
+	DKLen int    `json:"dklen"`
+	PRF   string `json:"prf"`
+	Salt  string `json:"salt"`
+}
+
+// decryptKeystore returns the raw private key held in a v3 keystore JSON document.
+func decryptKeystore(keyJSON []byte, passphrase secret.Secret) ([]byte, error) {
+	var ks keystoreJSON
+	if err := json.Unmarshal(keyJSON, &ks); err != nil {
+		return nil, fmt.Errorf("failed to decode keystore: %w", err)
+	}
+	if ks.Version != 3 {
+		return nil, fmt.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	c := ks.Crypto
+	if c.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported keystore cipher %q", c.Cipher)
+	}
+
+	derivedKey, err := keystoreDerivedKey(c, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(derivedKey)
+
+	cipherText, err := hex.DecodeString(c.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %w", err)
+	}
+	mac, err := hex.DecodeString(c.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore mac: %w", err)
+	}
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrKeystorePassphrase
+	}
+
+	iv, err := hex.DecodeString(c.CipherParams.IV)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore iv: %w", err)
+	}
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid keystore iv length %d", len(iv))
+	}
+	plainText := make([]byte, len(cipherText))
+	cipher.NewCTR(block, iv).XORKeyStream(plainText, cipherText)
+	return plainText, nil
+}
+
+// keystoreDerivedKey runs the keystore's key derivation function over the passphrase.
+func keystoreDerivedKey(c keystoreCrypto, passphrase secret.Secret) ([]byte, error) {
+	switch c.KDF {
+	case "scrypt":
+		var params scryptParams
+		if err := json.Unmarshal(c.KDFParams, &params); err != nil {
+			return nil, fmt.Errorf("invalid scrypt params: %w", err)
+		}
+		salt, err := hex.DecodeString(params.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scrypt salt: %w", err)
+		}
+		if params.DKLen < 32 {
+			return nil, fmt.Errorf("scrypt dklen must be at least 32, got %d", params.DKLen)
+		}
+		return scrypt.Key(passphrase.Bytes(), salt, params.N, params.R, params.P, params.DKLen)
+	case "pbkdf2":
+		var params pbkdf2Params
+		if err := json.Unmarshal(c.KDFParams, &params); err != nil {
+			return nil, fmt.Errorf("invalid pbkdf2 params: %w", err)
+		}
+		if params.PRF != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %q", params.PRF)
+		}
+		salt, err := hex.DecodeString(params.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pbkdf2 salt: %w", err)
+		}
+		if params.DKLen < 32 || params.C <= 0 {
+			return nil, fmt.Errorf("invalid pbkdf2 params: c=%d dklen=%d", params.C, params.DKLen)
+		}
+		return pbkdf2.Key(passphrase.Bytes(), salt, params.C, params.DKLen, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported keystore kdf %q", c.KDF)
+	}
+}
+
+// ParsePrivateKey decodes a secp256k1 private key given as raw bytes, hex (with or without 0x) or a
+// secret.Secret holding either, rejecting keys of the wrong length or outside the curve order. The
+// returned key is a new value the caller may wipe.
+func ParsePrivateKey(privateKey any) (*ecdsa.PrivateKey, error) {
+	var raw []byte
+	switch k := privateKey.(type) {
+	case []byte:
+		raw = k
+	case string:
+		decoded, err := decodeHexKey([]byte(k))
+		if err != nil {
+			return nil, err
+		}
+		defer clear(decoded)
+		raw = decoded
+	case secret.Secret:
+		raw = k.Bytes()
+		if len(raw) != 32 {
+			decoded, err := decodeHexKey(raw)
+			if err != nil {
+				return nil, err
+			}
+			defer clear(decoded)
+			raw = decoded
+		}
+	default:
+		return nil, fmt.Errorf("private key must be []byte, a hex string or a secret.Secret, got %T", privateKey)
+	}
+
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("private key must be 32 bytes, got %d", len(raw))
+	}
+	key, err := crypto.ToECDSA(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secp256k1 private key: %w", err)
+	}
+	return key, nil
+}
+
+// decodeHexKey decodes a hex private key, with or without 0x prefix, into a new slice.
+func decodeHexKey(text []byte) ([]byte, error) {
+	text = bytes.TrimSpace(text)
+	if len(text) >= 2 && text[0] == '0' && (text[1] == 'x' || text[1] == 'X') {
+		text = text[2:]
+	}
+	decoded := make([]byte, hex.DecodedLen(len(text)))
+	if _, err := hex.Decode(decoded, text); err != nil {
+		clear(decoded)
+		return nil, fmt.Errorf("private key is not valid hex: %w", err)
+	}
+	return decoded, nil
+}
+
+// zeroKey wipes the private scalar of key, best effort.
+func zeroKey(key *ecdsa.PrivateKey) {
+	clear(key.D.Bits())
+	key.D.SetInt64(0)
+}
@@ -0,0 +1,160 @@
+package vault
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/secret"
+)
+
+const (
+	// DefaultKubernetesMount is the path the Kubernetes auth method is mounted at unless
+	// KubernetesAuth.Mount says otherwise.
+	DefaultKubernetesMount = "kubernetes"
+	// DefaultServiceAccountTokenPath is where Kubernetes mounts the pod's service account token.
+	DefaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// Authenticator logs in to Vault for a client token, e.g. KubernetesAuth. When Vault.Auth is set, the
+// client logs in before its first request and again before the token expires, instead of using
+// Vault.Token.
+type Authenticator interface {
+	// Login returns a client token and its lease duration; 0 means the token does not expire.
+	Login(ctx context.Context, v *Vault) (secret.Secret, time.Duration, error)
+}
+
+// loginState caches the token of the last login and the lease of the client token, and controls the
+// renewal loop. Its zero value holds no token.
+type loginState struct {
+	mu        sync.Mutex
+	token     secret.Secret
+	refreshAt time.Time // When requests log in again; zero when the token does not expire
+	renewAt   time.Time // When the renewal loop renews the token
+
+	startOnce, stopOnce sync.Once
+	stop                chan struct{}
+}
+
+// setLease records a lease granted at now: the renewal loop renews the token after two thirds of it,
+// and requests log in again after 80%. A lease of 0 does not expire.
+func (s *loginState) setLease(now time.Time, lease time.Duration) {
+	s.refreshAt, s.renewAt = time.Time{}, time.Time{}
+	if lease > 0 {
+		s.refreshAt, s.renewAt = now.Add(lease*4/5), now.Add(lease*2/3)
+	}
+}
+
+// loginResponse is the part of an auth method's login response used here.
+type loginResponse struct {
+	Auth *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"` // Seconds
+	} `json:"auth"`
+}
+
+// Login posts payload to the login endpoint of an auth method, e.g. "/v1/auth/kubernetes/login", and
+// returns the client token and its lease duration. It sends no client token, and is not retried.
+func (v *Vault) Login(ctx context.Context, path string, payload any) (secret.Secret, time.Duration, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return secret.Secret{}, 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	defer clear(body)
+
+	req, err := v.newRequest(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return secret.Secret{}, 0, err
+	}
+	req.Header.Del("X-Vault-Token")
+
+	status, respBody, err := v.send(req)
+	if err != nil {
+		return secret.Secret{}, 0, err
+	}
+	defer clear(respBody)
+	if status != http.StatusOK {
+		return secret.Secret{}, 0, newVaultError(http.MethodPost, path, status, respBody)
+	}
+
+	var response loginResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return secret.Secret{}, 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.Auth == nil || response.Auth.ClientToken == "" {
+		return secret.Secret{}, 0, errors.New("vault login returned no client token")
+	}
+	return secret.New(response.Auth.ClientToken), time.Duration(response.Auth.LeaseDuration) * time.Second, nil
+}
+
+// clientToken returns the token to send: Token, or the token of the last login when Auth is set,
+// logging in again once 80% of its lease has passed.
+func (v *Vault) clientToken(ctx context.Context) (secret.Secret, error) {
+	v.startRenewal()
+	if v.Auth == nil {
+		return v.Token, nil
+	}
+
+	v.login.mu.Lock()
+	defer v.login.mu.Unlock()
+
+	now := clock.OrSystem(v.Clock).Now()
+	if !v.login.token.IsEmpty() && (v.login.refreshAt.IsZero() || now.Before(v.login.refreshAt)) {
+		return v.login.token, nil
+	}
+
+	token, lease, err := v.Auth.Login(ctx, v)
+	if err != nil {
+		return secret.Secret{}, fmt.Errorf("vault login failed: %w", err)
+	}
+	v.login.token = token
+	v.login.setLease(now, lease)
+	return token, nil
+}
+
+// dropToken forgets token after Vault refused it, e.g. because it was revoked, so the next request
+// logs in again. A token that was already replaced is left alone.
+func (v *Vault) dropToken(token secret.Secret) {
+	v.login.mu.Lock()
+	defer v.login.mu.Unlock()
+
+	if v.login.token.Reveal() == token.Reveal() {
+		v.login.token = secret.Secret{}
+	}
+}
+
+// KubernetesAuth logs in with Vault's Kubernetes auth method, using the service account token of the
+// pod, so a client running in a cluster needs no static Vault token.
+type KubernetesAuth struct {
+	Role      string // Vault role bound to the service account
+	Mount     string // Mount path of the auth method; DefaultKubernetesMount when empty
+	TokenPath string // Service account token file; DefaultServiceAccountTokenPath when empty
+}
+
+// Login reads the service account token, afresh on every login as Kubernetes rotates projected
+// tokens, and exchanges it for a client token.
+func (k KubernetesAuth) Login(ctx context.Context, v *Vault) (secret.Secret, time.Duration, error) {
+	if k.Role == "" {
+		return secret.Secret{}, 0, errors.New("kubernetes auth role is required")
+	}
+
+	jwt, err := os.ReadFile(cmp.Or(k.TokenPath, DefaultServiceAccountTokenPath))
+	if err != nil {
+		return secret.Secret{}, 0, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	defer clear(jwt)
+
+	mount := cmp.Or(strings.Trim(k.Mount, "/"), DefaultKubernetesMount)
+	return v.Login(ctx, "/v1/auth/"+mount+"/login", map[string]string{
+		"role": k.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+}
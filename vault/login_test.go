@@ -0,0 +1,162 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+func TestKubernetesAuth(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	writeJWT := func(jwt string) {
+		if err := os.WriteFile(tokenPath, []byte(jwt+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeJWT("jwt-1")
+
+	var mu sync.Mutex
+	var logins []string
+	valid := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/v1/auth/k8s/login" {
+			var body struct{ Role, JWT string }
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Role != "signer" || r.Header.Get("X-Vault-Token") != "" {
+				http.Error(w, `{"errors":["bad login"]}`, http.StatusBadRequest)
+				return
+			}
+			logins = append(logins, body.JWT)
+			token := fmt.Sprintf("s.%d", len(logins))
+			valid[token] = true
+			fmt.Fprintf(w, `{"auth":{"client_token":%q,"lease_duration":3600}}`, token)
+			return
+		}
+		if !valid[r.Header.Get("X-Vault-Token")] {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"signature":"0x` + strings.Repeat("11", 65) + `"}}`))
+	}))
+	defer srv.Close()
+
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	v := NewVault(srv.URL, "", 0)
+	v.Clock = fake
+	v.Auth = KubernetesAuth{Role: "signer", Mount: "/k8s/", TokenPath: tokenPath}
+	sign := func() {
+		t.Helper()
+		if _, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sign()
+	sign()
+	if len(logins) != 1 {
+		t.Fatalf("logins = %d, want the token to be reused", len(logins))
+	}
+
+	// Past 80% of the lease the client logs in again, with the rotated service account token.
+	writeJWT("jwt-2")
+	fake.Advance(49 * time.Minute)
+	sign()
+	if len(logins) != 2 || logins[1] != "jwt-2" {
+		t.Fatalf("logins = %q, want a second login with the rotated JWT", logins)
+	}
+
+	// A revoked token is replaced on the 403 answer.
+	mu.Lock()
+	valid["s.2"] = false
+	mu.Unlock()
+	sign()
+	if len(logins) != 3 {
+		t.Fatalf("logins = %d, want a login after the token was revoked", len(logins))
+	}
+
+	v.Auth = KubernetesAuth{Role: "signer", Mount: "k8s", TokenPath: filepath.Join(t.TempDir(), "missing")}
+	v.dropToken(v.login.token)
+	if _, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err == nil {
+		t.Error("expected an error without a service account token")
+	}
+}
+
+func TestTokenRenewal(t *testing.T) {
+	var mu sync.Mutex
+	var logins, renewals int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/auth/kubernetes/login":
+			logins++
+			fmt.Fprintf(w, `{"auth":{"client_token":"s.%d","lease_duration":60}}`, logins)
+		case "/v1/auth/token/renew-self":
+			renewals++
+			lease := 60
+			if renewals == 2 {
+				lease = 5 // The token reached its maximum TTL
+			}
+			fmt.Fprintf(w, `{"auth":{"client_token":%q,"lease_duration":%d}}`, r.Header.Get("X-Vault-Token"), lease)
+		default:
+			_, _ = w.Write([]byte(`{"data":{"signature":"0x` + strings.Repeat("11", 65) + `"}}`))
+		}
+	}))
+	defer srv.Close()
+	counts := func() (int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return logins, renewals
+	}
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("jwt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	v := NewVault(srv.URL, "", 0)
+	v.Clock = fake
+	v.Auth = KubernetesAuth{Role: "signer", TokenPath: tokenPath}
+	v.AutoRenew = true
+	advance := func(d time.Duration) {
+		t.Helper()
+		for fake.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		fake.Advance(d)
+	}
+
+	if _, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two thirds into the lease the token is renewed; the renewal hitting the maximum TTL logs in again.
+	advance(40 * time.Second)
+	advance(40 * time.Second)
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if l, r := counts(); l != 2 || r != 2 {
+		t.Fatalf("logins = %d, renewals = %d; want 2 and 2", l, r)
+	}
+
+	if err := v.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(time.Hour)
+	time.Sleep(20 * time.Millisecond)
+	if l, r := counts(); l != 2 || r != 2 {
+		t.Errorf("logins = %d, renewals = %d after Close", l, r)
+	}
+}
@@ -0,0 +1,165 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/secret"
+)
+
+const (
+	// minRenewLease is the shortest lease a renewal may grant; a shorter one means the token reached
+	// its maximum TTL, and is replaced by logging in again.
+	minRenewLease = 10 * time.Second
+	// renewRetryInterval is how long the renewal loop waits after a failed renewal and login.
+	renewRetryInterval = 30 * time.Second
+	// renewTimeout bounds each renewal and login of the renewal loop.
+	renewTimeout = 30 * time.Second
+)
+
+// ErrTokenNotRenewable is returned by RenewToken for a token that does not expire or cannot be renewed.
+var ErrTokenNotRenewable = errors.New("vault token is not renewable")
+
+// RenewToken renews the client token with /v1/auth/token/renew-self and returns its new lease. It is
+// not retried.
+func (v *Vault) RenewToken(ctx context.Context) (time.Duration, error) {
+	token, err := v.clientToken(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return v.renewToken(ctx, token)
+}
+
+// renewToken renews token and records its lease, so requests made with Auth set keep using it.
+func (v *Vault) renewToken(ctx context.Context, token secret.Secret) (time.Duration, error) {
+	const path = "/v1/auth/token/renew-self"
+	req, err := v.newRequest(ctx, http.MethodPost, path, []byte("{}"))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Vault-Token", token.Reveal())
+
+	status, body, err := v.send(req)
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, newVaultError(http.MethodPost, path, status, body)
+	}
+
+	var response loginResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.Auth == nil || response.Auth.LeaseDuration <= 0 {
+		return 0, ErrTokenNotRenewable
+	}
+	lease := time.Duration(response.Auth.LeaseDuration) * time.Second
+
+	v.login.mu.Lock()
+	defer v.login.mu.Unlock()
+	if v.Auth == nil || v.login.token.Reveal() == token.Reveal() {
+		v.login.setLease(clock.OrSystem(v.Clock).Now(), lease)
+	}
+	return lease, nil
+}
+
+// startRenewal starts the renewal loop once, when AutoRenew is set.
+func (v *Vault) startRenewal() {
+	if !v.AutoRenew {
+		return
+	}
+	v.login.startOnce.Do(func() {
+		v.login.mu.Lock()
+		defer v.login.mu.Unlock()
+		if v.login.stop == nil {
+			v.login.stop = make(chan struct{})
+		}
+		go v.renewLoop(v.login.stop)
+	})
+}
+
+// renewLoop keeps the client token valid until stop is closed: it renews the token when two thirds of
+// its lease have passed, and logs in again with Auth when renewal fails or the token reached its maximum
+// TTL. It returns when the token does not expire.
+func (v *Vault) renewLoop(stop <-chan struct{}) {
+	c := clock.OrSystem(v.Clock)
+	for {
+		wait, ok := v.refreshToken()
+		if !ok {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-c.After(wait):
+		}
+		select {
+		case <-stop: // Closed while waiting
+			return
+		default:
+		}
+	}
+}
+
+// refreshToken renews or replaces the client token when due, and returns how long to wait before the
+// next check; ok is false when the token does not expire.
+func (v *Vault) refreshToken() (wait time.Duration, ok bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), renewTimeout)
+	defer cancel()
+
+	now := clock.OrSystem(v.Clock).Now()
+	v.login.mu.Lock()
+	token, renewAt, expires := v.login.token, v.login.renewAt, !v.login.refreshAt.IsZero()
+	v.login.mu.Unlock()
+	if v.Auth == nil {
+		token = v.Token
+	}
+
+	switch {
+	case !token.IsEmpty() && expires && now.Before(renewAt):
+		return renewAt.Sub(now), true
+	case !token.IsEmpty() && (expires || v.Auth == nil):
+		// A static token is renewed on the first check to learn its lease.
+		lease, err := v.renewToken(ctx, token)
+		if err == nil && lease >= minRenewLease {
+			return lease * 2 / 3, true
+		}
+		if errors.Is(err, ErrTokenNotRenewable) && v.Auth == nil {
+			return 0, false
+		}
+	case !token.IsEmpty():
+		return 0, false // Logged in for a token that does not expire
+	}
+	if v.Auth == nil {
+		return renewRetryInterval, true
+	}
+
+	v.dropToken(token)
+	if _, err := v.clientToken(ctx); err != nil {
+		return renewRetryInterval, true
+	}
+	v.login.mu.Lock()
+	defer v.login.mu.Unlock()
+	if v.login.refreshAt.IsZero() {
+		return 0, false
+	}
+	return max(v.login.renewAt.Sub(now), 0), true
+}
+
+// stopRenewal stops the renewal loop, if it was started.
+func (v *Vault) stopRenewal() {
+	v.login.mu.Lock()
+	defer v.login.mu.Unlock()
+	v.login.stopOnce.Do(func() {
+		if v.login.stop == nil {
+			v.login.stop = make(chan struct{})
+		}
+		close(v.login.stop)
+	})
+}
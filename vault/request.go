@@ -0,0 +1,109 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// newRequest builds a request for path on the Vault API, e.g. "/v1/secp/accounts", with the headers every
+// Vault call carries. The path is joined to the path of v.Address, so Vault may sit behind a proxy under a
+// prefix. net/http derives the Host header from the URL and Content-Length from body, and can replay body
+// on redirects and retries.
+func (v *Vault) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	base, err := url.Parse(v.Address)
+	if err != nil || base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil, fmt.Errorf("invalid Vault address %q", v.Address)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, base.JoinPath(path).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("Accept", acceptHeader)
+	req.Header.Set("X-Vault-Token", v.Token.Reveal())
+	return req, nil
+}
+
+// do sends a request built by newRequest and returns the status and body of the answer. Failures v.Retry
+// accepts, by default answers 429 and 503 and transient network failures such as a connection reset or
+// closed mid-request, are retried up to v.MaxRetries times after the wait it sets: Vault signing and key
+// imports are idempotent, so a request that may have reached Vault is safe to send again. Each attempt sends the body from the start. With v.Auth set,
+// a 403 answer logs in again and is retried once, as the token may have been revoked.
+func (v *Vault) do(ctx context.Context, method, path string, body []byte) (int, []byte, error) {
+	relogged := false
+	for attempt := 0; ; attempt++ {
+		token, err := v.clientToken(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		req, err := v.newRequest(ctx, method, path, body)
+		if err != nil {
+			return 0, nil, err
+		}
+		req.Header.Set("X-Vault-Token", token.Reveal())
+
+		resp, respBody, err := v.roundTrip(req)
+		var status int
+		var retryAfter time.Duration
+		if resp != nil {
+			status, retryAfter = resp.StatusCode, ParseRetryAfter(resp.Header, clock.OrSystem(v.Clock).Now())
+		}
+		if status == http.StatusForbidden && v.Auth != nil && !relogged {
+			v.dropToken(token)
+			relogged = true
+			attempt--
+			continue
+		}
+		if (err == nil && status < http.StatusBadRequest) || attempt >= v.MaxRetries || ctx.Err() != nil {
+			return status, respBody, err
+		}
+		delay, retry := v.retryPolicy().Retry(RetryAttempt{Attempt: attempt, Status: status, Err: err, RetryAfter: retryAfter})
+		if !retry {
+			return status, respBody, err
+		}
+		if err := Wait(ctx, v.Clock, delay); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// send sends req and reads the whole answer.
+func (v *Vault) send(req *http.Request) (int, []byte, error) {
+	resp, body, err := v.roundTrip(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+// roundTrip sends req and reads the whole answer, returning a nil response when it failed.
+func (v *Vault) roundTrip(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := v.client().Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp, body, nil
+}
+
+// retryPolicy returns v.Retry, or the default ExponentialBackoff.
+func (v *Vault) retryPolicy() RetryPolicy {
+	if v.Retry == nil {
+		return ExponentialBackoff{}
+	}
+	return v.Retry
+}
@@ -0,0 +1,138 @@
+package vault
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+func TestNewRequest(t *testing.T) {
+	v := NewVault("https://vault.internal:8200/proxy/", "token")
+	body := []byte(`{"payload":"0x00"}`)
+	req, err := v.newRequest(context.Background(), http.MethodPost, "/v1/secp/accounts", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.URL.String(); got != "https://vault.internal:8200/proxy/v1/secp/accounts" {
+		t.Errorf("URL = %s", got)
+	}
+	if req.Host != "vault.internal:8200" || req.Header.Get("Host") != "" {
+		t.Errorf("Host = %q, header %q; want the URL host and no header", req.Host, req.Header.Get("Host"))
+	}
+	if req.ContentLength != int64(len(body)) || req.Header.Get("Content-Length") != "" || req.GetBody == nil {
+		t.Errorf("ContentLength = %d, header %q; want net/http to manage a replayable body", req.ContentLength, req.Header.Get("Content-Length"))
+	}
+	if req.Header.Get("X-Vault-Token") != "token" || req.Header.Get("Content-Type") != contentTypeJSON {
+		t.Errorf("unexpected headers: %v", req.Header)
+	}
+
+	for _, address := range []string{"vault:8200", "ftp://vault", "http://", "://vault"} {
+		v.Address = address
+		if _, err := v.newRequest(context.Background(), http.MethodPost, "/v1/secp/accounts", body); err == nil {
+			t.Errorf("accepted Vault address %q", address)
+		}
+	}
+}
+
+func TestSignMessageRequestHeaders(t *testing.T) {
+	var host string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Host != host {
+			t.Errorf("Host = %q, want %q", r.Host, host)
+		}
+		if r.ContentLength != int64(len(body)) {
+			t.Errorf("Content-Length %d for a %d-byte body", r.ContentLength, len(body))
+		}
+		_, _ = w.Write([]byte(`{"data":{"signature":"0x` + strings.Repeat("11", 65) + `"}}`))
+	}))
+	defer srv.Close()
+	host = strings.TrimPrefix(srv.URL, "http://")
+
+	v := NewVault(srv.URL, "token", 0)
+	if _, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMountPath(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_, _ = w.Write([]byte(`{"data":{"signature":"0x` + strings.Repeat("11", 65) + `"}}`))
+	}))
+	defer srv.Close()
+
+	v := NewVault(srv.URL, "token", 0)
+	for _, mount := range []string{"", "ethsign", "/team/ethsign/"} {
+		v.MountPath = mount
+		if _, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"/v1/secp/accounts/0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed/signRaw",
+		"/v1/ethsign/accounts/0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed/signRaw",
+		"/v1/team/ethsign/accounts/0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed/signRaw",
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("paths = %q, want %q", paths, want)
+	}
+}
+
+func TestRetryTransientFailure(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		first := len(bodies) == 1
+		mu.Unlock()
+		if first {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close() // The client sees EOF
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"signature":"0x` + strings.Repeat("11", 65) + `"}}`))
+	}))
+	defer srv.Close()
+	sent := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(bodies)
+	}
+
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	v := NewVault(srv.URL, "token", 1)
+	v.Clock = fake
+	go func() {
+		for fake.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		fake.Advance(time.Minute)
+	}()
+
+	if _, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err != nil {
+		t.Fatalf("SignMessage: %v", err)
+	}
+	if got := sent(); len(got) != 2 || got[0] != got[1] || got[0] == "" {
+		t.Errorf("bodies = %q, want the same body sent twice", got)
+	}
+
+	mu.Lock()
+	bodies = nil
+	mu.Unlock()
+	v.MaxRetries = 0
+	if _, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err == nil || len(sent()) != 1 {
+		t.Errorf("without retries: err = %v after %d requests", err, len(sent()))
+	}
+}
@@ -0,0 +1,101 @@
+package vault
+
+import (
+	"cmp"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultBackoffBase = time.Second
+	defaultBackoffMax  = 30 * time.Second
+)
+
+// RetryAttempt describes a failed call to a signing backend, for a RetryPolicy to decide on.
+type RetryAttempt struct {
+	Attempt    int           // Retries already made, 0 for the first failure
+	Status     int           // HTTP status of the answer; 0 when the request failed
+	Err        error         // Why the request failed, when it did
+	RetryAfter time.Duration // Wait requested by the answer's Retry-After header; 0 when absent
+}
+
+// RetryPolicy decides whether a failed call is retried and how long to wait first. The number of retries
+// is bounded separately, by Vault.MaxRetries and the context's RetryBudget.
+type RetryPolicy interface {
+	Retry(attempt RetryAttempt) (delay time.Duration, retry bool)
+}
+
+// ExponentialBackoff is the default RetryPolicy. It retries answers 429 and 503 and transient network
+// errors (see IsTransient), waiting Base, 2*Base, 4*Base... up to Max before each retry, less a random
+// jitter of up to half the wait so clients throttled together do not retry together. A Retry-After header
+// replaces the computed wait, still capped at Max.
+type ExponentialBackoff struct {
+	Base time.Duration // Wait before the first retry (default: 1s)
+	Max  time.Duration // Longest wait (default: 30s)
+}
+
+// Retry implements RetryPolicy.
+func (b ExponentialBackoff) Retry(attempt RetryAttempt) (time.Duration, bool) {
+	if !retryable(attempt.Status, attempt.Err) {
+		return 0, false
+	}
+	maxDelay := cmp.Or(b.Max, defaultBackoffMax)
+	if attempt.RetryAfter > 0 {
+		return min(attempt.RetryAfter, maxDelay), true
+	}
+	return b.delay(attempt.Attempt), true
+}
+
+// delay returns the jittered wait before retry attempt+1, in (d/2, d] for the exponential wait d.
+func (b ExponentialBackoff) delay(attempt int) time.Duration {
+	base, maxDelay := cmp.Or(b.Base, defaultBackoffBase), cmp.Or(b.Max, defaultBackoffMax)
+	d := maxDelay
+	if attempt < 32 {
+		if exp := base << attempt; exp > 0 && exp < maxDelay {
+			d = exp
+		}
+	}
+	if d < 2 {
+		return d
+	}
+	return d - rand.N(d/2)
+}
+
+// retryable reports whether an answer with status, or a request failing with err, may succeed when sent again.
+func retryable(status int, err error) bool {
+	if err != nil {
+		return IsTransient(err)
+	}
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// IsTransient reports whether a network error may not recur on a new connection: the server or a proxy
+// closed or reset the connection, or refused it while restarting, or the request timed out.
+func IsTransient(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// ParseRetryAfter returns the wait requested by a Retry-After header, given in seconds or as an HTTP date
+// relative to now; 0 when the header is absent or invalid.
+func ParseRetryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
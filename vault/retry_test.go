@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// recordingPolicy records the decisions of the default policy.
+type recordingPolicy struct {
+	mu       sync.Mutex
+	attempts []RetryAttempt
+	delays   []time.Duration
+}
+
+func (p *recordingPolicy) Retry(attempt RetryAttempt) (time.Duration, bool) {
+	delay, retry := ExponentialBackoff{}.Retry(attempt)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts = append(p.attempts, attempt)
+	p.delays = append(p.delays, delay)
+	return delay, retry
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"data":{"signature":"0x` + strings.Repeat("11", 65) + `"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	policy := &recordingPolicy{}
+	v := NewVault(srv.URL, "token", 3)
+	v.Clock = fake
+	v.Retry = policy
+	go func() {
+		for range 2 {
+			for fake.Waiters() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			fake.Advance(time.Minute)
+		}
+	}()
+
+	if _, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.attempts) != 2 {
+		t.Fatalf("attempts = %+v, want 2", policy.attempts)
+	}
+	if a := policy.attempts[0]; a.Status != http.StatusTooManyRequests || a.RetryAfter != 7*time.Second || policy.delays[0] != 7*time.Second {
+		t.Errorf("first retry %+v waited %v, want the 7s of Retry-After", a, policy.delays[0])
+	}
+	if a, d := policy.attempts[1], policy.delays[1]; a.Attempt != 1 || a.Status != http.StatusServiceUnavailable || d <= time.Second || d > 2*time.Second {
+		t.Errorf("second retry %+v waited %v, want (1s, 2s]", a, d)
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff{Base: 100 * time.Millisecond, Max: time.Second}
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		want *= time.Millisecond
+		for range 20 {
+			if d, ok := b.Retry(RetryAttempt{Attempt: attempt, Status: http.StatusTooManyRequests}); !ok || d <= want/2 || d > want {
+				t.Fatalf("attempt %d: delay %v, want (%v, %v]", attempt, d, want/2, want)
+			}
+		}
+	}
+
+	if d, _ := b.Retry(RetryAttempt{Status: http.StatusServiceUnavailable, RetryAfter: time.Hour}); d != time.Second {
+		t.Errorf("Retry-After was not capped at Max: %v", d)
+	}
+	for _, attempt := range []RetryAttempt{
+		{Status: http.StatusBadRequest},
+		{Status: http.StatusInternalServerError},
+		{Err: errors.New("invalid Vault address")},
+	} {
+		if _, ok := b.Retry(attempt); ok {
+			t.Errorf("%+v should not be retried", attempt)
+		}
+	}
+	if _, ok := b.Retry(RetryAttempt{Err: io.ErrUnexpectedEOF}); !ok {
+		t.Error("a connection closed mid-answer should be retried")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Wed, 01 Jan 2025 00:00:30 GMT": 30 * time.Second,
+		"Tue, 31 Dec 2024 23:59:00 GMT": 0,
+	} {
+		header := http.Header{}
+		if value != "" {
+			header.Set("Retry-After", value)
+		}
+		if got := ParseRetryAfter(header, now); got != want {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package vault
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LoadCACert reads a PEM bundle of CA certificates, e.g. the file named by VAULT_CACERT, into a pool
+// for the RootCAs of Vault.TLS.
+func LoadCACert(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no PEM certificate found in " + path)
+	}
+	return pool, nil
+}
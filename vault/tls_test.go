@@ -0,0 +1,99 @@
+package vault
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMutualTLS(t *testing.T) {
+	clientCert, clientPool := newClientCertificate(t)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"signature":"0x` + strings.Repeat("11", 65) + `"}}`))
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientPool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	roots, err := LoadCACert(caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(v *Vault) error {
+		_, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+		return err
+	}
+
+	v := NewVault(srv.URL, "token", 0)
+	v.TLS = &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}}
+	if err := sign(v); err != nil {
+		t.Fatalf("SignMessage over mTLS: %v", err)
+	}
+
+	v = NewVault(srv.URL, "token", 0)
+	v.TLS = &tls.Config{RootCAs: roots}
+	if err := sign(v); err == nil {
+		t.Error("expected the server to reject a client without certificate")
+	}
+
+	v = NewVault(srv.URL, "token", 0)
+	if err := sign(v); err == nil {
+		t.Error("expected the system roots to reject the test CA")
+	}
+
+	if _, err := LoadCACert(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+}
+
+// newClientCertificate creates a self-signed client certificate and a pool trusting it.
+func newClientCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "vc-auth"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
@@ -0,0 +1,241 @@
+package vault
+
+import (
+	"cmp"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultTransitMount is the path the transit secrets engine is mounted at unless Vault.TransitMount
+	// says otherwise.
+	DefaultTransitMount = "transit"
+	// TransitKeyType is the transit key type created and accepted for signing: ECDSA on P-256, signing
+	// ES256 JWS. The transit engine has no secp256k1 keys.
+	TransitKeyType = "ecdsa-p256"
+)
+
+// Backend selects the Vault secrets engine signatures come from.
+type Backend int
+
+const (
+	// BackendSecp signs with the secp256k1 accounts of the ethsign plugin mounted at MountPath (ES256K).
+	BackendSecp Backend = iota
+	// BackendTransit signs with ecdsa-p256 keys of the built-in transit secrets engine (ES256).
+	BackendTransit
+)
+
+// transitKeyResponse is the part of the transit key read response used here.
+type transitKeyResponse struct {
+	Data struct {
+		Type          string `json:"type"`
+		LatestVersion int    `json:"latest_version"`
+		Keys          map[string]struct {
+			PublicKey string `json:"public_key"`
+		} `json:"keys"`
+	} `json:"data"`
+}
+
+// transitSignResponse is the transit sign response.
+type transitSignResponse struct {
+	Data struct {
+		Signature string `json:"signature"` // "vault:v<version>:<signature>"
+	} `json:"data"`
+}
+
+// transitPath returns the path of an endpoint of the transit engine for the named key.
+func (v *Vault) transitPath(endpoint, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, "/?#") {
+		return "", fmt.Errorf("invalid transit key name %q", name)
+	}
+	mount := cmp.Or(strings.Trim(v.TransitMount, "/"), DefaultTransitMount)
+	return "/v1/" + mount + "/" + endpoint + "/" + url.PathEscape(name), nil
+}
+
+// CreateTransitKey creates the named ecdsa-p256 key in the transit engine, or keeps it when it already
+// exists, and returns its public key. Its private key never leaves Vault.
+func (v *Vault) CreateTransitKey(ctx context.Context, name string) (*ecdsa.PublicKey, error) {
+	path, err := v.transitPath("keys", name)
+	if err != nil {
+		return nil, err
+	}
+
+	status, body, err := v.do(ctx, http.MethodPost, path, []byte(`{"type":"`+TransitKeyType+`"}`))
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK && status != http.StatusNoContent {
+		return nil, newVaultError(http.MethodPost, path, status, body)
+	}
+	return v.TransitPublicKey(ctx, name)
+}
+
+// TransitPublicKey returns the public key of the latest version of the named transit key, which must
+// be an ecdsa-p256 key.
+func (v *Vault) TransitPublicKey(ctx context.Context, name string) (*ecdsa.PublicKey, error) {
+	path, err := v.transitPath("keys", name)
+	if err != nil {
+		return nil, err
+	}
+
+	status, body, err := v.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, transitError(http.MethodGet, path, status, body)
+	}
+
+	var response transitKeyResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.Data.Type != TransitKeyType {
+		return nil, fmt.Errorf("transit key %s is of type %q, want %s", name, response.Data.Type, TransitKeyType)
+	}
+
+	block, _ := pem.Decode([]byte(response.Data.Keys[strconv.Itoa(response.Data.LatestVersion)].PublicKey))
+	if block == nil {
+		return nil, fmt.Errorf("transit key %s has no public key", name)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transit public key: %w", err)
+	}
+	publicKey, ok := key.(*ecdsa.PublicKey)
+	if !ok || publicKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("transit key %s is not a P-256 key", name)
+	}
+	return publicKey, nil
+}
+
+// TransitSign signs a SHA-256 digest with the latest version of the named ecdsa-p256 transit key.
+//
+// - payload: 32 bytes SHA-256 hash of the message
+//
+// - return: 64 bytes r || s signature, or ErrUnknownSigner when the key does not exist
+func (v *Vault) TransitSign(ctx context.Context, name string, payload []byte) ([]byte, error) {
+	if len(payload) != 32 {
+		return nil, fmt.Errorf("payload must be 32 bytes")
+	}
+	path, err := v.transitPath("sign", name)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBody, err := json.Marshal(map[string]any{
+		"input":                base64.StdEncoding.EncodeToString(payload),
+		"prehashed":            true,
+		"hash_algorithm":       "sha2-256",
+		"marshaling_algorithm": "jws", // r || s rather than ASN.1
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	status, body, err := v.do(ctx, http.MethodPost, path, jsonBody)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, transitError(http.MethodPost, path, status, body)
+	}
+
+	var response transitSignResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	parts := strings.SplitN(response.Data.Signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, errors.New("vault returned a malformed transit signature")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if len(signature) != 64 {
+		return nil, fmt.Errorf("vault returned a %d-byte signature", len(signature))
+	}
+	return signature, nil
+}
+
+// transitCiphertextResponse is the transit encrypt and decrypt response.
+type transitCiphertextResponse struct {
+	Data struct {
+		Ciphertext string `json:"ciphertext"` // "vault:v<version>:<ciphertext>"
+		Plaintext  string `json:"plaintext"`  // Base64
+	} `json:"data"`
+}
+
+// TransitEncrypt encrypts plaintext, e.g. a data encryption key, with the latest version of the named
+// transit key, which must be an encryption key such as aes256-gcm96. The returned ciphertext is in
+// Vault's "vault:v<version>:..." form, to pass to TransitDecrypt.
+func (v *Vault) TransitEncrypt(ctx context.Context, name string, plaintext []byte) (string, error) {
+	response, err := v.transitCipher(ctx, "encrypt", name, map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)})
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(response.Data.Ciphertext, "vault:") {
+		return "", errors.New("vault returned a malformed transit ciphertext")
+	}
+	return response.Data.Ciphertext, nil
+}
+
+// TransitDecrypt decrypts a ciphertext returned by TransitEncrypt with the named transit key.
+func (v *Vault) TransitDecrypt(ctx context.Context, name, ciphertext string) ([]byte, error) {
+	response, err := v.transitCipher(ctx, "decrypt", name, map[string]string{"ciphertext": ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(response.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode plaintext: %w", err)
+	}
+	return plaintext, nil
+}
+
+// transitCipher posts request to the encrypt or decrypt endpoint of the named key.
+func (v *Vault) transitCipher(ctx context.Context, endpoint, name string, request map[string]string) (*transitCiphertextResponse, error) {
+	path, err := v.transitPath(endpoint, name)
+	if err != nil {
+		return nil, err
+	}
+	jsonBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	status, body, err := v.do(ctx, http.MethodPost, path, jsonBody)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, newVaultError(http.MethodPost, path, status, body)
+	}
+
+	var response transitCiphertextResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &response, nil
+}
+
+// transitError describes a failed transit request, wrapping ErrUnknownSigner when the key does not exist.
+func transitError(method, path string, status int, body []byte) error {
+	vaultErr := newVaultError(method, path, status, body)
+	if vaultErr.unknownAccount() {
+		return fmt.Errorf("%w: %w", ErrUnknownSigner, vaultErr)
+	}
+	return vaultErr
+}
@@ -0,0 +1,89 @@
+package vault
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransit(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	publicKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	created := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/signing/keys/holder", func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ Type string }
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		if in.Type != TransitKeyType {
+			t.Errorf("unexpected key type %q", in.Type)
+		}
+		created = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /v1/signing/keys/holder", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"type":"ecdsa-p256","latest_version":2,"keys":{"1":{"public_key":"old"},"2":{"public_key":` +
+			jsonString(publicKeyPEM) + `}}}}`))
+	})
+	mux.HandleFunc("POST /v1/signing/sign/holder", func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Input               []byte
+			Prehashed           bool
+			HashAlgorithm       string `json:"hash_algorithm"`
+			MarshalingAlgorithm string `json:"marshaling_algorithm"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		if !in.Prehashed || in.HashAlgorithm != "sha2-256" || in.MarshalingAlgorithm != "jws" {
+			t.Errorf("unexpected sign request %+v", in)
+		}
+		r1, s, _ := ecdsa.Sign(rand.Reader, key, in.Input)
+		signature := append(r1.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		_, _ = w.Write([]byte(`{"data":{"signature":"vault:v2:` + base64.RawURLEncoding.EncodeToString(signature) + `"}}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errors":["encryption key not found"]}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	v := NewVault(srv.URL, "token", 0)
+	v.TransitMount = "signing"
+	publicKey, err := v.CreateTransitKey(context.Background(), "holder")
+	if err != nil || !created || !publicKey.Equal(&key.PublicKey) {
+		t.Fatalf("CreateTransitKey = %v, %v", publicKey, err)
+	}
+
+	digest := make([]byte, 32)
+	signature, err := v.TransitSign(context.Background(), "holder", digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ecdsa.Verify(&key.PublicKey, digest, new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		t.Error("invalid transit signature")
+	}
+
+	if _, err := v.TransitSign(context.Background(), "missing", digest); !errors.Is(err, ErrUnknownSigner) {
+		t.Errorf("expected ErrUnknownSigner, got %v", err)
+	}
+	if _, err := v.TransitSign(context.Background(), "../sys", digest); err == nil {
+		t.Error("expected a key name with a slash to be rejected")
+	}
+}
+
+// jsonString encodes s as a JSON string.
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
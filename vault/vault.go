@@ -1,14 +1,25 @@
 package vault
 
 import (
-	"bytes"
+	"cmp"
 	"context"
+	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/ethaddr"
+	"github/hovanhoa/go-vc-auth/secret"
 )
 
 // StorePrivateKeyResponse represents the Vault API response
@@ -30,14 +41,61 @@ const (
 	acceptHeader      = "*/*"
 	defaultTimeout    = 10 * time.Second
 	defaultMaxRetries = 3
+
+	defaultDialTimeout         = 30 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+	defaultMaxIdleConnsPerHost = 16
+	defaultIdleConnTimeout     = 90 * time.Second
+
+	// DefaultMountPath is the path the ethsign plugin is mounted at unless Vault.MountPath says otherwise.
+	DefaultMountPath = "secp"
 )
 
-// Vault holds the configuration for the Vault endpoint
+var (
+	// ErrAddressMismatch is returned by StorePrivateKey when the address reported by Vault is not the one
+	// derived from the submitted key.
+	ErrAddressMismatch = errors.New("vault address does not match the stored key")
+	// ErrUnknownSigner is returned by SignMessage when Vault holds no account for the signer address, and by
+	// the transit methods when the named key does not exist.
+	ErrUnknownSigner = errors.New("vault holds no account for the signer address")
+)
+
+// Vault holds the configuration for the Vault endpoint.
+// A Vault is safe for concurrent use as long as its fields are not modified after the first request.
 type Vault struct {
-	Address    string // Vault server address (e.g., http://109.237.70.93:8200)
-	Token      string // Vault authentication token
-	MaxRetries int    // Maximum number of retries for HTTP requests
-	httpClient *http.Client
+	Address    string        // Vault server address (e.g., http://109.237.70.93:8200)
+	Token      secret.Secret // Vault authentication token, redacted when printed
+	MaxRetries int           // Maximum number of retries for HTTP requests
+	Clock      clock.Clock   // Time source for retry backoff; nil means the system clock
+	Retry      RetryPolicy   // Which failures are retried and how long to wait; ExponentialBackoff{} when nil
+
+	MountPath    string  // Mount path of the ethsign plugin, e.g. "ethsign"; DefaultMountPath when empty
+	Backend      Backend // Secrets engine the Vault provider signs with (default: BackendSecp)
+	TransitMount string  // Mount path of the transit engine; DefaultTransitMount when empty
+
+	Auth      Authenticator // Logs in for the client token instead of using Token, e.g. KubernetesAuth
+	AutoRenew bool          // Renew the client token in the background, from the first request until Close
+
+	// TLS configures the connections to Vault, e.g. a CA bundle, a client certificate for mTLS, or
+	// InsecureSkipVerify in development; nil uses the system roots. It is ignored after SetHTTPClient.
+	TLS *tls.Config
+	// Conn tunes the timeouts and connection pool of the HTTP client. It is ignored after SetHTTPClient.
+	Conn ConnConfig
+
+	httpClient *http.Client // Set by SetHTTPClient, or built from TLS on the first request
+	clientOnce sync.Once
+	login      loginState
+}
+
+// ConnConfig tunes the HTTP client used to call Vault. Zero fields keep their defaults. Signing services
+// making many concurrent requests should raise MaxIdleConnsPerHost to their concurrency, so requests reuse
+// warm connections instead of paying a TCP and TLS handshake each.
+type ConnConfig struct {
+	Timeout             time.Duration // Timeout of each request attempt, retries excluded (default: 10s)
+	DialTimeout         time.Duration // Timeout of establishing a connection (default: 30s)
+	KeepAlive           time.Duration // Period of TCP keep-alive probes; negative disables them (default: 30s)
+	MaxIdleConnsPerHost int           // Idle connections kept open to Vault (default: 16)
+	IdleConnTimeout     time.Duration // How long an idle connection is kept open (default: 90s)
 }
 
 // NewVault initializes a new Vault instance with the specified address, token, and optional max retries
@@ -49,96 +107,131 @@ func NewVault(address, token string, maxRetries ...int) *Vault {
 
 	return &Vault{
 		Address:    address,
-		Token:      token,
+		Token:      secret.New(token),
 		MaxRetries: retries,
-		httpClient: newHTTPClient(),
 	}
 }
 
-func newHTTPClient() *http.Client {
-	return &http.Client{
-		Timeout: defaultTimeout,
-	}
+// SetHTTPClient replaces the HTTP client used to call Vault, e.g. with one whose Transport fakes
+// Vault in tests or has its own TLS settings. It must be called before the first request.
+func (v *Vault) SetHTTPClient(client *http.Client) {
+	v.httpClient = client
 }
 
-// StorePrivateKey sends a private key to the Vault ethsign accounts endpoint and returns the associated address
-func (v *Vault) StorePrivateKey(ctx context.Context, privateKey string) (string, error) {
-	// Create request payload
-	reqBody := &StorePrivateKeyRequest{
-		PrivateKey: privateKey,
+// client returns the HTTP client set by SetHTTPClient, or builds one from TLS and Conn.
+func (v *Vault) client() *http.Client {
+	v.clientOnce.Do(func() {
+		if v.httpClient == nil {
+			v.httpClient = newHTTPClient(v.TLS, v.Conn)
+		}
+	})
+	return v.httpClient
+}
+
+func newHTTPClient(tlsConfig *tls.Config, conn ConnConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   cmp.Or(conn.DialTimeout, defaultDialTimeout),
+		KeepAlive: cmp.Or(conn.KeepAlive, defaultKeepAlive),
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConnsPerHost = cmp.Or(conn.MaxIdleConnsPerHost, defaultMaxIdleConnsPerHost)
+	transport.MaxIdleConns = max(transport.MaxIdleConns, transport.MaxIdleConnsPerHost)
+	transport.IdleConnTimeout = cmp.Or(conn.IdleConnTimeout, defaultIdleConnTimeout)
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig.Clone()
 	}
 
-	// Construct endpoint URL
-	endpoint := v.Address + "/v1/secp/accounts"
+	return &http.Client{
+		Timeout:   cmp.Or(conn.Timeout, defaultTimeout),
+		Transport: transport,
+	}
+}
 
-	for attempt := 0; attempt <= v.MaxRetries; attempt++ {
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(jsonBody))
-		if err != nil {
-			return "", fmt.Errorf("failed to create request: %w", err)
-		}
+// Close stops the token renewal and releases idle connections to the Vault server.
+// Requests still in flight are not interrupted; cancel their contexts to abort them.
+func (v *Vault) Close() error {
+	v.stopRenewal()
+	if v.httpClient != nil {
+		v.httpClient.CloseIdleConnections()
+	}
+	return nil
+}
 
-		req.Header.Set("Content-Type", contentTypeJSON)
-		req.Header.Set("X-Vault-Token", v.Token)
+// accountsPath returns the path of the ethsign accounts endpoint under the configured mount.
+func (v *Vault) accountsPath() string {
+	return "/v1/" + cmp.Or(strings.Trim(v.MountPath, "/"), DefaultMountPath) + "/accounts"
+}
 
-		resp, err := v.httpClient.Do(req)
-		if err != nil {
-			return "", fmt.Errorf("failed to send request: %w", err)
-		}
+// StoreKeystore decrypts an encrypted keystore (Web3 Secret Storage v3) JSON document with passphrase and
+// stores the key it holds with StorePrivateKey
+func (v *Vault) StoreKeystore(ctx context.Context, keystoreJSON []byte, passphrase secret.Secret) (*StoredKey, error) {
+	raw, err := decryptKeystore(keystoreJSON, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(raw)
+	return v.StorePrivateKey(ctx, raw)
+}
 
-		defer func() {
-			if cerr := resp.Body.Close(); cerr != nil {
-				fmt.Printf("failed to close response body: %v\n", cerr)
-			}
-		}()
-
-		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && attempt < v.MaxRetries {
-			select {
-			case <-ctx.Done():
-				return "", ctx.Err()
-			case <-time.After(time.Duration(attempt+1) * time.Second):
-				continue
-			}
-		}
+// StorePrivateKey sends a private key to the Vault ethsign accounts endpoint and returns the associated address
+// together with the key's public key. The address is checked against the one derived locally from the key.
+//
+// - privateKey: 32 raw bytes, a hex string with or without 0x prefix, or a secret.Secret holding either
+//
+// The key's length and curve are checked before anything is sent to Vault. Copies of the key made by
+// StorePrivateKey are wiped before it returns; the caller's input is left untouched.
+func (v *Vault) StorePrivateKey(ctx context.Context, privateKey any) (*StoredKey, error) {
+	key, err := ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key)
 
-		if resp.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-		}
+	// Create request payload without going through an immutable string, so it can be wiped
+	jsonBody := storePrivateKeyBody(key)
+	defer clear(jsonBody)
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return "", fmt.Errorf("failed to read response body: %w", err)
-		}
+	path := v.accountsPath()
+	status, body, err := v.do(ctx, http.MethodPost, path, jsonBody)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, newVaultError(http.MethodPost, path, status, body)
+	}
 
-		var response StorePrivateKeyResponse
-		if err := json.Unmarshal(body, &response); err != nil {
-			return "", fmt.Errorf("failed to decode response: %w", err)
-		}
+	var response StorePrivateKeyResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
 
-		return response.Data.Address, nil
+	// A misconfigured plugin could answer with another account; never sign under the wrong identity.
+	expected := crypto.PubkeyToAddress(key.PublicKey)
+	if !common.IsHexAddress(response.Data.Address) || common.HexToAddress(response.Data.Address) != expected {
+		return nil, fmt.Errorf("%w: vault returned %q, key derives %s", ErrAddressMismatch, response.Data.Address, expected.Hex())
 	}
 
-	return "", fmt.Errorf("max retries exceeded for request")
+	return &StoredKey{Address: response.Data.Address, PublicKey: &key.PublicKey}, nil
 }
 
 // SignMessage signs a message using the Vault ethsign endpoint and returns the signed message
 //
 // - payload: 32 bytes hash of the message
 //
-// - address: hexa string with 0x prefix of the address
+// - address: hexa string of the address, with or without 0x prefix; a mixed-case address must carry a valid
+// EIP-55 checksum
 //
-// - return: 64 bytes signature
+// - return: 64 bytes signature, or ErrUnknownSigner when Vault holds no account for the address
 func (v *Vault) SignMessage(ctx context.Context, payload []byte, address string) ([]byte, error) {
 	if len(payload) != 32 {
 		return nil, fmt.Errorf("payload must be 32 bytes")
 	}
 
-	if len(address) != 42 {
-		return nil, fmt.Errorf("address must be 42 characters")
+	address, err := ethaddr.Normalize(address)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create request payload
@@ -149,58 +242,31 @@ func (v *Vault) SignMessage(ctx context.Context, payload []byte, address string)
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Construct endpoint URL
-	endpoint := v.Address + "/v1/secp/accounts/" + address + "/signRaw"
-
-	for attempt := 0; attempt <= v.MaxRetries; attempt++ {
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(jsonBody))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-
-		req.Header.Set("Content-Type", contentTypeJSON)
-		req.Header.Set("X-Vault-Token", v.Token)
-		req.Header.Set("Accept", acceptHeader)
-		req.Header.Set("Host", v.Address)
-		req.Header.Set("Content-Length", fmt.Sprintf("%d", len(jsonBody)))
-
-		resp, err := v.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("failed to send request: %w", err)
-		}
-		defer resp.Body.Close()
-
-		// Read response body for error details
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
-		}
-
-		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && attempt < defaultMaxRetries {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(time.Duration(attempt+1) * time.Second):
-				continue
-			}
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("unexpected status code: %d, response body: %s", resp.StatusCode, string(body))
-		}
-
-		var response SignMessageResponse
-		if err := json.Unmarshal(body, &response); err != nil {
-			return nil, fmt.Errorf("failed to decode response: %w, response body: %s", err, string(body))
+	path := v.accountsPath() + "/" + address + "/signRaw"
+	status, body, err := v.do(ctx, http.MethodPost, path, jsonBody)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		vaultErr := newVaultError(http.MethodPost, path, status, body)
+		if vaultErr.unknownAccount() {
+			return nil, fmt.Errorf("%w: %w", ErrUnknownSigner, vaultErr)
 		}
+		return nil, vaultErr
+	}
 
-		signatureBytes, err := hex.DecodeString(response.Data.Signed[2:])
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode response: %w, response body: %s", err, string(body))
-		}
+	var response SignMessageResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
 
-		return signatureBytes[:64], nil
+	signatureBytes, err := hex.DecodeString(strings.TrimPrefix(response.Data.Signed, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if len(signatureBytes) < 64 {
+		return nil, fmt.Errorf("vault returned a %d-byte signature", len(signatureBytes))
 	}
 
-	return nil, fmt.Errorf("max retries exceeded")
+	return signatureBytes[:64], nil
 }
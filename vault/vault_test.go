@@ -0,0 +1,305 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github/hovanhoa/go-vc-auth/secret"
+)
+
+// Test vectors from the Web3 Secret Storage definition; both hold the same key under "testpassword".
+const (
+	testKeystoreKey = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+
+	testKeystorePBKDF2 = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"6087dab2f9fdbbfaddc31a909735c1e6"},` +
+		`"ciphertext":"5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46","kdf":"pbkdf2",` +
+		`"kdfparams":{"c":262144,"dklen":32,"prf":"hmac-sha256","salt":"ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"},` +
+		`"mac":"517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+
+	testKeystoreScrypt = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"83dbcc02d8ccb40e466191a123791e0e"},` +
+		`"ciphertext":"d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c","kdf":"scrypt",` +
+		`"kdfparams":{"dklen":32,"n":262144,"p":8,"r":1,"salt":"ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"},` +
+		`"mac":"2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+)
+
+// newTestVault starts a fake ethsign accounts endpoint that records the submitted keys and answers with address.
+func newTestVault(t *testing.T, address string) (*Vault, *[]string) {
+	t.Helper()
+	var submitted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req StorePrivateKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		submitted = append(submitted, req.PrivateKey)
+		_ = json.NewEncoder(w).Encode(StorePrivateKeyResponse{Data: StorePrivateKeyData{Address: address}})
+	}))
+	t.Cleanup(srv.Close)
+	return NewVault(srv.URL, "token", 0), &submitted
+}
+
+func TestStorePrivateKeyInputs(t *testing.T) {
+	key, err := crypto.HexToECDSA(testKeystoreKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	raw := crypto.FromECDSA(key)
+
+	inputs := map[string]any{
+		"bytes":      raw,
+		"hex":        testKeystoreKey,
+		"0x-hex":     "0x" + testKeystoreKey,
+		"upper hex":  "0X" + strings.ToUpper(testKeystoreKey),
+		"whitespace": " " + testKeystoreKey + "\n",
+		"secret hex": secret.New("0x" + testKeystoreKey),
+		"secret raw": secret.FromBytes(append([]byte(nil), raw...)),
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			v, submitted := newTestVault(t, address)
+			stored, err := v.StorePrivateKey(context.Background(), input)
+			if err != nil {
+				t.Fatalf("StorePrivateKey: %v", err)
+			}
+			if stored.Address != address {
+				t.Errorf("address = %s, want %s", stored.Address, address)
+			}
+			if !stored.PublicKey.Equal(&key.PublicKey) {
+				t.Error("public key does not match the submitted key")
+			}
+			if len(*submitted) != 1 || (*submitted)[0] != testKeystoreKey {
+				t.Errorf("submitted %v, want the normalized hex key", *submitted)
+			}
+		})
+	}
+}
+
+func TestStorePrivateKeyRejectsInvalidKeys(t *testing.T) {
+	order := crypto.S256().Params().N.Bytes()
+	inputs := map[string]any{
+		"short":      make([]byte, 31),
+		"long":       "0x" + strings.Repeat("11", 33),
+		"zero":       make([]byte, 32),
+		"curve":      order,
+		"not hex":    "0xzz" + testKeystoreKey[4:],
+		"wrong type": 42,
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			v, submitted := newTestVault(t, "")
+			if _, err := v.StorePrivateKey(context.Background(), input); err == nil {
+				t.Fatal("expected an error")
+			}
+			if len(*submitted) != 0 {
+				t.Error("invalid key was sent to Vault")
+			}
+		})
+	}
+}
+
+func TestStorePrivateKeyAddressCheck(t *testing.T) {
+	key, err := crypto.HexToECDSA(testKeystoreKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	v, _ := newTestVault(t, strings.ToLower(address))
+	if _, err := v.StorePrivateKey(context.Background(), testKeystoreKey); err != nil {
+		t.Errorf("lower-case address: %v", err)
+	}
+
+	for name, reported := range map[string]string{
+		"other account": "0x0000000000000000000000000000000000000001",
+		"malformed":     "not-an-address",
+		"empty":         "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			v, _ := newTestVault(t, reported)
+			if _, err := v.StorePrivateKey(context.Background(), testKeystoreKey); !errors.Is(err, ErrAddressMismatch) {
+				t.Fatalf("got %v, want ErrAddressMismatch", err)
+			}
+		})
+	}
+}
+
+func TestStoreKeystore(t *testing.T) {
+	for name, keystore := range map[string]string{"pbkdf2": testKeystorePBKDF2, "scrypt": testKeystoreScrypt} {
+		t.Run(name, func(t *testing.T) {
+			v, submitted := newTestVault(t, "0x008aeeda4d805471df9b2a5b0f38a0c3bcba786b")
+			if _, err := v.StoreKeystore(context.Background(), []byte(keystore), secret.New("wrong")); !errors.Is(err, ErrKeystorePassphrase) {
+				t.Fatalf("wrong passphrase: got %v, want ErrKeystorePassphrase", err)
+			}
+			if _, err := v.StoreKeystore(context.Background(), []byte(keystore), secret.New("testpassword")); err != nil {
+				t.Fatalf("StoreKeystore: %v", err)
+			}
+			if len(*submitted) != 1 || (*submitted)[0] != testKeystoreKey {
+				t.Errorf("submitted %v, want %s", *submitted, testKeystoreKey)
+			}
+		})
+	}
+
+	t.Run("unsupported version", func(t *testing.T) {
+		v, _ := newTestVault(t, "")
+		if _, err := v.StoreKeystore(context.Background(), []byte(`{"version":1}`), secret.New("testpassword")); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestVaultTokenRedacted(t *testing.T) {
+	v := NewVault("http://vault:8200", "s.supersecret")
+	for _, out := range []string{fmt.Sprint(v), fmt.Sprintf("%+v", v), fmt.Sprintf("%#v", v)} {
+		if strings.Contains(out, "supersecret") {
+			t.Errorf("token leaked: %s", out)
+		}
+	}
+}
+
+func TestSignMessageAddress(t *testing.T) {
+	const checksummed = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch {
+		case strings.Contains(r.URL.Path, "/accounts/0x00000000000000000000000000000000000000aa/"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.Contains(r.URL.Path, "/accounts/0x00000000000000000000000000000000000000bb/"):
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["account 0x00000000000000000000000000000000000000bb does not exist"]}`))
+		case strings.Contains(r.URL.Path, "/accounts/0x00000000000000000000000000000000000000cc/"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":["no handler for route \"secp/accounts\""]}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":{"signature":"0x` + strings.Repeat("11", 65) + `"}}`))
+		}
+	}))
+	defer srv.Close()
+	v := NewVault(srv.URL, "token", 0)
+	payload := make([]byte, 32)
+
+	for _, address := range []string{checksummed, strings.ToLower(checksummed)[2:]} {
+		if _, err := v.SignMessage(context.Background(), payload, address); err != nil {
+			t.Fatalf("SignMessage(%q): %v", address, err)
+		}
+	}
+	for _, path := range paths {
+		if path != "/v1/secp/accounts/0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed/signRaw" {
+			t.Errorf("unexpected path %s", path)
+		}
+	}
+
+	paths = nil
+	for _, address := range []string{"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", "0x5aaeb6053f", "0xzzaeb6053f3e94c9b9a09f33669435e7ef1beaed"} {
+		if _, err := v.SignMessage(context.Background(), payload, address); err == nil {
+			t.Errorf("SignMessage(%q) accepted an invalid address", address)
+		}
+	}
+	if len(paths) != 0 {
+		t.Errorf("invalid addresses reached Vault: %v", paths)
+	}
+
+	for _, address := range []string{"0x00000000000000000000000000000000000000aa", "0x00000000000000000000000000000000000000bb"} {
+		if _, err := v.SignMessage(context.Background(), payload, address); !errors.Is(err, ErrUnknownSigner) {
+			t.Errorf("SignMessage(%q) = %v, want ErrUnknownSigner", address, err)
+		}
+	}
+	var vaultErr *VaultError
+	if _, err := v.SignMessage(context.Background(), payload, "0x00000000000000000000000000000000000000bb"); !errors.As(err, &vaultErr) || vaultErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown signer error does not carry the Vault error: %v", err)
+	}
+	if _, err := v.SignMessage(context.Background(), payload, "0x00000000000000000000000000000000000000cc"); err == nil || errors.Is(err, ErrUnknownSigner) {
+		t.Errorf("unmounted plugin reported as %v", err)
+	}
+}
+
+func TestVaultError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+	}))
+	defer srv.Close()
+	v := NewVault(srv.URL, "token", 0)
+
+	_, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	var vaultErr *VaultError
+	if !errors.As(err, &vaultErr) {
+		t.Fatalf("expected a *VaultError, got %v", err)
+	}
+	if vaultErr.StatusCode != http.StatusForbidden || vaultErr.Method != http.MethodPost ||
+		vaultErr.Path != "/v1/secp/accounts/0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed/signRaw" ||
+		len(vaultErr.Errors) != 1 || vaultErr.Errors[0] != "permission denied" {
+		t.Errorf("unexpected error: %+v", vaultErr)
+	}
+	if errors.Is(err, ErrUnknownSigner) {
+		t.Error("permission denied reported as an unknown signer")
+	}
+
+	key, _ := crypto.GenerateKey()
+	if _, err := v.StorePrivateKey(context.Background(), crypto.FromECDSA(key)); !errors.As(err, &vaultErr) || vaultErr.Path != "/v1/secp/accounts" {
+		t.Errorf("StorePrivateKey: expected a *VaultError for /v1/secp/accounts, got %v", err)
+	}
+}
+
+func TestConnConfig(t *testing.T) {
+	var mu sync.Mutex
+	var conns int
+	var slow atomic.Bool
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow.Load() {
+			time.Sleep(200 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(`{"data":{"signature":"0x` + strings.Repeat("11", 65) + `"}}`))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	v := NewVault(srv.URL, "token", 0)
+	v.Conn = ConnConfig{Timeout: 50 * time.Millisecond, MaxIdleConnsPerHost: 8}
+	defer v.Close()
+
+	// Concurrent bursts reuse the pooled connections instead of dialing again.
+	for range 3 {
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if conns > 8 {
+		t.Errorf("opened %d connections for 8 concurrent callers", conns)
+	}
+
+	slow.Store(true)
+	if _, err := v.SignMessage(context.Background(), make([]byte, 32), "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"); err == nil {
+		t.Error("expected the request timeout to expire")
+	}
+}
@@ -0,0 +1,212 @@
+package wallet
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/secret"
+)
+
+// ErrBackupPassphrase is returned by Import when the passphrase is wrong or the backup was altered.
+var ErrBackupPassphrase = errors.New("wrong backup passphrase or corrupted backup")
+
+const (
+	backupVersion = 1
+	backupCipher  = "A256GCM"
+	backupKDF     = "scrypt"
+
+	// scrypt cost of new backups. Import bounds the memory scrypt needs for the untrusted parameters of a
+	// backup (128·N·r bytes, 32 MiB for new backups) and its parallelism, which multiplies the CPU time.
+	backupScryptN         = 1 << 15
+	maxBackupScryptMemory = 256 << 20
+	maxBackupScryptP      = 16
+)
+
+// backupHeader is the clear part of a backup. It is authenticated as additional data, so its
+// parameters cannot be altered without failing decryption.
+type backupHeader struct {
+	Version   int          `json:"version"`
+	Cipher    string       `json:"cipher"`
+	KDF       string       `json:"kdf"`
+	KDFParams backupScrypt `json:"kdfparams"`
+	Nonce     string       `json:"nonce"`
+}
+
+type backupScrypt struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"`
+}
+
+// backupFile is the portable JSON document written by Export.
+type backupFile struct {
+	backupHeader
+	CipherText string `json:"ciphertext"`
+}
+
+// backupContents is the encrypted part of a backup.
+type backupContents struct {
+	CreatedAt   time.Time      `json:"createdAt"`
+	Credentials []backupRecord `json:"credentials"`
+}
+
+// backupRecord keeps a credential without its decoded claims, which Import decodes again.
+type backupRecord struct {
+	ID      string    `json:"id"`
+	JWT     string    `json:"jwt"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+// Export writes every credential of the wallet, with the time it was added, to out as a JSON
+// document encrypted with a key derived from passphrase (scrypt, then AES-256-GCM), so the holder can
+// move them to another device. Keys are not part of the wallet and are not exported.
+func (w *Wallet) Export(ctx context.Context, out io.Writer, passphrase secret.Secret) error {
+	if passphrase.IsEmpty() {
+		return errors.New("backup passphrase is required")
+	}
+
+	credentials, err := w.store.List(ctx)
+	if err != nil {
+		return err
+	}
+	contents := backupContents{CreatedAt: w.clock.Now().UTC()}
+	for _, credential := range credentials {
+		contents.Credentials = append(contents.Credentials, backupRecord{ID: credential.ID, JWT: credential.JWT, AddedAt: credential.AddedAt})
+	}
+	plainText, err := json.Marshal(contents)
+	if err != nil {
+		return err
+	}
+	defer clear(plainText)
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	header := backupHeader{
+		Version:   backupVersion,
+		Cipher:    backupCipher,
+		KDF:       backupKDF,
+		KDFParams: backupScrypt{N: backupScryptN, R: 8, P: 1, Salt: base64.RawURLEncoding.EncodeToString(salt)},
+	}
+	aead, err := backupAEAD(header, passphrase)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	header.Nonce = base64.RawURLEncoding.EncodeToString(nonce)
+
+	additionalData, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	file := backupFile{
+		backupHeader: header,
+		CipherText:   base64.RawURLEncoding.EncodeToString(aead.Seal(nil, nonce, plainText, additionalData)),
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(file)
+}
+
+// Import decrypts a backup written by Export and adds its credentials to the wallet, replacing
+// credentials with the same id. It returns the number of credentials imported, and adds none when
+// the passphrase is wrong or the backup was altered.
+func (w *Wallet) Import(ctx context.Context, in io.Reader, passphrase secret.Secret) (int, error) {
+	var file backupFile
+	if err := json.NewDecoder(in).Decode(&file); err != nil {
+		return 0, fmt.Errorf("invalid backup: %w", err)
+	}
+	header := file.backupHeader
+	if header.Version != backupVersion || header.Cipher != backupCipher || header.KDF != backupKDF {
+		return 0, fmt.Errorf("unsupported backup version %d (%s, %s)", header.Version, header.Cipher, header.KDF)
+	}
+
+	nonce, err := base64.RawURLEncoding.DecodeString(header.Nonce)
+	if err != nil {
+		return 0, fmt.Errorf("invalid backup nonce: %w", err)
+	}
+	cipherText, err := base64.RawURLEncoding.DecodeString(file.CipherText)
+	if err != nil {
+		return 0, fmt.Errorf("invalid backup ciphertext: %w", err)
+	}
+	aead, err := backupAEAD(header, passphrase)
+	if err != nil {
+		return 0, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return 0, fmt.Errorf("invalid backup nonce length %d", len(nonce))
+	}
+
+	additionalData, err := json.Marshal(header)
+	if err != nil {
+		return 0, err
+	}
+	plainText, err := aead.Open(nil, nonce, cipherText, additionalData)
+	if err != nil {
+		return 0, ErrBackupPassphrase
+	}
+	defer clear(plainText)
+
+	var contents backupContents
+	if err := json.Unmarshal(plainText, &contents); err != nil {
+		return 0, fmt.Errorf("invalid backup contents: %w", err)
+	}
+
+	// Decode everything before storing anything, so a bad record imports nothing.
+	credentials := make([]Credential, len(contents.Credentials))
+	for i, record := range contents.Credentials {
+		claims, err := auth.ParseCredential(record.JWT)
+		if err != nil {
+			return 0, fmt.Errorf("backup credential %s: %w", record.ID, err)
+		}
+		credentials[i] = Credential{ID: record.ID, JWT: record.JWT, Claims: claims, AddedAt: record.AddedAt}
+	}
+	for i, credential := range credentials {
+		if err := w.store.Put(ctx, credential); err != nil {
+			return i, fmt.Errorf("failed to store credential: %w", err)
+		}
+	}
+	return len(credentials), nil
+}
+
+// backupAEAD derives the backup key from passphrase with the header's scrypt parameters.
+func backupAEAD(header backupHeader, passphrase secret.Secret) (cipher.AEAD, error) {
+	params := header.KDFParams
+	if params.N < 2 || params.R < 1 || params.P < 1 || params.P > maxBackupScryptP ||
+		params.N > maxBackupScryptMemory/128/params.R {
+		return nil, fmt.Errorf("unsupported scrypt parameters n=%d r=%d p=%d", params.N, params.R, params.P)
+	}
+	salt, err := base64.RawURLEncoding.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup salt: %w", err)
+	}
+
+	key, err := scrypt.Key(passphrase.Bytes(), salt, params.N, params.R, params.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,71 @@
+package wallet_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github/hovanhoa/go-vc-auth/secret"
+	"github/hovanhoa/go-vc-auth/wallet"
+)
+
+func TestWalletBackup(t *testing.T) {
+	ctx := context.Background()
+	source := wallet.New(wallet.NewMemoryStore())
+	added, err := source.Add(ctx, newJWT(t, map[string]any{
+		"id":                "urn:uuid:employee",
+		"type":              []string{"VerifiableCredential", "EmployeeCredential"},
+		"issuer":            "did:example:acme",
+		"credentialSubject": map[string]any{"id": "did:example:holder", "role": "engineer"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passphrase := secret.New("correct horse battery staple")
+	var backup bytes.Buffer
+	if err := source.Export(ctx, &backup, passphrase); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if strings.Contains(backup.String(), "engineer") || strings.Contains(backup.String(), added.JWT) {
+		t.Fatal("backup contains credential data in the clear")
+	}
+
+	target := wallet.New(wallet.NewMemoryStore())
+	if _, err := target.Import(ctx, bytes.NewReader(backup.Bytes()), secret.New("wrong")); !errors.Is(err, wallet.ErrBackupPassphrase) {
+		t.Errorf("Import with a wrong passphrase error = %v, want ErrBackupPassphrase", err)
+	}
+
+	// Tampering with the authenticated header fails like a wrong passphrase.
+	var file map[string]any
+	_ = json.Unmarshal(backup.Bytes(), &file)
+	file["kdfparams"].(map[string]any)["p"] = 2
+	tampered, _ := json.Marshal(file)
+	if _, err := target.Import(ctx, bytes.NewReader(tampered), passphrase); !errors.Is(err, wallet.ErrBackupPassphrase) {
+		t.Errorf("Import of a tampered backup error = %v, want ErrBackupPassphrase", err)
+	}
+
+	// Parameters that would make scrypt allocate gigabytes are refused before deriving the key.
+	for _, params := range []map[string]any{{"n": 1 << 20, "r": 1023}, {"n": 1 << 15, "r": 8, "p": 1 << 20}} {
+		_ = json.Unmarshal(backup.Bytes(), &file)
+		for name, value := range params {
+			file["kdfparams"].(map[string]any)[name] = value
+		}
+		costly, _ := json.Marshal(file)
+		if _, err := target.Import(ctx, bytes.NewReader(costly), passphrase); err == nil || errors.Is(err, wallet.ErrBackupPassphrase) {
+			t.Errorf("Import with scrypt parameters %v error = %v, want unsupported parameters", params, err)
+		}
+	}
+
+	n, err := target.Import(ctx, bytes.NewReader(backup.Bytes()), passphrase)
+	if err != nil || n != 1 {
+		t.Fatalf("Import = %d, %v", n, err)
+	}
+	restored, err := target.Get(ctx, added.ID)
+	if err != nil || restored.JWT != added.JWT || !restored.AddedAt.Equal(added.AddedAt) || restored.Claims.Issuer != "did:example:acme" {
+		t.Errorf("restored = %+v, %v", restored, err)
+	}
+}
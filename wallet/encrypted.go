@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/pii"
+)
+
+// sealedCredential is the personal data of a Credential sealed by NewEncryptedStore.
+type sealedCredential struct {
+	JWT    string        `json:"jwt"`
+	Claims auth.VcClaims `json:"claims"`
+}
+
+// encryptedStore seals credentials before they reach the underlying store.
+type encryptedStore struct {
+	store     Store
+	encryptor pii.Encryptor
+}
+
+// NewEncryptedStore wraps store so the JWT and claims of every credential are encrypted with
+// encryptor, bound to the credential id. The underlying store holds them in Credential.Sealed; the id
+// and AddedAt stay in clear.
+func NewEncryptedStore(store Store, encryptor pii.Encryptor) Store {
+	return &encryptedStore{store: store, encryptor: encryptor}
+}
+
+func (s *encryptedStore) Put(ctx context.Context, credential Credential) error {
+	data, err := json.Marshal(sealedCredential{JWT: credential.JWT, Claims: credential.Claims})
+	if err != nil {
+		return err
+	}
+	sealed, err := s.encryptor.Encrypt(ctx, data, []byte(credential.ID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt credential: %w", err)
+	}
+	return s.store.Put(ctx, Credential{ID: credential.ID, AddedAt: credential.AddedAt, Sealed: sealed})
+}
+
+func (s *encryptedStore) Get(ctx context.Context, id string) (Credential, error) {
+	credential, err := s.store.Get(ctx, id)
+	if err != nil {
+		return Credential{}, err
+	}
+	return s.open(ctx, credential)
+}
+
+func (s *encryptedStore) Delete(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, id)
+}
+
+func (s *encryptedStore) List(ctx context.Context) ([]Credential, error) {
+	credentials, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, credential := range credentials {
+		if credentials[i], err = s.open(ctx, credential); err != nil {
+			return nil, err
+		}
+	}
+	return credentials, nil
+}
+
+// open restores the JWT and claims of credential from Sealed. Credentials stored without encryption
+// are returned as they are.
+func (s *encryptedStore) open(ctx context.Context, credential Credential) (Credential, error) {
+	if len(credential.Sealed) == 0 {
+		return credential, nil
+	}
+
+	data, err := s.encryptor.Decrypt(ctx, credential.Sealed, []byte(credential.ID))
+	if err != nil {
+		return Credential{}, fmt.Errorf("failed to decrypt credential %s: %w", credential.ID, err)
+	}
+	var fields sealedCredential
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Credential{}, fmt.Errorf("invalid sealed credential %s: %w", credential.ID, err)
+	}
+	credential.JWT, credential.Claims, credential.Sealed = fields.JWT, fields.Claims, nil
+	return credential, nil
+}
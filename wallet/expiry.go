@@ -0,0 +1,169 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/redact"
+)
+
+// ExpiryEvent reports a credential whose validUntil is near or past.
+type ExpiryEvent struct {
+	Credential Credential  // Credential approaching expiry
+	ExpiresAt  time.Time   // Its validUntil
+	Expired    bool        // Whether validUntil has passed
+	Renewed    *Credential // Replacement stored by the Renewer, if any
+	Err        error       // Error of the Renewer, if it failed
+}
+
+// Redacted returns a copy of the event for logs and webhooks: the JWTs are dropped and the claims
+// redacted by policy, see auth.VcClaims.Redacted.
+func (e ExpiryEvent) Redacted(policy *redact.Policy) ExpiryEvent {
+	redacted := func(c Credential) Credential {
+		c.JWT, c.Sealed, c.Claims = "", nil, c.Claims.Redacted(policy)
+		return c
+	}
+	out := e
+	out.Credential = redacted(e.Credential)
+	if e.Renewed != nil {
+		renewed := redacted(*e.Renewed)
+		out.Renewed = &renewed
+	}
+	return out
+}
+
+// Renewer obtains a fresh copy of a credential, e.g. from the refreshService listed in its claims or
+// from the issuer over OpenID4VCI, and returns the new JWT VC.
+type Renewer interface {
+	Renew(ctx context.Context, credential Credential) (string, error)
+}
+
+// RenewerFunc adapts a function to a Renewer.
+type RenewerFunc func(ctx context.Context, credential Credential) (string, error)
+
+// Renew calls f.
+func (f RenewerFunc) Renew(ctx context.Context, credential Credential) (string, error) {
+	return f(ctx, credential)
+}
+
+// ExpiryWatcher watches a wallet for credentials approaching expiry, so holder applications can
+// renew them before a presentation fails. Each credential is reported once when it enters the
+// notice period and once more when it expires. It is safe for concurrent use.
+type ExpiryWatcher struct {
+	wallet  *Wallet
+	notice  time.Duration
+	renewer Renewer
+	handler func(ctx context.Context, event ExpiryEvent)
+
+	mu       sync.Mutex
+	reported map[string]bool // Credential id to whether it was reported expired
+}
+
+// WatchOpt configures an ExpiryWatcher.
+type WatchOpt func(*ExpiryWatcher)
+
+// WithRenewer makes the watcher renew credentials entering the notice period with renewer. Renewed
+// credentials are added to the wallet and replace the old ones.
+func WithRenewer(renewer Renewer) WatchOpt {
+	return func(x *ExpiryWatcher) {
+		x.renewer = renewer
+	}
+}
+
+// WithExpiryHandler calls handler with every event, e.g. to notify the user.
+func WithExpiryHandler(handler func(ctx context.Context, event ExpiryEvent)) WatchOpt {
+	return func(x *ExpiryWatcher) {
+		x.handler = handler
+	}
+}
+
+// NewExpiryWatcher creates a watcher reporting credentials of w that expire within notice.
+func NewExpiryWatcher(w *Wallet, notice time.Duration, opts ...WatchOpt) *ExpiryWatcher {
+	x := &ExpiryWatcher{wallet: w, notice: notice, reported: map[string]bool{}}
+	for _, opt := range opts {
+		opt(x)
+	}
+	return x
+}
+
+// Check looks for newly expiring or expired credentials once, renews them when a Renewer is set,
+// and returns the events it also handed to the handler.
+func (x *ExpiryWatcher) Check(ctx context.Context) ([]ExpiryEvent, error) {
+	credentials, err := x.wallet.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	now := x.wallet.clock.Now()
+	held := make(map[string]bool, len(credentials))
+	var events []ExpiryEvent
+	for _, credential := range credentials {
+		held[credential.ID] = true
+		expiresAt := credential.Claims.ValidUntil
+		if expiresAt.IsZero() || expiresAt.After(now.Add(x.notice)) {
+			continue
+		}
+
+		expired := !expiresAt.After(now)
+		if reportedExpired, ok := x.reported[credential.ID]; ok && (reportedExpired || !expired) {
+			continue
+		}
+		x.reported[credential.ID] = expired
+
+		event := ExpiryEvent{Credential: credential, ExpiresAt: expiresAt, Expired: expired}
+		if x.renewer != nil {
+			event.Renewed, event.Err = x.renew(ctx, credential)
+		}
+		if x.handler != nil {
+			x.handler(ctx, event)
+		}
+		events = append(events, event)
+	}
+
+	for id := range x.reported {
+		if !held[id] {
+			delete(x.reported, id)
+		}
+	}
+	return events, nil
+}
+
+// renew replaces credential with the copy returned by the Renewer.
+func (x *ExpiryWatcher) renew(ctx context.Context, credential Credential) (*Credential, error) {
+	vcJwt, err := x.renewer.Renew(ctx, credential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to renew credential %s: %w", credential.ID, err)
+	}
+
+	renewed, err := x.wallet.Add(ctx, vcJwt)
+	if err != nil {
+		return nil, err
+	}
+	if renewed.ID != credential.ID {
+		if err := x.wallet.Remove(ctx, credential.ID); err != nil {
+			return &renewed, fmt.Errorf("failed to remove renewed credential %s: %w", credential.ID, err)
+		}
+	}
+	if renewed.Claims.ValidUntil.After(credential.Claims.ValidUntil) {
+		delete(x.reported, credential.ID) // Report the new copy when it nears expiry in turn
+	}
+	return &renewed, nil
+}
+
+// Run calls Check every interval until ctx is done. Errors listing the wallet are retried at the
+// next interval.
+func (x *ExpiryWatcher) Run(ctx context.Context, interval time.Duration) {
+	for {
+		_, _ = x.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-x.wallet.clock.After(interval):
+		}
+	}
+}
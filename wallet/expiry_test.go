@@ -0,0 +1,76 @@
+package wallet_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/wallet"
+)
+
+func TestExpiryWatcher(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC))
+	w := wallet.New(wallet.NewMemoryStore(), wallet.WithClock(fake))
+
+	badge := func(id, validUntil string) map[string]any {
+		return map[string]any{
+			"id":                id,
+			"type":              []string{"VerifiableCredential", "BadgeCredential"},
+			"issuer":            "did:example:acme",
+			"validUntil":        validUntil,
+			"refreshService":    map[string]any{"id": "https://acme.example/refresh", "type": "VerifiableCredentialRefreshService2021"},
+			"credentialSubject": map[string]any{"id": "did:example:holder"},
+		}
+	}
+	old, err := w.Add(ctx, newJWT(t, badge("urn:uuid:badge-2025", "2026-01-01T00:00:00Z")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Add(ctx, newJWT(t, badge("urn:uuid:badge-long", "2030-01-01T00:00:00Z"))); err != nil {
+		t.Fatal(err)
+	}
+	if len(old.Claims.RefreshServices) != 1 || old.Claims.RefreshServices[0].ID != "https://acme.example/refresh" {
+		t.Fatalf("RefreshServices = %+v", old.Claims.RefreshServices)
+	}
+
+	var handled int
+	watcher := wallet.NewExpiryWatcher(w, 30*24*time.Hour, wallet.WithExpiryHandler(func(context.Context, wallet.ExpiryEvent) {
+		handled++
+	}))
+
+	events, err := watcher.Check(ctx)
+	if err != nil || len(events) != 1 || events[0].Credential.ID != old.ID || events[0].Expired {
+		t.Fatalf("first Check = %+v, %v; want the expiring badge", events, err)
+	}
+	if events, _ := watcher.Check(ctx); len(events) != 0 {
+		t.Errorf("second Check reported %d events again", len(events))
+	}
+
+	fake.Advance(14 * 24 * time.Hour)
+	events, _ = watcher.Check(ctx)
+	if len(events) != 1 || !events[0].Expired {
+		t.Fatalf("Check after expiry = %+v; want the expired badge", events)
+	}
+	if handled != 2 {
+		t.Errorf("handler called %d times, want 2", handled)
+	}
+
+	// A renewer replaces credentials entering the notice period.
+	renewing := wallet.NewExpiryWatcher(w, 30*24*time.Hour, wallet.WithRenewer(wallet.RenewerFunc(
+		func(ctx context.Context, credential wallet.Credential) (string, error) {
+			if credential.Claims.RefreshServices[0].ID != "https://acme.example/refresh" {
+				return "", errors.New("no refresh service")
+			}
+			return newJWT(t, badge("urn:uuid:badge-2026", "2027-01-01T00:00:00Z")), nil
+		})))
+	events, _ = renewing.Check(ctx)
+	if len(events) != 1 || events[0].Err != nil || events[0].Renewed == nil || events[0].Renewed.ID != "urn:uuid:badge-2026" {
+		t.Fatalf("renewing Check = %+v", events)
+	}
+	if _, err := w.Get(ctx, old.ID); !errors.Is(err, wallet.ErrNotFound) {
+		t.Errorf("renewed credential still held: %v", err)
+	}
+}
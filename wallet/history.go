@@ -0,0 +1,158 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+)
+
+// ErrNoHistory is returned by the history methods of a Wallet created without WithHistory.
+var ErrNoHistory = errors.New("wallet has no presentation history")
+
+// HistoryStore persists the consent receipts of the presentations made from a wallet, oldest first.
+// Implementations must be safe for concurrent use.
+type HistoryStore interface {
+	Append(ctx context.Context, receipt auth.ConsentReceipt) error
+	List(ctx context.Context) ([]auth.ConsentReceipt, error)
+}
+
+// memoryHistoryStore keeps receipts in memory.
+type memoryHistoryStore struct {
+	mu       sync.RWMutex
+	receipts []auth.ConsentReceipt
+}
+
+// NewMemoryHistoryStore creates an in-memory HistoryStore.
+func NewMemoryHistoryStore() HistoryStore {
+	return &memoryHistoryStore{}
+}
+
+func (s *memoryHistoryStore) Append(ctx context.Context, receipt auth.ConsentReceipt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.receipts = append(s.receipts, receipt)
+	return nil
+}
+
+func (s *memoryHistoryStore) List(ctx context.Context) ([]auth.ConsentReceipt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]auth.ConsentReceipt(nil), s.receipts...), nil
+}
+
+// WithHistory records the presentations made with the wallet as consent receipt sink in store.
+func WithHistory(store HistoryStore) Option {
+	return func(w *Wallet) {
+		w.history = store
+	}
+}
+
+// StoreReceipt records a presentation in the wallet history, so a Wallet can be passed to
+// auth.WithConsentReceipt:
+//
+//	token, err := a.CreateToken(ctx, jwts, holderDid, holderAddress,
+//	    auth.WithAudience(verifierDid), auth.WithConsentReceipt(w, "account opening"))
+func (w *Wallet) StoreReceipt(ctx context.Context, receipt auth.ConsentReceipt) error {
+	if w.history == nil {
+		return ErrNoHistory
+	}
+	return w.history.Append(ctx, receipt)
+}
+
+// HistoryQuery selects presentations from the history. Every non-zero criterion must match.
+type HistoryQuery struct {
+	Recipient  string    // Presentation was made to this verifier
+	Credential string    // Presentation included the credential with this id
+	Issuer     string    // Presentation included a credential of this issuer
+	Claim      string    // Presentation disclosed this credentialSubject claim
+	Since      time.Time // Presentation was made at or after this time
+	Until      time.Time // Presentation was made before this time
+}
+
+// Match reports whether the receipt satisfies every criterion of the query.
+func (q HistoryQuery) Match(receipt auth.ConsentReceipt) bool {
+	if q.Recipient != "" && receipt.Recipient != q.Recipient {
+		return false
+	}
+	if !q.Since.IsZero() && receipt.IssuedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !receipt.IssuedAt.Before(q.Until) {
+		return false
+	}
+
+	for _, credential := range receipt.Credentials {
+		if (q.Credential == "" || credential.ID == q.Credential) &&
+			(q.Issuer == "" || credential.Issuer == q.Issuer) &&
+			(q.Claim == "" || contains(credential.Claims, q.Claim)) {
+			return true
+		}
+	}
+	return q.Credential == "" && q.Issuer == "" && q.Claim == ""
+}
+
+// History returns the recorded presentations matching q, oldest first.
+func (w *Wallet) History(ctx context.Context, q HistoryQuery) ([]auth.ConsentReceipt, error) {
+	if w.history == nil {
+		return nil, ErrNoHistory
+	}
+
+	receipts, err := w.history.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []auth.ConsentReceipt
+	for _, receipt := range receipts {
+		if q.Match(receipt) {
+			matches = append(matches, receipt)
+		}
+	}
+	return matches, nil
+}
+
+// historyEntry is the export format of a consent receipt.
+type historyEntry struct {
+	ID               string                     `json:"id"`
+	Holder           string                     `json:"holder"`
+	Recipient        string                     `json:"recipient,omitempty"`
+	Purpose          string                     `json:"purpose,omitempty"`
+	PresentedAt      time.Time                  `json:"presentedAt"`
+	Credentials      []auth.ConsentedCredential `json:"credentials"`
+	PresentationHash string                     `json:"presentationHash"`
+	Receipt          string                     `json:"receipt"` // Signed receipt JWT, proving the entry
+}
+
+// ExportHistory writes the recorded presentations matching q to out as a JSON array, oldest first,
+// for review by the holder or a compliance team. Each entry carries its signed receipt.
+func (w *Wallet) ExportHistory(ctx context.Context, out io.Writer, q HistoryQuery) error {
+	receipts, err := w.History(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]historyEntry, len(receipts))
+	for i, receipt := range receipts {
+		entries[i] = historyEntry{
+			ID:               receipt.ID,
+			Holder:           receipt.Holder,
+			Recipient:        receipt.Recipient,
+			Purpose:          receipt.Purpose,
+			PresentedAt:      receipt.IssuedAt,
+			Credentials:      receipt.Credentials,
+			PresentationHash: receipt.PresentationHash,
+			Receipt:          receipt.JWT,
+		}
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
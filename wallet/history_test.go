@@ -0,0 +1,77 @@
+package wallet_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/wallet"
+)
+
+func TestWalletHistory(t *testing.T) {
+	ctx := context.Background()
+	if err := wallet.New(wallet.NewMemoryStore()).StoreReceipt(ctx, auth.ConsentReceipt{}); !errors.Is(err, wallet.ErrNoHistory) {
+		t.Errorf("StoreReceipt without history error = %v, want ErrNoHistory", err)
+	}
+
+	w := wallet.New(wallet.NewMemoryStore(), wallet.WithHistory(wallet.NewMemoryHistoryStore()))
+	var _ auth.ConsentSink = w
+
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	receipts := []auth.ConsentReceipt{
+		{ID: "r1", Holder: "did:example:holder", Recipient: "did:example:bank", IssuedAt: day, JWT: "jwt-1",
+			Credentials: []auth.ConsentedCredential{{ID: "urn:uuid:employee", Issuer: "did:example:acme", Claims: []string{"id", "role"}}}},
+		{ID: "r2", Holder: "did:example:holder", Recipient: "did:example:shop", IssuedAt: day.Add(24 * time.Hour), JWT: "jwt-2",
+			Credentials: []auth.ConsentedCredential{{ID: "urn:uuid:degree", Issuer: "did:example:university", Claims: []string{"id", "degree"}}}},
+	}
+	for _, receipt := range receipts {
+		if err := w.StoreReceipt(ctx, receipt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query wallet.HistoryQuery
+		want  []string
+	}{
+		{"all", wallet.HistoryQuery{}, []string{"r1", "r2"}},
+		{"recipient", wallet.HistoryQuery{Recipient: "did:example:shop"}, []string{"r2"}},
+		{"credential", wallet.HistoryQuery{Credential: "urn:uuid:employee"}, []string{"r1"}},
+		{"claim", wallet.HistoryQuery{Claim: "degree"}, []string{"r2"}},
+		{"issuer and claim of different credentials", wallet.HistoryQuery{Issuer: "did:example:acme", Claim: "degree"}, nil},
+		{"since", wallet.HistoryQuery{Since: day.Add(time.Hour)}, []string{"r2"}},
+		{"until", wallet.HistoryQuery{Until: day.Add(time.Hour)}, []string{"r1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.History(ctx, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, receipt := range got {
+				ids = append(ids, receipt.ID)
+			}
+			if len(ids) != len(tt.want) || (len(ids) > 0 && ids[0] != tt.want[0]) {
+				t.Errorf("History = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	if err := w.ExportHistory(ctx, &out, wallet.HistoryQuery{Recipient: "did:example:bank"}); err != nil {
+		t.Fatal(err)
+	}
+	var exported []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 1 || exported[0]["receipt"] != "jwt-1" || exported[0]["presentedAt"] != "2025-06-01T00:00:00Z" {
+		t.Errorf("exported = %v", exported)
+	}
+}
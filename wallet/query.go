@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/pe"
+)
+
+// Query selects credentials. Every non-zero criterion must match.
+type Query struct {
+	Types           []string            // Credential must declare all of these types
+	Issuers         []string            // Credential issuer must be one of these DIDs
+	Subject         map[string]any      // Subject claims that must equal these values
+	ValidAt         time.Time           // Credential must be valid at this time
+	InputDescriptor *pe.InputDescriptor // Credential must satisfy this Presentation Exchange input descriptor
+}
+
+// Credentials is a list of wallet credentials.
+type Credentials []Credential
+
+// JWTs returns the JWTs of the credentials, ready to pass to auth.Auth.CreateToken.
+func (c Credentials) JWTs() []string {
+	jwts := make([]string, len(c))
+	for i, credential := range c {
+		jwts[i] = credential.JWT
+	}
+	return jwts
+}
+
+// Query returns the credentials matching q, in the order the store lists them.
+func (w *Wallet) Query(ctx context.Context, q Query) (Credentials, error) {
+	credentials, err := w.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches Credentials
+	for _, credential := range credentials {
+		ok, err := q.Match(credential)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, credential)
+		}
+	}
+
+	return matches, nil
+}
+
+// Match reports whether the credential satisfies every criterion of the query.
+func (q Query) Match(credential Credential) (bool, error) {
+	claims := credential.Claims
+
+	for _, t := range q.Types {
+		if !claims.HasType(t) {
+			return false, nil
+		}
+	}
+
+	if len(q.Issuers) > 0 && !contains(q.Issuers, claims.Issuer) {
+		return false, nil
+	}
+
+	if !q.ValidAt.IsZero() {
+		if !claims.ValidFrom.IsZero() && q.ValidAt.Before(claims.ValidFrom) {
+			return false, nil
+		}
+		if !claims.ValidUntil.IsZero() && q.ValidAt.After(claims.ValidUntil) {
+			return false, nil
+		}
+	}
+
+	if len(q.Subject) > 0 && !matchesSubject(credential, q.Subject) {
+		return false, nil
+	}
+
+	if q.InputDescriptor != nil {
+		payload, err := jwtPayload(credential.JWT)
+		if err != nil {
+			return false, fmt.Errorf("credential %s: %w", credential.ID, err)
+		}
+		return q.InputDescriptor.Match(payload)
+	}
+
+	return true, nil
+}
+
+// matchesSubject reports whether one of the credential subjects has all the wanted claim values.
+func matchesSubject(credential Credential, want map[string]any) bool {
+	for _, subject := range credential.Claims.CredentialSubject {
+		matched := true
+		for name, value := range want {
+			got, ok := subject.Get(name)
+			if !ok || !equalJSON(got, value) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// equalJSON compares values after a JSON round trip, so 1 and 1.0 are equal.
+func equalJSON(a, b any) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+
+	var aValue, bValue any
+	_ = json.Unmarshal(aJSON, &aValue)
+	_ = json.Unmarshal(bJSON, &bValue)
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+// jwtPayload decodes the payload of a compact JWT.
+func jwtPayload(token string) (map[string]any, error) {
+	parts := strings.Split(strings.Trim(token, "\""), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid JWT format")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+	return payload, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the JWTs of the currently valid credentials satisfying the input descriptor,
+// so a Wallet can be used as an auth.CredentialSource.
+func (w *Wallet) Select(ctx context.Context, descriptor pe.InputDescriptor) ([]string, error) {
+	matches, err := w.Query(ctx, Query{ValidAt: w.clock.Now(), InputDescriptor: &descriptor})
+	if err != nil {
+		return nil, err
+	}
+	return matches.JWTs(), nil
+}
@@ -0,0 +1,104 @@
+// Package wallet stores the credentials of a holder and selects them for presentations.
+package wallet
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+)
+
+// ErrNotFound is returned when a credential is not in the store.
+var ErrNotFound = errors.New("credential not found")
+
+// Credential is a JWT VC held in a wallet, with its decoded claims.
+type Credential struct {
+	ID      string        `json:"id"`               // Credential id, or a digest of the JWT when the credential has none
+	JWT     string        `json:"jwt"`              // Compact JWT VC as received from the issuer
+	Claims  auth.VcClaims `json:"claims"`           // Claims decoded from JWT
+	AddedAt time.Time     `json:"addedAt"`          // When the credential was stored
+	Sealed  []byte        `json:"sealed,omitempty"` // JWT and Claims as encrypted by NewEncryptedStore, in the store only
+}
+
+// Store persists wallet credentials. Implementations must be safe for concurrent use.
+type Store interface {
+	Put(ctx context.Context, credential Credential) error
+	Get(ctx context.Context, id string) (Credential, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]Credential, error)
+}
+
+// memoryStore keeps credentials in memory.
+type memoryStore struct {
+	mu          sync.RWMutex
+	credentials map[string]memoryEntry
+	seq         uint64
+}
+
+// memoryEntry records insertion order, which clock resolution alone cannot guarantee.
+type memoryEntry struct {
+	credential Credential
+	seq        uint64
+}
+
+// NewMemoryStore creates an in-memory Store.
+func NewMemoryStore() Store {
+	return &memoryStore{credentials: map[string]memoryEntry{}}
+}
+
+// Put stores the credential, replacing any credential with the same id.
+func (s *memoryStore) Put(ctx context.Context, credential Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	s.credentials[credential.ID] = memoryEntry{credential: credential, seq: s.seq}
+	return nil
+}
+
+// Get returns the credential with the given id.
+func (s *memoryStore) Get(ctx context.Context, id string) (Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.credentials[id]
+	if !ok {
+		return Credential{}, ErrNotFound
+	}
+	return entry.credential, nil
+}
+
+// Delete removes the credential with the given id.
+func (s *memoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.credentials[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.credentials, id)
+	return nil
+}
+
+// List returns every credential in the order they were stored.
+func (s *memoryStore) List(ctx context.Context) ([]Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]memoryEntry, 0, len(s.credentials))
+	for _, entry := range s.credentials {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+
+	credentials := make([]Credential, len(entries))
+	for i, entry := range entries {
+		credentials[i] = entry.credential
+	}
+	return credentials, nil
+}
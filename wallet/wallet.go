@@ -0,0 +1,80 @@
+package wallet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/clock"
+)
+
+// Wallet holds credentials in a Store and answers queries over them.
+type Wallet struct {
+	store   Store
+	clock   clock.Clock
+	history HistoryStore
+}
+
+// Option configures a Wallet.
+type Option func(*Wallet)
+
+// WithClock sets the time source for AddedAt and for selecting currently valid credentials
+// (default: the system clock).
+func WithClock(c clock.Clock) Option {
+	return func(w *Wallet) {
+		w.clock = clock.OrSystem(c)
+	}
+}
+
+// New creates a Wallet backed by store.
+func New(store Store, opts ...Option) *Wallet {
+	w := &Wallet{store: store, clock: clock.System()}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Add decodes and stores a JWT VC. The credential is not verified; verify it with
+// auth.Service.VerifyCredential before accepting it from an untrusted source.
+func (w *Wallet) Add(ctx context.Context, vcJwt string) (Credential, error) {
+	claims, err := auth.ParseCredential(vcJwt)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	id := claims.ID
+	if id == "" {
+		digest := sha256.Sum256([]byte(vcJwt))
+		id = "urn:sha256:" + hex.EncodeToString(digest[:])
+	}
+
+	credential := Credential{
+		ID:      id,
+		JWT:     vcJwt,
+		Claims:  claims,
+		AddedAt: w.clock.Now().UTC(),
+	}
+	if err := w.store.Put(ctx, credential); err != nil {
+		return Credential{}, fmt.Errorf("failed to store credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+// Get returns the credential with the given id.
+func (w *Wallet) Get(ctx context.Context, id string) (Credential, error) {
+	return w.store.Get(ctx, id)
+}
+
+// Remove deletes the credential with the given id.
+func (w *Wallet) Remove(ctx context.Context, id string) error {
+	return w.store.Delete(ctx, id)
+}
+
+// List returns every credential in the wallet.
+func (w *Wallet) List(ctx context.Context) ([]Credential, error) {
+	return w.store.List(ctx)
+}
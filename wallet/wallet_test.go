@@ -0,0 +1,164 @@
+package wallet_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/clock"
+	"github/hovanhoa/go-vc-auth/pe"
+	"github/hovanhoa/go-vc-auth/pii"
+	"github/hovanhoa/go-vc-auth/secret"
+	"github/hovanhoa/go-vc-auth/wallet"
+)
+
+// newJWT builds an unsigned JWT VC; the wallet only decodes credentials.
+func newJWT(t *testing.T, vc map[string]any) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]any{"alg": "ES256K", "kid": vc["issuer"].(string) + "#key-1", "typ": "JWT"})
+	payload, err := json.Marshal(map[string]any{"iss": vc["issuer"], "jti": vc["id"], "vc": vc})
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload) + ".c2ln"
+}
+
+func TestWalletQuery(t *testing.T) {
+	ctx := context.Background()
+	w := wallet.New(wallet.NewMemoryStore())
+
+	employee, err := w.Add(ctx, newJWT(t, map[string]any{
+		"id":                "urn:uuid:employee",
+		"type":              []string{"VerifiableCredential", "EmployeeCredential"},
+		"issuer":            "did:example:acme",
+		"validFrom":         "2025-01-01T00:00:00Z",
+		"validUntil":        "2026-01-01T00:00:00Z",
+		"credentialSubject": map[string]any{"id": "did:example:holder", "role": "engineer", "level": 3},
+	}))
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	degree, err := w.Add(ctx, newJWT(t, map[string]any{
+		"id":                "urn:uuid:degree",
+		"type":              []string{"VerifiableCredential", "UniversityDegreeCredential"},
+		"issuer":            "did:example:university",
+		"credentialSubject": map[string]any{"id": "did:example:holder", "degree": map[string]any{"type": "BachelorDegree"}},
+	}))
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	descriptor := &pe.InputDescriptor{
+		ID: "degree",
+		Constraints: pe.Constraints{Fields: []pe.Field{{
+			Path:   []string{"$.vc.credentialSubject.degree.type", "$.credentialSubject.degree.type"},
+			Filter: json.RawMessage(`{"type":"string","const":"BachelorDegree"}`),
+		}}},
+	}
+
+	tests := []struct {
+		name  string
+		query wallet.Query
+		want  []string
+	}{
+		{"all", wallet.Query{}, []string{employee.ID, degree.ID}},
+		{"type", wallet.Query{Types: []string{"EmployeeCredential"}}, []string{employee.ID}},
+		{"issuer", wallet.Query{Issuers: []string{"did:example:university"}}, []string{degree.ID}},
+		{"subject value", wallet.Query{Subject: map[string]any{"role": "engineer", "level": 3}}, []string{employee.ID}},
+		{"subject mismatch", wallet.Query{Subject: map[string]any{"role": "manager"}}, nil},
+		{"valid", wallet.Query{Types: []string{"EmployeeCredential"}, ValidAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}, []string{employee.ID}},
+		{"expired", wallet.Query{Types: []string{"EmployeeCredential"}, ValidAt: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)}, nil},
+		{"input descriptor", wallet.Query{InputDescriptor: descriptor}, []string{degree.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Query returned %d credentials, want %d", len(got), len(tt.want))
+			}
+			for i, credential := range got {
+				if credential.ID != tt.want[i] {
+					t.Errorf("credential %d = %s, want %s", i, credential.ID, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if jwts := (wallet.Credentials{employee, degree}).JWTs(); len(jwts) != 2 || jwts[0] != employee.JWT {
+		t.Errorf("unexpected JWTs: %v", jwts)
+	}
+
+	if err := w.Remove(ctx, employee.ID); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := w.Get(ctx, employee.ID); err != wallet.ErrNotFound {
+		t.Fatalf("expected ErrNotFound after Remove, got %v", err)
+	}
+}
+
+func TestWalletSelectUsesClock(t *testing.T) {
+	ctx := context.Background()
+	fake := clock.NewFake(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	w := wallet.New(wallet.NewMemoryStore(), wallet.WithClock(fake))
+
+	employee, err := w.Add(ctx, newJWT(t, map[string]any{
+		"id":                "urn:uuid:employee",
+		"type":              []string{"VerifiableCredential", "EmployeeCredential"},
+		"issuer":            "did:example:acme",
+		"validUntil":        "2026-01-01T00:00:00Z",
+		"credentialSubject": map[string]any{"id": "did:example:holder"},
+	}))
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if !employee.AddedAt.Equal(fake.Now()) {
+		t.Errorf("AddedAt = %v, want %v", employee.AddedAt, fake.Now())
+	}
+
+	if jwts, err := w.Select(ctx, pe.InputDescriptor{ID: "any"}); err != nil || len(jwts) != 1 {
+		t.Fatalf("Select = %v, %v; want the valid credential", jwts, err)
+	}
+
+	fake.Advance(365 * 24 * time.Hour)
+	if jwts, err := w.Select(ctx, pe.InputDescriptor{ID: "any"}); err != nil || len(jwts) != 0 {
+		t.Fatalf("Select = %v, %v; want no credential once expired", jwts, err)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	ctx := context.Background()
+	inner := wallet.NewMemoryStore()
+	enc, err := pii.NewAESGCM(secret.FromBytes(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := wallet.New(wallet.NewEncryptedStore(inner, enc))
+
+	added, err := w.Add(ctx, newJWT(t, map[string]any{
+		"id":                "urn:uuid:id-card",
+		"issuer":            "did:example:state",
+		"credentialSubject": map[string]any{"id": "did:example:holder", "name": "Nguyen Van A"},
+	}))
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	stored, _ := inner.Get(ctx, added.ID)
+	if stored.JWT != "" || stored.Claims.Issuer != "" || len(stored.Sealed) == 0 {
+		t.Errorf("stored credential is not sealed: %+v", stored)
+	}
+	got, err := w.Get(ctx, added.ID)
+	if err != nil || got.JWT != added.JWT || got.Claims.Subject().Claims["name"] != "Nguyen Van A" {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+}
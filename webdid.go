@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+// webDIDContexts are the JSON-LD contexts of the did:web documents built by WebDIDDocument.
+var webDIDContexts = []string{"https://www.w3.org/ns/did/v1", "https://w3id.org/security/suites/jws-2020/v1"}
+
+// WebDIDKey is a provider-managed key to publish in a did:web document.
+type WebDIDKey struct {
+	Fragment      string   // Verification method fragment, e.g. "key-1", the default of WithKeyID
+	Address       string   // Signer address passed to the provider
+	Relationships []string // Verification relationships of the key; authentication and assertionMethod when empty
+}
+
+// WebDIDDocument builds the did:web document of id, e.g. "did:web:issuer.example", publishing the public
+// keys the provider holds for keys as JsonWebKey2020 verification methods. Keys are exported like
+// ExportJWKS does. Serve the document with WebDIDHandler, or write it for a static host with
+// WriteWebDIDDocument.
+func (a *Service) WebDIDDocument(ctx context.Context, id string, keys ...WebDIDKey) (*did.Document, error) {
+	if a.provider == nil {
+		return nil, ErrNilProvider
+	}
+	if _, err := did.WebDocumentURL(id); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("a did:web document needs at least one key")
+	}
+
+	doc := &did.Document{Context: webDIDContexts, ID: id}
+	for _, key := range keys {
+		if key.Fragment == "" {
+			return nil, fmt.Errorf("key %s has no fragment", key.Address)
+		}
+		methodID := id + "#" + key.Fragment
+		if _, err := doc.FindVerificationMethod(methodID); err == nil {
+			return nil, fmt.Errorf("duplicate verification method %s", methodID)
+		}
+
+		publicKey, err := a.publicKey(ctx, key.Address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export key %s: %w", methodID, err)
+		}
+		jwk, err := did.JWKFromKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		doc.VerificationMethod = append(doc.VerificationMethod, did.VerificationMethod{
+			ID:           methodID,
+			Type:         "JsonWebKey2020",
+			Controller:   id,
+			PublicKeyJwk: &jwk,
+		})
+
+		relationships := key.Relationships
+		if len(relationships) == 0 {
+			relationships = []string{did.Authentication, did.AssertionMethod}
+		}
+		for _, relationship := range relationships {
+			switch relationship {
+			case did.Authentication:
+				doc.Authentication = append(doc.Authentication, methodID)
+			case did.AssertionMethod:
+				doc.AssertionMethod = append(doc.AssertionMethod, methodID)
+			case did.CapabilityDelegation:
+				doc.CapabilityDelegation = append(doc.CapabilityDelegation, methodID)
+			default:
+				return nil, fmt.Errorf("unsupported verification relationship %q", relationship)
+			}
+		}
+	}
+	return doc, nil
+}
+
+// WebDIDHandler serves did:web documents at the paths their DIDs map to, e.g. /.well-known/did.json for
+// did:web:issuer.example and /users/alice/did.json for did:web:issuer.example:users:alice, so an issuer
+// and its holders can share one host. Other paths are answered with 404.
+func WebDIDHandler(docs ...*did.Document) (http.Handler, error) {
+	bodies := make(map[string][]byte, len(docs))
+	for _, doc := range docs {
+		path, err := webDIDPath(doc.ID)
+		if err != nil {
+			return nil, err
+		}
+		if bodies[path], err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		_, _ = w.Write(body)
+	}), nil
+}
+
+// WriteWebDIDDocument writes doc below dir at the path its DID maps to, e.g. dir/.well-known/did.json, for
+// upload to a static host, and returns the path of the written file. A document whose path would leave dir
+// is refused.
+func WriteWebDIDDocument(dir string, doc *did.Document) (string, error) {
+	path, err := webDIDPath(doc.ID)
+	if err != nil {
+		return "", err
+	}
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(dir, filepath.FromSlash(path))
+	if rel, err := filepath.Rel(filepath.Clean(dir), file); err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("DID document path %s is outside %s", path, dir)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(file, append(body, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write DID document: %w", err)
+	}
+	return file, nil
+}
+
+// webDIDPath returns the URL path the document of a did:web DID is served at.
+func webDIDPath(id string) (string, error) {
+	location, err := did.WebDocumentURL(id)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	return u.Path, nil
+}
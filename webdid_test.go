@@ -0,0 +1,123 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/did"
+)
+
+func TestWebDIDDocument(t *testing.T) {
+	ctx := context.Background()
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+	a := registry.newAuth(authtest.NewSigner(issuer, holder))
+
+	issuerDoc, err := a.WebDIDDocument(ctx, "did:web:issuer.example",
+		auth.WebDIDKey{Fragment: "key-1", Address: issuer.Address, Relationships: []string{did.AssertionMethod}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	holderDoc, err := a.WebDIDDocument(ctx, "did:web:issuer.example:users:alice", auth.WebDIDKey{Fragment: "key-1", Address: holder.Address})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm, err := issuerDoc.FindVerificationMethod("did:web:issuer.example#key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := vm.PublicKey(); err != nil || !key.Equal(&issuer.Key.PublicKey) {
+		t.Errorf("published key does not match the provider's: %v", err)
+	}
+	if alg, err := vm.Algorithm(); err != nil || alg != did.AlgES256K {
+		t.Errorf("Algorithm = %q, %v", alg, err)
+	}
+	if !issuerDoc.HasRelationship(did.AssertionMethod, vm.ID) || issuerDoc.HasRelationship(did.Authentication, vm.ID) {
+		t.Errorf("unexpected relationships: %+v", issuerDoc)
+	}
+	if !holderDoc.HasRelationship(did.Authentication, "did:web:issuer.example:users:alice#key-1") {
+		t.Errorf("holder key is not listed for authentication: %+v", holderDoc)
+	}
+
+	handler, err := auth.WebDIDHandler(issuerDoc, holderDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{"/.well-known/did.json": issuerDoc.ID, "/users/alice/did.json": holderDoc.ID} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var served did.Document
+		if err := json.Unmarshal(rec.Body.Bytes(), &served); rec.Code != http.StatusOK || err != nil || served.ID != want {
+			t.Errorf("GET %s: %d %s", path, rec.Code, rec.Body.String())
+		}
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/bob/did.json", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET of an unknown document: %d", rec.Code)
+	}
+
+	dir := t.TempDir()
+	file, err := auth.WriteWebDIDDocument(dir, holderDoc)
+	if err != nil || file != filepath.Join(dir, "users", "alice", "did.json") {
+		t.Fatalf("WriteWebDIDDocument = %q, %v", file, err)
+	}
+	if data, err := os.ReadFile(file); err != nil || !json.Valid(data) {
+		t.Errorf("written document is not JSON: %v", err)
+	}
+
+	escaping := &did.Document{ID: "did:web:issuer.example:a%2F..%2F..%2Fescaped"}
+	if _, err := auth.WriteWebDIDDocument(filepath.Join(dir, "site"), escaping); err == nil {
+		t.Error("expected an error for a document outside the directory")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("a document was written outside the directory: %v", err)
+	}
+
+	if _, err := a.WebDIDDocument(ctx, issuer.DID, auth.WebDIDKey{Fragment: "key-1", Address: issuer.Address}); err == nil {
+		t.Error("expected an error for a DID of another method")
+	}
+}
+
+func TestWebDocumentURL(t *testing.T) {
+	for id, want := range map[string]string{
+		"did:web:issuer.example":                  "https://issuer.example/.well-known/did.json",
+		"did:web:issuer.example:users:alice":      "https://issuer.example/users/alice/did.json",
+		"did:web:localhost%3A8443:issuers:test-1": "https://localhost:8443/issuers/test-1/did.json",
+	} {
+		got, err := did.WebDocumentURL(id)
+		if err != nil || got != want {
+			t.Errorf("WebDocumentURL(%s) = %q, %v; want %q", id, got, err, want)
+		}
+	}
+	for _, id := range []string{
+		"did:nda:testnet:0x01", "did:web:issuer.example:..", "did:web:user%40issuer.example",
+		"did:web:issuer.example:.", "did:web:issuer.example:a%2F..%2F..%2Fescaped", "did:web:issuer.example:a%5C..",
+	} {
+		if _, err := did.WebDocumentURL(id); err == nil {
+			t.Errorf("WebDocumentURL(%s) should fail", id)
+		}
+	}
+
+	for location, want := range map[string]string{
+		"https://issuer.example":                "did:web:issuer.example",
+		"https://issuer.example/users/alice/":   "did:web:issuer.example:users:alice",
+		"https://localhost:8443/issuers/test-1": "did:web:localhost%3A8443:issuers:test-1",
+	} {
+		if got, err := did.NewWebDID(location); err != nil || got != want {
+			t.Errorf("NewWebDID(%s) = %q, %v; want %q", location, got, err, want)
+		}
+	}
+	if _, err := did.NewWebDID("http://issuer.example"); err == nil {
+		t.Error("NewWebDID should require https")
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github/hovanhoa/go-vc-auth/trust"
+)
+
+// WithCertificateChain attaches an X.509 chain to the VP JWT as "x5c" and "x5t#S256" headers.
+// The leaf certificate comes first and should carry the holder DID as a URI subject alternative name.
+func WithCertificateChain(chain []*x509.Certificate) CreateOpt {
+	return func(o *createOptions) {
+		if len(chain) == 0 {
+			return
+		}
+
+		x5c := make([]string, len(chain))
+		for i, cert := range chain {
+			x5c[i] = base64.StdEncoding.EncodeToString(cert.Raw)
+		}
+
+		WithHeader("x5c", x5c)(o)
+		WithHeader("x5t#S256", certificateThumbprint(chain[0]))(o)
+	}
+}
+
+// WithX509Roots enables certificate binding checks against the given trust anchors.
+// Every credential must then carry an "x5c" chain that validates to roots and whose leaf certificate
+// names the issuer DID as a URI subject alternative name. A chain on the presentation itself is
+// verified the same way against the holder DID when present.
+func WithX509Roots(roots *x509.CertPool) VerifyOpt {
+	return func(o *verifyOptions) {
+		o.x509Roots = roots
+	}
+}
+
+// certificateThumbprint returns the base64url SHA-256 thumbprint of a certificate.
+func certificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// parseCertificateChain decodes the "x5c" header of a token; it returns nil when the header is absent.
+func parseCertificateChain(token *jwtToken) ([]*x509.Certificate, error) {
+	raw, ok := token.header["x5c"]
+	if !ok {
+		return nil, nil
+	}
+
+	entries, ok := raw.([]any)
+	if !ok || len(entries) == 0 {
+		return nil, errors.New("x5c header must be a non-empty array")
+	}
+
+	chain := make([]*x509.Certificate, len(entries))
+	for i, entry := range entries {
+		encoded, ok := entry.(string)
+		if !ok {
+			return nil, fmt.Errorf("x5c entry %d is not a string", i)
+		}
+
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("x5c entry %d: %w", i, err)
+		}
+
+		if chain[i], err = x509.ParseCertificate(der); err != nil {
+			return nil, fmt.Errorf("x5c entry %d: %w", i, err)
+		}
+	}
+
+	return chain, nil
+}
+
+// verifyCertificateBinding validates the token's certificate chain against roots at time now and
+// checks that the leaf certificate is issued to subjectDID.
+func verifyCertificateBinding(token *jwtToken, subjectDID string, roots *x509.CertPool, now time.Time) error {
+	chain, err := parseCertificateChain(token)
+	if err != nil {
+		return err
+	}
+	if chain == nil {
+		return errors.New("x5c header is required")
+	}
+
+	if thumbprint, ok := token.header["x5t#S256"].(string); ok && thumbprint != certificateThumbprint(chain[0]) {
+		return errors.New("x5t#S256 does not match the leaf certificate")
+	}
+
+	return verifyCertificateChain(chain, subjectDID, roots, now)
+}
+
+// verifyCertificateChain validates a leaf-first certificate chain against roots at time now and
+// checks that the leaf certificate is issued to subjectDID.
+func verifyCertificateChain(chain []*x509.Certificate, subjectDID string, roots *x509.CertPool, now time.Time) error {
+	leaf := chain[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return fmt.Errorf("certificate chain verification failed: %w", err)
+	}
+
+	for _, uri := range leaf.URIs {
+		if uri.String() == subjectDID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("leaf certificate is not issued to %s", subjectDID)
+}
+
+// checkIssuerTrust asks registry whether the issuer of the credential token, signed with issuerKey, is trusted.
+func checkIssuerTrust(ctx context.Context, registry trust.IssuerRegistry, vcToken *jwtToken, issuerDid string, issuerKey crypto.PublicKey) error {
+	chain, err := parseCertificateChain(vcToken)
+	if err != nil {
+		return fmt.Errorf("failed to parse issuer certificate: %w", err)
+	}
+
+	return checkIssuer(ctx, registry, trust.Issuer{DID: issuerDid, Certificates: chain, PublicKey: issuerKey})
+}
+
+// checkIssuer asks registry whether issuer is trusted.
+func checkIssuer(ctx context.Context, registry trust.IssuerRegistry, issuer trust.Issuer) error {
+	var trusted bool
+	err := runStep(ctx, "issuer trust check", statusBudget, func(ctx context.Context) (err error) {
+		trusted, err = registry.IsTrusted(ctx, issuer)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check issuer trust: %w", err)
+	}
+	if !trusted {
+		return fmt.Errorf("%w: %s", trust.ErrUntrustedIssuer, issuer.DID)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	auth "github/hovanhoa/go-vc-auth"
+	"github/hovanhoa/go-vc-auth/authtest"
+	"github/hovanhoa/go-vc-auth/trust"
+)
+
+// newTestCertificate issues a certificate for the given DID, self-signed when parent is nil.
+func newTestCertificate(t *testing.T, subjectDID string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: subjectDID},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	} else {
+		uri, _ := url.Parse(subjectDID)
+		template.URIs = []*url.URL{uri}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+// TestVerifyTokenX509Binding checks issuer certificate binding against configured roots.
+func TestVerifyTokenX509Binding(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	root, rootKey := newTestCertificate(t, "Test Root", nil, nil)
+	leaf, _ := newTestCertificate(t, issuer.DID, root, rootKey)
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	certified := registry.issueCredentialWithHeader(t, issuer, holder, map[string]any{"role": "viewer"}, map[string]any{
+		"x5c": []string{base64.StdEncoding.EncodeToString(leaf.Raw)},
+	})
+	token, err := a.CreateToken(context.Background(), []string{certified}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithX509Roots(roots)); err != nil {
+		t.Fatalf("VerifyToken failed: %v", err)
+	}
+
+	uncertified := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err = a.CreateToken(context.Background(), []string{uncertified}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithX509Roots(roots)); err == nil {
+		t.Fatalf("expected credential without x5c to be rejected")
+	}
+	if _, err := a.VerifyToken(context.Background(), token); err != nil {
+		t.Fatalf("expected x5c to be optional without roots: %v", err)
+	}
+}
+
+func TestVerifyTokenIssuerRegistry(t *testing.T) {
+	registry := newTestRegistry(t)
+	issuer := registry.newIdentity(t)
+	holder := registry.newIdentity(t)
+
+	a := registry.newAuth(authtest.NewSigner(holder))
+
+	vcJwt := registry.issueCredential(t, issuer, holder, map[string]any{"role": "viewer"})
+	token, err := a.CreateToken(context.Background(), []string{vcJwt}, holder.DID, holder.Address)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	if _, err := a.VerifyToken(context.Background(), token, auth.WithIssuerRegistry(trust.NewStaticRegistry(issuer.DID))); err != nil {
+		t.Fatalf("VerifyToken failed: %v", err)
+	}
+
+	_, err = a.VerifyToken(context.Background(), token, auth.WithIssuerRegistry(trust.NewStaticRegistry(holder.DID)))
+	if !errors.Is(err, trust.ErrUntrustedIssuer) {
+		t.Fatalf("expected ErrUntrustedIssuer, got %v", err)
+	}
+}